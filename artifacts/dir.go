@@ -0,0 +1,83 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// DirStore keeps artifacts as files in a local directory: the content in a
+// file named by the artifact ID and its description next to it in <id>.json
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates a store in dir, creating the directory if needed
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// validID matches the IDs NewID returns, so an ID never names a path outside the store
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Put writes data and its description to the directory
+func (s *DirStore) Put(_ context.Context, name, contentType string, data []byte) (Artifact, error) {
+	artifact := Artifact{ID: NewID(), Name: name, ContentType: contentType, Size: len(data), Created: time.Now()}
+	description, err := json.Marshal(artifact)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, artifact.ID), data, 0o600); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	// The description is written last, so a half written artifact is not found
+	if err := os.WriteFile(filepath.Join(s.dir, artifact.ID+".json"), description, 0o600); err != nil {
+		os.Remove(filepath.Join(s.dir, artifact.ID))
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return artifact, nil
+}
+
+// Get reads an artifact from the directory
+func (s *DirStore) Get(_ context.Context, id string) ([]byte, Artifact, error) {
+	if !validID.MatchString(id) {
+		return nil, Artifact{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	description, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Artifact{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, Artifact{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	var artifact Artifact
+	if err := json.Unmarshal(description, &artifact); err != nil {
+		return nil, Artifact{}, fmt.Errorf("invalid artifact description %s: %w", id, err)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id))
+	if err != nil {
+		return nil, Artifact{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return data, artifact, nil
+}
+
+// Delete removes an artifact from the directory
+func (s *DirStore) Delete(_ context.Context, id string) error {
+	if !validID.MatchString(id) {
+		return nil
+	}
+	for _, path := range []string{filepath.Join(s.dir, id+".json"), filepath.Join(s.dir, id)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete artifact: %w", err)
+		}
+	}
+	return nil
+}
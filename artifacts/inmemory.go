@@ -0,0 +1,50 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// InMemoryStore keeps artifacts in process memory
+type InMemoryStore struct {
+	mu        sync.RWMutex
+	artifacts map[string]Artifact
+	contents  map[string][]byte
+}
+
+// NewInMemoryStore creates an empty in-memory store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{artifacts: make(map[string]Artifact), contents: make(map[string][]byte)}
+}
+
+// Put stores a copy of data under a new ID
+func (s *InMemoryStore) Put(_ context.Context, name, contentType string, data []byte) (Artifact, error) {
+	artifact := Artifact{ID: NewID(), Name: name, ContentType: contentType, Size: len(data), Created: time.Now()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifacts[artifact.ID] = artifact
+	s.contents[artifact.ID] = append([]byte(nil), data...)
+	return artifact, nil
+}
+
+// Get returns a copy of the content of an artifact
+func (s *InMemoryStore) Get(_ context.Context, id string) ([]byte, Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	artifact, ok := s.artifacts[id]
+	if !ok {
+		return nil, Artifact{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return append([]byte(nil), s.contents[id]...), artifact, nil
+}
+
+// Delete removes an artifact
+func (s *InMemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.artifacts, id)
+	delete(s.contents, id)
+	return nil
+}
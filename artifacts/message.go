@@ -0,0 +1,32 @@
+package artifacts
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// Attach returns the message referring to the artifacts with the given IDs in
+// its llm.MetaArtifacts metadata, after the artifacts it already refers to
+func Attach(message llm.Message, ids ...string) llm.Message {
+	attached := Attached(message)
+	for _, id := range ids {
+		if id != "" && !slices.Contains(attached, id) {
+			attached = append(attached, id)
+		}
+	}
+	if len(attached) == 0 {
+		return message
+	}
+	return message.WithMeta(llm.MetaArtifacts, strings.Join(attached, ","))
+}
+
+// Attached returns the IDs of the artifacts the message refers to
+func Attached(message llm.Message) []string {
+	ids := message.Meta(llm.MetaArtifacts)
+	if ids == "" {
+		return nil
+	}
+	return strings.Split(ids, ",")
+}
@@ -0,0 +1,160 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/sigv4"
+)
+
+// S3Config configures an S3Store
+type S3Config struct {
+	Bucket          string       // Bucket the artifacts are stored in
+	Prefix          string       // Key prefix of the artifacts, e.g. "agent/artifacts/"
+	Region          string       // Defaults to AWS_REGION, then AWS_DEFAULT_REGION
+	Endpoint        string       // API endpoint for S3 compatible stores such as MinIO, addressed path style; defaults to https://<bucket>.s3.<region>.amazonaws.com
+	AccessKeyID     string       // Defaults to AWS_ACCESS_KEY_ID
+	SecretAccessKey string       // Defaults to AWS_SECRET_ACCESS_KEY
+	SessionToken    string       // Defaults to AWS_SESSION_TOKEN
+	HTTPClient      *http.Client // Defaults to a client with a 60 second timeout
+}
+
+// S3Store keeps artifacts as objects in an S3 bucket. The name and creation
+// time of an artifact are stored as object metadata.
+type S3Store struct {
+	config S3Config
+	client *http.Client
+}
+
+// NewS3Store creates a store on the S3 REST API
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is not set")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("S3 region is not set")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://" + config.Bucket + ".s3." + config.Region + ".amazonaws.com"
+	} else {
+		config.Endpoint = strings.TrimSuffix(config.Endpoint, "/") + "/" + config.Bucket
+	}
+	if config.AccessKeyID == "" {
+		credentials := sigv4.EnvCredentials()
+		config.AccessKeyID, config.SecretAccessKey, config.SessionToken = credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS credentials are not set")
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3Store{config: config, client: client}, nil
+}
+
+// Put uploads data as a new object
+func (s *S3Store) Put(ctx context.Context, name, contentType string, data []byte) (Artifact, error) {
+	artifact := Artifact{ID: NewID(), Name: name, ContentType: contentType, Size: len(data), Created: time.Now().UTC().Truncate(time.Second)}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("X-Amz-Meta-Name", url.QueryEscape(name))
+	header.Set("X-Amz-Meta-Created", artifact.Created.Format(time.RFC3339))
+
+	response, err := s.do(ctx, http.MethodPut, artifact.ID, header, data)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return Artifact{}, s3Error(response)
+	}
+	return artifact, nil
+}
+
+// Get downloads an object
+func (s *S3Store) Get(ctx context.Context, id string) ([]byte, Artifact, error) {
+	response, err := s.do(ctx, http.MethodGet, id, nil, nil)
+	if err != nil {
+		return nil, Artifact{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, Artifact{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, Artifact{}, s3Error(response)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, Artifact{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+
+	artifact := Artifact{ID: id, ContentType: response.Header.Get("Content-Type"), Size: len(data)}
+	artifact.Name, _ = url.QueryUnescape(response.Header.Get("X-Amz-Meta-Name"))
+	artifact.Created, _ = time.Parse(time.RFC3339, response.Header.Get("X-Amz-Meta-Created"))
+	return data, artifact, nil
+}
+
+// Delete removes an object; S3 does not report missing objects
+func (s *S3Store) Delete(ctx context.Context, id string) error {
+	if !validID.MatchString(id) {
+		return nil
+	}
+	response, err := s.do(ctx, http.MethodDelete, id, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		return s3Error(response)
+	}
+	return nil
+}
+
+// do sends a signed request for the object of an artifact
+func (s *S3Store) do(ctx context.Context, method, id string, header http.Header, payload []byte) (*http.Response, error) {
+	if !validID.MatchString(id) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, s.config.Endpoint+"/"+s.config.Prefix+id, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	hash := sha256.Sum256(payload)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(hash[:]))
+	credentials := sigv4.Credentials{AccessKeyID: s.config.AccessKeyID, SecretAccessKey: s.config.SecretAccessKey, SessionToken: s.config.SessionToken}
+	sigv4.Sign(request, payload, credentials, s.config.Region, "s3", time.Now())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s failed: %w", method, err)
+	}
+	return response, nil
+}
+
+// s3Error describes an unexpected S3 response
+func s3Error(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return fmt.Errorf("S3 returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+}
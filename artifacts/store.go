@@ -0,0 +1,46 @@
+// Package artifacts stores content too large to keep in flow state or in the
+// conversation, such as long tool results, under an ID that can be passed
+// around instead.
+package artifacts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned for IDs that name no stored artifact
+var ErrNotFound = errors.New("artifact not found")
+
+// Artifact describes stored content
+type Artifact struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`         // Human readable name, e.g. the tool that produced it
+	ContentType string    `json:"content_type"` // MIME type of the content
+	Size        int       `json:"size"`         // Size of the content in bytes
+	Created     time.Time `json:"created"`
+}
+
+// Store holds artifacts by ID
+type Store interface {
+	// Put stores data and returns the artifact describing it
+	Put(ctx context.Context, name, contentType string, data []byte) (Artifact, error)
+
+	// Get returns the content and description of an artifact, or ErrNotFound
+	Get(ctx context.Context, id string) ([]byte, Artifact, error)
+
+	// Delete removes an artifact; deleting a missing artifact is not an error
+	Delete(ctx context.Context, id string) error
+}
+
+// NewID returns a random artifact ID
+func NewID() string {
+	var id [12]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("artifact-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,117 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// testStore checks the behavior every Store implementation shares
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	artifact, err := store.Put(ctx, "read_file result", "text/plain", []byte("file contents"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if artifact.ID == "" || artifact.Size != 13 || artifact.Name != "read_file result" {
+		t.Errorf("Put() = %+v", artifact)
+	}
+
+	data, got, err := store.Get(ctx, artifact.ID)
+	if err != nil || string(data) != "file contents" || got.ContentType != "text/plain" {
+		t.Errorf("Get() = %q, %+v, %v", data, got, err)
+	}
+
+	if err := store.Delete(ctx, artifact.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := store.Get(ctx, artifact.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete(ctx, artifact.ID); err != nil {
+		t.Errorf("deleting a missing artifact = %v", err)
+	}
+}
+
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, NewInMemoryStore())
+}
+
+func TestDirStore(t *testing.T) {
+	store, err := NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirStore failed: %v", err)
+	}
+	testStore(t, store)
+	if _, _, err := store.Get(context.Background(), "../secrets"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() outside the store = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	// A bucket keeping objects with their headers, as S3 does
+	type object struct {
+		header http.Header
+		body   []byte
+	}
+	var mu sync.Mutex
+	objects := make(map[string]object)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = object{header: r.Header.Clone(), body: body}
+		case http.MethodGet:
+			stored, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			for _, name := range []string{"Content-Type", "X-Amz-Meta-Name", "X-Amz-Meta-Created"} {
+				w.Header().Set(name, stored.header.Get(name))
+			}
+			w.Write(stored.body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{Bucket: "agent", Prefix: "artifacts/", Region: "us-east-1", Endpoint: server.URL,
+		AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+	testStore(t, store)
+	if _, err := NewS3Store(S3Config{Region: "us-east-1"}); err == nil {
+		t.Error("expected an error without a bucket")
+	}
+}
+
+func TestAttach(t *testing.T) {
+	message := Attach(llm.Message{Role: llm.RoleUser, Content: "The chart is ready."}, "a1", "b2")
+	message = Attach(message, "a1", "c3")
+	if ids := Attached(message); !reflect.DeepEqual(ids, []string{"a1", "b2", "c3"}) {
+		t.Errorf("Attached() = %v", ids)
+	}
+	if ids := Attached(llm.Message{}); ids != nil {
+		t.Errorf("Attached() of a plain message = %v", ids)
+	}
+}
@@ -0,0 +1,85 @@
+// Command actioncheck reports Post methods returning actions their package
+// does not declare with Flow.DeclareActions:
+//
+//	go run github.com/alt-coder/pocketflow-go/cmd/actioncheck ./...
+//
+// It exits with status 1 when it finds any.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/core/actioncheck"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: actioncheck [packages]")
+		fmt.Fprintln(os.Stderr, "Packages are directories; dir/... includes the directories below dir.")
+	}
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	var dirs []string
+	for _, pattern := range patterns {
+		expanded, err := expand(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "actioncheck: %v\n", err)
+			os.Exit(2)
+		}
+		dirs = append(dirs, expanded...)
+	}
+
+	found := false
+	for _, dir := range dirs {
+		findings, err := actioncheck.CheckDir(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "actioncheck: %v\n", err)
+			os.Exit(2)
+		}
+		for _, finding := range findings {
+			fmt.Println(finding)
+			found = true
+		}
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// expand returns the package directories of a pattern
+func expand(pattern string) ([]string, error) {
+	root, recursive := strings.CutSuffix(pattern, "/...")
+	if !recursive {
+		return []string{pattern}, nil
+	}
+	if root == "" {
+		root = "."
+	}
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		name := entry.Name()
+		if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+		if matches, _ := filepath.Glob(filepath.Join(path, "*.go")); len(matches) > 0 {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs, err
+}
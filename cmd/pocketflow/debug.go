@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/debugger"
+	"github.com/alt-coder/pocketflow-go/flowdef"
+)
+
+// startDebugger attaches a debugger pausing before the nodes listed in
+// breakpoints to flow, with its console on the terminal or, given addr, over
+// HTTP under /debug/; stop closes the console
+func startDebugger(flow *core.Flow[flowdef.State], breakpoints, addr string) (stop func(), err error) {
+	var names []string
+	for _, name := range strings.Split(breakpoints, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	dbg := debugger.New[flowdef.State](names...)
+	dbg.Attach(flow)
+
+	if addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serve the debug console: %w", err)
+		}
+		server := &http.Server{Handler: http.StripPrefix("/debug", dbg.Handler())}
+		go server.Serve(listener)
+		fmt.Fprintf(os.Stderr, "debug console at http://%s/debug/\n", listener.Addr())
+		return func() { server.Close() }, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := dbg.Console(ctx, os.Stdin, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "debug console: %v\n", err)
+		}
+	}()
+	fmt.Fprintln(os.Stderr, `debug console on the terminal; type "help" when the run pauses`)
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/structured"
+)
+
+// extractUsage explains the extract command
+const extractUsage = `usage: pocketflow extract -schema file [flags] input...
+
+Parses every input file, and every file of input directories, into the schema
+and writes one JSON line per parsed input: {"input": ..., "data": ...}.
+
+The schema is a .go file with struct types, e.g.
+
+    type Invoice struct {
+        Number string    ` + "`json:\"number\" required:\"true\"`" + `
+        Issued time.Time ` + "`json:\"issued\"`" + `
+        Total  float64   ` + "`json:\"total\" min:\"0\"`" + ` // Amount due
+    }
+
+or a .json JSON Schema. Outputs that fail validation are sent back to the
+model with the errors, up to -retries times. Inputs that still fail are listed
+on stderr and make the command exit with status 1.
+
+The API key is read from OPENAI_API_KEY or GOOGLE_API_KEY.
+
+Flags:
+`
+
+// runExtract runs the extract command
+func runExtract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	var provider providerFlags
+	provider.register(flags)
+	schemaPath := flags.String("schema", "", "schema file: .go struct types or .json JSON Schema (required)")
+	typeName := flags.String("type", "", "struct of a .go schema to extract (default the first declared)")
+	extensions := flags.String("ext", "", "comma-separated extensions of the directory files to parse (default all)")
+	contextText := flags.String("context", "", "context added to every extraction prompt")
+	concurrency := flags.Int("concurrency", 4, "inputs parsed at once")
+	requestsPerSecond := flags.Float64("rps", 2, "LLM requests started per second; 0 disables limiting")
+	retries := flags.Int("retries", 2, "repair prompts per input after invalid output")
+	outPath := flags.String("out", "", "file to write the JSON lines to (default stdout)")
+	cacheDir := flags.String("cache", "", "directory caching results by file content, so unchanged inputs are not parsed again")
+	cacheTTL := flags.Duration("cache-ttl", 0, "how long cached results are used (default forever)")
+	quiet := flags.Bool("quiet", false, "do not report progress on stderr")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, extractUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *schemaPath == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", *concurrency)
+	}
+
+	schema, err := structured.LoadDynamicSchema(*schemaPath, *typeName)
+	if err != nil {
+		return err
+	}
+	inputs, err := extractInputs(flags.Args(), splitList(*extensions))
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("no input files found")
+	}
+
+	ctx := context.Background()
+	llmProvider, err := provider.newProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	config := structured.DefaultBatchConfig()
+	config.MaxRetries = *retries
+	config.RequestsPerSecond = *requestsPerSecond
+	config.Schema = schema
+	if *contextText != "" {
+		config.AdditionalContext = []string{*contextText}
+	}
+	if *cacheDir != "" {
+		store, err := structured.NewFileCache(*cacheDir)
+		if err != nil {
+			return err
+		}
+		config.Cache = &structured.CacheConfig{Store: store, TTL: *cacheTTL, Model: provider.model}
+	}
+	node, err := structured.NewBatchStructuredNode[map[string]any, *structured.BatchState[map[string]any]](llmProvider, config, nil)
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	flow := core.NewFlow(core.NewNode(node, 0, *concurrency))
+	if !*quiet {
+		flow.SetProgressReporter(func(progress core.Progress) {
+			if progress.Done > 0 {
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", progress.Done, progress.Total, progress.Description)
+			}
+		})
+	}
+	state := &structured.BatchState[map[string]any]{Inputs: inputs}
+	flow.Run(&state)
+
+	report := state.Report
+	if err := writeExtracted(out, schema, report); err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, report.Summary())
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d inputs failed", report.Failed, report.Total)
+	}
+	return nil
+}
+
+// extractInputs lists the files of the command line arguments; directories
+// contribute their files with one of the extensions
+func extractInputs(paths []string, extensions []string) ([]structured.BatchInput, error) {
+	var inputs []structured.BatchInput
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			inputs = append(inputs, structured.BatchInput{ID: path, FilePath: path})
+			continue
+		}
+		directoryInputs, err := structured.DirectoryInputs(path, extensions...)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, directoryInputs...)
+	}
+	return inputs, nil
+}
+
+// writeExtracted writes a JSON line for every parsed input, in input order
+func writeExtracted(out io.Writer, schema *structured.DynamicSchema, report *structured.BatchReport[map[string]any]) error {
+	encoder := json.NewEncoder(out)
+	for _, item := range report.Items {
+		if !item.Succeeded() {
+			continue
+		}
+		data, err := schema.Decode(*item.Result.Data)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", item.Input.ID, err)
+		}
+		line := struct {
+			Input string `json:"input"`
+			Data  any    `json:"data"`
+		}{item.Input.ID, data}
+		if err := encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/structured"
+)
+
+// writeFiles creates the files, relative to dir, with their contents
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtractInputs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"scans/b.txt":        "b",
+		"scans/a.TXT":        "a",
+		"scans/c.md":         "c",
+		"scans/nested/d.txt": "d",
+		"single.pdf":         "e",
+	})
+	scans := filepath.Join(dir, "scans")
+	single := filepath.Join(dir, "single.pdf")
+
+	tests := []struct {
+		name       string
+		paths      []string
+		extensions []string
+		want       []string
+	}{
+		{"directory files sorted, subdirectories skipped", []string{scans}, nil, []string{"scans/a.TXT", "scans/b.txt", "scans/c.md"}},
+		{"extensions filter directories", []string{scans}, []string{"txt"}, []string{"scans/a.TXT", "scans/b.txt"}},
+		{"extensions with dots", []string{scans}, []string{".md", ".pdf"}, []string{"scans/c.md"}},
+		{"files are kept whatever their extension", []string{single, scans}, []string{"md"}, []string{"single.pdf", "scans/c.md"}},
+		{"no matching files", []string{scans}, []string{"csv"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := extractInputs(tt.paths, tt.extensions)
+			if err != nil {
+				t.Fatalf("extractInputs() error = %v", err)
+			}
+			var got []string
+			for _, input := range inputs {
+				if input.ID != input.FilePath {
+					t.Errorf("input %s reads %s", input.ID, input.FilePath)
+				}
+				relative, _ := filepath.Rel(dir, input.ID)
+				got = append(got, filepath.ToSlash(relative))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractInputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := extractInputs([]string{filepath.Join(dir, "missing")}, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing input to fail, got %v", err)
+	}
+}
+
+func TestWriteExtracted(t *testing.T) {
+	schema, err := structured.ParseJSONSchema([]byte(`{"type": "object", "properties": {"number": {"type": "string"}, "total": {"type": "number"}}}`), "Invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := func(id string, data map[string]any) structured.BatchItemResult[map[string]any] {
+		return structured.BatchItemResult[map[string]any]{
+			Input:  structured.BatchInput{ID: id},
+			Result: structured.ParseResult[map[string]any]{Data: &data},
+		}
+	}
+	report := &structured.BatchReport[map[string]any]{Items: []structured.BatchItemResult[map[string]any]{
+		parsed("c.txt", map[string]any{"number": "C-3", "total": 30.0}),
+		{Input: structured.BatchInput{ID: "a.txt"}, Result: structured.ParseResult[map[string]any]{Error: errors.New("invalid output")}},
+		parsed("b.txt", map[string]any{"number": "B-2"}),
+	}}
+
+	var out bytes.Buffer
+	if err := writeExtracted(&out, schema, report); err != nil {
+		t.Fatalf("writeExtracted() error = %v", err)
+	}
+	want := `{"input":"c.txt","data":{"number":"C-3","total":30}}
+{"input":"b.txt","data":{"number":"B-2"}}
+`
+	if out.String() != want {
+		t.Errorf("writeExtracted() wrote\n%s\nwant\n%s", out.String(), want)
+	}
+
+	report.Items = append(report.Items, parsed("d.txt", map[string]any{"total": "thirty"}))
+	if err := writeExtracted(&bytes.Buffer{}, schema, report); err == nil || !strings.Contains(err.Error(), "failed to decode d.txt") {
+		t.Errorf("expected a decode error naming the input, got %v", err)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"txt", []string{"txt"}},
+		{"txt,md", []string{"txt", "md"}},
+		{" txt , .md ,", []string{"txt", ".md"}},
+		{",,", nil},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitList(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRunExtract_Failures(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"invoice.json":   `{"type": "object", "properties": {"number": {"type": "string"}}, "required": ["number"]}`,
+		"scans/a.txt":    "Invoice A-1",
+		"empty/notes.md": "no invoices here",
+	})
+	schema := filepath.Join(dir, "invoice.json")
+	scans := filepath.Join(dir, "scans")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"concurrency below one", []string{"-schema", schema, "-concurrency", "0", scans}, "concurrency must be at least 1"},
+		{"missing schema", []string{"-schema", filepath.Join(dir, "missing.json"), scans}, "missing.json"},
+		{"missing input", []string{"-schema", schema, filepath.Join(dir, "missing")}, "missing"},
+		{"no input files", []string{"-schema", schema, "-ext", "txt", filepath.Join(dir, "empty")}, "no input files found"},
+		{"unknown provider", []string{"-schema", schema, "-provider", "unknown", scans}, "unknown"},
+		// The mock provider's answer is not an invoice
+		{"inputs failed", []string{"-schema", schema, "-provider", "mock", "-retries", "0", "-rps", "0", "-quiet", "-out", filepath.Join(dir, "out.jsonl"), scans}, "1 of 1 inputs failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runExtract(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runExtract() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.jsonl"))
+	if err != nil || len(data) != 0 {
+		t.Errorf("expected an empty output for failed inputs, got %q, %v", data, err)
+	}
+}
+
+// TestExtract_ExitStatus runs the command in a subprocess, since usage errors
+// and failed inputs end it with os.Exit
+func TestExtract_ExitStatus(t *testing.T) {
+	if args := os.Getenv("POCKETFLOW_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"pocketflow"}, strings.Split(args, " ")...)
+		main()
+		os.Exit(0)
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"invoice.json": `{"type": "object", "properties": {"number": {"type": "string"}}, "required": ["number"]}`,
+		"scans/a.txt":  "Invoice A-1",
+	})
+
+	tests := []struct {
+		name string
+		args string
+		want int
+	}{
+		{"no command", "help", 2},
+		{"unknown command", "parse", 2},
+		{"missing schema", "extract " + filepath.Join(dir, "scans"), 2},
+		{"missing inputs", "extract -schema " + filepath.Join(dir, "invoice.json"), 2},
+		{"failed inputs", "extract -quiet -provider mock -retries 0 -rps 0 -schema " + filepath.Join(dir, "invoice.json") + " " + filepath.Join(dir, "scans"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestExtract_ExitStatus$")
+			cmd.Env = append(os.Environ(), "POCKETFLOW_TEST_ARGS="+tt.args)
+			err := cmd.Run()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != tt.want {
+				t.Errorf("pocketflow %s exited with %v, want status %d", tt.args, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Command pocketflow runs pocketflow pipelines without writing Go:
+//
+//	pocketflow run -config pocketflow.json -state ticket.json triage
+//	pocketflow extract -schema invoice.go -out invoices.jsonl ./scans
+//
+// Run "pocketflow <command> -h" for the flags of a command.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of pocketflow
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands are the subcommands in the order the usage lists them
+var commands = []command{
+	{name: "run", summary: "run a declarative flow and print its run report", run: runRun},
+	{name: "extract", summary: "parse documents into a schema with an LLM", run: runExtract},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "-help" || os.Args[1] == "help" {
+		usage()
+		os.Exit(2)
+	}
+	for _, command := range commands {
+		if command.name == os.Args[1] {
+			if err := command.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "pocketflow %s: %v\n", command.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "pocketflow: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+// usage lists the commands
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pocketflow <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.name, command.summary)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/alt-coder/pocketflow-go/flowdef"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// providerFlags select the LLM a command calls. API keys are read from the
+// environment, OPENAI_API_KEY or GOOGLE_API_KEY, so they stay out of shell history.
+type providerFlags struct {
+	provider    string
+	model       string
+	baseURL     string
+	temperature float64
+}
+
+// register adds the provider flags to flags
+func (p *providerFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&p.provider, "provider", "openai", "LLM provider: openai, gemini or one registered by a plugin")
+	flags.StringVar(&p.model, "model", "", "model name (default gpt-4o for openai, gemini-2.0-flash for gemini)")
+	flags.StringVar(&p.baseURL, "base-url", "", "API endpoint, e.g. of an OpenAI-compatible server")
+	flags.Float64Var(&p.temperature, "temperature", 0, "sampling temperature")
+}
+
+// newProvider creates the selected provider
+func (p *providerFlags) newProvider(ctx context.Context) (llm.LLMProvider, error) {
+	return flowdef.NewProvider(ctx, &flowdef.ProviderConfig{
+		Provider:    p.provider,
+		Model:       p.model,
+		BaseURL:     p.baseURL,
+		Temperature: float32(p.temperature),
+	})
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/flowdef"
+)
+
+// defaultConfigPath is the config run reads when -config is not given, if it exists
+const defaultConfigPath = "pocketflow.json"
+
+// runUsage explains the run command
+const runUsage = `usage: pocketflow run [flags] flow
+
+Runs a declarative flow, named in the config's flows or flow_dir or given as
+the path of its YAML or JSON definition, and writes a JSON run report with the
+final action, the steps, the final state and the retries spent.
+
+The config sets up the LLM providers and MCP tools of the nodes. Without
+-config, pocketflow.json is read if it exists; otherwise a single provider is
+chosen by OPENAI_API_KEY or GOOGLE_API_KEY.
+
+With -break, the run pauses before the named nodes and a debug console on
+the terminal shows and changes the state before the run continues or aborts;
+-debug-addr serves the console over HTTP instead.
+
+The command exits with status 1 when the flow ends with "failure".
+
+Flags:
+`
+
+// runOutput is the report the run command writes
+type runOutput struct {
+	Flow     string           `json:"flow"`
+	Action   core.Action      `json:"action"`
+	Duration core.Duration    `json:"duration"`
+	Steps    []core.StepEvent `json:"steps"`
+	State    flowdef.State    `json:"state"`
+	Report   core.RunReport   `json:"report"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// runConfigSummary is the configuration snapshot of the run report
+type runConfigSummary struct {
+	Flow   *flowdef.Definition `json:"flow"`
+	Config *flowdef.Config     `json:"config"`
+}
+
+// runRun runs the run command
+func runRun(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := flags.String("config", "", "JSON config of providers, MCP servers and flows (default pocketflow.json if present)")
+	statePath := flags.String("state", "", "JSON object with the initial state, or - for stdin")
+	stateJSON := flags.String("state-json", "", "initial state as inline JSON, e.g. '{\"ticket\": \"...\"}'")
+	outPath := flags.String("out", "", "file to write the run report to (default stdout)")
+	list := flags.Bool("list", false, "list the flows of the config and exit")
+	quiet := flags.Bool("quiet", false, "do not report steps on stderr")
+	breakAt := flags.String("break", "", "comma-separated nodes to pause before, or * for every node")
+	debugAddr := flags.String("debug-addr", "", "serve the debug console over HTTP on this address, e.g. localhost:6070, instead of the terminal")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, runUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	config, err := loadRunConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *list {
+		names, err := config.FlowNames()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	definition, err := config.LoadFlow(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	state, err := initialState(*statePath, *stateJSON)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	env, err := flowdef.NewEnvironment(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer env.Close(ctx)
+	flow, err := flowdef.Build(definition, env)
+	if err != nil {
+		return err
+	}
+	flow.SetConfigSnapshot(func() any {
+		return runConfigSummary{Flow: definition, Config: config.Redacted()}
+	})
+	if *breakAt != "" || *debugAddr != "" {
+		if *debugAddr == "" && *statePath == "-" {
+			return errors.New("-state - reads stdin, which the debug console needs; use -debug-addr")
+		}
+		stop, err := startDebugger(flow, *breakAt, *debugAddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	output := runOutput{Flow: definition.Name, Steps: []core.StepEvent{}}
+	started := time.Now()
+	output.Action, output.Report = flow.RunWithReport(&state, func(event core.StepEvent) {
+		output.Steps = append(output.Steps, event)
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "step %d: %s -> %s (%s)\n", event.Step, event.Name, event.Action, event.Duration.Round(time.Millisecond))
+		}
+	})
+	output.Duration = core.Duration(time.Since(started))
+	output.State = state
+	output.Error, _ = state[flowdef.ErrorKey].(string)
+
+	if err := writeRunOutput(*outPath, output); err != nil {
+		return err
+	}
+	if output.Action == core.ActionFailure {
+		if output.Error != "" {
+			return fmt.Errorf("flow %s failed: %s", definition.Name, output.Error)
+		}
+		return fmt.Errorf("flow %s failed", definition.Name)
+	}
+	return nil
+}
+
+// loadRunConfig reads the config at path, or the default config if it
+// exists, or else takes a provider from the environment
+func loadRunConfig(path string) (*flowdef.Config, error) {
+	if path != "" {
+		return flowdef.LoadConfig(path)
+	}
+	if _, err := os.Stat(defaultConfigPath); err == nil {
+		return flowdef.LoadConfig(defaultConfigPath)
+	}
+	return flowdef.ConfigFromEnv(), nil
+}
+
+// initialState reads the initial state from a file, stdin or inline JSON
+func initialState(path, inline string) (flowdef.State, error) {
+	if path != "" && inline != "" {
+		return nil, errors.New("-state and -state-json cannot be used together")
+	}
+	data := []byte(inline)
+	switch path {
+	case "":
+	case "-":
+		var err error
+		if data, err = io.ReadAll(os.Stdin); err != nil {
+			return nil, fmt.Errorf("failed to read state: %w", err)
+		}
+	default:
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read state: %w", err)
+		}
+	}
+
+	state := flowdef.State{}
+	if len(data) == 0 {
+		return state, nil
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("initial state must be a JSON object: %w", err)
+	}
+	return state, nil
+}
+
+// writeRunOutput writes the run report as indented JSON
+func writeRunOutput(path string, output runOutput) error {
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run report: %w", err)
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write run report: %w", err)
+	}
+	return nil
+}
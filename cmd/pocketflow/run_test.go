@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+const triageFlow = `
+name: triage
+nodes:
+  - name: classify
+    type: llm
+    config:
+      prompt: "Is this urgent? Answer yes or no: {{.ticket}}"
+    next:
+      success: route
+  - name: route
+    type: route
+    config:
+      action: "{{if eq .classify \"yes\"}}failure{{else}}success{{end}}"
+    next:
+      success: label
+  - name: label
+    type: set
+    config:
+      values:
+        label: "queued: {{.ticket}}"
+`
+
+func TestRunRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"flows/triage.yaml": triageFlow,
+		"pocketflow.json": `{
+			"providers": {"scripted": {"provider": "mock", "responses": {"outage": "yes", "typo": "no"}}},
+			"flow_dir": "flows"
+		}`,
+	})
+	config := filepath.Join(dir, "pocketflow.json")
+
+	tests := []struct {
+		name      string
+		ticket    string
+		wantErr   string
+		wantSteps []string
+		wantLabel string
+	}{
+		{"queued", "typo on the pricing page", "", []string{"classify", "route", "label"}, "queued: typo on the pricing page"},
+		{"failed", "outage in eu-west", "flow triage failed", []string{"classify", "route"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "report.json")
+			state, _ := json.Marshal(map[string]string{"ticket": tt.ticket})
+			err := runRun([]string{"-config", config, "-state-json", string(state), "-out", out, "-quiet", "triage"})
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("runRun() error = %v, want %q", err, tt.wantErr)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("expected a run report: %v", err)
+			}
+			var report runOutput
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("invalid run report %s: %v", data, err)
+			}
+			var steps []string
+			for _, step := range report.Steps {
+				steps = append(steps, step.Name)
+			}
+			if report.Flow != "triage" || strings.Join(steps, ",") != strings.Join(tt.wantSteps, ",") {
+				t.Errorf("ran %s through %v, want triage through %v", report.Flow, steps, tt.wantSteps)
+			}
+			if label, _ := report.State["label"].(string); label != tt.wantLabel {
+				t.Errorf("label = %q, want %q", label, tt.wantLabel)
+			}
+			if tt.wantErr != "" && report.Action != core.ActionFailure {
+				t.Errorf("action = %s, want failure", report.Action)
+			}
+		})
+	}
+}
+
+func TestRunRun_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"flows/triage.yaml": triageFlow,
+		"pocketflow.json":   `{"providers": {"scripted": {"provider": "mock"}}, "flow_dir": "flows"}`,
+		"state.json":        `["not", "an", "object"]`,
+	})
+	config := filepath.Join(dir, "pocketflow.json")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing config", []string{"-config", filepath.Join(dir, "missing.json"), "triage"}, "missing.json"},
+		{"unknown flow", []string{"-config", config, "escalate"}, "escalate"},
+		{"state twice", []string{"-config", config, "-state", filepath.Join(dir, "state.json"), "-state-json", "{}", "triage"}, "cannot be used together"},
+		{"state not an object", []string{"-config", config, "-state", filepath.Join(dir, "state.json"), "triage"}, "initial state must be a JSON object"},
+		{"stdin state with the terminal console", []string{"-config", config, "-state", "-", "-break", "*", "triage"}, "use -debug-addr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runRun(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runRun() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
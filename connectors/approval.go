@@ -0,0 +1,70 @@
+package connectors
+
+import (
+	"context"
+	"slices"
+
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+)
+
+// Values of the approval buttons
+const (
+	valueApprove = "approve"
+	valueAlways  = "always"
+	valueReject  = "reject"
+)
+
+// approvalButtons are the answers offered for an approval request
+var approvalButtons = []Button{
+	{Label: "Approve", Value: valueApprove},
+	{Label: "Always allow", Value: valueAlways},
+	{Label: "Reject", Value: valueReject},
+}
+
+// RequestApproval implements nodes.ApprovalSource: it asks in the
+// conversation with Approve, Always allow and Reject buttons, which only the
+// bridge's Approvers may press. A message sent instead of pressing a button
+// is returned as feedback.
+func (s *Session) RequestApproval(ctx context.Context, request nodes.ApprovalRequest) (nodes.ApprovalResponse, error) {
+	text := request.Title
+	if request.Details != "" {
+		text += "\n\n" + request.Details
+	}
+	answer, err := s.ask(ctx, text, approvalButtons, s.approvers())
+	if err != nil {
+		return nodes.ApprovalResponse{}, err
+	}
+
+	response := nodes.ApprovalResponse{RequestID: request.ID, Approver: answer.UserName}
+	if response.Approver == "" {
+		response.Approver = answer.User
+	}
+	switch {
+	case !answer.IsPress():
+		response.Decision = nodes.DecisionFeedback
+		response.Feedback = answer.Text
+	case answer.Value == valueApprove:
+		response.Decision = nodes.DecisionApprove
+	case answer.Value == valueAlways:
+		response.Decision = nodes.DecisionApprove
+		response.Remember = true
+	default:
+		response.Decision = nodes.DecisionReject
+	}
+	return response, nil
+}
+
+// approvers returns whether a press is by a user who may answer approval
+// requests: one of the Approvers or, without any, the user whose message the
+// session is handling
+func (s *Session) approvers() func(Event) bool {
+	if len(s.config.Approvers) > 0 {
+		return func(press Event) bool {
+			return slices.Contains(s.config.Approvers, press.User)
+		}
+	}
+	user := s.user
+	return func(press Event) bool {
+		return press.User != "" && press.User == user
+	}
+}
@@ -0,0 +1,363 @@
+package connectors
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Config configures a Bridge
+type Config struct {
+	StreamInterval time.Duration // Least time between the edits of a streamed reply; 0 uses 1s
+	IdleTimeout    time.Duration // Ends sessions without messages for this long; 0 keeps them until the bridge stops
+	Backlog        int           // Messages a busy session queues before refusing more; 0 uses 16
+	BusyMessage    string        // Reply to messages refused because the backlog is full
+	Logger         *log.Logger   // Logs failed sessions and replies; nil uses the standard logger
+
+	// Approvers are the IDs of the users whose presses answer approval
+	// requests; presses of anyone else are ignored. Empty allows only the
+	// user whose message the session is handling.
+	Approvers []string
+}
+
+// DefaultConfig edits streamed replies once a second and keeps sessions
+// until the bridge stops
+func DefaultConfig() *Config {
+	return &Config{
+		StreamInterval: time.Second,
+		Backlog:        16,
+		BusyMessage:    "Still working on your earlier messages, please wait.",
+	}
+}
+
+// Handler runs the agent of one session, e.g. a flow reading the user's
+// messages with Session.Next, until the session ends
+type Handler func(ctx context.Context, session *Session) error
+
+// Bridge routes the events of a platform to sessions, one per conversation.
+// A session starts with the first message of its conversation and ends when
+// its handler returns; the next message starts a new one.
+type Bridge struct {
+	platform Platform
+	handler  Handler
+	config   *Config
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	wg       sync.WaitGroup
+}
+
+// NewBridge creates a bridge running handler for the conversations of
+// platform; a nil config uses DefaultConfig. Unset fields of config take
+// their defaults.
+func NewBridge(platform Platform, handler Handler, config *Config) *Bridge {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	withDefaults := *config
+	if withDefaults.StreamInterval <= 0 {
+		withDefaults.StreamInterval = time.Second
+	}
+	if withDefaults.Backlog <= 0 {
+		withDefaults.Backlog = 16
+	}
+	if withDefaults.Logger == nil {
+		withDefaults.Logger = log.Default()
+	}
+	return &Bridge{platform: platform, handler: handler, config: &withDefaults, sessions: make(map[string]*Session)}
+}
+
+// Run listens to the platform until ctx is done or the platform fails, then
+// waits for the sessions' handlers to return
+func (b *Bridge) Run(ctx context.Context) error {
+	running, stop := context.WithCancel(ctx)
+	defer stop()
+
+	events := make(chan Event)
+	listened := make(chan error, 1)
+	go func() {
+		listened <- b.platform.Listen(running, events)
+	}()
+
+	var err error
+	for done := false; !done; {
+		select {
+		case event := <-events:
+			b.dispatch(running, event)
+		case err = <-listened:
+			done = true
+		}
+	}
+	stop()
+	b.wg.Wait()
+	if ctx.Err() != nil {
+		// Stopped by the caller
+		return nil
+	}
+	return err
+}
+
+// Sessions returns the number of running sessions
+func (b *Bridge) Sessions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.sessions)
+}
+
+// dispatch hands event to the session of its conversation, starting one for
+// a message; presses of sessions that ended are dropped
+func (b *Bridge) dispatch(ctx context.Context, event Event) {
+	b.mu.Lock()
+	session, ok := b.sessions[event.key()]
+	if !ok && event.IsPress() {
+		b.mu.Unlock()
+		return
+	}
+	if !ok {
+		session = newSession(event, b.platform, b.config)
+		b.sessions[event.key()] = session
+		b.wg.Add(1)
+		go b.serve(ctx, session)
+	}
+	b.mu.Unlock()
+
+	if event.IsPress() {
+		session.press(event)
+		return
+	}
+	select {
+	case session.messages <- event:
+	default:
+		if b.config.BusyMessage != "" {
+			if _, err := b.platform.Send(ctx, event.Conversation, b.config.BusyMessage); err != nil {
+				b.config.Logger.Printf("connectors: failed to reply in %s: %v", event.Conversation, err)
+			}
+		}
+	}
+}
+
+// serve runs the handler of session and forgets the session when it returns
+func (b *Bridge) serve(ctx context.Context, session *Session) {
+	defer b.wg.Done()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			b.config.Logger.Printf("connectors: session %s panicked: %v", session.Conversation, recovered)
+		}
+		b.mu.Lock()
+		delete(b.sessions, session.key())
+		b.mu.Unlock()
+	}()
+
+	if err := b.handler(ctx, session); err != nil && ctx.Err() == nil {
+		b.config.Logger.Printf("connectors: session %s ended: %v", session.Conversation, err)
+	}
+}
+
+// Session is the conversation of one channel or thread with the agent. Its
+// methods are meant to be called by one handler goroutine at a time.
+type Session struct {
+	Conversation
+	platform Platform
+	config   *Config
+	messages chan Event
+	user     string // Sender of the message being handled
+
+	mu      sync.Mutex
+	prompts map[string]*prompt
+}
+
+// prompt is a question of Ask waiting for a press
+type prompt struct {
+	pressed chan Event
+	allowed func(Event) bool // Presses it accepts; nil accepts every press
+}
+
+// newSession creates the session started by first, a message
+func newSession(first Event, platform Platform, config *Config) *Session {
+	return &Session{
+		Conversation: first.Conversation,
+		platform:     platform,
+		config:       config,
+		messages:     make(chan Event, config.Backlog),
+		user:         first.User,
+		prompts:      make(map[string]*prompt),
+	}
+}
+
+// Next waits for the next message of the conversation. It returns ErrIdle
+// once no message arrived within the bridge's IdleTimeout.
+func (s *Session) Next(ctx context.Context) (Event, error) {
+	var idle <-chan time.Time
+	if s.config.IdleTimeout > 0 {
+		timer := time.NewTimer(s.config.IdleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	select {
+	case event := <-s.messages:
+		s.user = event.User
+		return event, nil
+	case <-idle:
+		return Event{}, ErrIdle
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+}
+
+// Send posts text to the conversation
+func (s *Session) Send(ctx context.Context, text string) error {
+	_, err := s.platform.Send(ctx, s.Conversation, text)
+	return err
+}
+
+// Ask posts text with buttons and waits for one to be pressed. A message
+// sent instead answers too and is returned with its text.
+func (s *Session) Ask(ctx context.Context, text string, buttons []Button) (Event, error) {
+	return s.ask(ctx, text, buttons, nil)
+}
+
+// ask is Ask ignoring the presses allowed rejects; nil allows every press
+func (s *Session) ask(ctx context.Context, text string, buttons []Button, allowed func(Event) bool) (Event, error) {
+	promptID := newPromptID()
+	pressed := make(chan Event, 1)
+	s.mu.Lock()
+	s.prompts[promptID] = &prompt{pressed: pressed, allowed: allowed}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.prompts, promptID)
+		s.mu.Unlock()
+	}()
+
+	messageID, err := s.platform.Ask(ctx, s.Conversation, promptID, text, buttons)
+	if err != nil {
+		return Event{}, err
+	}
+
+	var answer Event
+	select {
+	case answer = <-pressed:
+	case answer = <-s.messages:
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	}
+
+	// The buttons are removed so the prompt cannot be answered twice
+	closed := text
+	if answer.IsPress() {
+		closed += "\n\n" + s.answered(answer, buttons)
+	}
+	if err := s.platform.Edit(ctx, s.Conversation, messageID, closed); err != nil {
+		s.config.Logger.Printf("connectors: failed to close prompt in %s: %v", s.Conversation, err)
+	}
+	return answer, nil
+}
+
+// answered describes the button pressed in answer, e.g. "Approve (by ana)"
+func (s *Session) answered(answer Event, buttons []Button) string {
+	label := answer.Value
+	for _, button := range buttons {
+		if button.Value == answer.Value {
+			label = button.Label
+		}
+	}
+	by := answer.UserName
+	if by == "" {
+		by = answer.User
+	}
+	if by == "" {
+		return label
+	}
+	return fmt.Sprintf("%s (by %s)", label, by)
+}
+
+// press delivers a button press to the prompt waiting for it, unless the
+// prompt does not accept presses of its user
+func (s *Session) press(event Event) {
+	s.mu.Lock()
+	waiting, ok := s.prompts[event.PromptID]
+	s.mu.Unlock()
+	if !ok || (waiting.allowed != nil && !waiting.allowed(event)) {
+		return
+	}
+	select {
+	case waiting.pressed <- event:
+	default:
+		// The prompt was answered already
+	}
+}
+
+// Stream starts a reply that is sent as it is written: the first write
+// posts a message and later writes edit it, at most once per the bridge's
+// StreamInterval. Close sends what is left.
+func (s *Session) Stream(ctx context.Context) *Stream {
+	return &Stream{session: s, ctx: ctx}
+}
+
+// Stream is a reply sent as it is written; it implements io.Writer and may
+// be written from several goroutines
+type Stream struct {
+	session *Session
+	ctx     context.Context
+
+	mu        sync.Mutex
+	text      []byte
+	sent      int
+	messageID string
+	edited    time.Time
+}
+
+// Write appends p to the reply and sends it when the stream interval passed
+func (w *Stream) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.text = append(w.text, p...)
+	if w.messageID != "" && time.Since(w.edited) < w.session.config.StreamInterval {
+		return len(p), nil
+	}
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close sends the whole reply if the last write was not sent yet
+func (w *Stream) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sent == len(w.text) {
+		return nil
+	}
+	return w.flush()
+}
+
+// flush sends the reply as written so far
+func (w *Stream) flush() error {
+	if len(w.text) == 0 {
+		return nil
+	}
+	var err error
+	if w.messageID == "" {
+		w.messageID, err = w.session.platform.Send(w.ctx, w.session.Conversation, string(w.text))
+	} else {
+		err = w.session.platform.Edit(w.ctx, w.session.Conversation, w.messageID, string(w.text))
+	}
+	if err != nil {
+		return err
+	}
+	w.sent = len(w.text)
+	w.edited = time.Now()
+	return nil
+}
+
+// newPromptID returns a short random ID, small enough for the button data
+// limits of the platforms
+func newPromptID() string {
+	id := make([]byte, 6)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
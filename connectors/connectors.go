@@ -0,0 +1,142 @@
+// Package connectors bridges chat platforms to agent flows. A Bridge receives
+// the events of a Platform, such as Telegram, Slack or Discord, and gives each
+// channel or thread a Session: the agent reads the user's messages from it,
+// sends or streams its replies, and asks for tool approval with buttons.
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrIdle is returned by Session.Next when no message arrived within the
+	// bridge's IdleTimeout, so the session can end
+	ErrIdle = errors.New("session is idle")
+	// ErrNotListening is returned by webhook platforms receiving events
+	// before Listen was called
+	ErrNotListening = errors.New("platform is not listening")
+)
+
+// Conversation identifies where a session's messages come from and its replies go
+type Conversation struct {
+	Platform string `json:"platform"`
+	Channel  string `json:"channel"`          // Chat or channel ID on the platform
+	Thread   string `json:"thread,omitempty"` // Thread or topic ID; empty for the channel itself
+}
+
+// key returns the conversation as a map key
+func (c Conversation) key() string {
+	return c.Platform + "\x00" + c.Channel + "\x00" + c.Thread
+}
+
+// unsafeID matches the characters not allowed in session IDs
+var unsafeID = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SessionID returns an ID for the conversation's saved session, e.g.
+// "telegram-12345" or "slack-C024BE91L-1712345678.000100", made of letters,
+// digits, '.', '_' and '-' as memory.SessionStore requires
+func (c Conversation) SessionID() string {
+	parts := []string{c.Platform, c.Channel}
+	if c.Thread != "" {
+		parts = append(parts, c.Thread)
+	}
+	id := unsafeID.ReplaceAllString(strings.Join(parts, "-"), "_")
+	return strings.TrimLeft(id, "._-")
+}
+
+// String implements fmt.Stringer
+func (c Conversation) String() string {
+	if c.Thread == "" {
+		return fmt.Sprintf("%s:%s", c.Platform, c.Channel)
+	}
+	return fmt.Sprintf("%s:%s/%s", c.Platform, c.Channel, c.Thread)
+}
+
+// Event is a message or a button press received from a platform
+type Event struct {
+	Conversation
+	User     string `json:"user"`                // ID of the user on the platform
+	UserName string `json:"user_name,omitempty"` // Display or user name, if known
+	Text     string `json:"text,omitempty"`      // Text of a message; empty for button presses
+	PromptID string `json:"prompt_id,omitempty"` // Prompt whose button was pressed
+	Value    string `json:"value,omitempty"`     // Value of the pressed button
+}
+
+// IsPress reports whether the event is a button press rather than a message
+func (e Event) IsPress() bool {
+	return e.PromptID != ""
+}
+
+// Button is an answer offered by a prompt
+type Button struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Platform sends and receives the messages of a chat platform
+type Platform interface {
+	// Name returns the platform's name, used in conversations, e.g. "telegram"
+	Name() string
+
+	// Listen delivers the platform's messages and button presses to events
+	// until ctx is done or the platform fails
+	Listen(ctx context.Context, events chan<- Event) error
+
+	// Send posts text to the conversation and returns the ID of the message
+	Send(ctx context.Context, conversation Conversation, text string) (string, error)
+
+	// Edit replaces the text of a message sent before, removing its buttons
+	Edit(ctx context.Context, conversation Conversation, messageID, text string) error
+
+	// Ask posts text with buttons whose presses are delivered as events with
+	// promptID and the button's value, and returns the ID of the message
+	Ask(ctx context.Context, conversation Conversation, promptID, text string, buttons []Button) (string, error)
+}
+
+// buttonData encodes the prompt ID and value carried by a button
+func buttonData(promptID, value string) string {
+	return promptID + ":" + value
+}
+
+// parseButtonData decodes the prompt ID and value of a pressed button
+func parseButtonData(data string) (promptID, value string, ok bool) {
+	return strings.Cut(data, ":")
+}
+
+// splitText splits text into parts of at most limit runes, preferring to
+// split at line breaks, for platforms limiting the length of a message
+func splitText(text string, limit int) []string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return []string{text}
+	}
+	var parts []string
+	for len(runes) > limit {
+		cut := limit
+		for i := limit - 1; i > limit/2; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+// truncateText cuts text to at most limit runes, marking the cut with an ellipsis
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
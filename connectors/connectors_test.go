@@ -0,0 +1,357 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+)
+
+// sentMessage is a message a fakePlatform sent or edited
+type sentMessage struct {
+	conversation Conversation
+	id           string
+	text         string
+	buttons      []Button
+	promptID     string
+}
+
+// fakePlatform delivers the events pushed to it and records what it sends
+type fakePlatform struct {
+	events chan Event
+
+	mu    sync.Mutex
+	sent  []sentMessage
+	edits []sentMessage
+	asked chan sentMessage
+}
+
+func newFakePlatform() *fakePlatform {
+	return &fakePlatform{events: make(chan Event), asked: make(chan sentMessage, 4)}
+}
+
+func (p *fakePlatform) Name() string { return "fake" }
+
+func (p *fakePlatform) Listen(ctx context.Context, events chan<- Event) error {
+	for {
+		select {
+		case event := <-p.events:
+			events <- event
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *fakePlatform) Send(_ context.Context, conversation Conversation, text string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := fmt.Sprint(len(p.sent) + 1)
+	p.sent = append(p.sent, sentMessage{conversation: conversation, id: id, text: text})
+	return id, nil
+}
+
+func (p *fakePlatform) Edit(_ context.Context, conversation Conversation, messageID, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.edits = append(p.edits, sentMessage{conversation: conversation, id: messageID, text: text})
+	return nil
+}
+
+func (p *fakePlatform) Ask(ctx context.Context, conversation Conversation, promptID, text string, buttons []Button) (string, error) {
+	id, _ := p.Send(ctx, conversation, text)
+	p.asked <- sentMessage{conversation: conversation, id: id, text: text, buttons: buttons, promptID: promptID}
+	return id, nil
+}
+
+func (p *fakePlatform) texts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	texts := make([]string, len(p.sent))
+	for i, message := range p.sent {
+		texts[i] = message.conversation.String() + " " + message.text
+	}
+	return texts
+}
+
+// startBridge runs a bridge over a fake platform until the test ends
+func startBridge(t *testing.T, handler Handler, config *Config) (*fakePlatform, *Bridge) {
+	t.Helper()
+	platform := newFakePlatform()
+	if config == nil {
+		config = DefaultConfig()
+	}
+	config.Logger = log.New(io.Discard, "", 0)
+	bridge := NewBridge(platform, handler, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- bridge.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-stopped; err != nil {
+			t.Errorf("Run returned %v", err)
+		}
+	})
+	return platform, bridge
+}
+
+// waitFor polls until condition holds
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func message(channel, thread, text string) Event {
+	return Event{Conversation: Conversation{Platform: "fake", Channel: channel, Thread: thread}, User: "u1", UserName: "ana", Text: text}
+}
+
+func TestBridge_SessionPerConversation(t *testing.T) {
+	var mu sync.Mutex
+	started := 0
+	echo := func(ctx context.Context, session *Session) error {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		for {
+			event, err := session.Next(ctx)
+			if err != nil {
+				return err
+			}
+			if err := session.Send(ctx, "echo: "+event.Text); err != nil {
+				return err
+			}
+		}
+	}
+	platform, bridge := startBridge(t, echo, nil)
+
+	platform.events <- message("c1", "", "hello")
+	platform.events <- message("c1", "t1", "in a thread")
+	platform.events <- message("c2", "", "elsewhere")
+	platform.events <- message("c1", "", "again")
+	waitFor(t, "four replies", func() bool { return len(platform.texts()) == 4 })
+
+	if bridge.Sessions() != 3 {
+		t.Errorf("Sessions() = %d, want 3", bridge.Sessions())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if started != 3 {
+		t.Errorf("started %d handlers, want 3", started)
+	}
+	texts := strings.Join(platform.texts(), "\n")
+	for _, want := range []string{"fake:c1 echo: hello", "fake:c1/t1 echo: in a thread", "fake:c2 echo: elsewhere", "fake:c1 echo: again"} {
+		if !strings.Contains(texts, want) {
+			t.Errorf("replies %q lack %q", texts, want)
+		}
+	}
+}
+
+func TestBridge_IdleTimeout(t *testing.T) {
+	ended := make(chan error, 1)
+	handler := func(ctx context.Context, session *Session) error {
+		if _, err := session.Next(ctx); err != nil {
+			return err
+		}
+		_, err := session.Next(ctx)
+		ended <- err
+		return err
+	}
+	config := DefaultConfig()
+	config.IdleTimeout = 10 * time.Millisecond
+	platform, bridge := startBridge(t, handler, config)
+
+	platform.events <- message("c1", "", "hello")
+	if err := <-ended; !errors.Is(err, ErrIdle) {
+		t.Fatalf("Next returned %v, want ErrIdle", err)
+	}
+	waitFor(t, "the session to end", func() bool { return bridge.Sessions() == 0 })
+}
+
+func TestSession_RequestApproval(t *testing.T) {
+	tests := []struct {
+		name      string
+		approvers []string
+		answer    func(prompt sentMessage) Event
+		want      nodes.ApprovalResponse
+		wantTag   string
+	}{
+		{
+			name: "approve",
+			answer: func(prompt sentMessage) Event {
+				return Event{Conversation: prompt.conversation, User: "u1", UserName: "ana", PromptID: prompt.promptID, Value: valueApprove}
+			},
+			want:    nodes.ApprovalResponse{RequestID: "r1", Decision: nodes.DecisionApprove, Approver: "ana"},
+			wantTag: "Approve (by ana)",
+		},
+		{
+			name: "always",
+			answer: func(prompt sentMessage) Event {
+				return Event{Conversation: prompt.conversation, User: "u1", PromptID: prompt.promptID, Value: valueAlways}
+			},
+			want:    nodes.ApprovalResponse{RequestID: "r1", Decision: nodes.DecisionApprove, Remember: true, Approver: "u1"},
+			wantTag: "Always allow (by u1)",
+		},
+		{
+			name: "reject",
+			answer: func(prompt sentMessage) Event {
+				return Event{Conversation: prompt.conversation, User: "u1", UserName: "ana", PromptID: prompt.promptID, Value: valueReject}
+			},
+			want:    nodes.ApprovalResponse{RequestID: "r1", Decision: nodes.DecisionReject, Approver: "ana"},
+			wantTag: "Reject (by ana)",
+		},
+		{
+			name: "feedback",
+			answer: func(prompt sentMessage) Event {
+				return message(prompt.conversation.Channel, "", "use the staging database")
+			},
+			want: nodes.ApprovalResponse{RequestID: "r1", Decision: nodes.DecisionFeedback, Feedback: "use the staging database", Approver: "ana"},
+		},
+		{
+			name:      "configured approver",
+			approvers: []string{"u3"},
+			answer: func(prompt sentMessage) Event {
+				return Event{Conversation: prompt.conversation, User: "u3", UserName: "cy", PromptID: prompt.promptID, Value: valueApprove}
+			},
+			want:    nodes.ApprovalResponse{RequestID: "r1", Decision: nodes.DecisionApprove, Approver: "cy"},
+			wantTag: "Approve (by cy)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := make(chan nodes.ApprovalResponse, 1)
+			handler := func(ctx context.Context, session *Session) error {
+				if _, err := session.Next(ctx); err != nil {
+					return err
+				}
+				response, err := session.RequestApproval(ctx, nodes.ApprovalRequest{ID: "r1", Title: "Run shell?", Details: "rm -rf build"})
+				if err != nil {
+					return err
+				}
+				responses <- response
+				return nil
+			}
+			config := DefaultConfig()
+			config.Approvers = tt.approvers
+			platform, _ := startBridge(t, handler, config)
+			platform.events <- message("c1", "", "clean up")
+
+			prompt := <-platform.asked
+			if prompt.text != "Run shell?\n\nrm -rf build" || len(prompt.buttons) != 3 {
+				t.Fatalf("prompt = %+v", prompt)
+			}
+			// Presses of unknown prompts are ignored
+			platform.events <- Event{Conversation: prompt.conversation, User: "u1", PromptID: "stale", Value: valueApprove}
+			// So are presses of users who may not approve
+			platform.events <- Event{Conversation: prompt.conversation, User: "u2", UserName: "bo", PromptID: prompt.promptID, Value: valueAlways}
+			if len(tt.approvers) > 0 {
+				platform.events <- Event{Conversation: prompt.conversation, User: "u1", UserName: "ana", PromptID: prompt.promptID, Value: valueAlways}
+			}
+			platform.events <- tt.answer(prompt)
+
+			if got := <-responses; got != tt.want {
+				t.Errorf("response = %+v, want %+v", got, tt.want)
+			}
+			platform.mu.Lock()
+			defer platform.mu.Unlock()
+			if len(platform.edits) != 1 || platform.edits[0].id != prompt.id || !strings.HasSuffix(platform.edits[0].text, tt.wantTag) {
+				t.Errorf("edits = %+v, want the prompt closed with %q", platform.edits, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestNewBridge_KeepsConfig(t *testing.T) {
+	config := &Config{}
+	bridge := NewBridge(newFakePlatform(), nil, config)
+	if config.StreamInterval != 0 || config.Backlog != 0 || config.Logger != nil {
+		t.Errorf("NewBridge changed the config to %+v", config)
+	}
+	if bridge.config.StreamInterval != time.Second || bridge.config.Backlog != 16 || bridge.config.Logger == nil {
+		t.Errorf("bridge config = %+v, want the defaults", bridge.config)
+	}
+}
+
+func TestSession_Stream(t *testing.T) {
+	done := make(chan struct{})
+	handler := func(ctx context.Context, session *Session) error {
+		defer close(done)
+		if _, err := session.Next(ctx); err != nil {
+			return err
+		}
+		stream := session.Stream(ctx)
+		for _, word := range []string{"one ", "two ", "three"} {
+			io.WriteString(stream, word)
+		}
+		return stream.Close()
+	}
+	config := DefaultConfig()
+	config.StreamInterval = time.Hour
+	platform, _ := startBridge(t, handler, config)
+	platform.events <- message("c1", "", "count")
+	<-done
+
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+	// The first write is sent and the rest waits for the interval or Close
+	if len(platform.sent) != 1 || platform.sent[0].text != "one " {
+		t.Errorf("sent = %+v", platform.sent)
+	}
+	if len(platform.edits) != 1 || platform.edits[0].id != platform.sent[0].id || platform.edits[0].text != "one two three" {
+		t.Errorf("edits = %+v", platform.edits)
+	}
+}
+
+func TestConversation_SessionID(t *testing.T) {
+	tests := []struct {
+		conversation Conversation
+		want         string
+	}{
+		{Conversation{Platform: "telegram", Channel: "-100123"}, "telegram--100123"},
+		{Conversation{Platform: "slack", Channel: "C024BE91L", Thread: "1712345678.000100"}, "slack-C024BE91L-1712345678.000100"},
+		{Conversation{Platform: "matrix", Channel: "!room:example.org"}, "matrix-_room_example.org"},
+	}
+	for _, tt := range tests {
+		if got := tt.conversation.SessionID(); got != tt.want {
+			t.Errorf("SessionID() of %v = %q, want %q", tt.conversation, got, tt.want)
+		}
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"short", "hello", 10, []string{"hello"}},
+		{"at line break", "first line\nsecond", 14, []string{"first line\n", "second"}},
+		{"no line break", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"runes", "ééééé", 2, []string{"éé", "éé", "é"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.limit)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := truncateText("abcdef", 4); got != "abc…" {
+		t.Errorf("truncateText() = %q", got)
+	}
+}
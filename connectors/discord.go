@@ -0,0 +1,278 @@
+package connectors
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// discordMaxLength is the longest message content Discord accepts
+const discordMaxLength = 2000
+
+// Types of the interactions Discord sends
+const (
+	discordPing      = 1
+	discordCommand   = 2
+	discordComponent = 3
+)
+
+// Types of the responses to interactions
+const (
+	discordPong           = 1
+	discordMessage        = 4
+	discordDeferredUpdate = 6
+)
+
+// Types of the message components and command options used
+const (
+	discordActionRow      = 1
+	discordButton         = 2
+	discordSecondaryStyle = 2
+	discordStringOption   = 3
+)
+
+const (
+	// discordDefaultCommand is the slash command used when none is configured
+	discordDefaultCommand = "ask"
+	// discordMessageOption is the command's option carrying the message
+	discordMessageOption = "message"
+	// discordQuoteLength is the length at which messages quoted in command
+	// responses are cut
+	discordQuoteLength = 200
+)
+
+// DiscordConfig configures a Discord application
+type DiscordConfig struct {
+	ApplicationID string       // Application ID, used by RegisterCommand
+	BotToken      string       // Bot token
+	PublicKey     string       // Hex public key verifying the interactions Discord sends
+	Command       string       // Slash command carrying the user's messages; empty uses "ask"
+	BaseURL       string       // API server; empty uses https://discord.com/api/v10
+	HTTPClient    *http.Client // Client for the API; nil uses one with a 30s timeout
+}
+
+// Discord receives the user's messages as a slash command and button
+// presses as message components, both sent to its ServeHTTP as the
+// application's interactions endpoint. Each channel or thread has a session.
+type Discord struct {
+	webhook
+	config    DiscordConfig
+	publicKey ed25519.PublicKey
+	client    *http.Client
+}
+
+// NewDiscord creates a Discord platform
+func NewDiscord(config DiscordConfig) (*Discord, error) {
+	if config.BotToken == "" {
+		return nil, fmt.Errorf("discord bot token is required")
+	}
+	publicKey, err := hex.DecodeString(config.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("discord public key must be %d hex-encoded bytes", ed25519.PublicKeySize)
+	}
+	if config.Command == "" {
+		config.Command = discordDefaultCommand
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://discord.com/api/v10"
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Discord{config: config, publicKey: publicKey, client: client}, nil
+}
+
+// Name implements Platform
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+// RegisterCommand creates or updates the application's global slash command,
+// e.g. "/ask message:...", through which users talk to the agent
+func (d *Discord) RegisterCommand(ctx context.Context) error {
+	if d.config.ApplicationID == "" {
+		return fmt.Errorf("discord application ID is required to register commands")
+	}
+	command := map[string]any{
+		"name":        d.config.Command,
+		"description": "Talk to the agent",
+		"options": []map[string]any{{
+			"type":        discordStringOption,
+			"name":        discordMessageOption,
+			"description": "Your message",
+			"required":    true,
+		}},
+	}
+	return d.call(ctx, http.MethodPost, "/applications/"+d.config.ApplicationID+"/commands", command, nil)
+}
+
+// ServeHTTP receives the interactions Discord sends
+func (d *Discord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	signature, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	message := append([]byte(r.Header.Get("X-Signature-Timestamp")), body...)
+	if err != nil || !ed25519.Verify(d.publicKey, message, signature) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+	event, response, ok := d.eventOf(interaction)
+	if ok {
+		if err := d.deliver(event); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// eventOf returns the event of a command or button press interaction and the
+// response acknowledging it
+func (d *Discord) eventOf(interaction discordInteraction) (Event, map[string]any, bool) {
+	user := interaction.User
+	if interaction.Member != nil {
+		// Interactions in servers carry the user in the member
+		user = &interaction.Member.User
+	}
+	event := Event{Conversation: Conversation{Platform: d.Name(), Channel: interaction.ChannelID}}
+	if user != nil {
+		event.User, event.UserName = user.ID, user.GlobalName
+		if event.UserName == "" {
+			event.UserName = user.Username
+		}
+	}
+
+	switch interaction.Type {
+	case discordPing:
+		return Event{}, map[string]any{"type": discordPong}, false
+	case discordCommand:
+		for _, option := range interaction.Data.Options {
+			if option.Name == discordMessageOption {
+				json.Unmarshal(option.Value, &event.Text)
+			}
+		}
+		if interaction.Data.Name != d.config.Command || event.Text == "" {
+			return Event{}, discordReply("Unknown command."), false
+		}
+		// Commands must be answered, so the answer shows what was asked
+		return event, discordReply(fmt.Sprintf("**%s:** %s", event.UserName, truncateText(event.Text, discordQuoteLength))), true
+	case discordComponent:
+		promptID, value, ok := parseButtonData(interaction.Data.CustomID)
+		if !ok {
+			return Event{}, map[string]any{"type": discordDeferredUpdate}, false
+		}
+		event.PromptID, event.Value = promptID, value
+		return event, map[string]any{"type": discordDeferredUpdate}, true
+	default:
+		return Event{}, discordReply("Unsupported interaction."), false
+	}
+}
+
+// discordReply returns an interaction response replying with content
+func discordReply(content string) map[string]any {
+	return map[string]any{"type": discordMessage, "data": map[string]any{"content": content}}
+}
+
+// Send implements Platform; texts over Discord's limit are sent as several
+// messages, and the ID of the last one is returned
+func (d *Discord) Send(ctx context.Context, conversation Conversation, text string) (string, error) {
+	var messageID string
+	for _, part := range splitText(text, discordMaxLength) {
+		id, err := d.post(ctx, conversation, map[string]any{"content": part})
+		if err != nil {
+			return "", err
+		}
+		messageID = id
+	}
+	return messageID, nil
+}
+
+// Edit implements Platform
+func (d *Discord) Edit(ctx context.Context, conversation Conversation, messageID, text string) error {
+	message := map[string]any{"content": truncateText(text, discordMaxLength), "components": []any{}}
+	return d.call(ctx, http.MethodPatch, "/channels/"+conversation.Channel+"/messages/"+messageID, message, nil)
+}
+
+// Ask implements Platform with a row of buttons
+func (d *Discord) Ask(ctx context.Context, conversation Conversation, promptID, text string, buttons []Button) (string, error) {
+	row := make([]map[string]any, len(buttons))
+	for i, button := range buttons {
+		row[i] = map[string]any{
+			"type":      discordButton,
+			"style":     discordSecondaryStyle,
+			"label":     button.Label,
+			"custom_id": buttonData(promptID, button.Value),
+		}
+	}
+	return d.post(ctx, conversation, map[string]any{
+		"content":    truncateText(text, discordMaxLength),
+		"components": []map[string]any{{"type": discordActionRow, "components": row}},
+	})
+}
+
+// post creates a message in the conversation's channel and returns its ID
+func (d *Discord) post(ctx context.Context, conversation Conversation, message map[string]any) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := d.call(ctx, http.MethodPost, "/channels/"+conversation.Channel+"/messages", message, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// call calls the API as the bot
+func (d *Discord) call(ctx context.Context, method, path string, body, result any) error {
+	header := http.Header{"Authorization": {"Bot " + d.config.BotToken}}
+	if err := callJSON(ctx, d.client, method, d.config.BaseURL+path, header, body, result); err != nil {
+		return fmt.Errorf("discord %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// discordInteraction is the part of an interaction the platform uses
+type discordInteraction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Member    *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+		CustomID string `json:"custom_id"`
+	} `json:"data"`
+}
+
+// discordUser is a Discord user
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+}
@@ -0,0 +1,157 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscord_ServeHTTP(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discord, err := NewDiscord(DiscordConfig{BotToken: "test", PublicKey: hex.EncodeToString(publicKey)})
+	if err != nil {
+		t.Fatalf("NewDiscord failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 1)
+	go discord.Listen(ctx, events)
+	waitFor(t, "Listen", func() bool {
+		discord.webhook.mu.Lock()
+		defer discord.webhook.mu.Unlock()
+		return discord.webhook.events != nil
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		key          ed25519.PrivateKey
+		wantStatus   int
+		wantResponse string
+		wantEvent    *Event
+	}{
+		{
+			name:         "ping",
+			body:         `{"type": 1}`,
+			key:          privateKey,
+			wantStatus:   http.StatusOK,
+			wantResponse: `{"type":1}`,
+		},
+		{
+			name: "command",
+			body: `{"type": 2, "channel_id": "C1", "member": {"user": {"id": "U1", "username": "ana"}},
+				"data": {"name": "ask", "options": [{"name": "message", "type": 3, "value": "hello"}]}}`,
+			key:          privateKey,
+			wantStatus:   http.StatusOK,
+			wantResponse: `{"data":{"content":"**ana:** hello"},"type":4}`,
+			wantEvent:    &Event{Conversation: Conversation{Platform: "discord", Channel: "C1"}, User: "U1", UserName: "ana", Text: "hello"},
+		},
+		{
+			name:         "button press",
+			body:         `{"type": 3, "channel_id": "C1", "user": {"id": "U2", "global_name": "Bo"}, "data": {"custom_id": "p1:approve"}}`,
+			key:          privateKey,
+			wantStatus:   http.StatusOK,
+			wantResponse: `{"type":6}`,
+			wantEvent:    &Event{Conversation: Conversation{Platform: "discord", Channel: "C1"}, User: "U2", UserName: "Bo", PromptID: "p1", Value: "approve"},
+		},
+		{
+			name:         "other command",
+			body:         `{"type": 2, "channel_id": "C1", "data": {"name": "roll"}}`,
+			key:          privateKey,
+			wantStatus:   http.StatusOK,
+			wantResponse: `{"data":{"content":"Unknown command."},"type":4}`,
+		},
+		{
+			name:       "wrong key",
+			body:       `{"type": 1}`,
+			key:        ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp := "1712345678"
+			request := httptest.NewRequest(http.MethodPost, "/discord", bytes.NewReader([]byte(tt.body)))
+			request.Header.Set("X-Signature-Timestamp", timestamp)
+			request.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(tt.key, []byte(timestamp+tt.body))))
+			recorder := httptest.NewRecorder()
+			discord.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d", recorder.Code)
+			}
+			if tt.wantResponse != "" && !bytes.Equal(bytes.TrimSpace(recorder.Body.Bytes()), []byte(tt.wantResponse)) {
+				t.Errorf("response = %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+			select {
+			case event := <-events:
+				if tt.wantEvent == nil || event != *tt.wantEvent {
+					t.Errorf("event = %+v, want %+v", event, tt.wantEvent)
+				}
+			default:
+				if tt.wantEvent != nil {
+					t.Errorf("no event, want %+v", *tt.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscord_Send(t *testing.T) {
+	type call struct {
+		method, path string
+		body         map[string]any
+	}
+	var calls []call
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		calls = append(calls, call{r.Method, r.URL.Path, body})
+		w.Write([]byte(`{"id": "M9"}`))
+	}))
+	defer server.Close()
+
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+	discord, err := NewDiscord(DiscordConfig{ApplicationID: "A1", BotToken: "test", PublicKey: hex.EncodeToString(publicKey), BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewDiscord failed: %v", err)
+	}
+	ctx := context.Background()
+	conversation := Conversation{Platform: "discord", Channel: "C1"}
+	id, err := discord.Ask(ctx, conversation, "p1", "Run shell?", approvalButtons)
+	if err != nil || id != "M9" {
+		t.Fatalf("Ask = %q, %v", id, err)
+	}
+	if err := discord.Edit(ctx, conversation, id, "Run shell?"); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	if err := discord.RegisterCommand(ctx); err != nil {
+		t.Fatalf("RegisterCommand failed: %v", err)
+	}
+
+	if len(calls) != 3 {
+		t.Fatalf("calls = %+v", calls)
+	}
+	components, _ := json.Marshal(calls[0].body["components"])
+	if calls[0].path != "/channels/C1/messages" || !bytes.Contains(components, []byte(`"custom_id":"p1:reject"`)) {
+		t.Errorf("prompt = %+v", calls[0])
+	}
+	if calls[1].method != http.MethodPatch || calls[1].path != "/channels/C1/messages/M9" || len(calls[1].body["components"].([]any)) != 0 {
+		t.Errorf("edit = %+v, want the buttons removed", calls[1])
+	}
+	if calls[2].path != "/applications/A1/commands" || calls[2].body["name"] != "ask" {
+		t.Errorf("command = %+v", calls[2])
+	}
+}
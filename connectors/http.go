@@ -0,0 +1,105 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxWebhookBody limits the events webhook platforms accept
+const maxWebhookBody = 1 << 20
+
+// callJSON sends body as JSON to url and decodes the response into out,
+// which may be nil. Responses other than 2xx are returned as errors.
+func callJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxWebhookBody))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if response.StatusCode/100 != 2 {
+		return &statusError{status: response.StatusCode, body: string(data)}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// statusError is a response with a status other than 2xx
+type statusError struct {
+	status int
+	body   string
+}
+
+// Error implements error
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.status, truncateText(e.body, 300))
+}
+
+// webhook delivers the events a platform receives over HTTP to the channel
+// it listens with; platforms embed it for their Listen method
+type webhook struct {
+	mu     sync.Mutex
+	events chan<- Event
+	ctx    context.Context
+}
+
+// Listen delivers the events the webhook receives until ctx is done
+func (w *webhook) Listen(ctx context.Context, events chan<- Event) error {
+	w.mu.Lock()
+	w.events, w.ctx = events, ctx
+	w.mu.Unlock()
+
+	<-ctx.Done()
+	w.mu.Lock()
+	w.events, w.ctx = nil, nil
+	w.mu.Unlock()
+	return ctx.Err()
+}
+
+// deliver passes event on to the listener
+func (w *webhook) deliver(event Event) error {
+	w.mu.Lock()
+	events, ctx := w.events, w.ctx
+	w.mu.Unlock()
+	if events == nil {
+		return ErrNotListening
+	}
+	select {
+	case events <- event:
+		return nil
+	case <-ctx.Done():
+		return ErrNotListening
+	}
+}
@@ -0,0 +1,334 @@
+package connectors
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// slackMaxLength is the length at which messages are split; Slack
+	// truncates longer ones and renders them poorly
+	slackMaxLength = 4000
+	// slackMaxSection is the longest text of a section block
+	slackMaxSection = 3000
+	// slackMaxSkew is the largest age of a request Slack signed
+	slackMaxSkew = 5 * time.Minute
+	// slackSeen is how many delivered messages are remembered to drop
+	// retried and duplicated events
+	slackSeen = 256
+)
+
+// SlackConfig configures a Slack app
+type SlackConfig struct {
+	BotToken      string       // Bot user OAuth token, "xoxb-..."
+	SigningSecret string       // Signing secret verifying the requests Slack sends
+	BaseURL       string       // Web API server; empty uses https://slack.com/api
+	HTTPClient    *http.Client // Client for the Web API; nil uses one with a 30s timeout
+}
+
+// Slack receives events through the Events API and button presses through
+// interactivity, both sent to its ServeHTTP. Top-level messages share the
+// session of their channel and thread replies have a session per thread.
+type Slack struct {
+	webhook
+	config SlackConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	seen map[string]bool
+	ring []string
+}
+
+// NewSlack creates a Slack platform
+func NewSlack(config SlackConfig) (*Slack, error) {
+	if config.BotToken == "" {
+		return nil, fmt.Errorf("slack bot token is required")
+	}
+	if config.SigningSecret == "" {
+		return nil, fmt.Errorf("slack signing secret is required")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://slack.com/api"
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Slack{config: config, client: client, seen: make(map[string]bool)}, nil
+}
+
+// Name implements Platform
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// ServeHTTP receives the events and interactions Slack sends, to be used as
+// both the Request URL of event subscriptions and of interactivity
+func (s *Slack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := s.verify(r.Header, body, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var event Event
+	var ok bool
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		event, ok, err = s.interaction(body)
+	} else {
+		var challenge string
+		event, ok, challenge, err = s.callback(body)
+		if challenge != "" {
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, challenge)
+			return
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		if err := s.deliver(event); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the signature Slack computes over the timestamp and body
+func (s *Slack) verify(header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing request timestamp")
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return fmt.Errorf("request timestamp is too old")
+	}
+	signature, ok := strings.CutPrefix(header.Get("X-Slack-Signature"), "v0=")
+	if !ok {
+		return fmt.Errorf("missing request signature")
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed request signature")
+	}
+	mac := hmac.New(sha256.New, []byte(s.config.SigningSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("invalid request signature")
+	}
+	return nil
+}
+
+// callback parses an Events API request: the challenge of a URL
+// verification, or a message from a user
+func (s *Slack) callback(body []byte) (event Event, ok bool, challenge string, err error) {
+	var request struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Event     struct {
+			Type     string `json:"type"`
+			Subtype  string `json:"subtype"`
+			BotID    string `json:"bot_id"`
+			User     string `json:"user"`
+			Channel  string `json:"channel"`
+			Text     string `json:"text"`
+			TS       string `json:"ts"`
+			ThreadTS string `json:"thread_ts"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return Event{}, false, "", fmt.Errorf("invalid event: %w", err)
+	}
+	if request.Type == "url_verification" {
+		return Event{}, false, request.Challenge, nil
+	}
+
+	message := request.Event
+	if request.Type != "event_callback" || (message.Type != "message" && message.Type != "app_mention") {
+		return Event{}, false, "", nil
+	}
+	// Edits, joins and the bot's own messages are not for the agent
+	if message.Subtype != "" || message.BotID != "" || message.User == "" || message.Text == "" {
+		return Event{}, false, "", nil
+	}
+	// A mention arrives both as a message and as an app_mention, and
+	// deliveries are retried when not acknowledged in time
+	if !s.firstSeen(message.Channel + "/" + message.TS) {
+		return Event{}, false, "", nil
+	}
+
+	event = Event{
+		Conversation: Conversation{Platform: s.Name(), Channel: message.Channel, Thread: message.ThreadTS},
+		User:         message.User,
+		Text:         message.Text,
+	}
+	return event, true, "", nil
+}
+
+// firstSeen reports whether key was not seen among the latest messages
+func (s *Slack) firstSeen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return false
+	}
+	if len(s.ring) == slackSeen {
+		delete(s.seen, s.ring[0])
+		s.ring = s.ring[1:]
+	}
+	s.seen[key] = true
+	s.ring = append(s.ring, key)
+	return true
+}
+
+// interaction parses the press of a button sent by Ask
+func (s *Slack) interaction(body []byte) (Event, bool, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return Event{}, false, fmt.Errorf("invalid interaction: %w", err)
+	}
+	var payload struct {
+		Type string `json:"type"`
+		User struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"user"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+		Message struct {
+			ThreadTS string `json:"thread_ts"`
+		} `json:"message"`
+		Actions []struct {
+			Value string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		return Event{}, false, fmt.Errorf("invalid interaction: %w", err)
+	}
+	if payload.Type != "block_actions" || len(payload.Actions) == 0 {
+		return Event{}, false, nil
+	}
+	promptID, value, ok := parseButtonData(payload.Actions[0].Value)
+	if !ok {
+		return Event{}, false, nil
+	}
+	event := Event{
+		Conversation: Conversation{Platform: s.Name(), Channel: payload.Channel.ID, Thread: payload.Message.ThreadTS},
+		User:         payload.User.ID,
+		UserName:     payload.User.Username,
+		PromptID:     promptID,
+		Value:        value,
+	}
+	return event, true, nil
+}
+
+// Send implements Platform, replying in the conversation's thread; texts
+// over the length limit are sent as several messages
+func (s *Slack) Send(ctx context.Context, conversation Conversation, text string) (string, error) {
+	var messageID string
+	for _, part := range splitText(text, slackMaxLength) {
+		id, err := s.post(ctx, conversation, map[string]any{"text": part})
+		if err != nil {
+			return "", err
+		}
+		messageID = id
+	}
+	return messageID, nil
+}
+
+// Edit implements Platform
+func (s *Slack) Edit(ctx context.Context, conversation Conversation, messageID, text string) error {
+	return s.call(ctx, "chat.update", map[string]any{
+		"channel": conversation.Channel,
+		"ts":      messageID,
+		"text":    truncateText(text, slackMaxLength),
+		"blocks":  []any{},
+	}, nil)
+}
+
+// Ask implements Platform with a section and an actions block
+func (s *Slack) Ask(ctx context.Context, conversation Conversation, promptID, text string, buttons []Button) (string, error) {
+	elements := make([]map[string]any, len(buttons))
+	for i, button := range buttons {
+		elements[i] = map[string]any{
+			"type":      "button",
+			"action_id": button.Value,
+			"text":      map[string]string{"type": "plain_text", "text": button.Label},
+			"value":     buttonData(promptID, button.Value),
+		}
+	}
+	return s.post(ctx, conversation, map[string]any{
+		"text": truncateText(text, slackMaxLength),
+		"blocks": []map[string]any{
+			{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": truncateText(text, slackMaxSection)}},
+			{"type": "actions", "block_id": promptID, "elements": elements},
+		},
+	})
+}
+
+// post posts a message with params to the conversation and returns its timestamp
+func (s *Slack) post(ctx context.Context, conversation Conversation, params map[string]any) (string, error) {
+	params["channel"] = conversation.Channel
+	if conversation.Thread != "" {
+		params["thread_ts"] = conversation.Thread
+	}
+	var posted struct {
+		TS string `json:"ts"`
+	}
+	if err := s.call(ctx, "chat.postMessage", params, &posted); err != nil {
+		return "", err
+	}
+	return posted.TS, nil
+}
+
+// call calls a Web API method and decodes its response into result, which may be nil
+func (s *Slack) call(ctx context.Context, method string, params, result any) error {
+	header := http.Header{"Authorization": {"Bearer " + s.config.BotToken}}
+	var raw json.RawMessage
+	if err := callJSON(ctx, s.client, http.MethodPost, s.config.BaseURL+"/"+method, header, params, &raw); err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	// The Web API reports failures with ok set to false
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("slack %s: failed to decode response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("slack %s: failed to decode response: %w", method, err)
+	}
+	return nil
+}
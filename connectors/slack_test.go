@@ -0,0 +1,171 @@
+package connectors
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedSlackRequest returns a request to the Slack endpoint signed with secret
+func signedSlackRequest(secret, contentType, body string, at time.Time) *http.Request {
+	timestamp := fmt.Sprint(at.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	request := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	request.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return request
+}
+
+func TestSlack_ServeHTTP(t *testing.T) {
+	interaction := url.Values{"payload": {`{"type": "block_actions", "user": {"id": "U2", "username": "bo"},
+		"channel": {"id": "C1"}, "message": {"ts": "3.0", "thread_ts": "1.0"}, "actions": [{"value": "p1:reject"}]}`}}.Encode()
+	mention := `{"type": "event_callback", "event": {"type": "app_mention", "user": "U1", "channel": "C1", "text": "<@B1> hi", "ts": "1.0"}}`
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		wantStatus int
+		wantBody   string
+		wantEvent  *Event
+	}{
+		{
+			name:       "url verification",
+			request:    signedSlackRequest("secret", "application/json", `{"type": "url_verification", "challenge": "c123"}`, time.Now()),
+			wantStatus: http.StatusOK,
+			wantBody:   "c123",
+		},
+		{
+			name:       "mention",
+			request:    signedSlackRequest("secret", "application/json", mention, time.Now()),
+			wantStatus: http.StatusOK,
+			wantEvent:  &Event{Conversation: Conversation{Platform: "slack", Channel: "C1"}, User: "U1", Text: "<@B1> hi"},
+		},
+		{
+			name: "same message again",
+			request: signedSlackRequest("secret", "application/json",
+				`{"type": "event_callback", "event": {"type": "message", "user": "U1", "channel": "C1", "text": "<@B1> hi", "ts": "1.0"}}`, time.Now()),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "thread reply",
+			request: signedSlackRequest("secret", "application/json",
+				`{"type": "event_callback", "event": {"type": "message", "user": "U1", "channel": "C1", "text": "more", "ts": "2.0", "thread_ts": "1.0"}}`, time.Now()),
+			wantStatus: http.StatusOK,
+			wantEvent:  &Event{Conversation: Conversation{Platform: "slack", Channel: "C1", Thread: "1.0"}, User: "U1", Text: "more"},
+		},
+		{
+			name: "bot message",
+			request: signedSlackRequest("secret", "application/json",
+				`{"type": "event_callback", "event": {"type": "message", "bot_id": "B1", "channel": "C1", "text": "reply", "ts": "4.0"}}`, time.Now()),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "button press",
+			request:    signedSlackRequest("secret", "application/x-www-form-urlencoded", interaction, time.Now()),
+			wantStatus: http.StatusOK,
+			wantEvent:  &Event{Conversation: Conversation{Platform: "slack", Channel: "C1", Thread: "1.0"}, User: "U2", UserName: "bo", PromptID: "p1", Value: "reject"},
+		},
+		{
+			name:       "wrong secret",
+			request:    signedSlackRequest("other", "application/json", mention, time.Now()),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "replayed",
+			request:    signedSlackRequest("secret", "application/json", mention, time.Now().Add(-time.Hour)),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	slack, err := NewSlack(SlackConfig{BotToken: "xoxb-test", SigningSecret: "secret"})
+	if err != nil {
+		t.Fatalf("NewSlack failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 1)
+	go slack.Listen(ctx, events)
+	waitFor(t, "Listen", func() bool {
+		slack.webhook.mu.Lock()
+		defer slack.webhook.mu.Unlock()
+		return slack.webhook.events != nil
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			slack.ServeHTTP(recorder, tt.request)
+			if recorder.Code != tt.wantStatus || (tt.wantBody != "" && recorder.Body.String() != tt.wantBody) {
+				t.Fatalf("response = %d %q", recorder.Code, recorder.Body.String())
+			}
+			select {
+			case event := <-events:
+				if tt.wantEvent == nil || event != *tt.wantEvent {
+					t.Errorf("event = %+v, want %+v", event, tt.wantEvent)
+				}
+			default:
+				if tt.wantEvent != nil {
+					t.Errorf("no event, want %+v", *tt.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestSlack_Send(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			w.Write([]byte(`{"ok": false, "error": "invalid_auth"}`))
+			return
+		}
+		var params map[string]any
+		json.NewDecoder(r.Body).Decode(&params)
+		params["method"] = r.URL.Path
+		requests = append(requests, params)
+		w.Write([]byte(`{"ok": true, "ts": "5.0"}`))
+	}))
+	defer server.Close()
+
+	slack, err := NewSlack(SlackConfig{BotToken: "xoxb-test", SigningSecret: "secret", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewSlack failed: %v", err)
+	}
+	ctx := context.Background()
+	conversation := Conversation{Platform: "slack", Channel: "C1", Thread: "1.0"}
+	id, err := slack.Ask(ctx, conversation, "p1", "Run shell?", approvalButtons)
+	if err != nil || id != "5.0" {
+		t.Fatalf("Ask = %q, %v", id, err)
+	}
+	if err := slack.Edit(ctx, conversation, id, "Run shell?\n\nApprove"); err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+
+	if len(requests) != 2 || requests[0]["method"] != "/chat.postMessage" || requests[1]["method"] != "/chat.update" {
+		t.Fatalf("requests = %v", requests)
+	}
+	blocks, _ := json.Marshal(requests[0]["blocks"])
+	if requests[0]["thread_ts"] != "1.0" || !strings.Contains(string(blocks), `"value":"p1:always"`) {
+		t.Errorf("prompt = %v", requests[0])
+	}
+	if requests[1]["ts"] != "5.0" || len(requests[1]["blocks"].([]any)) != 0 {
+		t.Errorf("edit = %v, want the buttons removed", requests[1])
+	}
+
+	bad, _ := NewSlack(SlackConfig{BotToken: "xoxb-wrong", SigningSecret: "secret", BaseURL: server.URL})
+	if _, err := bad.Send(ctx, conversation, "hi"); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("Send with a wrong token returned %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramMaxLength is the longest message the Bot API accepts, in characters
+const telegramMaxLength = 4096
+
+// TelegramConfig configures a Telegram bot
+type TelegramConfig struct {
+	Token       string        // Bot token from @BotFather
+	BaseURL     string        // Bot API server; empty uses https://api.telegram.org
+	PollTimeout time.Duration // How long a getUpdates call waits for updates; 0 uses 30s
+	HTTPClient  *http.Client  // Client for the Bot API; nil uses one without a timeout, as long polls take PollTimeout
+}
+
+// Telegram receives messages through long polling of the Bot API. Sessions
+// are per chat, or per topic in forum groups; approval buttons are inline
+// keyboards.
+type Telegram struct {
+	config TelegramConfig
+	client *http.Client
+}
+
+// NewTelegram creates a Telegram platform
+func NewTelegram(config TelegramConfig) (*Telegram, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("telegram bot token is required")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.telegram.org"
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.PollTimeout <= 0 {
+		config.PollTimeout = 30 * time.Second
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Telegram{config: config, client: client}, nil
+}
+
+// Name implements Platform
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+// Listen implements Platform by polling for updates. Failed polls are
+// retried with a growing delay, except when the token is refused.
+func (t *Telegram) Listen(ctx context.Context, events chan<- Event) error {
+	offset := int64(0)
+	delay := time.Second
+	for ctx.Err() == nil {
+		var updates []telegramUpdate
+		err := t.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         int(t.config.PollTimeout.Seconds()),
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+		if err != nil {
+			var apiErr *telegramError
+			if errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusNotFound) {
+				return err
+			}
+			select {
+			case <-time.After(delay):
+				delay = min(2*delay, 30*time.Second)
+			case <-ctx.Done():
+			}
+			continue
+		}
+		delay = time.Second
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			event, ok := t.eventOf(ctx, update)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// eventOf returns the event of a text message or callback query update
+func (t *Telegram) eventOf(ctx context.Context, update telegramUpdate) (Event, bool) {
+	if query := update.CallbackQuery; query != nil && query.Message != nil {
+		// Answering stops the client's loading indicator
+		t.call(ctx, "answerCallbackQuery", map[string]any{"callback_query_id": query.ID}, nil)
+		promptID, value, ok := parseButtonData(query.Data)
+		if !ok {
+			return Event{}, false
+		}
+		event := t.event(query.Message, query.From)
+		event.PromptID, event.Value = promptID, value
+		return event, true
+	}
+	if message := update.Message; message != nil && message.Text != "" && message.From != nil {
+		event := t.event(message, *message.From)
+		event.Text = message.Text
+		return event, true
+	}
+	return Event{}, false
+}
+
+// event returns an event in the conversation of message from user
+func (t *Telegram) event(message *telegramMessage, user telegramUser) Event {
+	conversation := Conversation{Platform: t.Name(), Channel: strconv.FormatInt(message.Chat.ID, 10)}
+	if message.IsTopicMessage && message.MessageThreadID != 0 {
+		conversation.Thread = strconv.FormatInt(message.MessageThreadID, 10)
+	}
+	name := user.Username
+	if name == "" {
+		name = user.FirstName
+	}
+	return Event{Conversation: conversation, User: strconv.FormatInt(user.ID, 10), UserName: name}
+}
+
+// Send implements Platform; texts over the Bot API's limit are sent as
+// several messages, and the ID of the last one is returned
+func (t *Telegram) Send(ctx context.Context, conversation Conversation, text string) (string, error) {
+	var messageID string
+	for _, part := range splitText(text, telegramMaxLength) {
+		id, err := t.send(ctx, conversation, part, nil)
+		if err != nil {
+			return "", err
+		}
+		messageID = id
+	}
+	return messageID, nil
+}
+
+// Edit implements Platform
+func (t *Telegram) Edit(ctx context.Context, conversation Conversation, messageID, text string) error {
+	id, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram message ID %q", messageID)
+	}
+	err = t.call(ctx, "editMessageText", map[string]any{
+		"chat_id":    conversation.Channel,
+		"message_id": id,
+		"text":       truncateText(text, telegramMaxLength),
+	}, nil)
+	var apiErr *telegramError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.Description, "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+// Ask implements Platform with an inline keyboard
+func (t *Telegram) Ask(ctx context.Context, conversation Conversation, promptID, text string, buttons []Button) (string, error) {
+	row := make([]map[string]string, len(buttons))
+	for i, button := range buttons {
+		row[i] = map[string]string{"text": button.Label, "callback_data": buttonData(promptID, button.Value)}
+	}
+	markup := map[string]any{"inline_keyboard": [][]map[string]string{row}}
+	return t.send(ctx, conversation, truncateText(text, telegramMaxLength), markup)
+}
+
+// send sends one message with an optional reply markup
+func (t *Telegram) send(ctx context.Context, conversation Conversation, text string, markup any) (string, error) {
+	params := map[string]any{"chat_id": conversation.Channel, "text": text}
+	if conversation.Thread != "" {
+		params["message_thread_id"] = conversation.Thread
+	}
+	if markup != nil {
+		params["reply_markup"] = markup
+	}
+	var sent telegramMessage
+	if err := t.call(ctx, "sendMessage", params, &sent); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(sent.MessageID, 10), nil
+}
+
+// call calls a Bot API method and decodes its result into result, which may be nil
+func (t *Telegram) call(ctx context.Context, method string, params, result any) error {
+	var response struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	err := callJSON(ctx, t.client, http.MethodPost, t.config.BaseURL+"/bot"+t.config.Token+"/"+method, nil, params, &response)
+	var status *statusError
+	if errors.As(err, &status) {
+		// The Bot API describes failures in the body of error responses
+		if json.Unmarshal([]byte(status.body), &response) != nil {
+			return fmt.Errorf("telegram %s: %w", method, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	if !response.OK {
+		return fmt.Errorf("telegram %s: %w", method, &telegramError{Code: response.ErrorCode, Description: response.Description})
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("telegram %s: failed to decode result: %w", method, err)
+	}
+	return nil
+}
+
+// telegramError is an error reported by the Bot API
+type telegramError struct {
+	Code        int
+	Description string
+}
+
+// Error implements error
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Description)
+}
+
+// telegramUpdate is an update of getUpdates
+type telegramUpdate struct {
+	UpdateID      int64                  `json:"update_id"`
+	Message       *telegramMessage       `json:"message"`
+	CallbackQuery *telegramCallbackQuery `json:"callback_query"`
+}
+
+// telegramMessage is a message of the Bot API
+type telegramMessage struct {
+	MessageID       int64         `json:"message_id"`
+	MessageThreadID int64         `json:"message_thread_id"`
+	IsTopicMessage  bool          `json:"is_topic_message"`
+	From            *telegramUser `json:"from"`
+	Chat            struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// telegramUser is a user of the Bot API
+type telegramUser struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+// telegramCallbackQuery is the press of an inline keyboard button
+type telegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    telegramUser     `json:"from"`
+	Message *telegramMessage `json:"message"`
+	Data    string           `json:"data"`
+}
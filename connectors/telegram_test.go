@@ -0,0 +1,132 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBotAPI serves the Bot API methods the platform calls
+type fakeBotAPI struct {
+	mu       sync.Mutex
+	updates  [][]byte
+	requests map[string][]map[string]any
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bottest-token/")
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"ok": false, "error_code": 401, "description": "Unauthorized"}`))
+		return
+	}
+	var params map[string]any
+	json.NewDecoder(r.Body).Decode(&params)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[method] = append(f.requests[method], params)
+
+	switch method {
+	case "getUpdates":
+		result := []byte("[]")
+		if len(f.updates) > 0 {
+			result, f.updates = f.updates[0], f.updates[1:]
+		}
+		w.Write([]byte(`{"ok": true, "result": ` + string(result) + `}`))
+	case "sendMessage":
+		w.Write([]byte(`{"ok": true, "result": {"message_id": 7, "chat": {"id": 42}}}`))
+	case "editMessageText":
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok": false, "error_code": 400, "description": "Bad Request: message is not modified"}`))
+	default:
+		w.Write([]byte(`{"ok": true, "result": true}`))
+	}
+}
+
+func newTestTelegram(t *testing.T, token string, api *fakeBotAPI) *Telegram {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	telegram, err := NewTelegram(TelegramConfig{Token: token, BaseURL: server.URL, HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("NewTelegram failed: %v", err)
+	}
+	return telegram
+}
+
+func TestTelegram_Listen(t *testing.T) {
+	api := &fakeBotAPI{requests: make(map[string][]map[string]any), updates: [][]byte{
+		[]byte(`[{"update_id": 10, "message": {"message_id": 1, "chat": {"id": 42}, "from": {"id": 5, "first_name": "Ana"}, "text": "hello"}},
+			{"update_id": 11, "message": {"message_id": 2, "chat": {"id": 42}, "from": {"id": 5}, "sticker": {}}}]`),
+		[]byte(`[{"update_id": 12, "callback_query": {"id": "cb1", "from": {"id": 6, "username": "bo"}, "data": "p1:approve",
+			"message": {"message_id": 3, "chat": {"id": -100}, "is_topic_message": true, "message_thread_id": 9}}}]`),
+	}}
+	telegram := newTestTelegram(t, "test-token", api)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event)
+	stopped := make(chan error, 1)
+	go func() { stopped <- telegram.Listen(ctx, events) }()
+	message, press := <-events, <-events
+	cancel()
+	<-stopped
+
+	wantMessage := Event{Conversation: Conversation{Platform: "telegram", Channel: "42"}, User: "5", UserName: "Ana", Text: "hello"}
+	if message != wantMessage {
+		t.Errorf("message = %+v, want %+v", message, wantMessage)
+	}
+	wantPress := Event{Conversation: Conversation{Platform: "telegram", Channel: "-100", Thread: "9"}, User: "6", UserName: "bo", PromptID: "p1", Value: "approve"}
+	if press != wantPress {
+		t.Errorf("press = %+v, want %+v", press, wantPress)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if polls := api.requests["getUpdates"]; len(polls) < 2 || polls[1]["offset"] != float64(12) {
+		t.Errorf("getUpdates requests = %v, want the second from offset 12", polls)
+	}
+	if answers := api.requests["answerCallbackQuery"]; len(answers) != 1 || answers[0]["callback_query_id"] != "cb1" {
+		t.Errorf("answerCallbackQuery requests = %v", answers)
+	}
+}
+
+func TestTelegram_Listen_Unauthorized(t *testing.T) {
+	telegram := newTestTelegram(t, "wrong-token", &fakeBotAPI{requests: make(map[string][]map[string]any)})
+	err := telegram.Listen(context.Background(), make(chan Event))
+	if err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Listen returned %v, want the token refused", err)
+	}
+}
+
+func TestTelegram_Send(t *testing.T) {
+	api := &fakeBotAPI{requests: make(map[string][]map[string]any)}
+	telegram := newTestTelegram(t, "test-token", api)
+	ctx := context.Background()
+	conversation := Conversation{Platform: "telegram", Channel: "42", Thread: "9"}
+
+	id, err := telegram.Ask(ctx, conversation, "p1", "Run shell?", []Button{{Label: "Approve", Value: "approve"}})
+	if err != nil || id != "7" {
+		t.Fatalf("Ask = %q, %v", id, err)
+	}
+	if err := telegram.Edit(ctx, conversation, id, "Run shell?"); err != nil {
+		t.Errorf("Edit of an unchanged message returned %v", err)
+	}
+	if _, err := telegram.Send(ctx, conversation, strings.Repeat("a", telegramMaxLength+1)); err != nil {
+		t.Errorf("Send failed: %v", err)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	sent := api.requests["sendMessage"]
+	if len(sent) != 3 {
+		t.Fatalf("sent %d messages, want the prompt and a reply in two parts", len(sent))
+	}
+	keyboard, _ := json.Marshal(sent[0]["reply_markup"])
+	if sent[0]["message_thread_id"] != "9" || string(keyboard) != `{"inline_keyboard":[[{"callback_data":"p1:approve","text":"Approve"}]]}` {
+		t.Errorf("prompt = %v", sent[0])
+	}
+}
@@ -0,0 +1,398 @@
+// Package actioncheck finds Post methods returning actions their package does
+// not declare, a vet-style check for the typos Flow.DeclareActions catches at
+// run time. It needs no type information: actions are the string constants,
+// literals and Action conversions the source spells out, and returns it cannot
+// resolve, such as variables, are not checked.
+package actioncheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// corePath is the import path of the core package
+const corePath = "github.com/alt-coder/pocketflow-go/core"
+
+// coreActions are the actions of the core package by constant name, used when
+// the core package is not part of the checked module
+var coreActions = map[string]core.Action{
+	"ActionContinue":  core.ActionContinue,
+	"ActionSuccess":   core.ActionSuccess,
+	"ActionFailure":   core.ActionFailure,
+	"ActionRetry":     core.ActionRetry,
+	"ActionDefault":   core.ActionDefault,
+	"ActionShutdown":  core.ActionShutdown,
+	"ActionCancelled": core.ActionCancelled,
+}
+
+// Finding is a Post method returning an undeclared action
+type Finding struct {
+	Pos    token.Position
+	Method string // Receiver type and method, e.g. "ChatNode.Post"
+	Action string // The action, or the constant it names when its value is unknown
+}
+
+// String formats the finding like a vet diagnostic
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s returns undeclared action %q", f.Pos, f.Method, f.Action)
+}
+
+// CheckDir checks the package in dir, without its tests. Packages that declare
+// no actions have no findings.
+func CheckDir(dir string) ([]Finding, error) {
+	fset := token.NewFileSet()
+	files, err := parseDir(fset, dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &checker{fset: fset, module: findModule(dir), packages: make(map[string]map[string]string)}
+	return c.check(files), nil
+}
+
+// parseDir parses the Go files of dir except tests
+func parseDir(fset *token.FileSet, dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// module is the Go module a checked package belongs to
+type module struct {
+	path string // Module path from go.mod
+	dir  string // Directory of go.mod
+}
+
+// findModule finds the go.mod above dir; the zero module means there is none
+func findModule(dir string) module {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return module{}
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if path, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					return module{path: strings.Trim(strings.TrimSpace(path), `"`), dir: dir}
+				}
+			}
+			return module{}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return module{}
+		}
+		dir = parent
+	}
+}
+
+// checker holds the constants of the checked package and of the packages it
+// imports from the same module
+type checker struct {
+	fset     *token.FileSet
+	module   module
+	consts   map[string]ast.Expr          // Constants of the checked package by name
+	packages map[string]map[string]string // Resolved string constants of imported packages by path
+}
+
+// method is a method declaration with the imports of its file
+type method struct {
+	decl    *ast.FuncDecl
+	imports map[string]string // Import paths by name
+}
+
+func (c *checker) check(files []*ast.File) []Finding {
+	c.consts = make(map[string]ast.Expr)
+	methods := make(map[string]method) // By "Type.Name"
+	var declarations []method
+	for _, file := range files {
+		imports := fileImports(file)
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				collectConsts(decl, c.consts)
+			case *ast.FuncDecl:
+				if decl.Recv != nil && len(decl.Recv.List) == 1 {
+					methods[receiverName(decl)+"."+decl.Name.Name] = method{decl, imports}
+				}
+				declarations = append(declarations, method{decl, imports})
+			}
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, decl := range declarations {
+		if decl.decl.Body == nil {
+			continue
+		}
+		ast.Inspect(decl.decl.Body, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if selector, ok := call.Fun.(*ast.SelectorExpr); ok && selector.Sel.Name == "DeclareActions" {
+				for _, arg := range call.Args {
+					if action, ok := c.resolve(arg, decl.imports, nil); ok {
+						declared[action] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	if len(declared) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	for name, post := range methods {
+		if post.decl.Name.Name != "Post" || post.decl.Body == nil || post.decl.Type.Results.NumFields() != 1 {
+			continue
+		}
+		c.checkReturns(post, name, methods, declared, map[string]bool{name: true}, &findings)
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Pos.Filename != findings[j].Pos.Filename {
+			return findings[i].Pos.Filename < findings[j].Pos.Filename
+		}
+		return findings[i].Pos.Offset < findings[j].Pos.Offset
+	})
+	return findings
+}
+
+// checkReturns reports the undeclared actions m returns, following returns of
+// other methods of the same receiver, such as a helper choosing the action
+func (c *checker) checkReturns(m method, post string, methods map[string]method, declared, visited map[string]bool, findings *[]Finding) {
+	receiver := receiverVar(m.decl)
+	ast.Inspect(m.decl.Body, func(node ast.Node) bool {
+		if _, ok := node.(*ast.FuncLit); ok {
+			return false
+		}
+		ret, ok := node.(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			return true
+		}
+		result := ret.Results[0]
+		if helper, ok := c.helper(result, receiver, receiverName(m.decl), methods); ok {
+			if !visited[helper] {
+				visited[helper] = true
+				c.checkReturns(methods[helper], post, methods, declared, visited, findings)
+			}
+			return true
+		}
+		if action, ok := c.resolve(result, m.imports, nil); ok && !declared[action] {
+			*findings = append(*findings, Finding{Pos: c.fset.Position(result.Pos()), Method: post, Action: display(action)})
+		}
+		return true
+	})
+}
+
+// helper returns the method a result calls on the receiver, if it is one of
+// the package's methods returning a single value
+func (c *checker) helper(result ast.Expr, receiver, typeName string, methods map[string]method) (string, bool) {
+	call, ok := result.(*ast.CallExpr)
+	if !ok || receiver == "" {
+		return "", false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if ident, ok := selector.X.(*ast.Ident); !ok || ident.Name != receiver {
+		return "", false
+	}
+	name := typeName + "." + selector.Sel.Name
+	m, ok := methods[name]
+	if !ok || m.decl.Body == nil || m.decl.Type.Results.NumFields() != 1 {
+		return "", false
+	}
+	return name, true
+}
+
+// resolve returns the action expr stands for. Constants of packages outside
+// the module resolve to a symbol, "pkg.Name", which matches the same symbol.
+func (c *checker) resolve(expr ast.Expr, imports map[string]string, seen map[string]bool) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind != token.STRING {
+			return "", false
+		}
+		value, err := strconv.Unquote(expr.Value)
+		return value, err == nil
+	case *ast.ParenExpr:
+		return c.resolve(expr.X, imports, seen)
+	case *ast.CallExpr:
+		// Conversions such as core.Action("plan")
+		if len(expr.Args) != 1 || !isActionType(expr.Fun) {
+			return "", false
+		}
+		return c.resolve(expr.Args[0], imports, seen)
+	case *ast.Ident:
+		value, ok := c.consts[expr.Name]
+		if !ok || seen[expr.Name] {
+			return "", false
+		}
+		if seen == nil {
+			seen = make(map[string]bool)
+		}
+		seen[expr.Name] = true
+		return c.resolve(value, imports, seen)
+	case *ast.SelectorExpr:
+		pkg, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return "", false
+		}
+		path, ok := imports[pkg.Name]
+		if !ok {
+			return "", false
+		}
+		if value, ok := c.packageConsts(path)[expr.Sel.Name]; ok {
+			return value, true
+		}
+		if action, ok := coreActions[expr.Sel.Name]; ok && path == corePath {
+			return string(action), true
+		}
+		return symbolPrefix + pkg.Name + "." + expr.Sel.Name, true
+	}
+	return "", false
+}
+
+// symbolPrefix marks an action known only by the name of its constant
+const symbolPrefix = "\x00"
+
+// display formats an action for a finding
+func display(action string) string {
+	return strings.TrimPrefix(action, symbolPrefix)
+}
+
+// packageConsts returns the string constants of an imported package of the
+// module; packages outside the module have none
+func (c *checker) packageConsts(path string) map[string]string {
+	if consts, ok := c.packages[path]; ok {
+		return consts
+	}
+	consts := make(map[string]string)
+	c.packages[path] = consts
+	rest, ok := strings.CutPrefix(path, c.module.path)
+	if c.module.path == "" || !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return consts
+	}
+	files, err := parseDir(token.NewFileSet(), filepath.Join(c.module.dir, filepath.FromSlash(rest)))
+	if err != nil {
+		return consts
+	}
+	imported := &checker{fset: c.fset, module: c.module, consts: make(map[string]ast.Expr), packages: c.packages}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.GenDecl); ok {
+				collectConsts(decl, imported.consts)
+			}
+		}
+	}
+	for _, file := range files {
+		imports := fileImports(file)
+		for name, expr := range imported.consts {
+			if _, done := consts[name]; done || !ast.IsExported(name) {
+				continue
+			}
+			if value, ok := imported.resolve(expr, imports, nil); ok && !strings.HasPrefix(value, symbolPrefix) {
+				consts[name] = value
+			}
+		}
+	}
+	return consts
+}
+
+// collectConsts adds the constants of decl that have a value
+func collectConsts(decl *ast.GenDecl, consts map[string]ast.Expr) {
+	if decl.Tok != token.CONST {
+		return
+	}
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		for i, name := range spec.Names {
+			if i < len(spec.Values) {
+				consts[name.Name] = spec.Values[i]
+			}
+		}
+	}
+}
+
+// fileImports returns the import paths of file by the name they are used with
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// isActionType reports whether expr names the Action type, e.g. core.Action
+func isActionType(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name == "Action"
+	case *ast.SelectorExpr:
+		return expr.Sel.Name == "Action"
+	}
+	return false
+}
+
+// receiverName returns the type name of a method's receiver, e.g. "ChatNode"
+// for (n *ChatNode[T])
+func receiverName(decl *ast.FuncDecl) string {
+	expr := decl.Recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// receiverVar returns the name of a method's receiver variable
+func receiverVar(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 || len(decl.Recv.List[0].Names) == 0 {
+		return ""
+	}
+	return decl.Recv.List[0].Names[0].Name
+}
@@ -0,0 +1,34 @@
+package actioncheck
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckDir(t *testing.T) {
+	findings, err := CheckDir(filepath.Join("testdata", "agent"))
+	if err != nil {
+		t.Fatalf("CheckDir() error = %v", err)
+	}
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.String())
+	}
+	want := []string{
+		`testdata/agent/agent.go:29:10: planner.Post returns undeclared action "paln"`,
+		`testdata/agent/agent.go:43:10: planner.Post returns undeclared action "reject"`,
+		`testdata/agent/agent.go:51:9: reviewer.Post returns undeclared action "failure"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestCheckDir_NothingDeclared(t *testing.T) {
+	findings, err := CheckDir(".")
+	if err != nil || len(findings) != 0 {
+		t.Errorf("CheckDir() = %v, %v; want no findings", findings, err)
+	}
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"github.com/alt-coder/pocketflow-go/core"
+	flownodes "github.com/alt-coder/pocketflow-go/core/nodes"
+)
+
+const (
+	ActionPlan core.Action = "plan"
+	ActionTypo             = "paln"
+	ActionAsk              = ActionPlan
+)
+
+type state struct{ done bool }
+
+func newFlow(start core.Workflow[state]) *core.Flow[state] {
+	flow := core.NewFlow(start)
+	flow.DeclareActions(ActionPlan, core.ActionSuccess, flownodes.ActionApprove, "review")
+	return flow
+}
+
+type planner[T any] struct{}
+
+func (p *planner[T]) Post(s *state, prep []int, results ...int) core.Action {
+	switch {
+	case s.done:
+		return core.ActionSuccess
+	case len(results) == 0:
+		return core.Action(ActionTypo)
+	case len(prep) == 0:
+		return p.route(s)
+	}
+	retry := func() core.Action { return "not checked" }
+	_ = retry
+	return ActionAsk
+}
+
+func (p *planner[T]) route(s *state) core.Action {
+	if s.done {
+		return flownodes.ActionApprove
+	}
+	if s == nil {
+		return flownodes.ActionReject
+	}
+	return "review"
+}
+
+type reviewer struct{}
+
+func (reviewer) Post(s *state, prep []int, results ...int) core.Action {
+	return core.ActionFailure
+}
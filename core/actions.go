@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DeclareActions declares the actions the steps of the flow may return, so a
+// typo in an action is caught: Validate reports routes on undeclared actions,
+// and a step returning one ends the run in the error handler. Flows without
+// declared actions accept any action. A nested flow declares its own actions.
+func (f *Flow[State]) DeclareActions(actions ...Action) {
+	if f.actions == nil && len(actions) > 0 {
+		f.actions = make(map[Action]bool, len(actions))
+	}
+	for _, action := range actions {
+		f.actions[action] = true
+	}
+}
+
+// Actions returns the declared actions in sorted order
+func (f *Flow[State]) Actions() []Action {
+	actions := make([]Action, 0, len(f.actions))
+	for action := range f.actions {
+		actions = append(actions, action)
+	}
+	return sortActions(actions)
+}
+
+// Validate checks that the workflows of the flow only route on declared
+// actions or ActionAny, including nested flows with actions of their own. The
+// successors of the flow itself are routes of the enclosing flow and are not
+// checked.
+func (f *Flow[State]) Validate() error {
+	if f.startNode == nil {
+		return fmt.Errorf("flow has no start node")
+	}
+	var errs []error
+	visited := make(map[Workflow[State]]bool)
+	queue := []Workflow[State]{f.startNode}
+	for len(queue) > 0 {
+		workflow := queue[0]
+		queue = queue[1:]
+		if visited[workflow] {
+			continue
+		}
+		visited[workflow] = true
+
+		if nested, ok := workflow.(*Flow[State]); ok && nested.actions != nil {
+			if err := nested.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("nested flow: %w", err))
+			}
+		}
+		routes, ok := workflow.(router[State])
+		if !ok {
+			continue
+		}
+		successors := routes.successorMap()
+		for _, action := range sortedActions(successors) {
+			if f.actions != nil && !f.actions[action] && action != ActionAny {
+				errs = append(errs, fmt.Errorf("%s routes undeclared action %q", WorkflowName(workflow), action))
+			}
+			queue = append(queue, successors[action])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// router is implemented by the workflows of this package to list their routes
+type router[State any] interface {
+	successorMap() map[Action]Workflow[State]
+}
+
+// successorMap implements router
+func (f *Flow[State]) successorMap() map[Action]Workflow[State] {
+	return f.successors
+}
+
+// successorMap implements router
+func (n *Node[State, PrepResult, ExecResults]) successorMap() map[Action]Workflow[State] {
+	return n.successors
+}
+
+// sortedActions returns the actions of successors in sorted order
+func sortedActions[State any](successors map[Action]Workflow[State]) []Action {
+	actions := make([]Action, 0, len(successors))
+	for action := range successors {
+		actions = append(actions, action)
+	}
+	return sortActions(actions)
+}
+
+// sortActions sorts actions in place and returns them
+func sortActions(actions []Action) []Action {
+	sort.Slice(actions, func(i, j int) bool { return actions[i] < actions[j] })
+	return actions
+}
+
+// declared reports whether the steps of the flow may return action
+func (f *Flow[State]) declared(action Action) bool {
+	return f.actions == nil || f.actions[action]
+}
+
+// undeclared ends a run whose step returned an undeclared action. A nested
+// flow returns ActionFailure to the enclosing flow; otherwise the run ends in
+// the error handler, or with ActionFailure when there is none.
+func (f *Flow[State]) undeclared(state *State, run *flowRun, nested bool, name string, action Action) Action {
+	if run == nil {
+		run = f.startRun()
+	}
+	run.reject(name, action)
+	if nested || f.errorHandler == nil {
+		return ActionFailure
+	}
+	return f.errorHandler(state, run.snapshot())
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	actionPlan Action = "plan"
+	actionDone Action = "done"
+)
+
+func TestFlow_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared []Action
+		route    Action
+		wantErr  string
+	}{
+		{"declared", []Action{actionPlan, actionDone}, actionPlan, ""},
+		{"typo", []Action{actionPlan, actionDone}, "paln", `benchNode routes undeclared action "paln"`},
+		{"nothing declared", nil, "paln", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := NewNode[benchState, int, int](&benchNode{action: actionPlan}, 0, 1)
+			first.AddSuccessor(NewNode[benchState, int, int](&benchNode{action: actionDone}, 0, 1), tt.route)
+			flow := NewFlow[benchState](first)
+			flow.DeclareActions(tt.declared...)
+
+			err := flow.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("nested flow", func(t *testing.T) {
+		inner := NewFlow[benchState](NewNode[benchState, int, int](&benchNode{action: actionDone}, 0, 1))
+		inner.startNode.AddSuccessor(NewNode[benchState, int, int](&benchNode{}, 0, 1), "dnoe")
+		inner.DeclareActions(actionDone)
+		outer := NewFlow[benchState](inner)
+		outer.DeclareActions(actionDone)
+		if err := outer.Validate(); err == nil || !strings.Contains(err.Error(), `nested flow: benchNode routes undeclared action "dnoe"`) {
+			t.Errorf("Validate() = %v", err)
+		}
+	})
+}
+
+func TestFlow_UndeclaredAction(t *testing.T) {
+	newFlow := func() (*Flow[benchState], *benchNode) {
+		typo := &benchNode{action: "paln"}
+		first := NewNode[benchState, int, int](&benchNode{action: actionPlan}, 0, 1)
+		first.AddSuccessor(NewNode[benchState, int, int](typo, 0, 1), actionPlan)
+		flow := NewFlow[benchState](first)
+		flow.DeclareActions(actionPlan, actionDone)
+		return flow, typo
+	}
+
+	t.Run("error handler", func(t *testing.T) {
+		flow, _ := newFlow()
+		var handled RunReport
+		flow.SetErrorHandler(func(state *benchState, report RunReport) Action {
+			handled = report
+			return actionDone
+		})
+		if action := flow.Run(&benchState{}); action != actionDone {
+			t.Errorf("Run() = %v, want the error handler's action", action)
+		}
+		if handled.UndeclaredAction != "paln" || handled.UndeclaredBy != "benchNode" {
+			t.Errorf("report = %+v", handled)
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		flow, _ := newFlow()
+		action, report := flow.RunWithReport(&benchState{}, nil)
+		if action != ActionFailure || report.UndeclaredAction != "paln" {
+			t.Errorf("RunWithReport() = %v, %+v", action, report)
+		}
+	})
+
+	t.Run("nested flow", func(t *testing.T) {
+		inner, _ := newFlow()
+		after := &benchNode{action: actionDone}
+		outer := NewFlow[benchState](inner)
+		inner.AddSuccessor(NewNode[benchState, int, int](after, 0, 1), ActionFailure)
+		if action := outer.Run(&benchState{}); action != actionDone {
+			t.Errorf("Run() = %v, want the nested flow to fail", action)
+		}
+	})
+
+	flow, _ := newFlow()
+	if actions := flow.Actions(); !reflect.DeepEqual(actions, []Action{actionDone, actionPlan}) {
+		t.Errorf("Actions() = %v", actions)
+	}
+}
+
+// visitNode records its name when it runs and returns action
+type visitNode struct {
+	benchNode
+	name   string
+	visits *[]string
+}
+
+func (n *visitNode) Post(state *benchState, prepResults []int, execResults ...int) Action {
+	*n.visits = append(*n.visits, n.name)
+	return n.action
+}
+
+func TestFlow_ActionAny(t *testing.T) {
+	var visits []string
+	newNode := func(name string, action Action) *Node[benchState, int, int] {
+		return NewNode[benchState, int, int](&visitNode{benchNode: benchNode{action: action}, name: name, visits: &visits}, 0, 1)
+	}
+	tests := []struct {
+		name   string
+		action Action
+		want   []string
+	}{
+		{"exact edge first", actionPlan, []string{"start", "plan"}},
+		{"flow edge before the catch-all", actionDone, []string{"start", "done"}},
+		{"catch-all", "unexpected", []string{"start", "fallback"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visits = nil
+			start := newNode("start", tt.action)
+			start.AddSuccessor(newNode("plan", ""), actionPlan)
+			start.AddSuccessor(newNode("fallback", ""), ActionAny)
+			flow := NewFlow[benchState](start)
+			flow.AddSuccessor(newNode("done", ""), actionDone)
+			flow.Run(&benchState{})
+			if !reflect.DeepEqual(visits, tt.want) {
+				t.Errorf("visited %v, want %v", visits, tt.want)
+			}
+		})
+	}
+
+	t.Run("nested flow", func(t *testing.T) {
+		visits = nil
+		inner := NewFlow[benchState](newNode("inner", actionDone))
+		inner.AddSuccessor(newNode("after", ""), ActionAny)
+		NewFlow[benchState](inner).Run(&benchState{})
+		if want := []string{"inner", "after"}; !reflect.DeepEqual(visits, want) {
+			t.Errorf("visited %v, want %v: the inner flow must end before its catch-all", visits, want)
+		}
+	})
+
+	t.Run("declared", func(t *testing.T) {
+		start := newNode("start", actionPlan)
+		start.AddSuccessor(newNode("fallback", ""), ActionAny)
+		flow := NewFlow[benchState](start)
+		flow.DeclareActions(actionPlan)
+		if err := flow.Validate(); err != nil {
+			t.Errorf("Validate() = %v", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchState is the state of the benchmark nodes
+type benchState struct {
+	Items []int
+	Sum   int
+}
+
+// benchNode sums its items, spinning for work iterations per item to stand in
+// for the cost of Exec
+type benchNode struct {
+	work   int
+	action Action
+}
+
+func (n *benchNode) Prep(state *benchState) []int {
+	return state.Items
+}
+
+func (n *benchNode) Exec(item int) (int, error) {
+	for i := 0; i < n.work; i++ {
+		item = item*31 + i
+	}
+	return item, nil
+}
+
+func (n *benchNode) Post(state *benchState, prepResults []int, execResults ...int) Action {
+	for _, result := range execResults {
+		state.Sum += result
+	}
+	return n.action
+}
+
+func (n *benchNode) ExecFallback(err error) int {
+	return 0
+}
+
+func benchItems(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return items
+}
+
+// BenchmarkNode_Run measures the overhead of a Prep, Exec and Post round trip
+func BenchmarkNode_Run(b *testing.B) {
+	node := NewNode[benchState, int, int](&benchNode{action: ActionSuccess}, 0, 1)
+	state := &benchState{Items: benchItems(1)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		node.Run(state)
+	}
+}
+
+// BenchmarkNode_RunConcurrent measures the worker fan-out at several
+// concurrency levels, with an Exec that does almost nothing and one that
+// does about a microsecond of work
+func BenchmarkNode_RunConcurrent(b *testing.B) {
+	for _, items := range []int{100, 10000} {
+		for _, work := range []int{0, 1000} {
+			for _, routines := range []int{1, 4, 16, 64} {
+				name := fmt.Sprintf("items=%d/work=%d/routines=%d", items, work, routines)
+				b.Run(name, func(b *testing.B) {
+					node := NewNode[benchState, int, int](&benchNode{work: work, action: ActionSuccess}, 0, routines)
+					state := &benchState{Items: benchItems(items)}
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						node.Run(state)
+					}
+					b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*items), "ns/item")
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkNode_GetSuccessor measures the action lookup between steps
+func BenchmarkNode_GetSuccessor(b *testing.B) {
+	node := NewNode[benchState, int, int](&benchNode{}, 0, 1)
+	for _, action := range []Action{ActionSuccess, ActionFailure, ActionRetry, ActionContinue, "tools", "summarize"} {
+		node.AddSuccessor(NewNode[benchState, int, int](&benchNode{}, 0, 1), action)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if node.GetSuccessor("tools") == nil {
+			b.Fatal("missing successor")
+		}
+	}
+}
+
+// benchChain links steps single-item nodes with ActionContinue, the last one ending the flow
+func benchChain(steps int) *Flow[benchState] {
+	first := NewNode[benchState, int, int](&benchNode{action: ActionContinue}, 0, 1)
+	var last Workflow[benchState] = first
+	for i := 1; i < steps; i++ {
+		action := ActionContinue
+		if i == steps-1 {
+			action = ActionSuccess
+		}
+		last = last.AddSuccessor(NewNode[benchState, int, int](&benchNode{action: action}, 0, 1), ActionContinue)
+	}
+	return NewFlow[benchState](first)
+}
+
+// BenchmarkFlow_Run measures the cost of a flow step, with and without an observer
+func BenchmarkFlow_Run(b *testing.B) {
+	const steps = 10
+	flow := benchChain(steps)
+	b.Run("unobserved", func(b *testing.B) {
+		state := &benchState{Items: benchItems(1)}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			flow.Run(state)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*steps), "ns/step")
+	})
+	b.Run("observed", func(b *testing.B) {
+		state := &benchState{Items: benchItems(1)}
+		observe := func(StepEvent) {}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			flow.RunObserved(state, observe)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*steps), "ns/step")
+	})
+}
+
+// TestPerformanceBudget_Allocations holds the scheduler to the allocation
+// budget in the README. Allocations are counted rather than timed, so the test
+// is stable on loaded machines; the benchmarks above measure time.
+func TestPerformanceBudget_Allocations(t *testing.T) {
+	one := &benchState{Items: benchItems(1)}
+	hundred := &benchState{Items: benchItems(100)}
+	single := NewNode[benchState, int, int](&benchNode{action: ActionSuccess}, 0, 1)
+	workers := NewNode[benchState, int, int](&benchNode{action: ActionSuccess}, 0, 16)
+	flow := benchChain(10)
+
+	tests := []struct {
+		name   string
+		run    func()
+		budget float64
+	}{
+		{"single item node", func() { single.Run(one) }, 1},
+		{"sequential node with 100 items", func() { single.Run(hundred) }, 1},
+		{"16 workers with 100 items", func() { workers.Run(hundred) }, 16 + 4},
+		{"flow of 10 steps", func() { flow.Run(one) }, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(20, tt.run); allocs > tt.budget {
+				t.Errorf("%s allocated %.0f times, over its budget of %.0f", tt.name, allocs, tt.budget)
+			}
+		})
+	}
+}
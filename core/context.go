@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextExecutor may be implemented by a BaseNode to receive the context of
+// the run in Exec, so the LLM calls and tool executions of its items stop
+// when the run is cancelled. Nodes call ExecContext instead of Exec.
+type ContextExecutor[PrepResult any, ExecResults any] interface {
+	ExecContext(ctx context.Context, item PrepResult) (ExecResults, error)
+}
+
+// scopeKey is the context key of the scope of a run
+type scopeKey struct{}
+
+// runScope tracks the goroutines started with Go during a run
+type runScope struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// start adds a goroutine to the scope, or reports false once the run ended
+func (s *runScope) start() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+// close refuses new goroutines and waits for those running
+func (s *runScope) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Go runs fn in a goroutine that belongs to the run of ctx, e.g. a request
+// hedged against a slow one or a tool call running next to an LLM call. The
+// run cancels fn's context when it ends and waits for fn before RunContext
+// returns. Once the run has ended, Go does not start fn. Outside of a run
+// started with RunContext, fn runs in a goroutine nothing waits for.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	scope, ok := ctx.Value(scopeKey{}).(*runScope)
+	if !ok {
+		go fn(ctx)
+		return
+	}
+	if !scope.start() {
+		return
+	}
+	go func() {
+		defer scope.wg.Done()
+		fn(ctx)
+	}()
+}
+
+// RunContext runs the flow like RunWithReport, bound to ctx. When ctx is
+// cancelled, items that have not started go to ExecFallback with the
+// context's error, failed items are not retried, nodes implementing
+// ContextExecutor see the cancellation, and the run ends with
+// ActionCancelled after the step it is in, also from within nested flows.
+//
+// However the run ends, its context is cancelled and the goroutines its nodes
+// started with Go are awaited before RunContext returns, so no work of the
+// run outlives it.
+func (f *Flow[State]) RunContext(ctx context.Context, state *State, observe StepObserver) (Action, RunReport) {
+	run := f.startRun()
+	scope := &runScope{}
+	var cancel context.CancelFunc
+	run.ctx, cancel = context.WithCancel(context.WithValue(ctx, scopeKey{}, scope))
+	action := f.runContext(state, observe, run)
+	cancel()
+	scope.close()
+
+	if action == ActionCancelled {
+		run.markCancelled()
+	}
+	return action, run.snapshot()
+}
+
+// ResumeContext continues a run like ResumeObserved, bound to ctx like
+// RunContext. Without actions it runs the flow from the start.
+func (f *Flow[State]) ResumeContext(ctx context.Context, state *State, actions []Action, observe StepObserver) (Action, RunReport, error) {
+	run := f.startRun()
+	currentWorkflow, action, err := f.resumeAt(actions)
+	if currentWorkflow == nil {
+		return action, run.snapshot(), err
+	}
+	scope := &runScope{}
+	var cancel context.CancelFunc
+	run.ctx, cancel = context.WithCancel(context.WithValue(ctx, scopeKey{}, scope))
+	action = f.runContextFrom(state, currentWorkflow, len(actions)+1, observe, run)
+	cancel()
+	scope.close()
+
+	if action == ActionCancelled {
+		run.markCancelled()
+	}
+	return action, run.snapshot(), nil
+}
+
+// runContext runs the flow as RunWithReport does, within run
+func (f *Flow[State]) runContext(state *State, observe StepObserver, run *flowRun) Action {
+	if f.startNode == nil {
+		return ActionFailure
+	}
+	return f.runContextFrom(state, f.startNode, 1, observe, run)
+}
+
+// runContextFrom runs the flow from currentWorkflow, numbering steps from step
+func (f *Flow[State]) runContextFrom(state *State, currentWorkflow Workflow[State], step int, observe StepObserver, run *flowRun) Action {
+	if !f.runs.Start() {
+		return ActionShutdown
+	}
+	defer f.runs.Done()
+	return f.runFrom(state, currentWorkflow, step, observe, run, false)
+}
+
+// context returns the context of the run, which is only cancelled for runs
+// started with RunContext
+func (r *flowRun) context() context.Context {
+	if r == nil || r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// cancelled reports whether the context of the run is done
+func (r *flowRun) cancelled() bool {
+	return r != nil && r.ctx != nil && r.ctx.Err() != nil
+}
+
+// markCancelled records that the run ended because its context was cancelled
+func (r *flowRun) markCancelled() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Cancelled = true
+	r.report.CancelCause = context.Cause(r.ctx).Error()
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitingNode blocks each item until the run's context is done, after
+// signalling that it started
+type waitingNode struct {
+	benchNode
+	started   chan struct{}
+	fallbacks atomic.Int32
+	calls     atomic.Int32
+}
+
+func (n *waitingNode) ExecContext(ctx context.Context, item int) (int, error) {
+	n.calls.Add(1)
+	n.started <- struct{}{}
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (n *waitingNode) ExecFallback(err error) int {
+	n.fallbacks.Add(1)
+	return 0
+}
+
+// spawningNode starts a goroutine with Go in each item, which runs until the
+// run's context is done
+type spawningNode struct {
+	benchNode
+	stopped atomic.Int32
+}
+
+func (n *spawningNode) ExecContext(ctx context.Context, item int) (int, error) {
+	Go(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		n.stopped.Add(1)
+	})
+	return item, nil
+}
+
+func TestFlow_RunContextCancelled(t *testing.T) {
+	waiting := &waitingNode{benchNode: benchNode{action: ActionSuccess}, started: make(chan struct{}, 10)}
+	inner := NewFlow[benchState](NewNode[benchState, int, int](waiting, 3, 2))
+	last := &benchNode{action: ActionSuccess}
+	inner.AddSuccessor(NewNode[benchState, int, int](last, 0, 1), ActionSuccess)
+	flow := NewFlow[benchState](inner)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		<-waiting.started
+		<-waiting.started
+		cancel(errors.New("user left"))
+	}()
+	action, report := flow.RunContext(ctx, &benchState{Items: benchItems(6)}, nil)
+
+	if action != ActionCancelled || !report.Cancelled || report.CancelCause != "user left" {
+		t.Fatalf("RunContext() = %v, report %+v, want a cancelled run", action, report)
+	}
+	// The two running items saw the cancellation and were not retried; the
+	// other four never ran
+	if calls, fallbacks := waiting.calls.Load(), waiting.fallbacks.Load(); calls != 2 || fallbacks != 6 || report.Retries != 0 {
+		t.Errorf("calls = %d, fallbacks = %d, retries = %d", calls, fallbacks, report.Retries)
+	}
+
+	// A cancelled context stops the run before its first step
+	state := &benchState{Items: benchItems(1)}
+	if action, _ := NewFlow[benchState](NewNode[benchState, int, int](last, 0, 1)).RunContext(ctx, state, nil); action != ActionCancelled || state.Sum != 0 {
+		t.Errorf("RunContext() with a cancelled context = %v, sum %d", action, state.Sum)
+	}
+}
+
+func TestFlow_RunContextAwaitsGoroutines(t *testing.T) {
+	spawning := &spawningNode{benchNode: benchNode{action: ActionSuccess}}
+	flow := NewFlow[benchState](NewNode[benchState, int, int](spawning, 0, 3))
+
+	action, report := flow.RunContext(context.Background(), &benchState{Items: benchItems(5)}, nil)
+	if action != ActionSuccess || report.Cancelled {
+		t.Fatalf("RunContext() = %v, report %+v", action, report)
+	}
+	if stopped := spawning.stopped.Load(); stopped != 5 {
+		t.Errorf("%d of 5 goroutines stopped before RunContext returned", stopped)
+	}
+}
+
+func TestFlow_ResumeContext(t *testing.T) {
+	type key struct{}
+	var captured context.Context
+	first := &captureNode{benchNode: benchNode{action: ActionContinue}, capture: func(context.Context) {
+		t.Error("resumed run ran the first step again")
+	}}
+	capture := &captureNode{benchNode: benchNode{action: ActionSuccess}, capture: func(ctx context.Context) { captured = ctx }}
+	firstNode := NewNode[benchState, int, int](first, 0, 1)
+	firstNode.AddSuccessor(NewNode[benchState, int, int](capture, 0, 1), ActionContinue)
+	flow := NewFlow[benchState](firstNode)
+
+	ctx := context.WithValue(context.Background(), key{}, "run-1")
+	var steps []int
+	action, _, err := flow.ResumeContext(ctx, &benchState{Items: benchItems(1)}, []Action{ActionContinue}, func(event StepEvent) {
+		steps = append(steps, event.Step)
+	})
+	if err != nil || action != ActionSuccess {
+		t.Fatalf("ResumeContext() = %v, %v", action, err)
+	}
+	if len(steps) != 1 || steps[0] != 2 {
+		t.Errorf("steps = %v, want only step 2", steps)
+	}
+	if captured == nil || captured.Value(key{}) != "run-1" {
+		t.Errorf("context of the resumed step = %v, want the values of ctx", captured)
+	}
+}
+
+func TestGo_AfterRun(t *testing.T) {
+	var captured context.Context
+	capture := &captureNode{capture: func(ctx context.Context) { captured = ctx }}
+	NewFlow[benchState](NewNode[benchState, int, int](capture, 0, 1)).RunContext(context.Background(), &benchState{Items: benchItems(1)}, nil)
+
+	if captured == nil || captured.Err() == nil {
+		t.Fatalf("context of the run = %v, want it cancelled after the run", captured)
+	}
+	ran := make(chan struct{}, 1)
+	Go(captured, func(context.Context) { ran <- struct{}{} })
+	select {
+	case <-ran:
+		t.Error("Go started a goroutine after the run ended")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// captureNode passes the context of its items to capture
+type captureNode struct {
+	benchNode
+	capture func(ctx context.Context)
+}
+
+func (n *captureNode) ExecContext(ctx context.Context, item int) (int, error) {
+	n.capture(ctx)
+	return item, nil
+}
@@ -0,0 +1,316 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DAG runs workflows in the order of the state keys they read and write
+// rather than by action: each step declares its input and output keys, and
+// once the steps writing its inputs are done, a step runs, next to all other
+// steps that are ready. ETL-style pipelines, where many extractions only
+// depend on the document and a few merges depend on them, run as fast as
+// their slowest chain instead of their sum.
+//
+// Steps share the state, so the DAG serializes the access to it: nodes hold
+// a lock of the run while Prep, PostChunk and Post use the state and release
+// it while their items run; other workflows, such as flows, hold it for their
+// whole run. A DAG is a Workflow, so it can be a step of a flow and route on
+// the action it ends with.
+type DAG[State any] struct {
+	steps       []dagStep[State]
+	producers   map[string]int // Step writing each key, by index
+	maxParallel int
+	successors  map[Action]Workflow[State]
+}
+
+// dagStep is a workflow of a DAG with the keys it reads and writes
+type dagStep[State any] struct {
+	name     string
+	workflow Workflow[State]
+	inputs   []string
+	outputs  []string
+}
+
+// NewDAG creates an empty DAG that runs all ready steps at once
+func NewDAG[State any]() *DAG[State] {
+	return &DAG[State]{
+		producers:  make(map[string]int),
+		successors: make(map[Action]Workflow[State]),
+	}
+}
+
+// Add adds a step that runs workflow once the steps writing its inputs are
+// done. Inputs no step writes are expected in the state the DAG runs on. Each
+// key is written by one step, and a step cannot depend on itself, directly
+// or through other steps; Add returns an error for such steps and leaves the
+// DAG unchanged.
+func (d *DAG[State]) Add(name string, workflow Workflow[State], inputs, outputs []string) error {
+	if name == "" || workflow == nil {
+		return fmt.Errorf("step name and workflow are required")
+	}
+	for _, step := range d.steps {
+		if step.name == name {
+			return fmt.Errorf("step %s is already added", name)
+		}
+	}
+	for _, key := range outputs {
+		if producer, ok := d.producers[key]; ok {
+			return fmt.Errorf("step %s writes %s, which step %s writes already", name, key, d.steps[producer].name)
+		}
+	}
+
+	d.steps = append(d.steps, dagStep[State]{name: name, workflow: workflow, inputs: slices.Clone(inputs), outputs: slices.Clone(outputs)})
+	for _, key := range outputs {
+		d.producers[key] = len(d.steps) - 1
+	}
+	if cycle := d.cycle(); cycle != nil {
+		d.steps = d.steps[:len(d.steps)-1]
+		for _, key := range outputs {
+			delete(d.producers, key)
+		}
+		return fmt.Errorf("step %s would close the cycle %v", name, cycle)
+	}
+	return nil
+}
+
+// SetMaxParallel limits how many steps run at the same time; 0, the default,
+// runs all ready steps
+func (d *DAG[State]) SetMaxParallel(steps int) {
+	if steps < 0 {
+		steps = 0
+	}
+	d.maxParallel = steps
+}
+
+// Levels returns the names of the steps grouped by how many steps must run
+// before them: the first level only reads keys of the state it starts with,
+// the next level reads keys written by the first, and so on. The steps of a
+// level can run at the same time.
+func (d *DAG[State]) Levels() [][]string {
+	var levels [][]string
+	depth := make([]int, len(d.steps))
+	for _, i := range d.order() {
+		for _, dependency := range d.dependencies(i) {
+			depth[i] = max(depth[i], depth[dependency]+1)
+		}
+		if depth[i] == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[depth[i]] = append(levels[depth[i]], d.steps[i].name)
+	}
+	return levels
+}
+
+// dependencies returns the steps writing the inputs of step i, by index
+func (d *DAG[State]) dependencies(i int) []int {
+	var dependencies []int
+	for _, key := range d.steps[i].inputs {
+		if producer, ok := d.producers[key]; ok && !slices.Contains(dependencies, producer) {
+			dependencies = append(dependencies, producer)
+		}
+	}
+	return dependencies
+}
+
+// order returns the steps in an order that runs every step after its
+// dependencies, by index, leaving out the steps of cycles
+func (d *DAG[State]) order() []int {
+	waiting := make([]int, len(d.steps))
+	dependents := make([][]int, len(d.steps))
+	var order []int
+	for i := range d.steps {
+		dependencies := d.dependencies(i)
+		waiting[i] = len(dependencies)
+		for _, dependency := range dependencies {
+			dependents[dependency] = append(dependents[dependency], i)
+		}
+		if waiting[i] == 0 {
+			order = append(order, i)
+		}
+	}
+	for next := 0; next < len(order); next++ {
+		for _, dependent := range dependents[order[next]] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				order = append(order, dependent)
+			}
+		}
+	}
+	return order
+}
+
+// cycle returns the names of the steps that cannot run because they depend
+// on themselves, or nil when every step can run
+func (d *DAG[State]) cycle() []string {
+	order := d.order()
+	if len(order) == len(d.steps) {
+		return nil
+	}
+	var cycle []string
+	for i, step := range d.steps {
+		if !slices.Contains(order, i) {
+			cycle = append(cycle, step.name)
+		}
+	}
+	return cycle
+}
+
+// Run implements the Workflow interface: it runs the steps and returns
+// ActionSuccess once all of them are done. A step returning ActionFailure,
+// ActionCancelled or ActionShutdown fails: no further steps start, the
+// running ones finish, and the DAG returns the action of the failed step.
+// Other actions count as done.
+func (d *DAG[State]) Run(state *State) Action {
+	return d.runLocked(state, nil, &sync.Mutex{})
+}
+
+// runIn runs the DAG as a step of a flow run. The DAG stops starting steps
+// once the run is cancelled or its retry budget is spent.
+func (d *DAG[State]) runIn(state *State, run *flowRun) Action {
+	return d.runLocked(state, run, &sync.Mutex{})
+}
+
+// dagResult is the action a step of a DAG ended with
+type dagResult struct {
+	step   int
+	action Action
+}
+
+// runLocked runs the steps, guarding the state with lock; a DAG nested in
+// another shares its lock
+func (d *DAG[State]) runLocked(state *State, run *flowRun, lock sync.Locker) Action {
+	waiting := make([]int, len(d.steps))
+	dependents := make([][]int, len(d.steps))
+	var ready []int
+	for i := range d.steps {
+		dependencies := d.dependencies(i)
+		waiting[i] = len(dependencies)
+		for _, dependency := range dependencies {
+			dependents[dependency] = append(dependents[dependency], i)
+		}
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	results := make(chan dagResult, len(d.steps))
+	running := 0
+	var failure Action
+	for {
+		for len(ready) > 0 && (d.maxParallel == 0 || running < d.maxParallel) && failure == "" && !d.stopped(run) {
+			step := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- dagResult{step: step, action: d.runStep(d.steps[step].workflow, state, run, lock)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		result := <-results
+		running--
+		if failed(result.action) {
+			if failure == "" {
+				failure = result.action
+			}
+			continue
+		}
+		for _, dependent := range dependents[result.step] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	switch {
+	case run.cancelled():
+		return ActionCancelled
+	case failure != "":
+		return failure
+	case run != nil && run.exhausted():
+		return ActionFailure
+	}
+	return ActionSuccess
+}
+
+// runStep runs workflow as a step of the DAG. Nodes and nested DAGs take the
+// lock while they use the state; other workflows hold it for their whole run.
+func (d *DAG[State]) runStep(workflow Workflow[State], state *State, run *flowRun, lock sync.Locker) Action {
+	if locked, ok := workflow.(lockedRunner[State]); ok {
+		return locked.runLocked(state, run, lock)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return runStep(workflow, state, run)
+}
+
+// stopped reports whether the DAG should start no further steps of run
+func (d *DAG[State]) stopped(run *flowRun) bool {
+	return run != nil && (run.cancelled() || run.exhausted())
+}
+
+// failed reports whether a step that returned action failed
+func failed(action Action) bool {
+	return action == ActionFailure || action == ActionCancelled || action == ActionShutdown
+}
+
+// GetSuccessor implements the Workflow interface - returns the successor workflow for a given action
+func (d *DAG[State]) GetSuccessor(action Action) Workflow[State] {
+	return d.successors[action]
+}
+
+// AddSuccessor implements the Workflow interface - connects a successor workflow for each of the actions,
+// or for ActionSuccess when there are none
+func (d *DAG[State]) AddSuccessor(successor Workflow[State], action ...Action) Workflow[State] {
+	if successor == nil {
+		return successor
+	}
+	if len(action) == 0 {
+		action = append(action, ActionSuccess)
+	}
+	for _, a := range action {
+		d.successors[a] = successor
+	}
+	return successor
+}
+
+// successorMap implements router
+func (d *DAG[State]) successorMap() map[Action]Workflow[State] {
+	return d.successors
+}
+
+// Name identifies the DAG in step events
+func (d *DAG[State]) Name() string {
+	return "DAG"
+}
+
+// Validate checks that the inputs no step writes are in keys, the keys of the
+// state the DAG starts with
+func (d *DAG[State]) Validate(keys ...string) error {
+	var errs []error
+	for _, step := range d.steps {
+		for _, key := range step.inputs {
+			if _, ok := d.producers[key]; !ok && !slices.Contains(keys, key) {
+				errs = append(errs, fmt.Errorf("step %s reads %s, which no step writes", step.name, key))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lockedRunner is implemented by the workflows of this package that release
+// the state lock of a DAG while their items run
+type lockedRunner[State any] interface {
+	runLocked(state *State, run *flowRun, lock sync.Locker) Action
+}
+
+// noLock is the lock of workflows that run on their own
+type noLock struct{}
+
+func (noLock) Lock()   {}
+func (noLock) Unlock() {}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dagNode sums its inputs into its output. Nodes sharing a barrier wait in
+// Exec until all of them run, and fail if they do not run at the same time.
+type dagNode struct {
+	inputs  []string
+	output  string
+	action  Action
+	barrier *sync.WaitGroup
+}
+
+func (n *dagNode) Prep(state *map[string]any) []int {
+	if len(n.inputs) == 0 {
+		return []int{1}
+	}
+	var items []int
+	for _, key := range n.inputs {
+		items = append(items, (*state)[key].(int))
+	}
+	return items
+}
+
+func (n *dagNode) Exec(item int) (int, error) {
+	if n.barrier == nil {
+		return item, nil
+	}
+	n.barrier.Done()
+	met := make(chan struct{})
+	go func() {
+		n.barrier.Wait()
+		close(met)
+	}()
+	select {
+	case <-met:
+		return item, nil
+	case <-time.After(time.Second):
+		return 0, Terminal(errors.New("ran alone"))
+	}
+}
+
+func (n *dagNode) Post(state *map[string]any, prepResults []int, execResults ...int) Action {
+	sum := 0
+	for _, result := range execResults {
+		sum += result
+	}
+	if n.output != "" {
+		(*state)[n.output] = sum
+	}
+	if n.action == "" {
+		return ActionSuccess
+	}
+	return n.action
+}
+
+func (n *dagNode) ExecFallback(err error) int {
+	return -100
+}
+
+// addDAGNode adds a node reading inputs and writing output to dag
+func addDAGNode(t *testing.T, dag *DAG[map[string]any], name string, node *dagNode) {
+	t.Helper()
+	var outputs []string
+	if node.output != "" {
+		outputs = append(outputs, node.output)
+	}
+	if err := dag.Add(name, NewNode[map[string]any, int, int](node, 0, 2), node.inputs, outputs); err != nil {
+		t.Fatalf("Add(%s) error = %v", name, err)
+	}
+}
+
+func TestDAG_Run(t *testing.T) {
+	barrier := &sync.WaitGroup{}
+	barrier.Add(3)
+	dag := NewDAG[map[string]any]()
+	addDAGNode(t, dag, "total", &dagNode{inputs: []string{"title", "dates", "people"}, output: "total"})
+	addDAGNode(t, dag, "title", &dagNode{output: "title", barrier: barrier})
+	addDAGNode(t, dag, "dates", &dagNode{output: "dates", barrier: barrier})
+	addDAGNode(t, dag, "people", &dagNode{inputs: []string{"text"}, output: "people", barrier: barrier})
+	addDAGNode(t, dag, "report", &dagNode{inputs: []string{"total", "title"}, output: "report"})
+
+	wantLevels := [][]string{{"title", "dates", "people"}, {"total"}, {"report"}}
+	if levels := dag.Levels(); !reflect.DeepEqual(levels, wantLevels) {
+		t.Errorf("Levels() = %v, want %v", levels, wantLevels)
+	}
+
+	state := map[string]any{"text": 5}
+	if action := dag.Run(&state); action != ActionSuccess {
+		t.Fatalf("Run() = %s, want %s", action, ActionSuccess)
+	}
+	// people is 5, title and dates are 1 each
+	if state["total"] != 7 || state["report"] != 8 {
+		t.Errorf("state = %v, want total 7 and report 8", state)
+	}
+}
+
+func TestDAG_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		outputs []string
+		wantErr bool
+	}{
+		{name: "new", inputs: []string{"b"}, outputs: []string{"c"}},
+		{name: "extract", outputs: []string{"d"}, wantErr: true},
+		{name: "other", outputs: []string{"b"}, wantErr: true},
+		{name: "self", inputs: []string{"e"}, outputs: []string{"e"}, wantErr: true},
+		{name: "back", inputs: []string{"b"}, outputs: []string{"a"}, wantErr: true},
+		{name: "", outputs: []string{"f"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dag := NewDAG[map[string]any]()
+			addDAGNode(t, dag, "extract", &dagNode{inputs: []string{"a"}, output: "b"})
+			err := dag.Add(tt.name, NewNode[map[string]any, int, int](&dagNode{}, 0, 1), tt.inputs, tt.outputs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			wantSteps := 2
+			if tt.wantErr {
+				wantSteps = 1
+			}
+			if len(dag.steps) != wantSteps || len(dag.producers) != wantSteps {
+				t.Errorf("DAG has %d steps and %d producers, want %d", len(dag.steps), len(dag.producers), wantSteps)
+			}
+		})
+	}
+}
+
+func TestDAG_Failure(t *testing.T) {
+	dag := NewDAG[map[string]any]()
+	addDAGNode(t, dag, "fetch", &dagNode{output: "page", action: ActionFailure})
+	addDAGNode(t, dag, "parse", &dagNode{inputs: []string{"page"}, output: "fields"})
+	dag.SetMaxParallel(1)
+
+	state := map[string]any{}
+	if action := dag.Run(&state); action != ActionFailure {
+		t.Errorf("Run() = %s, want %s", action, ActionFailure)
+	}
+	if _, ok := state["fields"]; ok {
+		t.Errorf("step after a failed one ran: %v", state)
+	}
+}
+
+func TestDAG_InFlow(t *testing.T) {
+	dag := NewDAG[map[string]any]()
+	addDAGNode(t, dag, "a", &dagNode{output: "a"})
+	addDAGNode(t, dag, "b", &dagNode{inputs: []string{"a", "seed"}, output: "b"})
+	if err := dag.Validate(); err == nil {
+		t.Error("Validate() = nil, want an error for seed")
+	}
+	if err := dag.Validate("seed"); err != nil {
+		t.Errorf("Validate(seed) error = %v", err)
+	}
+
+	final := &dagNode{inputs: []string{"b"}, output: "final"}
+	dag.AddSuccessor(NewNode[map[string]any, int, int](final, 0, 1))
+	flow := NewFlow[map[string]any](dag)
+	var steps []string
+	state := map[string]any{"seed": 2}
+	action := flow.RunObserved(&state, func(event StepEvent) { steps = append(steps, event.Name) })
+	if action != ActionSuccess || state["final"] != 3 {
+		t.Errorf("RunObserved() = %s with %v, want success with final 3", action, state)
+	}
+	if want := []string{"DAG", "dagNode"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+}
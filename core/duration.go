@@ -0,0 +1,73 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that config files can write as a string such as
+// "30s", "1m30s" or "500ms". Plain numbers are read as nanoseconds, as
+// time.Duration is by encoding/json.
+type Duration time.Duration
+
+// Std returns the duration as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats the duration like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON writes the duration as a string such as "30s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a duration string or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+// MarshalYAML writes the duration as a string such as "30s"
+func (d Duration) MarshalYAML() (any, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML reads a duration string or a number of nanoseconds
+func (d *Duration) UnmarshalYAML(unmarshal func(any) error) error {
+	var value any
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+// set parses a decoded JSON or YAML value
+func (d *Duration) set(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*d = 0
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: use a number with a unit such as \"30s\", \"5m\" or \"500ms\"", v)
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(v)
+	case int:
+		*d = Duration(v)
+	case uint64:
+		*d = Duration(v)
+	default:
+		return fmt.Errorf("invalid duration %v: expected a string such as \"30s\"", value)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{`"30s"`, 30 * time.Second, false},
+		{`"1m30s"`, 90 * time.Second, false},
+		{`"500ms"`, 500 * time.Millisecond, false},
+		{`1500000000`, 1500 * time.Millisecond, false},
+		{`null`, 0, false},
+		{`"30"`, 0, true},
+		{`"soon"`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var fromJSON struct {
+				Timeout Duration `json:"timeout"`
+			}
+			err := json.Unmarshal([]byte(`{"timeout": `+tt.input+`}`), &fromJSON)
+			if (err != nil) != tt.wantErr || fromJSON.Timeout.Std() != tt.want {
+				t.Errorf("JSON %s = %v, %v; want %v", tt.input, fromJSON.Timeout, err, tt.want)
+			}
+
+			var fromYAML struct {
+				Timeout Duration `yaml:"timeout"`
+			}
+			err = yaml.Unmarshal([]byte("timeout: "+tt.input), &fromYAML)
+			if (err != nil) != tt.wantErr || fromYAML.Timeout.Std() != tt.want {
+				t.Errorf("YAML %s = %v, %v; want %v", tt.input, fromYAML.Timeout, err, tt.want)
+			}
+		})
+	}
+
+	data, err := json.Marshal(struct {
+		Timeout Duration `json:"timeout"`
+	}{Duration(90 * time.Second)})
+	if err != nil || string(data) != `{"timeout":"1m30s"}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+	out, err := yaml.Marshal(map[string]Duration{"timeout": Duration(time.Second)})
+	if err != nil || string(out) != "timeout: 1s\n" {
+		t.Errorf("MarshalYAML = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RetryableError marks an Exec error as transient, e.g. a timeout or a rate
+// limit, so the node retries the item up to its maxRetries
+type RetryableError struct {
+	Err error
+}
+
+// Error implements error
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the marked error
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// TerminalError marks an Exec error that retrying cannot fix, e.g. bad input
+// or a failed validation, so the node goes to ExecFallback without retrying
+type TerminalError struct {
+	Err error
+}
+
+// Error implements error
+func (e *TerminalError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the marked error
+func (e *TerminalError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error an Exec that panicked fails with. Panics are not
+// retried, so the item goes to ExecFallback with this error.
+type PanicError struct {
+	Value any    // The value passed to panic
+	Stack []byte // The stack of the panicking goroutine
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Retryable marks err as transient; a nil err stays nil
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+// Terminal marks err as not worth retrying; a nil err stays nil
+func Terminal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TerminalError{Err: err}
+}
+
+// IsRetryable reports whether err may be retried: false when its chain holds
+// a TerminalError or a PanicError, true otherwise
+func IsRetryable(err error) bool {
+	var terminal *TerminalError
+	var panicked *PanicError
+	return !errors.As(err, &terminal) && !errors.As(err, &panicked)
+}
+
+// isMarkedRetryable reports whether err's chain holds a RetryableError and no
+// TerminalError
+func isMarkedRetryable(err error) bool {
+	var retryable *RetryableError
+	return IsRetryable(err) && errors.As(err, &retryable)
+}
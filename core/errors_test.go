@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// failingNode fails every Exec with err and keeps the error ExecFallback got
+type failingNode struct {
+	benchNode
+	err      error
+	calls    int
+	fallback error
+}
+
+func (n *failingNode) Exec(item int) (int, error) {
+	n.calls++
+	return 0, n.err
+}
+
+func (n *failingNode) ExecFallback(err error) int {
+	n.fallback = err
+	return 0
+}
+
+func TestNode_RetryableErrors(t *testing.T) {
+	invalid := errors.New("invalid input")
+	tests := []struct {
+		name       string
+		err        error
+		markedOnly bool
+		wantCalls  int
+	}{
+		{"unmarked", invalid, false, 4},
+		{"terminal", Terminal(invalid), false, 1},
+		{"wrapped terminal", fmt.Errorf("parse: %w", Terminal(invalid)), false, 1},
+		{"retryable", Retryable(invalid), false, 4},
+		{"unmarked, marked only", invalid, true, 1},
+		{"retryable, marked only", Retryable(invalid), true, 4},
+		{"terminal inside retryable", Retryable(Terminal(invalid)), true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := &failingNode{benchNode: benchNode{action: ActionSuccess}, err: tt.err}
+			node := NewNode[benchState, int, int](failing, 3, 1)
+			node.SetRetryMarkedOnly(tt.markedOnly)
+			node.Run(&benchState{Items: benchItems(1)})
+			if failing.calls != tt.wantCalls {
+				t.Errorf("Exec called %d times, want %d", failing.calls, tt.wantCalls)
+			}
+			if !errors.Is(failing.fallback, invalid) {
+				t.Errorf("ExecFallback got %v, want %v", failing.fallback, invalid)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if IsRetryable(errors.Join(errors.New("timeout"), Terminal(errors.New("bad input")))) {
+		t.Error("an error joined with a terminal error is retryable")
+	}
+	if !IsRetryable(errors.New("timeout")) || Terminal(nil) != nil || Retryable(nil) != nil {
+		t.Error("unexpected marks")
+	}
+	var terminal *TerminalError
+	if err := fmt.Errorf("step: %w", Terminal(errors.New("bad input"))); !errors.As(err, &terminal) || err.Error() != "step: bad input" {
+		t.Errorf("Terminal() = %v", err)
+	}
+}
+
+// panickingNode panics in Exec on odd items and keeps the errors ExecFallback got
+type panickingNode struct {
+	benchNode
+	calls     atomic.Int32
+	mu        sync.Mutex
+	fallbacks []error
+}
+
+func (n *panickingNode) Exec(item int) (int, error) {
+	n.calls.Add(1)
+	if item%2 == 1 {
+		panic(fmt.Sprintf("item %d", item))
+	}
+	return item, nil
+}
+
+func (n *panickingNode) ExecFallback(err error) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.fallbacks = append(n.fallbacks, err)
+	return -1
+}
+
+func TestNode_PanicRecovery(t *testing.T) {
+	panicking := &panickingNode{benchNode: benchNode{action: ActionSuccess}}
+	flow := NewFlow[benchState](NewNode[benchState, int, int](panicking, 3, 4))
+	state := &benchState{Items: benchItems(10)}
+	action, report := flow.RunWithReport(state, nil)
+
+	if action != ActionSuccess || state.Sum != 0+2+4+6+8-5 {
+		t.Errorf("RunWithReport() = %v with sum %d", action, state.Sum)
+	}
+	if calls := panicking.calls.Load(); calls != 10 {
+		t.Errorf("Exec called %d times, want panics not retried", calls)
+	}
+	if len(panicking.fallbacks) != 5 {
+		t.Fatalf("ExecFallback got %d errors, want 5", len(panicking.fallbacks))
+	}
+	var panicked *PanicError
+	if !errors.As(panicking.fallbacks[0], &panicked) || !strings.HasPrefix(panicked.Error(), "panic: item ") {
+		t.Errorf("ExecFallback got %v, want a PanicError", panicking.fallbacks[0])
+	}
+	if len(report.Panics) != 5 {
+		t.Fatalf("report has %d panics, want 5", len(report.Panics))
+	}
+	for _, p := range report.Panics {
+		if p.Node != "panickingNode" || !strings.HasPrefix(p.Value, "item ") || !strings.Contains(p.Stack, "panickingNode).Exec") {
+			t.Errorf("panic report = %+v", p)
+		}
+	}
+
+	// A node run outside a flow recovers too
+	panicking.fallbacks = nil
+	NewNode[benchState, int, int](panicking, 0, 1).Run(&benchState{Items: benchItems(2)})
+	if len(panicking.fallbacks) != 1 {
+		t.Errorf("ExecFallback got %d errors, want 1", len(panicking.fallbacks))
+	}
+
+	closed := errors.New("closed")
+	if wrapped := (&PanicError{Value: closed}); !errors.Is(wrapped, closed) || IsRetryable(wrapped) {
+		t.Errorf("PanicError of an error = %v", wrapped)
+	}
+}
@@ -0,0 +1,26 @@
+package core
+
+// ItemFailure describes an item whose Exec failed for good
+type ItemFailure[PrepResult any] struct {
+	Item     PrepResult // The item from Prep
+	Attempts int        // Times Exec ran on the item; 0 when it did not run, e.g. in a cancelled run
+	Errors   []error    // Error of each attempt in order, or the reason the item did not run
+	Err      error      // The error that ended the item, the last of Errors, as ExecFallback receives it
+}
+
+// ItemFallback may be implemented by a BaseNode whose fallback needs more
+// than the last error, e.g. to produce a placeholder that names the failed
+// item and what each attempt ran into. Nodes call ExecFallbackItem instead of
+// ExecFallback.
+type ItemFallback[PrepResult any, ExecResults any] interface {
+	ExecFallbackItem(failure ItemFailure[PrepResult]) ExecResults
+}
+
+// fallback returns the fallback result of an item that failed with errs
+func (n *Node[State, PrepResult, ExecResults]) fallback(item PrepResult, attempts int, errs []error) ExecResults {
+	err := errs[len(errs)-1]
+	if fallback, ok := n.node.(ItemFallback[PrepResult, ExecResults]); ok {
+		return fallback.ExecFallbackItem(ItemFailure[PrepResult]{Item: item, Attempts: attempts, Errors: errs, Err: err})
+	}
+	return n.node.ExecFallback(err)
+}
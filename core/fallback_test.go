@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// flakyItemNode fails item i with "attempt n of item i", as terminal errors for
+// negative items, and records the failures its fallback receives
+type flakyItemNode struct {
+	benchNode
+	mu       sync.Mutex
+	attempts map[int]int
+	failures map[int]ItemFailure[int]
+}
+
+func (n *flakyItemNode) Exec(item int) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.attempts[item]++
+	err := fmt.Errorf("attempt %d of item %d", n.attempts[item], item)
+	if item < 0 {
+		return 0, Terminal(err)
+	}
+	return 0, err
+}
+
+func (n *flakyItemNode) ExecFallbackItem(failure ItemFailure[int]) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.failures[failure.Item] = failure
+	return -1
+}
+
+func newFlakyItemNode() *flakyItemNode {
+	return &flakyItemNode{benchNode: benchNode{action: ActionSuccess}, attempts: map[int]int{}, failures: map[int]ItemFailure[int]{}}
+}
+
+func TestNode_ExecFallbackItem(t *testing.T) {
+	node := newFlakyItemNode()
+	state := &benchState{Items: []int{3, -5}}
+	NewNode[benchState, int, int](node, 2, 2).Run(state)
+
+	tests := []struct {
+		item       int
+		wantErrors []string
+	}{
+		{3, []string{"attempt 1 of item 3", "attempt 2 of item 3", "attempt 3 of item 3"}},
+		{-5, []string{"attempt 1 of item -5"}},
+	}
+	for _, tt := range tests {
+		failure := node.failures[tt.item]
+		if failure.Item != tt.item || failure.Attempts != len(tt.wantErrors) || len(failure.Errors) != len(tt.wantErrors) {
+			t.Fatalf("failure of item %d = %+v, want %d attempts", tt.item, failure, len(tt.wantErrors))
+		}
+		for i, err := range failure.Errors {
+			if err.Error() != tt.wantErrors[i] {
+				t.Errorf("error %d of item %d = %v, want %s", i, tt.item, err, tt.wantErrors[i])
+			}
+		}
+		if failure.Err != failure.Errors[len(failure.Errors)-1] {
+			t.Errorf("Err of item %d = %v, want the last error", tt.item, failure.Err)
+		}
+	}
+	if state.Sum != -2 {
+		t.Errorf("sum = %d, want the fallback results", state.Sum)
+	}
+
+	// Items of a cancelled run fail without running
+	node = newFlakyItemNode()
+	flow := NewFlow[benchState](NewNode[benchState, int, int](node, 2, 1))
+	ctx, cancel := context.WithCancel(context.Background())
+	flow.SetStepHook(func(StepStart, *benchState) bool {
+		cancel()
+		return true
+	})
+	flow.RunContext(ctx, &benchState{Items: []int{7}}, nil)
+	if failure := node.failures[7]; failure.Attempts != 0 || len(failure.Errors) != 1 || !errors.Is(failure.Err, context.Canceled) {
+		t.Errorf("failure of a cancelled item = %+v, want no attempts and context.Canceled", failure)
+	}
+}
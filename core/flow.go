@@ -1,9 +1,31 @@
 package core
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/inflight"
+)
+
 // Flow represents a workflow subgraph that implements Workflow interface
 type Flow[State any] struct {
-	startNode  Workflow[State]
-	successors map[Action]Workflow[State]
+	startNode    Workflow[State]
+	successors   map[Action]Workflow[State]
+	retryBudget  int
+	errorHandler ErrorHandler[State]
+	actions      map[Action]bool // Declared actions; nil accepts any action
+	progress     ProgressReporter
+	config       func() any // Snapshot of the configuration runs start with
+	stateDiff    bool       // Record how each step changes the state
+	labels       Labels     // Labels of the flow's runs
+	stepHook     StepHook[State]
+
+	// Runs in flight, which Shutdown waits for, and what it flushes after them
+	runs     inflight.Tracker
+	flushers []func(ctx context.Context) error
 }
 
 // NewFlow creates a new flow with the given initial state
@@ -17,40 +39,235 @@ func NewFlow[State any](startNode Workflow[State]) *Flow[State] {
 
 // Run implements the Workflow interface - executes the flow and returns an action
 func (f *Flow[State]) Run(state *State) Action {
-	// Create working state copy to avoid modifying input state
+	return f.RunObserved(state, nil)
+}
+
+// RunObserved executes the flow like Run and calls observe after each step. Steps
+// of nested flows are reported as a single step of the enclosing flow. Because
+// the observer is passed per call, a flow shared between concurrent runs can
+// report each run separately.
+func (f *Flow[State]) RunObserved(state *State, observe StepObserver) Action {
+	if f.startNode == nil {
+		return ActionFailure
+	}
+	if !f.runs.Start() {
+		return ActionShutdown
+	}
+	defer f.runs.Done()
+	return f.runFrom(state, f.startNode, 1, observe, f.newRun(), false)
+}
+
+// RunWithReport executes the flow like RunObserved and also returns how the
+// run spent its retries and the configuration it started with
+func (f *Flow[State]) RunWithReport(state *State, observe StepObserver) (Action, RunReport) {
+	run := f.startRun()
+	if f.startNode == nil {
+		return ActionFailure, run.snapshot()
+	}
+	if !f.runs.Start() {
+		return ActionShutdown, run.snapshot()
+	}
+	defer f.runs.Done()
+	action := f.runFrom(state, f.startNode, 1, observe, run, false)
+	return action, run.snapshot()
+}
+
+// OnShutdown adds a function Shutdown calls after the runs in flight have
+// stopped, e.g. to flush checkpoints or an audit log. Functions are called in
+// the order they were added.
+func (f *Flow[State]) OnShutdown(flush func(ctx context.Context) error) {
+	f.flushers = append(f.flushers, flush)
+}
+
+// Shutdown stops the flow gracefully. New runs end at once with
+// ActionShutdown; runs in flight finish the step they are in, which may be a
+// nested flow, and then end with ActionShutdown, so a checkpoint of their
+// steps can be resumed later. Shutdown waits for them until ctx is done, then
+// calls the OnShutdown functions; the errors of both are joined.
+func (f *Flow[State]) Shutdown(ctx context.Context) error {
+	err := f.runs.Shutdown(ctx)
+	for _, flush := range f.flushers {
+		err = errors.Join(err, flush(ctx))
+	}
+	return err
+}
+
+// SetRetryBudget limits the retries all nodes of a run may spend together,
+// including the nodes of nested flows; 0, the default, sets no limit. Once the
+// budget is spent, failing items go straight to ExecFallback, and after the
+// step the run ends in the error handler. The budget of a nested flow is not
+// used; the flow that starts the run sets it.
+func (f *Flow[State]) SetRetryBudget(retries int) {
+	if retries < 0 {
+		retries = 0
+	}
+	f.retryBudget = retries
+}
+
+// SetErrorHandler sets the handler a run ends in when its retry budget is
+// exhausted. Without one, such a run ends with ActionFailure.
+func (f *Flow[State]) SetErrorHandler(handler ErrorHandler[State]) {
+	f.errorHandler = handler
+}
+
+// newRun returns the accounting of a run, or nil when there is nothing to
+// account or report
+func (f *Flow[State]) newRun() *flowRun {
+	if f.retryBudget == 0 && f.progress == nil && f.config == nil && !f.stateDiff && len(f.labels) == 0 && f.stepHook == nil {
+		return nil
+	}
+	return f.startRun()
+}
+
+// startRun returns the accounting of a run, with a snapshot of the
+// configuration it starts with
+func (f *Flow[State]) startRun() *flowRun {
+	run := &flowRun{report: RunReport{RetryBudget: f.retryBudget, Labels: f.labels}, progress: f.progress, stateDiff: f.stateDiff, labels: f.labels}
+	if f.config != nil {
+		run.report.Config, run.report.ConfigError = snapshotConfig(f.config)
+	}
+	if f.stepHook != nil {
+		run.stepHook = f.stepHook
+	}
+	return run
+}
 
+// runIn runs the flow as a nested step of an enclosing run, which handles an
+// exhausted budget itself
+func (f *Flow[State]) runIn(state *State, run *flowRun) Action {
+	if f.startNode == nil {
+		return ActionFailure
+	}
+	return f.runFrom(state, f.startNode, 1, nil, run, true)
+}
+
+// ResumeObserved continues a run that stopped after the steps whose actions are
+// given in order, e.g. from the step events of a checkpoint. It follows those
+// actions from the start node without running the steps again, then runs the
+// rest of the flow like RunObserved, numbering steps after the skipped ones.
+// When the actions lead past the last step, the run had finished and the last
+// action is returned.
+func (f *Flow[State]) ResumeObserved(state *State, actions []Action, observe StepObserver) (Action, error) {
+	currentWorkflow, action, err := f.resumeAt(actions)
+	if currentWorkflow == nil {
+		return action, err
+	}
+	if !f.runs.Start() {
+		return ActionShutdown, nil
+	}
+	defer f.runs.Done()
+	return f.runFrom(state, currentWorkflow, len(actions)+1, observe, f.newRun(), false), nil
+}
+
+// resumeAt follows actions from the start node and returns the workflow to
+// resume with, or nil with the last action when the run had finished
+func (f *Flow[State]) resumeAt(actions []Action) (Workflow[State], Action, error) {
+	if f.startNode == nil {
+		return nil, ActionFailure, fmt.Errorf("flow has no start node")
+	}
 	currentWorkflow := f.startNode
+	for i, action := range actions {
+		if currentWorkflow == nil {
+			return nil, "", fmt.Errorf("cannot resume after step %d: the flow ended at step %d", len(actions), i)
+		}
+		currentWorkflow = f.next(currentWorkflow, action)
+	}
 	if currentWorkflow == nil {
-		return ActionFailure
+		return nil, actions[len(actions)-1], nil
 	}
+	return currentWorkflow, "", nil
+}
+
+// runFrom executes workflows in sequence from currentWorkflow, numbering steps
+// from step. A nested flow stops after a step that exhausted the retry budget
+// and leaves the error handling to the flow that started the run.
+func (f *Flow[State]) runFrom(state *State, currentWorkflow Workflow[State], step int, observe StepObserver, run *flowRun, nested bool) Action {
 	var finalAction Action = ActionSuccess
+	diffing := !nested && run.diffsState()
+	var before stateSnapshot
+	if diffing {
+		before = takeStateSnapshot(state)
+	}
 
 	// Execute workflows in sequence following action-based transitions
-	for currentWorkflow != nil {
-		// Run the current workflow with working state
-		action := currentWorkflow.Run(state)
+	for ; currentWorkflow != nil; step++ {
+		if run.cancelled() {
+			return ActionCancelled
+		}
+		if !beforeStep(run, state, currentWorkflow, step, nested, f.labels) {
+			return ActionAborted
+		}
+		started := time.Now()
+		if !nested && run.reportsProgress() {
+			run.startStep(step)
+		}
+		action := runStep(currentWorkflow, state, run)
 		finalAction = action
-
-		// Use GetSuccessor method for proper action-based routing
-		nextWorkflow := currentWorkflow.GetSuccessor(action)
-
-		// If no successor found in current workflow, check flow-level successors
-		if nextWorkflow == nil {
-			nextWorkflow = f.GetSuccessor(action)
+		if diffing {
+			after := takeStateSnapshot(state)
+			run.recordDiff(diffStates(step, WorkflowName(currentWorkflow), before, after))
+			before = after
+		}
+		if observe != nil {
+			observe(StepEvent{
+				Step:     step,
+				Name:     WorkflowName(currentWorkflow),
+				Action:   action,
+				Duration: time.Since(started),
+				Labels:   f.labels.Merge(stepLabels(currentWorkflow, state)),
+			})
+		}
+		if run != nil && run.exhausted() {
+			if nested {
+				return ActionFailure
+			}
+			if f.errorHandler == nil {
+				return ActionFailure
+			}
+			return f.errorHandler(state, run.snapshot())
+		}
+		if run.cancelled() {
+			return ActionCancelled
+		}
+		if run.aborted() {
+			return ActionAborted
+		}
+		if !f.declared(action) {
+			return f.undeclared(state, run, nested, WorkflowName(currentWorkflow), action)
+		}
+		currentWorkflow = f.next(currentWorkflow, action)
+		if currentWorkflow != nil && !nested && f.runs.Stopping() {
+			return ActionShutdown
 		}
-
-		// Update current workflow for next iteration
-		currentWorkflow = nextWorkflow
 	}
 	return finalAction
 }
 
+// next returns the workflow that follows current after action. An exact edge
+// of current comes first, then an exact edge of the flow, then the ActionAny
+// edge of current. The flow's own ActionAny edge belongs to the enclosing flow
+// and is not used here, so runs of the flow can still end.
+func (f *Flow[State]) next(current Workflow[State], action Action) Workflow[State] {
+	// Use GetSuccessor method for proper action-based routing
+	nextWorkflow := current.GetSuccessor(action)
+
+	// If no successor found in current workflow, check flow-level successors
+	if nextWorkflow == nil {
+		nextWorkflow = f.GetSuccessor(action)
+	}
+	if nextWorkflow == nil && action != ActionAny {
+		nextWorkflow = current.GetSuccessor(ActionAny)
+	}
+	return nextWorkflow
+}
+
 // GetSuccessor implements the Workflow interface - returns the successor workflow for a given action
 func (f *Flow[State]) GetSuccessor(action Action) Workflow[State] {
 	return f.successors[action]
 }
 
-// AddSuccessor implements the Workflow interface - connects a successor workflow for a specific action
+// AddSuccessor implements the Workflow interface - connects a successor workflow for each of the actions,
+// or for ActionSuccess when there are none
 func (f *Flow[State]) AddSuccessor(successor Workflow[State], action ...Action) Workflow[State] {
 	if f.successors == nil {
 		f.successors = make(map[Action]Workflow[State])
@@ -61,6 +278,54 @@ func (f *Flow[State]) AddSuccessor(successor Workflow[State], action ...Action)
 	if len(action) == 0 {
 		action = append(action, ActionSuccess)
 	}
-	f.successors[action[0]] = successor
+	for _, a := range action {
+		f.successors[a] = successor
+	}
 	return successor
 }
+
+// AddSuccessors connects the successor workflow of each action; nil
+// successors are skipped
+func (f *Flow[State]) AddSuccessors(successors map[Action]Workflow[State]) {
+	for action, successor := range successors {
+		f.AddSuccessor(successor, action)
+	}
+}
+
+// Name identifies the flow in step events
+func (f *Flow[State]) Name() string {
+	return "Flow"
+}
+
+// StepEvent describes a workflow that finished running as a step of a Flow
+type StepEvent struct {
+	Step     int           `json:"step"`
+	Name     string        `json:"name"`
+	Action   Action        `json:"action"`
+	Duration time.Duration `json:"duration"`
+	Labels   Labels        `json:"labels,omitempty"` // Labels of the flow and the step, see Flow.SetLabels
+}
+
+// StepObserver is called after each step of a flow run
+type StepObserver func(event StepEvent)
+
+// WorkflowName returns the name of a workflow for logs and events: its Name
+// method if it has one, otherwise its type name without package and type parameters
+func WorkflowName[State any](workflow Workflow[State]) string {
+	if named, ok := workflow.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return typeName(workflow)
+}
+
+// typeName formats the type of v as e.g. "ChatNode" for *main.ChatNode[...]
+func typeName(v any) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+	if index := strings.Index(name, "["); index != -1 {
+		name = name[:index]
+	}
+	if index := strings.LastIndex(name, "."); index != -1 {
+		name = name[index+1:]
+	}
+	return name
+}
@@ -0,0 +1,57 @@
+package core
+
+// StepStart describes a workflow about to run as a step of a Flow
+type StepStart struct {
+	Step   int    `json:"step"`
+	Name   string `json:"name"`
+	Nested bool   `json:"nested,omitempty"` // The step belongs to a flow nested in the one that started the run
+	Labels Labels `json:"labels,omitempty"` // Labels of the flow and the step, see Flow.SetLabels
+}
+
+// StepHook is called before each step of a flow run with the state the step
+// is about to run on, which it may change. Returning false aborts the run: it
+// ends with ActionAborted, also from within nested flows.
+type StepHook[State any] func(step StepStart, state *State) bool
+
+// SetStepHook sets the hook called before each step of the flow's runs,
+// including the steps of nested flows, e.g. to pause at breakpoints; nil
+// removes it. The hook of a nested flow is not used; the flow that starts the
+// run sets it. Steps of a DAG run in parallel and are not steps of a flow.
+func (f *Flow[State]) SetStepHook(hook StepHook[State]) {
+	f.stepHook = hook
+}
+
+// beforeStep calls the step hook of run, if any, before workflow runs as
+// step of a flow labeled labels; it reports false when the hook aborted the run
+func beforeStep[State any](run *flowRun, state *State, workflow Workflow[State], step int, nested bool, labels Labels) bool {
+	if run == nil {
+		return true
+	}
+	hook, ok := run.stepHook.(StepHook[State])
+	if !ok {
+		return true
+	}
+	start := StepStart{Step: step, Name: WorkflowName(workflow), Nested: nested, Labels: labels.Merge(stepLabels(workflow, state))}
+	if hook(start, state) {
+		return true
+	}
+	run.abort()
+	return false
+}
+
+// abort records that the step hook aborted the run
+func (r *flowRun) abort() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Aborted = true
+}
+
+// aborted reports whether the step hook aborted the run
+func (r *flowRun) aborted() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.report.Aborted
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlow_SetStepHook(t *testing.T) {
+	tests := []struct {
+		name       string
+		abortAt    int // Index of the hook call that aborts; -1 for none
+		wantAction Action
+		wantSteps  []StepStart
+		wantLast   bool
+	}{
+		{"no abort", -1, ActionDefault, []StepStart{{Step: 1, Name: "MockWorkflow"}, {Step: 2, Name: "Flow"}, {Step: 1, Name: "MockWorkflow", Nested: true}, {Step: 2, Name: "MockWorkflow", Nested: true}, {Step: 3, Name: "MockWorkflow"}}, true},
+		{"abort in nested flow", 2, ActionAborted, []StepStart{{Step: 1, Name: "MockWorkflow"}, {Step: 2, Name: "Flow"}, {Step: 1, Name: "MockWorkflow", Nested: true}}, false},
+		{"abort first step", 0, ActionAborted, []StepStart{{Step: 1, Name: "MockWorkflow"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := NewMockWorkflow[State]("first", ActionContinue)
+			inner := NewFlow[State](NewMockWorkflow[State]("inner", ActionSuccess))
+			last := NewMockWorkflow[State]("last", ActionDefault)
+			first.AddSuccessor(inner, ActionContinue)
+			inner.AddSuccessor(last)
+			// A successor on any action must not continue an aborted run
+			inner.AddSuccessor(last, ActionAny)
+			flow := NewFlow[State](first)
+
+			var steps []StepStart
+			flow.SetStepHook(func(step StepStart, state *State) bool {
+				steps = append(steps, step)
+				(*state)["hooked"] = len(steps)
+				return len(steps)-1 != tt.abortAt
+			})
+			state := State{}
+			action, report := flow.RunWithReport(&state, nil)
+
+			if action != tt.wantAction || report.Aborted != (tt.abortAt >= 0) {
+				t.Errorf("RunWithReport() = %s, aborted %v, want %s", action, report.Aborted, tt.wantAction)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("hook calls = %+v, want %+v", steps, tt.wantSteps)
+			}
+			if last.runCalled != tt.wantLast {
+				t.Errorf("last step ran = %v, want %v", last.runCalled, tt.wantLast)
+			}
+			if state["hooked"] != len(tt.wantSteps) {
+				t.Errorf("state = %v, want the hook's change", state)
+			}
+		})
+	}
+}
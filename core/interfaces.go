@@ -1,5 +1,6 @@
 package core
 
+import "iter"
 
 // BaseNode defines the core interface for all nodes in the workflow
 // This follows the three-phase execution model: Prep -> Exec -> Post
@@ -17,6 +18,30 @@ type BaseNode[State any , PrepResult any, ExecResults any] interface {
 	ExecFallback(err error) ExecResults
 }
 
+// DefaultChunkSize is how many results a ChunkPoster receives at a time unless
+// the node sets another size with SetChunkSize
+const DefaultChunkSize = 100
+
+// ChunkPoster is implemented by nodes with too many items to hold all of their
+// results at once. Their results are delivered in order, one chunk at a time,
+// to PostChunk as soon as the chunk is done; Post is called last with all the
+// prep results and no exec results, and chooses the action.
+type ChunkPoster[State any, PrepResult any, ExecResults any] interface {
+	// PostChunk handles the results of consecutive items; execResults is reused for the next chunk
+	PostChunk(state *State, prepResults []PrepResult, execResults []ExecResults)
+}
+
+// StreamPrepper may be implemented by a ChunkPoster with too many items to
+// prepare at once, e.g. the rows of a large file. PrepStream is called in place
+// of Prep, and a chunk of items is read from the stream only once the results
+// of the chunk before went to PostChunk, so the node holds no more than a
+// chunk of items and results. Post is called last with no prep or exec
+// results. The stream is read holding the state, like Prep; a cancelled run
+// stops reading it.
+type StreamPrepper[State any, PrepResult any] interface {
+	PrepStream(state *State) iter.Seq[PrepResult]
+}
+
 // Workflow represents a unit of execution that can be connected to other workflows
 // This interface is implemented by both Node and Flow to enable composition
 type Workflow[State any] interface {
@@ -26,6 +51,6 @@ type Workflow[State any] interface {
 	// GetSuccessor returns the successor workflow for a given action
 	GetSuccessor(action Action) Workflow[State]
 
-	// AddSuccessor connects a successor workflow for a specific action
+	// AddSuccessor connects a successor workflow for each of the actions
 	AddSuccessor( successor Workflow[State], action ...Action,) Workflow[State]
 }
@@ -0,0 +1,118 @@
+package core
+
+import "context"
+
+// Labels are dimensions such as the tenant, session or model of a run. Step
+// events, progress updates and the run report carry them, so multi-tenant
+// deployments can break latency and cost down by customer.
+type Labels map[string]string
+
+// Common label names
+const (
+	LabelTenant  = "tenant"
+	LabelSession = "session"
+	LabelModel   = "model"
+)
+
+// Labeler may be implemented by a BaseNode to label its steps with values
+// from the state, e.g. the tenant or session of the request. Labels is called
+// when the step starts, before Prep.
+type Labeler[State any] interface {
+	Labels(state *State) Labels
+}
+
+// Merge returns the labels with those of other added, other winning on
+// conflicts. It returns nil when both are empty and never changes l.
+func (l Labels) Merge(other Labels) Labels {
+	if len(other) == 0 {
+		return l
+	}
+	if len(l) == 0 {
+		return other
+	}
+	merged := make(Labels, len(l)+len(other))
+	for name, value := range l {
+		merged[name] = value
+	}
+	for name, value := range other {
+		merged[name] = value
+	}
+	return merged
+}
+
+// labeled is implemented by the workflows of this package, which label the
+// steps they run as
+type labeled[State any] interface {
+	stepLabels(state *State) Labels
+}
+
+// stepLabels returns the labels of workflow running as a step on state
+func stepLabels[State any](workflow Workflow[State], state *State) Labels {
+	if l, ok := workflow.(labeled[State]); ok {
+		return l.stepLabels(state)
+	}
+	return nil
+}
+
+// SetLabels sets the labels of the flow's runs, e.g. the deployment or
+// region. Step events, progress updates and the run report carry them, merged
+// with the labels of each node. Nested flows add their labels to the steps
+// they run as.
+func (f *Flow[State]) SetLabels(labels Labels) {
+	f.labels = labels
+}
+
+// stepLabels returns the labels of the flow, as a nested step
+func (f *Flow[State]) stepLabels(state *State) Labels {
+	return f.labels
+}
+
+// SetLabels sets labels the node's steps carry, e.g. the model it calls.
+// A BaseNode implementing Labeler adds labels from the state to them.
+func (n *Node[State, PrepResult, ExecResults]) SetLabels(labels Labels) {
+	n.labels = labels
+}
+
+// stepLabels returns the labels set on the node merged with those of its Labeler
+func (n *Node[State, PrepResult, ExecResults]) stepLabels(state *State) Labels {
+	if labeler, ok := n.node.(Labeler[State]); ok {
+		return n.labels.Merge(labeler.Labels(state))
+	}
+	return n.labels
+}
+
+// recordLabels records the labels node ran with
+func (r *flowRun) recordLabels(node string, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report.LabelsByNode == nil {
+		r.report.LabelsByNode = make(map[string]Labels)
+	}
+	r.report.LabelsByNode[node] = r.report.LabelsByNode[node].Merge(labels)
+}
+
+// labelsKey is the context key of the labels of a call
+type labelsKey struct{}
+
+// WithLabels returns ctx carrying labels, added to those ctx already carries.
+// Nodes implementing ContextExecutor get the labels of the run and their step
+// this way, so e.g. an llm.UsageTracker records their usage under them.
+func WithLabels(ctx context.Context, labels Labels) context.Context {
+	if len(labels) == 0 {
+		return ctx
+	}
+	merged := make(Labels, len(labels))
+	for name, value := range LabelsFrom(ctx) {
+		merged[name] = value
+	}
+	for name, value := range labels {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, labelsKey{}, merged)
+}
+
+// LabelsFrom returns the labels ctx carries; the map must not be changed
+func LabelsFrom(ctx context.Context) Labels {
+	labels, _ := ctx.Value(labelsKey{}).(Labels)
+	return labels
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// tenantNode is a benchNode labeling its steps with the tenant of the state
+type tenantNode struct {
+	benchNode
+}
+
+func (n *tenantNode) Labels(state *benchState) Labels {
+	return Labels{LabelTenant: fmt.Sprintf("tenant-%d", len(state.Items))}
+}
+
+func TestFlow_Labels(t *testing.T) {
+	first := NewNode[benchState, int, int](&tenantNode{benchNode{action: actionDone}}, 0, 1)
+	first.SetLabels(Labels{LabelModel: "gpt-4o", LabelTenant: "default"})
+	inner := NewFlow[benchState](NewNode[benchState, int, int](&benchNode{action: ActionSuccess}, 0, 1))
+	inner.SetLabels(Labels{"stage": "inner"})
+	first.AddSuccessor(inner, actionDone)
+
+	flow := NewFlow[benchState](first)
+	flow.SetLabels(Labels{"region": "eu"})
+	var updates []Progress
+	flow.SetProgressReporter(func(progress Progress) { updates = append(updates, progress) })
+
+	var events []StepEvent
+	action, report := flow.RunWithReport(&benchState{Items: benchItems(2)}, func(event StepEvent) {
+		events = append(events, event)
+	})
+	if action != ActionSuccess || len(events) != 2 {
+		t.Fatalf("RunWithReport() = %v with events %+v", action, events)
+	}
+
+	want := []Labels{
+		{"region": "eu", LabelModel: "gpt-4o", LabelTenant: "tenant-2"},
+		{"region": "eu", "stage": "inner"},
+	}
+	for i, event := range events {
+		if !reflect.DeepEqual(event.Labels, want[i]) {
+			t.Errorf("event %d labels = %v, want %v", i, event.Labels, want[i])
+		}
+	}
+	if !reflect.DeepEqual(updates[0].Labels, want[0]) || !reflect.DeepEqual(updates[2].Labels, want[0]) {
+		t.Errorf("progress labels = %v and %v, want %v", updates[0].Labels, updates[2].Labels, want[0])
+	}
+	if got := updates[len(updates)-1].Labels; !reflect.DeepEqual(got, Labels{"region": "eu"}) {
+		t.Errorf("progress labels of an unlabeled node = %v", got)
+	}
+
+	if !reflect.DeepEqual(report.Labels, Labels{"region": "eu"}) {
+		t.Errorf("report labels = %v", report.Labels)
+	}
+	wantByNode := map[string]Labels{"tenantNode": {LabelModel: "gpt-4o", LabelTenant: "tenant-2"}}
+	if !reflect.DeepEqual(report.LabelsByNode, wantByNode) {
+		t.Errorf("report labels by node = %v, want %v", report.LabelsByNode, wantByNode)
+	}
+}
+
+func TestLabels_Merge(t *testing.T) {
+	base := Labels{"a": "1", "b": "2"}
+	if got := base.Merge(Labels{"b": "3", "c": "4"}); !reflect.DeepEqual(got, Labels{"a": "1", "b": "3", "c": "4"}) {
+		t.Errorf("Merge() = %v", got)
+	}
+	if base["b"] != "2" {
+		t.Errorf("Merge() changed its receiver: %v", base)
+	}
+	if got := Labels(nil).Merge(nil); got != nil {
+		t.Errorf("Merge() of empty labels = %v, want nil", got)
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Retries, replays and runs resumed from a checkpoint can run a node twice.
+// The helpers below write results into the state so that a second Post with
+// the same results leaves it as the first one did.
+
+// MergeByKey writes results into existing by key: a result replaces the
+// element with the same key in place, and results with new keys are appended
+// in order. Of several results with one key, the last wins.
+func MergeByKey[T any, K comparable](existing []T, key func(T) K, results ...T) []T {
+	positions := make(map[K]int, len(existing)+len(results))
+	for i, element := range existing {
+		if _, ok := positions[key(element)]; !ok {
+			positions[key(element)] = i
+		}
+	}
+	for _, result := range results {
+		k := key(result)
+		if i, ok := positions[k]; ok {
+			existing[i] = result
+			continue
+		}
+		positions[k] = len(existing)
+		existing = append(existing, result)
+	}
+	return existing
+}
+
+// MergeInto sets results into dst by key, allocating dst when it is nil, and
+// returns it
+func MergeInto[K comparable, V any](dst map[K]V, key func(V) K, results ...V) map[K]V {
+	if dst == nil {
+		dst = make(map[K]V, len(results))
+	}
+	for _, result := range results {
+		dst[key(result)] = result
+	}
+	return dst
+}
+
+// AppendUnique appends the results whose content is neither in existing nor
+// among the results before them, comparing by ContentHash, e.g. messages
+// appended to a history
+func AppendUnique[T any](existing []T, results ...T) []T {
+	seen := make(map[string]bool, len(existing)+len(results))
+	for _, element := range existing {
+		seen[ContentHash(element)] = true
+	}
+	for _, result := range results {
+		hash := ContentHash(result)
+		if !seen[hash] {
+			seen[hash] = true
+			existing = append(existing, result)
+		}
+	}
+	return existing
+}
+
+// ContentHash returns the hex SHA-256 of the JSON encoding of v, so equal
+// values hash the same, map keys in any order included. Values JSON cannot
+// encode are hashed by their %#v formatting.
+func ContentHash(v any) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded = []byte(fmt.Sprintf("%#v", v))
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Applied records which results were written into a state, for results that
+// cannot be merged, such as amounts added to a total. Keep it in the state,
+// so it is saved with the checkpoints the state is resumed from.
+type Applied struct {
+	Keys map[string]bool `json:"keys,omitempty"`
+}
+
+// Once calls apply unless key was applied before, and reports whether it did
+func (a *Applied) Once(key string, apply func()) bool {
+	if a.Keys[key] {
+		return false
+	}
+	if a.Keys == nil {
+		a.Keys = make(map[string]bool)
+	}
+	apply()
+	a.Keys[key] = true
+	return true
+}
+
+// Has reports whether key was applied
+func (a *Applied) Has(key string) bool {
+	return a.Keys[key]
+}
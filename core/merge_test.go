@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type invoice struct {
+	ID    string  `json:"id"`
+	Total float64 `json:"total"`
+}
+
+func invoiceID(i invoice) string { return i.ID }
+
+func TestMergeByKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []invoice
+		results  []invoice
+		want     []invoice
+	}{
+		{"into empty", nil, []invoice{{"a", 1}, {"b", 2}}, []invoice{{"a", 1}, {"b", 2}}},
+		{"replay", []invoice{{"a", 1}, {"b", 2}}, []invoice{{"a", 1}, {"b", 2}}, []invoice{{"a", 1}, {"b", 2}}},
+		{"replace in place", []invoice{{"a", 1}, {"b", 2}}, []invoice{{"a", 3}, {"c", 4}}, []invoice{{"a", 3}, {"b", 2}, {"c", 4}}},
+		{"last result wins", nil, []invoice{{"a", 1}, {"a", 2}}, []invoice{{"a", 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeByKey(tt.existing, invoiceID, tt.results...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeByKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeInto(t *testing.T) {
+	byID := MergeInto(nil, invoiceID, invoice{"a", 1}, invoice{"b", 2})
+	byID = MergeInto(byID, invoiceID, invoice{"a", 3})
+	want := map[string]invoice{"a": {"a", 3}, "b": {"b", 2}}
+	if !reflect.DeepEqual(byID, want) {
+		t.Errorf("MergeInto() = %v, want %v", byID, want)
+	}
+}
+
+func TestAppendUnique(t *testing.T) {
+	history := []map[string]any{{"role": "user", "content": "hi"}}
+	results := []map[string]any{
+		{"content": "hi", "role": "user"}, // same content, keys in another order
+		{"role": "assistant", "content": "hello"},
+		{"role": "assistant", "content": "hello"},
+	}
+	history = AppendUnique(history, results...)
+	history = AppendUnique(history, results...)
+	if len(history) != 2 || history[1]["content"] != "hello" {
+		t.Errorf("AppendUnique() = %v", history)
+	}
+
+	if ContentHash(json.RawMessage(`{"a":1}`)) != ContentHash(map[string]int{"a": 1}) {
+		t.Error("ContentHash() differs for equal JSON")
+	}
+	if ContentHash(func() {}) == "" {
+		t.Error("ContentHash() of a value JSON cannot encode is empty")
+	}
+}
+
+func TestApplied(t *testing.T) {
+	var applied Applied
+	total := 0
+	for _, key := range []string{"a", "b", "a"} {
+		applied.Once(key, func() { total += 10 })
+	}
+	if total != 20 || !applied.Has("a") || applied.Has("c") {
+		t.Errorf("total = %d, applied = %v", total, applied.Keys)
+	}
+
+	// Applied keys survive a checkpoint of the state
+	encoded, _ := json.Marshal(applied)
+	var restored Applied
+	if err := json.Unmarshal(encoded, &restored); err != nil || restored.Once("b", func() { total += 10 }) {
+		t.Errorf("restored keys = %v, %v", restored.Keys, err)
+	}
+}
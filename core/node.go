@@ -1,21 +1,23 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"iter"
+	"runtime/debug"
 	"sync"
 )
 
-// task is a piece of data to be processed by a worker
-type task[T any] struct {
-	pos    int
-	result T
-}
-
 // Node represents a single node in the workflow graph and implements Workflow
 type Node[State any, PrepResult any, ExecResults any] struct {
 	node       BaseNode[State, PrepResult, ExecResults]
 	maxRetries int
 	successors map[Action]Workflow[State]
 	routines   int
+	chunkSize  int
+	labels     Labels
+
+	retryMarkedOnly bool // Retry only errors marked with Retryable
 }
 
 // createNode creates a new node with the specified configuration
@@ -28,6 +30,7 @@ func createNode[State any, PrepResult any, ExecResults any](basenode BaseNode[St
 		node:       basenode,
 		maxRetries: maxRetries,
 		routines:   maxRoutines,
+		chunkSize:  DefaultChunkSize,
 		successors: make(map[Action]Workflow[State]),
 	}
 }
@@ -37,76 +40,238 @@ func NewNode[State any, PrepResult any, ExecResults any](basenode BaseNode[State
 	return createNode(basenode, maxRetries, maxRoutines)
 }
 
-// executeWithRetry handles the retry logic and execution of a single item
-func (n *Node[State, PrepResult, ExecResults]) executeWithRetry(input PrepResult) (ExecResults, error) {
+// executeWithRetry handles the retry logic and execution of a single item and
+// returns the errors of the failed attempts, none when the item succeeded.
+// Errors marked with Terminal and panics are not retried. In a flow run with a
+// retry budget, each retry is taken from the budget and the item stops
+// retrying once it is spent. Panics are recorded in the run's report.
+func (n *Node[State, PrepResult, ExecResults]) executeWithRetry(ctx context.Context, input PrepResult, run *flowRun) (ExecResults, []error) {
 	var execResult ExecResults
 	var err error
+	var errs []error
 
 	for i := 0; i < n.maxRetries+1; i++ {
-		execResult, err = n.node.Exec(input)
+		if i > 0 && !n.retries(err) {
+			break
+		}
+		if i > 0 && run.cancelled() {
+			break
+		}
+		if i > 0 && run != nil && !run.takeRetry(n.Name()) {
+			break
+		}
+		execResult, err = n.exec(ctx, input)
 		if err == nil {
 			return execResult, nil
 		}
+		errs = append(errs, err)
+	}
+	var panicked *PanicError
+	if run != nil && errors.As(err, &panicked) {
+		run.recordPanic(n.Name(), panicked)
 	}
-	return execResult, err
+	return execResult, errs
+}
+
+// exec runs Exec on input, or ExecContext with ctx when the node has it, and
+// turns a panic into a PanicError, so a panicking item neither kills its
+// worker nor the process
+func (n *Node[State, PrepResult, ExecResults]) exec(ctx context.Context, input PrepResult) (execResult ExecResults, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Value: value, Stack: debug.Stack()}
+		}
+	}()
+	if executor, ok := n.node.(ContextExecutor[PrepResult, ExecResults]); ok {
+		return executor.ExecContext(ctx, input)
+	}
+	return n.node.Exec(input)
 }
 
 // Run implements the Workflow interface and executes the three-phase execution model
 func (n *Node[State, PrepResult, ExecResults]) Run(state *State) Action {
+	return n.runIn(state, nil)
+}
+
+// runIn runs the node as a step of a flow run, whose retry budget its retries
+// are taken from
+func (n *Node[State, PrepResult, ExecResults]) runIn(state *State, run *flowRun) Action {
+	return n.runLocked(state, run, noLock{})
+}
+
+// runLocked runs the node like runIn, holding lock while Prep, PostChunk and
+// Post use the state, so nodes of a DAG can run their items at the same time
+func (n *Node[State, PrepResult, ExecResults]) runLocked(state *State, run *flowRun, lock sync.Locker) Action {
+	lock.Lock()
+	if streamed, ok := n.node.(StreamPrepper[State, PrepResult]); ok {
+		if chunked, ok := n.node.(ChunkPoster[State, PrepResult, ExecResults]); ok {
+			return n.runStream(state, run, lock, streamed.PrepStream(state), chunked)
+		}
+	}
 	prepRes := n.node.Prep(state)
 	if len(prepRes) == 0 {
 		// Nothing to execute, just call Post.
+		defer lock.Unlock()
 		return n.node.Post(state, prepRes)
 	}
+	ctx := n.startItems(state, run, len(prepRes))
+	lock.Unlock()
 
-	numWorkers := n.routines
-	if numWorkers > len(prepRes) {
-		// Don't spawn more workers than there are items.
-		numWorkers = len(prepRes)
+	// Nodes that take their results in chunks only hold one chunk at a time
+	if chunked, ok := n.node.(ChunkPoster[State, PrepResult, ExecResults]); ok {
+		execResults := make([]ExecResults, min(n.chunkSize, len(prepRes)))
+		for start := 0; start < len(prepRes); start += n.chunkSize {
+			chunk := prepRes[start:min(start+n.chunkSize, len(prepRes))]
+			n.execInto(ctx, chunk, execResults[:len(chunk)], run)
+			lock.Lock()
+			chunked.PostChunk(state, chunk, execResults[:len(chunk)])
+			lock.Unlock()
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		return n.node.Post(state, prepRes)
 	}
 
 	execResults := make([]ExecResults, len(prepRes))
+	n.execInto(ctx, prepRes, execResults, run)
+	lock.Lock()
+	defer lock.Unlock()
+	return n.node.Post(state, prepRes, execResults...)
+}
+
+// runStream runs the items of a StreamPrepper like runLocked runs those of a
+// ChunkPoster, pulling each chunk from stream once the one before was posted.
+// It is called and returns with lock held, which is released while items run.
+func (n *Node[State, PrepResult, ExecResults]) runStream(state *State, run *flowRun, lock sync.Locker, stream iter.Seq[PrepResult], chunked ChunkPoster[State, PrepResult, ExecResults]) Action {
+	defer lock.Unlock()
+	ctx := n.startItems(state, run, 0)
+
+	chunk := make([]PrepResult, 0, n.chunkSize)
+	execResults := make([]ExecResults, n.chunkSize)
+	post := func() {
+		lock.Unlock()
+		n.execInto(ctx, chunk, execResults[:len(chunk)], run)
+		lock.Lock()
+		chunked.PostChunk(state, chunk, execResults[:len(chunk)])
+		chunk = chunk[:0]
+	}
+	for item := range stream {
+		chunk = append(chunk, item)
+		if len(chunk) < n.chunkSize {
+			continue
+		}
+		post()
+		// A cancelled run reads no further items
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(chunk) > 0 {
+		post()
+	}
+	return n.node.Post(state, nil)
+}
+
+// startItems records the labels of the step in run and reports that it
+// starts total items, and returns the context the items run with
+func (n *Node[State, PrepResult, ExecResults]) startItems(state *State, run *flowRun, total int) context.Context {
+	labels := n.stepLabels(state)
+	if run != nil {
+		if len(labels) > 0 {
+			run.recordLabels(n.Name(), labels)
+		}
+		if run.reportsProgress() {
+			run.startItems(n.Name(), total, labels)
+		}
+	}
+	return n.execContext(run, labels)
+}
 
+// execContext returns the context the items of a step run with: that of the
+// run, carrying the labels of the run and the step for nodes implementing
+// ContextExecutor, so their LLM usage is recorded under them
+func (n *Node[State, PrepResult, ExecResults]) execContext(run *flowRun, labels Labels) context.Context {
+	ctx := run.context()
+	if _, ok := n.node.(ContextExecutor[PrepResult, ExecResults]); !ok {
+		return ctx
+	}
+	if run != nil {
+		labels = run.labels.Merge(labels)
+	}
+	return WithLabels(ctx, labels)
+}
+
+// execInto runs Exec on each item with up to n.routines workers and stores the
+// results by position. Workers take the positions from a queue of four per
+// worker, so the queue does not grow with the number of items.
+func (n *Node[State, PrepResult, ExecResults]) execInto(ctx context.Context, items []PrepResult, execResults []ExecResults, run *flowRun) {
+	// Don't spawn more workers than there are items.
+	numWorkers := min(n.routines, len(items))
 	if numWorkers == 1 {
 		// Single worker case - no goroutines needed
-		for i, item := range prepRes {
-			execResult, err := n.executeWithRetry(item)
-			if err != nil {
-				execResults[i] = n.node.ExecFallback(err)
-			} else {
-				execResults[i] = execResult
-			}
+		for i := range items {
+			n.execAt(ctx, items, execResults, i, run)
 		}
-	} else {
-		// Multi-worker case with goroutines
-		wg := &sync.WaitGroup{}
-		prepResults := make(chan task[PrepResult], len(prepRes))
+		return
+	}
 
-		worker := func(wg *sync.WaitGroup) {
+	// Multi-worker case with goroutines
+	wg := &sync.WaitGroup{}
+	queue := make(chan int, 4*numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
 			defer wg.Done()
-			for item := range prepResults {
-				execResult, err := n.executeWithRetry(item.result)
-				if err != nil {
-					execResults[item.pos] = n.node.ExecFallback(err)
-				} else {
-					execResults[item.pos] = execResult
-				}
+			for i := range queue {
+				n.execAt(ctx, items, execResults, i, run)
 			}
-		}
+		}()
+	}
+	for i := range items {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+}
 
-		for i := 0; i < numWorkers; i++ {
-			wg.Add(1)
-			go worker(wg)
-		}
+// execAt runs Exec on items[i], falling back to ExecFallback or
+// ExecFallbackItem, into execResults[i]. Items of a cancelled run go to ExecFallback without running.
+func (n *Node[State, PrepResult, ExecResults]) execAt(ctx context.Context, items []PrepResult, execResults []ExecResults, i int, run *flowRun) {
+	if err := ctx.Err(); err != nil {
+		execResults[i] = n.fallback(items[i], 0, []error{Terminal(err)})
+	} else if execResult, errs := n.executeWithRetry(ctx, items[i], run); errs != nil {
+		execResults[i] = n.fallback(items[i], len(errs), errs)
+	} else {
+		execResults[i] = execResult
+	}
+	if run.reportsProgress() {
+		run.itemDone(n.describe(items[i]))
+	}
+}
 
-		for i, item := range prepRes {
-			prepResults <- task[PrepResult]{pos: i, result: item}
-		}
-		close(prepResults)
-		wg.Wait()
+// describe returns the description of item for progress updates, if the
+// wrapped BaseNode is an ItemDescriber
+func (n *Node[State, PrepResult, ExecResults]) describe(item PrepResult) string {
+	if describer, ok := n.node.(ItemDescriber[PrepResult]); ok {
+		return describer.DescribeItem(item)
 	}
+	return ""
+}
 
-	return n.node.Post(state, prepRes, execResults...)
+// Name returns the name of the wrapped BaseNode: its Name method if it has
+// one, otherwise its type name, e.g. "ChatNode"
+func (n *Node[State, PrepResult, ExecResults]) Name() string {
+	return NodeName(n.node)
+}
+
+// NodeName returns the name steps of node are reported under: its Name method
+// if it has one, otherwise its type name. Nodes wrapping another node return
+// it from their Name method.
+func NodeName(node any) string {
+	if named, ok := node.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return typeName(node)
 }
 
 // SetMaxRetries updates the maximum retry count
@@ -122,14 +287,37 @@ func (n *Node[State, PrepResult, ExecResults]) SetMaxRoutines(routines int) {
 	n.routines = routines
 }
 
+// retries reports whether the node retries an item that failed with err
+func (n *Node[State, PrepResult, ExecResults]) retries(err error) bool {
+	if n.retryMarkedOnly {
+		return isMarkedRetryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// SetRetryMarkedOnly makes the node retry only the errors marked with
+// Retryable. By default every error but those marked with Terminal is retried.
+func (n *Node[State, PrepResult, ExecResults]) SetRetryMarkedOnly(only bool) {
+	n.retryMarkedOnly = only
+}
+
+// SetChunkSize updates how many results a ChunkPoster receives at a time
+func (n *Node[State, PrepResult, ExecResults]) SetChunkSize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	n.chunkSize = size
+}
+
 // GetSuccessors returns a copy of the successors map (kept for backward compatibility)
 func (n *Node[State, PrepResult, ExecResults]) GetSuccessors() map[Action]Workflow[State] {
 	return n.successors
 }
 
-// AddSuccessor adds successor based on action with proper validation
+// AddSuccessor adds workflow as the successor for each of the actions, or for
+// ActionDefault when there are none
 func (n *Node[State, PrepResult, ExecResults]) AddSuccessor(workflow Workflow[State], action ...Action) Workflow[State] {
-	// Validate inputs - don't add if action is empty or workflow is nil
+	// Validate inputs - don't add if workflow is nil
 	if workflow == nil {
 		return workflow
 	}
@@ -137,10 +325,20 @@ func (n *Node[State, PrepResult, ExecResults]) AddSuccessor(workflow Workflow[St
 		n.successors[ActionDefault] = workflow
 		return workflow
 	}
-	n.successors[action[0]] = workflow
+	for _, a := range action {
+		n.successors[a] = workflow
+	}
 	return workflow
 }
 
+// AddSuccessors adds the successor workflow of each action; nil successors
+// are skipped
+func (n *Node[State, PrepResult, ExecResults]) AddSuccessors(successors map[Action]Workflow[State]) {
+	for action, successor := range successors {
+		n.AddSuccessor(successor, action)
+	}
+}
+
 // GetSuccessor gets the next WorkFlow as per action.
 func (n *Node[State, PrepResult, ExecResults]) GetSuccessor(action Action) Workflow[State] {
 	return n.successors[action]
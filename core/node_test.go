@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"iter"
+	"testing"
+)
+
+// chunkedNode squares its items and sums them a chunk at a time
+type chunkedNode struct {
+	chunks [][]int
+}
+
+func (n *chunkedNode) Prep(state *benchState) []int {
+	return state.Items
+}
+
+func (n *chunkedNode) Exec(item int) (int, error) {
+	return item * item, nil
+}
+
+func (n *chunkedNode) PostChunk(state *benchState, prepResults []int, execResults []int) {
+	n.chunks = append(n.chunks, append([]int(nil), prepResults...))
+	for i, result := range execResults {
+		if result != prepResults[i]*prepResults[i] {
+			panic("results out of order")
+		}
+		state.Sum += result
+	}
+}
+
+func (n *chunkedNode) Post(state *benchState, prepResults []int, execResults ...int) Action {
+	if len(execResults) != 0 {
+		return ActionFailure
+	}
+	return ActionSuccess
+}
+
+func (n *chunkedNode) ExecFallback(err error) int {
+	return 0
+}
+
+func TestNode_ChunkPoster(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      int
+		chunkSize  int
+		routines   int
+		wantChunks []int
+	}{
+		{"default chunk size", 250, 0, 8, []int{100, 100, 50}},
+		{"exact chunks", 40, 20, 3, []int{20, 20}},
+		{"single worker", 5, 2, 1, []int{2, 2, 1}},
+		{"no items", 0, 10, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &chunkedNode{}
+			node := NewNode[benchState, int, int](base, 0, tt.routines)
+			if tt.chunkSize > 0 {
+				node.SetChunkSize(tt.chunkSize)
+			}
+			state := &benchState{Items: benchItems(tt.items)}
+			if action := node.Run(state); action != ActionSuccess {
+				t.Fatalf("Run() = %s, want success", action)
+			}
+
+			var want int
+			for _, item := range state.Items {
+				want += item * item
+			}
+			if state.Sum != want {
+				t.Errorf("sum = %d, want %d", state.Sum, want)
+			}
+			if len(base.chunks) != len(tt.wantChunks) {
+				t.Fatalf("got %d chunks, want %d", len(base.chunks), len(tt.wantChunks))
+			}
+			next := 0
+			for i, chunk := range base.chunks {
+				if len(chunk) != tt.wantChunks[i] || chunk[0] != next {
+					t.Errorf("chunk %d has %d items from %d, want %d from %d", i, len(chunk), chunk[0], tt.wantChunks[i], next)
+				}
+				next += len(chunk)
+			}
+		})
+	}
+}
+
+// streamedNode is a chunkedNode reading its items from a stream
+type streamedNode struct {
+	chunkedNode
+	count    int
+	pulled   int
+	posted   int
+	maxAhead int // Most items read from the stream and not posted yet
+}
+
+func (n *streamedNode) Prep(state *benchState) []int {
+	panic("Prep called for a StreamPrepper")
+}
+
+func (n *streamedNode) PrepStream(state *benchState) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < n.count; i++ {
+			n.pulled++
+			n.maxAhead = max(n.maxAhead, n.pulled-n.posted)
+			state.Items = append(state.Items, i)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func (n *streamedNode) PostChunk(state *benchState, prepResults []int, execResults []int) {
+	n.chunkedNode.PostChunk(state, prepResults, execResults)
+	n.posted += len(prepResults)
+}
+
+func TestNode_StreamPrepper(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      int
+		wantChunks []int
+	}{
+		{"several chunks", 25, []int{10, 10, 5}},
+		{"exact chunks", 20, []int{10, 10}},
+		{"no items", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &streamedNode{count: tt.items}
+			node := NewNode[benchState, int, int](base, 0, 4)
+			node.SetChunkSize(10)
+			state := &benchState{}
+			if action := node.Run(state); action != ActionSuccess {
+				t.Fatalf("Run() = %s, want success", action)
+			}
+
+			var want int
+			for i := 0; i < tt.items; i++ {
+				want += i * i
+			}
+			if state.Sum != want || base.pulled != tt.items {
+				t.Errorf("sum = %d of %d items, want %d of %d", state.Sum, base.pulled, want, tt.items)
+			}
+			if base.maxAhead > 10 {
+				t.Errorf("%d items were read ahead of PostChunk, want at most a chunk", base.maxAhead)
+			}
+			if len(base.chunks) != len(tt.wantChunks) {
+				t.Fatalf("got %d chunks, want %d", len(base.chunks), len(tt.wantChunks))
+			}
+			for i, chunk := range base.chunks {
+				if len(chunk) != tt.wantChunks[i] {
+					t.Errorf("chunk %d has %d items, want %d", i, len(chunk), tt.wantChunks[i])
+				}
+			}
+		})
+	}
+}
+
+// cancellingNode cancels its run once it posted a chunk
+type cancellingNode struct {
+	streamedNode
+	cancel context.CancelFunc
+}
+
+func (n *cancellingNode) PostChunk(state *benchState, prepResults []int, execResults []int) {
+	n.streamedNode.PostChunk(state, prepResults, execResults)
+	n.cancel()
+}
+
+func TestNode_StreamPrepperCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	base := &cancellingNode{streamedNode: streamedNode{count: 1000}, cancel: cancel}
+	node := NewNode[benchState, int, int](base, 0, 4)
+	node.SetChunkSize(10)
+
+	NewFlow[benchState](node).RunContext(ctx, &benchState{}, nil)
+	if base.pulled != 10 {
+		t.Errorf("read %d items, want the stream left after the first chunk", base.pulled)
+	}
+}
+
+// orderNode fails unless its results reach Post in the order of the items
+type orderNode struct{ benchNode }
+
+func (n *orderNode) Post(state *benchState, prepResults []int, execResults ...int) Action {
+	for i, result := range execResults {
+		if result != prepResults[i] {
+			return ActionFailure
+		}
+	}
+	return ActionSuccess
+}
+
+func TestNode_ConcurrentResultsInOrder(t *testing.T) {
+	// Far more items than the work queue holds
+	node := NewNode[benchState, int, int](&orderNode{}, 0, 16)
+	if action := node.Run(&benchState{Items: benchItems(5000)}); action != ActionSuccess {
+		t.Errorf("Run() = %s, want results in item order", action)
+	}
+}
+
+// namedNode names itself instead of going by its type
+type namedNode struct{ benchNode }
+
+func (n *namedNode) Name() string { return "sum" }
+
+func TestNode_Name(t *testing.T) {
+	if name := NewNode[benchState, int, int](&benchNode{}, 0, 1).Name(); name != "benchNode" {
+		t.Errorf("Name() = %q, want the type name", name)
+	}
+	if name := NewNode[benchState, int, int](&namedNode{}, 0, 1).Name(); name != "sum" {
+		t.Errorf("Name() = %q, want the node's own name", name)
+	}
+}
@@ -0,0 +1,195 @@
+// Package nodes provides reusable nodes that are not tied to a particular LLM
+// task, such as gating a flow behind a human decision.
+package nodes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// Actions returned by HumanApprovalNode
+const (
+	ActionApprove  core.Action = "approve"
+	ActionReject   core.Action = "reject"
+	ActionFeedback core.Action = "feedback"
+)
+
+// Decision is a human's answer to an ApprovalRequest
+type Decision string
+
+const (
+	// DecisionApprove lets the gated action run
+	DecisionApprove Decision = "approve"
+	// DecisionReject stops the gated action
+	DecisionReject Decision = "reject"
+	// DecisionFeedback answers with a free-form message instead of a yes or no
+	DecisionFeedback Decision = "feedback"
+)
+
+// ApprovalRequest describes what a human is asked to approve
+type ApprovalRequest struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind,omitempty"` // Groups similar requests, e.g. by tool name, for remembered answers
+	Title   string `json:"title"`
+	Details string `json:"details,omitempty"`
+	Data    any    `json:"data,omitempty"` // Structured payload for UIs and webhooks
+}
+
+// ApprovalResponse is the human's decision
+type ApprovalResponse struct {
+	RequestID string   `json:"request_id"`
+	Decision  Decision `json:"decision"`
+	Feedback  string   `json:"feedback,omitempty"` // Message from the approver, or why the request was rejected
+	Remember  bool     `json:"remember,omitempty"` // Apply the decision to later requests of the same kind
+	Approver  string   `json:"approver,omitempty"`
+	TimedOut  bool     `json:"timed_out,omitempty"`
+}
+
+// Validate checks that the decision is known and feedback carries a message
+func (r ApprovalResponse) Validate() error {
+	switch r.Decision {
+	case DecisionApprove, DecisionReject:
+		return nil
+	case DecisionFeedback:
+		if r.Feedback == "" {
+			return fmt.Errorf("feedback decision requires a message")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown decision %q", r.Decision)
+	}
+}
+
+// ApprovalSource asks a human for a decision and waits for the answer
+type ApprovalSource interface {
+	RequestApproval(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error)
+}
+
+// ApprovalSourceFunc adapts a function to the ApprovalSource interface
+type ApprovalSourceFunc func(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error)
+
+// RequestApproval calls f
+func (f ApprovalSourceFunc) RequestApproval(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+	return f(ctx, request)
+}
+
+// ApprovalStateInterface is implemented by workflow states gated by a HumanApprovalNode
+type ApprovalStateInterface interface {
+	// GetApprovalRequest returns the pending request; false means nothing needs approval
+	GetApprovalRequest() (ApprovalRequest, bool)
+	SetApprovalResponse(response ApprovalResponse)
+}
+
+// ApprovalConfig configures a HumanApprovalNode
+type ApprovalConfig struct {
+	Timeout         time.Duration // How long to wait for an answer; 0 waits until the source returns
+	TimeoutDecision Decision      // Decision recorded when the timeout expires
+}
+
+// DefaultApprovalConfig waits indefinitely and rejects on timeout
+func DefaultApprovalConfig() *ApprovalConfig {
+	return &ApprovalConfig{TimeoutDecision: DecisionReject}
+}
+
+// HumanApprovalNode gates the following nodes behind a human decision. It returns
+// ActionApprove, ActionReject or ActionFeedback, so successors are wired per
+// answer; when the state has nothing to approve it returns ActionApprove.
+type HumanApprovalNode[S ApprovalStateInterface] struct {
+	source ApprovalSource
+	config *ApprovalConfig
+}
+
+// NewHumanApprovalNode creates an approval node asking source; a nil config uses
+// DefaultApprovalConfig
+func NewHumanApprovalNode[S ApprovalStateInterface](source ApprovalSource, config *ApprovalConfig) *HumanApprovalNode[S] {
+	if config == nil {
+		config = DefaultApprovalConfig()
+	}
+	if config.TimeoutDecision == "" {
+		config.TimeoutDecision = DecisionReject
+	}
+	return &HumanApprovalNode[S]{source: source, config: config}
+}
+
+// Prep reads the pending request from state and assigns it an ID if it has none
+func (n *HumanApprovalNode[S]) Prep(state *S) []ApprovalRequest {
+	request, ok := (*state).GetApprovalRequest()
+	if !ok {
+		return []ApprovalRequest{}
+	}
+	if request.ID == "" {
+		request.ID = newRequestID()
+	}
+	return []ApprovalRequest{request}
+}
+
+// Exec asks the source and waits up to the configured timeout
+func (n *HumanApprovalNode[S]) Exec(request ApprovalRequest) (ApprovalResponse, error) {
+	return n.ExecContext(context.Background(), request)
+}
+
+// ExecContext asks the source with the context of the run, so sources can
+// tell which run asks, e.g. to route the request to the run's websocket
+// clients, and stop waiting when the run is cancelled
+func (n *HumanApprovalNode[S]) ExecContext(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+
+	response, err := n.source.RequestApproval(ctx, request)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		return ApprovalResponse{RequestID: request.ID, Decision: n.config.TimeoutDecision, TimedOut: true}, nil
+	}
+	if err != nil {
+		return ApprovalResponse{}, fmt.Errorf("approval request %s failed: %w", request.ID, err)
+	}
+	if err := response.Validate(); err != nil {
+		return ApprovalResponse{}, fmt.Errorf("invalid approval response: %w", err)
+	}
+	response.RequestID = request.ID
+	return response, nil
+}
+
+// ExecFallback rejects when no valid answer could be obtained
+func (n *HumanApprovalNode[S]) ExecFallback(err error) ApprovalResponse {
+	return ApprovalResponse{Decision: DecisionReject, Feedback: err.Error()}
+}
+
+// Post stores the response in state and routes on the decision
+func (n *HumanApprovalNode[S]) Post(state *S, prepRes []ApprovalRequest, execResults ...ApprovalResponse) core.Action {
+	if len(execResults) == 0 {
+		return ActionApprove
+	}
+
+	response := execResults[0]
+	if response.RequestID == "" && len(prepRes) > 0 {
+		response.RequestID = prepRes[0].ID
+	}
+	(*state).SetApprovalResponse(response)
+
+	switch response.Decision {
+	case DecisionApprove:
+		return ActionApprove
+	case DecisionFeedback:
+		return ActionFeedback
+	default:
+		return ActionReject
+	}
+}
+
+// newRequestID returns a random identifier for requests created without one
+func newRequestID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("approval-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id[:])
+}
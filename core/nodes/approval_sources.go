@@ -0,0 +1,242 @@
+package nodes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TerminalSource asks for approval on a terminal with a y(es) / n(o) / a(lways)
+// prompt; any other non-empty answer is returned as feedback
+type TerminalSource struct {
+	mu      sync.Mutex
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+// NewTerminalSource creates a terminal source reading answers from in
+func NewTerminalSource(in io.Reader, out io.Writer) *TerminalSource {
+	return &TerminalSource{scanner: bufio.NewScanner(in), out: out}
+}
+
+// RequestApproval prints the request and reads answers until one is valid
+func (t *TerminalSource) RequestApproval(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(t.out, "\n%s\n", request.Title)
+	if request.Details != "" {
+		fmt.Fprintln(t.out, request.Details)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return ApprovalResponse{}, err
+		}
+
+		fmt.Fprint(t.out, "Approve? [y=yes, n=no, a=always]: ")
+		if !t.scanner.Scan() {
+			if err := t.scanner.Err(); err != nil {
+				return ApprovalResponse{}, fmt.Errorf("failed to read approval: %w", err)
+			}
+			return ApprovalResponse{}, io.EOF
+		}
+
+		answer := strings.TrimSpace(t.scanner.Text())
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return ApprovalResponse{Decision: DecisionApprove}, nil
+		case "n", "no":
+			return ApprovalResponse{Decision: DecisionReject}, nil
+		case "a", "always":
+			return ApprovalResponse{Decision: DecisionApprove, Remember: true}, nil
+		case "":
+			fmt.Fprintln(t.out, "Please enter y, n or a, or type a message")
+		default:
+			return ApprovalResponse{Decision: DecisionFeedback, Feedback: answer}, nil
+		}
+	}
+}
+
+// PendingApproval is a request waiting for an answer from a ChannelSource consumer
+type PendingApproval struct {
+	Request  ApprovalRequest
+	response chan ApprovalResponse
+}
+
+// Respond delivers the decision; only the first call has an effect
+func (p *PendingApproval) Respond(response ApprovalResponse) {
+	select {
+	case p.response <- response:
+	default:
+	}
+}
+
+// ChannelSource hands requests to another goroutine, e.g. a UI or a test, which
+// answers them through PendingApproval.Respond
+type ChannelSource struct {
+	requests chan *PendingApproval
+}
+
+// NewChannelSource creates a channel source; buffer is the number of requests that
+// may wait to be picked up
+func NewChannelSource(buffer int) *ChannelSource {
+	return &ChannelSource{requests: make(chan *PendingApproval, buffer)}
+}
+
+// Requests returns the channel on which pending requests are delivered
+func (c *ChannelSource) Requests() <-chan *PendingApproval {
+	return c.requests
+}
+
+// RequestApproval publishes the request and waits for its response
+func (c *ChannelSource) RequestApproval(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+	pending := &PendingApproval{Request: request, response: make(chan ApprovalResponse, 1)}
+	select {
+	case c.requests <- pending:
+	case <-ctx.Done():
+		return ApprovalResponse{}, ctx.Err()
+	}
+
+	select {
+	case response := <-pending.response:
+		return response, nil
+	case <-ctx.Done():
+		return ApprovalResponse{}, ctx.Err()
+	}
+}
+
+// HTTPCallbackSource asks for approval over HTTP. Each request is POSTed as JSON to
+// a webhook (for example a chat integration), and the decision comes back as an
+// ApprovalResponse POSTed to the source's handler at <mount>/<request id>. A GET
+// on the handler lists the pending requests.
+type HTTPCallbackSource struct {
+	webhookURL string
+	client     *http.Client
+
+	mu      sync.Mutex
+	pending map[string]*PendingApproval
+}
+
+// NewHTTPCallbackSource creates an HTTP callback source; an empty webhookURL only
+// serves the handler, for UIs that poll the pending list
+func NewHTTPCallbackSource(webhookURL string, client *http.Client) *HTTPCallbackSource {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &HTTPCallbackSource{
+		webhookURL: webhookURL,
+		client:     client,
+		pending:    make(map[string]*PendingApproval),
+	}
+}
+
+// RequestApproval notifies the webhook and waits for the callback
+func (h *HTTPCallbackSource) RequestApproval(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+	if request.ID == "" {
+		request.ID = newRequestID()
+	}
+	pending := &PendingApproval{Request: request, response: make(chan ApprovalResponse, 1)}
+
+	h.mu.Lock()
+	h.pending[request.ID] = pending
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.pending, request.ID)
+		h.mu.Unlock()
+	}()
+
+	if h.webhookURL != "" {
+		if err := h.notify(ctx, request); err != nil {
+			return ApprovalResponse{}, err
+		}
+	}
+
+	select {
+	case response := <-pending.response:
+		return response, nil
+	case <-ctx.Done():
+		return ApprovalResponse{}, ctx.Err()
+	}
+}
+
+// Pending returns the requests waiting for a decision, ordered by ID
+func (h *HTTPCallbackSource) Pending() []ApprovalRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	requests := make([]ApprovalRequest, 0, len(h.pending))
+	for _, pending := range h.pending {
+		requests = append(requests, pending.Request)
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID < requests[j].ID })
+	return requests
+}
+
+// ServeHTTP lists pending requests on GET and accepts decisions on POST
+func (h *HTTPCallbackSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Pending())
+	case http.MethodPost:
+		var response ApprovalResponse
+		if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+			http.Error(w, "invalid approval response: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if id := path.Base(r.URL.Path); response.RequestID == "" && id != "/" && id != "." {
+			response.RequestID = id
+		}
+		if err := response.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.mu.Lock()
+		pending, ok := h.pending[response.RequestID]
+		h.mu.Unlock()
+		if !ok {
+			http.Error(w, "no pending approval "+response.RequestID, http.StatusNotFound)
+			return
+		}
+		pending.Respond(response)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// notify posts the request to the webhook
+func (h *HTTPCallbackSource) notify(ctx context.Context, request ApprovalRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode approval request: %w", err)
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, h.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	response, err := h.client.Do(httpRequest)
+	if err != nil {
+		return fmt.Errorf("approval webhook failed: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("approval webhook returned status %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+type gateState struct {
+	Request  *ApprovalRequest
+	Response ApprovalResponse
+}
+
+func (s *gateState) GetApprovalRequest() (ApprovalRequest, bool) {
+	if s.Request == nil {
+		return ApprovalRequest{}, false
+	}
+	return *s.Request, true
+}
+
+func (s *gateState) SetApprovalResponse(response ApprovalResponse) {
+	s.Response = response
+}
+
+func runGate(t *testing.T, source ApprovalSource, config *ApprovalConfig, state *gateState) core.Action {
+	t.Helper()
+	node := core.NewNode(NewHumanApprovalNode[*gateState](source, config), 0, 1)
+	return node.Run(&state)
+}
+
+func TestHumanApprovalNode_Decisions(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   ApprovalResponse
+		wantAction core.Action
+	}{
+		{"approve", ApprovalResponse{Decision: DecisionApprove}, ActionApprove},
+		{"reject", ApprovalResponse{Decision: DecisionReject}, ActionReject},
+		{"feedback", ApprovalResponse{Decision: DecisionFeedback, Feedback: "use the staging database"}, ActionFeedback},
+		{"invalid decision falls back to reject", ApprovalResponse{Decision: "maybe"}, ActionReject},
+		{"feedback without message falls back to reject", ApprovalResponse{Decision: DecisionFeedback}, ActionReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := ApprovalSourceFunc(func(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+				return tt.response, nil
+			})
+			state := &gateState{Request: &ApprovalRequest{Title: "Deploy to production?"}}
+
+			if action := runGate(t, source, nil, state); action != tt.wantAction {
+				t.Errorf("action = %v, want %v", action, tt.wantAction)
+			}
+			if state.Response.RequestID == "" {
+				t.Error("response should carry the generated request ID")
+			}
+		})
+	}
+}
+
+func TestHumanApprovalNode_NothingToApprove(t *testing.T) {
+	called := false
+	source := ApprovalSourceFunc(func(ctx context.Context, request ApprovalRequest) (ApprovalResponse, error) {
+		called = true
+		return ApprovalResponse{Decision: DecisionReject}, nil
+	})
+	if action := runGate(t, source, nil, &gateState{}); action != ActionApprove || called {
+		t.Errorf("expected ActionApprove without asking, got %v (asked: %v)", action, called)
+	}
+}
+
+func TestHumanApprovalNode_Timeout(t *testing.T) {
+	source := NewChannelSource(1) // nobody answers
+	state := &gateState{Request: &ApprovalRequest{ID: "req-1", Title: "Send email?"}}
+
+	action := runGate(t, source, &ApprovalConfig{Timeout: 20 * time.Millisecond, TimeoutDecision: DecisionApprove}, state)
+	if action != ActionApprove || !state.Response.TimedOut || state.Response.RequestID != "req-1" {
+		t.Errorf("action = %v, response = %+v", action, state.Response)
+	}
+}
+
+func TestChannelSource(t *testing.T) {
+	source := NewChannelSource(0)
+	go func() {
+		pending := <-source.Requests()
+		pending.Respond(ApprovalResponse{Decision: DecisionApprove, Approver: "alice"})
+		pending.Respond(ApprovalResponse{Decision: DecisionReject}) // ignored
+	}()
+
+	state := &gateState{Request: &ApprovalRequest{Title: "Refund order 42?"}}
+	if action := runGate(t, source, nil, state); action != ActionApprove || state.Response.Approver != "alice" {
+		t.Errorf("action = %v, response = %+v", action, state.Response)
+	}
+}
+
+func TestTerminalSource(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ApprovalResponse
+	}{
+		{"y\n", ApprovalResponse{Decision: DecisionApprove}},
+		{"no\n", ApprovalResponse{Decision: DecisionReject}},
+		{"\na\n", ApprovalResponse{Decision: DecisionApprove, Remember: true}},
+		{"only read files\n", ApprovalResponse{Decision: DecisionFeedback, Feedback: "only read files"}},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		source := NewTerminalSource(strings.NewReader(tt.input), &out)
+		got, err := source.RequestApproval(context.Background(), ApprovalRequest{Title: "Run tool?", Details: "rm -rf build"})
+		if err != nil {
+			t.Fatalf("RequestApproval(%q) failed: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("RequestApproval(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "rm -rf build") {
+			t.Errorf("details were not shown: %q", out.String())
+		}
+	}
+
+	source := NewTerminalSource(strings.NewReader(""), &bytes.Buffer{})
+	if _, err := source.RequestApproval(context.Background(), ApprovalRequest{Title: "Run tool?"}); err == nil {
+		t.Error("expected an error at end of input")
+	}
+}
+
+func TestHTTPCallbackSource(t *testing.T) {
+	var source *HTTPCallbackSource
+	callbacks := httptest.NewServer(http.StripPrefix("/approvals", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source.ServeHTTP(w, r)
+	})))
+	defer callbacks.Close()
+
+	// The webhook answers asynchronously, like a chat integration would
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ApprovalRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		go func() {
+			pending, _ := http.Get(callbacks.URL + "/approvals")
+			var listed []ApprovalRequest
+			json.NewDecoder(pending.Body).Decode(&listed)
+			pending.Body.Close()
+			if len(listed) != 1 || listed[0].ID != request.ID {
+				t.Errorf("pending list = %+v", listed)
+			}
+
+			body := strings.NewReader(`{"decision":"feedback","feedback":"wait until Monday"}`)
+			response, err := http.Post(callbacks.URL+"/approvals/"+request.ID, "application/json", body)
+			if err != nil || response.StatusCode != http.StatusNoContent {
+				t.Errorf("callback failed: %v %v", err, response)
+			}
+		}()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer webhook.Close()
+
+	source = NewHTTPCallbackSource(webhook.URL, nil)
+	state := &gateState{Request: &ApprovalRequest{Title: "Publish release?"}}
+	if action := runGate(t, source, &ApprovalConfig{Timeout: 5 * time.Second}, state); action != ActionFeedback {
+		t.Fatalf("action = %v, response = %+v", action, state.Response)
+	}
+	if state.Response.Feedback != "wait until Monday" {
+		t.Errorf("response = %+v", state.Response)
+	}
+	if len(source.Pending()) != 0 {
+		t.Error("answered requests should no longer be pending")
+	}
+
+	response, _ := http.Post(callbacks.URL+"/approvals/unknown", "application/json", strings.NewReader(`{"decision":"approve"}`))
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown request returned status %d", response.StatusCode)
+	}
+	response, _ = http.Post(callbacks.URL+"/approvals/unknown", "application/json", strings.NewReader(`{"decision":"perhaps"}`))
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid decision returned status %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,278 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// BatchItem is the prep result of a ResumableBatchNode: an item of the
+// wrapped node and its entry in the queue
+type BatchItem[PrepResult any] struct {
+	Index int
+	Item  PrepResult
+	Done  bool // Set when an earlier run processed the item
+
+	batch  string
+	record *batchRecord // nil when the batch could not be queued
+	err    error        // Set when the item could not be loaded or queued
+}
+
+// batchRecord is the queue entry of an item, shared by the attempts of a run
+type batchRecord struct {
+	entry    BatchEntry
+	queueErr error // Set when the last outcome could not be stored
+}
+
+// BatchSummary is the outcome of a run of a ResumableBatchNode
+type BatchSummary struct {
+	Batch    string
+	Total    int
+	Resumed  int   // Items processed by an earlier run, whose results were reused
+	Done     int   // Items processed by this run
+	Failed   int   // Items that failed; a rerun retries them
+	Pending  int   // Items that did not run, e.g. because the run was cancelled
+	QueueErr error // Set when the queue could not be read or updated
+}
+
+// BatchReporter may be implemented by states that want the outcome of a
+// ResumableBatchNode, e.g. to log how much of a batch was resumed
+type BatchReporter interface {
+	SetBatchSummary(summary BatchSummary)
+}
+
+// ResumableBatchNode runs the items of a batch node through a BatchQueue, so
+// a batch interrupted by a crash resumes where it stopped instead of
+// processing and paying for every item again. The first run stores the items
+// Prep returns and records each item's result as soon as it is done; a later
+// run of the same batch reuses the stored items and results and only runs the
+// items that did not finish. Once every item is done, Post runs and the batch
+// is removed from the queue.
+//
+// Items and results must survive a round trip through JSON. Wrap it with
+// core.NewNode like the node it wraps; nodes implementing core.ChunkPoster are
+// not supported.
+type ResumableBatchNode[State any, PrepResult any, ExecResults any] struct {
+	node  core.BaseNode[State, PrepResult, ExecResults]
+	queue BatchQueue
+	batch func(state *State) string
+}
+
+// NewResumableBatchNode wraps node so its items are queued in queue. batch
+// names the batch a run works on, e.g. the ID of the job; a nil batch keeps
+// a single batch named after the node.
+func NewResumableBatchNode[State any, PrepResult any, ExecResults any](node core.BaseNode[State, PrepResult, ExecResults], queue BatchQueue, batch func(state *State) string) *ResumableBatchNode[State, PrepResult, ExecResults] {
+	if batch == nil {
+		name := core.NodeName(node)
+		batch = func(*State) string { return name }
+	}
+	return &ResumableBatchNode[State, PrepResult, ExecResults]{node: node, queue: queue, batch: batch}
+}
+
+// Name returns the name of the wrapped node, so steps are reported under it
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) Name() string {
+	return core.NodeName(r.node)
+}
+
+// Prep loads the items of the batch from the queue, or, for a new batch, takes
+// them from the wrapped node and queues them
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) Prep(state *State) []BatchItem[PrepResult] {
+	ctx := context.Background()
+	batch := r.batch(state)
+	entries, err := r.queue.Load(ctx, batch)
+	if err != nil {
+		return r.unqueued(batch, r.node.Prep(state), fmt.Errorf("failed to load batch: %w", err))
+	}
+	if len(entries) > 0 {
+		return r.resume(batch, entries)
+	}
+
+	items := r.node.Prep(state)
+	entries = make([]BatchEntry, len(items))
+	now := time.Now()
+	for i, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return r.unqueued(batch, items, fmt.Errorf("failed to encode item %d: %w", i, err))
+		}
+		entries[i] = BatchEntry{Index: i, Item: encoded, Status: BatchPending, UpdatedAt: now}
+	}
+	if len(entries) > 0 {
+		if err := r.queue.Enqueue(ctx, batch, entries); err != nil {
+			return r.unqueued(batch, items, fmt.Errorf("failed to queue batch: %w", err))
+		}
+	}
+
+	batchItems := make([]BatchItem[PrepResult], len(items))
+	for i, item := range items {
+		batchItems[i] = BatchItem[PrepResult]{Index: i, Item: item, batch: batch, record: &batchRecord{entry: entries[i]}}
+	}
+	return batchItems
+}
+
+// resume decodes the stored items of a batch; items whose result cannot be
+// decoded run again
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) resume(batch string, entries []BatchEntry) []BatchItem[PrepResult] {
+	items := make([]BatchItem[PrepResult], len(entries))
+	for i, entry := range entries {
+		item := BatchItem[PrepResult]{Index: i, batch: batch, record: &batchRecord{entry: entry}}
+		if err := json.Unmarshal(entry.Item, &item.Item); err != nil {
+			item.err = fmt.Errorf("failed to decode item %d of batch %s: %w", i, batch, err)
+		}
+		if entry.Status == BatchDone && item.err == nil {
+			var result ExecResults
+			if json.Unmarshal(entry.Result, &result) == nil {
+				item.Done = true
+			}
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// unqueued returns items that fail with err instead of running unrecorded,
+// which a rerun would pay for again
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) unqueued(batch string, items []PrepResult, err error) []BatchItem[PrepResult] {
+	batchItems := make([]BatchItem[PrepResult], len(items))
+	for i, item := range items {
+		batchItems[i] = BatchItem[PrepResult]{Index: i, Item: item, batch: batch, err: err}
+	}
+	return batchItems
+}
+
+// DescribeItem implements core.ItemDescriber with the wrapped node's descriptions
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) DescribeItem(item BatchItem[PrepResult]) string {
+	if describer, ok := r.node.(core.ItemDescriber[PrepResult]); ok {
+		return describer.DescribeItem(item.Item)
+	}
+	return ""
+}
+
+// Labels implements core.Labeler with the wrapped node's labels
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) Labels(state *State) core.Labels {
+	if labeler, ok := r.node.(core.Labeler[State]); ok {
+		return labeler.Labels(state)
+	}
+	return nil
+}
+
+// Exec runs an item without the context of the run
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) Exec(item BatchItem[PrepResult]) (ExecResults, error) {
+	return r.ExecContext(context.Background(), item)
+}
+
+// ExecContext returns the stored result of an item an earlier run processed,
+// and otherwise runs it with the wrapped node and records the outcome
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) ExecContext(ctx context.Context, item BatchItem[PrepResult]) (ExecResults, error) {
+	var result ExecResults
+	if item.err != nil {
+		return result, core.Terminal(item.err)
+	}
+	if item.Done {
+		err := json.Unmarshal(item.record.entry.Result, &result)
+		return result, err
+	}
+
+	if executor, ok := r.node.(core.ContextExecutor[PrepResult, ExecResults]); ok {
+		result, err := executor.ExecContext(ctx, item.Item)
+		return result, r.record(ctx, item, result, err)
+	}
+	result, err := r.node.Exec(item.Item)
+	return result, r.record(ctx, item, result, err)
+}
+
+// record stores the outcome of an attempt and returns the error of the item.
+// Attempts interrupted by the cancellation of the run are not recorded, and
+// a result that could not be stored is still returned.
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) record(ctx context.Context, item BatchItem[PrepResult], result ExecResults, err error) error {
+	if item.record == nil || (err != nil && ctx.Err() != nil) {
+		return err
+	}
+	entry := item.record.entry
+	entry.Attempts++
+	entry.UpdatedAt = time.Now()
+	if err != nil {
+		entry.Status = BatchFailed
+		entry.Error = err.Error()
+	} else if encoded, encodeErr := json.Marshal(result); encodeErr != nil {
+		entry.Status = BatchFailed
+		entry.Error = fmt.Sprintf("failed to encode result: %v", encodeErr)
+	} else {
+		entry.Status = BatchDone
+		entry.Error = ""
+		entry.Result = encoded
+	}
+
+	// The queue may be in another process, so the run's context does not
+	// cancel the update
+	item.record.queueErr = r.queue.Update(context.WithoutCancel(ctx), item.batch, entry)
+	if item.record.queueErr != nil {
+		entry.Status = BatchPending
+	}
+	item.record.entry = entry
+	return err
+}
+
+// ExecFallback returns the wrapped node's fallback
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) ExecFallback(err error) ExecResults {
+	return r.node.ExecFallback(err)
+}
+
+// ExecFallbackItem passes the failed item to the wrapped node, if it
+// implements core.ItemFallback, or else returns its ExecFallback
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) ExecFallbackItem(failure core.ItemFailure[BatchItem[PrepResult]]) ExecResults {
+	if fallback, ok := r.node.(core.ItemFallback[PrepResult, ExecResults]); ok {
+		return fallback.ExecFallbackItem(core.ItemFailure[PrepResult]{
+			Item:     failure.Item.Item,
+			Attempts: failure.Attempts,
+			Errors:   failure.Errors,
+			Err:      failure.Err,
+		})
+	}
+	return r.node.ExecFallback(failure.Err)
+}
+
+// Post passes the items and results to the wrapped node, reports the
+// outcome to states implementing BatchReporter and removes a finished batch
+func (r *ResumableBatchNode[State, PrepResult, ExecResults]) Post(state *State, prepRes []BatchItem[PrepResult], execResults ...ExecResults) core.Action {
+	items := make([]PrepResult, len(prepRes))
+	summary := BatchSummary{Batch: r.batch(state), Total: len(prepRes)}
+	var errs []error
+	for i, item := range prepRes {
+		items[i] = item.Item
+		switch {
+		case item.err != nil:
+			summary.Failed++
+			errs = append(errs, item.err)
+		case item.Done:
+			summary.Resumed++
+		case item.record.entry.Status == BatchDone:
+			summary.Done++
+		case item.record.entry.Status == BatchFailed:
+			summary.Failed++
+		default:
+			summary.Pending++
+		}
+		if item.record != nil && item.record.queueErr != nil {
+			errs = append(errs, fmt.Errorf("failed to record item %d: %w", item.Index, item.record.queueErr))
+		}
+	}
+
+	action := r.node.Post(state, items, execResults...)
+	if summary.Total > 0 && summary.Resumed+summary.Done == summary.Total {
+		if err := r.queue.Remove(context.Background(), summary.Batch); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove batch: %w", err))
+		}
+	}
+	summary.QueueErr = errors.Join(errs...)
+	if reporter, ok := any(state).(BatchReporter); ok {
+		reporter.SetBatchSummary(summary)
+	} else if reporter, ok := any(*state).(BatchReporter); ok {
+		reporter.SetBatchSummary(summary)
+	}
+	return action
+}
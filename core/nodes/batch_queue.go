@@ -0,0 +1,226 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BatchStatus is the state of an item of a resumable batch
+type BatchStatus string
+
+// Item states
+const (
+	BatchPending BatchStatus = "pending" // Not processed yet, or interrupted
+	BatchDone    BatchStatus = "done"    // Processed; the result is stored
+	BatchFailed  BatchStatus = "failed"  // The last attempt failed; a rerun retries it
+)
+
+// BatchEntry is an item of a resumable batch and its outcome
+type BatchEntry struct {
+	Index     int             `json:"index"`
+	Item      json.RawMessage `json:"item"`
+	Status    BatchStatus     `json:"status"`
+	Result    json.RawMessage `json:"result,omitempty"` // Set once the item is done
+	Error     string          `json:"error,omitempty"`  // Error of the last failed attempt
+	Attempts  int             `json:"attempts"`         // Attempts over all runs
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// BatchQueue persists the items of batches and their status, so a batch
+// interrupted by a crash resumes where it stopped
+type BatchQueue interface {
+	// Load returns the entries of a batch by index, none for an unknown batch
+	Load(ctx context.Context, batch string) ([]BatchEntry, error)
+	// Enqueue stores the entries of a new batch
+	Enqueue(ctx context.Context, batch string, entries []BatchEntry) error
+	// Update replaces an entry of a batch
+	Update(ctx context.Context, batch string, entry BatchEntry) error
+	// Remove deletes a batch; removing an unknown batch is not an error
+	Remove(ctx context.Context, batch string) error
+}
+
+// MemoryBatchQueue keeps batches in memory, for tests; its batches do not
+// survive the process
+type MemoryBatchQueue struct {
+	mu      sync.Mutex
+	batches map[string][]BatchEntry
+}
+
+// NewMemoryBatchQueue creates an empty in-memory queue
+func NewMemoryBatchQueue() *MemoryBatchQueue {
+	return &MemoryBatchQueue{batches: make(map[string][]BatchEntry)}
+}
+
+// Load returns the entries of a batch
+func (q *MemoryBatchQueue) Load(ctx context.Context, batch string) ([]BatchEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]BatchEntry(nil), q.batches[batch]...), nil
+}
+
+// Enqueue stores the entries of a new batch
+func (q *MemoryBatchQueue) Enqueue(ctx context.Context, batch string, entries []BatchEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.batches[batch] = append([]BatchEntry(nil), entries...)
+	return nil
+}
+
+// Update replaces an entry of a batch
+func (q *MemoryBatchQueue) Update(ctx context.Context, batch string, entry BatchEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries := q.batches[batch]
+	if entry.Index < 0 || entry.Index >= len(entries) {
+		return fmt.Errorf("batch %s has no item %d", batch, entry.Index)
+	}
+	entries[entry.Index] = entry
+	return nil
+}
+
+// Remove deletes a batch
+func (q *MemoryBatchQueue) Remove(ctx context.Context, batch string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.batches, batch)
+	return nil
+}
+
+// FileBatchQueue keeps each batch in a JSON lines file in a directory: the
+// entries of the batch, followed by an entry for every update, which is
+// synced to disk before Update returns. Load drops a line cut off by a crash.
+type FileBatchQueue struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileBatchQueue creates a queue that keeps its batches in dir, creating it if needed
+func NewFileBatchQueue(dir string) (*FileBatchQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create batch directory: %w", err)
+	}
+	return &FileBatchQueue{dir: dir}, nil
+}
+
+// Load reads the entries of a batch and applies its updates
+func (q *FileBatchQueue) Load(ctx context.Context, batch string) ([]BatchEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	data, err := os.ReadFile(q.path(batch))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch %s: %w", batch, err)
+	}
+
+	// Every line is written with its newline, so a last line without one was
+	// cut off by a crash; it is dropped before updates are appended
+	lines := bytes.Split(data, []byte("\n"))
+	if partial := lines[len(lines)-1]; len(partial) > 0 {
+		if err := os.Truncate(q.path(batch), int64(len(data)-len(partial))); err != nil {
+			return nil, fmt.Errorf("failed to repair batch %s: %w", batch, err)
+		}
+	}
+	lines = lines[:len(lines)-1]
+
+	var entries []BatchEntry
+	for i, line := range lines {
+		var entry BatchEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("invalid entry on line %d of batch %s: %w", i+1, batch, err)
+		}
+		switch {
+		case entry.Index == len(entries):
+			entries = append(entries, entry)
+		case entry.Index >= 0 && entry.Index < len(entries):
+			entries[entry.Index] = entry
+		default:
+			return nil, fmt.Errorf("invalid index %d on line %d of batch %s", entry.Index, i+1, batch)
+		}
+	}
+	return entries, nil
+}
+
+// Enqueue writes the entries of a new batch, replacing the file atomically
+func (q *FileBatchQueue) Enqueue(ctx context.Context, batch string, entries []BatchEntry) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode item %d of batch %s: %w", entry.Index, batch, err)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	temp, err := os.CreateTemp(q.dir, ".batch-*")
+	if err != nil {
+		return fmt.Errorf("failed to write batch %s: %w", batch, err)
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(buffer.Bytes())
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), q.path(batch))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write batch %s: %w", batch, err)
+	}
+	return nil
+}
+
+// Update appends the entry to the file of its batch
+func (q *FileBatchQueue) Update(ctx context.Context, batch string, entry BatchEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode item %d of batch %s: %w", entry.Index, batch, err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	file, err := os.OpenFile(q.path(batch), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to update batch %s: %w", batch, err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update batch %s: %w", batch, err)
+	}
+	return nil
+}
+
+// Remove deletes the file of a batch
+func (q *FileBatchQueue) Remove(ctx context.Context, batch string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := os.Remove(q.path(batch)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove batch %s: %w", batch, err)
+	}
+	return nil
+}
+
+// path returns the file of a batch
+func (q *FileBatchQueue) path(batch string) string {
+	return filepath.Join(q.dir, url.PathEscape(batch)+".jsonl")
+}
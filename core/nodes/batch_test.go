@@ -0,0 +1,158 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+type extractState struct {
+	Documents []string
+	Extracted []string
+	Summary   BatchSummary
+}
+
+func (s *extractState) SetBatchSummary(summary BatchSummary) { s.Summary = summary }
+
+// extractNode upper-cases documents and fails on those in broken
+type extractNode struct {
+	mu     sync.Mutex
+	calls  []string
+	broken map[string]bool
+	onCall func()
+}
+
+func (n *extractNode) Prep(state *extractState) []string { return state.Documents }
+func (n *extractNode) Exec(document string) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls = append(n.calls, document)
+	if n.onCall != nil {
+		n.onCall()
+	}
+	if n.broken[document] {
+		return "", core.Terminal(errors.New("unreadable"))
+	}
+	return strings.ToUpper(document), nil
+}
+func (n *extractNode) ExecFallback(err error) string { return "" }
+func (n *extractNode) Post(state *extractState, documents []string, results ...string) core.Action {
+	state.Extracted = results
+	return core.ActionSuccess
+}
+
+func TestResumableBatchNode(t *testing.T) {
+	queue, err := NewFileBatchQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := &extractNode{broken: map[string]bool{"c": true}}
+	node := core.NewNode(NewResumableBatchNode(extract, queue, func(*extractState) string { return "job-1" }), 0, 2)
+	if node.Name() != "extractNode" {
+		t.Errorf("Name() = %q", node.Name())
+	}
+
+	state := &extractState{Documents: []string{"a", "b", "c"}}
+	node.Run(state)
+	if strings.Join(state.Extracted, ",") != "A,B," {
+		t.Errorf("first run extracted %q", state.Extracted)
+	}
+	want := BatchSummary{Batch: "job-1", Total: 3, Done: 2, Failed: 1}
+	if state.Summary != want {
+		t.Errorf("first run summary = %+v, want %+v", state.Summary, want)
+	}
+	entries, _ := queue.Load(context.Background(), "job-1")
+	if len(entries) != 3 || entries[2].Status != BatchFailed || entries[2].Error != "unreadable" || entries[2].Attempts != 1 {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	// The rerun keeps the queued documents and only extracts the failed one
+	extract.calls, extract.broken = nil, nil
+	state = &extractState{Documents: []string{"a", "b", "c", "d"}}
+	node.Run(state)
+	if strings.Join(extract.calls, ",") != "c" {
+		t.Errorf("rerun extracted %v, want only c", extract.calls)
+	}
+	if strings.Join(state.Extracted, ",") != "A,B,C" {
+		t.Errorf("rerun extracted %q", state.Extracted)
+	}
+	want = BatchSummary{Batch: "job-1", Total: 3, Resumed: 2, Done: 1}
+	if state.Summary != want {
+		t.Errorf("rerun summary = %+v, want %+v", state.Summary, want)
+	}
+
+	// A finished batch is removed, so the next run starts over
+	if entries, _ := queue.Load(context.Background(), "job-1"); len(entries) != 0 {
+		t.Errorf("finished batch still has %d entries", len(entries))
+	}
+}
+
+func TestResumableBatchNode_Cancelled(t *testing.T) {
+	queue := NewMemoryBatchQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	extract := &extractNode{onCall: cancel}
+	flow := core.NewFlow(core.NewNode(NewResumableBatchNode(extract, queue, nil), 0, 1))
+
+	state := &extractState{Documents: []string{"a", "b"}}
+	if action, _ := flow.RunContext(ctx, state, nil); action != core.ActionCancelled {
+		t.Errorf("RunContext() = %v", action)
+	}
+	want := BatchSummary{Batch: "extractNode", Total: 2, Done: 1, Pending: 1}
+	if len(extract.calls) != 1 || state.Summary != want {
+		t.Errorf("calls = %v, summary = %+v", extract.calls, state.Summary)
+	}
+	if entries, _ := queue.Load(context.Background(), "extractNode"); len(entries) != 2 || entries[0].Status != BatchDone || entries[1].Status != BatchPending {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestFileBatchQueue_CutOffLine(t *testing.T) {
+	dir := t.TempDir()
+	queue, _ := NewFileBatchQueue(dir)
+	ctx := context.Background()
+	entries := []BatchEntry{{Index: 0, Item: []byte(`"a"`), Status: BatchPending}, {Index: 1, Item: []byte(`"b"`), Status: BatchPending}}
+	if err := queue.Enqueue(ctx, "crash/1", entries); err != nil {
+		t.Fatal(err)
+	}
+	done := entries[0]
+	done.Status, done.Result = BatchDone, []byte(`"A"`)
+	if err := queue.Update(ctx, "crash/1", done); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of an update leaves half a line
+	path := filepath.Join(dir, "crash%2F1.jsonl")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"index":1,"item":"b","sta`)
+	file.Close()
+
+	loaded, err := queue.Load(ctx, "crash/1")
+	if err != nil || len(loaded) != 2 || loaded[0].Status != BatchDone || loaded[1].Status != BatchPending {
+		t.Fatalf("Load() = %+v, %v", loaded, err)
+	}
+	failed := entries[1]
+	failed.Status = BatchFailed
+	if err := queue.Update(ctx, "crash/1", failed); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := queue.Load(ctx, "crash/1"); err != nil || loaded[1].Status != BatchFailed {
+		t.Errorf("Load() after update = %+v, %v", loaded, err)
+	}
+
+	if err := queue.Remove(ctx, "crash/1"); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := queue.Load(ctx, "crash/1"); err != nil || len(loaded) != 0 {
+		t.Errorf("Load() after Remove = %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,102 @@
+package nodes
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// ActionBudgetExceeded is returned by BudgetNode when a session spent its budget
+const ActionBudgetExceeded core.Action = "budget_exceeded"
+
+// Budget limits what a session may spend; zero values are unlimited
+type Budget struct {
+	MaxTokens    int     `json:"max_tokens"`     // LLM input and output tokens
+	MaxCost      float64 `json:"max_cost"`       // LLM cost in USD, as priced by the usage tracker
+	MaxToolSteps int     `json:"max_tool_steps"` // Tool execution steps of the agent loop
+}
+
+// BudgetUsage is what a session has spent so far
+type BudgetUsage struct {
+	Tokens    int     `json:"tokens"`
+	Cost      float64 `json:"cost"`
+	ToolSteps int     `json:"tool_steps"`
+}
+
+// BudgetExceeded describes the limit a session reached
+type BudgetExceeded struct {
+	Limit   string      `json:"limit"`   // "max_tokens", "max_cost" or "max_tool_steps"
+	Used    string      `json:"used"`    // Usage of that limit
+	Allowed string      `json:"allowed"` // Configured limit
+	Usage   BudgetUsage `json:"usage"`   // Everything spent
+	Message string      `json:"message"` // Human readable explanation
+}
+
+// Check returns the first limit usage has reached, or nil while it is within budget
+func (b Budget) Check(usage BudgetUsage) *BudgetExceeded {
+	exceeded := func(limit, used, allowed, what string) *BudgetExceeded {
+		return &BudgetExceeded{
+			Limit:   limit,
+			Used:    used,
+			Allowed: allowed,
+			Usage:   usage,
+			Message: fmt.Sprintf("The session used %s of its %s %s budget.", used, allowed, what),
+		}
+	}
+
+	switch {
+	case b.MaxTokens > 0 && usage.Tokens >= b.MaxTokens:
+		return exceeded("max_tokens", strconv.Itoa(usage.Tokens), strconv.Itoa(b.MaxTokens), "token")
+	case b.MaxCost > 0 && usage.Cost >= b.MaxCost:
+		return exceeded("max_cost", fmt.Sprintf("$%.4f", usage.Cost), fmt.Sprintf("$%.4f", b.MaxCost), "cost")
+	case b.MaxToolSteps > 0 && usage.ToolSteps >= b.MaxToolSteps:
+		return exceeded("max_tool_steps", strconv.Itoa(usage.ToolSteps), strconv.Itoa(b.MaxToolSteps), "tool step")
+	}
+	return nil
+}
+
+// BudgetStateInterface is implemented by workflow states whose spending is budgeted
+type BudgetStateInterface interface {
+	// GetBudgetUsage returns what the session has spent, e.g. from an llm.UsageTracker
+	GetBudgetUsage() BudgetUsage
+	// SetBudgetExceeded records the limit the session reached
+	SetBudgetExceeded(exceeded BudgetExceeded)
+}
+
+// BudgetNode checks a session's spending before the agent loop continues. Place
+// it before the LLM call of each turn; it returns ActionSuccess while the
+// session is within budget and ActionBudgetExceeded once a limit is reached,
+// so the flow can wrap up instead of looping until it is rate limited.
+type BudgetNode[S BudgetStateInterface] struct {
+	budget Budget
+}
+
+// NewBudgetNode creates a budget node
+func NewBudgetNode[S BudgetStateInterface](budget Budget) *BudgetNode[S] {
+	return &BudgetNode[S]{budget: budget}
+}
+
+// Prep reads the session's usage
+func (n *BudgetNode[S]) Prep(state *S) []BudgetUsage {
+	return []BudgetUsage{(*state).GetBudgetUsage()}
+}
+
+// Exec checks the usage against the budget
+func (n *BudgetNode[S]) Exec(usage BudgetUsage) (*BudgetExceeded, error) {
+	return n.budget.Check(usage), nil
+}
+
+// ExecFallback is not reached because Exec does not fail
+func (n *BudgetNode[S]) ExecFallback(err error) *BudgetExceeded {
+	return nil
+}
+
+// Post records an exceeded budget and routes the flow
+func (n *BudgetNode[S]) Post(state *S, usage []BudgetUsage, results ...*BudgetExceeded) core.Action {
+	if len(results) == 0 || results[0] == nil {
+		return core.ActionSuccess
+	}
+	(*state).SetBudgetExceeded(*results[0])
+	return ActionBudgetExceeded
+}
@@ -0,0 +1,53 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+type budgetState struct {
+	Usage    BudgetUsage
+	Exceeded *BudgetExceeded
+}
+
+func (s *budgetState) GetBudgetUsage() BudgetUsage               { return s.Usage }
+func (s *budgetState) SetBudgetExceeded(exceeded BudgetExceeded) { s.Exceeded = &exceeded }
+
+func TestBudgetNode(t *testing.T) {
+	budget := Budget{MaxTokens: 1000, MaxCost: 0.05, MaxToolSteps: 3}
+	node := core.NewNode(NewBudgetNode[*budgetState](budget), 0, 1)
+
+	tests := []struct {
+		name   string
+		usage  BudgetUsage
+		action core.Action
+		limit  string
+	}{
+		{"within budget", BudgetUsage{Tokens: 999, Cost: 0.01, ToolSteps: 2}, core.ActionSuccess, ""},
+		{"tokens spent", BudgetUsage{Tokens: 1000}, ActionBudgetExceeded, "max_tokens"},
+		{"cost spent", BudgetUsage{Tokens: 10, Cost: 0.06}, ActionBudgetExceeded, "max_cost"},
+		{"tool steps spent", BudgetUsage{ToolSteps: 3}, ActionBudgetExceeded, "max_tool_steps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &budgetState{Usage: tt.usage}
+			if action := node.Run(&state); action != tt.action {
+				t.Errorf("action = %v, expected %v", action, tt.action)
+			}
+			if tt.limit == "" {
+				if state.Exceeded != nil {
+					t.Errorf("unexpected exceeded budget %+v", state.Exceeded)
+				}
+				return
+			}
+			if state.Exceeded == nil || state.Exceeded.Limit != tt.limit || state.Exceeded.Message == "" {
+				t.Errorf("exceeded = %+v, expected limit %s", state.Exceeded, tt.limit)
+			}
+		})
+	}
+
+	if exceeded := (Budget{}).Check(BudgetUsage{Tokens: 1 << 30, Cost: 100, ToolSteps: 100}); exceeded != nil {
+		t.Errorf("an empty budget should be unlimited, got %+v", exceeded)
+	}
+}
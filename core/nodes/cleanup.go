@@ -0,0 +1,290 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// DefaultCleanupPrompt asks the model which earlier tool results the
+// conversation no longer needs
+const DefaultCleanupPrompt = `You keep the context of an AI assistant small. Below are the current request and numbered tool results from earlier in the conversation.
+List the results the assistant no longer needs to answer the request, one per line as "<number>: <one sentence with anything in it still worth knowing>".
+Reply with "none" when every result is still needed.`
+
+// CleanupConfig configures a CleanupNode
+type CleanupConfig struct {
+	Key             string        // Memory key passed to GetMemory
+	KeepRecent      int           // Most recent tool result messages left as they are
+	MaxResultLength int           // Longer tool results are cut to this many characters, keeping their start and end; 0 disables
+	Prompt          string        // Classification prompt; empty uses DefaultCleanupPrompt
+	PreviewLength   int           // Characters of each tool result shown to the classifier
+	Timeout         time.Duration // Timeout for the classification call
+}
+
+// DefaultCleanupConfig leaves the latest tool results alone and cuts older
+// ones to 4000 characters
+func DefaultCleanupConfig() *CleanupConfig {
+	return &CleanupConfig{
+		KeepRecent:      1,
+		MaxResultLength: 4000,
+		PreviewLength:   1000,
+		Timeout:         60 * time.Second,
+	}
+}
+
+// CleanupTask is the part of the history a CleanupNode checks
+type CleanupTask struct {
+	History    []llm.Message // Full history read in Prep
+	Candidates []int         // Indexes of the tool result messages to check
+}
+
+// CleanupResult describes a cleanup performed by a CleanupNode
+type CleanupResult struct {
+	History   []llm.Message // History after the cleanup
+	Removed   int           // Tool result messages the classifier replaced with a note
+	Truncated int           // Tool results cut to MaxResultLength
+	Err       error
+}
+
+// CleanupReporter may be implemented by states that want to observe cleanups
+type CleanupReporter interface {
+	SetCleanupResult(result CleanupResult)
+}
+
+// CleanupNode shrinks older tool results in the conversation history, which
+// are often large and rarely needed again once the model acted on them. Size
+// rules cut results to MaxResultLength without an LLM call. With a classifier
+// provider, the model also names the results the current request no longer
+// needs; they are replaced with a one sentence note, keeping the tool call
+// and result structure intact. Checked messages are tagged llm.TagCleaned and
+// not checked again.
+//
+// It returns ActionSuccess, also when there was nothing to clean up, and
+// ActionFailure when classification failed; the size rules are then still
+// applied. States implementing CleanupReporter receive the outcome.
+type CleanupNode[S MemoryStateInterface] struct {
+	classifier llm.LLMProvider
+	config     *CleanupConfig
+}
+
+// NewCleanupNode creates a cleanup node; a nil classifier applies only the size
+// rules, and a nil config uses DefaultCleanupConfig
+func NewCleanupNode[S MemoryStateInterface](classifier llm.LLMProvider, config *CleanupConfig) *CleanupNode[S] {
+	if config == nil {
+		config = DefaultCleanupConfig()
+	}
+	if config.Prompt == "" {
+		config.Prompt = DefaultCleanupPrompt
+	}
+	return &CleanupNode[S]{classifier: classifier, config: config}
+}
+
+// Prep reads the history and picks the tool result messages to check
+func (n *CleanupNode[S]) Prep(state *S) []CleanupTask {
+	history, err := (*state).GetMemory(n.config.Key).Messages(context.Background())
+	if err != nil {
+		if reporter, ok := any(*state).(CleanupReporter); ok {
+			reporter.SetCleanupResult(CleanupResult{Err: fmt.Errorf("failed to read history: %w", err)})
+		}
+		return []CleanupTask{}
+	}
+
+	var candidates []int
+	kept := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		if len(history[i].ToolResults) == 0 {
+			continue
+		}
+		if kept < n.config.KeepRecent {
+			kept++
+			continue
+		}
+		if !history[i].HasTag(llm.TagCleaned) {
+			candidates = append([]int{i}, candidates...)
+		}
+	}
+	if len(candidates) == 0 {
+		return []CleanupTask{}
+	}
+	return []CleanupTask{{History: history, Candidates: candidates}}
+}
+
+// Exec cleans up the history without the context of the run
+func (n *CleanupNode[S]) Exec(task CleanupTask) (CleanupResult, error) {
+	return n.ExecContext(context.Background(), task)
+}
+
+// ExecContext asks the classifier which candidates can go, cancelling its
+// call with ctx, and cleans up the history
+func (n *CleanupNode[S]) ExecContext(ctx context.Context, task CleanupTask) (CleanupResult, error) {
+	var removed map[int]string
+	if n.classifier != nil {
+		var err error
+		if removed, err = n.classify(ctx, task); err != nil {
+			return CleanupResult{}, err
+		}
+	}
+	return n.clean(task, removed), nil
+}
+
+// ExecFallback records the error; Post still applies the size rules
+func (n *CleanupNode[S]) ExecFallback(err error) CleanupResult {
+	return CleanupResult{Err: err}
+}
+
+// Post replaces the history with the cleaned up one
+func (n *CleanupNode[S]) Post(state *S, prepRes []CleanupTask, execResults ...CleanupResult) core.Action {
+	if len(execResults) == 0 {
+		return core.ActionSuccess
+	}
+
+	result := execResults[0]
+	if result.Err != nil {
+		err := result.Err
+		result = n.clean(prepRes[0], nil)
+		result.Err = err
+	}
+	if err := (*state).GetMemory(n.config.Key).Replace(context.Background(), result.History); err != nil && result.Err == nil {
+		result.Err = fmt.Errorf("failed to store cleaned up history: %w", err)
+	}
+
+	if reporter, ok := any(*state).(CleanupReporter); ok {
+		reporter.SetCleanupResult(result)
+	}
+	if result.Err != nil {
+		return core.ActionFailure
+	}
+	return core.ActionSuccess
+}
+
+// classify returns a note for each candidate the classifier no longer needs,
+// by history index
+func (n *CleanupNode[S]) classify(ctx context.Context, task CleanupTask) (map[int]string, error) {
+	var listing strings.Builder
+	if request := currentRequest(task.History); request != "" {
+		fmt.Fprintf(&listing, "Current request: %s\n\n", request)
+	}
+	for number, index := range task.Candidates {
+		for _, call := range task.History[index].ToolCalls {
+			fmt.Fprintf(&listing, "[%d] %s called with %v\n", number+1, call.ToolName, call.ToolArgs)
+		}
+		if len(task.History[index].ToolCalls) == 0 {
+			fmt.Fprintf(&listing, "[%d]\n", number+1)
+		}
+		fmt.Fprintf(&listing, "%s\n\n", preview(resultContent(task.History[index]), n.config.PreviewLength))
+	}
+
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+	response, err := n.classifier.CallLLM(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: n.config.Prompt},
+		{Role: llm.RoleUser, Content: listing.String()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify %d tool results: %w", len(task.Candidates), err)
+	}
+
+	removed := make(map[int]string)
+	for _, match := range classificationLine.FindAllStringSubmatch(response.Content, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 || number > len(task.Candidates) {
+			continue
+		}
+		removed[task.Candidates[number-1]] = strings.TrimSpace(match[2])
+	}
+	return removed, nil
+}
+
+// classificationLine matches "<number>: <note>" lines of a classifier reply
+var classificationLine = regexp.MustCompile(`(?m)^\W*(\d+)\W*[:.)-]\s*(.*)$`)
+
+// clean replaces the removed candidates with their note and cuts the others
+// to MaxResultLength
+func (n *CleanupNode[S]) clean(task CleanupTask, removed map[int]string) CleanupResult {
+	result := CleanupResult{History: append([]llm.Message(nil), task.History...)}
+	for _, index := range task.Candidates {
+		message := task.History[index]
+		message.ToolResults = append([]llm.ToolResults(nil), message.ToolResults...)
+
+		if note, ok := removed[index]; ok {
+			note = fmt.Sprintf("[Tool result removed from the conversation: %s]", note)
+			for i := range message.ToolResults {
+				message.ToolResults[i].Content = note
+				message.ToolResults[i].Media = nil
+			}
+			message.Content, message.Media, message.MimeType = note, nil, ""
+			result.Removed++
+		} else if n.config.MaxResultLength > 0 {
+			for i := range message.ToolResults {
+				if content, ok := truncate(message.ToolResults[i].Content, n.config.MaxResultLength); ok {
+					message.ToolResults[i].Content = content
+					result.Truncated++
+				}
+			}
+			// The message content repeats all results of the message
+			message.Content, _ = truncate(message.Content, n.config.MaxResultLength*max(len(message.ToolResults), 1))
+		}
+		// Blocks would still hold the results before cleaning, and providers
+		// send blocks instead of the fields
+		message.Blocks = nil
+		result.History[index] = message.WithTags(llm.TagCleaned)
+	}
+	return result
+}
+
+// truncate cuts s to limit characters, keeping its start and end, and reports
+// whether it did
+func truncate(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s, false
+	}
+	head, tail := limit*2/3, limit-limit*2/3
+	return fmt.Sprintf("%s\n[... %d characters cut ...]\n%s", string(runes[:head]), len(runes)-head-tail, string(runes[len(runes)-tail:])), true
+}
+
+// preview returns s cut to limit characters, or all of s when limit is not
+// positive
+func preview(s string, limit int) string {
+	if limit <= 0 {
+		return s
+	}
+	content, _ := truncate(s, limit)
+	return content
+}
+
+// resultContent returns the content of the tool results of message
+func resultContent(message llm.Message) string {
+	if message.Content != "" {
+		return message.Content
+	}
+	contents := make([]string, 0, len(message.ToolResults))
+	for _, result := range message.ToolResults {
+		contents = append(contents, result.Content)
+	}
+	return strings.Join(contents, "\n")
+}
+
+// currentRequest returns the content of the latest user message that is not
+// a tool result
+func currentRequest(history []llm.Message) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == llm.RoleUser && len(history[i].ToolResults) == 0 && !history[i].HasTag(llm.TagHidden) {
+			return history[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func toolTurn(id, tool, content string) []llm.Message {
+	calls := []llm.ToolCalls{{Id: id, ToolName: tool}}
+	return []llm.Message{
+		{Role: llm.RoleAssistant, ToolCalls: calls},
+		{Role: llm.RoleUser, Content: content, ToolCalls: calls, ToolResults: []llm.ToolResults{{Id: id, Content: content}}},
+	}
+}
+
+func cleanupHistory() []llm.Message {
+	history := []llm.Message{{Role: llm.RoleUser, Content: "Which log file is the largest?"}}
+	history = append(history, toolTurn("1", "list_directory", strings.Repeat("app.log ", 100))...)
+	history = append(history, toolTurn("2", "read_file", strings.Repeat("error ", 100))...)
+	return append(history, toolTurn("3", "stat", strings.Repeat("size ", 100))...)
+}
+
+func TestCleanupNode(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       string
+		fail        bool
+		wantAction  core.Action
+		wantRemoved int
+	}{
+		{"size rules only", "", false, core.ActionSuccess, 0},
+		{"classifier", "1: The directory holds app.log.\nnone of the others", false, core.ActionSuccess, 1},
+		{"classifier fails", "", true, core.ActionFailure, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var classifier llm.LLMProvider
+			if tt.reply != "" || tt.fail {
+				provider := llm.NewMockProvider("classifier")
+				provider.SetResponsePattern(map[string]string{"largest": tt.reply})
+				provider.SetError(tt.fail, "rate limited")
+				classifier = provider
+			}
+			config := &CleanupConfig{KeepRecent: 1, MaxResultLength: 60}
+			node := core.NewNode(NewCleanupNode[*chatState](classifier, config), 0, 1)
+			state := newChatState(t, cleanupHistory()...)
+			if action := node.Run(&state); action != tt.wantAction {
+				t.Fatalf("Run() = %v, want %v", action, tt.wantAction)
+			}
+
+			messages, _ := state.History.Messages(context.Background())
+			listing, read, stat := messages[2], messages[4], messages[6]
+			if tt.wantRemoved == 1 {
+				if !strings.Contains(listing.Content, "The directory holds app.log.") || !strings.Contains(listing.ToolResults[0].Content, "removed") {
+					t.Errorf("removed result = %q", listing.ToolResults[0].Content)
+				}
+			} else if !strings.Contains(listing.ToolResults[0].Content, "characters cut") {
+				t.Errorf("older result was not cut: %q", listing.ToolResults[0].Content)
+			}
+			if len([]rune(read.ToolResults[0].Content)) > 100 || !read.HasTag(llm.TagCleaned) || read.ToolResults[0].Id != "2" {
+				t.Errorf("older result = %+v", read)
+			}
+			if stat.HasTag(llm.TagCleaned) || len(stat.ToolResults[0].Content) != 500 {
+				t.Errorf("most recent result was changed: %+v", stat)
+			}
+		})
+	}
+
+	// Cleaned messages are not checked again
+	provider := llm.NewMockProvider("classifier")
+	node := core.NewNode(NewCleanupNode[*chatState](provider, nil), 0, 1)
+	state := newChatState(t, cleanupHistory()...)
+	node.Run(&state)
+	provider.Reset()
+	node.Run(&state)
+	if provider.GetCallCount() != 0 {
+		t.Errorf("classified cleaned messages again")
+	}
+}
@@ -0,0 +1,46 @@
+package nodes
+
+import (
+	"context"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// Reloader applies staged configuration changes, e.g. a hotreload.Watcher
+type Reloader interface {
+	ApplyPending(ctx context.Context) error
+}
+
+// ReloadNode applies configuration changes between the turns of a long-lived
+// flow, so a new model or tool allowlist never takes effect in the middle of an
+// LLM call or a tool call. Place it at the start of each turn. It always
+// returns ActionSuccess: a change that fails to apply is reported by the
+// Reloader and the flow keeps its current settings.
+type ReloadNode[S any] struct {
+	reloader Reloader
+}
+
+// NewReloadNode creates a reload node
+func NewReloadNode[S any](reloader Reloader) *ReloadNode[S] {
+	return &ReloadNode[S]{reloader: reloader}
+}
+
+// Prep runs Exec once per visit
+func (n *ReloadNode[S]) Prep(state *S) []struct{} {
+	return []struct{}{{}}
+}
+
+// Exec applies the pending changes
+func (n *ReloadNode[S]) Exec(struct{}) (struct{}, error) {
+	return struct{}{}, n.reloader.ApplyPending(context.Background())
+}
+
+// ExecFallback keeps the current settings when a change failed to apply
+func (n *ReloadNode[S]) ExecFallback(err error) struct{} {
+	return struct{}{}
+}
+
+// Post continues the flow
+func (n *ReloadNode[S]) Post(state *S, prepResults []struct{}, results ...struct{}) core.Action {
+	return core.ActionSuccess
+}
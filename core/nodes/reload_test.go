@@ -0,0 +1,39 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+type reloaderFunc func(ctx context.Context) error
+
+func (f reloaderFunc) ApplyPending(ctx context.Context) error { return f(ctx) }
+
+func TestReloadNode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"applied", nil},
+		{"failed", errors.New("unknown model")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			node := core.NewNode(NewReloadNode[budgetState](reloaderFunc(func(ctx context.Context) error {
+				calls++
+				return tt.err
+			})), 0, 1)
+			state := &budgetState{}
+			if action := node.Run(state); action != core.ActionSuccess {
+				t.Errorf("action = %v, expected %v", action, core.ActionSuccess)
+			}
+			if calls != 1 {
+				t.Errorf("ApplyPending called %d times, expected 1", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+// MemoryStateInterface is implemented by workflow states that keep conversation
+// history per node key
+type MemoryStateInterface interface {
+	GetMemory(key string) memory.Store
+}
+
+// SummarizerConfig configures a SummarizerNode
+type SummarizerConfig struct {
+	Key            string           // Memory key passed to GetMemory
+	Model          string           // Chat model whose tokenizer and context window size the history
+	MaxTokens      int              // Summarize above this many estimated tokens; 0 uses ContextPercent
+	ContextPercent float64          // Summarize once the prompt uses this percentage of Model's context window; 0 uses 50
+	Trigger        memory.Trigger   // Replaces the token check when set
+	Retention      memory.Retention // Recent messages and tool interactions kept verbatim
+	Timeout        time.Duration    // Timeout for the summary call
+}
+
+// DefaultSummarizerConfig keeps the last six messages and the latest tool interaction
+func DefaultSummarizerConfig() *SummarizerConfig {
+	return &SummarizerConfig{
+		Retention: memory.Retention{KeepMessages: 6, KeepToolInteractions: 1},
+		Timeout:   60 * time.Second,
+	}
+}
+
+// SummaryTask is the part of the history a SummarizerNode condenses
+type SummaryTask struct {
+	History []llm.Message // Full history read in Prep
+	Split   int           // Messages before Split are summarized
+}
+
+// SummaryResult describes a summarization performed by a SummarizerNode
+type SummaryResult struct {
+	Summary      llm.Message
+	Summarized   int // Number of messages replaced by the summary
+	TokensBefore int // Estimated history tokens before and after summarizing
+	TokensAfter  int
+	Err          error
+}
+
+// SummaryReporter may be implemented by states that want to observe summarizations
+type SummaryReporter interface {
+	SetSummaryResult(result SummaryResult)
+}
+
+// HistoryStatsReporter may be implemented by states that want the token stats
+// of the history each time a SummarizerNode checks it, e.g. to log them
+type HistoryStatsReporter interface {
+	SetHistoryStats(key string, stats memory.HistoryStats)
+}
+
+// SummarizerNode condenses older conversation history into a single summary
+// message once it grows past a token budget. The summary is written by a
+// memory.Summarizer, which may use a smaller, cheaper model than the chat.
+// It returns ActionSuccess, also when there was nothing to summarize, and
+// ActionFailure when the summary could not be written; the history is then left
+// as is. States implementing SummaryReporter receive the outcome, and states
+// implementing HistoryStatsReporter the stats of the history it checked.
+type SummarizerNode[S MemoryStateInterface] struct {
+	summarizer memory.Summarizer
+	config     *SummarizerConfig
+}
+
+// NewSummarizerNode creates a summarizer node; a nil config uses DefaultSummarizerConfig
+func NewSummarizerNode[S MemoryStateInterface](summarizer memory.Summarizer, config *SummarizerConfig) *SummarizerNode[S] {
+	if config == nil {
+		config = DefaultSummarizerConfig()
+	}
+	if config.Trigger == nil && config.MaxTokens > 0 {
+		config.Trigger = memory.TokenTrigger(config.MaxTokens, config.Model)
+	}
+	if config.Trigger == nil {
+		percent := config.ContextPercent
+		if percent <= 0 {
+			percent = 50
+		}
+		config.Trigger = memory.ContextTrigger(config.Model, percent)
+	}
+	return &SummarizerNode[S]{summarizer: summarizer, config: config}
+}
+
+// Prep reads the history and decides what to summarize
+func (n *SummarizerNode[S]) Prep(state *S) []SummaryTask {
+	history, err := (*state).GetMemory(n.config.Key).Messages(context.Background())
+	if err != nil {
+		if reporter, ok := any(*state).(SummaryReporter); ok {
+			reporter.SetSummaryResult(SummaryResult{Err: fmt.Errorf("failed to read history: %w", err)})
+		}
+		return []SummaryTask{}
+	}
+	if reporter, ok := any(*state).(HistoryStatsReporter); ok {
+		reporter.SetHistoryStats(n.config.Key, memory.AnalyzeHistory(history, n.config.Model))
+	}
+	if !n.config.Trigger(history) {
+		return []SummaryTask{}
+	}
+
+	split := n.config.Retention.Split(history, n.config.Model)
+	if split <= 1 {
+		return []SummaryTask{}
+	}
+	return []SummaryTask{{History: history, Split: split}}
+}
+
+// Exec summarizes the messages before the split without the context of the run
+func (n *SummarizerNode[S]) Exec(task SummaryTask) (SummaryResult, error) {
+	return n.ExecContext(context.Background(), task)
+}
+
+// ExecContext summarizes the messages before the split, cancelling the
+// summarizer with ctx
+func (n *SummarizerNode[S]) ExecContext(ctx context.Context, task SummaryTask) (SummaryResult, error) {
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+
+	summary, err := n.summarizer.Summarize(ctx, task.History[:task.Split])
+	if err != nil {
+		return SummaryResult{}, fmt.Errorf("failed to summarize %d messages: %w", task.Split, err)
+	}
+	return SummaryResult{
+		Summary:      summary,
+		Summarized:   task.Split,
+		TokensBefore: memory.EstimateMessageTokens(task.History, n.config.Model),
+	}, nil
+}
+
+// ExecFallback records the error; the history is left unchanged
+func (n *SummarizerNode[S]) ExecFallback(err error) SummaryResult {
+	return SummaryResult{Err: err}
+}
+
+// Post replaces the summarized messages with the summary
+func (n *SummarizerNode[S]) Post(state *S, prepRes []SummaryTask, execResults ...SummaryResult) core.Action {
+	if len(execResults) == 0 {
+		return core.ActionSuccess
+	}
+
+	result := execResults[0]
+	if result.Err == nil {
+		task := prepRes[0]
+		messages := append([]llm.Message{result.Summary}, task.History[task.Split:]...)
+		result.TokensAfter = memory.EstimateMessageTokens(messages, n.config.Model)
+		if err := (*state).GetMemory(n.config.Key).Replace(context.Background(), messages); err != nil {
+			result.Err = fmt.Errorf("failed to store summary: %w", err)
+		}
+	}
+
+	if reporter, ok := any(*state).(SummaryReporter); ok {
+		reporter.SetSummaryResult(result)
+	}
+	if result.Err != nil {
+		return core.ActionFailure
+	}
+	return core.ActionSuccess
+}
@@ -0,0 +1,106 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+type chatState struct {
+	History memory.Store
+	Summary SummaryResult
+	Stats   memory.HistoryStats
+}
+
+func (s *chatState) GetMemory(string) memory.Store      { return s.History }
+func (s *chatState) SetSummaryResult(res SummaryResult) { s.Summary = res }
+func (s *chatState) SetHistoryStats(_ string, stats memory.HistoryStats) {
+	s.Stats = stats
+}
+
+func newChatState(t *testing.T, messages ...llm.Message) *chatState {
+	t.Helper()
+	return &chatState{History: memory.NewInMemoryStore(messages...)}
+}
+
+func longTurns(n int) []llm.Message {
+	messages := make([]llm.Message, 0, n)
+	for i := 1; i <= n; i++ {
+		messages = append(messages, llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("turn %d %s", i, strings.Repeat("details ", 50))})
+	}
+	return messages
+}
+
+func TestSummarizerNode(t *testing.T) {
+	provider := llm.NewMockProvider("summary-model")
+	provider.SetResponsePattern(map[string]string{"turn 1": "The user shared details over several turns."})
+
+	config := &SummarizerConfig{MaxTokens: 200, Retention: memory.Retention{KeepMessages: 2}}
+	node := core.NewNode(NewSummarizerNode[*chatState](memory.NewLLMSummarizer(provider, ""), config), 1, 1)
+
+	state := newChatState(t, longTurns(6)...)
+	if action := node.Run(&state); action != core.ActionSuccess {
+		t.Fatalf("action = %v, result = %+v", action, state.Summary)
+	}
+
+	messages, _ := state.History.Messages(context.Background())
+	if len(messages) != 3 || !strings.HasPrefix(messages[0].Content, memory.SummaryPrefix) {
+		t.Fatalf("expected a summary and two kept messages, got %d messages", len(messages))
+	}
+	if !strings.HasPrefix(messages[1].Content, "turn 5") {
+		t.Errorf("first kept message = %q", messages[1].Content)
+	}
+	if state.Summary.Summarized != 4 || state.Summary.TokensAfter >= state.Summary.TokensBefore {
+		t.Errorf("summary result = %+v", state.Summary)
+	}
+	if state.Stats.Messages != 6 || state.Stats.Tokens != state.Summary.TokensBefore {
+		t.Errorf("history stats = %+v, want the 6 messages checked", state.Stats)
+	}
+
+	// Below the budget nothing happens
+	provider.Reset()
+	if action := node.Run(&state); action != core.ActionSuccess || provider.GetCallCount() != 0 {
+		t.Errorf("summarized below the budget: action = %v, calls = %d", action, provider.GetCallCount())
+	}
+}
+
+func TestSummarizerNode_PreservesToolInteractions(t *testing.T) {
+	provider := llm.NewMockProvider("summary-model")
+	call := llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "read_file"}}}
+	result := llm.Message{Role: llm.RoleUser, Content: strings.Repeat("file contents ", 100), ToolCalls: call.ToolCalls, ToolResults: []llm.ToolResults{{Id: "1"}}}
+
+	config := &SummarizerConfig{MaxTokens: 100, Retention: memory.Retention{KeepMessages: 1, KeepToolInteractions: 1}}
+	node := core.NewNode(NewSummarizerNode[*chatState](memory.NewLLMSummarizer(provider, ""), config), 0, 1)
+
+	state := newChatState(t, append(longTurns(3), call, result, llm.Message{Role: llm.RoleUser, Content: "thanks"})...)
+	node.Run(&state)
+
+	messages, _ := state.History.Messages(context.Background())
+	if len(messages) != 4 || len(messages[1].ToolCalls) != 1 || len(messages[2].ToolResults) != 1 {
+		t.Errorf("tool interaction was not kept verbatim: %+v", messages)
+	}
+}
+
+func TestSummarizerNode_Failure(t *testing.T) {
+	provider := llm.NewMockProvider("summary-model")
+	provider.SetError(true, "rate limited")
+
+	config := &SummarizerConfig{Trigger: memory.MessageCountTrigger(3), Retention: memory.Retention{KeepMessages: 1}}
+	node := core.NewNode(NewSummarizerNode[*chatState](memory.NewLLMSummarizer(provider, ""), config), 0, 1)
+
+	state := newChatState(t, longTurns(5)...)
+	if action := node.Run(&state); action != core.ActionFailure {
+		t.Errorf("expected ActionFailure, got %v", action)
+	}
+	if state.Summary.Err == nil {
+		t.Error("expected the error to be reported")
+	}
+	if n, _ := state.History.Len(context.Background()); n != 5 {
+		t.Errorf("history should be unchanged, Len = %d", n)
+	}
+}
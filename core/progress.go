@@ -0,0 +1,66 @@
+package core
+
+// Progress is a progress update of a flow run
+type Progress struct {
+	Step        int     `json:"step"`                  // Step of the run; steps of nested flows count as one step
+	Node        string  `json:"node"`                  // Node the update is from
+	Done        int     `json:"done"`                  // Items of the node finished
+	Total       int     `json:"total"`                 // Items the node's Prep returned; 0 for a StreamPrepper, whose total is not known
+	Percent     float64 `json:"percent"`               // Done of Total in percent
+	Description string  `json:"description,omitempty"` // The finished item, as described by the node
+	Labels      Labels  `json:"labels,omitempty"`      // Labels of the run and the node, see Flow.SetLabels
+}
+
+// ProgressReporter receives the progress updates of a flow run: one when a
+// node starts its items, with Done 0, and one after each finished item. Items
+// of a node run on several workers report from those workers, one at a time,
+// so the reporter should return quickly.
+type ProgressReporter func(progress Progress)
+
+// ItemDescriber may be implemented by a BaseNode to describe its items in
+// progress updates, e.g. by the file an item parses
+type ItemDescriber[PrepResult any] interface {
+	DescribeItem(item PrepResult) string
+}
+
+// SetProgressReporter sets the reporter that receives the progress of the
+// flow's runs, including the nodes of nested flows; nil removes it. Runs of
+// nested flows report to the flow that started the run.
+func (f *Flow[State]) SetProgressReporter(reporter ProgressReporter) {
+	f.progress = reporter
+}
+
+// startStep records the step of the run nodes report progress for
+func (r *flowRun) startStep(step int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.step = step
+}
+
+// startItems reports that node, labeled with labels, starts running total items
+func (r *flowRun) startItems(node string, total int, labels Labels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.node, r.done, r.total, r.nodeLabels = node, 0, total, r.labels.Merge(labels)
+	r.progress(Progress{Step: r.step, Node: node, Total: total, Labels: r.nodeLabels})
+}
+
+// itemDone reports that the running node finished an item
+func (r *flowRun) itemDone(description string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.done++
+	var percent float64
+	if r.total > 0 {
+		percent = float64(r.done) * 100 / float64(r.total)
+	}
+	r.progress(Progress{
+		Step:        r.step,
+		Node:        r.node,
+		Done:        r.done,
+		Total:       r.total,
+		Percent:     percent,
+		Description: description,
+		Labels:      r.nodeLabels,
+	})
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// describedNode is a benchNode that describes its items
+type describedNode struct {
+	benchNode
+}
+
+func (n *describedNode) DescribeItem(item int) string {
+	return fmt.Sprintf("item %d", item)
+}
+
+func TestFlow_ProgressReporter(t *testing.T) {
+	first := NewNode[benchState, int, int](&benchNode{action: actionDone}, 0, 4)
+	first.AddSuccessor(NewNode[benchState, int, int](&describedNode{}, 0, 1), actionDone)
+	flow := NewFlow[benchState](first)
+
+	var mu sync.Mutex
+	var updates []Progress
+	flow.SetProgressReporter(func(progress Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		updates = append(updates, progress)
+	})
+	flow.Run(&benchState{Items: benchItems(10)})
+
+	// One update when each node starts and one per item
+	if len(updates) != 22 {
+		t.Fatalf("got %d updates, want 22", len(updates))
+	}
+	for i, update := range updates {
+		step, done := 1, i
+		if i > 10 {
+			step, done = 2, i-11
+		}
+		if update.Step != step || update.Done != done || update.Total != 10 {
+			t.Errorf("update %d = %+v, want step %d, %d done", i, update, step, done)
+		}
+	}
+	if last := updates[21]; last.Percent != 100 || last.Node != "describedNode" || last.Description != "item 9" {
+		t.Errorf("last update = %+v", last)
+	}
+	if updates[10].Description != "" {
+		t.Errorf("update of a node without descriptions = %+v", updates[10])
+	}
+
+	flow.SetProgressReporter(nil)
+	if run := flow.newRun(); run != nil {
+		t.Errorf("newRun() without a reporter = %+v, want nil", run)
+	}
+}
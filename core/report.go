@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// RunReport describes how a flow run spent its retries, why it stopped early
+// and the configuration it started with
+type RunReport struct {
+	Retries         int            `json:"retries"`                // Retries spent by all nodes
+	RetriesByNode   map[string]int `json:"retries_by_node"`        // Retries spent by each node, by name
+	RetryBudget     int            `json:"retry_budget"`           // Retries allowed, 0 for no limit
+	BudgetExhausted bool           `json:"budget_exhausted"`       // A retry was refused because the budget was spent
+	ExhaustedBy     string         `json:"exhausted_by,omitempty"` // The node whose retry was refused first
+
+	UndeclaredAction Action `json:"undeclared_action,omitempty"` // Action a step returned that its flow did not declare
+	UndeclaredBy     string `json:"undeclared_by,omitempty"`     // The workflow that returned it
+
+	Panics []PanicReport `json:"panics,omitempty"` // Items whose Exec panicked, in the order they did
+
+	Cancelled   bool   `json:"cancelled,omitempty"`    // The run ended because its context was cancelled, see Flow.RunContext
+	CancelCause string `json:"cancel_cause,omitempty"` // Why the context was cancelled
+
+	Aborted bool `json:"aborted,omitempty"` // A step hook aborted the run, see Flow.SetStepHook
+
+	StateDiffs []StateDiff `json:"state_diffs,omitempty"` // How each step changed the state, see Flow.SetStateDiff
+
+	Labels       Labels            `json:"labels,omitempty"`         // Labels of the run, see Flow.SetLabels
+	LabelsByNode map[string]Labels `json:"labels_by_node,omitempty"` // Labels each node ran with, by name
+
+	Config      json.RawMessage `json:"config,omitempty"`       // Snapshot of the configuration the run started with, see Flow.SetConfigSnapshot
+	ConfigError string          `json:"config_error,omitempty"` // Why the snapshot could not be taken
+}
+
+// PanicReport describes an Exec that panicked; the item went to ExecFallback
+type PanicReport struct {
+	Node  string `json:"node"`  // The node whose Exec panicked
+	Value string `json:"value"` // The value passed to panic
+	Stack string `json:"stack"` // The stack of the panicking goroutine
+}
+
+// ErrorHandler handles a flow run that cannot go on, e.g. because its retry
+// budget is exhausted or a step returned an undeclared action, and returns the
+// action the run ends with
+type ErrorHandler[State any] func(state *State, report RunReport) Action
+
+// flowRun is the accounting of one flow run, shared by its nodes and nested
+// flows; nodes may take retries from several workers at once
+type flowRun struct {
+	mu     sync.Mutex
+	report RunReport
+
+	// Where nodes report their progress, if anywhere, the step they run in
+	// and the items of the running node; the steps of a run are sequential
+	progress    ProgressReporter
+	step        int
+	node        string
+	nodeLabels  Labels
+	done, total int
+
+	// Labels of the run
+	labels Labels
+
+	// Context of the run; nil unless it was started with RunContext
+	ctx context.Context
+
+	// Whether the steps of the run record how they change the state
+	stateDiff bool
+
+	// StepHook of the flow that started the run, if it has one
+	stepHook any
+}
+
+// diffsState reports whether the steps of run record how they change the state
+func (r *flowRun) diffsState() bool {
+	return r != nil && r.stateDiff
+}
+
+// recordDiff records how a step changed the state
+func (r *flowRun) recordDiff(diff StateDiff) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.StateDiffs = append(r.report.StateDiffs, diff)
+}
+
+// reportsProgress reports whether the nodes of run report their progress
+func (r *flowRun) reportsProgress() bool {
+	return r != nil && r.progress != nil
+}
+
+// takeRetry records a retry of node, or reports false when the budget is spent
+func (r *flowRun) takeRetry(node string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report.RetryBudget > 0 && r.report.Retries >= r.report.RetryBudget {
+		if !r.report.BudgetExhausted {
+			r.report.BudgetExhausted = true
+			r.report.ExhaustedBy = node
+		}
+		return false
+	}
+	r.report.Retries++
+	if r.report.RetriesByNode == nil {
+		r.report.RetriesByNode = make(map[string]int)
+	}
+	r.report.RetriesByNode[node]++
+	return true
+}
+
+// reject records that node returned an undeclared action
+func (r *flowRun) reject(node string, action Action) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.UndeclaredAction = action
+	r.report.UndeclaredBy = node
+}
+
+// recordPanic records that an Exec of node panicked
+func (r *flowRun) recordPanic(node string, panicked *PanicError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Panics = append(r.report.Panics, PanicReport{
+		Node:  node,
+		Value: fmt.Sprint(panicked.Value),
+		Stack: string(panicked.Stack),
+	})
+}
+
+// exhausted reports whether a retry was refused
+func (r *flowRun) exhausted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.report.BudgetExhausted
+}
+
+// snapshot returns a copy of the report
+func (r *flowRun) snapshot() RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	report.Panics = append([]PanicReport(nil), r.report.Panics...)
+	report.StateDiffs = append([]StateDiff(nil), r.report.StateDiffs...)
+	if r.report.LabelsByNode != nil {
+		report.LabelsByNode = make(map[string]Labels, len(r.report.LabelsByNode))
+		for node, labels := range r.report.LabelsByNode {
+			report.LabelsByNode[node] = labels
+		}
+	}
+	if r.report.RetriesByNode != nil {
+		report.RetriesByNode = make(map[string]int, len(r.report.RetriesByNode))
+		for node, retries := range r.report.RetriesByNode {
+			report.RetriesByNode[node] = retries
+		}
+	}
+	return report
+}
+
+// runner is implemented by the workflows of this package, which run as part
+// of an enclosing flow run rather than on their own
+type runner[State any] interface {
+	runIn(state *State, run *flowRun) Action
+}
+
+// runStep runs workflow as a step of run, which is nil when nothing is accounted
+func runStep[State any](workflow Workflow[State], state *State, run *flowRun) Action {
+	if run != nil {
+		if r, ok := workflow.(runner[State]); ok {
+			return r.runIn(state, run)
+		}
+	}
+	return workflow.Run(state)
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// flakyNode fails every Exec and counts the calls
+type flakyNode struct {
+	benchNode
+	calls atomic.Int32
+}
+
+func (n *flakyNode) Exec(item int) (int, error) {
+	n.calls.Add(1)
+	return 0, errors.New("service unavailable")
+}
+
+// flakyTail is a flakyNode with a name of its own
+type flakyTail struct{ flakyNode }
+
+func TestFlow_RetryBudget(t *testing.T) {
+	newChain := func() (*flakyNode, *flakyTail, *benchNode, Workflow[benchState]) {
+		head := &flakyNode{benchNode: benchNode{action: ActionContinue}}
+		tail := &flakyTail{flakyNode{benchNode: benchNode{action: ActionContinue}}}
+		last := &benchNode{action: ActionSuccess}
+		first := NewNode[benchState, int, int](head, 2, 1)
+		first.AddSuccessor(NewNode[benchState, int, int](tail, 5, 1), ActionContinue).
+			AddSuccessor(NewNode[benchState, int, int](last, 0, 1), ActionContinue)
+		return head, tail, last, first
+	}
+	exhausted := Action("budget_exhausted")
+
+	t.Run("unlimited", func(t *testing.T) {
+		_, tail, _, first := newChain()
+		action, report := NewFlow[benchState](first).RunWithReport(&benchState{Items: benchItems(1)}, nil)
+		if action != ActionSuccess || tail.calls.Load() != 6 {
+			t.Errorf("RunWithReport() = %v with %d tail calls", action, tail.calls.Load())
+		}
+		want := RunReport{Retries: 7, RetriesByNode: map[string]int{"flakyNode": 2, "flakyTail": 5}}
+		if !reflect.DeepEqual(report, want) {
+			t.Errorf("report = %+v, want %+v", report, want)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		head, tail, _, first := newChain()
+		flow := NewFlow[benchState](first)
+		flow.SetRetryBudget(3)
+		var handled RunReport
+		flow.SetErrorHandler(func(state *benchState, report RunReport) Action {
+			handled = report
+			return exhausted
+		})
+
+		state := &benchState{Items: benchItems(1)}
+		if action := flow.Run(state); action != exhausted {
+			t.Errorf("Run() = %v, want the error handler's action", action)
+		}
+		if head.calls.Load() != 3 || tail.calls.Load() != 2 || state.Sum != 0 {
+			t.Errorf("calls = %d, %d; sum %d", head.calls.Load(), tail.calls.Load(), state.Sum)
+		}
+		want := RunReport{
+			Retries:         3,
+			RetriesByNode:   map[string]int{"flakyNode": 2, "flakyTail": 1},
+			RetryBudget:     3,
+			BudgetExhausted: true,
+			ExhaustedBy:     "flakyTail",
+		}
+		if !reflect.DeepEqual(handled, want) {
+			t.Errorf("report = %+v, want %+v", handled, want)
+		}
+	})
+
+	t.Run("nested flow", func(t *testing.T) {
+		_, tail, _, first := newChain()
+		after := &benchNode{action: ActionSuccess}
+		inner := NewFlow[benchState](first)
+		inner.AddSuccessor(NewNode[benchState, int, int](after, 0, 1), ActionFailure)
+		outer := NewFlow[benchState](inner)
+		outer.SetRetryBudget(2)
+
+		state := &benchState{Items: benchItems(1)}
+		action, report := outer.RunWithReport(state, nil)
+		if action != ActionFailure || !report.BudgetExhausted || report.ExhaustedBy != "flakyTail" {
+			t.Errorf("RunWithReport() = %v, %+v", action, report)
+		}
+		if tail.calls.Load() != 1 || state.Sum != 0 {
+			t.Errorf("expected the run to stop in the nested flow, tail calls %d, sum %d", tail.calls.Load(), state.Sum)
+		}
+	})
+
+	t.Run("concurrent workers", func(t *testing.T) {
+		node := &flakyNode{benchNode: benchNode{action: ActionSuccess}}
+		flow := NewFlow[benchState](NewNode[benchState, int, int](node, 3, 4))
+		flow.SetRetryBudget(5)
+		action, report := flow.RunWithReport(&benchState{Items: benchItems(10)}, nil)
+		if action != ActionFailure || report.Retries != 5 || node.calls.Load() != 15 {
+			t.Errorf("RunWithReport() = %v, %+v with %d calls", action, report, node.calls.Load())
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SetConfigSnapshot sets the function that returns the configuration runs of
+// the flow start with, e.g. the models, prompts, tool allowlists and limits
+// in effect. It is called once when a run starts and its result is kept as
+// JSON in RunReport.Config, so later config changes do not alter the report.
+// The snapshot should leave out secrets such as API keys; nil removes it.
+// Runs of nested flows keep the snapshot of the flow that starts the run.
+func (f *Flow[State]) SetConfigSnapshot(snapshot func() any) {
+	f.config = snapshot
+}
+
+// snapshotConfig encodes the configuration snapshot returns for a run report
+func snapshotConfig(snapshot func() any) (json.RawMessage, string) {
+	config, err := json.Marshal(snapshot())
+	if err != nil {
+		return nil, fmt.Sprintf("failed to encode config: %v", err)
+	}
+	return config, ""
+}
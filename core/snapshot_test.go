@@ -0,0 +1,54 @@
+package core
+
+import "testing"
+
+// configNode changes the model of config when it runs
+type configNode struct {
+	benchNode
+	config *testConfig
+}
+
+func (n *configNode) Post(state *benchState, prepResults []int, execResults ...int) Action {
+	n.config.Model = "changed"
+	return n.action
+}
+
+type testConfig struct {
+	Model string   `json:"model"`
+	Tools []string `json:"tools"`
+}
+
+func TestFlow_ConfigSnapshot(t *testing.T) {
+	config := &testConfig{Model: "small", Tools: []string{"search"}}
+	flow := NewFlow[benchState](NewNode[benchState, int, int](&configNode{benchNode: benchNode{action: ActionSuccess}, config: config}, 0, 1))
+	flow.SetConfigSnapshot(func() any { return config })
+
+	_, report := flow.RunWithReport(&benchState{}, nil)
+	if want := `{"model":"small","tools":["search"]}`; string(report.Config) != want || report.ConfigError != "" {
+		t.Errorf("Config = %s (%s), want %s", report.Config, report.ConfigError, want)
+	}
+	_, report = flow.RunWithReport(&benchState{}, nil)
+	if want := `{"model":"changed","tools":["search"]}`; string(report.Config) != want {
+		t.Errorf("Config of the next run = %s, want %s", report.Config, want)
+	}
+
+	t.Run("error handler", func(t *testing.T) {
+		flow.DeclareActions(ActionFailure)
+		var handled RunReport
+		flow.SetErrorHandler(func(state *benchState, report RunReport) Action {
+			handled = report
+			return ActionFailure
+		})
+		flow.Run(&benchState{})
+		if len(handled.Config) == 0 {
+			t.Error("the error handler got no config snapshot")
+		}
+	})
+
+	t.Run("unencodable", func(t *testing.T) {
+		flow.SetConfigSnapshot(func() any { return make(chan int) })
+		if _, report := flow.RunWithReport(&benchState{}, nil); report.Config != nil || report.ConfigError == "" {
+			t.Errorf("report = %+v, want a config error", report)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Key is a typed key of a map state. Declaring each key once, e.g.
+//
+//	var InputData = core.Key[[]int]("input_data")
+//
+// replaces the string literals and type assertions of map[string]any states,
+// so a misspelled key is a compile error and a value of the wrong type is never
+// read as the right one.
+type Key[T any] string
+
+// Get returns the value of the key and whether it is set with a value of type T
+func (k Key[T]) Get(state map[string]any) (T, bool) {
+	value, ok := state[string(k)].(T)
+	return value, ok
+}
+
+// Value returns the value of the key, or the zero value of T when it is not set
+// or holds another type
+func (k Key[T]) Value(state map[string]any) T {
+	value, _ := k.Get(state)
+	return value
+}
+
+// Set stores the value of the key
+func (k Key[T]) Set(state map[string]any, value T) {
+	state[string(k)] = value
+}
+
+// Delete removes the key from the state
+func (k Key[T]) Delete(state map[string]any) {
+	delete(state, string(k))
+}
+
+// Field is a typed accessor for a field of a struct state S. The field is found
+// by reflection once, when the Field is created, so a misspelled name or a wrong
+// type fails at startup instead of in the middle of a flow. Nodes with a struct
+// state usually read and write its fields directly; Field is for code that is
+// written once for many state types, such as reusable nodes configured with the
+// fields they use.
+type Field[S any, T any] struct {
+	name  string
+	index []int
+}
+
+// NewField finds the field of S called name, by its Go name or its JSON name,
+// and checks that it is exported and of type T. Fields of embedded structs are
+// found as well.
+func NewField[S any, T any](name string) (Field[S, T], error) {
+	stateType := reflect.TypeOf((*S)(nil)).Elem()
+	if stateType.Kind() != reflect.Struct {
+		return Field[S, T]{}, fmt.Errorf("state type %s is not a struct", stateType)
+	}
+	field, ok := findField(stateType, name)
+	if !ok {
+		return Field[S, T]{}, fmt.Errorf("state type %s has no field %s", stateType, name)
+	}
+	if !field.IsExported() {
+		return Field[S, T]{}, fmt.Errorf("field %s of state type %s is not exported", field.Name, stateType)
+	}
+	valueType := reflect.TypeOf((*T)(nil)).Elem()
+	if field.Type != valueType {
+		return Field[S, T]{}, fmt.Errorf("field %s of state type %s is %s, not %s", field.Name, stateType, field.Type, valueType)
+	}
+	return Field[S, T]{name: field.Name, index: field.Index}, nil
+}
+
+// MustField is NewField that panics on error, for package-level fields
+func MustField[S any, T any](name string) Field[S, T] {
+	f, err := NewField[S, T](name)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Name returns the Go name of the field
+func (f Field[S, T]) Name() string {
+	return f.name
+}
+
+// Get returns the value of the field. A nil pointer to an embedded struct on
+// the way reads as the zero value.
+func (f Field[S, T]) Get(state *S) T {
+	var zero T
+	value, err := reflect.ValueOf(state).Elem().FieldByIndexErr(f.index)
+	if err != nil {
+		return zero
+	}
+	return value.Interface().(T)
+}
+
+// Set stores the value of the field, allocating nil embedded struct pointers on the way
+func (f Field[S, T]) Set(state *S, value T) {
+	target := reflect.ValueOf(state).Elem()
+	for i, index := range f.index {
+		if i > 0 && target.Kind() == reflect.Pointer {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		target = target.Field(index)
+	}
+	target.Set(reflect.ValueOf(&value).Elem())
+}
+
+// findField looks a field up by its Go name, then by its JSON name
+func findField(stateType reflect.Type, name string) (reflect.StructField, bool) {
+	if field, ok := stateType.FieldByName(name); ok {
+		return field, true
+	}
+	for _, field := range reflect.VisibleFields(stateType) {
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name && !field.Anonymous {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+var (
+	inputData  = Key[[]int]("input_data")
+	totalCount = Key[int]("total_count")
+)
+
+func TestKey(t *testing.T) {
+	state := State{"input_data": []int{1, 2}, "total_count": "three"}
+
+	if got, ok := inputData.Get(state); !ok || len(got) != 2 {
+		t.Errorf("Get(input_data) = %v, %v", got, ok)
+	}
+	if got, ok := totalCount.Get(state); ok || got != 0 {
+		t.Errorf("Get of a value of another type = %v, %v; want 0, false", got, ok)
+	}
+
+	totalCount.Set(state, 3)
+	if got := totalCount.Value(state); got != 3 {
+		t.Errorf("Value(total_count) = %d, want 3", got)
+	}
+	inputData.Delete(state)
+	if _, ok := state["input_data"]; ok {
+		t.Error("Delete left input_data in the state")
+	}
+	if got := inputData.Value(state); got != nil {
+		t.Errorf("Value of a missing key = %v, want nil", got)
+	}
+}
+
+type Metrics struct {
+	Processed int
+}
+
+type pipelineState struct {
+	Input   []int  `json:"input"`
+	Results []int  `json:"results,omitempty"`
+	Status  string `json:"status"`
+	*Metrics
+	secret string
+}
+
+func TestNewField(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   func() error
+		wantErr string
+	}{
+		{"Go name", func() error { _, err := NewField[pipelineState, []int]("Input"); return err }, ""},
+		{"JSON name", func() error { _, err := NewField[pipelineState, []int]("results"); return err }, ""},
+		{"embedded field", func() error { _, err := NewField[pipelineState, int]("Processed"); return err }, ""},
+		{"misspelled name", func() error { _, err := NewField[pipelineState, string]("Stauts"); return err }, "has no field Stauts"},
+		{"wrong type", func() error { _, err := NewField[pipelineState, int]("status"); return err }, "is string, not int"},
+		{"unexported field", func() error { _, err := NewField[pipelineState, string]("secret"); return err }, "is not exported"},
+		{"not a struct", func() error { _, err := NewField[State, int]("total_count"); return err }, "is not a struct"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.field()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("NewField failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("NewField error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMustField_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustField of a missing field did not panic")
+		}
+	}()
+	MustField[pipelineState, int]("Missing")
+}
+
+func TestField_GetSet(t *testing.T) {
+	status := MustField[pipelineState, string]("status")
+	processed := MustField[pipelineState, int]("Processed")
+	state := &pipelineState{}
+
+	if got := processed.Get(state); got != 0 {
+		t.Errorf("Get through a nil embedded pointer = %d, want 0", got)
+	}
+	processed.Set(state, 4)
+	if state.Metrics == nil || state.Processed != 4 {
+		t.Errorf("Set did not allocate the embedded struct: %+v", state)
+	}
+	status.Set(state, "done")
+	if state.Status != "done" || status.Get(state) != "done" || status.Name() != "Status" {
+		t.Errorf("status field = %q, name %q", state.Status, status.Name())
+	}
+}
+
+// doubler doubles the input of a struct state with no type assertions
+type doubler struct{}
+
+func (d *doubler) Prep(state *pipelineState) []int {
+	return state.Input
+}
+
+func (d *doubler) Exec(n int) (int, error) {
+	return n * 2, nil
+}
+
+func (d *doubler) Post(state *pipelineState, prepResults []int, execResults ...int) Action {
+	state.Results = execResults
+	return ActionContinue
+}
+
+func (d *doubler) ExecFallback(err error) int {
+	return 0
+}
+
+// recorder writes a status through a Field, as a reusable node would
+type recorder struct {
+	status Field[pipelineState, string]
+}
+
+func (r *recorder) Prep(state *pipelineState) []int {
+	return state.Results
+}
+
+func (r *recorder) Exec(n int) (int, error) {
+	return n, nil
+}
+
+func (r *recorder) Post(state *pipelineState, prepResults []int, execResults ...int) Action {
+	r.status.Set(state, "done")
+	return ActionSuccess
+}
+
+func (r *recorder) ExecFallback(err error) int {
+	return 0
+}
+
+func TestFlow_StructState(t *testing.T) {
+	double := NewNode[pipelineState, int, int](&doubler{}, 1, 2)
+	record := NewNode[pipelineState, int, int](&recorder{status: MustField[pipelineState, string]("status")}, 1, 1)
+	double.AddSuccessor(record, ActionContinue)
+
+	state := &pipelineState{Input: []int{1, 2, 3}}
+	if action := NewFlow[pipelineState](double).Run(state); action != ActionSuccess {
+		t.Fatalf("flow ended with %s, want success", action)
+	}
+	if len(state.Results) != 3 || state.Results[0] != 2 || state.Results[2] != 6 || state.Status != "done" {
+		t.Errorf("state = %+v", state)
+	}
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxDiffValueLength is the length values in state diffs are cut to
+	maxDiffValueLength = 200
+
+	// maxDiffChanges is the number of changes a state diff lists per step
+	maxDiffChanges = 100
+)
+
+// StateDiff describes how a step of a flow run changed the state
+type StateDiff struct {
+	Step    int           `json:"step"`
+	Node    string        `json:"node"`
+	Changes []StateChange `json:"changes,omitempty"`
+	Omitted int           `json:"omitted,omitempty"` // Changes left out after the first 100
+	Error   string        `json:"error,omitempty"`   // Why the state could not be compared
+}
+
+// StateChange is a value a step added, removed or changed. Paths name keys
+// and fields by their JSON names, e.g. "messages[3].content".
+type StateChange struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`             // "added", "removed" or "changed"
+	Before string `json:"before,omitempty"` // JSON of the value before the step, cut to 200 characters
+	After  string `json:"after,omitempty"`  // JSON of the value after the step, cut to 200 characters
+}
+
+// Kinds of state changes
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// SetStateDiff makes runs of the flow record how each step changed the state
+// in RunReport.StateDiffs, to find the node that corrupted or dropped data.
+// The state is encoded as JSON before and after each step, so unexported
+// fields are not compared and large states slow the run down; it is meant
+// for debugging. Steps of nested flows are compared as one step of the flow
+// that starts the run.
+func (f *Flow[State]) SetStateDiff(enabled bool) {
+	f.stateDiff = enabled
+}
+
+// stateSnapshot is the state as decoded from JSON, or why it could not be
+type stateSnapshot struct {
+	value any
+	err   error
+}
+
+// takeStateSnapshot encodes and decodes state so it can be compared after a step
+func takeStateSnapshot(state any) stateSnapshot {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return stateSnapshot{err: err}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return stateSnapshot{err: err}
+	}
+	return stateSnapshot{value: value}
+}
+
+// diffStates returns the diff of a step from the snapshots before and after it
+func diffStates(step int, node string, before, after stateSnapshot) StateDiff {
+	diff := StateDiff{Step: step, Node: node}
+	for _, snapshot := range []stateSnapshot{before, after} {
+		if snapshot.err != nil {
+			diff.Error = fmt.Sprintf("failed to encode state: %v", snapshot.err)
+			return diff
+		}
+	}
+	diffValues(&diff, "", before.value, after.value)
+	return diff
+}
+
+// DiffStates compares two states the way SetStateDiff compares the state
+// before and after a step, e.g. to show how a state differs from the one a
+// test expects. Step and Node of the diff are left empty.
+func DiffStates(before, after any) StateDiff {
+	return diffStates(0, "", takeStateSnapshot(before), takeStateSnapshot(after))
+}
+
+// diffValues adds the changes between two decoded JSON values at path
+func diffValues(diff *StateDiff, path string, before, after any) {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			keys := make([]string, 0, len(b)+len(a))
+			for key := range b {
+				keys = append(keys, key)
+			}
+			for key := range a {
+				if _, ok := b[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				beforeValue, inBefore := b[key]
+				afterValue, inAfter := a[key]
+				switch {
+				case !inBefore:
+					diff.add(StateChange{Path: joinPath(path, key), Kind: ChangeAdded, After: diffValue(afterValue)})
+				case !inAfter:
+					diff.add(StateChange{Path: joinPath(path, key), Kind: ChangeRemoved, Before: diffValue(beforeValue)})
+				default:
+					diffValues(diff, joinPath(path, key), beforeValue, afterValue)
+				}
+			}
+			return
+		}
+	case []any:
+		if a, ok := after.([]any); ok {
+			for i := range max(len(b), len(a)) {
+				elementPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(b):
+					diff.add(StateChange{Path: elementPath, Kind: ChangeAdded, After: diffValue(a[i])})
+				case i >= len(a):
+					diff.add(StateChange{Path: elementPath, Kind: ChangeRemoved, Before: diffValue(b[i])})
+				default:
+					diffValues(diff, elementPath, b[i], a[i])
+				}
+			}
+			return
+		}
+	}
+	if !equalValues(before, after) {
+		diff.add(StateChange{Path: path, Kind: ChangeChanged, Before: diffValue(before), After: diffValue(after)})
+	}
+}
+
+// add appends a change, or counts it as omitted once the diff is full
+func (d *StateDiff) add(change StateChange) {
+	if len(d.Changes) >= maxDiffChanges {
+		d.Omitted++
+		return
+	}
+	d.Changes = append(d.Changes, change)
+}
+
+// equalValues reports whether two decoded JSON values are the same, where
+// objects and arrays of different shapes are different
+func equalValues(a, b any) bool {
+	switch a.(type) {
+	case map[string]any, []any:
+		return diffValue(a) == diffValue(b)
+	}
+	switch b.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return a == b
+}
+
+// diffValue encodes a decoded JSON value for a change, cut to maxDiffValueLength
+func diffValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	text := []rune(string(data))
+	if len(text) > maxDiffValueLength {
+		return string(text[:maxDiffValueLength]) + "…"
+	}
+	return string(text)
+}
+
+// joinPath appends an object key to a path, quoting keys that would make the
+// path ambiguous
+func joinPath(path, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\" ") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
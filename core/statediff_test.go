@@ -0,0 +1,107 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// editNode changes a map state in Post
+type editNode struct {
+	edit func(state map[string]any)
+}
+
+func (n *editNode) Prep(state *map[string]any) []int {
+	return []int{0}
+}
+
+func (n *editNode) Exec(item int) (int, error) {
+	return item, nil
+}
+
+func (n *editNode) Post(state *map[string]any, prepResults []int, execResults ...int) Action {
+	n.edit(*state)
+	return ActionSuccess
+}
+
+func (n *editNode) ExecFallback(err error) int {
+	return 0
+}
+
+func TestFlow_StateDiff(t *testing.T) {
+	fill := NewNode[map[string]any, int, int](&editNode{edit: func(state map[string]any) {
+		state["query"] = "tides"
+		state["results"] = []any{"a", "b"}
+		state["meta"] = map[string]any{"source": "web", "count": 2}
+	}}, 0, 1)
+	corrupt := NewNode[map[string]any, int, int](&editNode{edit: func(state map[string]any) {
+		delete(state, "query")
+		state["results"] = []any{"a"}
+		state["meta"].(map[string]any)["count"] = 3
+		state["summary"] = strings.Repeat("x", 300)
+	}}, 0, 1)
+	fill.AddSuccessor(corrupt, ActionSuccess)
+	flow := NewFlow[map[string]any](fill)
+
+	if _, report := flow.RunWithReport(&map[string]any{}, nil); report.StateDiffs != nil {
+		t.Errorf("diffs recorded without SetStateDiff: %+v", report.StateDiffs)
+	}
+
+	flow.SetStateDiff(true)
+	_, report := flow.RunWithReport(&map[string]any{"user": "ada"}, nil)
+	want := []StateDiff{
+		{Step: 1, Node: "editNode", Changes: []StateChange{
+			{Path: "meta", Kind: ChangeAdded, After: `{"count":2,"source":"web"}`},
+			{Path: "query", Kind: ChangeAdded, After: `"tides"`},
+			{Path: "results", Kind: ChangeAdded, After: `["a","b"]`},
+		}},
+		{Step: 2, Node: "editNode", Changes: []StateChange{
+			{Path: "meta.count", Kind: ChangeChanged, Before: "2", After: "3"},
+			{Path: "query", Kind: ChangeRemoved, Before: `"tides"`},
+			{Path: "results[1]", Kind: ChangeRemoved, Before: `"b"`},
+			{Path: "summary", Kind: ChangeAdded, After: `"` + strings.Repeat("x", 199) + "…"},
+		}},
+	}
+	if !reflect.DeepEqual(report.StateDiffs, want) {
+		t.Errorf("StateDiffs = %+v, want %+v", report.StateDiffs, want)
+	}
+
+	t.Run("unencodable", func(t *testing.T) {
+		_, report := flow.RunWithReport(&map[string]any{"done": make(chan int)}, nil)
+		if len(report.StateDiffs) != 2 || report.StateDiffs[0].Error == "" {
+			t.Errorf("StateDiffs = %+v, want errors", report.StateDiffs)
+		}
+	})
+}
+
+func TestDiffValues(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after any
+		want          []StateChange
+	}{
+		{"equal", map[string]any{"a": []any{"x"}}, map[string]any{"a": []any{"x"}}, nil},
+		{"type change", map[string]any{"a": []any{"x"}}, map[string]any{"a": "x"}, []StateChange{{Path: "a", Kind: ChangeChanged, Before: `["x"]`, After: `"x"`}}},
+		{"to null", map[string]any{"a": "x"}, map[string]any{"a": nil}, []StateChange{{Path: "a", Kind: ChangeChanged, Before: `"x"`, After: "null"}}},
+		{"quoted key", map[string]any{}, map[string]any{"file.txt": true}, []StateChange{{Path: `["file.txt"]`, Kind: ChangeAdded, After: "true"}}},
+		{"nested array", []any{map[string]any{"role": "user"}}, []any{map[string]any{"role": "model"}}, []StateChange{{Path: "[0].role", Kind: ChangeChanged, Before: `"user"`, After: `"model"`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := StateDiff{}
+			diffValues(&diff, "", tt.before, tt.after)
+			if !reflect.DeepEqual(diff.Changes, tt.want) {
+				t.Errorf("changes = %+v, want %+v", diff.Changes, tt.want)
+			}
+		})
+	}
+
+	t.Run("omitted", func(t *testing.T) {
+		after := make([]any, maxDiffChanges+5)
+		diff := StateDiff{}
+		diffValues(&diff, "", []any{}, after)
+		if len(diff.Changes) != maxDiffChanges || diff.Omitted != 5 {
+			t.Errorf("got %d changes, %d omitted", len(diff.Changes), diff.Omitted)
+		}
+	})
+}
@@ -10,5 +10,13 @@ const (
 	ActionFailure  Action = "failure"
 	ActionRetry    Action = "retry"
 	ActionDefault  Action = "default"
+	// ActionShutdown ends runs that Flow.Shutdown stopped or refused
+	ActionShutdown Action = "shutdown"
+	// ActionCancelled ends runs whose context was cancelled, see Flow.RunContext
+	ActionCancelled Action = "cancelled"
+	// ActionAborted ends runs that a step hook aborted, see Flow.SetStepHook
+	ActionAborted Action = "aborted"
+	// ActionAny routes to a successor when no edge matches the action exactly
+	ActionAny Action = "*"
 )
 
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // MockWorkflow is a test implementation of the Workflow interface
@@ -693,3 +696,188 @@ func TestWorkflowInterface_StateManagement(t *testing.T) {
 		t.Error("State should be shared and consistent across workflow components")
 	}
 }
+
+// TestFlow_RunObserved tests that each step of a flow run is reported in order
+func TestFlow_RunObserved(t *testing.T) {
+	first := createNode(&TestBaseNode{prepResults: []any{}, postAction: ActionContinue}, 0, 1)
+	second := NewMockWorkflow[State]("second", ActionSuccess)
+	first.AddSuccessor(second, ActionContinue)
+	flow := NewFlow[State](first)
+
+	var events []StepEvent
+	state := State{}
+	action := flow.RunObserved(&state, func(event StepEvent) {
+		events = append(events, event)
+	})
+
+	if action != ActionSuccess {
+		t.Errorf("RunObserved() = %v, expected %v", action, ActionSuccess)
+	}
+	expected := []StepEvent{
+		{Step: 1, Name: "TestBaseNode", Action: ActionContinue},
+		{Step: 2, Name: "MockWorkflow", Action: ActionSuccess},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, event := range events {
+		event.Duration = 0
+		if !reflect.DeepEqual(event, expected[i]) {
+			t.Errorf("event %d = %+v, expected %+v", i, event, expected[i])
+		}
+	}
+
+	if name := WorkflowName[State](flow); name != "Flow" {
+		t.Errorf("WorkflowName(flow) = %q", name)
+	}
+}
+
+// TestFlow_ResumeObserved tests that a resumed run skips the steps it already ran
+func TestFlow_ResumeObserved(t *testing.T) {
+	first := NewMockWorkflow[State]("first", ActionContinue)
+	second := NewMockWorkflow[State]("second", ActionRetry)
+	third := NewMockWorkflow[State]("third", ActionSuccess)
+	first.AddSuccessor(second, ActionContinue)
+	second.AddSuccessor(third, ActionRetry)
+	flow := NewFlow[State](first)
+
+	var events []StepEvent
+	state := State{}
+	action, err := flow.ResumeObserved(&state, []Action{ActionContinue}, func(event StepEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatalf("ResumeObserved() error: %v", err)
+	}
+	if action != ActionSuccess {
+		t.Errorf("ResumeObserved() = %v, expected %v", action, ActionSuccess)
+	}
+	if first.runCalled || !second.runCalled || !third.runCalled {
+		t.Errorf("expected only the remaining steps to run: first=%v second=%v third=%v", first.runCalled, second.runCalled, third.runCalled)
+	}
+	if len(events) != 2 || events[0].Step != 2 || events[1].Step != 3 {
+		t.Errorf("expected steps 2 and 3, got %+v", events)
+	}
+
+	tests := []struct {
+		name     string
+		actions  []Action
+		expected Action
+		wantErr  bool
+	}{
+		{"finished run", []Action{ActionContinue, ActionRetry, ActionSuccess}, ActionSuccess, false},
+		{"past the end", []Action{ActionContinue, ActionRetry, ActionSuccess, ActionSuccess}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			third.runCalled = false
+			action, err := flow.ResumeObserved(&State{}, tt.actions, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResumeObserved() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if action != tt.expected || third.runCalled {
+				t.Errorf("ResumeObserved() = %v (ran last step: %v), expected %v", action, third.runCalled, tt.expected)
+			}
+		})
+	}
+}
+
+// blockingNode holds its Exec until release is closed
+type blockingNode struct {
+	benchNode
+	started chan struct{}
+	release chan struct{}
+}
+
+func (n *blockingNode) Exec(item int) (int, error) {
+	n.started <- struct{}{}
+	<-n.release
+	return item, nil
+}
+
+// TestFlow_Shutdown tests that Shutdown refuses new runs, lets a run in flight
+// finish its step and flushes afterwards
+func TestFlow_Shutdown(t *testing.T) {
+	blocking := &blockingNode{benchNode: benchNode{action: ActionContinue}, started: make(chan struct{}), release: make(chan struct{})}
+	next := &benchNode{action: ActionSuccess}
+	first := NewNode[benchState, int, int](blocking, 0, 1)
+	first.AddSuccessor(NewNode[benchState, int, int](next, 0, 1), ActionContinue)
+	flow := NewFlow[benchState](first)
+
+	var flushed []string
+	flow.OnShutdown(func(ctx context.Context) error {
+		flushed = append(flushed, "checkpoints")
+		return nil
+	})
+	flow.OnShutdown(func(ctx context.Context) error {
+		flushed = append(flushed, "audit")
+		return errors.New("audit log unavailable")
+	})
+
+	state := &benchState{Items: benchItems(1)}
+	actions := make(chan Action, 1)
+	go func() { actions <- flow.Run(state) }()
+	<-blocking.started
+
+	// The run is in flight, so Shutdown times out first
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := flow.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want DeadlineExceeded", err)
+	}
+	if action := flow.Run(&benchState{}); action != ActionShutdown {
+		t.Errorf("Run() after Shutdown = %v, want %v", action, ActionShutdown)
+	}
+
+	close(blocking.release)
+	if action := <-actions; action != ActionShutdown {
+		t.Errorf("run in flight ended with %v, want %v", action, ActionShutdown)
+	}
+	if state.Sum != 0 {
+		t.Error("the run went on past the step it was in")
+	}
+	flushed = nil
+	err := flow.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "audit log unavailable") {
+		t.Errorf("Shutdown() = %v, want the flush error", err)
+	}
+	if !reflect.DeepEqual(flushed, []string{"checkpoints", "audit"}) {
+		t.Errorf("flushed %v", flushed)
+	}
+}
+
+// TestWorkflowInterface_AddSuccessorActions tests that every action given to
+// AddSuccessor and AddSuccessors is routed
+func TestWorkflowInterface_AddSuccessorActions(t *testing.T) {
+	type routes interface {
+		Workflow[State]
+		AddSuccessors(map[Action]Workflow[State])
+	}
+	workflows := map[string]func() routes{
+		"Node": func() routes {
+			return createNode(&TestBaseNode{postAction: ActionSuccess}, 1, 1)
+		},
+		"Flow": func() routes {
+			return NewFlow[State](NewMockWorkflow("start", ActionSuccess))
+		},
+	}
+	for name, setup := range workflows {
+		t.Run(name, func(t *testing.T) {
+			workflow := setup()
+			handleError := NewMockWorkflow("error", ActionSuccess)
+			workflow.AddSuccessor(handleError, ActionFailure, ActionRetry, "timeout")
+			next := NewMockWorkflow("next", ActionSuccess)
+			workflow.AddSuccessors(map[Action]Workflow[State]{ActionContinue: next, "skipped": nil})
+
+			want := map[Action]Workflow[State]{ActionFailure: handleError, ActionRetry: handleError, "timeout": handleError, ActionContinue: next}
+			for action, successor := range want {
+				if got := workflow.GetSuccessor(action); got != successor {
+					t.Errorf("GetSuccessor(%v) = %v, want %v", action, got, successor)
+				}
+			}
+			if got := workflow.GetSuccessor("skipped"); got != nil {
+				t.Errorf("GetSuccessor(skipped) = %v, want nil", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// consoleHelp lists the commands of Console
+const consoleHelp = `Commands:
+  state              print the state
+  get PATH           print a value of the state, e.g. get messages.2.content
+  set PATH JSON      change a value, e.g. set query "shorter text"; . is the whole state
+  break NAME         pause before the steps named NAME; * pauses before every step
+  clear [NAME]       remove a breakpoint, or all of them
+  breakpoints        list the breakpoints
+  continue, c        resume the run
+  step, s            resume the run and pause before the next step
+  abort, a           end the run before this step
+  help               show this list
+`
+
+// Console drives the debugger from a terminal: it waits for a run to pause,
+// shows the step, and reads commands from in until one resumes the run. It
+// returns when ctx is done or in ends; when in ends, breakpoints are cleared
+// and a paused run continues, so no run waits for a console that is gone.
+func (d *Debugger[State]) Console(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		pause, err := d.Wait(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		fmt.Fprintf(out, "paused before step %d: %s\n", pause.Step.Step, pause.Step.Name)
+
+		for resumed := false; !resumed; {
+			fmt.Fprint(out, "(debug) ")
+			var line string
+			var ok bool
+			select {
+			case line, ok = <-lines:
+			case <-ctx.Done():
+				return nil
+			}
+			if !ok {
+				d.Clear()
+				d.Continue()
+				return nil
+			}
+			resumed, err = d.command(line, out)
+			if err != nil {
+				fmt.Fprintf(out, "error: %v\n", err)
+			}
+		}
+	}
+}
+
+// command runs a console command and reports whether it resumed the run
+func (d *Debugger[State]) command(line string, out io.Writer) (bool, error) {
+	name, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+	args = strings.TrimSpace(args)
+	switch name {
+	case "":
+		return false, nil
+	case "state", "get":
+		value, err := d.Get(args)
+		if err != nil {
+			return false, err
+		}
+		var indented bytes.Buffer
+		json.Indent(&indented, value, "", "  ")
+		fmt.Fprintln(out, indented.String())
+		return false, nil
+	case "set":
+		path, value, ok := strings.Cut(args, " ")
+		if !ok {
+			return false, fmt.Errorf("usage: set PATH JSON")
+		}
+		if err := d.Set(path, json.RawMessage(strings.TrimSpace(value))); err != nil {
+			return false, err
+		}
+		return false, nil
+	case "break":
+		if args == "" {
+			return false, fmt.Errorf("usage: break NAME")
+		}
+		d.Break(args)
+		return false, nil
+	case "clear":
+		if args == "" {
+			d.Clear()
+		} else {
+			d.Clear(args)
+		}
+		return false, nil
+	case "breakpoints":
+		for _, breakpoint := range d.Breakpoints() {
+			fmt.Fprintln(out, breakpoint)
+		}
+		return false, nil
+	case "continue", "c":
+		return true, d.Continue()
+	case "step", "s":
+		return true, d.Step()
+	case "abort", "a":
+		return true, d.Abort()
+	case "help":
+		fmt.Fprint(out, consoleHelp)
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown command %q, try help", name)
+	}
+}
@@ -0,0 +1,353 @@
+// Package debugger pauses flow runs before chosen steps, so the state can be
+// inspected and changed mid-flow before the run continues or aborts. A
+// Debugger is driven from Go, from a terminal with Console, or over HTTP with
+// Handler.
+//
+//	dbg := debugger.New[*TicketState]("ClassifyNode")
+//	dbg.Attach(flow)
+//	go dbg.Console(ctx, os.Stdin, os.Stderr)
+//	flow.Run(&state)
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// AllSteps is the breakpoint that pauses before every step
+const AllSteps = "*"
+
+var (
+	// ErrNotPaused is returned when inspecting or resuming while no run is paused
+	ErrNotPaused = errors.New("no run is paused")
+	// ErrInvalidPath is returned for a path that does not lead to a value of the state
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// Pause describes a run paused before a step
+type Pause struct {
+	Step  core.StepStart  `json:"step"`
+	State json.RawMessage `json:"state"` // The state the step is about to run on, as JSON
+}
+
+// Debugger pauses the runs of the flows it is attached to before the steps
+// with breakpoints, until it is told to continue, step or abort. Breakpoints
+// name steps as their step events do. Runs pause one at a time: when several
+// runs reach a breakpoint, the others wait until the paused one resumes.
+type Debugger[State any] struct {
+	mu          sync.Mutex
+	breakpoints map[string]bool
+	stepping    bool // Pause before the next step, whatever its name
+	paused      *pausedRun[State]
+	changed     chan struct{} // Closed and replaced when a run pauses or resumes
+	turn        chan struct{} // Held by the paused run
+}
+
+// pausedRun is a run waiting in the hook
+type pausedRun[State any] struct {
+	step   core.StepStart
+	state  *State
+	resume chan bool // Receives true to continue and false to abort
+}
+
+// New creates a debugger pausing before the steps named in breakpoints, or
+// before every step for AllSteps
+func New[State any](breakpoints ...string) *Debugger[State] {
+	d := &Debugger[State]{
+		breakpoints: make(map[string]bool),
+		changed:     make(chan struct{}),
+		turn:        make(chan struct{}, 1),
+	}
+	d.Break(breakpoints...)
+	return d
+}
+
+// Attach makes the debugger the step hook of flow, see core.Flow.SetStepHook
+func (d *Debugger[State]) Attach(flow *core.Flow[State]) {
+	flow.SetStepHook(d.Hook)
+}
+
+// Hook implements core.StepHook: it pauses before steps with a breakpoint
+// and reports whether the run continues
+func (d *Debugger[State]) Hook(step core.StepStart, state *State) bool {
+	if !d.breaksAt(step.Name) {
+		return true
+	}
+	d.turn <- struct{}{}
+	defer func() { <-d.turn }()
+
+	run := &pausedRun[State]{step: step, state: state, resume: make(chan bool, 1)}
+	d.mu.Lock()
+	d.stepping = false
+	d.paused = run
+	d.notify()
+	d.mu.Unlock()
+	return <-run.resume
+}
+
+// breaksAt reports whether runs pause before a step named name
+func (d *Debugger[State]) breaksAt(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stepping || d.breakpoints[name] || d.breakpoints[AllSteps]
+}
+
+// notify wakes the callers of Wait; d.mu must be held
+func (d *Debugger[State]) notify() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// Break adds breakpoints before the steps named names
+func (d *Debugger[State]) Break(names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, name := range names {
+		if name != "" {
+			d.breakpoints[name] = true
+		}
+	}
+}
+
+// Clear removes the breakpoints of names, or all breakpoints without names
+func (d *Debugger[State]) Clear(names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(names) == 0 {
+		clear(d.breakpoints)
+	}
+	for _, name := range names {
+		delete(d.breakpoints, name)
+	}
+}
+
+// Breakpoints returns the names of the breakpoints in order
+func (d *Debugger[State]) Breakpoints() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.breakpoints))
+	for name := range d.breakpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Paused returns the paused run, or ErrNotPaused
+func (d *Debugger[State]) Paused() (Pause, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pause()
+}
+
+// Wait waits until a run is paused and returns it
+func (d *Debugger[State]) Wait(ctx context.Context) (Pause, error) {
+	for {
+		d.mu.Lock()
+		pause, err := d.pause()
+		changed := d.changed
+		d.mu.Unlock()
+		if !errors.Is(err, ErrNotPaused) {
+			return pause, err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Pause{}, ctx.Err()
+		}
+	}
+}
+
+// pause describes the paused run; d.mu must be held
+func (d *Debugger[State]) pause() (Pause, error) {
+	if d.paused == nil {
+		return Pause{}, ErrNotPaused
+	}
+	state, err := json.Marshal(d.paused.state)
+	if err != nil {
+		return Pause{}, fmt.Errorf("failed to encode state: %w", err)
+	}
+	return Pause{Step: d.paused.step, State: state}, nil
+}
+
+// Get returns the value at path in the state of the paused run, as JSON.
+// Paths name keys and fields by their JSON names and list elements by their
+// index, separated by dots, e.g. "messages.2.content"; "" is the whole state.
+func (d *Debugger[State]) Get(path string) (json.RawMessage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused == nil {
+		return nil, ErrNotPaused
+	}
+	value, err := decodeState(d.paused.state)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range splitPath(path) {
+		if value, err = child(value, key); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidPath, path, err)
+		}
+	}
+	return json.Marshal(value)
+}
+
+// Set replaces the value at path, see Get, in the state of the paused run
+// with the JSON value. The top-level key or field of path is decoded into the
+// state from JSON, so other fields and unexported ones keep their values.
+// Setting the whole state does not remove keys of map states; set them to
+// null instead.
+func (d *Debugger[State]) Set(path string, value json.RawMessage) error {
+	var decoded any
+	if err := unmarshal(value, &decoded); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused == nil {
+		return ErrNotPaused
+	}
+	patch := decoded
+	if keys := splitPath(path); len(keys) > 0 {
+		state, err := decodeState(d.paused.state)
+		if err != nil {
+			return err
+		}
+		object, ok := state.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w %q: the state is not an object", ErrInvalidPath, path)
+		}
+		top, err := replace(object[keys[0]], keys[1:], decoded)
+		if err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidPath, path, err)
+		}
+		patch = map[string]any{keys[0]: top}
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := json.Unmarshal(data, d.paused.state); err != nil {
+		return fmt.Errorf("value does not fit the state: %w", err)
+	}
+	return nil
+}
+
+// Continue resumes the paused run
+func (d *Debugger[State]) Continue() error {
+	return d.resume(true, false)
+}
+
+// Step resumes the paused run and pauses again before the next step of any
+// run, whether it has a breakpoint or not
+func (d *Debugger[State]) Step() error {
+	return d.resume(true, true)
+}
+
+// Abort ends the paused run with core.ActionAborted before its step
+func (d *Debugger[State]) Abort() error {
+	return d.resume(false, false)
+}
+
+// resume lets the paused run go on, or abort
+func (d *Debugger[State]) resume(proceed, step bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused == nil {
+		return ErrNotPaused
+	}
+	d.paused.resume <- proceed
+	d.paused = nil
+	d.stepping = step
+	d.notify()
+	return nil
+}
+
+// decodeState returns the state as decoded from JSON
+func decodeState(state any) (any, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state: %w", err)
+	}
+	var value any
+	if err := unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return value, nil
+}
+
+// unmarshal decodes JSON keeping numbers as written
+func unmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// splitPath returns the keys of a dotted path
+func splitPath(path string) []string {
+	if path == "" || path == "." {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// child returns the value under key of an object or list
+func child(value any, key string) (any, error) {
+	switch value := value.(type) {
+	case map[string]any:
+		child, ok := value[key]
+		if !ok {
+			return nil, fmt.Errorf("no key %s", key)
+		}
+		return child, nil
+	case []any:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(value) {
+			return nil, fmt.Errorf("no index %s in a list of %d", key, len(value))
+		}
+		return value[index], nil
+	default:
+		return nil, fmt.Errorf("%s is not in an object or list", key)
+	}
+}
+
+// replace returns value with the value at path set to v. Objects gain keys
+// that are missing; lists keep their length.
+func replace(value any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	key := path[0]
+	switch value := value.(type) {
+	case map[string]any:
+		child, err := replace(value[key], path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		value[key] = child
+		return value, nil
+	case []any:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(value) {
+			return nil, fmt.Errorf("no index %s in a list of %d", key, len(value))
+		}
+		if value[index], err = replace(value[index], path[1:], v); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case nil:
+		// A missing key of an object becomes an object
+		return replace(map[string]any{}, path, v)
+	default:
+		return nil, fmt.Errorf("%s is not in an object or list", key)
+	}
+}
@@ -0,0 +1,196 @@
+package debugger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+type testState struct {
+	Count int      `json:"count"`
+	Log   []string `json:"log"`
+	note  string
+}
+
+// addNode adds by to the count and logs its name
+type addNode struct {
+	name string
+	by   int
+}
+
+func (n *addNode) Name() string                { return n.name }
+func (n *addNode) Prep(state *testState) []int { return []int{state.Count} }
+func (n *addNode) Exec(count int) (int, error) { return count + n.by, nil }
+func (n *addNode) ExecFallback(err error) int  { return 0 }
+func (n *addNode) Post(state *testState, _ []int, results ...int) core.Action {
+	state.Count = results[0]
+	state.Log = append(state.Log, n.name)
+	return core.ActionDefault
+}
+
+// startRun runs a flow of the steps a, b and c, adding 1, 2 and 3, under
+// debugger in the background; the result receives the final action
+func startRun(t *testing.T, debugger *Debugger[testState], state *testState) <-chan core.Action {
+	t.Helper()
+	a := core.NewNode[testState, int, int](&addNode{name: "a", by: 1}, 0, 1)
+	b := core.NewNode[testState, int, int](&addNode{name: "b", by: 2}, 0, 1)
+	c := core.NewNode[testState, int, int](&addNode{name: "c", by: 3}, 0, 1)
+	a.AddSuccessor(b)
+	b.AddSuccessor(c)
+	flow := core.NewFlow[testState](a)
+	debugger.Attach(flow)
+
+	result := make(chan core.Action, 1)
+	go func() { result <- flow.Run(state) }()
+	return result
+}
+
+// waitPause waits a second for a run to pause
+func waitPause(t *testing.T, debugger *Debugger[testState]) Pause {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	pause, err := debugger.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	return pause
+}
+
+func TestDebugger(t *testing.T) {
+	debugger := New[testState]("b")
+	state := &testState{note: "kept"}
+	result := startRun(t, debugger, state)
+
+	pause := waitPause(t, debugger)
+	if pause.Step.Name != "b" || pause.Step.Step != 2 || string(pause.State) != `{"count":1,"log":["a"]}` {
+		t.Fatalf("Wait() = %+v %s", pause.Step, pause.State)
+	}
+	if err := debugger.Set("count", json.RawMessage("10")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"count", "10", nil},
+		{"log.0", `"a"`, nil},
+		{"", `{"count":10,"log":["a"]}`, nil},
+		{"log.3", "", ErrInvalidPath},
+		{"count.value", "", ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		value, err := debugger.Get(tt.path)
+		if !errors.Is(err, tt.wantErr) || string(value) != tt.want {
+			t.Errorf("Get(%q) = %s, %v, want %s, %v", tt.path, value, err, tt.want, tt.wantErr)
+		}
+	}
+	if err := debugger.Set("log.3", json.RawMessage(`"x"`)); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("Set(log.3) error = %v, want ErrInvalidPath", err)
+	}
+	if err := debugger.Set("count", json.RawMessage(`"ten"`)); err == nil {
+		t.Error("Set() of a string count: error = nil")
+	}
+
+	if err := debugger.Step(); err != nil {
+		t.Fatalf("Step() error = %v", err)
+	}
+	if pause := waitPause(t, debugger); pause.Step.Name != "c" {
+		t.Errorf("paused before %s after Step, want c", pause.Step.Name)
+	}
+	if err := debugger.Abort(); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+	if action := <-result; action != core.ActionAborted {
+		t.Errorf("Run() = %s, want %s", action, core.ActionAborted)
+	}
+	if state.Count != 12 || !reflect.DeepEqual(state.Log, []string{"a", "b"}) || state.note != "kept" {
+		t.Errorf("state = %+v, want b run on the changed count and c aborted", state)
+	}
+	if err := debugger.Continue(); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("Continue() without a paused run: error = %v, want ErrNotPaused", err)
+	}
+}
+
+func TestDebugger_Console(t *testing.T) {
+	debugger := New[testState]("b", "c")
+	state := &testState{}
+	result := startRun(t, debugger, state)
+
+	// The input ends while c is paused, which lets the run finish
+	input := strings.NewReader("help\nget count\nset count 5\nbogus\nbreakpoints\nc\n")
+	var output strings.Builder
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := debugger.Console(ctx, input, &output); err != nil {
+		t.Fatalf("Console() error = %v", err)
+	}
+	if action := <-result; action != core.ActionDefault || state.Count != 10 {
+		t.Errorf("Run() = %s with count %d, want count 5+2+3", action, state.Count)
+	}
+	for _, want := range []string{"paused before step 2: b", "(debug) 1\n", "error: unknown command \"bogus\"", "b\nc\n", "paused before step 3: c"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("console output does not contain %q:\n%s", want, output.String())
+		}
+	}
+	if breakpoints := debugger.Breakpoints(); len(breakpoints) != 0 {
+		t.Errorf("Breakpoints() = %v after the input ended, want none", breakpoints)
+	}
+}
+
+func TestDebugger_Handler(t *testing.T) {
+	debugger := New[testState]()
+	server := httptest.NewServer(debugger.Handler())
+	defer server.Close()
+	request := func(method, path, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer response.Body.Close()
+		data, _ := io.ReadAll(response.Body)
+		return response.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	if status, body := request("PUT", "/breakpoints", `["b"]`); status != http.StatusOK || body != `["b"]` {
+		t.Fatalf("PUT /breakpoints = %d %s", status, body)
+	}
+	if status, _ := request("POST", "/continue", ""); status != http.StatusConflict {
+		t.Errorf("POST /continue without a paused run = %d, want 409", status)
+	}
+	state := &testState{}
+	result := startRun(t, debugger, state)
+
+	status, body := request("GET", "/?wait=true", "")
+	var paused overview
+	if err := json.Unmarshal([]byte(body), &paused); err != nil || status != http.StatusOK || paused.Paused == nil || paused.Paused.Step.Name != "b" {
+		t.Fatalf("GET /?wait=true = %d %s", status, body)
+	}
+	if status, body := request("PUT", "/state?path=count", "7"); status != http.StatusNoContent {
+		t.Errorf("PUT /state = %d %s", status, body)
+	}
+	if status, body := request("GET", "/state?path=count", ""); status != http.StatusOK || body != "7" {
+		t.Errorf("GET /state = %d %s, want 7", status, body)
+	}
+	if status, _ := request("GET", "/state?path=missing", ""); status != http.StatusBadRequest {
+		t.Errorf("GET /state of a missing key = %d, want 400", status)
+	}
+	if status, _ := request("POST", "/continue", ""); status != http.StatusNoContent {
+		t.Errorf("POST /continue = %d", status)
+	}
+	if action := <-result; action != core.ActionDefault || state.Count != 12 {
+		t.Errorf("Run() = %s with count %d, want count 7+2+3", action, state.Count)
+	}
+}
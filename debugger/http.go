@@ -0,0 +1,104 @@
+package debugger
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// maxBodyBytes is the largest state or value a request may set
+const maxBodyBytes = 10 << 20
+
+// overview is the response of GET /
+type overview struct {
+	Paused      *Pause   `json:"paused"` // nil while no run is paused
+	Breakpoints []string `json:"breakpoints"`
+}
+
+// Handler serves the debugger over HTTP, e.g. mounted with
+// http.StripPrefix("/debug", dbg.Handler()):
+//
+//	GET  /             the paused run, or null, and the breakpoints; ?wait=true waits for a run to pause
+//	GET  /state        a value of the state of the paused run; ?path= selects it
+//	PUT  /state        sets a value of the state to the JSON body; ?path= selects it
+//	PUT  /breakpoints  replaces the breakpoints with the JSON list of names in the body
+//	POST /continue     resumes the paused run
+//	POST /step         resumes the paused run and pauses before the next step
+//	POST /abort        ends the paused run
+//
+// Requests about the paused run answer 409 while no run is paused.
+func (d *Debugger[State]) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		var response overview
+		pause, err := d.Paused()
+		if errors.Is(err, ErrNotPaused) && r.URL.Query().Get("wait") == "true" {
+			pause, err = d.Wait(r.Context())
+		}
+		if err == nil {
+			response.Paused = &pause
+		} else if !errors.Is(err, ErrNotPaused) {
+			writeError(w, err)
+			return
+		}
+		response.Breakpoints = d.Breakpoints()
+		writeJSON(w, http.StatusOK, response)
+	})
+	mux.HandleFunc("GET /state", func(w http.ResponseWriter, r *http.Request) {
+		value, err := d.Get(r.URL.Query().Get("path"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, value)
+	})
+	mux.HandleFunc("PUT /state", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err == nil {
+			err = d.Set(r.URL.Query().Get("path"), body)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("PUT /breakpoints", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&names); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		d.Clear()
+		d.Break(names...)
+		writeJSON(w, http.StatusOK, d.Breakpoints())
+	})
+	for path, resume := range map[string]func() error{"/continue": d.Continue, "/step": d.Step, "/abort": d.Abort} {
+		mux.HandleFunc("POST "+path, func(w http.ResponseWriter, r *http.Request) {
+			if err := resume(); err != nil {
+				writeError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	return mux
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response, with 409 while no run is
+// paused and 400 for requests that do not fit the state
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, ErrNotPaused) {
+		status = http.StatusConflict
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
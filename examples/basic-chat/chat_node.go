@@ -9,12 +9,27 @@ import (
 
 	"github.com/alt-coder/pocketflow-go/core"
 	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
 )
 
 // ChatState represents the conversation state
 type ChatState struct {
-	Messages []llm.Message // Full conversation history using generic format
-	Active   bool          // Whether chat is active
+	History   memory.Store        // Conversation history
+	Active    bool                // Whether chat is active
+	SessionID string              // ID the conversation is saved under
+	Sessions  memory.SessionStore // Where the conversation is saved after each reply; nil disables saving
+}
+
+// SaveSession saves the conversation so it can be resumed after a restart
+func (s *ChatState) SaveSession(ctx context.Context) error {
+	if s.Sessions == nil {
+		return nil
+	}
+	session, err := memory.NewSession(ctx, s.SessionID, s.History, nil)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.SaveSession(ctx, session)
 }
 
 // PrepResult contains the conversation context for LLM call
@@ -77,21 +92,37 @@ func (c *ChatNode) Prep(state *ChatState) []PrepResult {
 		return c.Prep(state) // Recursively ask for input again
 	}
 
-	state.Messages = append(state.Messages, llm.Message{
+	ctx := context.Background()
+	if err := state.History.Append(ctx, llm.Message{
 		Role:    llm.RoleUser,
 		Content: input,
-	})
+	}); err != nil {
+		fmt.Printf("Error saving message: %v\n", err)
+	}
+
+	// Only the most recent messages are sent to keep requests bounded
+	messages, err := state.History.Window(ctx, c.config.MaxHistory)
+	if err != nil {
+		fmt.Printf("Error reading conversation history: %v\n", err)
+		return []PrepResult{}
+	}
 
 	// Return PrepResult with conversation context
 	return []PrepResult{
 		{
-			Messages:  state.Messages,	
+			Messages:  messages,	
 		},
 	}
 }
 
-// Exec implements the execution phase - makes LLM API call
+// Exec implements the execution phase without the context of the run
 func (c *ChatNode) Exec(prepResult PrepResult) (ExecResult, error) {
+	return c.ExecContext(context.Background(), prepResult)
+}
+
+// ExecContext implements the execution phase - makes LLM API call, which
+// cancelling the run cancels
+func (c *ChatNode) ExecContext(ctx context.Context, prepResult PrepResult) (ExecResult, error) {
 	if len(prepResult.Messages) == 0 {
 		return ExecResult{
 			Response: "No conversation history provided.",
@@ -99,7 +130,7 @@ func (c *ChatNode) Exec(prepResult PrepResult) (ExecResult, error) {
 		}, nil
 	}
 	// Make API call to LLM provider with conversation history
-	response, err := c.llmProvider.CallLLM(context.Background(), prepResult.Messages)
+	response, err := c.llmProvider.CallLLM(ctx, prepResult.Messages)
 	if err != nil {
 		return ExecResult{
 			Response: "",
@@ -141,11 +172,11 @@ func (c *ChatNode) Post(state *ChatState, prepResults []PrepResult, execResults
 		Content: execResult.Response,
 	}
 	
-	state.Messages = append(state.Messages, assistantMessage)
-
-	// Trim conversation history if it exceeds maximum length
-	if len(state.Messages) > c.config.MaxHistory {
-		state.Messages = state.Messages[len(state.Messages)-c.config.MaxHistory:]
+	if err := state.History.Append(context.Background(), assistantMessage); err != nil {
+		fmt.Printf("Error saving message: %v\n", err)
+	}
+	if err := state.SaveSession(context.Background()); err != nil {
+		fmt.Printf("Error saving session: %v\n", err)
 	}
 	// Continue the conversation loop
 	return core.ActionContinue
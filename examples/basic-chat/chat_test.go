@@ -7,6 +7,7 @@ import (
 	"github.com/alt-coder/pocketflow-go/core"
 	"github.com/alt-coder/pocketflow-go/llm"
 	"github.com/alt-coder/pocketflow-go/llm/gemini"
+	"github.com/alt-coder/pocketflow-go/memory"
 )
 
 // MockLLMProvider for testing
@@ -174,4 +175,32 @@ func TestChatNode_BaseNodeInterface(t *testing.T) {
 
 	// This should compile if ChatNode implements BaseNode interface correctly
 	var _ core.BaseNode[ChatState, PrepResult, ExecResult] = chatNode
-}
\ No newline at end of file
+}
+
+func TestChatState_SaveAndResumeSession(t *testing.T) {
+	ctx := context.Background()
+	sessions, err := memory.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create session store: %v", err)
+	}
+
+	// First run: a reply is saved with the session
+	state := ChatState{History: memory.NewInMemoryStore(), SessionID: "chat-1", Sessions: sessions}
+	state.History.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "Hi"})
+	chatNode := NewChatNode(&MockLLMProvider{}, NewChatConfig(&gemini.Config{}))
+	chatNode.Post(&state, []PrepResult{{}}, ExecResult{Response: "Hello!"})
+
+	// Second run: the conversation is restored from the latest session
+	resumed := ChatState{History: memory.NewInMemoryStore(), SessionID: "new", Sessions: sessions}
+	if err := resumeSession(ctx, &resumed, "last"); err != nil {
+		t.Fatalf("resumeSession failed: %v", err)
+	}
+	messages, _ := resumed.History.Messages(ctx)
+	if resumed.SessionID != "chat-1" || len(messages) != 2 || messages[1].Content != "Hello!" {
+		t.Errorf("Expected the saved conversation, got session %s with %+v", resumed.SessionID, messages)
+	}
+
+	if err := resumeSession(ctx, &resumed, "unknown"); err == nil {
+		t.Error("Expected an error for an unknown session")
+	}
+}
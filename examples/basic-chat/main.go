@@ -8,15 +8,18 @@ import (
 	"os"
 
 	"github.com/alt-coder/pocketflow-go/core"
-	"github.com/alt-coder/pocketflow-go/llm"
 	"github.com/alt-coder/pocketflow-go/llm/gemini"
+	"github.com/alt-coder/pocketflow-go/memory"
 )
 
 func main() {
 	// Command-line flag parsing
 	var (
-		model = flag.String("model", "gemini-2.0-flash", "Gemini model to use")
-		temp  = flag.Float64("temperature", 0.7, "Response temperature (0.0-1.0)")
+		model       = flag.String("model", "gemini-2.0-flash", "Gemini model to use")
+		temp        = flag.Float64("temperature", 0.7, "Response temperature (0.0-1.0)")
+		historyFile = flag.String("history", "", "JSON lines file to keep the conversation in across runs")
+		sessionDir  = flag.String("sessions", "sessions", "Directory conversations are saved in after each reply")
+		resume      = flag.String("resume", "", "ID of a saved session to continue, or \"last\" for the most recent one")
 	)
 	flag.Parse()
 
@@ -61,11 +64,33 @@ func main() {
 	// Create Flow
 	flow := core.NewFlow(node)
 
+	// Keep history in memory unless a file was given to resume from
+	var history memory.Store = memory.NewInMemoryStore()
+	if *historyFile != "" {
+		history, err = memory.NewFileStore(*historyFile)
+		if err != nil {
+			log.Fatalf("Failed to open conversation history: %v", err)
+		}
+	}
+
+	sessions, err := memory.NewFileSessionStore(*sessionDir)
+	if err != nil {
+		log.Fatalf("Failed to open session directory: %v", err)
+	}
+
 	// Initialize conversation state
 	initialState := ChatState{
-		Messages: []llm.Message{}, // Start with empty conversation history
-		Active:   true,            // Chat is active
+		History:   history,               // Conversation history
+		Active:    true,                  // Chat is active
+		SessionID: memory.NewSessionID(), // New session unless resuming
+		Sessions:  sessions,              // Saved after each reply
+	}
+	if *resume != "" {
+		if err := resumeSession(ctx, &initialState, *resume); err != nil {
+			log.Fatalf("Failed to resume session: %v", err)
+		}
 	}
+	fmt.Printf("Session %s (continue later with -resume %s)\n", initialState.SessionID, initialState.SessionID)
 
 	// Execute the chat flow
 	fmt.Printf("Starting chat with %s (model: %s, temperature: %.1f)\n\n", 
@@ -75,4 +100,30 @@ func main() {
 	
 	// Log final action for debugging
 	fmt.Printf("Chat ended with action: %v\n", finalAction)
-}
\ No newline at end of file
+}
+
+// resumeSession restores a saved conversation into state; "last" picks the most
+// recently saved session
+func resumeSession(ctx context.Context, state *ChatState, id string) error {
+	if id == "last" {
+		ids, err := state.Sessions.ListSessions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no saved sessions")
+		}
+		id = ids[0]
+	}
+
+	session, err := state.Sessions.LoadSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := session.Restore(ctx, state.History, nil); err != nil {
+		return err
+	}
+	state.SessionID = session.ID
+	fmt.Printf("Resumed session %s with %d messages\n", session.ID, len(session.Messages))
+	return nil
+}
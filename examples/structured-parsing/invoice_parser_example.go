@@ -86,7 +86,13 @@ func (i *InvoiceParserNode) Exec(filePath string) (structured.ParseResult[Invoic
 	currencyContext := fmt.Sprintf("Expected currency: %s. Convert all amounts to this currency if needed.", i.config.Currency)
 
 	// Parse from file using the structured framework - that's it!
-	return i.ParseFromFile(ctx, filePath, currencyContext)
+	result, err := i.ParseFromFile(ctx, filePath, currencyContext)
+	if err != nil {
+		return result, err
+	}
+
+	// Fatal validation errors trigger a retry; warnings are kept on the result
+	return i.ValidateAndAnnotate(result)
 }
 
 // Post handles the results and stores them in state
@@ -101,6 +107,9 @@ func (i *InvoiceParserNode) Post(state *InvoiceParserState, prepRes []string, ex
 			state.Context[fmt.Sprintf("%d", num)] = execResult.Data
 			fmt.Println(num)
 			i.displayInvoiceResults(execResult.Data)
+			for _, warning := range execResult.Warnings {
+				fmt.Printf("Warning: %s\n", warning)
+			}
 		}
 	}
 	if len(state.Context)==0 {
@@ -8,7 +8,9 @@ import (
 	"os"
 
 	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
 	"github.com/alt-coder/pocketflow-go/llm/gemini"
+	"github.com/alt-coder/pocketflow-go/structured"
 )
 
 func main() {
@@ -16,7 +18,9 @@ func main() {
 	var (
 		model      = flag.String("model", "gemini-2.0-flash", "Gemini model to use")
 		temp       = flag.Float64("temperature", 0.3, "Response temperature (0.0-1.0)")
-		resumeFile = flag.String("resume", "examples/structured-parsing/data.txt", "Path to resume file")
+		resumeFile = flag.String("resume", "examples/structured-parsing/data.txt", "Path to resume file (.txt, .md, .html, .docx or .pdf)")
+		resumeDir  = flag.String("dir", "", "Directory of resumes to parse in bulk (overrides -resume)")
+		workers    = flag.Int("workers", 4, "Concurrent workers in bulk mode")
 	)
 	flag.Parse()
 
@@ -46,6 +50,11 @@ func main() {
 		log.Fatalf("Failed to create Gemini client: %v", err)
 	}
 
+	if *resumeDir != "" {
+		runBatch(geminiClient, *resumeDir, *workers)
+		return
+	}
+
 	// Create resume parser configuration with target skills matching Python example
 	config := &ResumeParserConfig{
 		TargetSkills: []string{
@@ -110,3 +119,39 @@ func main() {
 		}
 	}
 }
+
+// runBatch parses every resume in a directory with the reusable BatchStructuredNode
+func runBatch(provider llm.LLMProvider, directory string, workers int) {
+	targetSkills := DefaultResumeParserConfig().TargetSkills
+
+	config := structured.DefaultBatchConfig()
+	config.AdditionalContext = []string{fmt.Sprintf("**Target Skills (use these indexes for skill_indexes field):**\n```\n%s```",
+		structured.FormatIndexedList(targetSkills))}
+
+	batchNode, err := structured.NewBatchStructuredNode[ResumeData, *structured.BatchState[ResumeData]](provider, config, NewResumeValidator(nil))
+	if err != nil {
+		log.Fatalf("Failed to create batch node: %v", err)
+	}
+
+	state := &structured.BatchState[ResumeData]{
+		Directory:  directory,
+		Extensions: []string{".txt", ".md", ".html", ".docx", ".pdf"},
+	}
+
+	fmt.Printf("Parsing resumes in %s with %d workers\n", directory, workers)
+	flow := core.NewFlow(core.NewNode(batchNode, 0, workers))
+	flow.SetProgressReporter(func(progress core.Progress) {
+		if progress.Done > 0 {
+			fmt.Printf("\r[%3.0f%%] %d/%d %-40.40s", progress.Percent, progress.Done, progress.Total, progress.Description)
+		}
+	})
+	action := flow.Run(&state)
+
+	fmt.Printf("\nBatch completed with action: %v\n", action)
+	fmt.Print(state.Report.Summary())
+	for _, item := range state.Report.Items {
+		if item.Succeeded() {
+			fmt.Printf("  %s: %s <%s>, %d positions\n", item.Input.ID, item.Result.Data.Name, item.Result.Data.Email, len(item.Result.Data.Experience))
+		}
+	}
+}
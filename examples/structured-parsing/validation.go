@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 )
@@ -176,4 +177,29 @@ func (v *InvoiceValidator) Validate(data *InvoiceData) error {
 	}
 
 	return nil
+}
+
+// ValidateWithWarnings implements structured.WarningValidator; totals that do not add
+// up are reported as warnings because invoices often include unlisted taxes or fees
+func (v *InvoiceValidator) ValidateWithWarnings(data *InvoiceData) ([]string, error) {
+	if err := v.Validate(data); err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	if len(data.LineItems) > 0 {
+		var lineTotal float64
+		for i, item := range data.LineItems {
+			lineTotal += item.Total
+			expected := float64(item.Quantity) * item.UnitPrice
+			if math.Abs(expected-item.Total) > 0.01 {
+				warnings = append(warnings, fmt.Sprintf("line_items[%d].total %.2f differs from quantity x unit_price %.2f", i, item.Total, expected))
+			}
+		}
+		if math.Abs(lineTotal-data.TotalAmount) > 0.01 {
+			warnings = append(warnings, fmt.Sprintf("line item totals %.2f do not add up to total_amount %.2f", lineTotal, data.TotalAmount))
+		}
+	}
+
+	return warnings, nil
 }
\ No newline at end of file
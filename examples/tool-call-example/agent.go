@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/alt-coder/pocketflow-go/artifacts"
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/prompt"
+	"github.com/alt-coder/pocketflow-go/tools"
+	"github.com/alt-coder/pocketflow-go/tools/web"
+)
+
+// agent holds what the sessions of the agent share: the configuration, the
+// MCP servers, the LLM clients, the prompts and the saved sessions. Each
+// session gets its own tool manager, usage tracker and flow from it.
+type agent struct {
+	config     *AgentWorkflowConfig
+	mcpManager *tools.MCPManager
+	providers  map[string]llm.LLMProvider // LLM clients by registry name, without usage tracking
+	models     map[string]string          // Model of each client by registry name
+	chatModel  string                     // Model of the chat node after node_providers
+	prompts    *prompt.Store
+	sessions   memory.SessionStore
+	artifacts  artifacts.Store // Where oversized tool results are kept; nil keeps them in the conversation
+}
+
+// newAgent starts the MCP servers and creates the LLM clients of config
+func newAgent(ctx context.Context, config *AgentWorkflowConfig) (*agent, error) {
+	a := &agent{
+		config:     config,
+		mcpManager: tools.NewMCPManager(config.MCP),
+		providers:  make(map[string]llm.LLMProvider),
+		models:     make(map[string]string),
+		chatModel:  config.LLM.Model,
+	}
+	if err := a.mcpManager.Initialize(ctx); err != nil {
+		a.Close()
+		return nil, fmt.Errorf("failed to initialize MCP tool manager: %w", err)
+	}
+	if err := a.addProvider(ctx, defaultProviderName, config.LLM); err != nil {
+		a.Close()
+		return nil, err
+	}
+	for name, providerConfig := range config.Providers {
+		if err := a.addProvider(ctx, name, providerConfig); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+	if config.Agent.SummaryModel != "" && config.Agent.SummaryModel != config.LLM.Model && config.Agent.NodeProviders[summarizeNodeName] == "" {
+		summaryConfig := *config.LLM
+		summaryConfig.Model = config.Agent.SummaryModel
+		if err := a.addProvider(ctx, summaryProviderName, &summaryConfig); err != nil {
+			a.Close()
+			return nil, err
+		}
+	}
+	if name := config.Agent.NodeProviders[chatNodeName]; config.Providers[name] != nil {
+		a.chatModel = config.Providers[name].Model
+	}
+
+	var err error
+	if a.prompts, err = loadPrompts(config.Agent); err != nil {
+		a.Close()
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+	if a.sessions, err = memory.NewFileSessionStore(config.Agent.SessionDir); err != nil {
+		a.Close()
+		return nil, fmt.Errorf("failed to open session directory: %w", err)
+	}
+	// Results too large for the conversation are kept aside for read_artifact,
+	// next to the sessions so they are still there when one is resumed
+	if config.Agent.MaxToolResultSize > 0 {
+		if a.artifacts, err = artifacts.NewDirStore(filepath.Join(config.Agent.SessionDir, "artifacts")); err != nil {
+			a.Close()
+			return nil, fmt.Errorf("failed to open artifact directory: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// addProvider creates the LLM client registered as name
+func (a *agent) addProvider(ctx context.Context, name string, config *LLMConfig) error {
+	provider, err := createLLMProvider(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to create LLM provider %s: %w", name, err)
+	}
+	a.providers[name] = provider
+	a.models[name] = config.Model
+	return nil
+}
+
+// Close stops the MCP servers and closes the LLM clients
+func (a *agent) Close() {
+	for _, provider := range a.providers {
+		closeLLMProvider(provider)
+	}
+	a.mcpManager.Close()
+}
+
+// newToolManager returns a tool manager over the agent's MCP servers that
+// allows the configured tools; tools allowed in a session stay in its manager
+func (a *agent) newToolManager() (*tools.ToolManager, error) {
+	toolManager := tools.NewToolManager()
+	toolManager.SetMCPManager(a.mcpManager)
+	for _, name := range a.config.Agent.AllowedTools {
+		toolManager.AlwaysAllow(name)
+	}
+	if a.artifacts != nil {
+		spillover := tools.SpilloverConfig{Store: a.artifacts, MaxSize: a.config.Agent.MaxToolResultSize}
+		if err := toolManager.SetSpillover(spillover); err != nil {
+			return nil, fmt.Errorf("failed to set up tool result spillover: %w", err)
+		}
+		toolManager.AlwaysAllow(tools.ReadArtifactTool)
+	}
+	if a.config.Web != nil {
+		if err := web.AddTools(toolManager, a.config.Web); err != nil {
+			return nil, fmt.Errorf("failed to add web tools: %w", err)
+		}
+	}
+	return toolManager, nil
+}
+
+// newProviders returns the registry of the agent's LLM clients with all of
+// them counting towards tracker, so the session's budget covers every node.
+// Nodes use the llm client unless node_providers assigns them a named one.
+func (a *agent) newProviders(tracker *llm.UsageTracker) (*llm.ProviderRegistry, error) {
+	providers := llm.NewProviderRegistry()
+	for name, provider := range a.providers {
+		if err := providers.Register(name, tracker.Wrap(provider, a.models[name])); err != nil {
+			return nil, fmt.Errorf("failed to register LLM provider %s: %w", name, err)
+		}
+	}
+	if err := providers.SetDefault(defaultProviderName); err != nil {
+		return nil, err
+	}
+	if _, ok := a.providers[summaryProviderName]; ok {
+		if err := providers.Assign(summarizeNodeName, summaryProviderName); err != nil {
+			return nil, fmt.Errorf("failed to assign summary LLM provider: %w", err)
+		}
+	}
+	for node, name := range a.config.Agent.NodeProviders {
+		if err := providers.Assign(node, name); err != nil {
+			return nil, fmt.Errorf("failed to assign LLM provider: %w", err)
+		}
+	}
+	return providers, nil
+}
+
+// newFlow returns the session's flow, which runs turn after turn until the
+// user stops or a budget is reached; options are applied after the
+// configured ones
+func (a *agent) newFlow(state *AgentState, toolManager *tools.ToolManager, providers *llm.ProviderRegistry, options ...ChatNodeOptions[*AgentState]) *core.Flow[*AgentState] {
+	summarizerConfig := nodes.DefaultSummarizerConfig()
+	summarizerConfig.MaxTokens = a.config.Agent.SummaryMaxTokens
+	summarizerConfig.ContextPercent = a.config.Agent.SummaryContextPercent
+	var cleanupConfig *nodes.CleanupConfig
+	var cleanupClassifier llm.LLMProvider
+	if a.config.Agent.CleanupMaxResultLength > 0 {
+		cleanupConfig = nodes.DefaultCleanupConfig()
+		cleanupConfig.MaxResultLength = a.config.Agent.CleanupMaxResultLength
+		if a.config.Agent.CleanupClassify {
+			cleanupClassifier = providers.For(cleanupNodeName)
+		}
+	}
+
+	options = append([]ChatNodeOptions[*AgentState]{
+		WithPrompts[*AgentState](a.prompts),
+		WithModel[*AgentState](a.chatModel),
+		WithSummarizer[*AgentState](memory.NewLLMSummarizer(providers.For(summarizeNodeName), ""), summarizerConfig),
+		WithCleanup[*AgentState](cleanupClassifier, cleanupConfig),
+		WithToolExecution[*AgentState](a.config.Agent.ToolExecution),
+		WithReasoning[*AgentState](a.config.Agent.Reasoning),
+		WithBudget[*AgentState](a.config.Agent.Budget),
+	}, options...)
+	workflow := NewToolUsageFlow(toolManager, providers.For(chatNodeName), nil, state, options...)
+	workflow.AddSuccessor(workflow, core.ActionSuccess)
+	return workflow.(*core.Flow[*AgentState])
+}
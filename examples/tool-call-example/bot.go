@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/connectors"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+// botIdleTimeout ends the sessions of conversations without messages for this
+// long; their next message resumes the saved session
+const botIdleTimeout = 30 * time.Minute
+
+// runBot serves the agent on a chat platform until interrupted. Every channel
+// or thread is a session of its own, saved under the conversation's ID.
+func runBot(ctx context.Context, agent *agent, name, listen string) error {
+	platform, webhook, err := newPlatform(ctx, name)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if webhook != nil {
+		server := &http.Server{Addr: listen, Handler: webhook, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Failed to receive %s events: %v", name, err)
+				stop()
+			}
+		}()
+		defer server.Shutdown(context.Background())
+		fmt.Printf("Receiving %s events on %s\n", name, listen)
+	}
+
+	config := connectors.DefaultConfig()
+	config.IdleTimeout = botIdleTimeout
+	bridge := connectors.NewBridge(platform, agent.serveSession, config)
+	fmt.Printf("🤖 Serving the agent on %s; press Ctrl+C to stop\n", name)
+	return bridge.Run(ctx)
+}
+
+// newPlatform connects to the named platform with the credentials in the
+// environment; webhook platforms also return the handler receiving their events
+func newPlatform(ctx context.Context, name string) (connectors.Platform, http.Handler, error) {
+	switch name {
+	case "telegram":
+		platform, err := connectors.NewTelegram(connectors.TelegramConfig{Token: os.Getenv("TELEGRAM_BOT_TOKEN")})
+		return platform, nil, err
+	case "slack":
+		platform, err := connectors.NewSlack(connectors.SlackConfig{
+			BotToken:      os.Getenv("SLACK_BOT_TOKEN"),
+			SigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+		})
+		return platform, platform, err
+	case "discord":
+		platform, err := connectors.NewDiscord(connectors.DiscordConfig{
+			ApplicationID: os.Getenv("DISCORD_APPLICATION_ID"),
+			BotToken:      os.Getenv("DISCORD_BOT_TOKEN"),
+			PublicKey:     os.Getenv("DISCORD_PUBLIC_KEY"),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		// Without an application ID the /ask command must exist already
+		if os.Getenv("DISCORD_APPLICATION_ID") != "" {
+			if err := platform.RegisterCommand(ctx); err != nil {
+				return nil, nil, fmt.Errorf("failed to register the /ask command: %w", err)
+			}
+		}
+		return platform, platform, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported connector: %s. Supported connectors: telegram, slack, discord", name)
+	}
+}
+
+// serveSession runs the agent for one conversation of a chat platform. Tools
+// allowed with "Always allow" and the budget apply to the conversation only.
+func (a *agent) serveSession(ctx context.Context, session *connectors.Session) error {
+	toolManager, err := a.newToolManager()
+	if err != nil {
+		return err
+	}
+	tracker := llm.NewUsageTracker(a.config.Agent.Pricing)
+	providers, err := a.newProviders(tracker)
+	if err != nil {
+		return err
+	}
+
+	state := NewAgentState(nil)
+	state.Sessions = a.sessions
+	state.SessionID = session.SessionID()
+	state.Tracker = tracker
+	// A conversation continues where it left off, also after a restart
+	if err := resumeSession(ctx, state, toolManager, state.SessionID); err != nil && !errors.Is(err, memory.ErrSessionNotFound) {
+		return fmt.Errorf("failed to resume session: %w", err)
+	}
+
+	flow := a.newFlow(state, toolManager, providers,
+		WithChatIO[*AgentState](sessionIO{ctx: ctx, session: session}),
+		WithApprovalSource[*AgentState](session))
+	flow.SetConfigSnapshot(func() any {
+		return runConfig{Config: a.config.Redacted(), ChatModel: a.chatModel, Tools: toolManager.AlwaysAllowedTools()}
+	})
+	_, report := flow.RunWithReport(&state, nil)
+	if err := saveRunReport(filepath.Join(a.config.Agent.SessionDir, "runs"), state.SessionID, report); err != nil {
+		log.Printf("Failed to save run report: %v", err)
+	}
+
+	if exceeded := state.BudgetExceeded; exceeded != nil {
+		return session.Send(ctx, "⛔ "+exceeded.Message)
+	}
+	return nil
+}
+
+// sessionIO chats with the user of a connector session. Replies are sent
+// whole: the planner answers in YAML, which is only shown once parsed.
+type sessionIO struct {
+	ctx     context.Context
+	session *connectors.Session
+}
+
+// ReadInput waits for the next message; an idle or stopped session reads an
+// empty one, which ends the flow
+func (s sessionIO) ReadInput(bool) string {
+	event, err := s.session.Next(s.ctx)
+	if err != nil {
+		return ""
+	}
+	return event.Text
+}
+
+// ShowReply sends the answer to the conversation
+func (s sessionIO) ShowReply(text string) {
+	if err := s.session.Send(s.ctx, text); err != nil {
+		log.Printf("Failed to reply in %s: %v", s.session.Conversation, err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ChatIO is where the chat node reads the user's messages and shows its replies
+type ChatIO interface {
+	// ReadInput waits for the user's next message; an empty message ends the session
+	ReadInput(firstTurn bool) string
+	// ShowReply shows the assistant's answer to the user
+	ShowReply(text string)
+}
+
+// terminalIO chats with the user at the terminal
+type terminalIO struct{}
+
+// ReadInput reads lines until two consecutive blank lines or the end of input
+func (terminalIO) ReadInput(firstTurn bool) string {
+	// Show welcome message only on first interaction
+	if firstTurn {
+		fmt.Println("How may I help you today?")
+	}
+
+	fmt.Print("You: ")
+	scanner := bufio.NewScanner(os.Stdin)
+	var lines []string
+	lastLineEmpty := false
+
+	for {
+		if !scanner.Scan() {
+			// Handle EOF or error
+			if err := scanner.Err(); err != nil {
+				log.Printf("Error reading input: %v", err)
+			}
+			break
+		}
+
+		line := scanner.Text()
+
+		// Two consecutive blank lines = end
+		if line == "" && lastLineEmpty {
+			break
+		}
+
+		lastLineEmpty = (line == "")
+		lines = append(lines, line)
+	}
+
+	// Remove trailing empty lines
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ShowReply prints the answer
+func (terminalIO) ShowReply(text string) {
+	fmt.Printf("\nAssistant: %s\n", text)
+}
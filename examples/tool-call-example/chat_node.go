@@ -1,16 +1,17 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
 	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/prompt"
 	"github.com/alt-coder/pocketflow-go/structured"
 	"github.com/alt-coder/pocketflow-go/tools"
 )
@@ -22,12 +23,29 @@ type ChatNode[T StateInterface] struct {
 	tools               []tools.ToolSchema
 	key                 string
 	toolUse             Permission
-	AlwaysAllowedTools  map[string]struct{}
-	errorRetryCount     int
 	toolManager         *tools.ToolManager
+	prompts             *prompt.Store
+	model               string
 	isUserInputRequired bool
+	summarizer          memory.Summarizer
+	summarizerConfig    *nodes.SummarizerConfig
+	cleanup             *nodes.CleanupConfig
+	cleanupClassifier   llm.LLMProvider
+	budget              nodes.Budget
+	toolExecution       *ToolExecutionConfig
+	reasoning           bool
+	reloader            nodes.Reloader
+	io                  ChatIO
+	approvalSource      nodes.ApprovalSource
 }
 
+// planAttempts is how often the planner may respond until its YAML parses:
+// the first response and two repairs
+const planAttempts = 3
+
+// systemPromptBudget is the share of the model's context window the system prompt may use
+const systemPromptBudget = 0.25
+
 type ChatNodeOptions[T StateInterface] func(n *ChatNode[T])
 
 func WithToolUse[T StateInterface](toolUse Permission) ChatNodeOptions[T] {
@@ -36,21 +54,159 @@ func WithToolUse[T StateInterface](toolUse Permission) ChatNodeOptions[T] {
 	}
 }
 
+// WithPrompts renders the planning prompt from the given store instead of the embedded defaults
+func WithPrompts[T StateInterface](prompts *prompt.Store) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.prompts = prompts
+	}
+}
+
+// WithModel sets the model name used to estimate the system prompt's share of the context window
+func WithModel[T StateInterface](model string) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.model = model
+	}
+}
+
+// WithSummarizer condenses older history with summarizer before each planning turn
+// once it grows past config.MaxTokens
+func WithSummarizer[T StateInterface](summarizer memory.Summarizer, config *nodes.SummarizerConfig) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.summarizer = summarizer
+		n.summarizerConfig = config
+	}
+}
+
+// WithCleanup shrinks older tool results before each planning turn. A nil
+// classifier only cuts them to config.MaxResultLength; otherwise it also
+// removes the results the current request no longer needs.
+func WithCleanup[T StateInterface](classifier llm.LLMProvider, config *nodes.CleanupConfig) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.cleanupClassifier = classifier
+		n.cleanup = config
+	}
+}
+
+// WithToolExecution sets how approved tool calls run; nil uses DefaultToolExecutionConfig
+func WithToolExecution[T StateInterface](config *ToolExecutionConfig) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.toolExecution = config
+	}
+}
+
+// WithReasoning makes the planner write down a thought before each step. The
+// thoughts, tool calls and tool results are kept in the state's reasoning trace
+// and are not shown to the user.
+func WithReasoning[T StateInterface](reasoning bool) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.reasoning = reasoning
+	}
+}
+
+// WithBudget ends the session with ActionBudgetExceeded once it reaches a limit of budget
+func WithBudget[T StateInterface](budget nodes.Budget) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.budget = budget
+	}
+}
+
+// WithReload applies the config changes reloader staged at the start of each turn
+func WithReload[T StateInterface](reloader nodes.Reloader) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.reloader = reloader
+	}
+}
+
+// WithChatIO reads the user's messages from and shows the replies through io
+// instead of the terminal
+func WithChatIO[T StateInterface](io ChatIO) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.io = io
+	}
+}
+
+// WithApprovalSource asks source for the approval of tool calls instead of
+// the user at the terminal
+func WithApprovalSource[T StateInterface](source nodes.ApprovalSource) ChatNodeOptions[T] {
+	return func(n *ChatNode[T]) {
+		n.approvalSource = source
+	}
+}
+
 func NewToolUsageFlow[T StateInterface](manager *tools.ToolManager, llmProvider llm.LLMProvider,
 	config *AgentConfig, state T, options ...ChatNodeOptions[T]) core.Workflow[T] {
-	tools := manager.GetAvailableTools()
+	availableTools := manager.GetAvailableTools()
 	chatNode := NewChatNode[T](llmProvider, config)
-	chatNode.tools = tools
+	chatNode.tools = availableTools
 	chatNode.toolManager = manager
-	chatNode.AlwaysAllowedTools = make(map[string]struct{})
 	for _, option := range options {
 		option(chatNode)
 	}
-	chatNode.key = "chat"
+	chatNode.key = chatNodeName
 	node := core.NewNode(chatNode, 3, 1)
 	node.AddSuccessor(node, core.Action(ActionContinue))
-	node.AddSuccessor(node, core.ActionRetry)
-	return core.NewFlow(node)
+
+	// Tool calls go through the approval node unless they are already allowed
+	approvalSource := chatNode.approvalSource
+	if approvalSource == nil {
+		approvalSource = nodes.NewTerminalSource(os.Stdin, os.Stdout)
+	}
+	approval := core.NewNode(nodes.NewHumanApprovalNode[T](approvalSource, nil), 0, 1)
+	executeConfig := chatNode.toolExecution
+	if executeConfig == nil {
+		executeConfig = DefaultToolExecutionConfig()
+	}
+	execute := core.NewNode(NewToolExecutionNode[T](manager, chatNode.key, executeConfig), 0, max(executeConfig.MaxConcurrency, 1))
+	node.AddSuccessor(approval, ActionRequestApproval)
+	node.AddSuccessor(execute, ActionApprove)
+	approval.AddSuccessor(execute, ActionApprove)
+	approval.AddSuccessor(node, ActionReject, ActionFeedback)
+
+	start := core.Workflow[T](node)
+	if chatNode.summarizer != nil {
+		summaryConfig := chatNode.summarizerConfig
+		if summaryConfig == nil {
+			summaryConfig = nodes.DefaultSummarizerConfig()
+		}
+		summaryConfig.Key = chatNode.key
+		if summaryConfig.Model == "" {
+			summaryConfig.Model = chatNode.model
+		}
+		summarize := core.NewNode(nodes.NewSummarizerNode[T](chatNode.summarizer, summaryConfig), 1, 1)
+		// A failed summary is not fatal; the chat continues with the full history
+		summarize.AddSuccessor(node, core.ActionSuccess)
+		summarize.AddSuccessor(node, core.ActionFailure)
+		start = summarize
+	}
+	if chatNode.cleanup != nil {
+		chatNode.cleanup.Key = chatNode.key
+		// Shrinking tool results first may spare a summary
+		cleanup := core.NewNode(nodes.NewCleanupNode[T](chatNode.cleanupClassifier, chatNode.cleanup), 1, 1)
+		cleanup.AddSuccessor(start, core.ActionSuccess)
+		cleanup.AddSuccessor(start, core.ActionFailure)
+		start = cleanup
+	}
+
+	if chatNode.budget != (nodes.Budget{}) {
+		// The budget is checked before every turn and after every tool step;
+		// ActionBudgetExceeded has no successor, so it ends the flow
+		budget := core.NewNode(nodes.NewBudgetNode[T](chatNode.budget), 0, 1)
+		budget.AddSuccessor(start, core.ActionSuccess)
+		start = budget
+	}
+	if chatNode.reloader != nil {
+		// Config changes take effect between turns, never during a model or tool call
+		reload := core.NewNode(nodes.NewReloadNode[T](chatNode.reloader), 0, 1)
+		reload.AddSuccessor(start, core.ActionSuccess)
+		start = reload
+	}
+
+	// Routing on an action the nodes do not return is caught by flow.Validate
+	// and, for Post methods returning a typo, by cmd/actioncheck
+	flow := core.NewFlow(start)
+	flow.DeclareActions(ActionContinue, ActionRequestApproval, ActionApprove, ActionReject, ActionFeedback,
+		core.ActionSuccess, core.ActionFailure, nodes.ActionBudgetExceeded)
+	return flow
 }
 
 // NewChatNode creates a new planning node
@@ -64,25 +220,34 @@ You must respond with structured YAML in the exact format specified below. Do no
 	}
 
 	return &ChatNode[T]{
-		llmProvider:        llmProvider,
-		config:             config,
-		AlwaysAllowedTools: make(map[string]struct{}), // Initialize here to prevent nil map
+		llmProvider: llmProvider,
+		config:      config,
+		io:          terminalIO{},
 	}
 }
 
 // Prep prepares the messages and context for LLM planning
 func (n *ChatNode[T]) Prep(state *T) []ChatContext {
-	messages := (*state).GetConversation(n.key)
+	ctx := context.Background()
+	history := (*state).GetMemory(n.key)
+
+	count, err := history.Len(ctx)
+	if err != nil {
+		log.Printf("Error reading conversation history: %v", err)
+		return []ChatContext{}
+	}
 
-	// Handle first interaction or when user input is required
-	if len(*messages) == 0 || n.isUserInputRequired {
-		userInput := n.getUserInput(messages)
+	// Handle first interaction, a resumed conversation that ended with an answer,
+	// or when user input is required
+	if count == 0 || n.isUserInputRequired || n.awaitingUser(ctx, history) {
+		userInput := n.io.ReadInput(count == 0)
 		if userInput != "" {
 			message := llm.Message{
 				Role:    llm.RoleUser,
 				Content: userInput,
 			}
-			(*state).AddMessage(message)
+			(*state).StartTurn()
+			n.addMessages(state, message)
 			n.isUserInputRequired = false
 		} else {
 			// Return empty context if no input provided
@@ -90,8 +255,12 @@ func (n *ChatNode[T]) Prep(state *T) []ChatContext {
 		}
 	}
 
-	// Refresh messages after potential addition
-	messages = (*state).GetConversation(n.key)
+	// Only the most recent messages are sent to the model
+	messages, err := history.Window(ctx, n.config.MaxHistory)
+	if err != nil {
+		log.Printf("Error reading conversation history: %v", err)
+		return []ChatContext{}
+	}
 
 	context := ChatContext{
 		Messages: messages,
@@ -100,67 +269,56 @@ func (n *ChatNode[T]) Prep(state *T) []ChatContext {
 	return []ChatContext{context}
 }
 
-// getUserInput handles user input collection with proper validation
-func (n *ChatNode[T]) getUserInput(messages *[]llm.Message) string {
-	// Show welcome message only on first interaction
-	if len(*messages) == 0 {
-		fmt.Println("How may I help you today?")
-	}
-
-	fmt.Print("You: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	var lines []string
-	lastLineEmpty := false
-
-	for {
-		if !scanner.Scan() {
-			// Handle EOF or error
-			if err := scanner.Err(); err != nil {
-				log.Printf("Error reading input: %v", err)
-			}
-			break
-		}
-
-		line := scanner.Text()
-
-		// Two consecutive blank lines = end
-		if line == "" && lastLineEmpty {
-			break
-		}
-
-		lastLineEmpty = (line == "")
-		lines = append(lines, line)
+// awaitingUser reports whether the conversation ended with an answer from the
+// assistant, as when a saved session is resumed
+func (n *ChatNode[T]) awaitingUser(ctx context.Context, history memory.Store) bool {
+	last, err := history.Window(ctx, 1)
+	if err != nil || len(last) == 0 {
+		return false
 	}
+	return last[0].Role == llm.RoleAssistant && len(last[0].ToolCalls) == 0
+}
 
-	// Remove trailing empty lines
-	for len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
+// addMessages appends messages to the conversation history, logging failures so
+// a storage problem does not end the session
+func (n *ChatNode[T]) addMessages(state *T, messages ...llm.Message) {
+	for i := range messages {
+		messages[i] = (*state).WithProvenance(messages[i], chatNodeName)
 	}
-
-	if len(lines) == 0 {
-		return ""
+	if err := (*state).GetMemory(n.key).Append(context.Background(), messages...); err != nil {
+		log.Printf("Error saving conversation history: %v", err)
 	}
-
-	return strings.Join(lines, "\n")
 }
 
-// Exec calls planning LLM with the prepared messages
+// Exec calls planning LLM without the context of the run
 func (n *ChatNode[T]) Exec(chatcontext ChatContext) (llm.Message, error) {
+	return n.ExecContext(context.Background(), chatcontext)
+}
+
+// ExecContext calls planning LLM with the prepared messages; cancelling the
+// run cancels the call. Responses that are not valid YAML are sent back with
+// the parse error until the model repairs them.
+func (n *ChatNode[T]) ExecContext(ctx context.Context, chatcontext ChatContext) (llm.Message, error) {
 	// Validate context
-	if chatcontext.Messages == nil || len(*chatcontext.Messages) == 0 {
-		return llm.Message{}, fmt.Errorf("no messages to process")
+	if len(chatcontext.Messages) == 0 {
+		return llm.Message{}, core.Terminal(fmt.Errorf("no messages to process"))
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	// The prompt asks for YAML, so constrained decoding stays off
+	parser, err := structured.NewParser(n.llmProvider, &structured.Config{Timeout: 60 * time.Second, DisableConstrainedDecoding: true})
+	if err != nil {
+		return llm.Message{}, core.Terminal(err)
+	}
 
 	// Prepare messages with system prompt
-	messages := n.prepareMessagesWithSystemPrompt(*chatcontext.Messages)
+	messages := n.prepareMessagesWithSystemPrompt(chatcontext.Messages)
 
-	// Call LLM provider
-	response, err := n.llmProvider.CallLLM(ctx, messages)
+	result, response, err := structured.ParseMessagesWithRepair[LLMResponse](parser, ctx, messages, nil, planAttempts)
 	if err != nil {
+		if err == result.Error {
+			// The model could not repair its response; planning again will not either
+			return llm.Message{}, core.Terminal(err)
+		}
 		return llm.Message{}, fmt.Errorf("LLM call failed: %w", err)
 	}
 
@@ -193,29 +351,12 @@ func (n *ChatNode[T]) Post(state *T, prepResults []ChatContext, execResults ...l
 
 	execResult := execResults[0]
 
-	// Check for maximum retry limit
-	if n.errorRetryCount >= 3 {
-		log.Printf("Maximum retry limit reached. Last response: %s", execResult.Content)
-		n.errorRetryCount = 0 // Reset for next interaction
-		return core.Action(ActionFailure)
-	}
-
-	// Parse the YAML response
+	// Parse the YAML response; Exec already repaired it, so only the
+	// response of ExecFallback fails here
 	result, err := n.parseYAMLResponse(execResult.Content)
 	if err != nil {
-		n.errorRetryCount++
-		log.Printf("Error parsing response: %v, retrying (%d/3)", err, n.errorRetryCount)
-
-		// Add the failed response and error message to conversation
-		(*state).AddMessage(llm.Message{
-			Role:    llm.RoleAssistant,
-			Content: execResult.Content,
-		})
-		(*state).AddMessage(llm.Message{
-			Role:    llm.RoleUser,
-			Content: fmt.Sprintf("I apologize, but I encountered an error processing your response: %v. Please try again with the correct YAML format.", err),
-		})
-		return core.ActionRetry
+		log.Printf("Error parsing response: %v. Last response: %s", err, execResult.Content)
+		return core.Action(ActionFailure)
 	}
 
 	// Validate response content
@@ -228,14 +369,18 @@ func (n *ChatNode[T]) Post(state *T, prepResults []ChatContext, execResults ...l
 	execResult.ToolCalls = result.LLMToolCalls
 
 	// Add the assistant message to state
-	(*state).AddMessage(execResult)
+	n.addMessages(state, execResult)
+	if err := (*state).SaveSession(context.Background()); err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
 
-	// Reset error retry count on success
-	n.errorRetryCount = 0
+	if n.reasoning {
+		(*state).AddReasoningStep(NewReasoningStep(result.Thought, execResult.ToolCalls, result.Response))
+	}
 
 	// Display the response to user
 	if result.Response != "" {
-		fmt.Printf("\nAssistant: %s\n", result.Response)
+		n.io.ShowReply(result.Response)
 	}
 
 	// Handle tool calls if present
@@ -248,125 +393,19 @@ func (n *ChatNode[T]) Post(state *T, prepResults []ChatContext, execResults ...l
 	return core.ActionSuccess
 }
 
-// handleToolCalls processes tool calls with permission checking and execution
+// handleToolCalls stores the planned tool calls and routes them to execution,
+// through the approval node unless every tool is already allowed
 func (n *ChatNode[T]) handleToolCalls(state *T, toolCalls []llm.ToolCalls) core.Action {
-	approvedTools := toolCalls
-	var action core.Action = core.ActionSuccess
-
-	// Check permissions if not set to always allow
-	if n.toolUse != PermissionAllow {
-		approvedTools, action = n.AskToolPermission(*state, toolCalls)
-		if action == core.ActionFailure || action == core.ActionContinue {
-			return action
-		}
-	}
-
-	// Execute approved tools
-	results := make([]llm.ToolResults, 0, len(approvedTools))
-	responseContent := ""
-
-	for _, tool := range approvedTools {
-		result, err := n.toolManager.ExecuteTool(context.Background(), tool)
-		if err != nil {
-			log.Printf("Error executing tool %s: %v", tool.ToolName, err)
-			result = llm.ToolResults{
-				Id:      tool.Id,
-				Content: result.Content,
-				IsError: true,
-				Error:   fmt.Sprintf("Tool execution failed: %v", err),
-			}
-		}
-		results = append(results, result)
-
-		// Build response content
-		responseContent += fmt.Sprintf("## Tool %s result:\n%s\n", tool.ToolName, result.Content)
-		if result.IsError {
-			responseContent += fmt.Sprintf("Error: %s\n", result.Error)
-		}
-	}
-
-	// Create tool results message
-	if len(results) > 0 {
-		responseMessage := llm.Message{
-			Role:        llm.RoleUser,
-			Content:     responseContent,
-			ToolCalls:   approvedTools,
-			ToolResults: results,
-		}
-
-		// Handle media from tool results
-		for _, result := range results {
-			if len(result.Media) > 0 {
-				responseMessage.Media = result.Media
-				responseMessage.MimeType = result.MetaData.ContentType
-				break // Only handle first media result
-			}
-		}
-
-		(*state).AddMessage(responseMessage)
-	}
-
-	return core.ActionSuccess
-}
-
-// AskToolPermission handles tool permission requests with improved input validation
-func (n *ChatNode[T]) AskToolPermission(state T, availableTools []llm.ToolCalls) ([]llm.ToolCalls, core.Action) {
-	if len(availableTools) == 0 {
-		return []llm.ToolCalls{}, core.ActionSuccess
+	(*state).SetPendingToolCalls(toolCalls)
+	if n.toolUse == PermissionAllow {
+		return ActionApprove
 	}
-
-	results := make([]llm.ToolCalls, 0, len(availableTools))
-	scanner := bufio.NewScanner(os.Stdin)
-
-	for _, tool := range availableTools {
-		// Skip if already always allowed
-		if _, ok := n.AlwaysAllowedTools[tool.ToolName]; ok {
-			results = append(results, tool)
-			continue
-		}
-
-		fmt.Printf("\nTool '%s' requires permission.\n", tool.ToolName)
-		fmt.Printf("Arguments: %v\n", tool.ToolArgs)
-
-		for {
-			fmt.Print("Allow? [y=yes, n=no, a=always allow]: ")
-
-			if !scanner.Scan() {
-				if err := scanner.Err(); err != nil {
-					log.Printf("Error reading input: %v", err)
-				}
-				return []llm.ToolCalls{}, core.ActionFailure
-			}
-
-			response := strings.TrimSpace(strings.ToLower(scanner.Text()))
-
-			switch response {
-			case "y", "yes":
-				results = append(results, tool)
-				goto nextTool
-			case "n", "no":
-				goto nextTool
-			case "a", "always":
-				n.AlwaysAllowedTools[tool.ToolName] = struct{}{}
-				results = append(results, tool)
-				goto nextTool
-			case "":
-				fmt.Println("Please enter a valid response (y/n/a)")
-				continue
-			default:
-				// Treat other input as user message
-				message := llm.Message{
-					Role:    llm.RoleUser,
-					Content: response,
-				}
-				state.AddMessage(message)
-				return []llm.ToolCalls{}, core.Action(ActionContinue)
-			}
+	for _, toolCall := range toolCalls {
+		if !n.toolManager.IsAlwaysAllowed(toolCall.ToolName) {
+			return ActionRequestApproval
 		}
-	nextTool:
 	}
-
-	return results, core.ActionSuccess
+	return ActionApprove
 }
 
 // ExecFallback provides a safe error response
@@ -379,84 +418,44 @@ func (n *ChatNode[T]) ExecFallback(err error) llm.Message {
 
 // buildSystemPromptWithTools creates a system prompt that includes available tools and instructions
 func (n *ChatNode[T]) buildSystemPromptWithTools(summarizedHistory string) string {
-	var promptBuilder strings.Builder
 	var availableTools []tools.ToolSchema
 	if n.toolManager != nil {
 		availableTools = n.toolManager.GetAvailableTools()
 	}
 
-	// Start with base system prompt
-	promptBuilder.WriteString(n.config.SystemPrompt)
-	promptBuilder.WriteString("\n\n")
-
-	if summarizedHistory != "" {
-		promptBuilder.WriteString("## Previous Conversation Summary:\n")
-		promptBuilder.WriteString(summarizedHistory)
-		promptBuilder.WriteString("\n\n")
+	prompts := n.prompts
+	if prompts == nil {
+		prompts = defaultPrompts
 	}
 
-	if len(availableTools) > 0 {
-		promptBuilder.WriteString("## Available Tools:\n")
-		for _, tool := range availableTools {
-			promptBuilder.WriteString(fmt.Sprintf("\n- **%s**: %s\n", tool.Name, tool.Description))
-			if len(tool.Parameters) > 0 {
-				promptBuilder.WriteString("  Parameters:\n")
-				n.formatToolParameters(&promptBuilder, tool.Parameters, "    ")
-			}
-		}
-		promptBuilder.WriteString("\n")
-	}
-
-	// Add strict YAML format instructions
-	promptBuilder.WriteString("## Response Format:\n")
-	promptBuilder.WriteString("Respond with EXACTLY this YAML structure (no additional text):\n\n")
-	promptBuilder.WriteString("```yaml\n")
-	promptBuilder.WriteString("intent: \"Brief description of what you're trying to accomplish\"\n")
-	promptBuilder.WriteString("response: \"Your response to the user\"\n")
-	promptBuilder.WriteString("tool_calls:\n")
-	promptBuilder.WriteString("  - \"tool_name_1\"\n")
-	promptBuilder.WriteString("  - \"tool_name_2\"\n")
-	promptBuilder.WriteString("tool_args:\n")
-	promptBuilder.WriteString("  - arg1: \"value1\"\n")
-	promptBuilder.WriteString("    arg2: \"value2\"\n")
-	promptBuilder.WriteString("  - arg1: \"value3\"\n")
-	promptBuilder.WriteString("```\n\n")
-	promptBuilder.WriteString("If no tools are needed, use empty arrays:\n")
-	promptBuilder.WriteString("tool_calls: []\n")
-	promptBuilder.WriteString("tool_args: []\n\n")
-	promptBuilder.WriteString("IMPORTANT: Use sequential tool calls only when there's a dependency between them. For independent operations, use a single tool call with all required arguments.\n")
-	promptBuilder.WriteString("Analyze the following request and respond with the structured YAML format that must be parseable.\n")
-
-	return promptBuilder.String()
-}
-
-// formatToolParameters recursively formats tool parameters from tools.Parameter
-func (n *ChatNode[T]) formatToolParameters(builder *strings.Builder, params map[string]tools.Parameter, indent string) {
-	for paramName, param := range params {
-		// Format parameter with type and description
-		builder.WriteString(fmt.Sprintf("%s- %s (%s)", indent, paramName, param.Type))
-
-		if param.Description != "" {
-			builder.WriteString(fmt.Sprintf(": %s", param.Description))
-		}
-
-		// Add required indicator
-		if param.Required {
-			builder.WriteString(" [required]")
-		}
+	render := func(toolSchemas []tools.ToolSchema) (string, error) {
+		return prompts.Render("planning", prompt.Vars{
+			"SystemPrompt": n.config.SystemPrompt,
+			"Summary":      summarizedHistory,
+			"Tools":        toolSchemas,
+			"Reasoning":    n.reasoning,
+		})
+	}
 
-		// Add enum values if present
-		if len(param.Enum) > 0 {
-			builder.WriteString(fmt.Sprintf(" [options: %s]", strings.Join(param.Enum, ", ")))
-		}
+	systemPrompt, err := render(availableTools)
+	if err != nil {
+		log.Printf("Warning: failed to render planning prompt, using the base system prompt: %v", err)
+		return n.config.SystemPrompt
+	}
 
-		// Add default value if present
-		if param.Default != nil {
-			builder.WriteString(fmt.Sprintf(" [default: %v]", param.Default))
+	// Large tool inventories can crowd out the conversation; shorten tool descriptions first
+	budget := prompt.CheckPromptBudget(systemPrompt, n.model, systemPromptBudget)
+	if budget.Exceeded() && len(availableTools) > 0 {
+		log.Printf("Warning: system %s; compacting tool descriptions", budget)
+		if compacted, err := render(tools.CompactToolSchemas(availableTools, 100)); err == nil {
+			systemPrompt = compacted
+			budget = prompt.CheckPromptBudget(systemPrompt, n.model, systemPromptBudget)
 		}
-
-		builder.WriteString("\n")
 	}
+	if budget.Exceeded() {
+		log.Printf("Warning: system %s; consider fewer tools or a model with a larger context window", budget)
+	}
+	return systemPrompt
 }
 
 // parseYAMLResponse parses the strict YAML response from LLM with better error handling
@@ -479,10 +478,8 @@ func (n *ChatNode[T]) parseYAMLResponse(responseContent string) (ParsedResult, e
 			break
 		}
 
-		callID := fmt.Sprintf("call_%d_%d", time.Now().Unix(), i+1)
-
 		llmToolCall := llm.ToolCalls{
-			Id:       callID,
+			Id:       llm.NewCallID(),
 			ToolName: toolName,
 			ToolArgs: response.ToolArgs[i],
 		}
@@ -491,6 +488,7 @@ func (n *ChatNode[T]) parseYAMLResponse(responseContent string) (ParsedResult, e
 
 	return ParsedResult{
 		Response:     response.Response,
+		Thought:      response.Thought,
 		LLMToolCalls: llmToolCalls,
 	}, nil
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+func TestNewToolUsageFlow_Validate(t *testing.T) {
+	var running, peak int32
+	provider := llm.NewMockProvider("mock")
+	workflow := NewToolUsageFlow(testToolManager(t, &running, &peak), provider, nil, NewAgentState(nil),
+		WithSummarizer[*AgentState](memory.NewLLMSummarizer(provider, ""), nil),
+		WithBudget[*AgentState](nodes.Budget{MaxToolSteps: 3}))
+	if err := workflow.(*core.Flow[*AgentState]).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+// scriptedProvider answers with its responses in order
+type scriptedProvider struct {
+	responses []string
+	calls     [][]llm.Message
+}
+
+func (p *scriptedProvider) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	p.calls = append(p.calls, messages)
+	return llm.Message{Role: llm.RoleAssistant, Content: p.responses[min(len(p.calls), len(p.responses))-1]}, nil
+}
+
+func (p *scriptedProvider) GetName() string { return "scripted" }
+
+func (p *scriptedProvider) SetConfig(config map[string]any) error { return nil }
+
+func TestChatNode_RepairsInvalidYAML(t *testing.T) {
+	answer := "intent: answer\nresponse: Hi there.\ntool_calls: []\ntool_args: []"
+	provider := &scriptedProvider{responses: []string{"response: [unclosed", answer}}
+	chat := NewChatNode[*AgentState](provider, nil)
+	turn := ChatContext{Messages: []llm.Message{{Role: llm.RoleUser, Content: "Hi"}}}
+
+	response, err := chat.ExecContext(context.Background(), turn)
+	if err != nil || response.Content != answer {
+		t.Fatalf("ExecContext() = %q, %v; want the repaired answer", response.Content, err)
+	}
+	if len(provider.calls) != 2 || provider.calls[1][len(provider.calls[1])-2].Content != "response: [unclosed" {
+		t.Errorf("calls = %v, want the invalid response sent back for repair", provider.calls)
+	}
+
+	// A chat node keeps no repair state between turns
+	provider = &scriptedProvider{responses: []string{"response: [unclosed"}}
+	chat = NewChatNode[*AgentState](provider, nil)
+	for turns := 0; turns < 2; turns++ {
+		if _, err := chat.ExecContext(context.Background(), turn); core.IsRetryable(err) || len(provider.calls) != planAttempts*(turns+1) {
+			t.Errorf("ExecContext() error = %v after %d calls, want a terminal error after %d attempts", err, len(provider.calls), planAttempts)
+		}
+	}
+}
@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"time"
 
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/secrets"
 	"github.com/alt-coder/pocketflow-go/tools"
+	"github.com/alt-coder/pocketflow-go/tools/web"
 )
 
 // AgentWorkflowConfig represents the complete configuration for the agent workflow
 type AgentWorkflowConfig struct {
-	Agent *AgentConfig     `json:"agent"`
-	MCP   *tools.MCPConfig `json:"mcp"`
-	LLM   *LLMConfig       `json:"llm"`
+	Agent     *AgentConfig          `json:"agent"`
+	MCP       *tools.MCPConfig      `json:"mcp"`
+	Web       *web.Config           `json:"web,omitempty"` // Built-in web_search and fetch_url tools; nil adds neither
+	LLM       *LLMConfig            `json:"llm"`
+	Providers map[string]*LLMConfig `json:"providers,omitempty"` // More providers by name, for the nodes agent.node_providers assigns them to
 }
 
 // AgentConfig represents the main agent configuration
@@ -20,6 +29,56 @@ type AgentConfig struct {
 	MaxToolCalls int    `json:"max_tool_calls"` // Maximum tool calls per turn
 	MaxHistory   int    `json:"max_history"`    // Maximum conversation history
 	SystemPrompt string `json:"system_prompt"`  // System prompt for the agent
+
+	PromptDir      string            `json:"prompt_dir,omitempty"`      // Directory with <name>[@<version>].tmpl files overriding the embedded prompts
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"` // Prompt versions to use instead of the latest, by name
+
+	HistoryFile string `json:"history_file,omitempty"` // JSON lines file keeping the conversation across runs; empty keeps it in memory
+	SessionDir  string `json:"session_dir,omitempty"`  // Directory sessions are saved in for -resume
+
+	SummaryModel     string `json:"summary_model,omitempty"`      // Model that summarizes older history; empty uses the chat model
+	SummaryMaxTokens int    `json:"summary_max_tokens,omitempty"` // Summarize history above this many estimated tokens; 0 uses summary_context_percent
+
+	SummaryContextPercent float64 `json:"summary_context_percent,omitempty"` // Summarize once the prompt uses this percentage of the context window; 0 uses 50
+
+	CleanupMaxResultLength int  `json:"cleanup_max_result_length,omitempty"` // Cut tool results older than the latest to this many characters; 0 disables the cleanup
+	CleanupClassify        bool `json:"cleanup_classify,omitempty"`          // Also have the model remove older tool results the current request no longer needs
+
+	NodeProviders map[string]string `json:"node_providers,omitempty"` // Names of the providers the "chat", "summarize" and "cleanup" nodes use instead of llm
+
+	ToolExecution *ToolExecutionConfig `json:"tool_execution,omitempty"` // How approved tool calls run; nil uses DefaultToolExecutionConfig
+	Reasoning     bool                 `json:"reasoning,omitempty"`      // Have the planner write a thought before each step, kept in the session's reasoning trace
+	AllowedTools  []string             `json:"allowed_tools,omitempty"`  // Tools that run without asking for approval
+
+	MaxToolResultSize int `json:"max_tool_result_size,omitempty"` // Keep longer tool results out of the conversation, readable with read_artifact; 0 keeps them all
+
+	Budget  nodes.Budget           `json:"budget"`            // Token, cost and tool step limits per session; zero values are unlimited
+	Pricing map[string]llm.Pricing `json:"pricing,omitempty"` // Model prices by name or prefix, used for the cost budget
+}
+
+// ToolExecutionConfig controls how the approved tool calls of a turn run
+type ToolExecutionConfig struct {
+	MaxConcurrency int           `json:"max_concurrency"`        // Calls run at the same time; 1 runs them one after another
+	FailureMode    FailureMode   `json:"failure_mode"`           // "continue", "abort" or "retry"
+	MaxRetries     int           `json:"max_retries"`            // Extra attempts of a failed call in "retry" mode
+	RetryDelay     core.Duration `json:"retry_delay,omitempty"`  // Wait before the first retry, growing with each attempt, e.g. "500ms"
+	ToolTimeout    core.Duration `json:"tool_timeout,omitempty"` // Limit of a single call, e.g. "30s"; 0 leaves it to the tool's server
+
+	Deduplicate     bool     `json:"deduplicate,omitempty"`      // Answer a call repeating an earlier call of the turn with its result instead of running it again
+	RepeatableTools []string `json:"repeatable_tools,omitempty"` // Tools that always run, e.g. because their results change or they have side effects
+}
+
+// DefaultToolExecutionConfig runs up to four calls at once, reports failures
+// without affecting the other calls and answers repeated calls from the
+// earlier result
+func DefaultToolExecutionConfig() *ToolExecutionConfig {
+	return &ToolExecutionConfig{
+		MaxConcurrency: 4,
+		FailureMode:    FailureContinue,
+		MaxRetries:     2,
+		RetryDelay:     core.Duration(500 * time.Millisecond),
+		Deduplicate:    true,
+	}
 }
 
 // MCPServerConfig represents configuration for a single MCP server
@@ -28,7 +87,7 @@ type MCPServerConfig struct {
 	Args       []string          `json:"args"`
 	Env        map[string]string `json:"env,omitempty"`
 	WorkingDir string            `json:"working_dir,omitempty"`
-	Timeout    time.Duration     `json:"timeout,omitempty"`
+	Timeout    core.Duration     `json:"timeout,omitempty"`
 }
 
 // LLMConfig represents LLM provider configuration
@@ -40,12 +99,19 @@ type LLMConfig struct {
 	Temperature float32 `json:"temperature"`
 }
 
-// LoadConfig loads configuration from a JSON file
+// LoadConfig loads configuration from a JSON file. API keys and MCP server
+// environments may refer to environment variables as ${NAME} or be secret URIs
+// such as file:///run/secrets/openai, vault://secret/agent#api_key or
+// awssm://prod/agent#api_key; they are expanded before decoding.
 func LoadConfig(filename string) (*AgentWorkflowConfig, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	data, err = secrets.NewExpander(nil).ExpandJSON(context.Background(), data, "api_key", "env")
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand secrets: %w", err)
+	}
 
 	var config AgentWorkflowConfig
 	if err := json.Unmarshal(data, &config); err != nil {
@@ -57,6 +123,49 @@ func LoadConfig(filename string) (*AgentWorkflowConfig, error) {
 	return &config, nil
 }
 
+// Redacted returns a copy of the configuration without API keys and MCP
+// server environments, which may hold secrets, e.g. for run reports
+func (c *AgentWorkflowConfig) Redacted() *AgentWorkflowConfig {
+	var redacted AgentWorkflowConfig
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = json.Unmarshal(data, &redacted)
+	}
+	if err != nil {
+		return &AgentWorkflowConfig{}
+	}
+
+	for _, llmConfig := range append([]*LLMConfig{redacted.LLM}, mapValues(redacted.Providers)...) {
+		if llmConfig != nil && llmConfig.APIKey != "" {
+			llmConfig.APIKey = redactedValue
+		}
+	}
+	if redacted.MCP != nil {
+		for name, server := range redacted.MCP.Servers {
+			for key := range server.Env {
+				server.Env[key] = redactedValue
+			}
+			redacted.MCP.Servers[name] = server
+		}
+	}
+	if redacted.Web != nil && redacted.Web.Search != nil && redacted.Web.Search.APIKey != "" {
+		redacted.Web.Search.APIKey = redactedValue
+	}
+	return &redacted
+}
+
+// redactedValue replaces secrets in redacted configurations
+const redactedValue = "[redacted]"
+
+// mapValues returns the values of m in no particular order
+func mapValues[K comparable, V any](m map[K]V) []V {
+	values := make([]V, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
+
 // LoadConfigFromEnv loads configuration from environment variables with defaults
 func LoadConfigFromEnv() *AgentWorkflowConfig {
 	config := &AgentWorkflowConfig{}
@@ -93,6 +202,27 @@ func applyDefaults(config *AgentWorkflowConfig) {
 	if config.Agent.MaxHistory == 0 {
 		config.Agent.MaxHistory = 20
 	}
+	if config.Agent.SessionDir == "" {
+		config.Agent.SessionDir = "sessions"
+	}
+	if config.Agent.ToolExecution == nil {
+		config.Agent.ToolExecution = DefaultToolExecutionConfig()
+	} else {
+		defaults := DefaultToolExecutionConfig()
+		execution := config.Agent.ToolExecution
+		if execution.MaxConcurrency == 0 {
+			execution.MaxConcurrency = defaults.MaxConcurrency
+		}
+		if execution.FailureMode == "" {
+			execution.FailureMode = defaults.FailureMode
+		}
+		if execution.MaxRetries == 0 {
+			execution.MaxRetries = defaults.MaxRetries
+		}
+		if execution.RetryDelay == 0 {
+			execution.RetryDelay = defaults.RetryDelay
+		}
+	}
 	if config.Agent.SystemPrompt == "" {
 		config.Agent.SystemPrompt = "You are a helpful assistant with who might have access to various tools. Use tools(if available) when necessary to help the user accomplish their tasks."
 	}
@@ -124,4 +254,25 @@ func applyDefaults(config *AgentWorkflowConfig) {
 		config.LLM.Temperature = 0.7
 	}
 
+	// Named providers inherit what they leave out from llm
+	for _, provider := range config.Providers {
+		if provider.Provider == "" {
+			provider.Provider = config.LLM.Provider
+		}
+		if provider.Provider == config.LLM.Provider {
+			if provider.APIKey == "" {
+				provider.APIKey = config.LLM.APIKey
+			}
+			if provider.BaseURL == "" {
+				provider.BaseURL = config.LLM.BaseURL
+			}
+		}
+		if provider.Model == "" {
+			provider.Model = config.LLM.Model
+		}
+		if provider.Temperature == 0 {
+			provider.Temperature = config.LLM.Temperature
+		}
+	}
+
 }
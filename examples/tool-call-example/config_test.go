@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/tools"
+	"github.com/alt-coder/pocketflow-go/tools/web"
+)
+
+func TestAgentWorkflowConfig_Redacted(t *testing.T) {
+	config := &AgentWorkflowConfig{
+		Agent:     &AgentConfig{AllowedTools: []string{"search"}},
+		LLM:       &LLMConfig{Provider: "openai", Model: "gpt-4o", APIKey: "sk-main"},
+		Providers: map[string]*LLMConfig{"cheap": {Model: "gpt-4o-mini", APIKey: "sk-cheap"}},
+		MCP:       &tools.MCPConfig{Servers: map[string]tools.MCPServerConfig{"github": {Command: "github-mcp", Env: map[string]string{"TOKEN": "ghp"}}}},
+		Web:       &web.Config{Search: &web.SearchConfig{Backend: web.BackendBrave, APIKey: "brave-key"}},
+	}
+	redacted := config.Redacted()
+
+	if redacted.LLM.APIKey != redactedValue || redacted.Providers["cheap"].APIKey != redactedValue || redacted.MCP.Servers["github"].Env["TOKEN"] != redactedValue {
+		t.Errorf("Redacted() kept secrets: %+v, %+v, %+v", redacted.LLM, redacted.Providers["cheap"], redacted.MCP.Servers["github"])
+	}
+	if redacted.LLM.Model != "gpt-4o" || redacted.Agent.AllowedTools[0] != "search" {
+		t.Errorf("Redacted() lost settings: %+v", redacted)
+	}
+	if redacted.Web.Search.APIKey != redactedValue || redacted.Web.Search.Backend != web.BackendBrave {
+		t.Errorf("Redacted() kept the search key or lost the backend: %+v", redacted.Web.Search)
+	}
+	if config.LLM.APIKey != "sk-main" || config.MCP.Servers["github"].Env["TOKEN"] != "ghp" || config.Web.Search.APIKey != "brave-key" {
+		t.Error("Redacted() changed the original config")
+	}
+}
+
+func TestLoadConfig_ExpandsSecretFields(t *testing.T) {
+	t.Setenv("AGENT_API_KEY", "sk-env")
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+		"agent": {"system_prompt": "Reply with ${ when asked"},
+		"llm": {"provider": "openai", "api_key": "${AGENT_API_KEY}"},
+		"mcp": {"servers": {"files": {"command": "mcp-files", "args": ["file:///srv/docs"], "env": {"TOKEN": "${AGENT_API_KEY}"}}}}
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	files := loaded.MCP.Servers["files"]
+	if loaded.LLM.APIKey != "sk-env" || files.Env["TOKEN"] != "sk-env" {
+		t.Errorf("expected the key and environment expanded, got %+v and %+v", loaded.LLM, files)
+	}
+	if files.Args[0] != "file:///srv/docs" || loaded.Agent.SystemPrompt != "Reply with ${ when asked" {
+		t.Errorf("expected the arguments and prompt left alone, got %v and %q", files.Args, loaded.Agent.SystemPrompt)
+	}
+}
@@ -2,50 +2,108 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
 	"github.com/alt-coder/pocketflow-go/llm"
 	"github.com/alt-coder/pocketflow-go/llm/gemini"
 	"github.com/alt-coder/pocketflow-go/llm/openai"
+	"github.com/alt-coder/pocketflow-go/memory"
 	"github.com/alt-coder/pocketflow-go/tools"
 )
 
 func main() {
+	resume := flag.String("resume", "", "ID of a saved session to continue, or \"last\" for the most recent one")
+	trace := flag.String("trace", "", "File to export the reasoning trace to as JSON when the session ends; enables reasoning mode")
+	connector := flag.String("connector", "", "Serve the agent as a telegram, slack or discord bot instead of in the terminal")
+	listen := flag.String("listen", ":8080", "Address the slack and discord connectors receive events on")
+	flag.Parse()
+
 	// Load configuration
 	config, err := loadConfiguration()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 	fmt.Printf("Using %s provider with model %s\n", config.LLM.Provider, config.LLM.Model)
-	// Initialize MCP tool manager
-	mcpManager := tools.NewMCPManager(config.MCP)
+
+	// Start the MCP servers and create the LLM providers
+	ctx := context.Background()
+	agent, err := newAgent(ctx, config)
 	if err != nil {
-		log.Fatalf("Failed to create MCP tool manager: %v", err)
+		log.Fatalf("Failed to set up the agent: %v", err)
 	}
-	defer mcpManager.Close()
+	defer agent.Close()
 
-	// Initialize MCP connections
-	ctx := context.Background()
-	if err := mcpManager.Initialize(ctx); err != nil {
-		log.Fatalf("Failed to initialize MCP tool manager: %v", err)
+	if *connector != "" {
+		if err := runBot(ctx, agent, *connector, *listen); err != nil {
+			log.Fatalf("Bot stopped: %v", err)
+		}
+		return
 	}
-	toolManager := tools.NewToolManager()
-	toolManager.SetMCPManager(mcpManager)
 
-	// Initialize LLM provider based on configuration
-	llmProvider, err := createLLMProvider(ctx, config.LLM)
+	toolManager, err := agent.newToolManager()
+	if err != nil {
+		log.Fatalf("Failed to create tool manager: %v", err)
+	}
+	// All providers count towards the session's budget
+	tracker := llm.NewUsageTracker(config.Agent.Pricing)
+	providers, err := agent.newProviders(tracker)
 	if err != nil {
-		log.Fatalf("Failed to create LLM provider: %v", err)
+		log.Fatalf("Failed to create LLM providers: %v", err)
 	}
-	defer closeLLMProvider(llmProvider)
-	agentState := &AgentState{}
-	workflow := NewToolUsageFlow(toolManager, llmProvider, nil, agentState)
-	workflow.AddSuccessor(workflow, core.ActionSuccess)
+
+	var history memory.Store
+	if config.Agent.HistoryFile != "" {
+		history, err = memory.NewFileStore(config.Agent.HistoryFile)
+		if err != nil {
+			log.Fatalf("Failed to open conversation history: %v", err)
+		}
+	}
+
+	agentState := NewAgentState(history)
+	agentState.Sessions = agent.sessions
+	agentState.SessionID = memory.NewSessionID()
+	agentState.Tracker = tracker
+	if *resume != "" {
+		if err := resumeSession(ctx, agentState, toolManager, *resume); err != nil {
+			log.Fatalf("Failed to resume session: %v", err)
+		}
+	}
+	fmt.Printf("Session %s (continue later with -resume %s)\n", agentState.SessionID, agentState.SessionID)
+
+	// Changes to config.json are picked up between turns without a restart
+	var reloader nodes.Reloader
+	currentConfig := func() *AgentWorkflowConfig { return config }
+	if _, err := os.Stat("config.json"); err == nil {
+		defaultProvider, _ := providers.Get(defaultProviderName)
+		watcher, err := newConfigWatcher("config.json", defaultProvider, toolManager, agent.mcpManager)
+		if err != nil {
+			log.Fatalf("Failed to watch config.json: %v", err)
+		}
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		go watcher.Run(watchCtx)
+		reloader = watcher
+		currentConfig = watcher.Current
+	}
+
+	flow := agent.newFlow(agentState, toolManager, providers,
+		WithReload[*AgentState](reloader),
+		WithReasoning[*AgentState](config.Agent.Reasoning || *trace != ""))
+
+	// The run report keeps the settings the session started with, without
+	// secrets, so a postmortem can tell which settings produced its behavior
+	flow.SetConfigSnapshot(func() any {
+		return runConfig{Config: currentConfig().Redacted(), ChatModel: agent.chatModel, Tools: toolManager.AlwaysAllowedTools()}
+	})
 
 	// Display welcome message
 	fmt.Println("🤖 Agent with Tool Calling Capabilities")
@@ -61,11 +119,88 @@ func main() {
 	fmt.Println("Type your requests below. The agent will ask for approval before using tools.")
 	fmt.Println()
 
-	workflow.Run(&agentState)
+	_, report := flow.RunWithReport(&agentState, nil)
+	if err := saveRunReport(filepath.Join(config.Agent.SessionDir, "runs"), agentState.SessionID, report); err != nil {
+		log.Printf("Failed to save run report: %v", err)
+	}
 
+	if exceeded := agentState.BudgetExceeded; exceeded != nil {
+		fmt.Printf("⛔ %s\n", exceeded.Message)
+	}
+	usage := tracker.Usage()
+	fmt.Printf("Usage: %d LLM calls, %d tokens, $%.4f\n", usage.Calls, usage.TotalTokens(), tracker.Cost())
+	if *trace != "" {
+		if err := agentState.Trace.Export(*trace); err != nil {
+			log.Printf("Failed to export reasoning trace: %v", err)
+		} else {
+			fmt.Printf("Reasoning trace with %d steps written to %s\n", len(agentState.Trace), *trace)
+		}
+	}
 	fmt.Println("Agent session ended.")
 }
 
+// runConfig is the configuration snapshot of a run report
+type runConfig struct {
+	Config    *AgentWorkflowConfig `json:"config"`        // Loaded configuration, without secrets
+	ChatModel string               `json:"chat_model"`    // Model of the chat node after node_providers
+	Tools     []string             `json:"allowed_tools"` // Tools that run without approval, including those allowed in the session
+}
+
+// saveRunReport writes the report of a run to <dir>/<session>-<time>.json
+func saveRunReport(dir, sessionID string, report core.RunReport) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.json", sessionID, time.Now().UTC().Format("20060102T150405Z"))
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
+
+// resumeSession restores a saved session into state and re-applies the tool
+// permissions granted in it; "last" picks the most recently saved session
+func resumeSession(ctx context.Context, state *AgentState, toolManager *tools.ToolManager, id string) error {
+	if id == "last" {
+		ids, err := state.Sessions.ListSessions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no saved sessions")
+		}
+		id = ids[0]
+	}
+
+	session, err := state.Sessions.LoadSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := session.Restore(ctx, state.Memory, state); err != nil {
+		return err
+	}
+	state.SessionID = session.ID
+	if state.Tracker != nil {
+		for model, usage := range state.Usage {
+			state.Tracker.Record(model, usage)
+		}
+	}
+	for _, name := range state.AllowedTools {
+		toolManager.AlwaysAllow(name)
+	}
+
+	// Tool calls that were waiting for approval are not run after a restart
+	if len(state.PendingToolCalls) > 0 {
+		state.SetApprovalResponse(nodes.ApprovalResponse{
+			Decision: nodes.DecisionReject,
+			Feedback: "the session ended before they were approved",
+		})
+	}
+	fmt.Printf("Resumed session %s with %d messages\n", session.ID, len(session.Messages))
+	return nil
+}
+
 // loadConfiguration loads the agent configuration from file or environment
 func loadConfiguration() (*AgentWorkflowConfig, error) {
 	// Try to load from config file first
@@ -89,6 +224,18 @@ func loadConfiguration() (*AgentWorkflowConfig, error) {
 	return config, nil
 }
 
+// Names of the nodes and providers in the provider registry and in the
+// metadata of messages
+const (
+	chatNodeName        = "chat"
+	summarizeNodeName   = "summarize"
+	cleanupNodeName     = "cleanup"
+	toolNodeName        = "tools"
+	approvalNodeName    = "approval"
+	defaultProviderName = "default"
+	summaryProviderName = "summary_model"
+)
+
 // createLLMProvider creates the appropriate LLM provider based on configuration
 func createLLMProvider(ctx context.Context, config *LLMConfig) (llm.LLMProvider, error) {
 	switch strings.ToLower(config.Provider) {
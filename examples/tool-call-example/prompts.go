@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// embeddedPrompts holds the default prompt templates shipped with the agent
+//
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// defaultPrompts is used by chat nodes created without WithPrompts
+var defaultPrompts = mustLoadPrompts(nil)
+
+// loadPrompts loads the embedded prompts, then the overrides in config.PromptDir,
+// and pins the versions listed in config.PromptVersions
+func loadPrompts(config *AgentConfig) (*prompt.Store, error) {
+	store := prompt.NewStore()
+	if err := store.LoadFS(embeddedPrompts, "prompts"); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return store, nil
+	}
+
+	if config.PromptDir != "" {
+		if err := store.LoadDir(config.PromptDir); err != nil {
+			return nil, fmt.Errorf("failed to load prompt overrides: %w", err)
+		}
+	}
+	for name, version := range config.PromptVersions {
+		if err := store.Use(name, version); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// mustLoadPrompts is loadPrompts for the embedded defaults, which are known to be valid
+func mustLoadPrompts(config *AgentConfig) *prompt.Store {
+	store, err := loadPrompts(config)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
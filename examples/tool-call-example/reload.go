@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/hotreload"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// newConfigWatcher watches the config file and applies the settings that can
+// change while the agent runs: the model, the temperature, the tools allowed
+// without approval and the MCP servers. Other changes are reported as needing
+// a restart.
+func newConfigWatcher(path string, provider llm.LLMProvider, toolManager *tools.ToolManager, mcpManager *tools.MCPManager) (*hotreload.Watcher[*AgentWorkflowConfig], error) {
+	config := hotreload.DefaultConfig()
+	config.OnEvent = func(event hotreload.Event) {
+		fmt.Printf("\n⚙️  %s\n", event)
+	}
+	watcher, err := hotreload.NewWatcher(path, LoadConfig, config)
+	if err != nil {
+		return nil, err
+	}
+
+	setProvider := func(ctx context.Context, old, new *AgentWorkflowConfig, changes []hotreload.Change) error {
+		return provider.SetConfig(map[string]any{"model": new.LLM.Model, "temperature": new.LLM.Temperature})
+	}
+	watcher.Handle("llm.model", setProvider)
+	watcher.Handle("llm.temperature", setProvider)
+
+	watcher.Handle("agent.allowed_tools", func(ctx context.Context, old, new *AgentWorkflowConfig, changes []hotreload.Change) error {
+		for _, name := range old.Agent.AllowedTools {
+			toolManager.Disallow(name)
+		}
+		for _, name := range new.Agent.AllowedTools {
+			toolManager.AlwaysAllow(name)
+		}
+		return nil
+	})
+
+	watcher.Handle("mcp.servers", func(ctx context.Context, old, new *AgentWorkflowConfig, changes []hotreload.Change) error {
+		// Restart each server whose settings changed; a change of "mcp.servers"
+		// itself means the list went from or to empty
+		var names []string
+		seen := make(map[string]bool)
+		for _, change := range changes {
+			if change.Path == "mcp.servers" {
+				for name := range old.MCP.Servers {
+					names = append(names, name)
+				}
+				for name := range new.MCP.Servers {
+					names = append(names, name)
+				}
+				continue
+			}
+			name, _, _ := strings.Cut(strings.TrimPrefix(change.Path, "mcp.servers."), ".")
+			names = append(names, name)
+		}
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if err := mcpManager.RemoveServer(name); err != nil {
+				return fmt.Errorf("failed to stop MCP server %s: %w", name, err)
+			}
+			if server, ok := new.MCP.Servers[name]; ok {
+				if err := mcpManager.AddServer(ctx, name, server); err != nil {
+					return fmt.Errorf("failed to start MCP server %s: %w", name, err)
+				}
+			}
+		}
+		return nil
+	})
+	return watcher, nil
+}
@@ -1,35 +1,226 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/alt-coder/pocketflow-go/core/nodes"
 	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/tools"
 )
 
 // AgentState represents the enhanced state for multi-step tool calling with approval
 type AgentState struct {
-	// Two separate message histories
-	CleanedMessages       []llm.Message          `json:"cleaned_messages"`        // Cleaned conversation history for planner
+	Memory           memory.Store           `json:"-"`                  // Conversation history for the planner
+	PendingToolCalls []llm.ToolCalls        `json:"pending_tool_calls"` // Tool calls waiting for approval or execution
+	Approval         nodes.ApprovalResponse `json:"approval"`           // Last answer from the approval node
+	ToolHistory      []ToolInteraction      `json:"tool_history"`       // Tool calls made in this session, approved or not
+	AllowedTools     []string               `json:"allowed_tools"`      // Tools the user allowed for the rest of the session
+	ToolSteps        int                    `json:"tool_steps"`         // Tool execution steps run in this session
+	Turn             int                    `json:"turn"`               // Turns started in this session, counting from 1
+	Usage            map[string]llm.Usage   `json:"usage,omitempty"`    // LLM usage of this session by model, saved from Tracker
+	Trace            ReasoningTrace         `json:"trace,omitempty"`    // Planner thoughts, tool calls and results in reasoning mode
+
+	Tracker        *llm.UsageTracker     `json:"-"` // Records the LLM usage of the session's providers; nil disables cost and token budgets
+	BudgetExceeded *nodes.BudgetExceeded `json:"-"` // Limit the session reached, if any
+
+	SessionID string              `json:"-"` // ID the session is saved under
+	Sessions  memory.SessionStore `json:"-"` // Where the session is saved; nil disables saving
+
+	toolCalls tools.CallCache // Results of the tool calls of the current turn
 }
 
-func (s AgentState) GetConversation(_ string) *[]llm.Message {
-	return &s.CleanedMessages
+// AddToolHistory records tool interactions
+func (s *AgentState) AddToolHistory(interactions ...ToolInteraction) {
+	s.ToolHistory = append(s.ToolHistory, interactions...)
 }
 
-type StateInterface interface {
-	GetConversation(key string) *[]llm.Message
-	AddMessage(msg llm.Message)
+// ToolCallCache returns the results of the tool calls of the current turn
+func (s *AgentState) ToolCallCache() *tools.CallCache {
+	return &s.toolCalls
 }
 
-// NewAgentState creates a new agent state with default values
-func NewAgentState() *AgentState {
-	return &AgentState{
-		CleanedMessages:       make([]llm.Message, 0),
+// StartTurn starts a new turn on new user input; tools are called again for
+// fresh results
+func (s *AgentState) StartTurn() {
+	s.Turn++
+	s.toolCalls.Reset()
+}
 
+// WithProvenance returns the message marked with the current turn and the node
+// that produced it
+func (s *AgentState) WithProvenance(message llm.Message, node string) llm.Message {
+	return message.WithMeta(llm.MetaTurnID, strconv.Itoa(s.Turn)).WithMeta(llm.MetaNode, node)
+}
+
+// SetAllowedTools records the tools that no longer need approval
+func (s *AgentState) SetAllowedTools(names []string) {
+	s.AllowedTools = names
+}
+
+// AddReasoningStep records a planner step in the reasoning trace
+func (s *AgentState) AddReasoningStep(step ReasoningStep) {
+	s.Trace = append(s.Trace, step)
+}
+
+// ObserveToolResults records tool results in the reasoning trace
+func (s *AgentState) ObserveToolResults(results []llm.ToolResults) {
+	s.Trace.Observe(results)
+}
+
+// AddToolStep counts a tool execution step against the session's budget
+func (s *AgentState) AddToolStep() {
+	s.ToolSteps++
+}
+
+// GetBudgetUsage returns the tokens, cost and tool steps the session has spent
+func (s *AgentState) GetBudgetUsage() nodes.BudgetUsage {
+	usage := nodes.BudgetUsage{ToolSteps: s.ToolSteps}
+	if s.Tracker != nil {
+		usage.Tokens = s.Tracker.Usage().TotalTokens()
+		usage.Cost = s.Tracker.Cost()
+	}
+	return usage
+}
+
+// SetBudgetExceeded records the limit that ended the session
+func (s *AgentState) SetBudgetExceeded(exceeded nodes.BudgetExceeded) {
+	s.BudgetExceeded = &exceeded
+}
+
+// SaveSession saves the conversation, tool history and permissions so the
+// session can be resumed after a restart
+func (s *AgentState) SaveSession(ctx context.Context) error {
+	if s.Sessions == nil {
+		return nil
+	}
+	if s.Tracker != nil {
+		s.Usage = s.Tracker.ByModel()
 	}
+	session, err := memory.NewSession(ctx, s.SessionID, s.Memory, s)
+	if err != nil {
+		return err
+	}
+	return s.Sessions.SaveSession(ctx, session)
+}
+
+// GetMemory returns the conversation history used by the node with the given key
+func (s *AgentState) GetMemory(_ string) memory.Store {
+	return s.Memory
 }
 
-// AddMessage adds a message to both actual and cleaned conversation histories
-func (s *AgentState) AddMessage(msg llm.Message) {
-	s.CleanedMessages = append(s.CleanedMessages, msg)
+// SetPendingToolCalls records the tool calls planned by the chat node
+func (s *AgentState) SetPendingToolCalls(calls []llm.ToolCalls) {
+	s.PendingToolCalls = calls
+	s.Approval = nodes.ApprovalResponse{}
 }
 
+// TakeApprovedToolCalls returns the pending tool calls and clears them; always is
+// set when the approver asked not to be asked again for these tools
+func (s *AgentState) TakeApprovedToolCalls() (calls []llm.ToolCalls, always bool) {
+	calls, always = s.PendingToolCalls, s.Approval.Remember
+	s.PendingToolCalls = nil
+	s.Approval = nodes.ApprovalResponse{}
+	return calls, always
+}
+
+// GetApprovalRequest describes the pending tool calls for the approval node
+func (s *AgentState) GetApprovalRequest() (nodes.ApprovalRequest, bool) {
+	if len(s.PendingToolCalls) == 0 {
+		return nodes.ApprovalRequest{}, false
+	}
+
+	var details strings.Builder
+	names := make(map[string]struct{})
+	for _, call := range s.PendingToolCalls {
+		fmt.Fprintf(&details, "- %s %v\n", call.ToolName, call.ToolArgs)
+		names[call.ToolName] = struct{}{}
+	}
+
+	request := nodes.ApprovalRequest{
+		Kind:    "tool_calls",
+		Title:   fmt.Sprintf("The assistant wants to run %d tool call(s):", len(s.PendingToolCalls)),
+		Details: strings.TrimSuffix(details.String(), "\n"),
+		Data:    s.PendingToolCalls,
+	}
+	if len(names) == 1 {
+		request.Kind = s.PendingToolCalls[0].ToolName
+	}
+	return request, true
+}
+
+// SetApprovalResponse stores the approver's answer. When the tool calls will not
+// run, they are dropped and the answer is added to the conversation for the planner.
+func (s *AgentState) SetApprovalResponse(response nodes.ApprovalResponse) {
+	s.Approval = response
+	if response.Decision == nodes.DecisionApprove {
+		return
+	}
+
+	var message llm.Message
+	switch response.Decision {
+	case nodes.DecisionFeedback:
+		// Treat the approver's answer as a new user message
+		message = llm.Message{Role: llm.RoleUser, Content: response.Feedback}
+	default:
+		reason := "The user denied this tool call"
+		if response.Feedback != "" {
+			reason += ": " + response.Feedback
+		}
+		results := make([]llm.ToolResults, 0, len(s.PendingToolCalls))
+		for _, call := range s.PendingToolCalls {
+			results = append(results, llm.ErrorResult(call.Id, llm.ToolErrorPermissionDenied, reason))
+			s.AddToolHistory(ToolInteraction{
+				ToolCall:   call,
+				ToolResult: ToolResult{ToolCallID: call.Id, IsError: true, Code: llm.ToolErrorPermissionDenied, Error: reason},
+				Timestamp:  time.Now(),
+			})
+		}
+		message = llm.Message{
+			Role:        llm.RoleUser,
+			Content:     reason + ".",
+			ToolCalls:   s.PendingToolCalls,
+			ToolResults: results,
+		}.WithTags(llm.TagHidden)
+		s.ObserveToolResults(results)
+	}
+	s.PendingToolCalls = nil
+
+	message = s.WithProvenance(message, approvalNodeName)
+	if err := s.Memory.Append(context.Background(), message); err != nil {
+		log.Printf("Error saving conversation history: %v", err)
+	}
+}
+
+type StateInterface interface {
+	nodes.ApprovalStateInterface
+	nodes.BudgetStateInterface
+	GetMemory(key string) memory.Store
+	SetPendingToolCalls(calls []llm.ToolCalls)
+	TakeApprovedToolCalls() (calls []llm.ToolCalls, always bool)
+	AddToolHistory(interactions ...ToolInteraction)
+	ToolCallCache() *tools.CallCache
+	StartTurn()
+	WithProvenance(message llm.Message, node string) llm.Message
+	SetAllowedTools(names []string)
+	AddToolStep()
+	AddReasoningStep(step ReasoningStep)
+	ObserveToolResults(results []llm.ToolResults)
+	SaveSession(ctx context.Context) error
+}
+
+// NewAgentState creates a new agent state keeping history in the given store,
+// or in memory when store is nil
+func NewAgentState(store memory.Store) *AgentState {
+	if store == nil {
+		store = memory.NewInMemoryStore()
+	}
+	return &AgentState{
+		Memory: store,
+	}
+}
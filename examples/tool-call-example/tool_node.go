@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// ToolExecutionNode runs the tool calls approved by the user and adds their results
+// to the conversation. The calls of one turn are independent, since the model
+// planned them without seeing each other's results, so up to
+// ToolExecutionConfig.MaxConcurrency of them run at the same time.
+type ToolExecutionNode[T StateInterface] struct {
+	toolManager *tools.ToolManager
+	key         string
+	config      *ToolExecutionConfig
+}
+
+// toolTask is a tool call together with the batch of calls it was approved in
+type toolTask struct {
+	call  llm.ToolCalls
+	batch *toolBatch
+}
+
+// toolBatch is shared by the calls of one run; in FailureAbort mode the first
+// failure cancels it, so calls still running are interrupted and the rest are skipped
+type toolBatch struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *tools.CallCache // Results of the turn's earlier calls; nil runs every call
+	mu     sync.Mutex
+	failed string // Name of the tool whose failure aborted the batch
+}
+
+// duplicateCallNotice is added to the result of a call that repeated an
+// earlier call of the turn
+const duplicateCallNotice = "Note: this call repeats an earlier call with the same arguments, so its earlier result is shown again. Use it instead of calling the tool again."
+
+// abort cancels the batch because tool failed, unless it is already aborted
+func (b *toolBatch) abort(tool string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed == "" {
+		b.failed = tool
+		b.cancel()
+	}
+}
+
+// aborted returns the tool whose failure aborted the batch, if any
+func (b *toolBatch) aborted() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failed, b.failed != ""
+}
+
+// NewToolExecutionNode creates a tool execution node; a nil config uses
+// DefaultToolExecutionConfig. Wrap it with core.NewNode using
+// config.MaxConcurrency routines to run calls concurrently.
+func NewToolExecutionNode[T StateInterface](toolManager *tools.ToolManager, key string, config *ToolExecutionConfig) *ToolExecutionNode[T] {
+	if config == nil {
+		config = DefaultToolExecutionConfig()
+	}
+	return &ToolExecutionNode[T]{
+		toolManager: toolManager,
+		key:         key,
+		config:      config,
+	}
+}
+
+// Prep takes the approved tool calls from state; each call is executed separately
+func (n *ToolExecutionNode[T]) Prep(state *T) []toolTask {
+	calls, always := (*state).TakeApprovedToolCalls()
+	if always {
+		for _, call := range calls {
+			n.toolManager.AlwaysAllow(call.ToolName)
+		}
+		(*state).SetAllowedTools(n.toolManager.AlwaysAllowedTools())
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+
+	batch := &toolBatch{}
+	batch.ctx, batch.cancel = context.WithCancel(context.Background())
+	if n.config.Deduplicate {
+		batch.cache = (*state).ToolCallCache()
+	}
+	tasks := make([]toolTask, len(calls))
+	for i, call := range calls {
+		tasks[i] = toolTask{call: call, batch: batch}
+	}
+	return tasks
+}
+
+// Exec runs a single tool call, unless it repeats an earlier call of the turn,
+// in which case the earlier result is returned with a notice
+func (n *ToolExecutionNode[T]) Exec(task toolTask) (llm.ToolResults, error) {
+	if task.batch.cache == nil || slices.Contains(n.config.RepeatableTools, task.call.ToolName) {
+		return n.run(task), nil
+	}
+	result, repeated := task.batch.cache.Do(task.call, func() llm.ToolResults { return n.run(task) })
+	if repeated {
+		log.Printf("Tool %s was called again with the same arguments, reusing its result", task.call.ToolName)
+		result.Content = duplicateCallNotice + "\n" + result.Content
+	}
+	return result, nil
+}
+
+// run runs a tool call, retrying it in FailureRetry mode. Failures are
+// reported to the LLM as error results.
+func (n *ToolExecutionNode[T]) run(task toolTask) llm.ToolResults {
+	call, batch := task.call, task.batch
+	attempts := 1
+	if n.config.FailureMode == FailureRetry {
+		attempts += n.config.MaxRetries
+	}
+
+	var result llm.ToolResults
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if tool, ok := batch.aborted(); ok {
+			return llm.ToolResults{
+				Id:      call.Id,
+				IsError: true,
+				Code:    llm.ToolErrorUnavailable,
+				Error:   fmt.Sprintf("Not run: the batch was aborted after %s failed", tool),
+			}
+		}
+		if attempt > 1 {
+			time.Sleep(n.config.RetryDelay.Std() * time.Duration(attempt-1))
+		}
+
+		var err error
+		result, err = n.execute(batch.ctx, call)
+		if err != nil {
+			log.Printf("Error executing tool %s: %v", call.ToolName, err)
+			result = llm.ToolResults{
+				Id:      call.Id,
+				Content: result.Content,
+				IsError: true,
+				Code:    llm.ToolErrorCodeOf(err, llm.ToolErrorFailed),
+				Error:   fmt.Sprintf("Tool execution failed: %v", err),
+			}
+		}
+		if !result.IsError {
+			return result
+		}
+	}
+
+	if n.config.FailureMode == FailureAbort {
+		batch.abort(call.ToolName)
+	}
+	if attempts > 1 {
+		result.Error = fmt.Sprintf("%s (after %d attempts)", result.Error, attempts)
+	}
+	return result
+}
+
+// execute runs one attempt of a call within the configured tool timeout
+func (n *ToolExecutionNode[T]) execute(ctx context.Context, call llm.ToolCalls) (llm.ToolResults, error) {
+	if n.config.ToolTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.ToolTimeout.Std())
+		defer cancel()
+	}
+	return n.toolManager.ExecuteTool(ctx, call)
+}
+
+// ExecFallback is not reached because Exec reports failures as results
+func (n *ToolExecutionNode[T]) ExecFallback(err error) llm.ToolResults {
+	return llm.ErrorResult("", llm.ToolErrorCodeOf(err, llm.ToolErrorFailed), fmt.Sprintf("Tool execution failed: %v", err))
+}
+
+// Post adds a message with all tool results to the conversation
+func (n *ToolExecutionNode[T]) Post(state *T, tasks []toolTask, results ...llm.ToolResults) core.Action {
+	if len(results) == 0 {
+		return core.ActionSuccess
+	}
+	tasks[0].batch.cancel()
+	(*state).AddToolStep()
+
+	calls := make([]llm.ToolCalls, len(tasks))
+	for i, task := range tasks {
+		calls[i] = task.call
+	}
+
+	var content strings.Builder
+	for i, result := range results {
+		fmt.Fprintf(&content, "## Tool %s result:\n%s\n", calls[i].ToolName, result.Content)
+		if result.IsError {
+			fmt.Fprintf(&content, "Error: %s\n", result.Error)
+		}
+		(*state).AddToolHistory(ToolInteraction{
+			ToolCall: calls[i],
+			ToolResult: ToolResult{
+				ToolCallID: calls[i].Id,
+				Content:    result.Content,
+				IsError:    result.IsError,
+				Code:       result.Code,
+				Error:      result.Error,
+			},
+			Timestamp: time.Now(),
+			Approved:  true,
+		})
+	}
+	(*state).ObserveToolResults(results)
+
+	message := llm.Message{
+		Role:        llm.RoleUser,
+		Content:     content.String(),
+		ToolCalls:   calls,
+		ToolResults: results,
+	}
+
+	// Handle media from tool results
+	for _, result := range results {
+		if len(result.Media) > 0 {
+			message.Media = result.Media
+			message.MimeType = result.MetaData.ContentType
+			break // Only handle first media result
+		}
+	}
+
+	message = (*state).WithProvenance(message.WithTags(llm.TagHidden), toolNodeName)
+	if err := (*state).GetMemory(n.key).Append(context.Background(), message); err != nil {
+		log.Printf("Error saving conversation history: %v", err)
+	}
+	if err := (*state).SaveSession(context.Background()); err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
+	return core.ActionSuccess
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// testToolManager provides a "slow" tool that waits until it is cancelled or
+// 200ms passed, a "fail" tool failing after 20ms and a "flaky" tool failing its
+// first two calls
+func testToolManager(t *testing.T, running, peak *int32) *tools.ToolManager {
+	manager := tools.NewToolManager()
+	var flakyCalls int32
+	var mu sync.Mutex
+	add := func(name string, handler tools.ToolHandler) {
+		if err := manager.AddLocalToolLegacy(tools.LocalTool{Name: name, Handler: handler}); err != nil {
+			t.Fatalf("AddLocalToolLegacy(%s) failed: %v", name, err)
+		}
+	}
+	add("slow", func(ctx context.Context, args map[string]any) (string, error) {
+		mu.Lock()
+		if now := atomic.AddInt32(running, 1); now > *peak {
+			*peak = now
+		}
+		mu.Unlock()
+		defer atomic.AddInt32(running, -1)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			return "done", nil
+		}
+	})
+	add("fail", func(ctx context.Context, args map[string]any) (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "", errors.New("boom")
+	})
+	add("flaky", func(ctx context.Context, args map[string]any) (string, error) {
+		if atomic.AddInt32(&flakyCalls, 1) <= 2 {
+			return "", errors.New("temporarily unavailable")
+		}
+		return "recovered", nil
+	})
+	return manager
+}
+
+func TestToolExecutionNode(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *ToolExecutionConfig
+		tools       []string
+		expected    []string // Content, or "error: <substring>" for error results
+		concurrent  int32
+		maxDuration time.Duration
+	}{
+		{
+			"concurrent calls",
+			&ToolExecutionConfig{MaxConcurrency: 3, FailureMode: FailureContinue},
+			[]string{"slow", "slow", "slow"},
+			[]string{"done", "done", "done"},
+			3, 450 * time.Millisecond,
+		},
+		{
+			"continue after failure",
+			&ToolExecutionConfig{MaxConcurrency: 1, FailureMode: FailureContinue},
+			[]string{"fail", "slow"},
+			[]string{"error: boom", "done"},
+			1, time.Second,
+		},
+		{
+			"abort skips remaining calls",
+			&ToolExecutionConfig{MaxConcurrency: 1, FailureMode: FailureAbort},
+			[]string{"fail", "slow"},
+			[]string{"error: boom", "error: aborted after fail failed"},
+			0, time.Second,
+		},
+		{
+			"abort interrupts running calls",
+			&ToolExecutionConfig{MaxConcurrency: 2, FailureMode: FailureAbort},
+			[]string{"slow", "fail"},
+			[]string{"error: context canceled", "error: boom"},
+			1, 150 * time.Millisecond,
+		},
+		{
+			"retry recovers",
+			&ToolExecutionConfig{MaxConcurrency: 1, FailureMode: FailureRetry, MaxRetries: 2},
+			[]string{"flaky"},
+			[]string{"recovered"},
+			0, time.Second,
+		},
+		{
+			"retry gives up",
+			&ToolExecutionConfig{MaxConcurrency: 1, FailureMode: FailureRetry, MaxRetries: 1},
+			[]string{"fail"},
+			[]string{"error: after 2 attempts"},
+			0, time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak int32
+			manager := testToolManager(t, &running, &peak)
+			node := core.NewNode(NewToolExecutionNode[*AgentState](manager, "chat", tt.config), 0, tt.config.MaxConcurrency)
+
+			state := NewAgentState(nil)
+			for i, name := range tt.tools {
+				state.PendingToolCalls = append(state.PendingToolCalls, llm.ToolCalls{Id: string(rune('a' + i)), ToolName: name})
+			}
+			state.Approval = nodes.ApprovalResponse{Decision: nodes.DecisionApprove}
+
+			start := time.Now()
+			if action := node.Run(&state); action != core.ActionSuccess {
+				t.Fatalf("Run() = %q", action)
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxDuration {
+				t.Errorf("Run() took %v, expected at most %v", elapsed, tt.maxDuration)
+			}
+			if peak != tt.concurrent {
+				t.Errorf("%d calls ran at the same time, expected %d", peak, tt.concurrent)
+			}
+
+			messages, err := state.Memory.Messages(context.Background())
+			if err != nil || len(messages) != 1 {
+				t.Fatalf("expected one tool result message, got %d (%v)", len(messages), err)
+			}
+			results := messages[0].ToolResults
+			if len(results) != len(tt.expected) {
+				t.Fatalf("got %d results, expected %d", len(results), len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				result := results[i]
+				if result.Id != string(rune('a'+i)) {
+					t.Errorf("result %d has id %q", i, result.Id)
+				}
+				if substring, ok := strings.CutPrefix(expected, "error: "); ok {
+					if !result.IsError || !strings.Contains(result.Error, substring) {
+						t.Errorf("result %d = %+v, expected an error containing %q", i, result, substring)
+					}
+				} else if result.IsError || result.Content != expected {
+					t.Errorf("result %d = %+v, expected %q", i, result, expected)
+				}
+			}
+			if state.ToolSteps != 1 {
+				t.Errorf("ToolSteps = %d, expected 1", state.ToolSteps)
+			}
+		})
+	}
+}
+
+func TestToolExecutionNode_Deduplicate(t *testing.T) {
+	var running, peak int32
+	manager := testToolManager(t, &running, &peak)
+	config := &ToolExecutionConfig{MaxConcurrency: 2, FailureMode: FailureContinue, Deduplicate: true}
+	node := core.NewNode(NewToolExecutionNode[*AgentState](manager, "chat", config), 0, config.MaxConcurrency)
+	state := NewAgentState(nil)
+
+	run := func(calls ...llm.ToolCalls) []llm.ToolResults {
+		state.PendingToolCalls = calls
+		state.Approval = nodes.ApprovalResponse{Decision: nodes.DecisionApprove}
+		if action := node.Run(&state); action != core.ActionSuccess {
+			t.Fatalf("Run() = %q", action)
+		}
+		messages, err := state.Memory.Messages(context.Background())
+		if err != nil || len(messages) == 0 {
+			t.Fatalf("expected a tool result message (%v)", err)
+		}
+		return messages[len(messages)-1].ToolResults
+	}
+	// The same call twice in one batch runs once
+	results := run(llm.ToolCalls{Id: "a", ToolName: "slow"}, llm.ToolCalls{Id: "b", ToolName: "slow"})
+	if peak != 1 {
+		t.Errorf("%d calls ran, expected 1", peak)
+	}
+	repeats := 0
+	for _, result := range results {
+		if strings.HasPrefix(result.Content, duplicateCallNotice) {
+			repeats++
+		}
+	}
+	if repeats != 1 || results[0].Id != "a" || results[1].Id != "b" {
+		t.Errorf("results = %+v, expected one repeated result", results)
+	}
+
+	// A later step of the turn reuses the result, and a new turn runs the call again
+	start := time.Now()
+	if results := run(llm.ToolCalls{Id: "c", ToolName: "slow"}); !strings.HasPrefix(results[0].Content, duplicateCallNotice) || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("results = %+v, expected the earlier result", results)
+	}
+	state.StartTurn()
+	if results := run(llm.ToolCalls{Id: "d", ToolName: "slow"}); results[0].Content != "done" {
+		t.Errorf("results = %+v after a new turn, expected the call to run", results)
+	}
+	messages, _ := state.Memory.Messages(context.Background())
+	if last := messages[len(messages)-1]; last.Meta(llm.MetaTurnID) != "1" || last.Meta(llm.MetaNode) != toolNodeName || !last.HasTag(llm.TagHidden) {
+		t.Errorf("tool result provenance = %v, tags %v", last.Metadata, last.Tags)
+	}
+}
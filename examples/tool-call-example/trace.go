@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ReasoningStep is one thought/action/observation step of the planner in
+// reasoning mode. The thought explains the actions; their observations are
+// filled in once the tools ran. A step without actions ends with an answer.
+type ReasoningStep struct {
+	Thought   string            `json:"thought"`
+	Actions   []ReasoningAction `json:"actions,omitempty"`
+	Answer    string            `json:"answer,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// ReasoningAction is a tool call of a reasoning step and what it returned
+type ReasoningAction struct {
+	ToolCallID  string            `json:"tool_call_id"`
+	Tool        string            `json:"tool"`
+	Args        map[string]any    `json:"args,omitempty"`
+	Observation string            `json:"observation,omitempty"`
+	IsError     bool              `json:"is_error,omitempty"`
+	ErrorCode   llm.ToolErrorCode `json:"error_code,omitempty"`
+	Observed    bool              `json:"observed"` // Whether the tool result arrived; false while the call is pending
+}
+
+// ReasoningTrace is the reasoning of a session, oldest step first
+type ReasoningTrace []ReasoningStep
+
+// NewReasoningStep creates a step from a planner thought and the tool calls or
+// answer that followed it
+func NewReasoningStep(thought string, calls []llm.ToolCalls, answer string) ReasoningStep {
+	step := ReasoningStep{Thought: thought, Answer: answer, Timestamp: time.Now()}
+	for _, call := range calls {
+		step.Actions = append(step.Actions, ReasoningAction{ToolCallID: call.Id, Tool: call.ToolName, Args: call.ToolArgs})
+	}
+	return step
+}
+
+// Observe records tool results as the observations of the actions that made them
+func (t ReasoningTrace) Observe(results []llm.ToolResults) {
+	for _, result := range results {
+		for i := len(t) - 1; i >= 0; i-- {
+			if t[i].observe(result) {
+				break
+			}
+		}
+	}
+}
+
+// observe records result on the matching action of the step
+func (s *ReasoningStep) observe(result llm.ToolResults) bool {
+	for i := range s.Actions {
+		action := &s.Actions[i]
+		if action.ToolCallID != result.Id || action.Observed {
+			continue
+		}
+		action.Observation, action.IsError, action.Observed = result.Content, result.IsError, true
+		if result.IsError {
+			action.Observation, action.ErrorCode = result.Error, result.Code
+		}
+		return true
+	}
+	return false
+}
+
+// Export writes the trace to filename as indented JSON
+func (t ReasoningTrace) Export(filename string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode reasoning trace: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write reasoning trace: %w", err)
+	}
+	return nil
+}
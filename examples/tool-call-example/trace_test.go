@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestReasoningTrace(t *testing.T) {
+	var running, peak int32
+	manager := testToolManager(t, &running, &peak)
+	chat := NewChatNode[*AgentState](llm.NewMockProvider("mock"), nil)
+	chat.toolManager = manager
+	chat.key = "chat"
+	if prompt := chat.buildSystemPromptWithTools(""); strings.Contains(prompt, "thought:") {
+		t.Error("the planning prompt should not ask for a thought by default")
+	}
+	WithReasoning[*AgentState](true)(chat)
+
+	if prompt := chat.buildSystemPromptWithTools(""); !strings.Contains(prompt, "thought:") {
+		t.Error("the planning prompt should ask for a thought in reasoning mode")
+	}
+	state := NewAgentState(nil)
+
+	// A tool step, its observation, then the answer
+	plan := "thought: the user wants a slow result\nintent: run it\nresponse: \"\"\ntool_calls: [slow, fail]\ntool_args: [{}, {}]"
+	if action := chat.Post(&state, nil, llm.Message{Role: llm.RoleAssistant, Content: plan}); action != ActionRequestApproval {
+		t.Fatalf("Post() = %q, expected %q", action, ActionRequestApproval)
+	}
+	state.Approval = nodes.ApprovalResponse{Decision: nodes.DecisionApprove}
+	execute := core.NewNode(NewToolExecutionNode[*AgentState](manager, "chat", nil), 0, 2)
+	execute.Run(&state)
+
+	answer := "thought: slow returned done\nintent: answer\nresponse: It is done.\ntool_calls: []\ntool_args: []"
+	chat.Post(&state, nil, llm.Message{Role: llm.RoleAssistant, Content: answer})
+
+	if len(state.Trace) != 2 {
+		t.Fatalf("trace has %d steps, expected 2: %+v", len(state.Trace), state.Trace)
+	}
+	step := state.Trace[0]
+	if step.Thought != "the user wants a slow result" || len(step.Actions) != 2 {
+		t.Fatalf("first step = %+v", step)
+	}
+	if slow := step.Actions[0]; slow.Tool != "slow" || !slow.Observed || slow.Observation != "done" {
+		t.Errorf("slow action = %+v", slow)
+	}
+	if fail := step.Actions[1]; !fail.Observed || !fail.IsError || !strings.Contains(fail.Observation, "boom") {
+		t.Errorf("fail action = %+v", fail)
+	}
+	if final := state.Trace[1]; final.Thought != "slow returned done" || final.Answer != "It is done." || len(final.Actions) != 0 {
+		t.Errorf("final step = %+v", final)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
 	"github.com/alt-coder/pocketflow-go/llm"
 )
 
@@ -14,21 +15,18 @@ import (
 // Action definitions for the agent workflow
 const (
 	// Flow control actions
-	ActionPlan            core.Action = "plan"             // UserInput -> Planning
-	ActionSummarize       core.Action = "summarize"        // UserInput -> Summarizer (context overflow)
-	ActionRequestApproval core.Action = "request_approval" // Planning -> ApprovalNode (tool calls detected)
-	ActionApprove         core.Action = "approve"          // ApprovalNode -> ToolExecution ("y" or "a")
-	ActionReject          core.Action = "reject"           // ApprovalNode -> Planning ("r")
-	ActionAppend          core.Action = "append"           // ApprovalNode -> Planning (other input)
-	ActionCleanup         core.Action = "cleanup"          // ToolExecution -> ToolResultCleanupNode (tools executed)
-	ActionContinue        core.Action = "continue"         // Planning -> UserInput (final response) OR ToolResultCleanupNode -> Planning (cleanup complete)
+	ActionPlan            core.Action = "plan"               // UserInput -> Planning
+	ActionSummarize       core.Action = "summarize"          // UserInput -> Summarizer (context overflow)
+	ActionRequestApproval core.Action = "request_approval"   // Planning -> ApprovalNode (tool calls detected)
+	ActionApprove                     = nodes.ActionApprove  // Planning or ApprovalNode -> ToolExecution ("y" or "a")
+	ActionReject                      = nodes.ActionReject   // ApprovalNode -> Planning ("n")
+	ActionFeedback                    = nodes.ActionFeedback // ApprovalNode -> Planning (other input)
+	ActionCleanup         core.Action = "cleanup"            // ToolExecution -> ToolResultCleanupNode (tools executed)
+	ActionContinue        core.Action = "continue"           // Planning -> UserInput (final response) OR ToolResultCleanupNode -> Planning (cleanup complete)
 
 	// Terminal actions
 	ActionExit    = "exit"    // User requested exit
 	ActionFailure = "failure" // Unrecoverable error
-
-	// Retry actions
-	ActionRetry = "retry" // Retry current operation
 )
 
 // ToolCall represents a tool call made by the LLM (matches llm.ToolCalls structure)
@@ -40,10 +38,11 @@ type ToolCall struct {
 
 // ToolResult represents the result of a tool execution
 type ToolResult struct {
-	ToolCallID string `json:"tool_call_id"` // References the tool call ID
-	Content    string `json:"content"`      // Tool execution result
-	IsError    bool   `json:"is_error"`     // Whether the result is an error
-	Error      string `json:"error"`        // Error message if IsError is true
+	ToolCallID string            `json:"tool_call_id"`   // References the tool call ID
+	Content    string            `json:"content"`        // Tool execution result
+	IsError    bool              `json:"is_error"`       // Whether the result is an error
+	Code       llm.ToolErrorCode `json:"code,omitempty"` // Kind of error if IsError is true
+	Error      string            `json:"error"`          // Error message if IsError is true
 }
 
 // ToolSchema represents the schema of an available tool
@@ -71,9 +70,19 @@ const (
 	PermissionAlwaysAsk Permission = "always_ask"
 )
 
+// FailureMode decides what happens to a batch of tool calls when one fails
+type FailureMode string
+
+const (
+	FailureContinue FailureMode = "continue" // Run the other calls and report the failure
+	FailureAbort    FailureMode = "abort"    // Interrupt the other calls and skip those not started
+	FailureRetry    FailureMode = "retry"    // Retry the failed call before reporting it
+)
+
 // LLMResponse represents the structured YAML response from the planning LLM
 type LLMResponse struct {
 	Intent    string                   `yaml:"intent"`
+	Thought   string                   `yaml:"thought"` // Reasoning behind the tool calls; only asked for in reasoning mode
 	Response  string                   `yaml:"response"`
 	ToolCalls []string                 `yaml:"tool_calls"`
 	ToolArgs  []map[string]interface{} `yaml:"tool_args"`
@@ -87,11 +96,12 @@ type UserInput struct {
 // ParsedResult represents the result of planning
 type ParsedResult struct {
 	Response     string          `json:"response"`
+	Thought      string          `json:"thought,omitempty"`
 	LLMToolCalls []llm.ToolCalls `json:"llm_tool_calls"` // LLM format for message
 	Error        error           `json:"error,omitempty"`
 }
 
 // ChatContext represents context for planning
 type ChatContext struct {
-	Messages *[]llm.Message `json:"messages"` // Messages prepared for LLM call
+	Messages []llm.Message `json:"messages"` // Messages prepared for LLM call
 }
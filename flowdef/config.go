@@ -0,0 +1,337 @@
+package flowdef
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/llm/gemini"
+	"github.com/alt-coder/pocketflow-go/llm/openai"
+	"github.com/alt-coder/pocketflow-go/secrets"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// Config sets up the environment of declarative flows and names the flows
+// that can be run:
+//
+//	{
+//	  "providers": {"default": {"provider": "openai", "model": "gpt-4o", "api_key": "${OPENAI_API_KEY}"}},
+//	  "mcp": {"servers": {"files": {"command": "mcp-files", "args": ["/srv/docs"]}}},
+//	  "plugins": ["plugins/jira.so"],
+//	  "tool_backends": {"tickets": {"backend": "jira", "options": {"project": "OPS"}}},
+//	  "flows": {"triage": "flows/triage.yaml"},
+//	  "flow_dir": "flows"
+//	}
+type Config struct {
+	Providers       map[string]*ProviderConfig    `json:"providers,omitempty"`        // LLM providers by name
+	DefaultProvider string                        `json:"default_provider,omitempty"` // Provider of nodes without one; defaults to "default", or the only provider
+	MCP             *tools.MCPConfig              `json:"mcp,omitempty"`              // MCP servers whose tools "tool" nodes may call
+	Plugins         []string                      `json:"plugins,omitempty"`          // Go plugins loaded before the providers are created
+	ToolBackends    map[string]*ToolBackendConfig `json:"tool_backends,omitempty"`    // Registered tool backends whose tools "tool" nodes may call
+	Flows           map[string]string             `json:"flows,omitempty"`            // Flow definition files by flow name
+	FlowDir         string                        `json:"flow_dir,omitempty"`         // Directory of <name>.yaml, .yml or .json definitions of more flows
+
+	// Dir is the directory relative paths are resolved against; LoadConfig
+	// sets it to the directory of the file
+	Dir string `json:"-"`
+}
+
+// ProviderConfig configures an LLM provider
+type ProviderConfig struct {
+	Provider    string  `json:"provider"`              // "openai", "gemini", "mock" or a registered provider
+	Model       string  `json:"model,omitempty"`       // Defaults to gpt-4o or gemini-2.0-flash
+	APIKey      string  `json:"api_key,omitempty"`     // Defaults to OPENAI_API_KEY or GOOGLE_API_KEY
+	BaseURL     string  `json:"base_url,omitempty"`    // Endpoint of an OpenAI-compatible API or a proxy
+	Temperature float32 `json:"temperature,omitempty"` // Sampling temperature
+	MaxRetries  int     `json:"max_retries,omitempty"` // Retries of failed requests; defaults to 3
+	RateLimit   int     `json:"rate_limit,omitempty"`  // Requests per minute; 0 for no limit
+
+	// Answers of a "mock" provider by a text the prompt contains, for dry
+	// runs; other prompts are answered with "Mock response to: <prompt>"
+	Responses map[string]string `json:"responses,omitempty"`
+
+	// Settings of a registered provider
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// secretKeys are the fields of a configuration that may hold secrets: API
+// keys, MCP server environments and the options of providers and tool backends
+var secretKeys = []string{"api_key", "env", "options"}
+
+// LoadConfig reads a JSON configuration. API keys, MCP server environments and
+// options may refer to environment variables as ${NAME} or be secret URIs such
+// as vault://secret/flows#openai; they are expanded before decoding.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	data, err = secrets.NewExpander(nil).ExpandJSON(context.Background(), data, secretKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand secrets: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	config.Dir = filepath.Dir(path)
+	return &config, nil
+}
+
+// ConfigFromEnv returns the configuration of a single default provider
+// chosen by the API key in the environment: OPENAI_API_KEY, with
+// OPENAI_BASE_URL, or else GOOGLE_API_KEY
+func ConfigFromEnv() *Config {
+	config := &Config{}
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		config.Providers = map[string]*ProviderConfig{"default": {Provider: "openai", APIKey: apiKey, BaseURL: os.Getenv("OPENAI_BASE_URL")}}
+	} else if apiKey := os.Getenv("GOOGLE_API_KEY"); apiKey != "" {
+		config.Providers = map[string]*ProviderConfig{"default": {Provider: "gemini", APIKey: apiKey}}
+	}
+	return config
+}
+
+// Redacted returns a copy of the configuration without API keys, MCP server
+// environments and the option values of providers and tool backends, e.g.
+// for run reports
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Providers = make(map[string]*ProviderConfig, len(c.Providers))
+	for name, provider := range c.Providers {
+		copied := *provider
+		if copied.APIKey != "" {
+			copied.APIKey = redactedValue
+		}
+		copied.Options = redactedOptions(copied.Options)
+		redacted.Providers[name] = &copied
+	}
+	if c.ToolBackends != nil {
+		redacted.ToolBackends = make(map[string]*ToolBackendConfig, len(c.ToolBackends))
+		for name, backend := range c.ToolBackends {
+			redacted.ToolBackends[name] = &ToolBackendConfig{Backend: backend.Backend, Options: redactedOptions(backend.Options)}
+		}
+	}
+	if c.MCP != nil {
+		redacted.MCP = &tools.MCPConfig{Servers: make(map[string]tools.MCPServerConfig, len(c.MCP.Servers))}
+		for name, server := range c.MCP.Servers {
+			env := make(map[string]string, len(server.Env))
+			for key := range server.Env {
+				env[key] = redactedValue
+			}
+			server.Env = env
+			redacted.MCP.Servers[name] = server
+		}
+	}
+	return &redacted
+}
+
+// redactedValue replaces secrets in redacted configurations
+const redactedValue = "[redacted]"
+
+// redactedOptions returns options with their values redacted, as any of them may be a secret
+func redactedOptions(options map[string]any) map[string]any {
+	if options == nil {
+		return nil
+	}
+	redacted := make(map[string]any, len(options))
+	for key := range options {
+		redacted[key] = redactedValue
+	}
+	return redacted
+}
+
+// FlowNames returns the names of the flows of Flows and FlowDir, sorted
+func (c *Config) FlowNames() ([]string, error) {
+	names := make(map[string]bool, len(c.Flows))
+	for name := range c.Flows {
+		names[name] = true
+	}
+	if c.FlowDir != "" {
+		entries, err := os.ReadDir(c.path(c.FlowDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read flow directory: %w", err)
+		}
+		for _, entry := range entries {
+			extension := filepath.Ext(entry.Name())
+			if entry.Type().IsRegular() && slices.Contains(definitionExtensions, extension) {
+				names[strings.TrimSuffix(entry.Name(), extension)] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// definitionExtensions are the extensions of flow definition files, in the
+// order LoadFlow looks for them
+var definitionExtensions = []string{".yaml", ".yml", ".json"}
+
+// LoadFlow loads a flow definition by its name in Flows or FlowDir, or by
+// the path of its file
+func (c *Config) LoadFlow(name string) (*Definition, error) {
+	if path, ok := c.Flows[name]; ok {
+		return LoadDefinition(c.path(path))
+	}
+	if c.FlowDir != "" {
+		for _, extension := range definitionExtensions {
+			path := filepath.Join(c.path(c.FlowDir), name+extension)
+			if _, err := os.Stat(path); err == nil {
+				return LoadDefinition(path)
+			}
+		}
+	}
+	if _, err := os.Stat(name); err == nil {
+		return LoadDefinition(name)
+	}
+	return nil, fmt.Errorf("unknown flow %s: not in flows or flow_dir, and no such file", name)
+}
+
+// path resolves a path of the configuration against its directory
+func (c *Config) path(path string) string {
+	if filepath.IsAbs(path) || c.Dir == "" {
+		return path
+	}
+	return filepath.Join(c.Dir, path)
+}
+
+// NewEnvironment loads the plugins of the configuration, creates its
+// providers, starts its MCP servers and adds the tools of its tool backends.
+// Close the environment to stop them.
+func NewEnvironment(ctx context.Context, config *Config) (*Environment, error) {
+	for _, path := range config.Plugins {
+		if err := LoadPlugin(config.path(path)); err != nil {
+			return nil, err
+		}
+	}
+
+	providers := llm.NewProviderRegistry()
+	for name, providerConfig := range config.Providers {
+		provider, err := NewProvider(ctx, providerConfig)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		if err := providers.Register(name, provider); err != nil {
+			return nil, err
+		}
+	}
+	if defaultProvider := config.defaultProvider(); defaultProvider != "" {
+		if err := providers.SetDefault(defaultProvider); err != nil {
+			return nil, fmt.Errorf("default provider: %w", err)
+		}
+	} else if len(config.Providers) > 1 {
+		return nil, fmt.Errorf("default_provider is required with several providers and none named default")
+	}
+
+	env := &Environment{Providers: providers, Tools: tools.NewToolManager()}
+	if config.MCP != nil && len(config.MCP.Servers) > 0 {
+		mcpManager := tools.NewMCPManager(config.MCP)
+		if err := mcpManager.Initialize(ctx); err != nil {
+			mcpManager.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to start MCP servers: %w", err)
+		}
+		env.Tools.SetMCPManager(mcpManager)
+	}
+	for name, backend := range config.ToolBackends {
+		if err := addToolBackend(ctx, backend, env.Tools); err != nil {
+			env.Close(ctx)
+			return nil, fmt.Errorf("tool backend %s: %w", name, err)
+		}
+	}
+	return env, nil
+}
+
+// defaultProvider returns the name of the default provider, if it can be told
+func (c *Config) defaultProvider() string {
+	if c.DefaultProvider != "" {
+		return c.DefaultProvider
+	}
+	if _, ok := c.Providers["default"]; ok {
+		return "default"
+	}
+	if len(c.Providers) == 1 {
+		for name := range c.Providers {
+			return name
+		}
+	}
+	return ""
+}
+
+// Close stops the MCP servers of the environment and shuts down its providers
+func (e *Environment) Close(ctx context.Context) error {
+	var errs []error
+	if e.Tools != nil {
+		errs = append(errs, e.Tools.Shutdown(ctx))
+	}
+	if e.Providers != nil {
+		for _, name := range e.Providers.Names() {
+			provider, _ := e.Providers.Get(name)
+			if shutdowner, ok := provider.(llm.Shutdowner); ok {
+				errs = append(errs, shutdowner.Shutdown(ctx))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewProvider creates an LLM provider
+func NewProvider(ctx context.Context, config *ProviderConfig) (llm.LLMProvider, error) {
+	maxRetries := config.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 3
+	}
+
+	switch strings.ToLower(config.Provider) {
+	case "openai":
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+		return openai.NewOpenAIClient(ctx, &openai.Config{
+			APIKey:            orDefault(config.APIKey, os.Getenv("OPENAI_API_KEY")),
+			Model:             orDefault(config.Model, "gpt-4o"),
+			Temperature:       config.Temperature,
+			MaxRetries:        maxRetries,
+			BaseURL:           baseURL,
+			TopP:              1,
+			RateLimit:         config.RateLimit,
+			RateLimitInterval: time.Minute,
+		})
+
+	case "gemini":
+		return gemini.NewGeminiClient(ctx, &gemini.Config{
+			APIKey:            orDefault(config.APIKey, os.Getenv("GOOGLE_API_KEY")),
+			Model:             orDefault(config.Model, "gemini-2.0-flash"),
+			Temperature:       config.Temperature,
+			MaxRetries:        maxRetries,
+			BaseURL:           config.BaseURL,
+			RateLimit:         config.RateLimit,
+			RateLimitInterval: time.Minute,
+		})
+
+	case "mock":
+		mock := llm.NewMockProvider(orDefault(config.Model, "mock"))
+		if len(config.Responses) > 0 {
+			mock.SetResponsePattern(config.Responses)
+		}
+		return mock, nil
+
+	default:
+		if factory, ok := registeredProvider(config.Provider); ok {
+			return factory(ctx, config)
+		}
+		return nil, fmt.Errorf("unsupported provider: %s. Supported providers: %s", config.Provider, strings.Join(ProviderNames(), ", "))
+	}
+}
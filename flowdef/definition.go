@@ -0,0 +1,135 @@
+// Package flowdef builds flows from declarative definitions, so flows made of
+// ready-made nodes can be written as YAML or JSON files and run without Go
+// code, e.g. with the pocketflow run command.
+package flowdef
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// State is the state of declarative flows: the initial state of a run and
+// the values its nodes write, by key
+type State = map[string]any
+
+// Definition describes a flow: its nodes and the actions that lead from one
+// to the next. A run ends at a node without a successor for its action.
+type Definition struct {
+	Name        string           `yaml:"name" json:"name"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Start       string           `yaml:"start,omitempty" json:"start,omitempty"`               // Node the flow starts at; defaults to the first node
+	RetryBudget int              `yaml:"retry_budget,omitempty" json:"retry_budget,omitempty"` // Retries allowed across all nodes of a run; 0 for no limit
+	Nodes       []NodeDefinition `yaml:"nodes" json:"nodes"`
+
+	// Dir is the directory relative paths of node configurations are resolved
+	// against; LoadDefinition sets it to the directory of the file
+	Dir string `yaml:"-" json:"-"`
+}
+
+// NodeDefinition describes a node of a flow
+type NodeDefinition struct {
+	Name       string            `yaml:"name" json:"name"`
+	Type       string            `yaml:"type" json:"type"`                                   // Node type registered in the Registry, e.g. "llm"
+	Provider   string            `yaml:"provider,omitempty" json:"provider,omitempty"`       // LLM provider of the node; empty uses the default
+	MaxRetries int               `yaml:"max_retries,omitempty" json:"max_retries,omitempty"` // Retries of a failed Exec
+	Config     map[string]any    `yaml:"config,omitempty" json:"config,omitempty"`           // Settings of the node type
+	Next       map[string]string `yaml:"next,omitempty" json:"next,omitempty"`               // Successor node by action; "*" matches any other action
+}
+
+// DecodeConfig decodes the node's configuration into target, a pointer to a
+// struct with yaml tags; unknown settings are errors
+func (n NodeDefinition) DecodeConfig(target any) error {
+	data, err := yaml.Marshal(n.Config)
+	if err != nil {
+		return fmt.Errorf("node %s: invalid config: %w", n.Name, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(target); err != nil && len(n.Config) > 0 {
+		return fmt.Errorf("node %s: invalid config: %w", n.Name, err)
+	}
+	return nil
+}
+
+// LoadDefinition reads a flow definition from a YAML or JSON file
+func LoadDefinition(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flow definition: %w", err)
+	}
+	definition, err := ParseDefinition(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	definition.Dir = filepath.Dir(path)
+	return definition, nil
+}
+
+// ParseDefinition parses a flow definition in YAML or JSON and validates it
+func ParseDefinition(data []byte) (*Definition, error) {
+	var definition Definition
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&definition); err != nil {
+		return nil, fmt.Errorf("invalid flow definition: %w", err)
+	}
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// Validate checks that the nodes have unique names and types, and that the
+// start and every successor name a node
+func (d *Definition) Validate() error {
+	if len(d.Nodes) == 0 {
+		return fmt.Errorf("flow %s has no nodes", d.Name)
+	}
+	names := make(map[string]bool, len(d.Nodes))
+	for _, node := range d.Nodes {
+		if node.Name == "" {
+			return fmt.Errorf("flow %s has a node without a name", d.Name)
+		}
+		if names[node.Name] {
+			return fmt.Errorf("flow %s has two nodes named %s", d.Name, node.Name)
+		}
+		if node.Type == "" {
+			return fmt.Errorf("node %s has no type", node.Name)
+		}
+		if node.MaxRetries < 0 {
+			return fmt.Errorf("node %s: max_retries cannot be negative", node.Name)
+		}
+		names[node.Name] = true
+	}
+	if d.Start != "" && !names[d.Start] {
+		return fmt.Errorf("flow %s starts at unknown node %s", d.Name, d.Start)
+	}
+	for _, node := range d.Nodes {
+		for action, successor := range node.Next {
+			if !names[successor] {
+				return fmt.Errorf("node %s leads to unknown node %s on %q", node.Name, successor, action)
+			}
+		}
+	}
+	return nil
+}
+
+// StartNode returns the name of the node the flow starts at
+func (d *Definition) StartNode() string {
+	if d.Start != "" {
+		return d.Start
+	}
+	return d.Nodes[0].Name
+}
+
+// Path resolves a path of a node configuration against the definition's directory
+func (d *Definition) Path(path string) string {
+	if path == "" || filepath.IsAbs(path) || d.Dir == "" {
+		return path
+	}
+	return filepath.Join(d.Dir, path)
+}
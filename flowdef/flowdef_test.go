@@ -0,0 +1,367 @@
+package flowdef
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+const triageFlow = `
+name: triage
+nodes:
+  - name: classify
+    type: llm
+    config:
+      system: You sort support tickets.
+      prompt: "Is this urgent? Answer yes or no: {{.ticket}}"
+    next:
+      success: route
+  - name: route
+    type: route
+    config:
+      action: "{{if eq (lower .classify) \"yes\"}}page{{else}}queue{{end}}"
+    next:
+      page: page
+      "*": label
+  - name: page
+    type: tool
+    config:
+      tool: page
+      args:
+        message: "Urgent: {{.ticket}}"
+    next:
+      success: label
+  - name: label
+    type: set
+    config:
+      values:
+        label: "{{if .page}}paged{{else}}queued{{end}}"
+        source: triage
+`
+
+type pageInput struct {
+	Message string `json:"message"`
+}
+
+func newTestEnvironment(t *testing.T, answers map[string]string) (*Environment, *[]string) {
+	t.Helper()
+	mock := llm.NewMockProvider("mock")
+	mock.SetResponsePattern(answers)
+	providers := llm.NewProviderRegistry()
+	if err := providers.Register("default", mock); err != nil {
+		t.Fatal(err)
+	}
+
+	var pages []string
+	toolManager := tools.NewToolManager()
+	if err := toolManager.AddLocalTool("page", "Page the on-call engineer", func(input pageInput) string {
+		pages = append(pages, input.Message)
+		return "paged"
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return &Environment{Providers: providers, Tools: toolManager}, &pages
+}
+
+func TestBuild_RunsDefinition(t *testing.T) {
+	definition, err := ParseDefinition([]byte(triageFlow))
+	if err != nil {
+		t.Fatalf("ParseDefinition() error = %v", err)
+	}
+
+	tests := []struct {
+		ticket string
+		label  string
+		steps  string
+	}{
+		{"The site is down", "paged", "classify,route,page,label"},
+		{"Typo on the pricing page", "queued", "classify,route,label"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			env, pages := newTestEnvironment(t, map[string]string{"site is down": "Yes", "typo": "no"})
+			flow, err := Build(definition, env)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			var steps []string
+			state := State{"ticket": tt.ticket}
+			action := flow.RunObserved(&state, func(event core.StepEvent) { steps = append(steps, event.Name) })
+			if action != core.ActionSuccess {
+				t.Errorf("Run() = %s, state %v", action, state)
+			}
+			if strings.Join(steps, ",") != tt.steps {
+				t.Errorf("steps = %v, want %s", steps, tt.steps)
+			}
+			if state["label"] != tt.label || state["source"] != "triage" {
+				t.Errorf("unexpected state %v", state)
+			}
+			if tt.label == "paged" && (len(*pages) != 1 || (*pages)[0] != "Urgent: The site is down") {
+				t.Errorf("unexpected pages %v", *pages)
+			}
+		})
+	}
+}
+
+func TestBuild_NodeErrorEndsInFailure(t *testing.T) {
+	definition, err := ParseDefinition([]byte(`
+name: greet
+nodes:
+  - name: greet
+    type: llm
+    max_retries: 1
+    config: {prompt: "Greet {{.name}}"}
+`))
+	if err != nil {
+		t.Fatalf("ParseDefinition() error = %v", err)
+	}
+	env, _ := newTestEnvironment(t, nil)
+	flow, err := Build(definition, env)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	state := State{}
+	if action := flow.Run(&state); action != core.ActionFailure {
+		t.Errorf("Run() = %s, want failure for a missing variable", action)
+	}
+	if message, _ := state[ErrorKey].(string); !strings.Contains(message, "greet: ") || !strings.Contains(message, "name") {
+		t.Errorf("expected the error in state, got %v", state)
+	}
+}
+
+func TestParseDefinition_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		want       string
+	}{
+		{"no nodes", "name: empty", "has no nodes"},
+		{"duplicate", "nodes: [{name: a, type: set}, {name: a, type: set}]", "two nodes named a"},
+		{"no type", "nodes: [{name: a}]", "node a has no type"},
+		{"unknown start", "start: b\nnodes: [{name: a, type: set}]", "starts at unknown node b"},
+		{"unknown successor", "nodes: [{name: a, type: set, next: {success: b}}]", "leads to unknown node b"},
+		{"unknown field", "nodes: [{name: a, type: set, retries: 2}]", "field retries not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDefinition([]byte(tt.definition))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		want       string
+	}{
+		{"unknown type", "nodes: [{name: a, type: shell}]", "unknown type shell"},
+		{"unknown provider", "nodes: [{name: a, type: llm, provider: strong, config: {prompt: hi}}]", "unknown provider strong"},
+		{"unknown tool", "nodes: [{name: a, type: tool, config: {tool: deploy}}]", "unknown tool deploy"},
+		{"unknown setting", "nodes: [{name: a, type: llm, config: {prompt: hi, model: x}}]", "field model not found"},
+		{"missing setting", "nodes: [{name: a, type: route}]", "action is required"},
+		{"bad template", "nodes: [{name: a, type: llm, config: {prompt: '{{.x'}}]", "failed to parse prompt template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition, err := ParseDefinition([]byte(tt.definition))
+			if err != nil {
+				t.Fatalf("ParseDefinition() error = %v", err)
+			}
+			env, _ := newTestEnvironment(t, nil)
+			if _, err := Build(definition, env); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register("llm", newSetNode); err == nil {
+		t.Error("expected an error replacing a built-in type")
+	}
+	if err := registry.Register("upper", func(spec NodeSpec) (core.Workflow[State], error) {
+		return newStateNode(spec, spec.Name, func(ctx context.Context, state State) (any, error) {
+			text, _ := state["text"].(string)
+			return strings.ToUpper(text), nil
+		}), nil
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if types := strings.Join(registry.Types(), ","); types != "extract,llm,route,set,tool,upper" {
+		t.Errorf("Types() = %s", types)
+	}
+
+	definition, _ := ParseDefinition([]byte("nodes: [{name: shout, type: upper}]"))
+	flow, err := registry.Build(definition, nil)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	state := State{"text": "hello"}
+	flow.Run(&state)
+	if state["shout"] != "HELLO" {
+		t.Errorf("unexpected state %v", state)
+	}
+}
+
+func TestConfig_LoadFlow(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("flows/greet.yaml", "name: greet\nnodes: [{name: greet, type: set, config: {values: {greeting: hi}}}]")
+	write("other/report.json", `{"name": "report", "nodes": [{"name": "report", "type": "set", "config": {"values": {"done": "yes"}}}]}`)
+	t.Setenv("FLOW_API_KEY", "sk-test")
+	write("pocketflow.json", `{
+		"providers": {"fast": {"provider": "mock", "api_key": "${FLOW_API_KEY}", "responses": {"greet": "Use ${ as is"}}},
+		"mcp": {"servers": {"files": {"command": "mcp-files", "args": ["file:///srv/docs"], "env": {"TOKEN": "${FLOW_API_KEY}"}}}},
+		"flows": {"report": "other/report.json"},
+		"flow_dir": "flows"
+	}`)
+
+	config, err := LoadConfig(filepath.Join(dir, "pocketflow.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if config.Providers["fast"].APIKey != "sk-test" || config.Redacted().Providers["fast"].APIKey != redactedValue {
+		t.Errorf("expected the expanded key and a redacted copy, got %+v", config.Providers["fast"])
+	}
+	// Only secret-bearing fields are expanded
+	files := config.MCP.Servers["files"]
+	if files.Env["TOKEN"] != "sk-test" || files.Args[0] != "file:///srv/docs" || config.Providers["fast"].Responses["greet"] != "Use ${ as is" {
+		t.Errorf("expected only the environment expanded, got %+v and %v", files, config.Providers["fast"].Responses)
+	}
+
+	names, err := config.FlowNames()
+	if err != nil || strings.Join(names, ",") != "greet,report" {
+		t.Errorf("FlowNames() = %v, %v", names, err)
+	}
+	for _, name := range []string{"greet", "report", filepath.Join(dir, "other/report.json")} {
+		if _, err := config.LoadFlow(name); err != nil {
+			t.Errorf("LoadFlow(%s) error = %v", name, err)
+		}
+	}
+	if _, err := config.LoadFlow("missing"); err == nil {
+		t.Error("expected an error for an unknown flow")
+	}
+
+	env, err := NewEnvironment(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewEnvironment() error = %v", err)
+	}
+	defer env.Close(context.Background())
+	if provider, err := env.Providers.Lookup("any"); err != nil || provider.GetName() != "mock" {
+		t.Errorf("expected the only provider as the default, got %v, %v", provider, err)
+	}
+}
+
+type lookupInput struct {
+	Key string `json:"key"`
+}
+
+// forgetPlugins removes the providers and tool backends a test registers when
+// it ends, so the test can run again in the same process
+func forgetPlugins(t *testing.T, providers, toolBackends []string) {
+	t.Cleanup(func() {
+		plugins.mu.Lock()
+		defer plugins.mu.Unlock()
+		for _, name := range providers {
+			delete(plugins.providers, name)
+		}
+		for _, name := range toolBackends {
+			delete(plugins.toolBackends, name)
+		}
+	})
+}
+
+func TestPlugins(t *testing.T) {
+	forgetPlugins(t, []string{"test-echo"}, []string{"test-kv"})
+	if err := RegisterProvider("openai", nil); err == nil {
+		t.Error("expected an error replacing a built-in provider")
+	}
+	err := RegisterProvider("test-echo", func(ctx context.Context, config *ProviderConfig) (llm.LLMProvider, error) {
+		mock := llm.NewMockProvider(orDefault(config.Model, "echo"))
+		mock.SetResponsePattern(map[string]string{"": config.Options["prefix"].(string) + "pong"})
+		return mock, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterProvider() error = %v", err)
+	}
+	err = RegisterToolBackend("test-kv", func(ctx context.Context, config *ToolBackendConfig, manager *tools.ToolManager) error {
+		values := config.Options
+		return manager.AddLocalTool("lookup", "Look up a value", func(input lookupInput) string {
+			value, _ := values[input.Key].(string)
+			return value
+		})
+	})
+	if err != nil {
+		t.Fatalf("RegisterToolBackend() error = %v", err)
+	}
+	if err := RegisterToolBackend("test-kv", func(context.Context, *ToolBackendConfig, *tools.ToolManager) error { return nil }); err == nil {
+		t.Error("expected an error registering a tool backend twice")
+	}
+	if names := ProviderNames(); !slices.Contains(names, "openai") || !slices.Contains(names, "test-echo") || !slices.IsSorted(names) {
+		t.Errorf("ProviderNames() = %v, want the built-in and registered providers in order", names)
+	}
+	if names := ToolBackendNames(); !slices.Contains(names, "test-kv") {
+		t.Errorf("ToolBackendNames() = %v, want test-kv", names)
+	}
+
+	config := &Config{
+		Providers:    map[string]*ProviderConfig{"default": {Provider: "test-echo", Options: map[string]any{"prefix": "> "}}},
+		ToolBackends: map[string]*ToolBackendConfig{"kv": {Backend: "test-kv", Options: map[string]any{"region": "eu"}}},
+	}
+	env, err := NewEnvironment(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewEnvironment() error = %v", err)
+	}
+	defer env.Close(context.Background())
+
+	definition, _ := ParseDefinition([]byte(`
+nodes:
+  - {name: ask, type: llm, config: {prompt: ping}, next: {success: region}}
+  - {name: region, type: tool, config: {tool: lookup, args: {key: region}}}
+`))
+	flow, err := Build(definition, env)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	state := State{}
+	flow.Run(&state)
+	if state["ask"] != "> pong" || state["region"] != `"eu"` {
+		t.Errorf("unexpected state %v", state)
+	}
+	if redacted := config.Redacted(); redacted.ToolBackends["kv"].Options["region"] != redactedValue || redacted.Providers["default"].Options["prefix"] != redactedValue {
+		t.Errorf("options not redacted: %+v", redacted.ToolBackends["kv"])
+	}
+
+	for _, config := range []*Config{
+		{Providers: map[string]*ProviderConfig{"default": {Provider: "unregistered"}}},
+		{ToolBackends: map[string]*ToolBackendConfig{"kv": {Backend: "unregistered"}}},
+		{Plugins: []string{"missing.so"}, Dir: t.TempDir()},
+	} {
+		if _, err := NewEnvironment(context.Background(), config); err == nil {
+			t.Errorf("NewEnvironment(%+v) succeeded", config)
+		}
+	}
+}
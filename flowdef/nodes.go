@@ -0,0 +1,329 @@
+package flowdef
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/prompt"
+	"github.com/alt-coder/pocketflow-go/structured"
+)
+
+// ErrorKey is the state key the built-in nodes write their error to before
+// returning core.ActionFailure
+const ErrorKey = "error"
+
+// builtinNodes are the node types of every new Registry
+var builtinNodes = map[string]NodeFactory{
+	"llm":     newLLMNode,
+	"extract": newExtractNode,
+	"tool":    newToolNode,
+	"set":     newSetNode,
+	"route":   newRouteNode,
+}
+
+// result is the outcome of a built-in node's Exec
+type result struct {
+	value any
+	err   error
+}
+
+// work is the part a built-in node type adds to stateNode: computing a value
+// from the state, which Post then stores under the node's output key
+type work func(ctx context.Context, state State) (any, error)
+
+// stateNode is the BaseNode of the built-in node types. Prep hands Exec a copy
+// of the state, Exec runs the work and Post stores its value, or the error
+// under ErrorKey. Nodes without an output key return the value as the action.
+type stateNode struct {
+	name   string
+	output string // State key of the value; "." merges a State value, empty makes the value the action
+	work   work
+}
+
+// newStateNode wraps work in a node named after its definition
+func newStateNode(spec NodeSpec, output string, work work) core.Workflow[State] {
+	return core.NewNode[State, State, result](&stateNode{name: spec.Name, output: output, work: work}, spec.MaxRetries, 1)
+}
+
+// Name implements the naming of core.Node, so steps and reports show the node's name
+func (n *stateNode) Name() string {
+	return n.name
+}
+
+// Prep passes a copy of the state, so the work cannot change it
+func (n *stateNode) Prep(state *State) []State {
+	return []State{maps.Clone(*state)}
+}
+
+// Exec runs the work
+func (n *stateNode) Exec(state State) (result, error) {
+	value, err := n.work(context.Background(), state)
+	if err != nil {
+		return result{}, err
+	}
+	return result{value: value}, nil
+}
+
+// ExecFallback reports the error of the last attempt
+func (n *stateNode) ExecFallback(err error) result {
+	return result{err: err}
+}
+
+// Post stores the value and continues with "success", or records the error
+// and returns "failure"
+func (n *stateNode) Post(state *State, prepRes []State, execResults ...result) core.Action {
+	outcome := execResults[0]
+	if outcome.err != nil {
+		(*state)[ErrorKey] = fmt.Sprintf("%s: %v", n.name, outcome.err)
+		return core.ActionFailure
+	}
+	if n.output == "" {
+		action, _ := outcome.value.(string)
+		if action = strings.TrimSpace(action); action == "" {
+			return core.ActionDefault
+		}
+		return core.Action(action)
+	}
+	if values, ok := outcome.value.(State); ok && n.output == "." {
+		maps.Copy(*state, values)
+	} else {
+		(*state)[n.output] = outcome.value
+	}
+	return core.ActionSuccess
+}
+
+// llmConfig configures "llm" nodes
+type llmConfig struct {
+	Prompt string `yaml:"prompt"` // Template of the user message, e.g. "Summarize: {{.text}}"
+	System string `yaml:"system"` // Template of the system message; optional
+	Output string `yaml:"output"` // State key of the answer; defaults to the node name
+}
+
+// newLLMNode creates a node that sends a prompt rendered from the state and
+// stores the answer
+func newLLMNode(spec NodeSpec) (core.Workflow[State], error) {
+	var config llmConfig
+	if err := spec.DecodeConfig(&config); err != nil {
+		return nil, err
+	}
+	if config.Prompt == "" {
+		return nil, fmt.Errorf("node %s: prompt is required", spec.Name)
+	}
+	userTemplate, err := prompt.NewTemplate(spec.Name, config.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	var systemTemplate *prompt.Template
+	if config.System != "" {
+		if systemTemplate, err = prompt.NewTemplate(spec.Name+".system", config.System); err != nil {
+			return nil, err
+		}
+	}
+	provider, err := spec.LLM()
+	if err != nil {
+		return nil, err
+	}
+
+	return newStateNode(spec, orDefault(config.Output, spec.Name), func(ctx context.Context, state State) (any, error) {
+		var messages []llm.Message
+		if systemTemplate != nil {
+			system, err := systemTemplate.Render(prompt.Vars(state))
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: system})
+		}
+		user, err := userTemplate.Render(prompt.Vars(state))
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, llm.Message{Role: llm.RoleUser, Content: user})
+
+		response, err := provider.CallLLM(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		_, answer := llm.SplitReasoning(response.Content)
+		return strings.TrimSpace(answer), nil
+	}), nil
+}
+
+// extractConfig configures "extract" nodes
+type extractConfig struct {
+	Schema  string `yaml:"schema"`  // Go struct or JSON Schema file, relative to the flow definition
+	Type    string `yaml:"type"`    // Struct of a Go schema; defaults to the first
+	Input   string `yaml:"input"`   // Template of the text to extract from
+	Output  string `yaml:"output"`  // State key of the extracted object; defaults to the node name
+	Retries int    `yaml:"retries"` // Repair prompts after invalid output; defaults to 2
+}
+
+// newExtractNode creates a node that extracts an object of a schema from text
+// rendered from the state, see structured.ParseDynamic
+func newExtractNode(spec NodeSpec) (core.Workflow[State], error) {
+	config := extractConfig{Retries: 2}
+	if err := spec.DecodeConfig(&config); err != nil {
+		return nil, err
+	}
+	if config.Schema == "" || config.Input == "" {
+		return nil, fmt.Errorf("node %s: schema and input are required", spec.Name)
+	}
+	schema, err := structured.LoadDynamicSchema(spec.Flow.Path(config.Schema), config.Type)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", spec.Name, err)
+	}
+	input, err := prompt.NewTemplate(spec.Name, config.Input)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := spec.LLM()
+	if err != nil {
+		return nil, err
+	}
+	parser, err := structured.NewParser(provider, structured.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return newStateNode(spec, orDefault(config.Output, spec.Name), func(ctx context.Context, state State) (any, error) {
+		text, err := input.Render(prompt.Vars(state))
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := structured.ParseDynamic(parser, ctx, schema, text, config.Retries+1)
+		if err != nil {
+			return nil, err
+		}
+		return *parsed.Data, nil
+	}), nil
+}
+
+// toolConfig configures "tool" nodes
+type toolConfig struct {
+	Tool   string         `yaml:"tool"`   // Name of a local or MCP tool
+	Args   map[string]any `yaml:"args"`   // Arguments; strings are templates
+	Output string         `yaml:"output"` // State key of the result; defaults to the node name
+}
+
+// newToolNode creates a node that calls a tool with arguments rendered from
+// the state and stores its result
+func newToolNode(spec NodeSpec) (core.Workflow[State], error) {
+	var config toolConfig
+	if err := spec.DecodeConfig(&config); err != nil {
+		return nil, err
+	}
+	if config.Tool == "" {
+		return nil, fmt.Errorf("node %s: tool is required", spec.Name)
+	}
+	if spec.Env.Tools == nil || !spec.Env.Tools.HasTool(config.Tool) {
+		return nil, fmt.Errorf("node %s: unknown tool %s", spec.Name, config.Tool)
+	}
+	toolManager := spec.Env.Tools
+
+	return newStateNode(spec, orDefault(config.Output, spec.Name), func(ctx context.Context, state State) (any, error) {
+		args, err := render(spec.Name, config.Args, state)
+		if err != nil {
+			return nil, err
+		}
+		arguments, _ := args.(map[string]any)
+		toolResult, err := toolManager.ExecuteTool(ctx, llm.ToolCalls{Id: spec.Name, ToolName: config.Tool, ToolArgs: arguments})
+		if err != nil {
+			return nil, err
+		}
+		if toolResult.IsError {
+			return nil, fmt.Errorf("tool %s failed: %w", config.Tool, llm.NewToolError(toolResult.Code, orDefault(toolResult.Error, toolResult.Content)))
+		}
+		return toolResult.Content, nil
+	}), nil
+}
+
+// setConfig configures "set" nodes
+type setConfig struct {
+	Values map[string]any `yaml:"values"` // Values by state key; strings are templates
+}
+
+// newSetNode creates a node that writes values rendered from the state
+func newSetNode(spec NodeSpec) (core.Workflow[State], error) {
+	var config setConfig
+	if err := spec.DecodeConfig(&config); err != nil {
+		return nil, err
+	}
+	if len(config.Values) == 0 {
+		return nil, fmt.Errorf("node %s: values are required", spec.Name)
+	}
+	return newStateNode(spec, ".", func(ctx context.Context, state State) (any, error) {
+		values, err := render(spec.Name, config.Values, state)
+		if err != nil {
+			return nil, err
+		}
+		return State(values.(map[string]any)), nil
+	}), nil
+}
+
+// routeConfig configures "route" nodes
+type routeConfig struct {
+	Action string `yaml:"action"` // Template of the action, e.g. "{{if .approved}}publish{{else}}revise{{end}}"
+}
+
+// newRouteNode creates a node that returns an action rendered from the
+// state, choosing the next node; an empty action is "default"
+func newRouteNode(spec NodeSpec) (core.Workflow[State], error) {
+	var config routeConfig
+	if err := spec.DecodeConfig(&config); err != nil {
+		return nil, err
+	}
+	if config.Action == "" {
+		return nil, fmt.Errorf("node %s: action is required", spec.Name)
+	}
+	action, err := prompt.NewTemplate(spec.Name, config.Action)
+	if err != nil {
+		return nil, err
+	}
+	return newStateNode(spec, "", func(ctx context.Context, state State) (any, error) {
+		return action.Render(prompt.Vars(state))
+	}), nil
+}
+
+// render renders the strings of a configuration value as templates over the
+// state, in nested maps and lists too
+func render(name string, value any, state State) (any, error) {
+	switch value := value.(type) {
+	case string:
+		template, err := prompt.NewTemplate(name, value)
+		if err != nil {
+			return nil, err
+		}
+		return template.Render(prompt.Vars(state))
+	case map[string]any:
+		rendered := make(map[string]any, len(value))
+		for key, item := range value {
+			var err error
+			if rendered[key], err = render(name+"."+key, item, state); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(value))
+		for i, item := range value {
+			var err error
+			if rendered[i], err = render(fmt.Sprintf("%s[%d]", name, i), item, state); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
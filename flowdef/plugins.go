@@ -0,0 +1,114 @@
+package flowdef
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// Packages outside this module contribute LLM providers and tool backends by
+// registering them by name in an init function, the way database/sql drivers
+// do. A program gets them by importing the package for its side effects, or
+// at runtime by loading it as a Go plugin with LoadPlugin; configurations
+// then name them like the built-in ones.
+
+// ProviderFactory creates an LLM provider from its configuration; settings
+// of its own are in config.Options
+type ProviderFactory func(ctx context.Context, config *ProviderConfig) (llm.LLMProvider, error)
+
+// ToolBackendFactory adds the tools of a backend to manager
+type ToolBackendFactory func(ctx context.Context, config *ToolBackendConfig, manager *tools.ToolManager) error
+
+// ToolBackendConfig configures a tool backend
+type ToolBackendConfig struct {
+	Backend string         `json:"backend"`           // Name the backend was registered under
+	Options map[string]any `json:"options,omitempty"` // Settings of the backend
+}
+
+// builtinProviders are the providers NewProvider creates itself
+var builtinProviders = []string{"gemini", "mock", "openai"}
+
+// plugins holds the registered providers and tool backends
+var plugins = struct {
+	mu           sync.RWMutex
+	providers    map[string]ProviderFactory
+	toolBackends map[string]ToolBackendFactory
+}{providers: make(map[string]ProviderFactory), toolBackends: make(map[string]ToolBackendFactory)}
+
+// RegisterProvider makes a provider available to configurations under name;
+// names cannot be registered twice or replace a built-in provider
+func RegisterProvider(name string, factory ProviderFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("provider name and factory are required")
+	}
+	plugins.mu.Lock()
+	defer plugins.mu.Unlock()
+	if _, exists := plugins.providers[name]; exists || slices.Contains(builtinProviders, name) {
+		return fmt.Errorf("provider %s is already registered", name)
+	}
+	plugins.providers[name] = factory
+	return nil
+}
+
+// RegisterToolBackend makes a tool backend available to configurations under
+// name; names cannot be registered twice
+func RegisterToolBackend(name string, factory ToolBackendFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("tool backend name and factory are required")
+	}
+	plugins.mu.Lock()
+	defer plugins.mu.Unlock()
+	if _, exists := plugins.toolBackends[name]; exists {
+		return fmt.Errorf("tool backend %s is already registered", name)
+	}
+	plugins.toolBackends[name] = factory
+	return nil
+}
+
+// ProviderNames returns the names of the built-in and registered providers in sorted order
+func ProviderNames() []string {
+	plugins.mu.RLock()
+	defer plugins.mu.RUnlock()
+	names := slices.Clone(builtinProviders)
+	for name := range plugins.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ToolBackendNames returns the names of the registered tool backends in sorted order
+func ToolBackendNames() []string {
+	plugins.mu.RLock()
+	defer plugins.mu.RUnlock()
+	names := make([]string, 0, len(plugins.toolBackends))
+	for name := range plugins.toolBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registeredProvider returns the factory of a registered provider
+func registeredProvider(name string) (ProviderFactory, bool) {
+	plugins.mu.RLock()
+	defer plugins.mu.RUnlock()
+	factory, ok := plugins.providers[name]
+	return factory, ok
+}
+
+// addToolBackend adds the tools of a configured backend to manager
+func addToolBackend(ctx context.Context, config *ToolBackendConfig, manager *tools.ToolManager) error {
+	plugins.mu.RLock()
+	factory, ok := plugins.toolBackends[config.Backend]
+	plugins.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown tool backend %s; registered backends: %v", config.Backend, ToolBackendNames())
+	}
+	return factory(ctx, config, manager)
+}
@@ -0,0 +1,19 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package flowdef
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin loads a Go plugin built with go build -buildmode=plugin, whose
+// init functions register its providers and tool backends. The plugin must
+// be built with the same Go version and versions of this module's packages as
+// the program. Loading a plugin again does nothing.
+func LoadPlugin(path string) error {
+	if _, err := plugin.Open(path); err != nil {
+		return fmt.Errorf("failed to load plugin %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package flowdef
+
+import "fmt"
+
+// LoadPlugin fails: Go plugins need cgo on Linux, macOS or FreeBSD. Import
+// the plugin's package for its side effects instead.
+func LoadPlugin(path string) error {
+	return fmt.Errorf("cannot load plugin %s: Go plugins are not supported by this build", path)
+}
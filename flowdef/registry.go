@@ -0,0 +1,134 @@
+package flowdef
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// Environment holds what the nodes of declarative flows use
+type Environment struct {
+	Providers *llm.ProviderRegistry // LLM providers by name
+	Tools     *tools.ToolManager    // Tools of "tool" nodes; nil if there are none
+}
+
+// NodeSpec is what a NodeFactory builds a node from
+type NodeSpec struct {
+	NodeDefinition
+	Flow *Definition  // The flow the node belongs to
+	Env  *Environment // Providers and tools of the flow
+}
+
+// LLM returns the provider of the node, the one its definition names or else
+// the default provider
+func (s NodeSpec) LLM() (llm.LLMProvider, error) {
+	if s.Env == nil || s.Env.Providers == nil || len(s.Env.Providers.Names()) == 0 {
+		return nil, fmt.Errorf("node %s: no LLM providers configured", s.Name)
+	}
+	if _, err := s.Env.Providers.Lookup(s.Name); err != nil {
+		return nil, fmt.Errorf("node %s: %w", s.Name, err)
+	}
+	return s.Env.Providers.For(s.Name), nil
+}
+
+// NodeFactory builds the node of a definition
+type NodeFactory func(spec NodeSpec) (core.Workflow[State], error)
+
+// Registry holds the node types definitions may use
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]NodeFactory
+}
+
+// NewRegistry creates a registry with the built-in node types: "llm",
+// "extract", "tool", "set" and "route"
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]NodeFactory)}
+	for nodeType, factory := range builtinNodes {
+		r.factories[nodeType] = factory
+	}
+	return r
+}
+
+// DefaultRegistry is the registry Build uses
+var DefaultRegistry = NewRegistry()
+
+// Register adds a node type; types cannot be replaced
+func (r *Registry) Register(nodeType string, factory NodeFactory) error {
+	if nodeType == "" || factory == nil {
+		return fmt.Errorf("node type and factory are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.factories[nodeType]; exists {
+		return fmt.Errorf("node type %s is already registered", nodeType)
+	}
+	r.factories[nodeType] = factory
+	return nil
+}
+
+// Types returns the registered node types in sorted order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.factories))
+	for nodeType := range r.factories {
+		types = append(types, nodeType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build creates the flow of a definition with the nodes of the registry.
+// Nodes naming a provider are assigned it in env.Providers.
+func (r *Registry) Build(definition *Definition, env *Environment) (*core.Flow[State], error) {
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+	if env == nil {
+		env = &Environment{}
+	}
+
+	workflows := make(map[string]core.Workflow[State], len(definition.Nodes))
+	for _, node := range definition.Nodes {
+		r.mu.RLock()
+		factory, ok := r.factories[node.Type]
+		r.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("node %s has unknown type %s; known types: %v", node.Name, node.Type, r.Types())
+		}
+		if node.Provider != "" {
+			if env.Providers == nil {
+				return nil, fmt.Errorf("node %s uses provider %s, but no providers are configured", node.Name, node.Provider)
+			}
+			if err := env.Providers.Assign(node.Name, node.Provider); err != nil {
+				return nil, err
+			}
+		}
+
+		workflow, err := factory(NodeSpec{NodeDefinition: node, Flow: definition, Env: env})
+		if err != nil {
+			return nil, err
+		}
+		workflows[node.Name] = workflow
+	}
+
+	for _, node := range definition.Nodes {
+		for action, successor := range node.Next {
+			workflows[node.Name].AddSuccessor(workflows[successor], core.Action(action))
+		}
+	}
+
+	flow := core.NewFlow(workflows[definition.StartNode()])
+	flow.SetRetryBudget(definition.RetryBudget)
+	return flow, nil
+}
+
+// Build creates the flow of a definition with the node types of DefaultRegistry
+func Build(definition *Definition, env *Environment) (*core.Flow[State], error) {
+	return DefaultRegistry.Build(definition, env)
+}
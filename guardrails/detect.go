@@ -0,0 +1,70 @@
+package guardrails
+
+import "regexp"
+
+// SecretRules returns rules redacting credentials: cloud and API keys, access
+// tokens, private keys and password assignments. Matches are kept out of findings.
+func SecretRules() []Rule {
+	secret := func(name, pattern string) Rule {
+		return Rule{Name: name, Pattern: regexp.MustCompile(pattern), Verdict: VerdictRedact, Secret: true}
+	}
+	return []Rule{
+		secret("private_key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+		secret("aws_access_key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		secret("github_token", `\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+		secret("slack_token", `\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+		secret("google_api_key", `\bAIza[0-9A-Za-z_-]{35}\b`),
+		secret("api_key", `\bsk-[A-Za-z0-9_-]{20,}\b`),
+		secret("jwt", `\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+		secret("bearer_token", `(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`),
+		secret("credential", `(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token)\s*[:=]\s*["']?[^\s"']{6,}["']?`),
+	}
+}
+
+// PIIRules returns rules redacting personal data: card numbers that pass the
+// Luhn check, US social security numbers, email addresses, phone numbers and IP addresses
+func PIIRules() []Rule {
+	pii := func(name, pattern string) Rule {
+		return Rule{Name: name, Pattern: regexp.MustCompile(pattern), Verdict: VerdictRedact}
+	}
+	card := pii("credit_card", `\b(?:\d[ -]?){12,18}\d\b`)
+	card.Validate = luhn
+	return []Rule{
+		card,
+		pii("us_ssn", `\b\d{3}-\d{2}-\d{4}\b`),
+		pii("email", `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+		pii("phone", `(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`),
+		pii("ip_address", `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+	}
+}
+
+// NewSecretFilter creates a filter redacting SecretRules
+func NewSecretFilter() *RuleFilter {
+	return NewRuleFilter("secrets", SecretRules()...)
+}
+
+// NewPIIFilter creates a filter redacting PIIRules
+func NewPIIFilter() *RuleFilter {
+	return NewRuleFilter("pii", PIIRules()...)
+}
+
+// luhn reports whether the digits in number pass the Luhn checksum
+func luhn(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
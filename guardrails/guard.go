@@ -0,0 +1,184 @@
+// Package guardrails checks content on its way into and out of LLMs. Filters
+// match rules, detect secrets and personal data, or ask a model to classify
+// content; a Guard runs them and blocks or redacts what they flag. Guards are
+// attached to a provider with NewProvider or to a node with NewGuardedNode, so
+// unsafe content is stopped before it reaches tools or the user.
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Verdict is what should happen to checked content
+type Verdict string
+
+const (
+	// VerdictAllow lets content through unchanged
+	VerdictAllow Verdict = "allow"
+	// VerdictRedact lets content through with the flagged parts replaced
+	VerdictRedact Verdict = "redact"
+	// VerdictBlock stops content
+	VerdictBlock Verdict = "block"
+)
+
+// severity orders verdicts from allow to block
+func (v Verdict) severity() int {
+	switch v {
+	case VerdictRedact:
+		return 1
+	case VerdictBlock:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Direction says whether content goes to a model or comes from one
+type Direction string
+
+const (
+	// DirectionInput is content sent to a model, such as user messages and tool results
+	DirectionInput Direction = "input"
+	// DirectionOutput is content produced by a model, such as replies and tool calls
+	DirectionOutput Direction = "output"
+)
+
+// Finding is something a filter flagged
+type Finding struct {
+	Filter  string  `json:"filter"`
+	Rule    string  `json:"rule"`
+	Verdict Verdict `json:"verdict"`
+	Match   string  `json:"match,omitempty"`  // Flagged text; empty for secrets, which should not be repeated
+	Reason  string  `json:"reason,omitempty"` // Explanation, e.g. from a classifier
+}
+
+// Result is the outcome of checking content
+type Result struct {
+	Verdict  Verdict   // Strictest verdict of the findings
+	Text     string    // Content after redaction
+	Findings []Finding // What the filters flagged, in order
+}
+
+// Blocked reports whether the content must not be used
+func (r Result) Blocked() bool {
+	return r.Verdict == VerdictBlock
+}
+
+// add records findings and raises the verdict to the strictest one
+func (r *Result) add(findings ...Finding) {
+	for _, finding := range findings {
+		r.Findings = append(r.Findings, finding)
+		if finding.Verdict.severity() > r.Verdict.severity() {
+			r.Verdict = finding.Verdict
+		}
+	}
+}
+
+// Filter checks content and returns its verdict and the redacted text
+type Filter interface {
+	Name() string
+	Check(ctx context.Context, text string) (Result, error)
+}
+
+// BlockedError reports content stopped by a guard
+type BlockedError struct {
+	Direction Direction
+	Findings  []Finding
+}
+
+// Error implements the error interface
+func (e *BlockedError) Error() string {
+	reasons := make([]string, 0, len(e.Findings))
+	for _, finding := range e.Findings {
+		if finding.Verdict != VerdictBlock {
+			continue
+		}
+		reason := finding.Filter + "/" + finding.Rule
+		if finding.Reason != "" {
+			reason += ": " + finding.Reason
+		}
+		reasons = append(reasons, reason)
+	}
+	return fmt.Sprintf("%s blocked by guardrails (%s)", e.Direction, strings.Join(reasons, ", "))
+}
+
+// Config configures a Guard
+type Config struct {
+	Input    []Filter // Run on content sent to a model
+	Output   []Filter // Run on content produced by a model
+	FailOpen bool     // Allow content when a filter fails; by default it is blocked
+	// Report is called with each result that has findings, e.g. to keep an audit log
+	Report func(direction Direction, result Result)
+}
+
+// Guard runs filters on content. Each filter sees the text as redacted by the
+// filters before it; checking stops at the first block.
+type Guard struct {
+	config *Config
+}
+
+// New creates a guard
+func New(config *Config) *Guard {
+	if config == nil {
+		config = &Config{}
+	}
+	return &Guard{config: config}
+}
+
+// CheckInput runs the input filters on text
+func (g *Guard) CheckInput(ctx context.Context, text string) Result {
+	return g.Check(ctx, DirectionInput, text)
+}
+
+// CheckOutput runs the output filters on text
+func (g *Guard) CheckOutput(ctx context.Context, text string) Result {
+	return g.Check(ctx, DirectionOutput, text)
+}
+
+// Check runs the filters for direction on text
+func (g *Guard) Check(ctx context.Context, direction Direction, text string) Result {
+	filters := g.config.Input
+	if direction == DirectionOutput {
+		filters = g.config.Output
+	}
+	result := g.check(ctx, filters, text)
+	if len(result.Findings) > 0 && g.config.Report != nil {
+		g.config.Report(direction, result)
+	}
+	return result
+}
+
+// check runs filters in order on text
+func (g *Guard) check(ctx context.Context, filters []Filter, text string) Result {
+	result := Result{Verdict: VerdictAllow, Text: text}
+	if strings.TrimSpace(text) == "" {
+		return result
+	}
+
+	for _, filter := range filters {
+		checked, err := filter.Check(ctx, result.Text)
+		if err != nil {
+			if g.config.FailOpen {
+				continue
+			}
+			result.add(Finding{
+				Filter:  filter.Name(),
+				Rule:    "error",
+				Verdict: VerdictBlock,
+				Reason:  err.Error(),
+			})
+			return result
+		}
+
+		result.add(checked.Findings...)
+		if checked.Verdict == VerdictRedact {
+			result.Text = checked.Text
+		}
+		if result.Blocked() {
+			return result
+		}
+	}
+	return result
+}
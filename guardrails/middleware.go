@@ -0,0 +1,284 @@
+package guardrails
+
+import (
+	"context"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// Walker passes each piece of text in value to check and returns value with
+// the texts check returned; it must not modify value itself
+type Walker[T any] func(value T, check func(text string) string) T
+
+// StringText walks a string
+func StringText(value string, check func(text string) string) string {
+	return check(value)
+}
+
+// MessageText walks a message's content, the string arguments of its tool
+// calls and the content of its tool results. A message with blocks has its
+// blocks walked and its other fields set from them.
+func MessageText(message llm.Message, check func(text string) string) llm.Message {
+	if len(message.Blocks) > 0 {
+		return message.WithBlocks(blocksText(message.Blocks, check)...)
+	}
+	message.Content = check(message.Content)
+	if len(message.ToolCalls) > 0 {
+		calls := make([]llm.ToolCalls, len(message.ToolCalls))
+		for i, call := range message.ToolCalls {
+			call.ToolArgs = walkArgs(call.ToolArgs, check).(map[string]any)
+			calls[i] = call
+		}
+		message.ToolCalls = calls
+	}
+	if len(message.ToolResults) > 0 {
+		results := make([]llm.ToolResults, len(message.ToolResults))
+		for i, result := range message.ToolResults {
+			result.Content = check(result.Content)
+			results[i] = result
+		}
+		message.ToolResults = results
+	}
+	return message
+}
+
+// blocksText walks the text, tool call arguments and tool result content of
+// blocks
+func blocksText(blocks []llm.ContentBlock, check func(text string) string) []llm.ContentBlock {
+	walked := make([]llm.ContentBlock, len(blocks))
+	for i, block := range blocks {
+		switch {
+		case block.Type == llm.BlockText:
+			block.Text = check(block.Text)
+		case block.Type == llm.BlockToolUse && block.ToolCall != nil:
+			call := *block.ToolCall
+			call.ToolArgs = walkArgs(call.ToolArgs, check).(map[string]any)
+			block.ToolCall = &call
+		case block.Type == llm.BlockToolResult && block.ToolResult != nil:
+			result := *block.ToolResult
+			result.Content = check(result.Content)
+			block.ToolResult = &result
+		}
+		walked[i] = block
+	}
+	return walked
+}
+
+// MessagesText walks every message
+func MessagesText(messages []llm.Message, check func(text string) string) []llm.Message {
+	walked := make([]llm.Message, len(messages))
+	for i, message := range messages {
+		walked[i] = MessageText(message, check)
+	}
+	return walked
+}
+
+// RecentMessagesText walks the messages after the last assistant message: the
+// new user input and tool results of a turn. Earlier messages were checked on
+// previous turns, so they are not sent to the filters again.
+func RecentMessagesText(messages []llm.Message, check func(text string) string) []llm.Message {
+	start := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == llm.RoleAssistant {
+			start = i + 1
+			break
+		}
+	}
+	walked := make([]llm.Message, len(messages))
+	copy(walked, messages[:start])
+	for i := start; i < len(messages); i++ {
+		walked[i] = MessageText(messages[i], check)
+	}
+	return walked
+}
+
+// walkArgs walks the strings in decoded JSON arguments, copying maps and slices
+func walkArgs(value any, check func(text string) string) any {
+	switch v := value.(type) {
+	case string:
+		return check(v)
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		walked := make(map[string]any, len(v))
+		for key, item := range v {
+			walked[key] = walkArgs(item, check)
+		}
+		return walked
+	case []any:
+		walked := make([]any, len(v))
+		for i, item := range v {
+			walked[i] = walkArgs(item, check)
+		}
+		return walked
+	default:
+		return value
+	}
+}
+
+// Apply checks every text of value in direction and returns value with the
+// redactions applied, or a *BlockedError when a filter blocks any of the texts
+func Apply[T any](ctx context.Context, guard *Guard, direction Direction, walk Walker[T], value T) (T, error) {
+	var findings []Finding
+	blocked := false
+	walked := walk(value, func(text string) string {
+		if blocked {
+			return text
+		}
+		result := guard.Check(ctx, direction, text)
+		findings = append(findings, result.Findings...)
+		if result.Blocked() {
+			blocked = true
+			return text
+		}
+		return result.Text
+	})
+	if blocked {
+		return value, &BlockedError{Direction: direction, Findings: findings}
+	}
+	return walked, nil
+}
+
+// Provider wraps an LLM provider with a guard. The new messages of each call
+// are checked by the input filters and the response by the output filters, so
+// tool calls are checked before they reach tools. Blocked calls return a
+// *BlockedError. Redactions apply to what is sent and returned; the caller's
+// messages are not modified.
+type Provider struct {
+	llm.LLMProvider
+	guard *Guard
+}
+
+// NewProvider wraps provider with guard
+func NewProvider(provider llm.LLMProvider, guard *Guard) *Provider {
+	return &Provider{LLMProvider: provider, guard: guard}
+}
+
+// CallLLM checks the new messages, calls the wrapped provider and checks its response
+func (p *Provider) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	checked, err := Apply(ctx, p.guard, DirectionInput, RecentMessagesText, messages)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	response, err := p.LLMProvider.CallLLM(ctx, checked)
+	if err != nil {
+		return response, err
+	}
+	return Apply(ctx, p.guard, DirectionOutput, MessageText, response)
+}
+
+// NodeConfig says where a guarded node finds the text of its work items and results
+type NodeConfig[P any, E any] struct {
+	Input  Walker[P] // Text of work items, checked by the input filters before Exec; nil skips the check
+	Output Walker[E] // Text of results, checked by the output filters after Exec; nil skips the check
+	// OnBlock returns the result used in place of blocked content, e.g. a refusal
+	// message. When nil, Exec fails with the *BlockedError, so the node retries
+	// and then falls back to ExecFallback.
+	OnBlock func(err *BlockedError) E
+}
+
+// GuardedNode wraps a node so its work items and results pass through a guard
+type GuardedNode[S any, P any, E any] struct {
+	node   core.BaseNode[S, P, E]
+	guard  *Guard
+	config *NodeConfig[P, E]
+}
+
+// NewGuardedNode wraps node with guard; wrap the result with core.NewNode as usual
+func NewGuardedNode[S any, P any, E any](node core.BaseNode[S, P, E], guard *Guard, config *NodeConfig[P, E]) *GuardedNode[S, P, E] {
+	if config == nil {
+		config = &NodeConfig[P, E]{}
+	}
+	return &GuardedNode[S, P, E]{node: node, guard: guard, config: config}
+}
+
+// Prep delegates to the wrapped node
+func (n *GuardedNode[S, P, E]) Prep(state *S) []P {
+	return n.node.Prep(state)
+}
+
+// Name returns the name of the wrapped node, so steps are reported under it
+func (n *GuardedNode[S, P, E]) Name() string {
+	return core.NodeName(n.node)
+}
+
+// Labels implements core.Labeler with the wrapped node's labels
+func (n *GuardedNode[S, P, E]) Labels(state *S) core.Labels {
+	if labeler, ok := n.node.(core.Labeler[S]); ok {
+		return labeler.Labels(state)
+	}
+	return nil
+}
+
+// DescribeItem implements core.ItemDescriber with the wrapped node's descriptions
+func (n *GuardedNode[S, P, E]) DescribeItem(item P) string {
+	if describer, ok := n.node.(core.ItemDescriber[P]); ok {
+		return describer.DescribeItem(item)
+	}
+	return ""
+}
+
+// Exec runs an item without the context of the run
+func (n *GuardedNode[S, P, E]) Exec(item P) (E, error) {
+	return n.ExecContext(context.Background(), item)
+}
+
+// ExecContext checks the work item, runs the wrapped node with the context
+// of the run and checks its result
+func (n *GuardedNode[S, P, E]) ExecContext(ctx context.Context, item P) (E, error) {
+	if n.config.Input != nil {
+		checked, err := Apply(ctx, n.guard, DirectionInput, n.config.Input, item)
+		if err != nil {
+			return n.blocked(err)
+		}
+		item = checked
+	}
+
+	var result E
+	var err error
+	if executor, ok := n.node.(core.ContextExecutor[P, E]); ok {
+		result, err = executor.ExecContext(ctx, item)
+	} else {
+		result, err = n.node.Exec(item)
+	}
+	if err != nil || n.config.Output == nil {
+		return result, err
+	}
+	checked, err := Apply(ctx, n.guard, DirectionOutput, n.config.Output, result)
+	if err != nil {
+		return n.blocked(err)
+	}
+	return checked, nil
+}
+
+// blocked returns the OnBlock result or the error
+func (n *GuardedNode[S, P, E]) blocked(err error) (E, error) {
+	if n.config.OnBlock != nil {
+		if blocked, ok := err.(*BlockedError); ok {
+			return n.config.OnBlock(blocked), nil
+		}
+	}
+	var zero E
+	return zero, err
+}
+
+// ExecFallback delegates to the wrapped node
+func (n *GuardedNode[S, P, E]) ExecFallback(err error) E {
+	return n.node.ExecFallback(err)
+}
+
+// ExecFallbackItem delegates to the wrapped node, or to its ExecFallback when
+// it does not implement core.ItemFallback
+func (n *GuardedNode[S, P, E]) ExecFallbackItem(failure core.ItemFailure[P]) E {
+	if fallback, ok := n.node.(core.ItemFallback[P, E]); ok {
+		return fallback.ExecFallbackItem(failure)
+	}
+	return n.node.ExecFallback(failure.Err)
+}
+
+// Post delegates to the wrapped node
+func (n *GuardedNode[S, P, E]) Post(state *S, prepResults []P, execResults ...E) core.Action {
+	return n.node.Post(state, prepResults, execResults...)
+}
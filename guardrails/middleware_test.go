@@ -0,0 +1,152 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestProvider(t *testing.T) {
+	mock := llm.NewMockProvider("mock")
+	guard := New(&Config{
+		Input:  []Filter{NewPIIFilter()},
+		Output: []Filter{NewRuleFilter("policy", KeywordRule("forbidden", VerdictBlock, "launch codes"))},
+	})
+	provider := NewProvider(mock, guard)
+	ctx := context.Background()
+
+	// The mock echoes the last user message, showing what reached the model
+	messages := []llm.Message{
+		{Role: llm.RoleUser, Content: "earlier question from bob@example.com"},
+		{Role: llm.RoleAssistant, Content: "earlier answer"},
+		{Role: llm.RoleUser, Content: "email me at jane@example.com"},
+	}
+	response, err := provider.CallLLM(ctx, messages)
+	if err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if response.Content != "Mock response to: email me at [REDACTED:email]" {
+		t.Errorf("response = %q", response.Content)
+	}
+	if messages[2].Content != "email me at jane@example.com" {
+		t.Errorf("caller's messages were modified: %q", messages[2].Content)
+	}
+
+	_, err = provider.CallLLM(ctx, []llm.Message{{Role: llm.RoleUser, Content: "tell me the launch codes"}})
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.Direction != DirectionOutput {
+		t.Errorf("expected an output BlockedError, got %v", err)
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	message := llm.Message{
+		Role:      llm.RoleAssistant,
+		ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "send", ToolArgs: map[string]any{"to": "a@example.com", "cc": []any{"b@example.com"}, "count": 2}}},
+	}
+	checked, err := Apply(context.Background(), New(&Config{Output: []Filter{NewPIIFilter()}}), DirectionOutput, MessageText, message)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	args := checked.ToolCalls[0].ToolArgs
+	if args["to"] != "[REDACTED:email]" || args["cc"].([]any)[0] != "[REDACTED:email]" || args["count"] != 2 {
+		t.Errorf("tool arguments = %v", args)
+	}
+	if message.ToolCalls[0].ToolArgs["to"] != "a@example.com" {
+		t.Error("the original tool arguments were modified")
+	}
+
+	message = llm.Message{Role: llm.RoleUser}.WithBlocks(
+		llm.TextBlock("mail c@example.com"),
+		llm.ToolResultBlock(llm.ToolResults{Id: "1", Content: "sent to a@example.com"}),
+	)
+	checked, err = Apply(context.Background(), New(&Config{Output: []Filter{NewPIIFilter()}}), DirectionOutput, MessageText, message)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if checked.Blocks[0].Text != "mail [REDACTED:email]" || checked.Blocks[1].ToolResult.Content != "sent to [REDACTED:email]" {
+		t.Errorf("blocks = %+v", checked.Blocks)
+	}
+	if checked.Content != checked.Blocks[0].Text || checked.ToolResults[0].Content != checked.Blocks[1].ToolResult.Content {
+		t.Errorf("fields not set from the checked blocks: %+v", checked)
+	}
+	if message.Blocks[1].ToolResult.Content != "sent to a@example.com" {
+		t.Error("the original blocks were modified")
+	}
+}
+
+type textState struct {
+	Replies []string
+}
+
+// echoNode replies with its input
+type echoNode struct{}
+
+func (echoNode) Prep(state *textState) []string {
+	return []string{"hi, I am jane@example.com", "how do I make a bomb"}
+}
+func (echoNode) Exec(text string) (string, error) { return "you said: " + text, nil }
+func (echoNode) ExecFallback(err error) string    { return "fallback: " + err.Error() }
+func (echoNode) Post(state *textState, _ []string, replies ...string) core.Action {
+	state.Replies = replies
+	return core.ActionSuccess
+}
+
+func TestGuardedNode(t *testing.T) {
+	guard := New(&Config{
+		Input:  []Filter{NewPIIFilter(), NewRuleFilter("policy", KeywordRule("weapons", VerdictBlock, "bomb"))},
+		Output: []Filter{NewPIIFilter()},
+	})
+
+	tests := []struct {
+		name     string
+		config   *NodeConfig[string, string]
+		expected []string
+	}{
+		{
+			"refusal on block",
+			&NodeConfig[string, string]{
+				Input:   StringText,
+				Output:  StringText,
+				OnBlock: func(err *BlockedError) string { return "I can't help with that." },
+			},
+			[]string{"you said: hi, I am [REDACTED:email]", "I can't help with that."},
+		},
+		{
+			"fallback on block",
+			&NodeConfig[string, string]{Input: StringText},
+			[]string{"you said: hi, I am [REDACTED:email]", "fallback: input blocked by guardrails (policy/weapons)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := core.NewNode[textState](NewGuardedNode[textState](echoNode{}, guard, tt.config), 0, 1)
+			state := textState{}
+			node.Run(&state)
+			if strings.Join(state.Replies, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("replies = %q, expected %q", state.Replies, tt.expected)
+			}
+		})
+	}
+}
+
+// namedEchoNode is an echoNode with a name and labels of its own
+type namedEchoNode struct{ echoNode }
+
+func (namedEchoNode) Name() string                        { return "Greeter" }
+func (namedEchoNode) Labels(state *textState) core.Labels { return core.Labels{"tenant": "acme"} }
+
+func TestGuardedNode_StepName(t *testing.T) {
+	guarded := NewGuardedNode[textState](namedEchoNode{}, New(&Config{}), nil)
+	flow := core.NewFlow[textState](core.NewNode[textState](guarded, 0, 1))
+
+	var events []core.StepEvent
+	flow.RunObserved(&textState{}, func(event core.StepEvent) { events = append(events, event) })
+	if len(events) != 1 || events[0].Name != "Greeter" || events[0].Labels["tenant"] != "acme" {
+		t.Errorf("steps = %+v, want one Greeter step labeled with the tenant", events)
+	}
+}
@@ -0,0 +1,82 @@
+package guardrails
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Rule flags text matching a pattern
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Verdict     Verdict                 // VerdictRedact replaces matches, VerdictBlock stops the content
+	Replacement string                  // Replaces redacted matches; empty uses "[REDACTED:<name>]"
+	Validate    func(match string) bool // Optional check that a match is real, e.g. a card checksum
+	Secret      bool                    // Leave matches out of findings, so they are not logged
+}
+
+// KeywordRule matches any of the keywords as whole words, ignoring case
+func KeywordRule(name string, verdict Verdict, keywords ...string) Rule {
+	quoted := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		quoted[i] = regexp.QuoteMeta(keyword)
+	}
+	return Rule{
+		Name:    name,
+		Pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		Verdict: verdict,
+	}
+}
+
+// RuleFilter applies rules in order
+type RuleFilter struct {
+	name  string
+	rules []Rule
+}
+
+// NewRuleFilter creates a filter from rules
+func NewRuleFilter(name string, rules ...Rule) *RuleFilter {
+	return &RuleFilter{name: name, rules: rules}
+}
+
+// Name returns the filter name
+func (f *RuleFilter) Name() string {
+	return f.name
+}
+
+// Rules returns the filter's rules
+func (f *RuleFilter) Rules() []Rule {
+	return f.rules
+}
+
+// Check flags the matches of each rule, redacting them or stopping at the first blocking rule
+func (f *RuleFilter) Check(ctx context.Context, text string) (Result, error) {
+	result := Result{Verdict: VerdictAllow, Text: text}
+	for _, rule := range f.rules {
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED:" + rule.Name + "]"
+		}
+
+		result.Text = rule.Pattern.ReplaceAllStringFunc(result.Text, func(match string) string {
+			if rule.Validate != nil && !rule.Validate(match) {
+				return match
+			}
+			finding := Finding{Filter: f.name, Rule: rule.Name, Verdict: rule.Verdict}
+			if !rule.Secret {
+				finding.Match = match
+			}
+			result.add(finding)
+			if rule.Verdict == VerdictRedact {
+				return replacement
+			}
+			return match
+		})
+		if result.Blocked() {
+			result.Text = text
+			return result, nil
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,360 @@
+// Package hotreload watches a config file and applies its changes to a
+// running program. Each setting that can change safely at runtime gets a
+// handler; changes to any other setting are reported as needing a restart.
+// Changes are staged when they are detected and applied when the program calls
+// ApplyPending, e.g. from a core/nodes.ReloadNode between the steps of a flow,
+// so a handler never runs in the middle of an LLM call or a tool call.
+package hotreload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventKind is the kind of an Event
+type EventKind string
+
+const (
+	// EventApplied reports changes that were applied by their handlers
+	EventApplied EventKind = "applied"
+	// EventRestartRequired reports changes that have no handler and take effect after a restart
+	EventRestartRequired EventKind = "restart_required"
+	// EventFailed reports a config file that could not be loaded, or a handler that failed
+	EventFailed EventKind = "failed"
+)
+
+// Event reports what happened to a change of the config file. It names the
+// changed settings but not their values, which may be secrets.
+type Event struct {
+	Kind  EventKind `json:"kind"`
+	Paths []string  `json:"paths,omitempty"` // Changed settings, e.g. "llm.model"
+	Err   error     `json:"-"`
+	Time  time.Time `json:"time"`
+}
+
+// String describes the event for logs
+func (e Event) String() string {
+	paths := strings.Join(e.Paths, ", ")
+	switch e.Kind {
+	case EventApplied:
+		return "config change applied: " + paths
+	case EventRestartRequired:
+		return "config change needs a restart: " + paths
+	}
+	if paths == "" {
+		return fmt.Sprintf("config reload failed: %v", e.Err)
+	}
+	return fmt.Sprintf("config change to %s failed: %v", paths, e.Err)
+}
+
+// Change is a setting that differs between two configs. Paths join the JSON
+// names of nested objects with dots; arrays are compared as a whole.
+type Change struct {
+	Path string
+	Old  any // JSON value before the change; nil when the setting was added
+	New  any // JSON value after the change; nil when the setting was removed
+}
+
+// Handler applies the changes under its prefix to the running program
+type Handler[C any] func(ctx context.Context, old, new C, changes []Change) error
+
+// Config configures a Watcher
+type Config struct {
+	Interval      time.Duration // How often the file is checked; default 2s
+	ApplyOnChange bool          // Apply changes as soon as they are detected, for handlers that are safe to run at any time
+	OnEvent       func(Event)   // Receives every event; nil ignores them
+}
+
+// DefaultConfig checks the file every two seconds and stages changes until ApplyPending
+func DefaultConfig() *Config {
+	return &Config{Interval: 2 * time.Second}
+}
+
+// handler is a registered Handler with its path prefix
+type handler[C any] struct {
+	prefix string
+	apply  Handler[C]
+}
+
+// Watcher reloads a config file when it changes
+type Watcher[C any] struct {
+	path   string
+	load   func(path string) (C, error)
+	config Config
+
+	applyMu  sync.Mutex // Serializes ApplyPending, which runs handlers without holding mu
+	mu       sync.Mutex
+	handlers []handler[C]
+	applied  C    // Config the handlers have applied
+	loaded   C    // Latest config read from the file
+	pending  bool // Whether loaded has changes not applied yet
+	modTime  time.Time
+	size     int64
+}
+
+// NewWatcher loads the config file with load and watches it; a nil config uses DefaultConfig
+func NewWatcher[C any](path string, load func(path string) (C, error), config *Config) (*Watcher[C], error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	w := &Watcher[C]{path: path, load: load, config: *config}
+	if w.config.Interval <= 0 {
+		w.config.Interval = 2 * time.Second
+	}
+	if w.config.OnEvent == nil {
+		w.config.OnEvent = func(Event) {}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	current, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	w.applied, w.loaded = current, current
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return w, nil
+}
+
+// Handle registers apply for the settings at prefix and below, e.g. "llm.model"
+// or "mcp.servers". A change goes to the handler with the longest matching prefix.
+func (w *Watcher[C]) Handle(prefix string, apply Handler[C]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, handler[C]{prefix: prefix, apply: apply})
+	sort.SliceStable(w.handlers, func(i, j int) bool { return len(w.handlers[i].prefix) > len(w.handlers[j].prefix) })
+}
+
+// Current returns the config as of the last applied change. Settings that need
+// a restart may differ from the values the program started with.
+func (w *Watcher[C]) Current() C {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.applied
+}
+
+// Run checks the file every Interval until ctx is done
+func (w *Watcher[C]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check reloads the file if it changed since the last check, reports the
+// changes that need a restart and stages the others. It reports whether
+// changes are pending.
+func (w *Watcher[C]) Check(ctx context.Context) bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.emit(Event{Kind: EventFailed, Err: err})
+		return w.hasPending()
+	}
+
+	w.mu.Lock()
+	unchanged := info.ModTime().Equal(w.modTime) && info.Size() == w.size
+	w.mu.Unlock()
+	if unchanged {
+		return w.hasPending()
+	}
+
+	next, err := w.load(w.path)
+	w.mu.Lock()
+	w.modTime, w.size = info.ModTime(), info.Size()
+	if err != nil {
+		w.mu.Unlock()
+		// The last valid config stays in effect
+		w.emit(Event{Kind: EventFailed, Err: err})
+		return w.hasPending()
+	}
+	changes, err := Diff(w.loaded, next)
+	if err != nil {
+		w.mu.Unlock()
+		w.emit(Event{Kind: EventFailed, Err: err})
+		return w.hasPending()
+	}
+	var restart []string
+	handled := false
+	for _, change := range changes {
+		if w.handlerFor(change.Path) == nil {
+			restart = append(restart, change.Path)
+		} else {
+			handled = true
+		}
+	}
+	w.loaded = next
+	w.pending = w.pending || handled
+	w.mu.Unlock()
+
+	if len(restart) > 0 {
+		w.emit(Event{Kind: EventRestartRequired, Paths: restart})
+	}
+	if handled && w.config.ApplyOnChange {
+		w.ApplyPending(ctx)
+	}
+	return w.hasPending()
+}
+
+// ApplyPending runs the handlers of the staged changes. A failed handler is
+// reported and not retried until its settings change again. The returned error
+// joins the handler errors.
+func (w *Watcher[C]) ApplyPending(ctx context.Context) error {
+	w.applyMu.Lock()
+	defer w.applyMu.Unlock()
+
+	w.mu.Lock()
+	if !w.pending {
+		w.mu.Unlock()
+		return nil
+	}
+	w.pending = false
+	old, next := w.applied, w.loaded
+	changes, err := Diff(old, next)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	// Group the changes by handler, in the order of their first change
+	var order []*handler[C]
+	groups := make(map[*handler[C]][]Change)
+	for _, change := range changes {
+		h := w.handlerFor(change.Path)
+		if h == nil {
+			continue
+		}
+		if _, ok := groups[h]; !ok {
+			order = append(order, h)
+		}
+		groups[h] = append(groups[h], change)
+	}
+	w.mu.Unlock()
+
+	var applied []string
+	var failures []error
+	for _, h := range order {
+		paths := changePaths(groups[h])
+		if err := h.apply(ctx, old, next, groups[h]); err != nil {
+			w.emit(Event{Kind: EventFailed, Paths: paths, Err: err})
+			failures = append(failures, fmt.Errorf("%s: %w", h.prefix, err))
+			continue
+		}
+		applied = append(applied, paths...)
+	}
+
+	w.mu.Lock()
+	w.applied = next
+	w.mu.Unlock()
+	if len(applied) > 0 {
+		w.emit(Event{Kind: EventApplied, Paths: applied})
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to apply config changes: %w", errors.Join(failures...))
+	}
+	return nil
+}
+
+// handlerFor returns the handler with the longest prefix matching path; w.mu must be held
+func (w *Watcher[C]) handlerFor(path string) *handler[C] {
+	for i := range w.handlers {
+		prefix := w.handlers[i].prefix
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return &w.handlers[i]
+		}
+	}
+	return nil
+}
+
+// hasPending reports whether changes are staged
+func (w *Watcher[C]) hasPending() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pending
+}
+
+// emit stamps and sends an event
+func (w *Watcher[C]) emit(event Event) {
+	event.Time = time.Now()
+	w.config.OnEvent(event)
+}
+
+// Diff returns the settings that differ between two configs, sorted by path,
+// comparing their JSON encodings
+func Diff[C any](old, new C) ([]Change, error) {
+	oldValues, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flatten(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for path, value := range oldValues {
+		if next, ok := newValues[path]; !ok || !reflect.DeepEqual(value, next) {
+			changes = append(changes, Change{Path: path, Old: value, New: next})
+		}
+	}
+	for path, value := range newValues {
+		if _, ok := oldValues[path]; !ok {
+			changes = append(changes, Change{Path: path, New: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flatten returns the leaf values of a config's JSON encoding by path
+func flatten(config any) (map[string]any, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	values := make(map[string]any)
+	var walk func(path string, value any)
+	walk = func(path string, value any) {
+		object, ok := value.(map[string]any)
+		if !ok || (len(object) == 0 && path != "") {
+			values[path] = value
+			return
+		}
+		for key, item := range object {
+			if path == "" {
+				walk(key, item)
+			} else {
+				walk(path+"."+key, item)
+			}
+		}
+	}
+	walk("", document)
+	return values, nil
+}
+
+// changePaths returns the paths of changes
+func changePaths(changes []Change) []string {
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	return paths
+}
@@ -0,0 +1,182 @@
+package hotreload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	LLM struct {
+		Model       string  `json:"model"`
+		Temperature float32 `json:"temperature"`
+		APIKey      string  `json:"api_key"`
+	} `json:"llm"`
+	AllowedTools []string          `json:"allowed_tools,omitempty"`
+	Servers      map[string]string `json:"servers,omitempty"`
+}
+
+func loadTestConfig(path string) (testConfig, error) {
+	var config testConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	return config, json.Unmarshal(data, &config)
+}
+
+// writeConfig writes the file and moves its modification time forward, so
+// the change is seen even on filesystems with coarse timestamps
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var old, new testConfig
+	old.LLM.Model, new.LLM.Model = "gpt-4o", "gpt-4o-mini"
+	old.LLM.Temperature, new.LLM.Temperature = 0.2, 0.2
+	old.AllowedTools, new.AllowedTools = []string{"search"}, []string{"search", "read_file"}
+	new.Servers = map[string]string{"files": "npx files"}
+
+	changes, err := Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Path: "allowed_tools", Old: []any{"search"}, New: []any{"search", "read_file"}},
+		{Path: "llm.model", Old: "gpt-4o", New: "gpt-4o-mini"},
+		{Path: "servers.files", New: "npx files"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, path, `{"llm": {"model": "gpt-4o", "temperature": 0.2, "api_key": "sk-1"}}`)
+
+	var events []Event
+	watcher, err := NewWatcher(path, loadTestConfig, &Config{OnEvent: func(e Event) { events = append(events, e) }})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	var model string
+	var calls int
+	watcher.Handle("llm.model", func(ctx context.Context, old, new testConfig, changes []Change) error {
+		calls++
+		if new.LLM.Model == "unknown" {
+			return errors.New("unknown model")
+		}
+		model = new.LLM.Model
+		return nil
+	})
+	watcher.Handle("llm.temperature", func(ctx context.Context, old, new testConfig, changes []Change) error {
+		return nil
+	})
+	ctx := context.Background()
+
+	// Unchanged file
+	if watcher.Check(ctx) || len(events) != 0 {
+		t.Fatalf("Check of an unchanged file: events %v", events)
+	}
+
+	// Handled changes are staged until ApplyPending; others need a restart
+	writeConfig(t, path, `{"llm": {"model": "gpt-4o-mini", "temperature": 0.5, "api_key": "sk-2"}}`)
+	if !watcher.Check(ctx) {
+		t.Fatal("Check should stage the model and temperature change")
+	}
+	if model != "" {
+		t.Error("handler ran before ApplyPending")
+	}
+	if len(events) != 1 || events[0].Kind != EventRestartRequired || !reflect.DeepEqual(events[0].Paths, []string{"llm.api_key"}) {
+		t.Fatalf("events = %v, want a restart for llm.api_key", events)
+	}
+	if strings.Contains(events[0].String(), "sk-") {
+		t.Errorf("event leaks a value: %s", events[0])
+	}
+	if err := watcher.ApplyPending(ctx); err != nil {
+		t.Fatalf("ApplyPending failed: %v", err)
+	}
+	if model != "gpt-4o-mini" || watcher.Current().LLM.Temperature != 0.5 {
+		t.Errorf("model = %q, current = %+v", model, watcher.Current())
+	}
+	if last := events[len(events)-1]; last.Kind != EventApplied || !reflect.DeepEqual(last.Paths, []string{"llm.model", "llm.temperature"}) {
+		t.Errorf("event = %v, want llm.model and llm.temperature applied", last)
+	}
+	if err := watcher.ApplyPending(ctx); err != nil || calls != 1 {
+		t.Errorf("second ApplyPending = %v after %d calls, want a no-op", err, calls)
+	}
+
+	// A failed handler is reported and not retried
+	events = nil
+	writeConfig(t, path, `{"llm": {"model": "unknown", "temperature": 0.5, "api_key": "sk-2"}}`)
+	watcher.Check(ctx)
+	if err := watcher.ApplyPending(ctx); err == nil || !strings.Contains(err.Error(), "unknown model") {
+		t.Errorf("ApplyPending error = %v, want unknown model", err)
+	}
+	if len(events) != 1 || events[0].Kind != EventFailed {
+		t.Errorf("events = %v, want one failure", events)
+	}
+	if watcher.Check(ctx) || watcher.ApplyPending(ctx) != nil || calls != 2 {
+		t.Errorf("failed change was retried: %d calls", calls)
+	}
+
+	// An invalid file keeps the last config
+	events = nil
+	writeConfig(t, path, `{"llm": `)
+	if watcher.Check(ctx) || len(events) != 1 || events[0].Kind != EventFailed {
+		t.Errorf("events = %v, want one failure", events)
+	}
+	writeConfig(t, path, `{"llm": {"model": "gpt-4o", "temperature": 0.5, "api_key": "sk-2"}}`)
+	watcher.Check(ctx)
+	if err := watcher.ApplyPending(ctx); err != nil || model != "gpt-4o" {
+		t.Errorf("ApplyPending after a fix = %v, model %q", err, model)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, path, `{"llm": {"model": "gpt-4o"}}`)
+
+	applied := make(chan string, 1)
+	watcher, err := NewWatcher(path, loadTestConfig, &Config{Interval: 10 * time.Millisecond, ApplyOnChange: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.Handle("llm", func(ctx context.Context, old, new testConfig, changes []Change) error {
+		applied <- new.LLM.Model
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	writeConfig(t, path, `{"llm": {"model": "gpt-4o-mini"}}`)
+	select {
+	case model := <-applied:
+		if model != "gpt-4o-mini" {
+			t.Errorf("applied model %q", model)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not applied")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package flowtest holds a counting flow for the tests of the packages that
+// serve, checkpoint and resume flows
+package flowtest
+
+import "github.com/alt-coder/pocketflow-go/core"
+
+// Counter is the state of the counting flow. Packages whose states must
+// implement an interface of their own embed it.
+type Counter struct {
+	Count int `json:"count"`
+	Limit int `json:"limit"`
+
+	// Emit, if set, receives a "count" event after each step
+	Emit func(event string, data any) `json:"-"`
+}
+
+// Counts returns the counter, so IncrementNode can work on states embedding it
+func (c *Counter) Counts() *Counter { return c }
+
+// Counted is a state IncrementNode can count in
+type Counted interface {
+	Counts() *Counter
+}
+
+// IncrementNode adds one to the count until the limit is reached
+type IncrementNode[S Counted] struct {
+	CrashAt int // Panic when the count would reach this, to interrupt runs; 0 never
+}
+
+// NewFlow returns a flow looping node until the count reaches the limit
+func NewFlow[S Counted](node *IncrementNode[S]) *core.Flow[S] {
+	looped := core.NewNode[S](node, 0, 1)
+	looped.AddSuccessor(looped, core.ActionContinue)
+	return core.NewFlow[S](looped)
+}
+
+func (n *IncrementNode[S]) Prep(state *S) []int         { return []int{(*state).Counts().Count} }
+func (n *IncrementNode[S]) Exec(count int) (int, error) { return count + 1, nil }
+func (n *IncrementNode[S]) ExecFallback(err error) int  { return 0 }
+func (n *IncrementNode[S]) Post(state *S, _ []int, results ...int) core.Action {
+	if n.CrashAt != 0 && results[0] == n.CrashAt {
+		panic("interrupted")
+	}
+	counter := (*state).Counts()
+	counter.Count = results[0]
+	if counter.Emit != nil {
+		counter.Emit("count", map[string]int{"count": results[0]})
+	}
+	if counter.Count >= counter.Limit {
+		return core.ActionSuccess
+	}
+	return core.ActionContinue
+}
@@ -0,0 +1,73 @@
+// Package inflight counts the work a component has in flight, so that its
+// Shutdown can stop taking new work and wait for what is running
+package inflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Tracker counts calls in flight; the zero value is ready to use
+type Tracker struct {
+	mu       sync.Mutex
+	stopping atomic.Bool
+	active   int
+	idle     chan struct{} // Closed when the last call ends after Shutdown started
+}
+
+// Start records a new call, or reports false once Shutdown has started. Every
+// call that was started must be ended with Done.
+func (t *Tracker) Start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopping.Load() {
+		return false
+	}
+	t.active++
+	return true
+}
+
+// Done records the end of a call
+func (t *Tracker) Done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.active == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// Stopping reports whether Shutdown has started, for long calls that can stop
+// early, e.g. between the steps of a flow
+func (t *Tracker) Stopping() bool {
+	return t.stopping.Load()
+}
+
+// Shutdown stops new calls from starting and waits for the calls in flight
+// until ctx is done
+func (t *Tracker) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	t.stopping.Store(true)
+	if t.active == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		active := t.active
+		t.mu.Unlock()
+		return fmt.Errorf("%d calls still in flight: %w", active, ctx.Err())
+	}
+}
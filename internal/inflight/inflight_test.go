@@ -0,0 +1,47 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTracker_Shutdown(t *testing.T) {
+	var tracker Tracker
+	if !tracker.Start() {
+		t.Fatal("Start() refused a call before Shutdown")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- tracker.Shutdown(context.Background()) }()
+	for !tracker.Stopping() {
+		time.Sleep(time.Millisecond)
+	}
+	if tracker.Start() {
+		t.Error("Start() accepted a call after Shutdown")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown() returned %v with a call in flight", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	tracker.Done()
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+	if err := tracker.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() = %v", err)
+	}
+}
+
+func TestTracker_ShutdownDeadline(t *testing.T) {
+	var tracker Tracker
+	tracker.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tracker.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want DeadlineExceeded", err)
+	}
+}
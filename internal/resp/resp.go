@@ -0,0 +1,199 @@
+// Package resp is a small client for the Redis serialization protocol, shared
+// by the packages that keep data in Redis without a client library
+package resp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures a Client
+type Config struct {
+	Addr        string        // host:port; default "localhost:6379"
+	Username    string        // ACL user; empty authenticates with the password only
+	Password    string        // Sent with AUTH when set
+	DB          int           // Database selected after connecting
+	TLS         *tls.Config   // Connects with TLS when set
+	DialTimeout time.Duration // Default 10s
+}
+
+// Error is an error reply from Redis
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client runs commands on a small pool of connections
+type Client struct {
+	config Config
+	idle   chan *conn
+}
+
+// NewClient creates a client; connections are dialed when first needed
+func NewClient(config Config) *Client {
+	if config.Addr == "" {
+		config.Addr = "localhost:6379"
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	return &Client{config: config, idle: make(chan *conn, 16)}
+}
+
+// Do runs a command and returns its reply: a string, an int64, a []any, nil
+// or an Error. The connection is closed when ctx is done before the reply
+// arrives, which interrupts blocking commands.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { cn.conn.Close() })
+	reply, err := cn.do(args...)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		cn.conn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the idle connections
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// get returns an idle connection or dials a new one
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: c.config.DialTimeout}
+	var nc net.Conn
+	var err error
+	if c.config.TLS != nil {
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: c.config.TLS}).DialContext(ctx, "tcp", c.config.Addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", c.config.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	cn := &conn{conn: nc, reader: bufio.NewReader(nc)}
+	var setup [][]string
+	if c.config.Password != "" {
+		if c.config.Username != "" {
+			setup = append(setup, []string{"AUTH", c.config.Username, c.config.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.config.Password})
+		}
+	}
+	if c.config.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.config.DB)})
+	}
+	for _, command := range setup {
+		if _, err := cn.do(command...); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("redis %s failed: %w", command[0], err)
+		}
+	}
+	return cn, nil
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.conn.Close()
+	}
+}
+
+// conn speaks the protocol on one connection
+type conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// do sends a command and reads its reply
+func (c *conn) do(args ...string) (any, error) {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, command.String()); err != nil {
+		return nil, err
+	}
+	return ReadReply(c.reader)
+}
+
+// ReadReply reads one reply, or one command sent by a client
+func ReadReply(reader *bufio.Reader) (any, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = ReadReply(reader); err != nil {
+				var replyErr Error
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected redis reply %q", line)
+}
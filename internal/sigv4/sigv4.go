@@ -0,0 +1,112 @@
+// Package sigv4 signs HTTP requests with AWS Signature Version 4, for the
+// packages that call AWS APIs without the AWS SDK
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Credentials are the AWS credentials requests are signed with
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary credentials
+}
+
+// EnvCredentials reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+func EnvCredentials() Credentials {
+	return Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Sign adds the X-Amz-Date, security token and Authorization headers to request,
+// whose body is payload
+func Sign(request *http.Request, payload []byte, credentials Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	request.Header.Set("Host", request.URL.Host)
+
+	names := make([]string, 0, len(request.Header))
+	headers := make(map[string]string, len(request.Header))
+	for name, values := range request.Header {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		headers[lower] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := request.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			canonicalQuery = append(canonicalQuery, escape(key)+"="+escape(value))
+		}
+	}
+
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escape percent-encodes everything except the unreserved characters, as SigV4 requires
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
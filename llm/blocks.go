@@ -0,0 +1,137 @@
+package llm
+
+import "strings"
+
+// BlockType is the kind of a ContentBlock
+type BlockType string
+
+const (
+	BlockText       BlockType = "text"        // Text written by or for the model
+	BlockImage      BlockType = "image"       // Media with its MIME type, e.g. an image/png screenshot
+	BlockToolUse    BlockType = "tool_use"    // A tool call of the model
+	BlockToolResult BlockType = "tool_result" // The result of a tool call
+	BlockThinking   BlockType = "thinking"    // Thinking of a reasoning model, never sent back to the model
+)
+
+// ContentBlock is one part of the content of a message, as modern provider
+// APIs send it: a message is a list of blocks in the order they were written,
+// so text can surround several images or tool calls
+type ContentBlock struct {
+	Type       BlockType
+	Text       string       `json:",omitempty"` // Text of text and thinking blocks
+	MimeType   string       `json:",omitempty"` // MIME type of image blocks
+	Data       []byte       `json:",omitempty"` // Media of image blocks
+	ToolCall   *ToolCalls   `json:",omitempty"` // Call of tool_use blocks
+	ToolResult *ToolResults `json:",omitempty"` // Result of tool_result blocks
+}
+
+// TextBlock returns a text block
+func TextBlock(text string) ContentBlock {
+	return ContentBlock{Type: BlockText, Text: text}
+}
+
+// ImageBlock returns an image block of data with its MIME type
+func ImageBlock(mimeType string, data []byte) ContentBlock {
+	return ContentBlock{Type: BlockImage, MimeType: mimeType, Data: data}
+}
+
+// ToolUseBlock returns a tool_use block of call
+func ToolUseBlock(call ToolCalls) ContentBlock {
+	return ContentBlock{Type: BlockToolUse, ToolCall: &call}
+}
+
+// ToolResultBlock returns a tool_result block of result
+func ToolResultBlock(result ToolResults) ContentBlock {
+	return ContentBlock{Type: BlockToolResult, ToolResult: &result}
+}
+
+// ThinkingBlock returns a thinking block
+func ThinkingBlock(text string) ContentBlock {
+	return ContentBlock{Type: BlockThinking, Text: text}
+}
+
+// NewBlockMessage creates a message of blocks, normalizing its role with
+// NormalizeRole, see WithBlocks
+func NewBlockMessage(role string, blocks ...ContentBlock) (Message, error) {
+	normalized, err := NormalizeRole(role)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Role: normalized}.WithBlocks(blocks...), nil
+}
+
+// WithBlocks returns the message with its content replaced by blocks. The
+// other content fields are set from the blocks, for code that reads them: the
+// text blocks joined as Content, the thinking blocks as ReasoningContent, the
+// first image as Media and MimeType, and the tool calls and results. Media of
+// later images is only in Blocks.
+func (m Message) WithBlocks(blocks ...ContentBlock) Message {
+	m.Blocks = blocks
+	m.Content, m.ReasoningContent = "", ""
+	m.Media, m.MimeType = nil, ""
+	m.ToolCalls, m.ToolResults = nil, nil
+	var thinking []string
+	for _, block := range blocks {
+		switch block.Type {
+		case BlockText:
+			m.Content += block.Text
+		case BlockThinking:
+			thinking = append(thinking, block.Text)
+		case BlockImage:
+			if m.Media == nil {
+				m.Media, m.MimeType = block.Data, block.MimeType
+			}
+		case BlockToolUse:
+			if block.ToolCall != nil {
+				m.ToolCalls = append(m.ToolCalls, *block.ToolCall)
+			}
+		case BlockToolResult:
+			if block.ToolResult != nil {
+				m.ToolResults = append(m.ToolResults, *block.ToolResult)
+			}
+		}
+	}
+	m.ReasoningContent = strings.Join(thinking, "\n\n")
+	return m
+}
+
+// ContentBlocks returns the content of the message as blocks: its Blocks, or
+// for a message without them, blocks made from its other fields, in the order
+// thinking, text, image, tool calls and tool results
+func (m Message) ContentBlocks() []ContentBlock {
+	if len(m.Blocks) > 0 {
+		return m.Blocks
+	}
+	var blocks []ContentBlock
+	if m.ReasoningContent != "" {
+		blocks = append(blocks, ThinkingBlock(m.ReasoningContent))
+	}
+	if m.Content != "" {
+		blocks = append(blocks, TextBlock(m.Content))
+	}
+	if len(m.Media) > 0 {
+		blocks = append(blocks, ImageBlock(m.MimeType, m.Media))
+	}
+	for _, call := range m.ToolCalls {
+		blocks = append(blocks, ToolUseBlock(call))
+	}
+	for _, result := range m.ToolResults {
+		blocks = append(blocks, ToolResultBlock(result))
+	}
+	return blocks
+}
+
+// Text returns the text of the message: its text blocks joined, or Content
+// for a message without blocks
+func (m Message) Text() string {
+	if len(m.Blocks) == 0 {
+		return m.Content
+	}
+	var text strings.Builder
+	for _, block := range m.Blocks {
+		if block.Type == BlockText {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMessage_WithBlocks(t *testing.T) {
+	call := ToolCalls{Id: "call_1", ToolName: "screenshot", ToolArgs: map[string]any{"page": "home"}}
+	result := ToolResults{Id: "call_1", Content: "taken"}
+	message, err := NewBlockMessage("model",
+		ThinkingBlock("Compare them."),
+		TextBlock("Before "),
+		ImageBlock("image/png", []byte("before")),
+		TextBlock("and after."),
+		ImageBlock("image/jpeg", []byte("after")),
+		ThinkingBlock("The sidebar moved."),
+		ToolUseBlock(call),
+		ToolResultBlock(result),
+	)
+	if err != nil {
+		t.Fatalf("NewBlockMessage() error = %v", err)
+	}
+	if message.Role != RoleAssistant || message.Content != "Before and after." || message.Text() != "Before and after." {
+		t.Errorf("message = %q %q, text %q", message.Role, message.Content, message.Text())
+	}
+	if message.ReasoningContent != "Compare them.\n\nThe sidebar moved." {
+		t.Errorf("ReasoningContent = %q", message.ReasoningContent)
+	}
+	if string(message.Media) != "before" || message.MimeType != "image/png" {
+		t.Errorf("Media = %q %q, want the first image", message.Media, message.MimeType)
+	}
+	if !reflect.DeepEqual(message.ToolCalls, []ToolCalls{call}) || !reflect.DeepEqual(message.ToolResults, []ToolResults{result}) {
+		t.Errorf("ToolCalls = %v, ToolResults = %v", message.ToolCalls, message.ToolResults)
+	}
+	if blocks := message.ContentBlocks(); len(blocks) != 8 || blocks[4].MimeType != "image/jpeg" {
+		t.Errorf("ContentBlocks() = %+v, want the blocks in order", blocks)
+	}
+	if size := MessageSize(message); size != len("Before and after.")+len("before")+len("after")+len("screenshot")+len(`{"page":"home"}`)+len("taken") {
+		t.Errorf("MessageSize() = %d", size)
+	}
+
+	// Replacing the blocks clears fields the new blocks do not set
+	message = message.WithMeta(MetaNode, "vision").WithBlocks(TextBlock("Done."))
+	if message.Content != "Done." || message.Media != nil || message.ToolCalls != nil || message.ReasoningContent != "" || message.Meta(MetaNode) != "vision" {
+		t.Errorf("WithBlocks() = %+v", message)
+	}
+
+	if _, err := NewBlockMessage("narrator", TextBlock("Once")); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("NewBlockMessage() error = %v, want ErrUnknownRole", err)
+	}
+}
+
+func TestMessage_ContentBlocks(t *testing.T) {
+	message := Message{
+		Role:             RoleUser,
+		Content:          "What changed?",
+		ReasoningContent: "Look closely.",
+		Media:            []byte("image"),
+		MimeType:         "image/png",
+		ToolResults:      []ToolResults{{Id: "call_1", Content: "ok"}},
+	}
+	var types []BlockType
+	for _, block := range message.ContentBlocks() {
+		types = append(types, block.Type)
+	}
+	want := []BlockType{BlockThinking, BlockText, BlockImage, BlockToolResult}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("ContentBlocks() types = %v, want %v", types, want)
+	}
+	if message.Text() != "What changed?" {
+		t.Errorf("Text() = %q", message.Text())
+	}
+	if blocks := (Message{Role: RoleUser}).ContentBlocks(); len(blocks) != 0 {
+		t.Errorf("ContentBlocks() of an empty message = %+v", blocks)
+	}
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// CallIDPrefix starts every ID NewCallID makes
+const CallIDPrefix = "call_"
+
+// crockford is the ULID alphabet, Crockford's base32
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidSource makes ULIDs that increase even within a millisecond
+type ulidSource struct {
+	mu   sync.Mutex
+	last [16]byte
+	now  func() time.Time
+}
+
+// callIDs makes the IDs of NewCallID
+var callIDs = &ulidSource{now: time.Now}
+
+// NewCallID returns a new tool call ID: CallIDPrefix and a ULID, so IDs sort
+// by creation time and do not collide, even for calls made in the same
+// millisecond. Use it for calls parsed from text and for providers that
+// return calls without IDs.
+func NewCallID() string {
+	return CallIDPrefix + callIDs.next()
+}
+
+// next returns the next ULID: 48 bits of Unix milliseconds and 80 random
+// bits. Within a millisecond, or when the clock goes back, it increments the
+// last ULID instead, so IDs stay unique and ordered.
+func (s *ulidSource) next() string {
+	ms := uint64(s.now().UnixMilli())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lastMs [8]byte
+	copy(lastMs[2:], s.last[:6])
+	if ms > binary.BigEndian.Uint64(lastMs[:]) {
+		var stamp [8]byte
+		binary.BigEndian.PutUint64(stamp[:], ms)
+		copy(s.last[:6], stamp[2:])
+		binary.BigEndian.PutUint16(s.last[6:8], uint16(rand.Uint32()))
+		binary.BigEndian.PutUint64(s.last[8:], rand.Uint64())
+	} else {
+		for i := len(s.last) - 1; i >= 0; i-- {
+			s.last[i]++
+			if s.last[i] != 0 {
+				break
+			}
+		}
+	}
+	return encodeULID(s.last)
+}
+
+// encodeULID writes 128 bits as 26 characters of Crockford's base32
+func encodeULID(id [16]byte) string {
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ToolExchange is a tool call in a conversation and the result it got
+type ToolExchange struct {
+	Call        ToolCalls
+	Result      *ToolResults // nil while the call has no result
+	CallIndex   int          // Index of the message that made the call
+	ResultIndex int          // Index of the message with the result, -1 without one
+}
+
+// CorrelateToolCalls pairs the tool calls of messages with their results by
+// ID, in the order the calls were made. A call repeated by a later message,
+// as tool nodes do when they answer it, is the same call. Calls without an ID
+// never get a result.
+func CorrelateToolCalls(messages []Message) []ToolExchange {
+	exchanges, _ := correlate(messages)
+	return exchanges
+}
+
+// PendingToolCalls returns the calls of messages that have no result yet
+func PendingToolCalls(messages []Message) []ToolCalls {
+	var pending []ToolCalls
+	for _, exchange := range CorrelateToolCalls(messages) {
+		if exchange.Result == nil {
+			pending = append(pending, exchange.Call)
+		}
+	}
+	return pending
+}
+
+// OrphanedToolResults returns the results of messages that answer no earlier
+// call, e.g. after older messages were cut off, and second results for a call.
+// Providers reject such results.
+func OrphanedToolResults(messages []Message) []ToolResults {
+	_, orphans := correlate(messages)
+	return orphans
+}
+
+// FindToolCall returns the call of messages with the ID, the one a result
+// with that ID answers
+func FindToolCall(messages []Message, id string) (ToolCalls, bool) {
+	for _, message := range messages {
+		for _, call := range message.ToolCalls {
+			if call.Id == id && id != "" {
+				return call, true
+			}
+		}
+	}
+	return ToolCalls{}, false
+}
+
+// correlate pairs calls and results, returning the results that pair with no call
+func correlate(messages []Message) ([]ToolExchange, []ToolResults) {
+	var exchanges []ToolExchange
+	var orphans []ToolResults
+	byID := make(map[string]int)
+	for i, message := range messages {
+		for _, call := range message.ToolCalls {
+			if _, seen := byID[call.Id]; seen && call.Id != "" {
+				continue
+			}
+			if call.Id != "" {
+				byID[call.Id] = len(exchanges)
+			}
+			exchanges = append(exchanges, ToolExchange{Call: call, CallIndex: i, ResultIndex: -1})
+		}
+		for _, result := range message.ToolResults {
+			index, ok := byID[result.Id]
+			if !ok || exchanges[index].Result != nil {
+				orphans = append(orphans, result)
+				continue
+			}
+			exchanges[index].Result = &result
+			exchanges[index].ResultIndex = i
+		}
+	}
+	return exchanges, orphans
+}
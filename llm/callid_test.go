@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCallID(t *testing.T) {
+	seen := make(map[string]bool)
+	previous := ""
+	for range 1000 {
+		id := NewCallID()
+		if !strings.HasPrefix(id, CallIDPrefix) || len(id) != len(CallIDPrefix)+26 {
+			t.Fatalf("NewCallID() = %q, want %s and 26 characters", id, CallIDPrefix)
+		}
+		if seen[id] || id <= previous {
+			t.Fatalf("NewCallID() = %q after %q, want a new, greater ID", id, previous)
+		}
+		seen[id], previous = true, id
+	}
+}
+
+func TestULIDSource(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	source := &ulidSource{now: func() time.Time { return now }}
+
+	first := source.next()
+	if got := source.next(); got <= first {
+		t.Errorf("ULID in the same millisecond = %q, want greater than %q", got, first)
+	}
+	now = now.Add(-time.Second)
+	if got := source.next(); got <= first {
+		t.Errorf("ULID after the clock went back = %q, want greater than %q", got, first)
+	}
+	now = now.Add(time.Hour)
+	later := source.next()
+	if later[:10] <= first[:10] {
+		t.Errorf("ULID an hour later = %q, want a greater timestamp than %q", later, first)
+	}
+	if got := encodeULID([16]byte{}); got != strings.Repeat("0", 26) {
+		t.Errorf("encodeULID(zero) = %q", got)
+	}
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := encodeULID(max); got != "7"+strings.Repeat("Z", 25) {
+		t.Errorf("encodeULID(max) = %q", got)
+	}
+}
+
+func TestCorrelateToolCalls(t *testing.T) {
+	search := ToolCalls{Id: "1", ToolName: "search"}
+	fetch := ToolCalls{Id: "2", ToolName: "fetch"}
+	messages := []Message{
+		{Role: RoleUser, Content: "Find it"},
+		{Role: RoleAssistant, ToolCalls: []ToolCalls{search, fetch}},
+		{Role: RoleUser, ToolCalls: []ToolCalls{search}, ToolResults: []ToolResults{{Id: "1", Content: "found"}, {Id: "9", Content: "stray"}}},
+		{Role: RoleUser, ToolResults: []ToolResults{{Id: "1", Content: "again"}}},
+	}
+
+	exchanges := CorrelateToolCalls(messages)
+	if len(exchanges) != 2 {
+		t.Fatalf("CorrelateToolCalls() = %+v, want 2 exchanges", exchanges)
+	}
+	if got := exchanges[0]; got.Call.Id != "1" || got.Result == nil || got.Result.Content != "found" || got.CallIndex != 1 || got.ResultIndex != 2 {
+		t.Errorf("exchange 0 = %+v, want search answered by message 2", got)
+	}
+	if got := exchanges[1]; got.Call.Id != "2" || got.Result != nil || got.ResultIndex != -1 {
+		t.Errorf("exchange 1 = %+v, want fetch pending", got)
+	}
+	if got := PendingToolCalls(messages); !reflect.DeepEqual(got, []ToolCalls{fetch}) {
+		t.Errorf("PendingToolCalls() = %+v, want fetch", got)
+	}
+	orphans := OrphanedToolResults(messages)
+	if len(orphans) != 2 || orphans[0].Content != "stray" || orphans[1].Content != "again" {
+		t.Errorf("OrphanedToolResults() = %+v, want stray and again", orphans)
+	}
+	if call, ok := FindToolCall(messages, "2"); !ok || call.ToolName != "fetch" {
+		t.Errorf("FindToolCall(2) = %+v, %v", call, ok)
+	}
+	if _, ok := FindToolCall(messages, ""); ok {
+		t.Error("FindToolCall(\"\") found a call")
+	}
+}
@@ -0,0 +1,309 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QueuePolicy decides which waiting call a FairQueueProvider starts next
+type QueuePolicy string
+
+// Queue policies
+const (
+	// QueueFIFO starts calls in the order they arrived, whatever their queue
+	QueueFIFO QueuePolicy = "fifo"
+	// QueueWeighted shares the calls started between the queues with waiting
+	// calls by their weights, so a queue of a thousand batch calls does not
+	// hold up a chat session that arrives after them
+	QueueWeighted QueuePolicy = "weighted"
+)
+
+// FairQueueConfig configures a FairQueueProvider
+type FairQueueConfig struct {
+	Rate          int           // Calls started per Interval, spaced evenly; 0 for no limit
+	Interval      time.Duration // Window of Rate, default: 1 minute
+	MaxConcurrent int           // Calls in flight at once; 0 for no limit
+	Policy        QueuePolicy   // Which waiting call starts next, default: QueueWeighted
+
+	// Label is the label of the call context that names the queue of a call,
+	// see WithLabels, e.g. a label each flow or node passes with its calls.
+	// Calls without it share the queue "". Default: "flow".
+	Label   string
+	Weights map[string]int // Share of each queue under QueueWeighted; queues not listed weigh 1
+}
+
+// DefaultFairQueueConfig runs four calls at once, shared by the "flow" label
+// of the calls
+func DefaultFairQueueConfig() *FairQueueConfig {
+	return &FairQueueConfig{Interval: time.Minute, MaxConcurrent: 4, Policy: QueueWeighted, Label: "flow"}
+}
+
+// QueueStats counts the calls of one queue of a FairQueueProvider
+type QueueStats struct {
+	Started int           `json:"started"` // Calls started
+	Waiting int           `json:"waiting"` // Calls waiting to start
+	Waited  time.Duration `json:"waited"`  // Time the started calls waited in total
+}
+
+// FairQueueProvider coordinates the calls of many concurrent flows sharing
+// one provider: it holds calls back to the configured rate and concurrency
+// and, when calls wait, starts them in the order of the queue policy. Calls
+// whose context is done while they wait fail with the context's error.
+type FairQueueProvider struct {
+	provider LLMProvider
+	config   *FairQueueConfig
+
+	mu       sync.Mutex
+	queues   map[string]*callQueue
+	arrivals uint64    // Calls queued so far, which orders them for QueueFIFO
+	virtual  float64   // Virtual start of the last started call, for QueueWeighted
+	inFlight int       // Calls started and not yet finished
+	next     time.Time // When the rate allows the next call to start
+	timer    *time.Timer
+}
+
+// callQueue holds the waiting calls of one queue
+type callQueue struct {
+	name     string
+	waiting  []*queuedCall
+	inFlight int     // Calls started and not yet finished
+	finish   float64 // Virtual finish of the last started call
+	stats    QueueStats
+}
+
+// queuedCall is a call waiting to start; ready is closed when it may
+type queuedCall struct {
+	ready   chan struct{}
+	arrival uint64
+	queued  time.Time
+}
+
+// NewFairQueueProvider wraps provider; a nil config uses
+// DefaultFairQueueConfig, and an unset Interval, Policy or Label of config
+// takes its default
+func NewFairQueueProvider(provider LLMProvider, config *FairQueueConfig) (*FairQueueProvider, error) {
+	defaults := DefaultFairQueueConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Rate < 0 || config.MaxConcurrent < 0 || config.Interval < 0 {
+		return nil, fmt.Errorf("rate, interval and concurrency cannot be negative")
+	}
+	for queue, weight := range config.Weights {
+		if weight <= 0 {
+			return nil, fmt.Errorf("weight of queue %q must be positive, got %d", queue, weight)
+		}
+	}
+	withDefaults := *config
+	if withDefaults.Interval == 0 {
+		withDefaults.Interval = defaults.Interval
+	}
+	if withDefaults.Label == "" {
+		withDefaults.Label = defaults.Label
+	}
+	switch withDefaults.Policy {
+	case "":
+		withDefaults.Policy = defaults.Policy
+	case QueueFIFO, QueueWeighted:
+	default:
+		return nil, fmt.Errorf("unknown queue policy %q", config.Policy)
+	}
+	return &FairQueueProvider{provider: provider, config: &withDefaults, queues: make(map[string]*callQueue)}, nil
+}
+
+// CallLLM calls the wrapped provider once the call's turn has come
+func (p *FairQueueProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	queue, err := p.acquire(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	defer p.release(queue)
+	return p.provider.CallLLM(ctx, messages)
+}
+
+// CallLLMWithSchema calls the wrapped provider's constrained decoding, if it has any
+func (p *FairQueueProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	schemaProvider, ok := p.provider.(SchemaProvider)
+	if !ok {
+		return Message{}, ErrSchemaNotSupported
+	}
+	queue, err := p.acquire(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	defer p.release(queue)
+	return schemaProvider.CallLLMWithSchema(ctx, messages, schema)
+}
+
+// GetName returns the name of the wrapped provider
+func (p *FairQueueProvider) GetName() string {
+	return p.provider.GetName()
+}
+
+// SetConfig updates the wrapped provider
+func (p *FairQueueProvider) SetConfig(config map[string]any) error {
+	return p.provider.SetConfig(config)
+}
+
+// Shutdown implements Shutdowner for wrapped providers that have it
+func (p *FairQueueProvider) Shutdown(ctx context.Context) error {
+	if shutdowner, ok := p.provider.(Shutdowner); ok {
+		return shutdowner.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Stats returns the counts of each queue with calls waiting or in flight, by
+// name. A queue is dropped once all its calls have finished, so its counts
+// start over with its next call.
+func (p *FairQueueProvider) Stats() map[string]QueueStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]QueueStats, len(p.queues))
+	for name, queue := range p.queues {
+		stats[name] = queue.stats
+	}
+	return stats
+}
+
+// acquire waits until the call of ctx may start and returns its queue
+func (p *FairQueueProvider) acquire(ctx context.Context) (*callQueue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := LabelsFrom(ctx)[p.config.Label]
+	call := &queuedCall{ready: make(chan struct{})}
+
+	p.mu.Lock()
+	queue, ok := p.queues[name]
+	if !ok {
+		queue = &callQueue{name: name}
+		p.queues[name] = queue
+	}
+	call.arrival, call.queued = p.arrivals, time.Now()
+	p.arrivals++
+	queue.waiting = append(queue.waiting, call)
+	queue.stats.Waiting++
+	p.dispatch()
+	p.mu.Unlock()
+
+	select {
+	case <-call.ready:
+		return queue, nil
+	case <-ctx.Done():
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-call.ready:
+		// Started while the context was cancelled: give the slot to the next call
+		p.inFlight--
+		queue.inFlight--
+		p.dispatch()
+	default:
+		for i, waiting := range queue.waiting {
+			if waiting == call {
+				queue.waiting = append(queue.waiting[:i], queue.waiting[i+1:]...)
+				break
+			}
+		}
+		queue.stats.Waiting--
+	}
+	p.drop(queue)
+	return nil, ctx.Err()
+}
+
+// release ends a started call of queue and starts the next one
+func (p *FairQueueProvider) release(queue *callQueue) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight--
+	queue.inFlight--
+	p.drop(queue)
+	p.dispatch()
+}
+
+// drop forgets queue once it has no calls waiting or in flight, so the queues
+// of finished flows do not pile up. Its next call starts a new queue at the
+// current virtual time, which gains it at most the one call its finish was
+// ahead by.
+func (p *FairQueueProvider) drop(queue *callQueue) {
+	if len(queue.waiting) == 0 && queue.inFlight == 0 {
+		delete(p.queues, queue.name)
+	}
+}
+
+// dispatch starts waiting calls while the rate and concurrency allow it. When
+// only the rate holds them back, it tries again once the rate allows a call.
+func (p *FairQueueProvider) dispatch() {
+	for {
+		if p.config.MaxConcurrent > 0 && p.inFlight >= p.config.MaxConcurrent {
+			return
+		}
+		name, queue := p.pick()
+		if queue == nil {
+			return
+		}
+		if p.config.Rate > 0 {
+			now := time.Now()
+			if now.Before(p.next) {
+				if p.timer == nil {
+					p.timer = time.AfterFunc(p.next.Sub(now), func() {
+						p.mu.Lock()
+						defer p.mu.Unlock()
+						p.timer = nil
+						p.dispatch()
+					})
+				}
+				return
+			}
+			p.next = now.Add(p.config.Interval / time.Duration(p.config.Rate))
+		}
+
+		call := queue.waiting[0]
+		queue.waiting = queue.waiting[1:]
+		start := max(queue.finish, p.virtual)
+		queue.finish = start + 1/float64(p.weight(name))
+		p.virtual = start
+		queue.stats.Started++
+		queue.stats.Waiting--
+		queue.stats.Waited += time.Since(call.queued)
+		queue.inFlight++
+		p.inFlight++
+		close(call.ready)
+	}
+}
+
+// pick returns the queue whose call starts next, or nil when no call waits.
+// Under QueueWeighted it is the queue whose next call would finish first in
+// virtual time, where a call of a queue of weight w takes 1/w.
+func (p *FairQueueProvider) pick() (string, *callQueue) {
+	var pickedName string
+	var picked *callQueue
+	var best float64
+	for name, queue := range p.queues {
+		if len(queue.waiting) == 0 {
+			continue
+		}
+		var rank float64
+		if p.config.Policy == QueueFIFO {
+			rank = float64(queue.waiting[0].arrival)
+		} else {
+			rank = max(queue.finish, p.virtual) + 1/float64(p.weight(name))
+		}
+		if picked == nil || rank < best || rank == best && queue.waiting[0].arrival < picked.waiting[0].arrival {
+			pickedName, picked, best = name, queue, rank
+		}
+	}
+	return pickedName, picked
+}
+
+// weight returns the weight of the queue name
+func (p *FairQueueProvider) weight(name string) int {
+	if weight, ok := p.config.Weights[name]; ok {
+		return weight
+	}
+	return 1
+}
@@ -0,0 +1,204 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateProvider records the order calls start in and holds each call until
+// the gate lets it through
+type gateProvider struct {
+	gate    chan struct{}
+	mu      sync.Mutex
+	started []string
+}
+
+func (p *gateProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	p.mu.Lock()
+	p.started = append(p.started, messages[0].Content)
+	p.mu.Unlock()
+	if p.gate != nil {
+		<-p.gate
+	}
+	return Message{Role: RoleAssistant, Content: messages[0].Content}, nil
+}
+
+func (p *gateProvider) GetName() string                { return "gate" }
+func (p *gateProvider) SetConfig(map[string]any) error { return nil }
+
+// waitFor polls until condition holds
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairQueueProvider_Policy(t *testing.T) {
+	tests := []struct {
+		policy QueuePolicy
+		want   []string
+	}{
+		{QueueFIFO, []string{"batch-0", "batch-1", "batch-2", "batch-3", "chat-0"}},
+		{QueueWeighted, []string{"batch-0", "chat-0", "batch-1", "batch-2", "batch-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			inner := &gateProvider{gate: make(chan struct{})}
+			provider, err := NewFairQueueProvider(inner, &FairQueueConfig{MaxConcurrent: 1, Policy: tt.policy})
+			if err != nil {
+				t.Fatalf("NewFairQueueProvider() error = %v", err)
+			}
+
+			var wg sync.WaitGroup
+			call := func(flow, content string, started, waiting int) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctx := WithLabels(context.Background(), map[string]string{"flow": flow})
+					if _, err := provider.CallLLM(ctx, []Message{{Role: RoleUser, Content: content}}); err != nil {
+						t.Errorf("CallLLM(%s) error = %v", content, err)
+					}
+				}()
+				// Queue the calls one after another, so they arrive in order
+				waitFor(t, func() bool {
+					stats := provider.Stats()[flow]
+					return stats.Started == started && stats.Waiting == waiting
+				})
+			}
+			call("batch", "batch-0", 1, 0)
+			call("batch", "batch-1", 1, 1)
+			call("batch", "batch-2", 1, 2)
+			call("batch", "batch-3", 1, 3)
+			call("chat", "chat-0", 0, 1)
+			for range tt.want {
+				inner.gate <- struct{}{}
+			}
+			wg.Wait()
+
+			if !reflect.DeepEqual(inner.started, tt.want) {
+				t.Errorf("calls started in order %v, want %v", inner.started, tt.want)
+			}
+			if stats := provider.Stats(); len(stats) != 0 {
+				t.Errorf("Stats() = %+v, want the finished queues dropped", stats)
+			}
+		})
+	}
+}
+
+func TestFairQueueProvider_Rate(t *testing.T) {
+	provider, err := NewFairQueueProvider(&gateProvider{}, &FairQueueConfig{Rate: 2, Interval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewFairQueueProvider() error = %v", err)
+	}
+	started := time.Now()
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Errorf("3 calls at 2 per 100ms took %v", elapsed)
+	}
+}
+
+func TestFairQueueProvider_Cancelled(t *testing.T) {
+	inner := &gateProvider{gate: make(chan struct{})}
+	provider, _ := NewFairQueueProvider(inner, &FairQueueConfig{MaxConcurrent: 1})
+	go provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "first"}})
+	waitFor(t, func() bool { return provider.Stats()[""].Started == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := provider.CallLLM(ctx, []Message{{Role: RoleUser, Content: "second"}})
+		done <- err
+	}()
+	waitFor(t, func() bool { return provider.Stats()[""].Waiting == 1 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("CallLLM() error = %v, want context.Canceled", err)
+	}
+	if stats := provider.Stats()[""]; stats.Waiting != 0 || stats.Started != 1 {
+		t.Errorf("Stats() = %+v, want the cancelled call removed", stats)
+	}
+	inner.gate <- struct{}{}
+}
+
+func TestFairQueueProvider_DropsFinishedQueues(t *testing.T) {
+	inner := &gateProvider{gate: make(chan struct{})}
+	provider, _ := NewFairQueueProvider(inner, &FairQueueConfig{MaxConcurrent: 1})
+	first := WithLabels(context.Background(), map[string]string{"flow": "first"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		provider.CallLLM(first, []Message{{Role: RoleUser, Content: "first"}})
+	}()
+	waitFor(t, func() bool { return provider.Stats()["first"].Started == 1 })
+
+	// A queue whose only call gave up waiting is dropped
+	ctx, cancel := context.WithCancel(WithLabels(context.Background(), map[string]string{"flow": "cancelled"}))
+	cancelled := make(chan struct{})
+	go func() {
+		defer close(cancelled)
+		provider.CallLLM(ctx, []Message{{Role: RoleUser, Content: "cancelled"}})
+	}()
+	waitFor(t, func() bool { return provider.Stats()["cancelled"].Waiting == 1 })
+	cancel()
+	<-cancelled
+	if _, ok := provider.Stats()["cancelled"]; ok {
+		t.Errorf("Stats() = %+v, want the queue of the cancelled call dropped", provider.Stats())
+	}
+
+	// The queue of the call in flight stays until the call finishes
+	if stats := provider.Stats(); len(stats) != 1 || stats["first"].Started != 1 {
+		t.Errorf("Stats() = %+v, want the queue of the call in flight", stats)
+	}
+	inner.gate <- struct{}{}
+	<-done
+	for i := range 100 {
+		ctx := WithLabels(context.Background(), map[string]string{"flow": fmt.Sprint("flow-", i)})
+		go func() { inner.gate <- struct{}{} }()
+		if _, err := provider.CallLLM(ctx, []Message{{Role: RoleUser, Content: "hi"}}); err != nil {
+			t.Fatalf("CallLLM() error = %v", err)
+		}
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if len(provider.queues) != 0 {
+		t.Errorf("%d queues left after all calls finished, want 0", len(provider.queues))
+	}
+}
+
+func TestNewFairQueueProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *FairQueueConfig
+		wantErr bool
+	}{
+		{"default", nil, false},
+		{"negative rate", &FairQueueConfig{Rate: -1}, true},
+		{"zero weight", &FairQueueConfig{Weights: map[string]int{"chat": 0}}, true},
+		{"unknown policy", &FairQueueConfig{Policy: "lifo"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFairQueueProvider(&gateProvider{}, tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("NewFairQueueProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
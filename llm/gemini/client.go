@@ -2,9 +2,13 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/alt-coder/pocketflow-go/internal/inflight"
 	"github.com/alt-coder/pocketflow-go/llm"
 	"google.golang.org/genai"
 )
@@ -17,14 +21,42 @@ type GeminiClient struct {
 	// Rate limiting
 	rateLimiter *time.Ticker
 	tokens      chan struct{}
+
+	// Calls in flight, which Shutdown waits for
+	calls inflight.Tracker
 }
 
 // CallLLM implements the generic interface, converting messages internally
 func (c *GeminiClient) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	return c.callLLM(ctx, messages, nil)
+}
+
+// CallLLMWithSchema implements llm.SchemaProvider by setting the response MIME
+// type to JSON and passing the schema as responseJsonSchema
+func (c *GeminiClient) CallLLMWithSchema(ctx context.Context, messages []llm.Message, schema llm.ResponseSchema) (llm.Message, error) {
+	if c.config.DisableResponseSchema {
+		return llm.Message{}, llm.ErrSchemaNotSupported
+	}
+	if len(schema.Schema) == 0 {
+		return llm.Message{}, fmt.Errorf("response schema cannot be empty")
+	}
+	return c.callLLM(ctx, messages, &genai.GenerateContentConfig{
+		ResponseMIMEType:   "application/json",
+		ResponseJsonSchema: schema.Schema,
+	})
+}
+
+// callLLM sends a generate content request with an optional generation config
+func (c *GeminiClient) callLLM(ctx context.Context, messages []llm.Message, config *genai.GenerateContentConfig) (llm.Message, error) {
 	result := llm.Message{}
 	if len(messages) == 0 {
 		return result, fmt.Errorf("no messages to send")
 	}
+	if !c.calls.Start() {
+		return result, llm.ErrShutdown
+	}
+	defer c.calls.Done()
+	config = c.config.generateConfig(config)
 
 	// Apply rate limiting if enabled
 	if c.tokens != nil {
@@ -38,68 +70,182 @@ func (c *GeminiClient) CallLLM(ctx context.Context, messages []llm.Message) (llm
 	fmt.Println("--------")
 
 	// Convert messages to Gemini format
-	genaiMessages, err := c.convertToGenaiMessages(messages)
+	genaiMessages, system, err := c.convertToGenaiMessages(messages)
 	if err != nil {
 		return result, fmt.Errorf("failed to convert messages: %w", err)
 	}
+	if system != nil {
+		if config == nil {
+			config = &genai.GenerateContentConfig{}
+		}
+		config.SystemInstruction = system
+	}
 
-	respone, err := c.genaiClient.Models.GenerateContent(ctx, c.config.Model, genaiMessages, nil)
+	respone, err := c.genaiClient.Models.GenerateContent(ctx, c.config.Model, genaiMessages, config)
 
 	if err != nil {
 		fmt.Println("Error calling Gemini LLM:", err)
+		var apiErr genai.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+			// RESOURCE_EXHAUSTED: a rate limit or the quota of the key
+			err = fmt.Errorf("%w: %w", llm.ErrRateLimited, err)
+		}
 		return llm.Message{}, fmt.Errorf("failed to generate content: %w", err)
 	}
 
+	if usage := respone.UsageMetadata; usage != nil {
+		// Thinking tokens are billed as output
+		llm.ReportUsage(ctx, llm.Usage{
+			InputTokens:  int(usage.PromptTokenCount + usage.ToolUsePromptTokenCount),
+			OutputTokens: int(usage.CandidatesTokenCount + usage.ThoughtsTokenCount),
+		})
+	}
+
 	for _, functionCall := range respone.FunctionCalls() {
+		// Gemini leaves IDs out unless the request asks for them
+		id := functionCall.ID
+		if id == "" {
+			id = llm.NewCallID()
+		}
 		result.ToolCalls = append(result.ToolCalls, llm.ToolCalls{
-			Id:       functionCall.ID,
+			Id:       id,
 			ToolName: functionCall.Name,
 			ToolArgs: functionCall.Args,
 		})
 	}
 	result.Role = "assistant"
+	// Text leaves out thought parts, which come back with IncludeThoughts
 	result.Content = respone.Text()
+	result.ReasoningContent = thoughts(respone)
 	return result, nil
 
 }
 
-// convertToGenaiMessages converts generic messages to Gemini format
-func (c *GeminiClient) convertToGenaiMessages(messages []llm.Message) ([]*genai.Content, error) {
-	var genaiMessages []*genai.Content
+// thoughts joins the thought summaries of the first candidate
+func thoughts(response *genai.GenerateContentResponse) string {
+	if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		if part.Thought && part.Text != "" {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
+}
 
+// convertToGenaiMessages converts generic messages to Gemini format. System
+// messages are returned as the system instruction, since Gemini has no system
+// turns; roles NormalizeRole does not know are an error. The contents and
+// parts are allocated in two blocks rather than one by one, and media is
+// referenced rather than copied.
+func (c *GeminiClient) convertToGenaiMessages(messages []llm.Message) ([]*genai.Content, *genai.Content, error) {
+	partCount := 0
 	for _, msg := range messages {
-		content := &genai.Content{
-			Role: getRole(msg.Role),
-			Parts: []*genai.Part{
-				{
-					Text: msg.Content,
-				},
-			},
+		partCount += countParts(msg)
+	}
+	genaiMessages := make([]*genai.Content, len(messages))
+	contents := make([]genai.Content, len(messages))
+	parts := make([]genai.Part, partCount)
+	partPointers := make([]*genai.Part, partCount)
+	for i := range parts {
+		partPointers[i] = &parts[i]
+	}
+
+	var system *genai.Content
+	next, count := 0, 0
+	for i, msg := range messages {
+		role, err := llm.NormalizeRole(msg.Role)
+		if err != nil {
+			return nil, nil, fmt.Errorf("message %d: %w", i, err)
 		}
-		if len(msg.Media) > 0 {
-			content.Parts = append(content.Parts, &genai.Part{
-				InlineData: &genai.Blob{
-					MIMEType: msg.MimeType,
-					Data:     msg.Media,
-				},
-			})
+		size := countParts(msg)
+		setParts(parts[next:next+size], msg)
+		if role == llm.RoleSystem {
+			if system == nil {
+				system = &genai.Content{}
+			}
+			system.Parts = append(system.Parts, partPointers[next:next+size]...)
+		} else {
+			contents[count] = genai.Content{
+				Role:  getRole(role),
+				Parts: partPointers[next : next+size : next+size],
+			}
+			genaiMessages[count] = &contents[count]
+			count++
 		}
+		next += size
+	}
 
-		genaiMessages = append(genaiMessages, content)
+	return genaiMessages[:count], system, nil
+}
+
+// countParts returns the number of parts of a message: one per text, image
+// and tool result block, and at least one
+func countParts(msg llm.Message) int {
+	if len(msg.Blocks) == 0 {
+		if len(msg.Media) > 0 {
+			return 2
+		}
+		return 1
 	}
+	count := 0
+	for _, block := range msg.Blocks {
+		switch block.Type {
+		case llm.BlockText, llm.BlockImage:
+			count++
+		case llm.BlockToolResult:
+			if block.ToolResult != nil {
+				count++
+			}
+		}
+	}
+	return max(count, 1)
+}
 
-	return genaiMessages, nil
+// setParts sets parts, of the length countParts returns, to the content of a
+// message. Tool results are sent as text, like the content of messages
+// without blocks repeats them; tool calls and thinking are not sent.
+func setParts(parts []genai.Part, msg llm.Message) {
+	if len(msg.Blocks) == 0 {
+		parts[0].Text = msg.Content
+		if len(msg.Media) > 0 {
+			parts[1].InlineData = &genai.Blob{
+				MIMEType: msg.MimeType,
+				Data:     msg.Media,
+			}
+		}
+		return
+	}
+	next := 0
+	for _, block := range msg.Blocks {
+		switch block.Type {
+		case llm.BlockText:
+			parts[next].Text = block.Text
+		case llm.BlockImage:
+			parts[next].InlineData = &genai.Blob{
+				MIMEType: block.MimeType,
+				Data:     block.Data,
+			}
+		case llm.BlockToolResult:
+			if block.ToolResult == nil {
+				continue
+			}
+			parts[next].Text = block.ToolResult.Content
+		default:
+			continue
+		}
+		next++
+	}
 }
 
+// getRole returns the Gemini role of a normalized user or assistant role
 func getRole(role string) string {
-	switch role {
-	case "user":
-		return genai.RoleUser
-	case "assistant":
+	if role == llm.RoleAssistant {
 		return genai.RoleModel
-	default:
-		return genai.RoleUser
 	}
+	return genai.RoleUser
 }
 
 // GetName returns the provider name
@@ -132,6 +278,15 @@ func (c *GeminiClient) SetConfig(config map[string]any) error {
 	if rateLimitInterval, ok := config["rateLimitInterval"].(time.Duration); ok {
 		c.config.RateLimitInterval = rateLimitInterval
 	}
+	if thinkingBudget, ok := config["thinkingBudget"].(int32); ok {
+		c.config.ThinkingBudget = &thinkingBudget
+	}
+	if includeThoughts, ok := config["includeThoughts"].(bool); ok {
+		c.config.IncludeThoughts = includeThoughts
+	}
+	if stopSequences, ok := config["stopSequences"].([]string); ok {
+		c.config.StopSequences = stopSequences
+	}
 
 	return nil
 }
@@ -148,10 +303,13 @@ func NewGeminiClient(ctx context.Context, config *Config) (*GeminiClient, error)
 	}
 
 	// Create the GenAI client with the specified backend
-	genaiClient, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  config.APIKey,
-		Backend: config.Backend,
-	})
+	clientConfig := config.clientConfig()
+	httpClient, err := config.httpClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	clientConfig.HTTPClient = httpClient
+	genaiClient, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GenAI client: %w", err)
 	}
@@ -191,6 +349,20 @@ func NewGeminiClientFromEnv(ctx context.Context) (*GeminiClient, error) {
 	return NewGeminiClient(ctx, config)
 }
 
+// NewKeyRotatingClient creates a client for each of the configured keys,
+// APIKey and then APIKeys, and rotates to the next key when one is rate
+// limited, e.g. to combine the free-tier quotas of several keys
+func NewKeyRotatingClient(ctx context.Context, config *Config, rotation *llm.KeyRotationConfig) (*llm.KeyRotatingProvider, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	return llm.NewKeyRotatingProvider(config.keys(), func(key string) (llm.LLMProvider, error) {
+		keyConfig := *config
+		keyConfig.APIKey, keyConfig.APIKeys = key, nil
+		return NewGeminiClient(ctx, &keyConfig)
+	}, rotation)
+}
+
 // refillTokens runs in a goroutine to refill the token bucket at the configured rate
 func (c *GeminiClient) refillTokens() {
 	for range c.rateLimiter.C {
@@ -203,6 +375,14 @@ func (c *GeminiClient) refillTokens() {
 	}
 }
 
+// Shutdown implements llm.Shutdowner: new calls fail with llm.ErrShutdown,
+// calls in flight are waited for until ctx is done, and then the client is closed
+func (c *GeminiClient) Shutdown(ctx context.Context) error {
+	err := c.calls.Shutdown(ctx)
+	c.Close()
+	return err
+}
+
 // Close stops the rate limiter and cleans up resources
 func (c *GeminiClient) Close() {
 	if c.rateLimiter != nil {
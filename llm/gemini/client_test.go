@@ -2,9 +2,19 @@ package gemini
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alt-coder/pocketflow-go/llm"
+	"google.golang.org/genai"
 )
 
 func TestNewConfigFromEnv(t *testing.T) {
@@ -75,6 +85,16 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "rotated API keys only",
+			config: Config{
+				APIKeys:     []string{"key-1", "key-2"},
+				Model:       "gemini-pro",
+				Temperature: 0.7,
+				MaxRetries:  3,
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid temperature",
 			config: Config{
@@ -95,6 +115,47 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid thinking budget",
+			config: Config{
+				APIKey:         "test-key",
+				Model:          "gemini-2.5-flash",
+				Temperature:    0.7,
+				MaxRetries:     3,
+				ThinkingBudget: genai.Ptr[int32](-2),
+			},
+			wantErr: true,
+		},
+		{
+			name: "vertex AI without an API key",
+			config: Config{
+				Model:       "gemini-2.5-flash",
+				Temperature: 0.7,
+				Backend:     genai.BackendVertexAI,
+				Project:     "my-project",
+				Location:    "europe-west4",
+			},
+			wantErr: false,
+		},
+		{
+			name: "vertex AI without a project",
+			config: Config{
+				Model:       "gemini-2.5-flash",
+				Temperature: 0.7,
+				Backend:     genai.BackendVertexAI,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid header name",
+			config: Config{
+				APIKey:      "test-key",
+				Model:       "gemini-pro",
+				Temperature: 0.7,
+				Headers:     map[string]string{"X-Gateway Key": "secret"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -174,3 +235,264 @@ func TestNewGeminiClient_InvalidConfig(t *testing.T) {
 		t.Error("Expected error with invalid config")
 	}
 }
+
+func TestGeminiClient_ConvertMessages(t *testing.T) {
+	client := &GeminiClient{}
+	contents, _, err := client.convertToGenaiMessages([]llm.Message{
+		{Role: llm.RoleUser, Content: "What's in this image?", Media: []byte("fake-image-data"), MimeType: "image/png"},
+		{Role: llm.RoleAssistant, Content: "A cat."},
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+	if len(contents) != 2 || contents[0].Role != "user" || contents[1].Role != "model" {
+		t.Fatalf("Unexpected contents %+v", contents)
+	}
+	if len(contents[0].Parts) != 2 || contents[0].Parts[0].Text != "What's in this image?" || contents[0].Parts[1].InlineData.MIMEType != "image/png" {
+		t.Errorf("Unexpected parts of the first message %+v", contents[0].Parts)
+	}
+	if len(contents[1].Parts) != 1 || contents[1].Parts[0].Text != "A cat." {
+		t.Errorf("Unexpected parts of the second message %+v", contents[1].Parts)
+	}
+}
+
+func TestGeminiClient_ConvertMessagesWithBlocks(t *testing.T) {
+	client := &GeminiClient{}
+	user, _ := llm.NewBlockMessage(llm.RoleUser,
+		llm.TextBlock("Before:"),
+		llm.ImageBlock("image/png", []byte("before")),
+		llm.TextBlock("After:"),
+		llm.ImageBlock("image/jpeg", []byte("after")),
+	)
+	assistant, _ := llm.NewBlockMessage(llm.RoleAssistant, llm.ThinkingBlock("Compare."), llm.TextBlock("The sidebar moved."))
+	results, _ := llm.NewBlockMessage(llm.RoleUser, llm.ToolResultBlock(llm.ToolResults{Id: "call_1", Content: "200 OK"}))
+	contents, _, err := client.convertToGenaiMessages([]llm.Message{user, assistant, results})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+	if len(contents) != 3 {
+		t.Fatalf("Unexpected contents %+v", contents)
+	}
+	parts := contents[0].Parts
+	if len(parts) != 4 || parts[0].Text != "Before:" || parts[1].InlineData.MIMEType != "image/png" || parts[2].Text != "After:" || parts[3].InlineData.MIMEType != "image/jpeg" {
+		t.Errorf("Unexpected parts of the first message %+v", parts)
+	}
+	if len(contents[1].Parts) != 1 || contents[1].Parts[0].Text != "The sidebar moved." {
+		t.Errorf("Unexpected parts of the second message %+v", contents[1].Parts)
+	}
+	if len(contents[2].Parts) != 1 || contents[2].Parts[0].Text != "200 OK" {
+		t.Errorf("Unexpected parts of the third message %+v", contents[2].Parts)
+	}
+}
+
+func TestGeminiClient_ConvertMessageRoles(t *testing.T) {
+	client := &GeminiClient{}
+	contents, system, err := client.convertToGenaiMessages([]llm.Message{
+		{Role: llm.RoleSystem, Content: "Answer in French."},
+		{Role: "Human", Content: "Hello"},
+		{Role: "model", Content: "Bonjour"},
+		{Role: llm.RoleSystem, Content: "Be brief."},
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+	if len(contents) != 2 || contents[0].Role != "user" || contents[1].Role != "model" || contents[1].Parts[0].Text != "Bonjour" {
+		t.Errorf("Unexpected contents %+v", contents)
+	}
+	if system == nil || len(system.Parts) != 2 || system.Parts[0].Text != "Answer in French." || system.Parts[1].Text != "Be brief." {
+		t.Errorf("Unexpected system instruction %+v", system)
+	}
+
+	if _, _, err := client.convertToGenaiMessages([]llm.Message{{Role: "narrator", Content: "Once"}}); !errors.Is(err, llm.ErrUnknownRole) {
+		t.Errorf("Expected ErrUnknownRole, got %v", err)
+	}
+}
+
+func BenchmarkConvertToGenaiMessages(b *testing.B) {
+	image := make([]byte, 64<<10)
+	client := &GeminiClient{}
+	for _, turns := range []int{10, 100} {
+		var messages []llm.Message
+		for i := 0; i < turns; i++ {
+			user := llm.Message{Role: llm.RoleUser, Content: "Describe what changed since the last screenshot."}
+			if i%10 == 0 {
+				user.Media, user.MimeType = image, "image/png"
+			}
+			messages = append(messages, user, llm.Message{Role: llm.RoleAssistant, Content: "The sidebar is collapsed."})
+		}
+		b.Run(fmt.Sprintf("turns=%d", turns), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := client.convertToGenaiMessages(messages); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newTestClient returns a client that sends its requests to server
+func newTestClient(t *testing.T, server *httptest.Server, config *Config) *GeminiClient {
+	t.Helper()
+	genaiClient, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL + "/"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create GenAI client: %v", err)
+	}
+	return &GeminiClient{genaiClient: genaiClient, config: config}
+}
+
+func TestGeminiClient_CallLLMWithSchema(t *testing.T) {
+	var request struct {
+		GenerationConfig struct {
+			ResponseMIMEType   string          `json:"responseMimeType"`
+			ResponseJSONSchema json.RawMessage `json:"responseJsonSchema"`
+		} `json:"generationConfig"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/models/gemini-2.0-flash:generateContent") {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"{\"city\":\"Paris\"}"}]}}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, &Config{Model: "gemini-2.0-flash"})
+	schema := llm.ResponseSchema{Name: "answer", Schema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`)}
+	response, err := client.CallLLMWithSchema(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Capital of France?"}}, schema)
+	if err != nil {
+		t.Fatalf("CallLLMWithSchema failed: %v", err)
+	}
+	if response.Content != `{"city":"Paris"}` {
+		t.Errorf("Unexpected content %q", response.Content)
+	}
+	var sent, want any
+	json.Unmarshal(request.GenerationConfig.ResponseJSONSchema, &sent)
+	json.Unmarshal(schema.Schema, &want)
+	if request.GenerationConfig.ResponseMIMEType != "application/json" || !reflect.DeepEqual(sent, want) {
+		t.Errorf("Unexpected generation config %+v", request.GenerationConfig)
+	}
+
+	if _, err := client.CallLLMWithSchema(context.Background(), nil, llm.ResponseSchema{}); err == nil {
+		t.Error("Expected an error for an empty schema")
+	}
+	client.config.DisableResponseSchema = true
+	if _, err := client.CallLLMWithSchema(context.Background(), nil, schema); !errors.Is(err, llm.ErrSchemaNotSupported) {
+		t.Errorf("Expected ErrSchemaNotSupported, got %v", err)
+	}
+}
+
+func TestGeminiClient_Thinking(t *testing.T) {
+	var request struct {
+		GenerationConfig *struct {
+			ThinkingConfig struct {
+				IncludeThoughts bool   `json:"includeThoughts"`
+				ThinkingBudget  *int32 `json:"thinkingBudget"`
+			} `json:"thinkingConfig"`
+		} `json:"generationConfig"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request.GenerationConfig = nil
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Adding the numbers","thought":true},{"text":"4"}]}}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, &Config{Model: "gemini-2.5-flash", ThinkingBudget: genai.Ptr[int32](1024), IncludeThoughts: true})
+	response, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "2 + 2?"}})
+	if err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if response.Content != "4" || response.ReasoningContent != "Adding the numbers" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	if request.GenerationConfig == nil || !request.GenerationConfig.ThinkingConfig.IncludeThoughts ||
+		request.GenerationConfig.ThinkingConfig.ThinkingBudget == nil || *request.GenerationConfig.ThinkingConfig.ThinkingBudget != 1024 {
+		t.Errorf("Unexpected generation config %+v", request.GenerationConfig)
+	}
+
+	client.config = &Config{Model: "gemini-2.5-flash"}
+	if _, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "2 + 2?"}}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if request.GenerationConfig != nil {
+		t.Errorf("Expected no generation config without thinking settings, got %+v", request.GenerationConfig)
+	}
+}
+
+func TestGeminiClient_HeadersAndStopSequences(t *testing.T) {
+	var header http.Header
+	var request struct {
+		GenerationConfig struct {
+			StopSequences []string `json:"stopSequences"`
+		} `json:"generationConfig"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewGeminiClient(context.Background(), &Config{
+		APIKey:        "test-key",
+		Model:         "gemini-2.0-flash",
+		BaseURL:       server.URL + "/",
+		Headers:       map[string]string{"X-Gateway-Key": "gateway-secret", "x-goog-user-project": "billing-project"},
+		StopSequences: []string{"END"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if header.Get("X-Gateway-Key") != "gateway-secret" || header.Get("X-Goog-User-Project") != "billing-project" {
+		t.Errorf("Unexpected headers %v", header)
+	}
+	if !reflect.DeepEqual(request.GenerationConfig.StopSequences, []string{"END"}) {
+		t.Errorf("Expected stop sequences [END], got %v", request.GenerationConfig.StopSequences)
+	}
+}
+
+func TestGeminiClient_Transport(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.Host
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]}}]}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewGeminiClient(context.Background(), &Config{
+		APIKey:    "test-key",
+		Model:     "gemini-2.0-flash",
+		BaseURL:   "http://gemini.internal/",
+		Transport: llm.TransportConfig{ProxyURL: proxy.URL, Timeout: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("CallLLM through the proxy failed: %v", err)
+	}
+	if proxied != "gemini.internal" {
+		t.Errorf("Proxy received request for %q", proxied)
+	}
+
+	config := &Config{APIKey: "test-key", Model: "gemini-2.0-flash", Transport: llm.TransportConfig{ProxyURL: "ftp://proxy"}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for an ftp proxy")
+	}
+}
@@ -1,11 +1,18 @@
 package gemini
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+	"github.com/alt-coder/pocketflow-go/llm"
 	"google.golang.org/genai"
 )
 
@@ -17,21 +24,69 @@ type Config struct {
 	MaxRetries  int           // Default: 3
 	Backend     genai.Backend // Default: genai.BackendGeminiAPI
 
+	// Project and Location select the Google Cloud project and region of the
+	// Vertex AI backend, which authenticates with Application Default
+	// Credentials when APIKey is empty
+	Project  string
+	Location string
+
+	// BaseURL replaces the API endpoint, e.g. with a proxy or gateway
+	BaseURL string
+	// Headers are added to every request, e.g. the key of an API gateway
+	// or x-goog-user-project to bill another project
+	Headers map[string]string
+
+	// Transport sets the proxy, CA bundle, client certificate and timeouts
+	// of the HTTP client
+	Transport llm.TransportConfig
+
+	// StopSequences end the response when the model writes one; at most 5
+	StopSequences []string
+
+	// APIKeys are keys to rotate between when one is rate limited, used by
+	// NewKeyRotatingClient together with APIKey
+	APIKeys []string
+
 	// Rate limiting configuration (optional)
 	RateLimit         int           // Requests per minute, 0 = disabled (default)
 	RateLimitInterval time.Duration // Rate limit window, default: 1 minute
+
+	// ThinkingBudget caps the thinking tokens of Gemini 2.5 and later models:
+	// 0 turns thinking off and -1 lets the model decide. Nil leaves the
+	// model's default (default).
+	ThinkingBudget *int32
+	// IncludeThoughts returns summaries of the model's thinking in
+	// llm.Message.ReasoningContent
+	IncludeThoughts bool
+
+	// DisableResponseSchema makes CallLLMWithSchema report llm.ErrSchemaNotSupported,
+	// for models without structured output
+	DisableResponseSchema bool
 }
 
 // NewConfigFromEnv creates config from environment variables with sensible defaults
 func NewConfigFromEnv() (*Config, error) {
 	config := &Config{
 		APIKey:            getEnvOrDefault("GOOGLE_API_KEY", ""),
+		APIKeys:           getEnvListOrDefault("GOOGLE_API_KEYS", nil),
 		Model:             getEnvOrDefault("CHAT_MODEL", "gemini-2.0-flash"),
 		Temperature:       getEnvFloatOrDefault("CHAT_TEMPERATURE", 0.7),
 		MaxRetries:        getEnvIntOrDefault("CHAT_MAX_RETRIES", 3),
 		Backend:           genai.BackendGeminiAPI,
+		BaseURL:           getEnvOrDefault("GEMINI_BASE_URL", ""),
+		Headers:           getEnvMapOrDefault("GEMINI_HEADERS", nil),
+		Transport:         transportFromEnv(),
+		StopSequences:     getEnvListOrDefault("GEMINI_STOP_SEQUENCES", nil),
 		RateLimit:         getEnvIntOrDefault("GEMINI_RATE_LIMIT", 0),
 		RateLimitInterval: time.Duration(getEnvIntOrDefault("GEMINI_RATE_LIMIT_INTERVAL_SECONDS", 60)) * time.Second,
+		IncludeThoughts:   os.Getenv("GEMINI_INCLUDE_THOUGHTS") == "true",
+	}
+	if value := os.Getenv("GEMINI_THINKING_BUDGET"); value != "" {
+		budget, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GEMINI_THINKING_BUDGET %q: %w", value, err)
+		}
+		config.ThinkingBudget = genai.Ptr(int32(budget))
 	}
 
 	// Validate required configuration
@@ -44,7 +99,12 @@ func NewConfigFromEnv() (*Config, error) {
 
 // Validate checks if the configuration is valid and complete
 func (c *Config) Validate() error {
-	if c.APIKey == "" {
+	if c.Backend == genai.BackendVertexAI {
+		// Vertex AI express mode takes an API key instead
+		if len(c.keys()) == 0 && (c.Project == "" || c.Location == "") {
+			return fmt.Errorf("project and location are required for the Vertex AI backend")
+		}
+	} else if len(c.keys()) == 0 {
 		return fmt.Errorf("GOOGLE_API_KEY environment variable is required. Please set it with your Google API key")
 	}
 
@@ -68,9 +128,133 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("rateLimitInterval must be positive when rate limiting is enabled, got %v", c.RateLimitInterval)
 	}
 
+	if len(c.StopSequences) > 5 {
+		return fmt.Errorf("at most 5 stop sequences are allowed, got %d", len(c.StopSequences))
+	}
+
+	for name := range c.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+
+	if err := c.Transport.Validate(); err != nil {
+		return fmt.Errorf("invalid transport: %w", err)
+	}
+
+	if c.ThinkingBudget != nil && *c.ThinkingBudget < -1 {
+		return fmt.Errorf("thinkingBudget must be -1, 0 or positive, got %d", *c.ThinkingBudget)
+	}
+
 	return nil
 }
 
+// keys returns APIKey followed by the other APIKeys
+func (c *Config) keys() []string {
+	var keys []string
+	for _, key := range append([]string{c.APIKey}, c.APIKeys...) {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// clientConfig returns the GenAI client settings, with the first of the keys
+func (c *Config) clientConfig() *genai.ClientConfig {
+	var apiKey string
+	if keys := c.keys(); len(keys) > 0 {
+		apiKey = keys[0]
+	}
+	config := &genai.ClientConfig{
+		APIKey:   apiKey,
+		Backend:  c.Backend,
+		Project:  c.Project,
+		Location: c.Location,
+	}
+	config.HTTPOptions.BaseURL = c.BaseURL
+	if len(c.Headers) > 0 {
+		config.HTTPOptions.Headers = make(http.Header, len(c.Headers))
+		for name, value := range c.Headers {
+			config.HTTPOptions.Headers.Set(name, value)
+		}
+	}
+	return config
+}
+
+// httpClient returns the HTTP client of the configured transport, or nil for
+// the GenAI default. The Vertex AI backend authenticates with Application
+// Default Credentials through it when no API key is set, as the GenAI client
+// only does so for its default client.
+func (c *Config) httpClient(ctx context.Context) (*http.Client, error) {
+	if c.Transport.IsZero() {
+		return nil, nil
+	}
+	client, err := c.Transport.NewHTTPClient()
+	if err != nil || c.Backend != genai.BackendVertexAI || len(c.keys()) > 0 {
+		return client, err
+	}
+
+	credential, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+		Client: client,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find default credentials: %w", err)
+	}
+	quotaProject, err := credential.QuotaProjectID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota project ID: %w", err)
+	}
+	authorized, err := httptransport.NewClient(&httptransport.Options{
+		Credentials:      credential,
+		Headers:          http.Header{"X-Goog-User-Project": []string{quotaProject}},
+		BaseRoundTripper: client.Transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	authorized.Timeout = client.Timeout
+	return authorized, nil
+}
+
+// generateConfig adds the configured generation settings to config, which may
+// be nil. Nil is returned when there is nothing to set, leaving the model's
+// defaults.
+func (c *Config) generateConfig(config *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	thinking := c.thinkingConfig()
+	if thinking == nil && len(c.StopSequences) == 0 {
+		return config
+	}
+	if config == nil {
+		config = &genai.GenerateContentConfig{}
+	}
+	config.ThinkingConfig = thinking
+	config.StopSequences = c.StopSequences
+	return config
+}
+
+// thinkingConfig returns the thinking settings of a request, or nil to leave
+// the model's defaults
+func (c *Config) thinkingConfig() *genai.ThinkingConfig {
+	if c.ThinkingBudget == nil && !c.IncludeThoughts {
+		return nil
+	}
+	return &genai.ThinkingConfig{ThinkingBudget: c.ThinkingBudget, IncludeThoughts: c.IncludeThoughts}
+}
+
+// transportFromEnv reads the HTTP client settings from environment variables
+func transportFromEnv() llm.TransportConfig {
+	return llm.TransportConfig{
+		ProxyURL:       getEnvOrDefault("GEMINI_PROXY_URL", ""),
+		CAFile:         getEnvOrDefault("GEMINI_CA_FILE", ""),
+		CertFile:       getEnvOrDefault("GEMINI_CLIENT_CERT_FILE", ""),
+		KeyFile:        getEnvOrDefault("GEMINI_CLIENT_KEY_FILE", ""),
+		ConnectTimeout: time.Duration(getEnvIntOrDefault("GEMINI_CONNECT_TIMEOUT_SECONDS", 0)) * time.Second,
+		Timeout:        time.Duration(getEnvIntOrDefault("GEMINI_TIMEOUT_SECONDS", 0)) * time.Second,
+	}
+}
+
 // getEnvOrDefault returns the environment variable value or a default if not set
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -98,3 +282,34 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvListOrDefault returns a comma-separated environment variable as a list
+// or default if not set
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMapOrDefault returns an environment variable of comma-separated
+// name=value pairs as a map or default if not set
+func getEnvMapOrDefault(key string, defaultValue map[string]string) map[string]string {
+	pairs := getEnvListOrDefault(key, nil)
+	if pairs == nil {
+		return defaultValue
+	}
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, _ := strings.Cut(pair, "=")
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
@@ -0,0 +1,186 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HedgeConfig configures a HedgedProvider
+type HedgeConfig struct {
+	Delay time.Duration // Wait this long for the first response before sending the backup request; 0 sends both at once
+}
+
+// DefaultHedgeConfig sends the backup request after two seconds, so only slow
+// calls cost a second request
+func DefaultHedgeConfig() *HedgeConfig {
+	return &HedgeConfig{Delay: 2 * time.Second}
+}
+
+// HedgeStats counts what hedging cost and won
+type HedgeStats struct {
+	Calls      int // Calls made through the provider
+	Hedged     int // Calls that sent the backup request
+	BackupWins int // Calls answered by the backup request
+}
+
+// HedgedProvider cuts tail latency by racing requests: it sends a call to
+// the primary provider and, when no response arrived after the configured
+// delay or the primary failed, the same call to the backup. The first
+// successful response is returned once the other request was cancelled and
+// has stopped, so no request outlives the call. When both fail, the error
+// joins both errors.
+//
+// Every hedged call may be paid twice. The usage of both requests is reported
+// to a UsageTracker wrapping the HedgedProvider, as far as the cancelled one
+// reports it.
+type HedgedProvider struct {
+	primary LLMProvider
+	backup  LLMProvider
+	config  *HedgeConfig
+
+	mu    sync.Mutex
+	stats HedgeStats
+}
+
+// NewHedgedProvider creates a hedged provider; a nil backup sends the backup
+// request to primary again, which must then be safe for concurrent use, and a
+// nil config uses DefaultHedgeConfig
+func NewHedgedProvider(primary, backup LLMProvider, config *HedgeConfig) *HedgedProvider {
+	if backup == nil {
+		backup = primary
+	}
+	if config == nil {
+		config = DefaultHedgeConfig()
+	}
+	return &HedgedProvider{primary: primary, backup: backup, config: config}
+}
+
+// CallLLM races the call between the primary and backup providers
+func (p *HedgedProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	return p.hedge(ctx, func(ctx context.Context, provider LLMProvider) (Message, error) {
+		return provider.CallLLM(ctx, messages)
+	})
+}
+
+// CallLLMWithSchema races constrained decoding, if both providers have it
+func (p *HedgedProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	primary, primaryOK := p.primary.(SchemaProvider)
+	backup, backupOK := p.backup.(SchemaProvider)
+	if !primaryOK || !backupOK {
+		return Message{}, ErrSchemaNotSupported
+	}
+	return p.hedge(ctx, func(ctx context.Context, provider LLMProvider) (Message, error) {
+		if provider == p.primary {
+			return primary.CallLLMWithSchema(ctx, messages, schema)
+		}
+		return backup.CallLLMWithSchema(ctx, messages, schema)
+	})
+}
+
+// GetName returns the name of the primary provider
+func (p *HedgedProvider) GetName() string {
+	return p.primary.GetName()
+}
+
+// SetConfig updates both providers
+func (p *HedgedProvider) SetConfig(config map[string]any) error {
+	if err := p.primary.SetConfig(config); err != nil {
+		return err
+	}
+	if p.backup != p.primary {
+		return p.backup.SetConfig(config)
+	}
+	return nil
+}
+
+// Stats returns the counts of the calls made so far
+func (p *HedgedProvider) Stats() HedgeStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// hedgeOutcome is what one of the raced requests returned
+type hedgeOutcome struct {
+	response Message
+	err      error
+	usage    *Usage
+	backup   bool
+}
+
+// hedge runs call with the primary provider and, after the delay or a
+// failure, with the backup, returning the first success
+func (p *HedgedProvider) hedge(ctx context.Context, call func(ctx context.Context, provider LLMProvider) (Message, error)) (Message, error) {
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outcomes := make(chan hedgeOutcome, 2)
+	start := func(provider LLMProvider, backup bool) {
+		usage := new(Usage)
+		go func() {
+			response, err := call(context.WithValue(raceCtx, usageKey{}, usage), provider)
+			outcomes <- hedgeOutcome{response: response, err: err, usage: usage, backup: backup}
+		}()
+	}
+	start(p.primary, false)
+	timer := time.NewTimer(p.config.Delay)
+	defer timer.Stop()
+
+	running, hedged := 1, false
+	hedge := func() {
+		if !hedged {
+			hedged = true
+			running++
+			start(p.backup, true)
+		}
+	}
+
+	var usage Usage
+	var errs []error
+	for running > 0 {
+		select {
+		case <-timer.C:
+			hedge()
+		case outcome := <-outcomes:
+			running--
+			usage = usage.Add(*outcome.usage)
+			if outcome.err == nil {
+				// Wait for the losing request to stop
+				cancel()
+				for ; running > 0; running-- {
+					usage = usage.Add(*(<-outcomes).usage)
+				}
+				ReportUsage(ctx, usage)
+				p.record(hedged, outcome.backup)
+				return outcome.response, nil
+			}
+			if outcome.backup {
+				errs = append(errs, fmt.Errorf("backup: %w", outcome.err))
+			} else {
+				errs = append(errs, fmt.Errorf("primary: %w", outcome.err))
+			}
+			if ctx.Err() == nil {
+				hedge()
+			}
+		}
+	}
+	ReportUsage(ctx, usage)
+	p.record(hedged, false)
+	return Message{}, fmt.Errorf("hedged request failed: %w", errors.Join(errs...))
+}
+
+// record counts a finished call
+func (p *HedgedProvider) record(hedged, backupWon bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Calls++
+	if hedged {
+		p.stats.Hedged++
+	}
+	if backupWon {
+		p.stats.BackupWins++
+	}
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// raceProvider answers after a delay, or fails, and reports its usage
+type raceProvider struct {
+	name      string
+	delay     time.Duration
+	err       error
+	calls     atomic.Int32
+	cancelled atomic.Int32
+}
+
+func (p *raceProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		p.cancelled.Add(1)
+		return Message{}, ctx.Err()
+	}
+	ReportUsage(ctx, Usage{InputTokens: 10, OutputTokens: 5})
+	if p.err != nil {
+		return Message{}, p.err
+	}
+	return Message{Role: RoleAssistant, Content: p.name}, nil
+}
+
+func (p *raceProvider) GetName() string                { return p.name }
+func (p *raceProvider) SetConfig(map[string]any) error { return nil }
+
+func TestHedgedProvider(t *testing.T) {
+	failure := errors.New("overloaded")
+	tests := []struct {
+		name      string
+		primary   *raceProvider
+		backup    *raceProvider
+		delay     time.Duration
+		want      string
+		wantErr   bool
+		wantStats HedgeStats
+	}{
+		{"fast primary", &raceProvider{name: "primary"}, &raceProvider{name: "backup"}, time.Second, "primary", false, HedgeStats{Calls: 1}},
+		{"slow primary", &raceProvider{name: "primary", delay: time.Second}, &raceProvider{name: "backup"}, 10 * time.Millisecond, "backup", false, HedgeStats{Calls: 1, Hedged: 1, BackupWins: 1}},
+		{"failed primary", &raceProvider{name: "primary", err: failure}, &raceProvider{name: "backup"}, time.Second, "backup", false, HedgeStats{Calls: 1, Hedged: 1, BackupWins: 1}},
+		{"both at once", &raceProvider{name: "primary", delay: time.Second}, &raceProvider{name: "backup"}, 0, "backup", false, HedgeStats{Calls: 1, Hedged: 1, BackupWins: 1}},
+		{"both fail", &raceProvider{name: "primary", err: failure}, &raceProvider{name: "backup", err: failure}, time.Second, "", true, HedgeStats{Calls: 1, Hedged: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewUsageTracker(nil)
+			hedged := NewHedgedProvider(tt.primary, tt.backup, &HedgeConfig{Delay: tt.delay})
+			provider := tracker.Wrap(hedged, "model")
+
+			start := time.Now()
+			response, err := provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+			if (err != nil) != tt.wantErr || response.Content != tt.want {
+				t.Fatalf("CallLLM() = %q, %v, want %q", response.Content, err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("CallLLM() took %s, want the faster response", elapsed)
+			}
+			if got := hedged.Stats(); got != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.wantStats)
+			}
+			if tt.wantErr && (!errors.Is(err, failure) || !strings.Contains(err.Error(), "primary: ") || !strings.Contains(err.Error(), "backup: ")) {
+				t.Errorf("error = %v, want both failures", err)
+			}
+			if usage := tracker.Usage(); usage.Calls != 1 || usage.InputTokens == 0 {
+				t.Errorf("usage = %+v, want the finished requests reported", usage)
+			}
+		})
+	}
+}
+
+func TestHedgedProvider_CancelsLoser(t *testing.T) {
+	primary := &raceProvider{name: "primary", delay: time.Minute}
+	hedged := NewHedgedProvider(primary, nil, &HedgeConfig{Delay: 10 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Both requests go to the same slow provider, so both lose to the deadline
+	if _, err := hedged.CallLLM(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CallLLM() error = %v, want the deadline", err)
+	}
+	if calls, cancelled := primary.calls.Load(), primary.cancelled.Load(); calls != 2 || cancelled != 2 {
+		t.Errorf("calls = %d, cancelled = %d, want 2 cancelled requests", calls, cancelled)
+	}
+
+	fast := &raceProvider{name: "fast", delay: 20 * time.Millisecond}
+	slow := &raceProvider{name: "slow", delay: time.Minute}
+	hedged = NewHedgedProvider(slow, fast, &HedgeConfig{Delay: 0})
+	if response, err := hedged.CallLLM(context.Background(), nil); err != nil || response.Content != "fast" {
+		t.Fatalf("CallLLM() = %q, %v", response.Content, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for slow.cancelled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if slow.cancelled.Load() != 1 {
+		t.Error("the losing request was not cancelled")
+	}
+}
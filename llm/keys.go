@@ -0,0 +1,204 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is wrapped by the errors of providers for calls refused with
+// HTTP 429, because of a rate limit or a used up quota
+var ErrRateLimited = errors.New("rate limited")
+
+// KeyRotationConfig configures a KeyRotatingProvider
+type KeyRotationConfig struct {
+	Cooldown time.Duration // How long a rate limited key is skipped, default: 1 minute
+}
+
+// DefaultKeyRotationConfig skips a rate limited key for a minute, the window
+// of most per-minute limits
+func DefaultKeyRotationConfig() *KeyRotationConfig {
+	return &KeyRotationConfig{Cooldown: time.Minute}
+}
+
+// KeyStats counts the calls and usage of one API key
+type KeyStats struct {
+	Key          string    `json:"key"`                     // The key masked to its last four characters
+	Calls        int       `json:"calls"`                   // Calls made with the key
+	RateLimited  int       `json:"rate_limited"`            // Calls refused for a rate limit or quota
+	Failed       int       `json:"failed"`                  // Calls that failed otherwise
+	Usage        Usage     `json:"usage"`                   // Usage reported by the calls made with the key
+	CoolingUntil time.Time `json:"cooling_until,omitempty"` // When the key is used again after a rate limit
+}
+
+// KeyRotatingProvider spreads calls over several API keys of one provider,
+// e.g. Gemini free-tier keys or the keys of several OpenAI organizations. It
+// calls with one key until the key is rate limited, then skips the key for the
+// cooldown and retries the call with the next one. Other errors are returned
+// without rotating.
+type KeyRotatingProvider struct {
+	providers []LLMProvider
+	config    *KeyRotationConfig
+
+	mu      sync.Mutex
+	current int
+	stats   []KeyStats
+	now     func() time.Time
+}
+
+// NewKeyRotatingProvider creates a provider for each key with newProvider and
+// rotates between them; a nil config uses DefaultKeyRotationConfig, and an
+// unset Cooldown of config takes its default
+func NewKeyRotatingProvider(keys []string, newProvider func(key string) (LLMProvider, error), config *KeyRotationConfig) (*KeyRotatingProvider, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one API key is required")
+	}
+	defaults := DefaultKeyRotationConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Cooldown < 0 {
+		return nil, fmt.Errorf("cooldown cannot be negative")
+	}
+	withDefaults := *config
+	if withDefaults.Cooldown == 0 {
+		withDefaults.Cooldown = defaults.Cooldown
+	}
+	p := &KeyRotatingProvider{config: &withDefaults, now: time.Now}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			return nil, fmt.Errorf("API keys must be non-empty and distinct")
+		}
+		seen[key] = true
+		provider, err := newProvider(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider for key %s: %w", MaskKey(key), err)
+		}
+		p.providers = append(p.providers, provider)
+		p.stats = append(p.stats, KeyStats{Key: MaskKey(key)})
+	}
+	return p, nil
+}
+
+// MaskKey returns the last four characters of an API key, for logs and stats
+func MaskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "..." + key[len(key)-4:]
+}
+
+// CallLLM calls with the current key, rotating on rate limits
+func (p *KeyRotatingProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	return p.rotate(ctx, func(ctx context.Context, provider LLMProvider) (Message, error) {
+		return provider.CallLLM(ctx, messages)
+	})
+}
+
+// CallLLMWithSchema calls the constrained decoding of the providers, if they have it
+func (p *KeyRotatingProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	if _, ok := p.providers[0].(SchemaProvider); !ok {
+		return Message{}, ErrSchemaNotSupported
+	}
+	return p.rotate(ctx, func(ctx context.Context, provider LLMProvider) (Message, error) {
+		return provider.(SchemaProvider).CallLLMWithSchema(ctx, messages, schema)
+	})
+}
+
+// GetName returns the name of the providers
+func (p *KeyRotatingProvider) GetName() string {
+	return p.providers[0].GetName()
+}
+
+// SetConfig updates the providers of all keys; an "apiKey" setting is
+// rejected, as it would give them all the same key
+func (p *KeyRotatingProvider) SetConfig(config map[string]any) error {
+	if _, ok := config["apiKey"]; ok {
+		return fmt.Errorf("cannot set the API key of a key rotating provider")
+	}
+	for _, provider := range p.providers {
+		if err := provider.SetConfig(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown implements Shutdowner, shutting down the providers of all keys
+func (p *KeyRotatingProvider) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, provider := range p.providers {
+		if shutdowner, ok := provider.(Shutdowner); ok {
+			errs = append(errs, shutdowner.Shutdown(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats returns the counts of each key, in the order the keys were given
+func (p *KeyRotatingProvider) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]KeyStats, len(p.stats))
+	copy(stats, p.stats)
+	return stats
+}
+
+// rotate runs call with the current key and, while it is rate limited, with
+// the next key that is not cooling down, trying each key at most once
+func (p *KeyRotatingProvider) rotate(ctx context.Context, call func(ctx context.Context, provider LLMProvider) (Message, error)) (Message, error) {
+	var errs []error
+	for attempt := 0; ; attempt++ {
+		index, ok := p.next()
+		if !ok || attempt == len(p.providers) {
+			if len(errs) == 0 {
+				return Message{}, fmt.Errorf("all %d API keys are cooling down: %w", len(p.providers), ErrRateLimited)
+			}
+			return Message{}, fmt.Errorf("all %d API keys are rate limited: %w", len(p.providers), errors.Join(errs...))
+		}
+
+		var usage Usage
+		response, err := call(context.WithValue(ctx, usageKey{}, &usage), p.providers[index])
+		ReportUsage(ctx, usage)
+		p.record(index, usage, err)
+		if err == nil || !errors.Is(err, ErrRateLimited) || ctx.Err() != nil {
+			return response, err
+		}
+		errs = append(errs, fmt.Errorf("key %s: %w", p.stats[index].Key, err))
+	}
+}
+
+// next returns the current key, moving on to the next key when it is cooling down
+func (p *KeyRotatingProvider) next() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for i := range p.providers {
+		index := (p.current + i) % len(p.providers)
+		if !now.Before(p.stats[index].CoolingUntil) {
+			p.current = index
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// record counts a call made with a key and puts the key on cooldown when it was rate limited
+func (p *KeyRotatingProvider) record(index int, usage Usage, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := &p.stats[index]
+	stats.Calls++
+	stats.Usage = stats.Usage.Add(usage)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrRateLimited):
+		stats.RateLimited++
+		stats.CoolingUntil = p.now().Add(p.config.Cooldown)
+	default:
+		stats.Failed++
+	}
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// keyProvider answers with its key, or fails when its key is limited
+type keyProvider struct {
+	key     string
+	limited map[string]bool
+}
+
+func (p *keyProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	if p.limited[p.key] {
+		return Message{}, fmt.Errorf("status 429: %w", ErrRateLimited)
+	}
+	ReportUsage(ctx, Usage{InputTokens: 10, OutputTokens: 5})
+	return Message{Role: RoleAssistant, Content: p.key}, nil
+}
+
+func (p *keyProvider) GetName() string                { return "keys" }
+func (p *keyProvider) SetConfig(map[string]any) error { return nil }
+
+func TestKeyRotatingProvider(t *testing.T) {
+	limited := map[string]bool{}
+	provider, err := NewKeyRotatingProvider([]string{"key-one-0001", "key-two-0002", "key-three-0003"}, func(key string) (LLMProvider, error) {
+		return &keyProvider{key: key, limited: limited}, nil
+	}, &KeyRotationConfig{Cooldown: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	call := func() (string, error) {
+		response, err := provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+		return response.Content, err
+	}
+
+	// Calls stay with a key until it is rate limited
+	for _, want := range []string{"key-one-0001", "key-one-0001"} {
+		if got, err := call(); got != want || err != nil {
+			t.Errorf("CallLLM() = %q, %v, want %q", got, err, want)
+		}
+	}
+	limited["key-one-0001"] = true
+	if got, err := call(); got != "key-two-0002" || err != nil {
+		t.Errorf("CallLLM() after rate limit = %q, %v", got, err)
+	}
+
+	// A key rate limited while the others cool down fails the call
+	limited["key-two-0002"], limited["key-three-0003"] = true, true
+	if _, err := call(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("CallLLM() with all keys limited = %v", err)
+	}
+	if _, err := call(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("CallLLM() with all keys cooling = %v", err)
+	}
+
+	// After the cooldown the keys are tried again
+	limited = map[string]bool{}
+	for _, p := range provider.providers {
+		p.(*keyProvider).limited = limited
+	}
+	now = now.Add(time.Minute)
+	if got, err := call(); got != "key-three-0003" || err != nil {
+		t.Errorf("CallLLM() after cooldown = %q, %v", got, err)
+	}
+
+	stats := provider.Stats()
+	want := []KeyStats{
+		{Key: "...0001", Calls: 3, RateLimited: 1, Usage: Usage{InputTokens: 20, OutputTokens: 10}, CoolingUntil: now},
+		{Key: "...0002", Calls: 2, RateLimited: 1, Usage: Usage{InputTokens: 10, OutputTokens: 5}, CoolingUntil: now},
+		{Key: "...0003", Calls: 2, RateLimited: 1, Usage: Usage{InputTokens: 10, OutputTokens: 5}, CoolingUntil: now},
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("Stats()[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
+
+func TestNewKeyRotatingProvider_InvalidKeys(t *testing.T) {
+	newProvider := func(key string) (LLMProvider, error) { return &keyProvider{key: key}, nil }
+	for _, keys := range [][]string{nil, {"a", ""}, {"a", "a"}} {
+		if _, err := NewKeyRotatingProvider(keys, newProvider, nil); err == nil {
+			t.Errorf("NewKeyRotatingProvider(%q) succeeded", keys)
+		}
+	}
+	if _, err := NewKeyRotatingProvider([]string{"a"}, newProvider, &KeyRotationConfig{Cooldown: -time.Second}); err == nil {
+		t.Error("NewKeyRotatingProvider() with a negative cooldown succeeded")
+	}
+	if MaskKey("sk-proj-abcdefgh1234") != "...1234" || MaskKey("short") != "****" {
+		t.Error("MaskKey() shows too much of the key")
+	}
+}
+
+func TestKeyRotatingProvider_ZeroCooldown(t *testing.T) {
+	limited := map[string]bool{"key-one-0001": true, "key-two-0002": true}
+	provider, err := NewKeyRotatingProvider([]string{"key-one-0001", "key-two-0002"}, func(key string) (LLMProvider, error) {
+		return &keyProvider{key: key, limited: limited}, nil
+	}, &KeyRotationConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.config.Cooldown != time.Minute {
+		t.Errorf("Cooldown = %v, want the default", provider.config.Cooldown)
+	}
+
+	// Without the default every limited key would be tried again at once
+	done := make(chan error, 1)
+	go func() {
+		_, err := provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "hi"}})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("CallLLM() with all keys limited = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CallLLM() kept retrying the limited keys")
+	}
+	for _, stats := range provider.Stats() {
+		if stats.Calls != 1 {
+			t.Errorf("key %s was called %d times, want once", stats.Key, stats.Calls)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// WithLabels returns ctx carrying labels such as the tenant or session of a
+// request, added to those ctx already carries. A UsageTracker records the
+// usage of calls made with the context under each of its labels. Nodes
+// implementing core.ContextExecutor already get the labels of their step.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	return core.WithLabels(ctx, labels)
+}
+
+// LabelsFrom returns the labels ctx carries; the map must not be changed
+func LabelsFrom(ctx context.Context) map[string]string {
+	return core.LabelsFrom(ctx)
+}
+
+// labelUsage is the usage recorded under one label value, by model
+type labelUsage map[string]Usage
+
+// recordLabeled adds usage of a call made with labels, by model
+func (t *UsageTracker) recordLabeled(model string, usage Usage, labels map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage[model] = t.usage[model].Add(usage)
+	for name, value := range labels {
+		if t.byLabel == nil {
+			t.byLabel = make(map[string]map[string]labelUsage)
+		}
+		if t.byLabel[name] == nil {
+			t.byLabel[name] = make(map[string]labelUsage)
+		}
+		if t.byLabel[name][value] == nil {
+			t.byLabel[name][value] = make(labelUsage)
+		}
+		t.byLabel[name][value][model] = t.byLabel[name][value][model].Add(usage)
+	}
+}
+
+// ByLabel returns the usage of the calls made with each value of the label,
+// e.g. ByLabel("tenant") for the usage of each tenant. Calls without the
+// label are left out.
+func (t *UsageTracker) ByLabel(name string) map[string]Usage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	usage := make(map[string]Usage, len(t.byLabel[name]))
+	for value, byModel := range t.byLabel[name] {
+		for _, recorded := range byModel {
+			usage[value] = usage[value].Add(recorded)
+		}
+	}
+	return usage
+}
+
+// CostByLabel returns the price in USD of the calls made with each value of
+// the label
+func (t *UsageTracker) CostByLabel(name string) map[string]float64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	cost := make(map[string]float64, len(t.byLabel[name]))
+	for value, byModel := range t.byLabel[name] {
+		for model, recorded := range byModel {
+			cost[value] += t.pricingFor(model).Cost(recorded)
+		}
+	}
+	return cost
+}
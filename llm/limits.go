@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// ErrSizeLimit is matched by every SizeLimitError
+var ErrSizeLimit = errors.New("size limit exceeded")
+
+// SizeLimitError reports a prompt, response or tool result larger than its limit
+type SizeLimitError struct {
+	What  string // "prompt", "response" or "tool result"
+	Size  int    // Size in bytes
+	Limit int    // Limit in bytes
+}
+
+// Error implements error
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("%s of %d bytes exceeds the limit of %d bytes", e.What, e.Size, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrSizeLimit) match
+func (e *SizeLimitError) Is(target error) bool {
+	return target == ErrSizeLimit
+}
+
+// SizeLimitConfig configures a SizeGuardedProvider
+type SizeLimitConfig struct {
+	MaxPromptBytes   int // Most bytes of the messages of a call, media included; 0 for no limit
+	MaxResponseBytes int // Most bytes of a response; 0 for no limit
+}
+
+// DefaultSizeLimitConfig allows prompts of 8 MiB, room for a few images, and
+// responses of 1 MiB
+func DefaultSizeLimitConfig() *SizeLimitConfig {
+	return &SizeLimitConfig{MaxPromptBytes: 8 << 20, MaxResponseBytes: 1 << 20}
+}
+
+// MessageSize returns the bytes of a message that are sent to a model: its
+// content, media, tool calls and tool results, or its blocks other than
+// thinking. Reasoning, metadata and tags are never sent.
+func MessageSize(message Message) int {
+	if len(message.Blocks) > 0 {
+		return blocksSize(message.Blocks)
+	}
+	size := len(message.Content) + len(message.Media)
+	for _, call := range message.ToolCalls {
+		args, _ := json.Marshal(call.ToolArgs)
+		size += len(call.ToolName) + len(args)
+	}
+	for _, result := range message.ToolResults {
+		size += len(result.Content) + len(result.Media) + len(result.Error)
+	}
+	return size
+}
+
+// blocksSize returns the bytes of blocks that are sent to a model
+func blocksSize(blocks []ContentBlock) int {
+	size := 0
+	for _, block := range blocks {
+		switch block.Type {
+		case BlockText:
+			size += len(block.Text)
+		case BlockImage:
+			size += len(block.Data)
+		case BlockToolUse:
+			if block.ToolCall != nil {
+				args, _ := json.Marshal(block.ToolCall.ToolArgs)
+				size += len(block.ToolCall.ToolName) + len(args)
+			}
+		case BlockToolResult:
+			if block.ToolResult != nil {
+				size += len(block.ToolResult.Content) + len(block.ToolResult.Media) + len(block.ToolResult.Error)
+			}
+		}
+	}
+	return size
+}
+
+// PromptSize returns the bytes of messages that are sent to a model
+func PromptSize(messages []Message) int {
+	size := 0
+	for _, message := range messages {
+		size += MessageSize(message)
+	}
+	return size
+}
+
+// SizeGuardedProvider fails calls whose prompt or response exceeds a hard
+// limit with a SizeLimitError, so a runaway node fails cleanly instead of
+// sending megabytes to the API. Oversized prompts are not sent; since the
+// same prompt fails again, their error is marked core.Terminal, so nodes do
+// not retry it.
+type SizeGuardedProvider struct {
+	provider LLMProvider
+	config   *SizeLimitConfig
+}
+
+// NewSizeGuardedProvider wraps provider; a nil config uses DefaultSizeLimitConfig
+func NewSizeGuardedProvider(provider LLMProvider, config *SizeLimitConfig) *SizeGuardedProvider {
+	if config == nil {
+		config = DefaultSizeLimitConfig()
+	}
+	return &SizeGuardedProvider{provider: provider, config: config}
+}
+
+// CallLLM calls the wrapped provider within the limits
+func (p *SizeGuardedProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	return p.guard(messages, func() (Message, error) {
+		return p.provider.CallLLM(ctx, messages)
+	})
+}
+
+// CallLLMWithSchema calls the wrapped provider's constrained decoding, if it has any
+func (p *SizeGuardedProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	schemaProvider, ok := p.provider.(SchemaProvider)
+	if !ok {
+		return Message{}, ErrSchemaNotSupported
+	}
+	return p.guard(messages, func() (Message, error) {
+		return schemaProvider.CallLLMWithSchema(ctx, messages, schema)
+	})
+}
+
+// GetName returns the name of the wrapped provider
+func (p *SizeGuardedProvider) GetName() string {
+	return p.provider.GetName()
+}
+
+// SetConfig updates the wrapped provider
+func (p *SizeGuardedProvider) SetConfig(config map[string]any) error {
+	return p.provider.SetConfig(config)
+}
+
+// Shutdown implements Shutdowner for wrapped providers that have it
+func (p *SizeGuardedProvider) Shutdown(ctx context.Context) error {
+	if shutdowner, ok := p.provider.(Shutdowner); ok {
+		return shutdowner.Shutdown(ctx)
+	}
+	return nil
+}
+
+// guard checks the size of the prompt before call and of the response after it
+func (p *SizeGuardedProvider) guard(messages []Message, call func() (Message, error)) (Message, error) {
+	if limit := p.config.MaxPromptBytes; limit > 0 {
+		if size := PromptSize(messages); size > limit {
+			return Message{}, core.Terminal(&SizeLimitError{What: "prompt", Size: size, Limit: limit})
+		}
+	}
+	response, err := call()
+	if err != nil {
+		return response, err
+	}
+	if limit := p.config.MaxResponseBytes; limit > 0 {
+		if size := MessageSize(response) + len(response.ReasoningContent); size > limit {
+			return Message{}, &SizeLimitError{What: "response", Size: size, Limit: limit}
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+func TestSizeGuardedProvider(t *testing.T) {
+	mock := NewMockProvider("guarded")
+	provider := NewSizeGuardedProvider(mock, &SizeLimitConfig{MaxPromptBytes: 100, MaxResponseBytes: 50})
+	short := []Message{{Role: RoleUser, Content: "hi"}}
+
+	tests := []struct {
+		name     string
+		messages []Message
+		response string
+		wantErr  *SizeLimitError
+	}{
+		{"within limits", short, "hello", nil},
+		{"prompt too large", []Message{{Role: RoleUser, Content: strings.Repeat("x", 60), Media: make([]byte, 60)}}, "hello", &SizeLimitError{What: "prompt", Size: 120, Limit: 100}},
+		{"response too large", short, strings.Repeat("y", 51), &SizeLimitError{What: "response", Size: 51, Limit: 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.Reset()
+			mock.SetResponsePattern(map[string]string{"x": tt.response, "hi": tt.response})
+			_, err := provider.CallLLM(context.Background(), tt.messages)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("CallLLM() error = %v", err)
+				}
+				return
+			}
+			var sizeErr *SizeLimitError
+			if !errors.As(err, &sizeErr) || *sizeErr != *tt.wantErr || !errors.Is(err, ErrSizeLimit) {
+				t.Fatalf("CallLLM() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr.What == "prompt" && mock.GetCallCount() != 0 {
+				t.Error("oversized prompt was sent")
+			}
+		})
+	}
+}
+
+// sizeLimitedNode calls the provider once per attempt and records the error
+// its fallback receives
+type sizeLimitedNode struct {
+	provider LLMProvider
+	attempts int
+	err      error
+}
+
+func (n *sizeLimitedNode) Prep(state *string) []string { return []string{*state} }
+func (n *sizeLimitedNode) Exec(prompt string) (string, error) {
+	n.attempts++
+	response, err := n.provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: prompt}})
+	return response.Content, err
+}
+func (n *sizeLimitedNode) ExecFallback(err error) string {
+	n.err = err
+	return ""
+}
+func (n *sizeLimitedNode) Post(state *string, _ []string, _ ...string) core.Action {
+	return core.ActionSuccess
+}
+
+func TestSizeGuardedProvider_PromptErrorNotRetried(t *testing.T) {
+	mock := NewMockProvider("guarded")
+	node := &sizeLimitedNode{provider: NewSizeGuardedProvider(mock, &SizeLimitConfig{MaxPromptBytes: 10})}
+	state := strings.Repeat("x", 20)
+	core.NewNode[string](node, 3, 1).Run(&state)
+
+	if node.attempts != 1 || mock.GetCallCount() != 0 {
+		t.Errorf("%d attempts and %d calls, want one attempt and no call", node.attempts, mock.GetCallCount())
+	}
+	if !errors.Is(node.err, ErrSizeLimit) || core.IsRetryable(node.err) {
+		t.Errorf("fallback error = %v, want a terminal size limit error", node.err)
+	}
+}
+
+func TestPromptSize(t *testing.T) {
+	messages := []Message{
+		{Role: RoleAssistant, Content: "ok", ReasoningContent: "not sent", ToolCalls: []ToolCalls{{ToolName: "search", ToolArgs: map[string]any{"q": "go"}}}},
+		{Role: RoleUser, ToolResults: []ToolResults{{Content: "result", Media: []byte{1, 2}}}},
+	}
+	// "ok" + "search" + `{"q":"go"}` + "result" + 2 media bytes
+	if got := PromptSize(messages); got != 2+6+10+6+2 {
+		t.Errorf("PromptSize() = %d", got)
+	}
+}
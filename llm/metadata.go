@@ -0,0 +1,62 @@
+package llm
+
+import "slices"
+
+// Metadata keys set by the nodes of this module
+const (
+	MetaTurnID = "turn_id" // Turn of the conversation the message belongs to
+	MetaNode   = "node"    // Node that produced the message
+
+	// Prompt and response tokens of the call that produced the message, as
+	// reported by the provider; set by the providers UsageTracker wraps
+	MetaInputTokens  = "input_tokens"
+	MetaOutputTokens = "output_tokens"
+
+	// Model a RouterProvider sent the call that produced the message to
+	MetaModel = "model"
+
+	// Comma separated IDs of the artifacts the message refers to, see the
+	// artifacts package
+	MetaArtifacts = "artifacts"
+)
+
+// Message tags set by the nodes of this module
+const (
+	TagHidden  = "hidden-from-user" // Part of the model's context but not of the conversation shown to the user
+	TagSummary = "summary"          // Replaces summarized history
+	TagRepair  = "repair"           // A malformed response or the request to repair it, irrelevant once repaired
+	TagCleaned = "cleaned"          // Tool results already checked, and possibly shortened, by a cleanup
+)
+
+// HasTag reports whether the message is tagged with tag
+func (m Message) HasTag(tag string) bool {
+	return slices.Contains(m.Tags, tag)
+}
+
+// WithTags returns the message with tags added, each at most once
+func (m Message) WithTags(tags ...string) Message {
+	m.Tags = slices.Clone(m.Tags)
+	for _, tag := range tags {
+		if !slices.Contains(m.Tags, tag) {
+			m.Tags = append(m.Tags, tag)
+		}
+	}
+	return m
+}
+
+// WithMeta returns the message with the metadata key set to value; the
+// metadata of m is not changed
+func (m Message) WithMeta(key, value string) Message {
+	metadata := make(map[string]string, len(m.Metadata)+1)
+	for k, v := range m.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	m.Metadata = metadata
+	return m
+}
+
+// Meta returns the metadata value of key, or "" when it is not set
+func (m Message) Meta(key string) string {
+	return m.Metadata[key]
+}
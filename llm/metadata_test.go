@@ -0,0 +1,24 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessage_TagsAndMetadata(t *testing.T) {
+	original := Message{Role: RoleAssistant, Content: "Paris", Tags: []string{TagHidden}}
+	tagged := original.WithTags(TagRepair, TagHidden).WithMeta(MetaNode, "chat").WithMeta(MetaTurnID, "3")
+
+	if !reflect.DeepEqual(tagged.Tags, []string{TagHidden, TagRepair}) {
+		t.Errorf("Tags = %v", tagged.Tags)
+	}
+	if !tagged.HasTag(TagRepair) || tagged.HasTag(TagSummary) {
+		t.Errorf("HasTag mismatch for %v", tagged.Tags)
+	}
+	if tagged.Meta(MetaNode) != "chat" || tagged.Meta(MetaTurnID) != "3" {
+		t.Errorf("Metadata = %v", tagged.Metadata)
+	}
+	if original.HasTag(TagRepair) || original.Metadata != nil {
+		t.Errorf("original message was changed: %+v", original)
+	}
+}
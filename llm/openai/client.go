@@ -2,11 +2,15 @@ package openai
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/alt-coder/pocketflow-go/internal/inflight"
 	"github.com/alt-coder/pocketflow-go/llm"
 	"github.com/sashabaranov/go-openai"
 )
@@ -15,18 +19,102 @@ import (
 type OpenAIClient struct {
 	client *openai.Client
 	config *Config
+	media  *dataURLCache
 
 	// Rate limiting
 	rateLimiter *time.Ticker
 	tokens      chan struct{}
+
+	// Calls in flight, which Shutdown waits for
+	calls inflight.Tracker
 }
 
 // CallLLM implements the generic interface, converting messages internally
 func (c *OpenAIClient) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	return c.callLLM(ctx, messages, nil)
+}
+
+// CallLLMWithSchema implements llm.SchemaProvider using the json_schema response format.
+// OpenAI-compatible servers such as Ollama and vLLM translate the schema into a grammar.
+func (c *OpenAIClient) CallLLMWithSchema(ctx context.Context, messages []llm.Message, schema llm.ResponseSchema) (llm.Message, error) {
+	if c.config.DisableResponseSchema {
+		return llm.Message{}, llm.ErrSchemaNotSupported
+	}
+	if len(schema.Schema) == 0 {
+		return llm.Message{}, fmt.Errorf("response schema cannot be empty")
+	}
+
+	name := schema.Name
+	if name == "" {
+		name = "response"
+	}
+
+	return c.callLLM(ctx, messages, &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:        name,
+			Description: schema.Description,
+			Schema:      schema.Schema,
+			Strict:      schema.Strict,
+		},
+	})
+}
+
+// Embed returns one embedding per text using Config.EmbeddingModel, so the client
+// can back a retrieval index
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if !c.calls.Start() {
+		return nil, llm.ErrShutdown
+	}
+	defer c.calls.Done()
+
+	if c.tokens != nil {
+		select {
+		case <-c.tokens:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	model := c.config.EmbeddingModel
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+
+	response, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return embeddings, nil
+}
+
+// callLLM sends a chat completion request with an optional response format
+func (c *OpenAIClient) callLLM(ctx context.Context, messages []llm.Message, responseFormat *openai.ChatCompletionResponseFormat) (llm.Message, error) {
 	result := llm.Message{}
 	if len(messages) == 0 {
 		return result, fmt.Errorf("no messages to send")
 	}
+	if !c.calls.Start() {
+		return result, llm.ErrShutdown
+	}
+	defer c.calls.Done()
 
 	// Apply rate limiting if enabled
 	if c.tokens != nil {
@@ -38,27 +126,42 @@ func (c *OpenAIClient) CallLLM(ctx context.Context, messages []llm.Message) (llm
 		}
 	}
 
-	// Convert messages to OpenAI format
-	openaiMessages, err := c.convertToOpenAIMessages(messages)
+	// Convert messages to OpenAI format, into a slice returned to the pool
+	// once the request is sent
+	pooled := messagePool.Get().(*[]openai.ChatCompletionMessage)
+	openaiMessages, err := c.appendOpenAIMessages((*pooled)[:0], messages)
+	defer func() {
+		clear(openaiMessages)
+		*pooled = openaiMessages[:0]
+		messagePool.Put(pooled)
+	}()
 	if err != nil {
 		return result, fmt.Errorf("failed to convert messages: %w", err)
 	}
 
 	// Create request
 	request := openai.ChatCompletionRequest{
-		Model:    c.config.Model,
-		Messages: openaiMessages,
+		Model:          c.config.Model,
+		Messages:       openaiMessages,
+		ResponseFormat: responseFormat,
 	}
 
 	// Add optional parameters
-	if c.config.Temperature != 0.7 { // Only set if different from default
-		request.Temperature = c.config.Temperature
-	}
-	if c.config.MaxTokens > 0 {
-		request.MaxTokens = c.config.MaxTokens
-	}
-	if c.config.TopP != 1.0 {
-		request.TopP = c.config.TopP
+	if c.config.ReasoningEffort != "" {
+		// Reasoning models count their thinking against max_completion_tokens
+		// and reject sampling parameters
+		request.ReasoningEffort = c.config.ReasoningEffort
+		request.MaxCompletionTokens = c.config.MaxTokens
+	} else {
+		if c.config.Temperature != 0.7 { // Only set if different from default
+			request.Temperature = c.config.Temperature
+		}
+		if c.config.MaxTokens > 0 {
+			request.MaxTokens = c.config.MaxTokens
+		}
+		if c.config.TopP != 1.0 {
+			request.TopP = c.config.TopP
+		}
 	}
 	if c.config.FrequencyPenalty != 0.0 {
 		request.FrequencyPenalty = c.config.FrequencyPenalty
@@ -66,6 +169,7 @@ func (c *OpenAIClient) CallLLM(ctx context.Context, messages []llm.Message) (llm
 	if c.config.PresencePenalty != 0.0 {
 		request.PresencePenalty = c.config.PresencePenalty
 	}
+	request.Stop = c.config.StopSequences
 
 	// Make API call with retries
 	var response openai.ChatCompletionResponse
@@ -90,9 +194,17 @@ func (c *OpenAIClient) CallLLM(ctx context.Context, messages []llm.Message) (llm
 	}
 
 	if lastErr != nil {
+		if rateLimited(lastErr) {
+			lastErr = fmt.Errorf("%w: %w", llm.ErrRateLimited, lastErr)
+		}
 		return result, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
 	}
 
+	llm.ReportUsage(ctx, llm.Usage{
+		InputTokens:  response.Usage.PromptTokens,
+		OutputTokens: response.Usage.CompletionTokens,
+	})
+
 	if len(response.Choices) == 0 {
 		return result, fmt.Errorf("no choices returned from OpenAI API")
 	}
@@ -101,6 +213,10 @@ func (c *OpenAIClient) CallLLM(ctx context.Context, messages []llm.Message) (llm
 	choice := response.Choices[0]
 	result.Role = llm.RoleAssistant
 	result.Content = choice.Message.Content
+	result.ReasoningContent = choice.Message.ReasoningContent
+	// Models served through OpenAI-compatible endpoints may write their
+	// thinking into the content instead
+	result = llm.ExtractReasoning(result)
 
 	// Handle tool calls
 	for _, toolCall := range choice.Message.ToolCalls {
@@ -122,73 +238,163 @@ func (c *OpenAIClient) CallLLM(ctx context.Context, messages []llm.Message) (llm
 	return result, nil
 }
 
+// rateLimited reports whether the API refused a request with HTTP 429, for a
+// rate limit or an exhausted quota
+func rateLimited(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var requestErr *openai.RequestError
+	return errors.As(err, &requestErr) && requestErr.HTTPStatusCode == http.StatusTooManyRequests
+}
+
+// messagePool holds the converted message slices of finished requests, which
+// grow with the conversation, for the next request
+var messagePool = sync.Pool{New: func() any { return new([]openai.ChatCompletionMessage) }}
+
 // convertToOpenAIMessages converts generic messages to OpenAI format
 func (c *OpenAIClient) convertToOpenAIMessages(messages []llm.Message) ([]openai.ChatCompletionMessage, error) {
-	var openaiMessages []openai.ChatCompletionMessage
-
+	size := len(messages)
 	for _, msg := range messages {
+		size += len(msg.ToolResults)
+	}
+	openaiMessages, err := c.appendOpenAIMessages(make([]openai.ChatCompletionMessage, 0, size), messages)
+	if err != nil {
+		return nil, err
+	}
+	return openaiMessages, nil
+}
+
+// appendOpenAIMessages appends messages converted to OpenAI format to dst. On
+// error it returns what was appended so far, so dst can be reused.
+func (c *OpenAIClient) appendOpenAIMessages(dst []openai.ChatCompletionMessage, messages []llm.Message) ([]openai.ChatCompletionMessage, error) {
+	for i, msg := range messages {
+		role, err := llm.NormalizeRole(msg.Role)
+		if err != nil {
+			return dst, fmt.Errorf("message %d: %w", i, err)
+		}
 		openaiMsg := openai.ChatCompletionMessage{
-			Role: msg.Role,
+			Role: role,
+		}
+
+		if len(msg.Blocks) > 0 {
+			if dst, err = c.appendBlocks(dst, &openaiMsg, msg.Blocks); err != nil {
+				return dst, err
+			}
+			dst = append(dst, openaiMsg)
+			continue
 		}
 
 		// Handle content with media
 		if len(msg.Media) > 0 {
-			// Multi-part content with image
-			parts := []openai.ChatMessagePart{
+			// Multi-part content with text and image
+			openaiMsg.MultiContent = []openai.ChatMessagePart{
 				{
 					Type: openai.ChatMessagePartTypeText,
 					Text: msg.Content,
 				},
-			}
-
-			// Add image part
-			imageURL := fmt.Sprintf("data:%s;base64,%s", msg.MimeType, base64.StdEncoding.EncodeToString(msg.Media))
-			parts = append(parts, openai.ChatMessagePart{
-				Type: openai.ChatMessagePartTypeImageURL,
-				ImageURL: &openai.ChatMessageImageURL{
-					URL:    imageURL,
-					Detail: openai.ImageURLDetailAuto,
+				{
+					Type: openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{
+						URL:    c.media.get(msg.MimeType, msg.Media),
+						Detail: openai.ImageURLDetailAuto,
+					},
 				},
-			})
-
-			openaiMsg.MultiContent = parts
+			}
 		} else {
 			// Simple text content
 			openaiMsg.Content = msg.Content
 		}
 
 		// Handle tool calls
+		if len(msg.ToolCalls) > 0 {
+			openaiMsg.ToolCalls = make([]openai.ToolCall, 0, len(msg.ToolCalls))
+		}
 		for _, toolCall := range msg.ToolCalls {
-			args, err := json.Marshal(toolCall.ToolArgs)
+			call, err := convertToolCall(toolCall)
 			if err != nil {
-				return nil, fmt.Errorf("failed to marshal tool arguments: %w", err)
+				return dst, err
 			}
-
-			openaiMsg.ToolCalls = append(openaiMsg.ToolCalls, openai.ToolCall{
-				ID:   toolCall.Id,
-				Type: openai.ToolTypeFunction,
-				Function: openai.FunctionCall{
-					Name:      toolCall.ToolName,
-					Arguments: string(args),
-				},
-			})
+			openaiMsg.ToolCalls = append(openaiMsg.ToolCalls, call)
 		}
 
-		// Handle tool results
+		// Handle tool results, each as a separate message
 		for _, toolResult := range msg.ToolResults {
-			// Add tool result as a separate message
-			toolResultMsg := openai.ChatCompletionMessage{
-				Role:       openai.ChatMessageRoleTool,
-				Content:    toolResult.Content,
-				ToolCallID: toolResult.Id,
-			}
-			openaiMessages = append(openaiMessages, toolResultMsg)
+			dst = append(dst, toolMessage(toolResult))
 		}
 
-		openaiMessages = append(openaiMessages, openaiMsg)
+		dst = append(dst, openaiMsg)
 	}
 
-	return openaiMessages, nil
+	return dst, nil
+}
+
+// appendBlocks sets the content and tool calls of openaiMsg from blocks, in
+// their order, and appends their tool results to dst as tool messages.
+// Content with images is sent in parts; thinking is not sent.
+func (c *OpenAIClient) appendBlocks(dst []openai.ChatCompletionMessage, openaiMsg *openai.ChatCompletionMessage, blocks []llm.ContentBlock) ([]openai.ChatCompletionMessage, error) {
+	multipart := slices.ContainsFunc(blocks, func(block llm.ContentBlock) bool { return block.Type == llm.BlockImage })
+	for _, block := range blocks {
+		switch block.Type {
+		case llm.BlockText:
+			if multipart {
+				openaiMsg.MultiContent = append(openaiMsg.MultiContent, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeText,
+					Text: block.Text,
+				})
+			} else {
+				openaiMsg.Content += block.Text
+			}
+		case llm.BlockImage:
+			openaiMsg.MultiContent = append(openaiMsg.MultiContent, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL:    c.media.get(block.MimeType, block.Data),
+					Detail: openai.ImageURLDetailAuto,
+				},
+			})
+		case llm.BlockToolUse:
+			if block.ToolCall == nil {
+				continue
+			}
+			call, err := convertToolCall(*block.ToolCall)
+			if err != nil {
+				return dst, err
+			}
+			openaiMsg.ToolCalls = append(openaiMsg.ToolCalls, call)
+		case llm.BlockToolResult:
+			if block.ToolResult != nil {
+				dst = append(dst, toolMessage(*block.ToolResult))
+			}
+		}
+	}
+	return dst, nil
+}
+
+// convertToolCall converts a tool call to OpenAI format
+func convertToolCall(toolCall llm.ToolCalls) (openai.ToolCall, error) {
+	args, err := json.Marshal(toolCall.ToolArgs)
+	if err != nil {
+		return openai.ToolCall{}, fmt.Errorf("failed to marshal tool arguments: %w", err)
+	}
+	return openai.ToolCall{
+		ID:   toolCall.Id,
+		Type: openai.ToolTypeFunction,
+		Function: openai.FunctionCall{
+			Name:      toolCall.ToolName,
+			Arguments: string(args),
+		},
+	}, nil
+}
+
+// toolMessage converts a tool result to an OpenAI tool message
+func toolMessage(toolResult llm.ToolResults) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role:       openai.ChatMessageRoleTool,
+		Content:    toolResult.Content,
+		ToolCallID: toolResult.Id,
+	}
 }
 
 // GetName returns the provider name
@@ -209,40 +415,42 @@ func (c *OpenAIClient) SetConfig(config map[string]any) error {
 	if temp, ok := config["temperature"].(float32); ok {
 		c.config.Temperature = temp
 	}
+	// The API key, base URL and headers are set on the client, which is
+	// recreated when one of them changes
+	recreate := false
 	if apiKey, ok := config["apiKey"].(string); ok {
 		c.config.APIKey = apiKey
-		// Recreate client with new API key
-		clientConfig := openai.DefaultConfig(apiKey)
-		if c.config.BaseURL != "" {
-			clientConfig.BaseURL = c.config.BaseURL
-		}
-		if c.config.OrgID != "" {
-			clientConfig.OrgID = c.config.OrgID
-		}
-		c.client = openai.NewClientWithConfig(clientConfig)
+		recreate = true
 	}
 	if maxRetries, ok := config["maxRetries"].(int); ok {
 		c.config.MaxRetries = maxRetries
 	}
 	if baseURL, ok := config["baseURL"].(string); ok {
 		c.config.BaseURL = baseURL
-		// Recreate client with new base URL
-		clientConfig := openai.DefaultConfig(c.config.APIKey)
-		clientConfig.BaseURL = baseURL
-		if c.config.OrgID != "" {
-			clientConfig.OrgID = c.config.OrgID
-		}
-		c.client = openai.NewClientWithConfig(clientConfig)
+		recreate = true
 	}
 	if orgID, ok := config["orgID"].(string); ok {
 		c.config.OrgID = orgID
-		// Recreate client with new org ID
-		clientConfig := openai.DefaultConfig(c.config.APIKey)
-		if c.config.BaseURL != "" {
-			clientConfig.BaseURL = c.config.BaseURL
+		recreate = true
+	}
+	if projectID, ok := config["projectID"].(string); ok {
+		c.config.ProjectID = projectID
+		recreate = true
+	}
+	if headers, ok := config["headers"].(map[string]string); ok {
+		c.config.Headers = headers
+		recreate = true
+	}
+	if transport, ok := config["transport"].(llm.TransportConfig); ok {
+		c.config.Transport = transport
+		recreate = true
+	}
+	if recreate {
+		client, err := newAPIClient(c.config)
+		if err != nil {
+			return err
 		}
-		clientConfig.OrgID = orgID
-		c.client = openai.NewClientWithConfig(clientConfig)
+		c.client = client
 	}
 	if rateLimit, ok := config["rateLimit"].(int); ok {
 		c.config.RateLimit = rateLimit
@@ -262,6 +470,18 @@ func (c *OpenAIClient) SetConfig(config map[string]any) error {
 	if presPenalty, ok := config["presencePenalty"].(float32); ok {
 		c.config.PresencePenalty = presPenalty
 	}
+	if disableSchema, ok := config["disableResponseSchema"].(bool); ok {
+		c.config.DisableResponseSchema = disableSchema
+	}
+	if stopSequences, ok := config["stopSequences"].([]string); ok {
+		c.config.StopSequences = stopSequences
+	}
+	if reasoningEffort, ok := config["reasoningEffort"].(string); ok {
+		c.config.ReasoningEffort = reasoningEffort
+	}
+	if embeddingModel, ok := config["embeddingModel"].(string); ok {
+		c.config.EmbeddingModel = embeddingModel
+	}
 
 	return nil
 }
@@ -277,21 +497,14 @@ func NewOpenAIClient(ctx context.Context, config *Config) (*OpenAIClient, error)
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Create OpenAI client configuration
-	clientConfig := openai.DefaultConfig(config.APIKey)
-	if config.BaseURL != "" {
-		clientConfig.BaseURL = config.BaseURL
-	}
-	if config.OrgID != "" {
-		clientConfig.OrgID = config.OrgID
+	apiClient, err := newAPIClient(config)
+	if err != nil {
+		return nil, err
 	}
-
-	// Create the OpenAI client
-	openaiClient := openai.NewClientWithConfig(clientConfig)
-
 	client := &OpenAIClient{
-		client: openaiClient,
+		client: apiClient,
 		config: config,
+		media:  newDataURLCache(),
 	}
 
 	// Initialize rate limiter only if rate limiting is enabled
@@ -314,6 +527,63 @@ func NewOpenAIClient(ctx context.Context, config *Config) (*OpenAIClient, error)
 	return client, nil
 }
 
+// newAPIClient creates the go-openai client for a configuration, with the
+// first of its keys
+func newAPIClient(config *Config) (*openai.Client, error) {
+	var apiKey string
+	if keys := config.keys(); len(keys) > 0 {
+		apiKey = keys[0]
+	}
+	clientConfig := openai.DefaultConfig(apiKey)
+	if config.BaseURL != "" {
+		clientConfig.BaseURL = config.BaseURL
+	}
+	if config.OrgID != "" {
+		clientConfig.OrgID = config.OrgID
+	}
+	headers := make(http.Header, len(config.Headers)+1)
+	for name, value := range config.Headers {
+		headers.Set(name, value)
+	}
+	if config.ProjectID != "" {
+		headers.Set("OpenAI-Project", config.ProjectID)
+	}
+	if len(headers) > 0 || !config.Transport.IsZero() {
+		httpClient := &http.Client{}
+		if !config.Transport.IsZero() {
+			var err error
+			if httpClient, err = config.Transport.NewHTTPClient(); err != nil {
+				return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+			}
+		}
+		if len(headers) > 0 {
+			httpClient.Transport = &headerTransport{base: httpClient.Transport, headers: headers}
+		}
+		clientConfig.HTTPClient = httpClient
+	}
+	return openai.NewClientWithConfig(clientConfig), nil
+}
+
+// headerTransport adds headers to every request; go-openai has no option for
+// headers of its own
+type headerTransport struct {
+	base    http.RoundTripper // http.DefaultTransport when nil
+	headers http.Header
+}
+
+// RoundTrip sends request with the extra headers
+func (t *headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	for name, values := range t.headers {
+		request.Header[name] = values
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(request)
+}
+
 // NewOpenAIClientFromEnv creates a new OpenAI client using environment variables
 func NewOpenAIClientFromEnv(ctx context.Context) (*OpenAIClient, error) {
 	config, err := NewConfigFromEnv()
@@ -324,6 +594,20 @@ func NewOpenAIClientFromEnv(ctx context.Context) (*OpenAIClient, error) {
 	return NewOpenAIClient(ctx, config)
 }
 
+// NewKeyRotatingClient creates a client for each of the configured keys,
+// APIKey and then APIKeys, and rotates to the next key when one is rate
+// limited. Lower MaxRetries, so a rate limited key is not retried first.
+func NewKeyRotatingClient(ctx context.Context, config *Config, rotation *llm.KeyRotationConfig) (*llm.KeyRotatingProvider, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	return llm.NewKeyRotatingProvider(config.keys(), func(key string) (llm.LLMProvider, error) {
+		keyConfig := *config
+		keyConfig.APIKey, keyConfig.APIKeys = key, nil
+		return NewOpenAIClient(ctx, &keyConfig)
+	}, rotation)
+}
+
 // refillTokens runs in a goroutine to refill the token bucket at the configured rate
 func (c *OpenAIClient) refillTokens() {
 	for range c.rateLimiter.C {
@@ -336,6 +620,14 @@ func (c *OpenAIClient) refillTokens() {
 	}
 }
 
+// Shutdown implements llm.Shutdowner: new calls fail with llm.ErrShutdown,
+// calls in flight are waited for until ctx is done, and then the client is closed
+func (c *OpenAIClient) Shutdown(ctx context.Context) error {
+	err := c.calls.Shutdown(ctx)
+	c.Close()
+	return err
+}
+
 // Close stops the rate limiter and cleans up resources
 func (c *OpenAIClient) Close() {
 	if c.rateLimiter != nil {
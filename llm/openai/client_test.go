@@ -2,10 +2,16 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNewOpenAIClient_InvalidConfig(t *testing.T) {
@@ -273,6 +279,70 @@ func TestOpenAIClient_ConvertMessagesWithToolResults(t *testing.T) {
 	}
 }
 
+func TestOpenAIClient_ConvertMessagesWithBlocks(t *testing.T) {
+	client, err := NewOpenAIClient(context.Background(), &Config{APIKey: "test-key", Model: "gpt-4o", MaxRetries: 3})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	user, _ := llm.NewBlockMessage(llm.RoleUser,
+		llm.TextBlock("Before:"),
+		llm.ImageBlock("image/png", []byte("before")),
+		llm.TextBlock("After:"),
+		llm.ImageBlock("image/png", []byte("after")),
+	)
+	assistant, _ := llm.NewBlockMessage(llm.RoleAssistant,
+		llm.ThinkingBlock("Check the page first."),
+		llm.TextBlock("Let me look."),
+		llm.ToolUseBlock(llm.ToolCalls{Id: "call_1", ToolName: "fetch", ToolArgs: map[string]any{"url": "/home"}}),
+	)
+	results, _ := llm.NewBlockMessage(llm.RoleUser, llm.ToolResultBlock(llm.ToolResults{Id: "call_1", Content: "200 OK"}))
+
+	openaiMessages, err := client.convertToOpenAIMessages([]llm.Message{user, assistant, results})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+	if len(openaiMessages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(openaiMessages))
+	}
+
+	parts := openaiMessages[0].MultiContent
+	if len(parts) != 4 || parts[0].Text != "Before:" || parts[1].ImageURL == nil || parts[2].Text != "After:" || parts[3].ImageURL == nil {
+		t.Errorf("Expected the text and both images in order, got %+v", parts)
+	}
+	if parts[1].ImageURL.URL == parts[3].ImageURL.URL {
+		t.Error("Expected the images to differ")
+	}
+
+	if openaiMessages[1].Content != "Let me look." || len(openaiMessages[1].ToolCalls) != 1 || openaiMessages[1].ToolCalls[0].Function.Arguments != `{"url":"/home"}` {
+		t.Errorf("Expected the text and the tool call without thinking, got %+v", openaiMessages[1])
+	}
+	if openaiMessages[2].Role != "tool" || openaiMessages[2].ToolCallID != "call_1" || openaiMessages[2].Content != "200 OK" {
+		t.Errorf("Expected the tool result as a tool message, got %+v", openaiMessages[2])
+	}
+}
+
+func TestOpenAIClient_ConvertMessageRoles(t *testing.T) {
+	client, err := NewOpenAIClient(context.Background(), &Config{APIKey: "test-key", Model: "gpt-4", BaseURL: "https://api.openai.com/v1"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	openaiMessages, err := client.convertToOpenAIMessages([]llm.Message{{Role: "developer", Content: "Be brief."}, {Role: "Human", Content: "Hi"}, {Role: "model", Content: "Hello"}})
+	if err != nil {
+		t.Fatalf("Failed to convert messages: %v", err)
+	}
+	for i, want := range []string{"system", "user", "assistant"} {
+		if openaiMessages[i].Role != want {
+			t.Errorf("Message %d has role %q, want %q", i, openaiMessages[i].Role, want)
+		}
+	}
+
+	if _, err := client.convertToOpenAIMessages([]llm.Message{{Role: "narrator", Content: "Once"}}); !errors.Is(err, llm.ErrUnknownRole) {
+		t.Errorf("Expected ErrUnknownRole, got %v", err)
+	}
+}
+
 func TestOpenAIClient_RateLimiting(t *testing.T) {
 	config := &Config{
 		APIKey:            "test-key",
@@ -333,6 +403,30 @@ func TestOpenAIClient_Close(t *testing.T) {
 	// But at least verify Close() doesn't panic
 }
 
+func TestOpenAIClient_Shutdown(t *testing.T) {
+	client, err := NewOpenAIClient(context.Background(), &Config{
+		APIKey:      "test-key",
+		Model:       "gpt-4",
+		Temperature: 0.7,
+		MaxRetries:  3,
+		BaseURL:     "https://api.openai.com/v1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+
+	_, err = client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "Hello"}})
+	if !errors.Is(err, llm.ErrShutdown) {
+		t.Errorf("CallLLM() after Shutdown = %v, want %v", err, llm.ErrShutdown)
+	}
+	if _, err := client.Embed(context.Background(), []string{"Hello"}); !errors.Is(err, llm.ErrShutdown) {
+		t.Errorf("Embed() after Shutdown = %v, want %v", err, llm.ErrShutdown)
+	}
+}
+
 func TestNewOpenAIClientFromEnv(t *testing.T) {
 	// This test would require setting environment variables
 	// For now, we'll just test that it fails without required env vars
@@ -346,3 +440,353 @@ func TestNewOpenAIClientFromEnv(t *testing.T) {
 		t.Log("NewOpenAIClientFromEnv failed as expected without API key")
 	}
 }
+
+func TestOpenAIClient_CallLLMWithSchema(t *testing.T) {
+	var requestBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"name\":\"widget\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient(context.Background(), &Config{
+		APIKey:  "test-key",
+		Model:   "gpt-4o",
+		BaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	schema := llm.ResponseSchema{Name: "widget", Schema: json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`)}
+	response, err := client.CallLLMWithSchema(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}, schema)
+	if err != nil {
+		t.Fatalf("CallLLMWithSchema failed: %v", err)
+	}
+	if response.Content != `{"name":"widget"}` {
+		t.Errorf("Unexpected content %q", response.Content)
+	}
+
+	format, _ := requestBody["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Fatalf("Expected json_schema response format, got %v", requestBody["response_format"])
+	}
+	jsonSchema, _ := format["json_schema"].(map[string]any)
+	if jsonSchema["name"] != "widget" || jsonSchema["schema"] == nil {
+		t.Errorf("Unexpected json_schema %v", jsonSchema)
+	}
+
+	client.SetConfig(map[string]any{"disableResponseSchema": true})
+	if _, err := client.CallLLMWithSchema(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}, schema); !errors.Is(err, llm.ErrSchemaNotSupported) {
+		t.Errorf("Expected ErrSchemaNotSupported, got %v", err)
+	}
+}
+
+func TestOpenAIClient_Reasoning(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       string
+		wantContent   string
+		wantReasoning string
+	}{
+		{"reasoning field", `{"role":"assistant","content":"4","reasoning_content":"2 + 2"}`, "4", "2 + 2"},
+		{"inline think block", `{"role":"assistant","content":"<think>2 + 2</think>\n4"}`, "4", "2 + 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestBody map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&requestBody)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"choices":[{"message":` + tt.message + `}]}`))
+			}))
+			defer server.Close()
+
+			client, err := NewOpenAIClient(context.Background(), &Config{
+				APIKey:          "test-key",
+				Model:           "o3-mini",
+				BaseURL:         server.URL,
+				Temperature:     0.2,
+				MaxTokens:       2000,
+				ReasoningEffort: "high",
+			})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			response, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "2 + 2?"}})
+			if err != nil {
+				t.Fatalf("CallLLM failed: %v", err)
+			}
+			if response.Content != tt.wantContent || response.ReasoningContent != tt.wantReasoning {
+				t.Errorf("Unexpected response %+v", response)
+			}
+			if requestBody["reasoning_effort"] != "high" || requestBody["max_completion_tokens"] != float64(2000) {
+				t.Errorf("Expected reasoning_effort and max_completion_tokens, got %v", requestBody)
+			}
+			if _, ok := requestBody["temperature"]; ok {
+				t.Errorf("Expected no temperature for a reasoning model, got %v", requestBody["temperature"])
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_HeadersAndStopSequences(t *testing.T) {
+	var header http.Header
+	var requestBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient(context.Background(), &Config{
+		APIKey:        "test-key",
+		Model:         "gpt-4o",
+		BaseURL:       server.URL,
+		OrgID:         "org-1",
+		ProjectID:     "proj-1",
+		Headers:       map[string]string{"X-Gateway-Key": "gateway-secret"},
+		StopSequences: []string{"END"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"Authorization":       "Bearer test-key",
+		"OpenAI-Organization": "org-1",
+		"OpenAI-Project":      "proj-1",
+		"X-Gateway-Key":       "gateway-secret",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("Header %s = %q, want %q", name, got, want)
+		}
+	}
+	if stop, _ := requestBody["stop"].([]any); len(stop) != 1 || stop[0] != "END" {
+		t.Errorf("Expected stop [END], got %v", requestBody["stop"])
+	}
+
+	client.SetConfig(map[string]any{"projectID": "proj-2"})
+	if _, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if header.Get("OpenAI-Project") != "proj-2" || header.Get("X-Gateway-Key") != "gateway-secret" {
+		t.Errorf("Unexpected headers after SetConfig %v", header)
+	}
+}
+
+func TestOpenAIClient_Embed(t *testing.T) {
+	var requestBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&requestBody)
+		w.Header().Set("Content-Type", "application/json")
+		// Returned out of order to check results follow the input order
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient(context.Background(), &Config{
+		APIKey:  "test-key",
+		Model:   "gpt-4o",
+		BaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	embeddings, err := client.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(embeddings) != 2 || embeddings[0][0] != 1 || embeddings[1][1] != 1 {
+		t.Errorf("Unexpected embeddings %v", embeddings)
+	}
+	if requestBody["model"] != DefaultEmbeddingModel {
+		t.Errorf("Expected default embedding model, got %v", requestBody["model"])
+	}
+}
+
+func TestOpenAIClient_ReportsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOpenAIClient(context.Background(), &Config{
+		APIKey:  "test-key",
+		Model:   "gpt-4o",
+		BaseURL: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	tracker := llm.NewUsageTracker(map[string]llm.Pricing{"gpt-4o": {InputPerMillion: 2.5, OutputPerMillion: 10}})
+	provider := tracker.Wrap(client, "gpt-4o")
+	if _, err := provider.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+
+	usage := tracker.Usage()
+	if usage.Calls != 1 || usage.InputTokens != 12 || usage.OutputTokens != 3 || usage.Estimated {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	if cost := tracker.Cost(); cost != (12*2.5+3*10)/1e6 {
+		t.Errorf("Unexpected cost %v", cost)
+	}
+}
+
+// benchmarkConversation returns a conversation of turns user and assistant
+// messages, with a tool call and result in every turn and an image in every
+// tenth user message
+func benchmarkConversation(turns int) []llm.Message {
+	image := make([]byte, 64<<10)
+	var messages []llm.Message
+	for i := 0; i < turns; i++ {
+		user := llm.Message{Role: llm.RoleUser, Content: "Describe what changed since the last screenshot."}
+		if i%10 == 0 {
+			user.Media, user.MimeType = image, "image/png"
+		}
+		messages = append(messages, user, llm.Message{
+			Role:      llm.RoleAssistant,
+			Content:   "Let me look at the file.",
+			ToolCalls: []llm.ToolCalls{{Id: "call_1", ToolName: "read_file", ToolArgs: map[string]any{"path": "main.go"}}},
+		}, llm.Message{
+			Role:        llm.RoleUser,
+			ToolResults: []llm.ToolResults{{Id: "call_1", Content: "package main"}},
+		})
+	}
+	return messages
+}
+
+func BenchmarkConvertToOpenAIMessages(b *testing.B) {
+	for _, turns := range []int{10, 100} {
+		messages := benchmarkConversation(turns)
+		b.Run(fmt.Sprintf("turns=%d", turns), func(b *testing.B) {
+			client := &OpenAIClient{config: &Config{Model: "gpt-4o"}}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.convertToOpenAIMessages(messages); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		// As callLLM converts them: into a pooled slice, with cached media
+		b.Run(fmt.Sprintf("turns=%d/pooled", turns), func(b *testing.B) {
+			client := &OpenAIClient{config: &Config{Model: "gpt-4o"}, media: newDataURLCache()}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pooled := messagePool.Get().(*[]openai.ChatCompletionMessage)
+				converted, err := client.appendOpenAIMessages((*pooled)[:0], messages)
+				if err != nil {
+					b.Fatal(err)
+				}
+				clear(converted)
+				*pooled = converted[:0]
+				messagePool.Put(pooled)
+			}
+		})
+	}
+}
+
+func TestDataURLCache(t *testing.T) {
+	cache := newDataURLCache()
+	media := []byte("fake-image-data")
+	want := "data:image/png;base64,ZmFrZS1pbWFnZS1kYXRh"
+	if got := cache.get("image/png", media); got != want {
+		t.Errorf("get() = %q, want %q", got, want)
+	}
+	if got := cache.get("image/png", media); got != want {
+		t.Errorf("cached get() = %q, want %q", got, want)
+	}
+
+	// Media changed in place is encoded again
+	media[0] = 'F'
+	if got := cache.get("image/png", media); got != "data:image/png;base64,RmFrZS1pbWFnZS1kYXRh" {
+		t.Errorf("get() of changed media = %q", got)
+	}
+
+	for i := 0; i < maxCachedDataURLs+10; i++ {
+		cache.get("image/png", []byte{byte(i)})
+	}
+	if len(cache.entries) != maxCachedDataURLs || len(cache.order) != maxCachedDataURLs {
+		t.Errorf("cache holds %d entries, want %d", len(cache.entries), maxCachedDataURLs)
+	}
+}
+
+func TestNewKeyRotatingClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") == "Bearer key-free-tier-1" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"You exceeded your current quota","type":"insufficient_quota"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewKeyRotatingClient(context.Background(), &Config{
+		APIKey:  "key-free-tier-1",
+		APIKeys: []string{"key-free-tier-1", "key-free-tier-2"},
+		Model:   "gpt-4o",
+		BaseURL: server.URL,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	response, err := provider.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}})
+	if err != nil || response.Content != "hello" {
+		t.Fatalf("CallLLM() = %+v, %v", response, err)
+	}
+	stats := provider.Stats()
+	if len(stats) != 2 || stats[0].RateLimited != 1 || stats[1].Calls != 1 || stats[1].Usage.InputTokens != 12 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestOpenAIClient_Transport(t *testing.T) {
+	var header http.Header
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host != "api.openai.internal" {
+			t.Errorf("Proxy received request for %s", r.URL.Host)
+		}
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewOpenAIClient(context.Background(), &Config{
+		APIKey:    "test-key",
+		Model:     "gpt-4o",
+		BaseURL:   "http://api.openai.internal/v1",
+		Headers:   map[string]string{"X-Gateway-Key": "gateway-secret"},
+		Transport: llm.TransportConfig{ProxyURL: proxy.URL, Timeout: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}}); err != nil {
+		t.Fatalf("CallLLM through the proxy failed: %v", err)
+	}
+	if header.Get("X-Gateway-Key") != "gateway-secret" {
+		t.Errorf("Headers were not sent through the proxy: %v", header)
+	}
+
+	if err := client.SetConfig(map[string]any{"transport": llm.TransportConfig{CAFile: "/missing/ca.pem"}}); err == nil {
+		t.Error("Expected an error for a missing CA bundle")
+	}
+}
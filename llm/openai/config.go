@@ -3,10 +3,17 @@ package openai
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
 )
 
+// DefaultEmbeddingModel is used by Embed when Config.EmbeddingModel is empty
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
 // Config holds OpenAI-specific configuration settings
 type Config struct {
 	APIKey      string  // OpenAI API key
@@ -14,7 +21,20 @@ type Config struct {
 	Temperature float32 // Default: 0.7
 	MaxRetries  int     // Default: 3
 	BaseURL     string  // Default: "https://api.openai.com/v1"
-	OrgID       string  // Optional organization ID
+	OrgID       string  // Optional organization ID, sent as OpenAI-Organization
+	ProjectID   string  // Optional project ID, sent as OpenAI-Project
+
+	// Headers are added to every request, e.g. the key of an API gateway
+	// or the routing headers of a proxy
+	Headers map[string]string
+
+	// Transport sets the proxy, CA bundle, client certificate and timeouts
+	// of the HTTP client
+	Transport llm.TransportConfig
+
+	// APIKeys are keys to rotate between when one is rate limited, used by
+	// NewKeyRotatingClient together with APIKey
+	APIKeys []string
 
 	// Rate limiting configuration (optional)
 	RateLimit         int           // Requests per minute, 0 = disabled (default)
@@ -25,23 +45,47 @@ type Config struct {
 	TopP             float32 // Nucleus sampling parameter, default: 1.0
 	FrequencyPenalty float32 // Frequency penalty, default: 0.0
 	PresencePenalty  float32 // Presence penalty, default: 0.0
+
+	// StopSequences end the response when the model writes one; at most 4
+	StopSequences []string
+
+	// ReasoningEffort is "low", "medium" or "high" for o-series and other
+	// reasoning models; empty leaves it to the model (default). When set,
+	// MaxTokens is sent as max_completion_tokens, since it also covers the
+	// reasoning tokens, and Temperature and TopP are left out, as reasoning
+	// models reject them.
+	ReasoningEffort string
+
+	// EmbeddingModel is used by Embed; default: "text-embedding-3-small"
+	EmbeddingModel string
+
+	// DisableResponseSchema makes CallLLMWithSchema report llm.ErrSchemaNotSupported,
+	// for OpenAI-compatible endpoints without json_schema support
+	DisableResponseSchema bool
 }
 
 // NewConfigFromEnv creates config from environment variables with sensible defaults
 func NewConfigFromEnv() (*Config, error) {
 	config := &Config{
 		APIKey:            getEnvOrDefault("OPENAI_API_KEY", ""),
+		APIKeys:           getEnvListOrDefault("OPENAI_API_KEYS", nil),
 		Model:             getEnvOrDefault("OPENAI_MODEL", "gpt-4o"),
 		Temperature:       getEnvFloatOrDefault("OPENAI_TEMPERATURE", 0.7),
 		MaxRetries:        getEnvIntOrDefault("OPENAI_MAX_RETRIES", 3),
 		BaseURL:           getEnvOrDefault("OPENAI_BASE_URL", "https://api.openai.com/v1"),
 		OrgID:             getEnvOrDefault("OPENAI_ORG_ID", ""),
+		ProjectID:         getEnvOrDefault("OPENAI_PROJECT_ID", ""),
+		Headers:           getEnvMapOrDefault("OPENAI_HEADERS", nil),
+		Transport:         transportFromEnv(),
 		RateLimit:         getEnvIntOrDefault("OPENAI_RATE_LIMIT", 0),
 		RateLimitInterval: time.Duration(getEnvIntOrDefault("OPENAI_RATE_LIMIT_INTERVAL_SECONDS", 60)) * time.Second,
 		MaxTokens:         getEnvIntOrDefault("OPENAI_MAX_TOKENS", 0),
 		TopP:              getEnvFloatOrDefault("OPENAI_TOP_P", 1.0),
 		FrequencyPenalty:  getEnvFloatOrDefault("OPENAI_FREQUENCY_PENALTY", 0.0),
 		PresencePenalty:   getEnvFloatOrDefault("OPENAI_PRESENCE_PENALTY", 0.0),
+		ReasoningEffort:   getEnvOrDefault("OPENAI_REASONING_EFFORT", ""),
+		StopSequences:     getEnvListOrDefault("OPENAI_STOP_SEQUENCES", nil),
+		EmbeddingModel:    getEnvOrDefault("OPENAI_EMBEDDING_MODEL", DefaultEmbeddingModel),
 	}
 
 	// Validate required configuration
@@ -54,7 +98,7 @@ func NewConfigFromEnv() (*Config, error) {
 
 // Validate checks if the configuration is valid and complete
 func (c *Config) Validate() error {
-	if c.APIKey == "" {
+	if len(c.keys()) == 0 {
 		return fmt.Errorf("OPENAI_API_KEY environment variable is required. Please set it with your OpenAI API key")
 	}
 
@@ -94,9 +138,52 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("presencePenalty must be between -2.0 and 2.0, got %f", c.PresencePenalty)
 	}
 
+	if len(c.StopSequences) > 4 {
+		return fmt.Errorf("at most 4 stop sequences are allowed, got %d", len(c.StopSequences))
+	}
+
+	for name := range c.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+
+	if err := c.Transport.Validate(); err != nil {
+		return fmt.Errorf("invalid transport: %w", err)
+	}
+
+	switch c.ReasoningEffort {
+	case "", "low", "medium", "high":
+	default:
+		return fmt.Errorf("reasoningEffort must be low, medium or high, got %q", c.ReasoningEffort)
+	}
+
 	return nil
 }
 
+// keys returns APIKey followed by the other APIKeys
+func (c *Config) keys() []string {
+	var keys []string
+	for _, key := range append([]string{c.APIKey}, c.APIKeys...) {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// transportFromEnv reads the HTTP client settings from environment variables
+func transportFromEnv() llm.TransportConfig {
+	return llm.TransportConfig{
+		ProxyURL:       getEnvOrDefault("OPENAI_PROXY_URL", ""),
+		CAFile:         getEnvOrDefault("OPENAI_CA_FILE", ""),
+		CertFile:       getEnvOrDefault("OPENAI_CLIENT_CERT_FILE", ""),
+		KeyFile:        getEnvOrDefault("OPENAI_CLIENT_KEY_FILE", ""),
+		ConnectTimeout: time.Duration(getEnvIntOrDefault("OPENAI_CONNECT_TIMEOUT_SECONDS", 0)) * time.Second,
+		Timeout:        time.Duration(getEnvIntOrDefault("OPENAI_TIMEOUT_SECONDS", 0)) * time.Second,
+	}
+}
+
 // getEnvOrDefault returns the environment variable value or a default if not set
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -124,3 +211,34 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvListOrDefault returns a comma-separated environment variable as a list
+// or default if not set
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMapOrDefault returns an environment variable of comma-separated
+// name=value pairs as a map or default if not set
+func getEnvMapOrDefault(key string, defaultValue map[string]string) map[string]string {
+	pairs := getEnvListOrDefault(key, nil)
+	if pairs == nil {
+		return defaultValue
+	}
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, _ := strings.Cut(pair, "=")
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
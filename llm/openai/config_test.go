@@ -2,6 +2,7 @@ package openai
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -33,6 +34,17 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "rotated API keys only",
+			config: &Config{
+				APIKeys:     []string{"key-1", "key-2"},
+				Model:       "gpt-4",
+				Temperature: 0.7,
+				MaxRetries:  3,
+				BaseURL:     "https://api.openai.com/v1",
+			},
+			wantErr: false,
+		},
 		{
 			name: "empty model",
 			config: &Config{
@@ -186,6 +198,40 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid reasoning effort",
+			config: &Config{
+				APIKey:          "test-key",
+				Model:           "o3-mini",
+				Temperature:     0.7,
+				MaxRetries:      3,
+				BaseURL:         "https://api.openai.com/v1",
+				ReasoningEffort: "maximum",
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many stop sequences",
+			config: &Config{
+				APIKey:        "test-key",
+				Model:         "gpt-4",
+				Temperature:   0.7,
+				MaxRetries:    3,
+				StopSequences: []string{"a", "b", "c", "d", "e"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid header name",
+			config: &Config{
+				APIKey:      "test-key",
+				Model:       "gpt-4",
+				Temperature: 0.7,
+				MaxRetries:  3,
+				Headers:     map[string]string{"X-Gateway Key": "secret"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -214,6 +260,10 @@ func TestNewConfigFromEnv(t *testing.T) {
 		"OPENAI_TOP_P",
 		"OPENAI_FREQUENCY_PENALTY",
 		"OPENAI_PRESENCE_PENALTY",
+		"OPENAI_PROJECT_ID",
+		"OPENAI_HEADERS",
+		"OPENAI_STOP_SEQUENCES",
+		"OPENAI_REASONING_EFFORT",
 	}
 
 	for _, env := range envVars {
@@ -302,6 +352,10 @@ func TestNewConfigFromEnv_CustomValues(t *testing.T) {
 		"OPENAI_TOP_P",
 		"OPENAI_FREQUENCY_PENALTY",
 		"OPENAI_PRESENCE_PENALTY",
+		"OPENAI_PROJECT_ID",
+		"OPENAI_HEADERS",
+		"OPENAI_STOP_SEQUENCES",
+		"OPENAI_REASONING_EFFORT",
 	}
 
 	for _, env := range envVars {
@@ -445,4 +499,19 @@ func TestGetEnvHelpers(t *testing.T) {
 		t.Error("getEnvIntOrDefault failed for invalid env var")
 	}
 	os.Unsetenv("TEST_INT")
+
+	// Test getEnvListOrDefault and getEnvMapOrDefault
+	os.Setenv("TEST_LIST", "END, STOP,")
+	if list := getEnvListOrDefault("TEST_LIST", nil); !reflect.DeepEqual(list, []string{"END", "STOP"}) {
+		t.Errorf("getEnvListOrDefault returned %v", list)
+	}
+	os.Setenv("TEST_MAP", "X-Gateway-Key=secret, X-Route=eu")
+	if values := getEnvMapOrDefault("TEST_MAP", nil); !reflect.DeepEqual(values, map[string]string{"X-Gateway-Key": "secret", "X-Route": "eu"}) {
+		t.Errorf("getEnvMapOrDefault returned %v", values)
+	}
+	if getEnvMapOrDefault("NON_EXISTENT", nil) != nil {
+		t.Error("getEnvMapOrDefault failed for non-existent env var")
+	}
+	os.Unsetenv("TEST_LIST")
+	os.Unsetenv("TEST_MAP")
 }
@@ -0,0 +1,83 @@
+package openai
+
+import (
+	"encoding/base64"
+	"hash/maphash"
+	"strings"
+	"sync"
+)
+
+// maxCachedDataURLs bounds the data URLs a client keeps
+const maxCachedDataURLs = 64
+
+// dataURLCache remembers the data URLs of recently sent media. A conversation
+// sends its images again on every turn, and base64 encoding them is most of the
+// cost of building a request.
+type dataURLCache struct {
+	mu      sync.Mutex
+	seed    maphash.Seed
+	entries map[mediaKey]dataURLEntry
+	order   []mediaKey // Oldest first, for eviction
+}
+
+// mediaKey identifies media by its backing array, so a lookup does not read it
+type mediaKey struct {
+	data     *byte
+	size     int
+	mimeType string
+}
+
+// dataURLEntry is a cached data URL with the hash of the media it encodes,
+// which catches media changed in place since it was cached
+type dataURLEntry struct {
+	media []byte // Keeps the backing array of the key alive
+	hash  uint64
+	url   string
+}
+
+func newDataURLCache() *dataURLCache {
+	return &dataURLCache{seed: maphash.MakeSeed(), entries: make(map[mediaKey]dataURLEntry)}
+}
+
+// get returns the data URL of media, encoding it on a miss; a nil cache always encodes
+func (c *dataURLCache) get(mimeType string, media []byte) string {
+	if c == nil || len(media) == 0 {
+		return dataURL(mimeType, media)
+	}
+	key := mediaKey{data: &media[0], size: len(media), mimeType: mimeType}
+	hash := maphash.Bytes(c.seed, media)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.hash == hash {
+		return entry.url
+	}
+
+	url := dataURL(mimeType, media)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = dataURLEntry{media: media, hash: hash, url: url}
+	for len(c.order) > maxCachedDataURLs {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	return url
+}
+
+// dataURL encodes media as a data URL with a single allocation
+func dataURL(mimeType string, media []byte) string {
+	const scheme, encoding = "data:", ";base64,"
+	var builder strings.Builder
+	builder.Grow(len(scheme) + len(mimeType) + len(encoding) + base64.StdEncoding.EncodedLen(len(media)))
+	builder.WriteString(scheme)
+	builder.WriteString(mimeType)
+	builder.WriteString(encoding)
+	encoder := base64.NewEncoder(base64.StdEncoding, &builder)
+	encoder.Write(media)
+	encoder.Close()
+	return builder.String()
+}
@@ -0,0 +1,68 @@
+package llm
+
+import "strings"
+
+// reasoningTags are the tags reasoning models wrap their thinking in when they
+// write it into the content, e.g. DeepSeek-R1 and Qwen served through
+// OpenAI-compatible endpoints
+var reasoningTags = []string{"think", "thinking", "reasoning"}
+
+// SplitReasoning separates the thinking blocks at the start of a response from
+// the answer after them. Only leading blocks are taken, so tags quoted later in
+// the answer are left alone. A block that is never closed, e.g. cut off by the
+// token limit, makes the whole response reasoning; a closing </think> without an
+// opening tag, which some servers send, ends the reasoning.
+func SplitReasoning(content string) (reasoning, answer string) {
+	rest := strings.TrimLeft(content, " \t\r\n")
+	var blocks []string
+	for {
+		tag := leadingReasoningTag(rest)
+		if tag == "" {
+			break
+		}
+		opening, closing := "<"+tag+">", "</"+tag+">"
+		end := strings.Index(rest, closing)
+		if end < 0 {
+			blocks = append(blocks, strings.TrimSpace(rest[len(opening):]))
+			rest = ""
+			break
+		}
+		blocks = append(blocks, strings.TrimSpace(rest[len(opening):end]))
+		rest = strings.TrimLeft(rest[end+len(closing):], " \t\r\n")
+	}
+	if len(blocks) > 0 {
+		return strings.Join(blocks, "\n\n"), rest
+	}
+
+	if end := strings.Index(content, "</think>"); end >= 0 && !strings.Contains(content[:end], "<think>") {
+		return strings.TrimSpace(content[:end]), strings.TrimLeft(content[end+len("</think>"):], " \t\r\n")
+	}
+	return "", content
+}
+
+// leadingReasoningTag returns the reasoning tag content opens with, or ""
+func leadingReasoningTag(content string) string {
+	for _, tag := range reasoningTags {
+		if strings.HasPrefix(content, "<"+tag+">") {
+			return tag
+		}
+	}
+	return ""
+}
+
+// ExtractReasoning moves thinking written into the content of a message to its
+// ReasoningContent, after any reasoning the provider returned separately
+func ExtractReasoning(message Message) Message {
+	reasoning, answer := SplitReasoning(message.Content)
+	if reasoning == "" && answer == message.Content {
+		return message
+	}
+	message.Content = answer
+	if reasoning != "" {
+		if message.ReasoningContent != "" {
+			message.ReasoningContent += "\n\n"
+		}
+		message.ReasoningContent += reasoning
+	}
+	return message
+}
@@ -0,0 +1,43 @@
+package llm
+
+import "testing"
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		reasoning string
+		answer    string
+	}{
+		{"no reasoning", "The answer is 4.", "", "The answer is 4."},
+		{"think block", "<think>\n2 + 2 is 4\n</think>\n\nThe answer is 4.", "2 + 2 is 4", "The answer is 4."},
+		{"several blocks", "<thinking>first</thinking>\n<reasoning>second</reasoning>\nDone", "first\n\nsecond", "Done"},
+		{"missing opening tag", "2 + 2 is 4\n</think>\nThe answer is 4.", "2 + 2 is 4", "The answer is 4."},
+		{"unterminated block", "<think>2 + 2 is", "2 + 2 is", ""},
+		{"tag inside the answer", "Wrap it in <think></think> tags.", "", "Wrap it in <think></think> tags."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasoning, answer := SplitReasoning(tt.content)
+			if reasoning != tt.reasoning || answer != tt.answer {
+				t.Errorf("SplitReasoning() = %q, %q; want %q, %q", reasoning, answer, tt.reasoning, tt.answer)
+			}
+		})
+	}
+}
+
+func TestExtractReasoning(t *testing.T) {
+	message := ExtractReasoning(Message{
+		Role:             RoleAssistant,
+		Content:          "<think>check the units</think>\n42 km",
+		ReasoningContent: "convert miles",
+	})
+	if message.Content != "42 km" || message.ReasoningContent != "convert miles\n\ncheck the units" {
+		t.Errorf("ExtractReasoning() = %+v", message)
+	}
+
+	plain := Message{Role: RoleAssistant, Content: "42 km"}
+	if got := ExtractReasoning(plain); got.Content != plain.Content || got.ReasoningContent != "" {
+		t.Errorf("ExtractReasoning() changed a message without reasoning: %+v", got)
+	}
+}
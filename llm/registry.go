@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ProviderRegistry holds providers by name and assigns them to the nodes of a
+// flow, e.g. a cheap model for summaries and a strong one for planning. Nodes
+// without an assignment use the default provider.
+type ProviderRegistry struct {
+	mu          sync.RWMutex
+	providers   map[string]LLMProvider
+	assignments map[string]string // Provider names by node name
+	fallback    string
+}
+
+// NewProviderRegistry creates an empty registry
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{providers: make(map[string]LLMProvider), assignments: make(map[string]string)}
+}
+
+// Register adds a provider under a unique name; the first provider registered
+// is the default until SetDefault picks another
+func (r *ProviderRegistry) Register(name string, provider LLMProvider) error {
+	if name == "" || provider == nil {
+		return fmt.Errorf("provider name and provider are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[name]; exists {
+		return fmt.Errorf("provider %s is already registered", name)
+	}
+	r.providers[name] = provider
+	if r.fallback == "" {
+		r.fallback = name
+	}
+	return nil
+}
+
+// SetDefault makes the named provider the one used by nodes without an assignment
+func (r *ProviderRegistry) SetDefault(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[name]; !exists {
+		return fmt.Errorf("unknown provider %s", name)
+	}
+	r.fallback = name
+	return nil
+}
+
+// Assign makes node use the named provider; an empty name removes the
+// assignment so the node uses the default again
+func (r *ProviderRegistry) Assign(node, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name == "" {
+		delete(r.assignments, node)
+		return nil
+	}
+	if _, exists := r.providers[name]; !exists {
+		return fmt.Errorf("cannot assign unknown provider %s to node %s", name, node)
+	}
+	r.assignments[node] = name
+	return nil
+}
+
+// Get returns the provider registered under name
+func (r *ProviderRegistry) Get(name string) (LLMProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names returns the registered provider names in sorted order
+func (r *ProviderRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the provider of node: its assigned provider, or else the default
+func (r *ProviderRegistry) Lookup(node string) (LLMProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, assigned := r.assignments[node]
+	if !assigned {
+		name = r.fallback
+	}
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("no provider for node %s", node)
+	}
+	return provider, nil
+}
+
+// For returns a provider for node that looks up its provider on every call,
+// so assignments changed while the flow runs apply from the next call. It can
+// be passed to any node or helper that takes an LLMProvider.
+func (r *ProviderRegistry) For(node string) LLMProvider {
+	return &nodeProvider{registry: r, node: node}
+}
+
+// nodeProvider is the LLMProvider of a node, resolved through the registry
+type nodeProvider struct {
+	registry *ProviderRegistry
+	node     string
+}
+
+// CallLLM implements LLMProvider with the node's current provider
+func (p *nodeProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	provider, err := p.registry.Lookup(p.node)
+	if err != nil {
+		return Message{}, err
+	}
+	return provider.CallLLM(ctx, messages)
+}
+
+// CallLLMWithSchema calls the constrained decoding of the node's current
+// provider, if it has any
+func (p *nodeProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	provider, err := p.registry.Lookup(p.node)
+	if err != nil {
+		return Message{}, err
+	}
+	schemaProvider, ok := provider.(SchemaProvider)
+	if !ok {
+		return Message{}, ErrSchemaNotSupported
+	}
+	return schemaProvider.CallLLMWithSchema(ctx, messages, schema)
+}
+
+// GetName implements LLMProvider with the name of the node's current provider
+func (p *nodeProvider) GetName() string {
+	provider, err := p.registry.Lookup(p.node)
+	if err != nil {
+		return ""
+	}
+	return provider.GetName()
+}
+
+// SetConfig implements LLMProvider; it configures the node's current
+// provider, which other nodes may share
+func (p *nodeProvider) SetConfig(config map[string]any) error {
+	provider, err := p.registry.Lookup(p.node)
+	if err != nil {
+		return err
+	}
+	return provider.SetConfig(config)
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProviderRegistry(t *testing.T) {
+	registry := NewProviderRegistry()
+	if _, err := registry.For("plan").CallLLM(context.Background(), nil); err == nil {
+		t.Error("expected an error from an empty registry")
+	}
+
+	strong, cheap := NewMockProvider("strong"), NewMockProvider("cheap")
+	strong.SetResponsePattern(map[string]string{"plan": "from strong"})
+	cheap.SetResponsePattern(map[string]string{"plan": "from cheap"})
+	if err := registry.Register("strong", strong); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register("cheap", cheap); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register("cheap", cheap); err == nil {
+		t.Error("expected an error for a duplicate name")
+	}
+	if err := registry.Assign("summarize", "missing"); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+	if err := registry.Assign("summarize", "cheap"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"cheap", "strong"}) {
+		t.Errorf("Names() = %v", names)
+	}
+
+	plan, summarize := registry.For("plan"), registry.For("summarize")
+	tests := []struct {
+		name     string
+		provider LLMProvider
+		want     string
+	}{
+		{"default", plan, "strong"},
+		{"assigned", summarize, "cheap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "Plan the trip"}})
+			if err != nil {
+				t.Fatalf("CallLLM failed: %v", err)
+			}
+			if want := "from " + tt.want; response.Content != want || tt.provider.GetName() != tt.want {
+				t.Errorf("CallLLM() = %q from %s, want %q", response.Content, tt.provider.GetName(), want)
+			}
+		})
+	}
+
+	// Changes apply to the providers handed out before
+	if err := registry.SetDefault("cheap"); err != nil {
+		t.Fatalf("SetDefault failed: %v", err)
+	}
+	if err := registry.Assign("summarize", "strong"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if plan.GetName() != "cheap" || summarize.GetName() != "strong" {
+		t.Errorf("providers after the change = %s, %s", plan.GetName(), summarize.GetName())
+	}
+	if err := registry.Assign("summarize", ""); err != nil || summarize.GetName() != "cheap" {
+		t.Errorf("removing the assignment = %v, provider %s", err, summarize.GetName())
+	}
+
+	_, err := summarize.(SchemaProvider).CallLLMWithSchema(context.Background(), nil, ResponseSchema{})
+	if !errors.Is(err, ErrSchemaNotSupported) {
+		t.Errorf("CallLLMWithSchema() = %v, want %v", err, ErrSchemaNotSupported)
+	}
+}
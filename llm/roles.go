@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownRole is returned for a message whose role is neither one of the
+// Role constants nor a name NormalizeRole accepts for one
+var ErrUnknownRole = errors.New("unknown message role")
+
+// roleAliases are the names other APIs and libraries use for the roles
+var roleAliases = map[string]string{
+	RoleSystem:    RoleSystem,
+	RoleUser:      RoleUser,
+	RoleAssistant: RoleAssistant,
+	"developer":   RoleSystem,
+	"human":       RoleUser,
+	"model":       RoleAssistant,
+	"ai":          RoleAssistant,
+	"bot":         RoleAssistant,
+}
+
+// NormalizeRole returns the Role constant for role, in any case and with
+// surrounding spaces, also accepting "developer" for system, "human" for user
+// and "model", "ai" or "bot" for assistant. Other roles, including the empty
+// one, are an ErrUnknownRole.
+func NormalizeRole(role string) (string, error) {
+	if normalized, ok := roleAliases[role]; ok {
+		return normalized, nil
+	}
+	if normalized, ok := roleAliases[strings.ToLower(strings.TrimSpace(role))]; ok {
+		return normalized, nil
+	}
+	return "", fmt.Errorf("%w %q: use %s, %s or %s", ErrUnknownRole, role, RoleSystem, RoleUser, RoleAssistant)
+}
+
+// NewMessage creates a text message, normalizing its role with NormalizeRole
+func NewMessage(role, content string) (Message, error) {
+	normalized, err := NormalizeRole(role)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Role: normalized, Content: content}, nil
+}
+
+// NormalizeRoles returns messages with their roles normalized by
+// NormalizeRole, for providers to call before converting a request. The
+// messages are copied only when a role changes. The error names the first
+// message with an unknown role.
+func NormalizeRoles(messages []Message) ([]Message, error) {
+	normalized, copied := messages, false
+	for i, message := range messages {
+		role, err := NormalizeRole(message.Role)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if role == message.Role {
+			continue
+		}
+		if !copied {
+			normalized, copied = append([]Message(nil), messages...), true
+		}
+		normalized[i].Role = role
+	}
+	return normalized, nil
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeRole(t *testing.T) {
+	tests := []struct {
+		role    string
+		want    string
+		wantErr bool
+	}{
+		{"user", RoleUser, false},
+		{"assistant", RoleAssistant, false},
+		{"system", RoleSystem, false},
+		{" Assistant ", RoleAssistant, false},
+		{"USER", RoleUser, false},
+		{"model", RoleAssistant, false},
+		{"AI", RoleAssistant, false},
+		{"human", RoleUser, false},
+		{"developer", RoleSystem, false},
+		{"", "", true},
+		{"tool", "", true},
+		{"narrator", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			got, err := NormalizeRole(tt.role)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnknownRole) {
+					t.Errorf("NormalizeRole(%q) error = %v, want ErrUnknownRole", tt.role, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeRole(%q) = %q, %v, want %q", tt.role, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	message, err := NewMessage("Model", "Hello")
+	if err != nil || message.Role != RoleAssistant || message.Content != "Hello" {
+		t.Errorf("NewMessage() = %+v, %v", message, err)
+	}
+	if _, err := NewMessage("narrator", "Once upon a time"); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("NewMessage() error = %v, want ErrUnknownRole", err)
+	}
+}
+
+func TestNormalizeRoles(t *testing.T) {
+	messages := []Message{{Role: RoleSystem}, {Role: RoleUser}, {Role: RoleAssistant}}
+	normalized, err := NormalizeRoles(messages)
+	if err != nil || &normalized[0] != &messages[0] {
+		t.Errorf("NormalizeRoles() copied normalized messages: %v", err)
+	}
+
+	messages = []Message{{Role: "human"}, {Role: "model"}}
+	normalized, err = NormalizeRoles(messages)
+	if err != nil || normalized[0].Role != RoleUser || normalized[1].Role != RoleAssistant {
+		t.Errorf("NormalizeRoles() = %+v, %v", normalized, err)
+	}
+	if messages[0].Role != "human" {
+		t.Error("NormalizeRoles() changed the caller's messages")
+	}
+
+	if _, err := NormalizeRoles([]Message{{Role: RoleUser}, {Role: "narrator"}}); !errors.Is(err, ErrUnknownRole) || err.Error() != `message 1: unknown message role "narrator": use system, user or assistant` {
+		t.Errorf("NormalizeRoles() error = %v", err)
+	}
+}
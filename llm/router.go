@@ -0,0 +1,281 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteRule sends the calls that match all its conditions to a model; a rule
+// without conditions matches every call
+type RouteRule struct {
+	Model           string   // Name of the model the matched calls go to
+	Tasks           []string // Match calls whose task label is one of these
+	MinPromptTokens int      // Match prompts of at least this many tokens, estimated from the messages
+	MaxPromptTokens int      // Match prompts of at most this many tokens; 0 for no limit
+	Tools           bool     // Match only conversations with tool calls or tool results
+	MaxBudgetLeft   float64  // Match only while at most this much of the budget, in USD, is left; 0 ignores the budget
+}
+
+// RouterConfig configures a RouterProvider
+type RouterConfig struct {
+	Rules   []RouteRule // Checked in order; the first rule a call matches picks its model
+	Default string      // Model of the calls no rule matches
+
+	// TaskLabel is the label of the call context that names the task of a
+	// call, see WithLabels, e.g. a label each node passes with its calls.
+	// Default: "task".
+	TaskLabel string
+
+	// Budget is what the calls through the router may cost in USD, as
+	// Tracker prices them; rules with MaxBudgetLeft move calls to cheaper
+	// models as it runs out. 0 for no budget.
+	Budget float64
+	// Tracker records the usage of each model under its name, see
+	// UsageTracker.Wrap; do not wrap the models with it as well. Required
+	// for Budget.
+	Tracker *UsageTracker
+
+	OnDecision func(RouteDecision) // Receives every decision, e.g. to log it; nil ignores them
+}
+
+// RouteDecision records which model a RouterProvider picked for a call and why
+type RouteDecision struct {
+	Time         time.Time `json:"time"`
+	Model        string    `json:"model"`
+	Rule         int       `json:"rule"`   // Index of the rule that matched, -1 for the default
+	Reason       string    `json:"reason"` // The conditions the call matched
+	Task         string    `json:"task,omitempty"`
+	PromptTokens int       `json:"prompt_tokens"` // Estimated tokens of the prompt
+	Tools        bool      `json:"tools"`
+	BudgetLeft   float64   `json:"budget_left,omitempty"` // USD left of the budget before the call
+}
+
+// String formats the decision as a log line
+func (d RouteDecision) String() string {
+	rule := "default"
+	if d.Rule >= 0 {
+		rule = fmt.Sprintf("rule %d", d.Rule)
+	}
+	return fmt.Sprintf("routed to %s by %s: %s", d.Model, rule, d.Reason)
+}
+
+// RouterProvider picks a model for each call by rules on its prompt length,
+// its use of tools, the task label of its context and the budget left, e.g. a
+// flash model for summaries and a pro model for planning. Calls are not
+// retried with another model when the picked one fails.
+type RouterProvider struct {
+	models map[string]LLMProvider
+	config *RouterConfig
+
+	mu    sync.Mutex
+	stats map[string]int
+	now   func() time.Time
+}
+
+// NewRouterProvider routes calls between models, by name, as config says.
+// The names are the model names Tracker prices. An unset TaskLabel of config
+// takes its default.
+func NewRouterProvider(models map[string]LLMProvider, config *RouterConfig) (*RouterProvider, error) {
+	if config == nil || config.Default == "" {
+		return nil, fmt.Errorf("a router config with a default model is required")
+	}
+	if models[config.Default] == nil {
+		return nil, fmt.Errorf("unknown default model %q", config.Default)
+	}
+	for i, rule := range config.Rules {
+		if models[rule.Model] == nil {
+			return nil, fmt.Errorf("rule %d: unknown model %q", i, rule.Model)
+		}
+		if rule.MinPromptTokens < 0 || rule.MaxPromptTokens < 0 || rule.MaxBudgetLeft < 0 {
+			return nil, fmt.Errorf("rule %d: limits cannot be negative", i)
+		}
+		if rule.MaxPromptTokens > 0 && rule.MinPromptTokens > rule.MaxPromptTokens {
+			return nil, fmt.Errorf("rule %d: MinPromptTokens exceeds MaxPromptTokens", i)
+		}
+		if rule.MaxBudgetLeft > 0 && config.Budget == 0 {
+			return nil, fmt.Errorf("rule %d: MaxBudgetLeft requires a budget", i)
+		}
+	}
+	if config.Budget < 0 {
+		return nil, fmt.Errorf("budget cannot be negative")
+	}
+	if config.Budget > 0 && config.Tracker == nil {
+		return nil, fmt.Errorf("a budget requires a usage tracker")
+	}
+
+	withDefaults := *config
+	if withDefaults.TaskLabel == "" {
+		withDefaults.TaskLabel = "task"
+	}
+	routed := make(map[string]LLMProvider, len(models))
+	for name, provider := range models {
+		if provider == nil {
+			return nil, fmt.Errorf("model %q has no provider", name)
+		}
+		if config.Tracker != nil {
+			provider = config.Tracker.Wrap(provider, name)
+		}
+		routed[name] = provider
+	}
+	return &RouterProvider{models: routed, config: &withDefaults, stats: make(map[string]int), now: time.Now}, nil
+}
+
+// CallLLM calls the model the rules pick for the call
+func (p *RouterProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	decision := p.route(ctx, messages)
+	response, err := p.models[decision.Model].CallLLM(ctx, messages)
+	if err != nil {
+		return response, err
+	}
+	return response.WithMeta(MetaModel, decision.Model), nil
+}
+
+// CallLLMWithSchema calls the constrained decoding of the model the rules
+// pick, if it has any
+func (p *RouterProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	decision := p.route(ctx, messages)
+	schemaProvider, ok := p.models[decision.Model].(SchemaProvider)
+	if !ok {
+		return Message{}, ErrSchemaNotSupported
+	}
+	response, err := schemaProvider.CallLLMWithSchema(ctx, messages, schema)
+	if err != nil {
+		return response, err
+	}
+	return response.WithMeta(MetaModel, decision.Model), nil
+}
+
+// GetName returns "router"
+func (p *RouterProvider) GetName() string {
+	return "router"
+}
+
+// SetConfig updates every model
+func (p *RouterProvider) SetConfig(config map[string]any) error {
+	for _, name := range p.names() {
+		if err := p.models[name].SetConfig(config); err != nil {
+			return fmt.Errorf("model %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Shutdown implements Shutdowner for the models that have it
+func (p *RouterProvider) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, name := range p.names() {
+		if shutdowner, ok := p.models[name].(Shutdowner); ok {
+			if err := shutdowner.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("model %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats returns the number of calls routed to each model
+func (p *RouterProvider) Stats() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]int, len(p.stats))
+	for model, calls := range p.stats {
+		stats[model] = calls
+	}
+	return stats
+}
+
+// names returns the names of the models in order
+func (p *RouterProvider) names() []string {
+	names := make([]string, 0, len(p.models))
+	for name := range p.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// route picks the model of a call and reports the decision
+func (p *RouterProvider) route(ctx context.Context, messages []Message) RouteDecision {
+	decision := RouteDecision{
+		Time:         p.now(),
+		Model:        p.config.Default,
+		Rule:         -1,
+		Reason:       "no rule matched",
+		Task:         LabelsFrom(ctx)[p.config.TaskLabel],
+		PromptTokens: estimateTokens(messages, p.config.Default),
+		Tools:        usesTools(messages),
+	}
+	if p.config.Budget > 0 {
+		decision.BudgetLeft = p.config.Budget - p.config.Tracker.Cost()
+	}
+	for i, rule := range p.config.Rules {
+		if reasons, ok := p.match(rule, decision); ok {
+			decision.Model, decision.Rule = rule.Model, i
+			decision.Reason = "matches every call"
+			if len(reasons) > 0 {
+				decision.Reason = strings.Join(reasons, ", ")
+			}
+			break
+		}
+	}
+
+	p.mu.Lock()
+	p.stats[decision.Model]++
+	p.mu.Unlock()
+	if p.config.OnDecision != nil {
+		p.config.OnDecision(decision)
+	}
+	return decision
+}
+
+// match reports whether a call matches rule and which conditions it matched
+func (p *RouterProvider) match(rule RouteRule, call RouteDecision) ([]string, bool) {
+	var reasons []string
+	if len(rule.Tasks) > 0 {
+		if !slices.Contains(rule.Tasks, call.Task) {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("task %s", call.Task))
+	}
+	if rule.MinPromptTokens > 0 {
+		if call.PromptTokens < rule.MinPromptTokens {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("prompt of %d tokens >= %d", call.PromptTokens, rule.MinPromptTokens))
+	}
+	if rule.MaxPromptTokens > 0 {
+		if call.PromptTokens > rule.MaxPromptTokens {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("prompt of %d tokens <= %d", call.PromptTokens, rule.MaxPromptTokens))
+	}
+	if rule.Tools {
+		if !call.Tools {
+			return nil, false
+		}
+		reasons = append(reasons, "uses tools")
+	}
+	if rule.MaxBudgetLeft > 0 {
+		if call.BudgetLeft > rule.MaxBudgetLeft {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("budget left $%.4f <= $%.4f", call.BudgetLeft, rule.MaxBudgetLeft))
+	}
+	return reasons, true
+}
+
+// usesTools reports whether a conversation has tool calls or tool results
+func usesTools(messages []Message) bool {
+	for _, message := range messages {
+		if len(message.ToolCalls) > 0 || len(message.ToolResults) > 0 {
+			return true
+		}
+	}
+	return false
+}
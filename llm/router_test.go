@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouterProvider(t *testing.T) {
+	// Each call of pro reports 10 input tokens, which cost $1
+	tracker := NewUsageTracker(map[string]Pricing{"pro": {InputPerMillion: 100000}})
+	var decisions []RouteDecision
+	router, err := NewRouterProvider(map[string]LLMProvider{
+		"flash": &raceProvider{name: "flash"},
+		"pro":   &raceProvider{name: "pro"},
+	}, &RouterConfig{
+		Rules: []RouteRule{
+			{Model: "flash", Tasks: []string{"summary"}},
+			{Model: "flash", MaxBudgetLeft: 1},
+			{Model: "pro", Tools: true},
+			{Model: "pro", MinPromptTokens: 50},
+		},
+		Default:    "flash",
+		Budget:     2.5,
+		Tracker:    tracker,
+		OnDecision: func(decision RouteDecision) { decisions = append(decisions, decision) },
+	})
+	if err != nil {
+		t.Fatalf("NewRouterProvider() error = %v", err)
+	}
+
+	long := []Message{{Role: RoleUser, Content: strings.Repeat("plan the migration of every service ", 20)}}
+	short := []Message{{Role: RoleUser, Content: "hi"}}
+	tools := []Message{{Role: RoleAssistant, ToolCalls: []ToolCalls{{Id: "1", ToolName: "search"}}}, {Role: RoleUser, ToolResults: []ToolResults{{Id: "1", Content: "found"}}}}
+	summary := WithLabels(context.Background(), map[string]string{"task": "summary"})
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		messages []Message
+		want     string
+		wantRule int
+	}{
+		{"task", summary, long, "flash", 0},
+		{"no rule", context.Background(), short, "flash", -1},
+		{"long prompt", context.Background(), long, "pro", 3},
+		{"tools", context.Background(), tools, "pro", 2},
+		{"budget running out", context.Background(), long, "flash", 1},
+	}
+	for i, tt := range tests {
+		response, err := router.CallLLM(tt.ctx, tt.messages)
+		if err != nil {
+			t.Fatalf("%s: CallLLM() error = %v", tt.name, err)
+		}
+		if response.Content != tt.want || response.Meta(MetaModel) != tt.want {
+			t.Errorf("%s: answered by %s with model %q, want %s", tt.name, response.Content, response.Meta(MetaModel), tt.want)
+		}
+		if decision := decisions[i]; decision.Model != tt.want || decision.Rule != tt.wantRule {
+			t.Errorf("%s: decision = %s, want rule %d", tt.name, decision, tt.wantRule)
+		}
+	}
+	if decision := decisions[4]; decision.BudgetLeft != 0.5 || !strings.Contains(decision.Reason, "budget left $0.5000") {
+		t.Errorf("decision = %+v, want $0.50 of the budget left", decision)
+	}
+	if decision := decisions[0]; decision.Task != "summary" || decision.String() != "routed to flash by rule 0: task summary" {
+		t.Errorf("decision = %s, task %q", decision, decision.Task)
+	}
+	if stats := router.Stats(); !reflect.DeepEqual(stats, map[string]int{"flash": 3, "pro": 2}) {
+		t.Errorf("Stats() = %v", stats)
+	}
+	if calls := tracker.ByModel()["pro"].Calls; calls != 2 {
+		t.Errorf("tracker recorded %d calls of pro, want 2", calls)
+	}
+}
+
+func TestNewRouterProvider_InvalidConfig(t *testing.T) {
+	models := map[string]LLMProvider{"flash": &raceProvider{name: "flash"}}
+	tests := []struct {
+		name   string
+		config *RouterConfig
+	}{
+		{"nil config", nil},
+		{"no default", &RouterConfig{}},
+		{"unknown default", &RouterConfig{Default: "pro"}},
+		{"unknown rule model", &RouterConfig{Default: "flash", Rules: []RouteRule{{Model: "pro"}}}},
+		{"inverted prompt limits", &RouterConfig{Default: "flash", Rules: []RouteRule{{Model: "flash", MinPromptTokens: 100, MaxPromptTokens: 10}}}},
+		{"budget rule without budget", &RouterConfig{Default: "flash", Rules: []RouteRule{{Model: "flash", MaxBudgetLeft: 1}}}},
+		{"budget without tracker", &RouterConfig{Default: "flash", Budget: 10}},
+	}
+	for _, tt := range tests {
+		if _, err := NewRouterProvider(models, tt.config); err == nil {
+			t.Errorf("%s: NewRouterProvider() error = nil", tt.name)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrSchemaNotSupported is returned by SchemaProvider implementations that cannot
+// constrain the current model or endpoint; callers fall back to plain CallLLM
+var ErrSchemaNotSupported = errors.New("constrained decoding is not supported by this provider")
+
+// ResponseSchema describes the JSON document the model must produce
+type ResponseSchema struct {
+	Name        string          // Identifier of the schema, letters, digits, '_' and '-' only
+	Description string          // Optional description of the expected output
+	Schema      json.RawMessage // JSON Schema of the response
+	Strict      bool            // Ask the provider to reject output that does not match exactly
+}
+
+// SchemaProvider is implemented by providers that support constrained decoding
+// (OpenAI json_schema response format, or grammar-based decoding in Ollama and vLLM)
+type SchemaProvider interface {
+	LLMProvider
+
+	// CallLLMWithSchema sends messages and constrains the response to schema
+	CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error)
+}
@@ -0,0 +1,17 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrShutdown is returned by providers for calls made after Shutdown
+var ErrShutdown = errors.New("provider is shut down")
+
+// Shutdowner is implemented by providers and other components that can finish
+// the work in flight before they close
+type Shutdowner interface {
+	// Shutdown stops taking new work, waits for the work in flight until ctx
+	// is done and then releases its resources
+	Shutdown(ctx context.Context) error
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// ToolErrorCode classifies a failed tool call, so planners and cleanup nodes
+// can react to the kind of failure instead of matching error messages
+type ToolErrorCode string
+
+// Codes of failed tool calls
+const (
+	ToolErrorNotFound         ToolErrorCode = "not_found"         // The tool, or what it was asked to find, does not exist
+	ToolErrorTimeout          ToolErrorCode = "timeout"           // The call ran out of time
+	ToolErrorPermissionDenied ToolErrorCode = "permission_denied" // The call was refused, e.g. by an approver
+	ToolErrorInvalidArgs      ToolErrorCode = "invalid_args"      // The arguments did not match the tool's parameters
+	ToolErrorServerCrash      ToolErrorCode = "server_crash"      // The process or server running the tool failed
+	ToolErrorQuotaExceeded    ToolErrorCode = "quota_exceeded"    // A call or runtime quota was used up
+	ToolErrorUnavailable      ToolErrorCode = "unavailable"       // The tool cannot be run now, e.g. during shutdown
+	ToolErrorTooLarge         ToolErrorCode = "too_large"         // The result exceeded the size limit
+	ToolErrorFailed           ToolErrorCode = "failed"            // The tool ran and failed for another reason
+)
+
+// ToolError is an error with a code. Tool handlers return one to set the code
+// of their result; other errors are classified by ToolErrorCodeOf.
+type ToolError struct {
+	Code    ToolErrorCode
+	Message string
+}
+
+// NewToolError creates a tool error with a code
+func NewToolError(code ToolErrorCode, message string) *ToolError {
+	return &ToolError{Code: code, Message: message}
+}
+
+// Error implements error
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+// ToolErrorCodeOf returns the code of a ToolError in err's chain, or the code
+// of well-known errors: context.DeadlineExceeded is a timeout, fs.ErrNotExist
+// not found, fs.ErrPermission permission denied and ErrSizeLimit too large.
+// Other errors get fallback.
+func ToolErrorCodeOf(err error, fallback ToolErrorCode) ToolErrorCode {
+	var toolErr *ToolError
+	switch {
+	case errors.As(err, &toolErr):
+		return toolErr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return ToolErrorTimeout
+	case errors.Is(err, fs.ErrNotExist):
+		return ToolErrorNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ToolErrorPermissionDenied
+	case errors.Is(err, ErrSizeLimit):
+		return ToolErrorTooLarge
+	}
+	return fallback
+}
+
+// ErrorResult returns the result of a failed tool call
+func ErrorResult(id string, code ToolErrorCode, message string) ToolResults {
+	return ToolResults{Id: id, IsError: true, Code: code, Error: message}
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestToolErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ToolErrorCode
+	}{
+		{"tool error", NewToolError(ToolErrorInvalidArgs, "bad query"), ToolErrorInvalidArgs},
+		{"wrapped tool error", fmt.Errorf("search: %w", NewToolError(ToolErrorServerCrash, "exited")), ToolErrorServerCrash},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), ToolErrorTimeout},
+		{"missing file", &os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}, ToolErrorNotFound},
+		{"forbidden file", &os.PathError{Op: "open", Path: "x", Err: os.ErrPermission}, ToolErrorPermissionDenied},
+		{"too large", &SizeLimitError{What: "tool result", Size: 2048, Limit: 1024}, ToolErrorTooLarge},
+		{"other", errors.New("boom"), ToolErrorFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToolErrorCodeOf(tt.err, ToolErrorFailed); got != tt.want {
+				t.Errorf("ToolErrorCodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorResult(t *testing.T) {
+	result := ErrorResult("1", ToolErrorTimeout, "too slow")
+	want := ToolResults{Id: "1", IsError: true, Code: ToolErrorTimeout, Error: "too slow"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("ErrorResult() = %+v, want %+v", result, want)
+	}
+}
@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig customizes the HTTP client of a provider, for corporate
+// networks where the default transport cannot reach the API. The zero value
+// leaves the provider's default client.
+type TransportConfig struct {
+	// ProxyURL is an http://, https:// or socks5:// proxy. Empty uses the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	ProxyURL string
+	// CAFile is a PEM bundle of CAs trusted in addition to the system ones,
+	// e.g. the CA of a TLS-inspecting proxy
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key of mutual TLS
+	CertFile string
+	KeyFile  string
+
+	ConnectTimeout time.Duration // Timeout of dialing and the TLS handshake, 0 for the default of 30s
+	Timeout        time.Duration // Timeout of a whole request, response included; 0 for none
+}
+
+// IsZero reports whether the config changes nothing
+func (c TransportConfig) IsZero() bool {
+	return c == TransportConfig{}
+}
+
+// Validate checks the proxy URL and that a client certificate comes with its key
+func (c TransportConfig) Validate() error {
+	if c.ProxyURL != "" {
+		proxy, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("proxy URL must use http, https or socks5, got %q", c.ProxyURL)
+		}
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("client certificate and key must be set together")
+	}
+	if c.ConnectTimeout < 0 || c.Timeout < 0 {
+		return fmt.Errorf("timeouts cannot be negative")
+	}
+	return nil
+}
+
+// NewHTTPClient creates a client with the proxy, TLS settings and timeouts of the config
+func (c TransportConfig) NewHTTPClient() (*http.Client, error) {
+	transport, err := c.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: c.Timeout}, nil
+}
+
+// NewTransport creates a transport with the proxy and TLS settings of the
+// config, based on the settings of http.DefaultTransport
+func (c TransportConfig) NewTransport() (*http.Transport, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.ProxyURL != "" {
+		proxy, _ := url.Parse(c.ProxyURL)
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if c.ConnectTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: c.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = c.ConnectTimeout
+	}
+
+	if c.CAFile == "" && c.CertFile == "" {
+		return transport, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -6,10 +6,14 @@ import "context"
 type Message struct {
 	Role    string // "user", "assistant", "system"
 	Content string // The actual message content
+	ReasoningContent string `json:",omitempty"` // Thinking of a reasoning model, kept out of Content and never sent back to the model
 	Media []byte
 	MimeType  string
 	ToolCalls []ToolCalls
 	ToolResults []ToolResults
+	Metadata map[string]string `json:",omitempty"` // Provenance such as MetaTurnID and MetaNode; never sent to the model
+	Tags []string `json:",omitempty"` // Flags such as TagHidden; never sent to the model
+	Blocks []ContentBlock `json:",omitempty"` // The content as ordered blocks, see WithBlocks; when set, providers send these instead of the fields above
 }
 
 type ToolResults struct {
@@ -18,6 +22,7 @@ type ToolResults struct {
 	Media []byte // Optional media content
 	MetaData MetaData // Optional metadata for the tool result
 	IsError bool // Whether the result is an error
+	Code ToolErrorCode `json:",omitempty"` // Kind of error if IsError is true, e.g. ToolErrorTimeout
 	Error string // Error message if IsError is true
 }
 
@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// Usage counts the LLM calls and tokens spent
+type Usage struct {
+	Calls        int  `json:"calls"`
+	InputTokens  int  `json:"input_tokens"`
+	OutputTokens int  `json:"output_tokens"`
+	Estimated    bool `json:"estimated,omitempty"` // Some counts were estimated because the provider did not report them
+}
+
+// TotalTokens returns the input and output tokens
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		Calls:        u.Calls + other.Calls,
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+		Estimated:    u.Estimated || other.Estimated,
+	}
+}
+
+// Pricing is the price of a model in USD per million tokens
+type Pricing struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// Cost returns the price of usage
+func (p Pricing) Cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*p.InputPerMillion + float64(usage.OutputTokens)*p.OutputPerMillion) / 1e6
+}
+
+// usageKey is the context key of the usage reported for a call
+type usageKey struct{}
+
+// ReportUsage records the token usage of a call made with ctx. Providers call
+// it with the counts from the API response; outside of a tracked call it does nothing.
+func ReportUsage(ctx context.Context, usage Usage) {
+	if reported, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		*reported = reported.Add(usage)
+	}
+}
+
+// UsageTracker records the usage of the providers it wraps, by model, and
+// prices it. Share one tracker between the providers of a session to get its
+// total usage.
+type UsageTracker struct {
+	mu      sync.RWMutex
+	pricing map[string]Pricing
+	usage   map[string]Usage
+	byLabel map[string]map[string]labelUsage // Usage by label name and value, see WithLabels
+}
+
+// NewUsageTracker creates a tracker; pricing maps model names or prefixes to
+// their prices, and models without a price cost nothing
+func NewUsageTracker(pricing map[string]Pricing) *UsageTracker {
+	return &UsageTracker{pricing: pricing, usage: make(map[string]Usage)}
+}
+
+// Wrap returns provider recording its calls under model. Calls whose
+// provider does not report usage are estimated from the message text.
+func (t *UsageTracker) Wrap(provider LLMProvider, model string) LLMProvider {
+	return &trackedProvider{LLMProvider: provider, tracker: t, model: model}
+}
+
+// Record adds usage to a model, e.g. to restore the usage of a saved session
+func (t *UsageTracker) Record(model string, usage Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage[model] = t.usage[model].Add(usage)
+}
+
+// Usage returns the usage of all models
+func (t *UsageTracker) Usage() Usage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var total Usage
+	for _, usage := range t.usage {
+		total = total.Add(usage)
+	}
+	return total
+}
+
+// ByModel returns a copy of the usage of each model
+func (t *UsageTracker) ByModel() map[string]Usage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	usage := make(map[string]Usage, len(t.usage))
+	for model, recorded := range t.usage {
+		usage[model] = recorded
+	}
+	return usage
+}
+
+// Cost returns the price of all recorded usage in USD
+func (t *UsageTracker) Cost() float64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	cost := 0.0
+	for model, usage := range t.usage {
+		cost += t.pricingFor(model).Cost(usage)
+	}
+	return cost
+}
+
+// Reset clears all recorded usage, e.g. when a new session starts
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage = make(map[string]Usage)
+	t.byLabel = nil
+}
+
+// pricingFor returns the price of the longest matching model prefix
+func (t *UsageTracker) pricingFor(model string) Pricing {
+	prefixes := make([]string, 0, len(t.pricing))
+	for prefix := range t.pricing {
+		if strings.HasPrefix(model, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return Pricing{}
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return t.pricing[prefixes[0]]
+}
+
+// trackedProvider records the usage of a provider's calls
+type trackedProvider struct {
+	LLMProvider
+	tracker *UsageTracker
+	mu      sync.RWMutex
+	model   string
+}
+
+// SetConfig updates the wrapped provider and, when the model changes, the model
+// its usage is recorded under
+func (p *trackedProvider) SetConfig(config map[string]any) error {
+	if err := p.LLMProvider.SetConfig(config); err != nil {
+		return err
+	}
+	if model, ok := config["model"].(string); ok && model != "" {
+		p.mu.Lock()
+		p.model = model
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// CallLLM calls the wrapped provider and records its usage
+func (p *trackedProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	return p.track(ctx, messages, func(ctx context.Context) (Message, error) {
+		return p.LLMProvider.CallLLM(ctx, messages)
+	})
+}
+
+// CallLLMWithSchema calls the wrapped provider's constrained decoding, if it has any
+func (p *trackedProvider) CallLLMWithSchema(ctx context.Context, messages []Message, schema ResponseSchema) (Message, error) {
+	schemaProvider, ok := p.LLMProvider.(SchemaProvider)
+	if !ok {
+		return Message{}, ErrSchemaNotSupported
+	}
+	return p.track(ctx, messages, func(ctx context.Context) (Message, error) {
+		return schemaProvider.CallLLMWithSchema(ctx, messages, schema)
+	})
+}
+
+// track runs call and records the usage it reported, or an estimate
+func (p *trackedProvider) track(ctx context.Context, messages []Message, call func(ctx context.Context) (Message, error)) (Message, error) {
+	p.mu.RLock()
+	model := p.model
+	p.mu.RUnlock()
+
+	var reported Usage
+	response, err := call(context.WithValue(ctx, usageKey{}, &reported))
+	if err != nil && reported.TotalTokens() == 0 {
+		return response, err
+	}
+
+	usage := Usage{Calls: 1, InputTokens: reported.InputTokens, OutputTokens: reported.OutputTokens}
+	if reported.TotalTokens() == 0 {
+		usage.InputTokens = estimateTokens(messages, model)
+		usage.OutputTokens = estimateTokens([]Message{response}, model)
+		usage.Estimated = true
+	} else if err == nil {
+		response = response.WithMeta(MetaInputTokens, strconv.Itoa(reported.InputTokens)).
+			WithMeta(MetaOutputTokens, strconv.Itoa(reported.OutputTokens))
+	}
+	p.tracker.recordLabeled(model, usage, LabelsFrom(ctx))
+	return response, err
+}
+
+// estimateTokens estimates the tokens of messages' text and tool calls
+func estimateTokens(messages []Message, model string) int {
+	var text strings.Builder
+	for _, message := range messages {
+		text.WriteString(message.Content)
+		for _, call := range message.ToolCalls {
+			text.WriteString(call.ToolName)
+			for key, value := range call.ToolArgs {
+				text.WriteString(key)
+				if s, ok := value.(string); ok {
+					text.WriteString(s)
+				}
+			}
+		}
+		for _, result := range message.ToolResults {
+			text.WriteString(result.Content)
+		}
+	}
+	return prompt.EstimateTokens(text.String(), model)
+}
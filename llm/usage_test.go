@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+func TestUsageTracker(t *testing.T) {
+	tracker := NewUsageTracker(map[string]Pricing{
+		"gpt-4o":      {InputPerMillion: 2.5, OutputPerMillion: 10},
+		"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	})
+
+	// The mock does not report usage, so its calls are estimated
+	provider := tracker.Wrap(NewMockProvider("mock"), "gpt-4o-mini-2024")
+	if _, err := provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "How many tokens is this?"}}); err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	estimated := tracker.ByModel()["gpt-4o-mini-2024"]
+	if estimated.Calls != 1 || estimated.InputTokens == 0 || estimated.OutputTokens == 0 || !estimated.Estimated {
+		t.Errorf("estimated usage = %+v", estimated)
+	}
+
+	tracker.Record("gpt-4o", Usage{Calls: 2, InputTokens: 1000, OutputTokens: 100})
+	usage := tracker.Usage()
+	if usage.Calls != 3 || usage.TotalTokens() != estimated.TotalTokens()+1100 {
+		t.Errorf("total usage = %+v", usage)
+	}
+
+	// The longest matching prefix prices each model
+	expected := Pricing{InputPerMillion: 2.5, OutputPerMillion: 10}.Cost(Usage{InputTokens: 1000, OutputTokens: 100}) +
+		Pricing{InputPerMillion: 0.15, OutputPerMillion: 0.6}.Cost(estimated)
+	if cost := tracker.Cost(); cost != expected {
+		t.Errorf("Cost() = %v, expected %v", cost, expected)
+	}
+
+	mock := NewMockProvider("failing")
+	mock.SetError(true, "unavailable")
+	if _, err := tracker.Wrap(mock, "gpt-4o").CallLLM(context.Background(), nil); err == nil {
+		t.Error("expected the provider's error")
+	}
+	if tracker.Usage().Calls != 3 {
+		t.Error("failed calls without reported usage should not be recorded")
+	}
+
+	// Switching the model records later calls under the new model
+	if err := provider.SetConfig(map[string]any{"model": "gpt-4o"}); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "And now?"}})
+	if tracker.ByModel()["gpt-4o"].Calls != 3 || tracker.ByModel()["gpt-4o-mini-2024"].Calls != 1 {
+		t.Errorf("usage by model after SetConfig = %+v", tracker.ByModel())
+	}
+
+	tracker.Reset()
+	if tracker.Usage().Calls != 0 || tracker.Cost() != 0 {
+		t.Error("expected no usage after Reset")
+	}
+}
+
+// reportingProvider reports fixed usage for every call, as API clients do
+type reportingProvider struct {
+	*MockProvider
+	usage Usage
+}
+
+func (p *reportingProvider) CallLLM(ctx context.Context, messages []Message) (Message, error) {
+	ReportUsage(ctx, p.usage)
+	return p.MockProvider.CallLLM(ctx, messages)
+}
+
+func TestUsageTracker_ResponseMetadata(t *testing.T) {
+	tracker := NewUsageTracker(nil)
+	provider := tracker.Wrap(&reportingProvider{NewMockProvider("mock"), Usage{InputTokens: 1200, OutputTokens: 30}}, "gpt-4o")
+	response, err := provider.CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}})
+	if err != nil {
+		t.Fatalf("CallLLM failed: %v", err)
+	}
+	if response.Meta(MetaInputTokens) != "1200" || response.Meta(MetaOutputTokens) != "30" {
+		t.Errorf("response metadata = %v", response.Metadata)
+	}
+
+	// Estimates are not presented as reported counts
+	response, _ = tracker.Wrap(NewMockProvider("mock"), "gpt-4o").CallLLM(context.Background(), []Message{{Role: RoleUser, Content: "Hi"}})
+	if response.Metadata != nil {
+		t.Errorf("metadata of an estimated call = %v", response.Metadata)
+	}
+}
+
+func TestUsageTracker_ByLabel(t *testing.T) {
+	tracker := NewUsageTracker(map[string]Pricing{
+		"gpt-4o":      {InputPerMillion: 2.5, OutputPerMillion: 10},
+		"gpt-4o-mini": {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	})
+	strong := tracker.Wrap(&reportingProvider{NewMockProvider("mock"), Usage{InputTokens: 1000, OutputTokens: 100}}, "gpt-4o")
+	cheap := tracker.Wrap(&reportingProvider{NewMockProvider("mock"), Usage{InputTokens: 1000, OutputTokens: 100}}, "gpt-4o-mini")
+
+	acme := WithLabels(context.Background(), map[string]string{"tenant": "acme"})
+	acme = WithLabels(acme, map[string]string{"session": "s1"})
+	globex := WithLabels(context.Background(), map[string]string{"tenant": "globex"})
+	for _, call := range []struct {
+		ctx      context.Context
+		provider LLMProvider
+	}{{acme, strong}, {acme, cheap}, {globex, cheap}, {context.Background(), strong}} {
+		if _, err := call.provider.CallLLM(call.ctx, []Message{{Role: RoleUser, Content: "Hi"}}); err != nil {
+			t.Fatalf("CallLLM failed: %v", err)
+		}
+	}
+
+	byTenant := tracker.ByLabel("tenant")
+	if len(byTenant) != 2 || byTenant["acme"].Calls != 2 || byTenant["globex"].Calls != 1 {
+		t.Errorf("ByLabel(tenant) = %+v", byTenant)
+	}
+	if bySession := tracker.ByLabel("session"); bySession["s1"].Calls != 2 {
+		t.Errorf("ByLabel(session) = %+v", bySession)
+	}
+	cost := tracker.CostByLabel("tenant")
+	want := Pricing{InputPerMillion: 2.5, OutputPerMillion: 10}.Cost(Usage{InputTokens: 1000, OutputTokens: 100}) +
+		Pricing{InputPerMillion: 0.15, OutputPerMillion: 0.6}.Cost(Usage{InputTokens: 1000, OutputTokens: 100})
+	if cost["acme"] != want {
+		t.Errorf("CostByLabel(tenant)[acme] = %v, want %v", cost["acme"], want)
+	}
+	if tracker.Usage().Calls != 4 {
+		t.Errorf("Usage() = %+v, want all 4 calls", tracker.Usage())
+	}
+
+	tracker.Reset()
+	if got := tracker.ByLabel("tenant"); len(got) != 0 {
+		t.Errorf("ByLabel() after Reset = %+v", got)
+	}
+}
+
+type tenantState struct {
+	Tenant string
+}
+
+// askNode asks the provider once, labeled with the tenant of the state
+type askNode struct {
+	provider LLMProvider
+}
+
+func (n *askNode) Prep(state *tenantState) []string { return []string{"Hi"} }
+func (n *askNode) Labels(state *tenantState) core.Labels {
+	return core.Labels{core.LabelTenant: state.Tenant}
+}
+func (n *askNode) Exec(prompt string) (Message, error) {
+	return n.ExecContext(context.Background(), prompt)
+}
+func (n *askNode) ExecContext(ctx context.Context, prompt string) (Message, error) {
+	return n.provider.CallLLM(ctx, []Message{{Role: RoleUser, Content: prompt}})
+}
+func (n *askNode) ExecFallback(err error) Message { return Message{} }
+func (n *askNode) Post(state *tenantState, prompts []string, replies ...Message) core.Action {
+	return core.ActionSuccess
+}
+
+func TestUsageTracker_NodeLabels(t *testing.T) {
+	tracker := NewUsageTracker(nil)
+	provider := tracker.Wrap(&reportingProvider{NewMockProvider("mock"), Usage{InputTokens: 10, OutputTokens: 1}}, "gpt-4o")
+	node := core.NewNode[tenantState](&askNode{provider: provider}, 0, 1)
+	node.SetLabels(core.Labels{"node": "ask"})
+	flow := core.NewFlow[tenantState](node)
+	flow.SetLabels(core.Labels{"region": "eu"})
+
+	for _, tenant := range []string{"acme", "acme", "globex"} {
+		flow.Run(&tenantState{Tenant: tenant})
+	}
+	if byTenant := tracker.ByLabel(core.LabelTenant); byTenant["acme"].Calls != 2 || byTenant["globex"].Calls != 1 {
+		t.Errorf("ByLabel(tenant) = %+v, want the calls under the tenant of each run", byTenant)
+	}
+	for _, name := range []string{"node", "region"} {
+		if usage := tracker.ByLabel(name); len(usage) != 1 {
+			t.Errorf("ByLabel(%s) = %+v, want the labels of the node and flow", name, usage)
+		}
+	}
+}
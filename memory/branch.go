@@ -0,0 +1,281 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// MainBranch is the name of the branch a BranchingStore starts with
+const MainBranch = "main"
+
+var (
+	// ErrBranchNotFound is returned for branch names a BranchingStore does not know
+	ErrBranchNotFound = errors.New("branch not found")
+	// ErrBranchExists is returned when forking to a name that is already taken
+	ErrBranchExists = errors.New("branch already exists")
+)
+
+// Generator writes the next assistant message of a conversation, e.g. an
+// llm.LLMProvider's CallLLM method
+type Generator func(ctx context.Context, messages []llm.Message) (llm.Message, error)
+
+// BranchInfo describes a branch of a conversation
+type BranchInfo struct {
+	Name     string `json:"name"`
+	Parent   string `json:"parent,omitempty"` // Branch it was forked from; empty for the main branch
+	ForkedAt int    `json:"forked_at"`        // Messages taken over from the parent
+	Active   bool   `json:"active"`
+}
+
+// BranchDiff compares two branches of a conversation
+type BranchDiff struct {
+	Common int           `json:"common"` // Leading messages both branches share
+	A      []llm.Message `json:"a"`      // Messages of the first branch after the common ones
+	B      []llm.Message `json:"b"`      // Messages of the second branch after the common ones
+}
+
+// branch is a version of the conversation and where it came from
+type branch struct {
+	info  BranchInfo
+	store Store
+}
+
+// BranchingStore keeps several versions of a conversation. It implements Store
+// for the active branch, so nodes keep working on whichever branch is checked
+// out, while Fork, Regenerate and Compare support "try again" and A/B
+// comparisons of prompts or models.
+type BranchingStore struct {
+	mu       sync.RWMutex
+	branches map[string]*branch
+	order    []string
+	active   string
+	newStore func(name string) (Store, error)
+}
+
+// NewBranchingStore creates a branching store whose main branch is main.
+// newStore creates the store of each new branch; nil keeps branches in memory.
+func NewBranchingStore(main Store, newStore func(name string) (Store, error)) *BranchingStore {
+	if newStore == nil {
+		newStore = func(string) (Store, error) { return NewInMemoryStore(), nil }
+	}
+	return &BranchingStore{
+		branches: map[string]*branch{MainBranch: {info: BranchInfo{Name: MainBranch}, store: main}},
+		order:    []string{MainBranch},
+		active:   MainBranch,
+		newStore: newStore,
+	}
+}
+
+// Fork creates branch name from the first at messages of the active branch and
+// checks it out. The fork point may not separate tool calls from their results.
+func (s *BranchingStore) Fork(ctx context.Context, name string, at int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fork(ctx, s.active, name, at)
+}
+
+// fork creates branch name from the first at messages of branch from and
+// checks it out; the caller holds the lock
+func (s *BranchingStore) fork(ctx context.Context, from, name string, at int) error {
+	if name == "" {
+		return fmt.Errorf("branch name cannot be empty")
+	}
+	if _, ok := s.branches[name]; ok {
+		return fmt.Errorf("%w: %s", ErrBranchExists, name)
+	}
+
+	parent := s.branches[from]
+	messages, err := parent.store.Messages(ctx)
+	if err != nil {
+		return err
+	}
+	if at < 0 || at > len(messages) {
+		return fmt.Errorf("cannot fork at message %d of %d", at, len(messages))
+	}
+	if at > 0 && at < len(messages) && len(messages[at].ToolResults) > 0 {
+		return fmt.Errorf("cannot fork at message %d: it separates tool calls from their results", at)
+	}
+
+	store, err := s.newStore(name)
+	if err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+	if err := store.Replace(ctx, messages[:at]); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+	s.branches[name] = &branch{
+		info:  BranchInfo{Name: name, Parent: parent.info.Name, ForkedAt: at},
+		store: store,
+	}
+	s.order = append(s.order, name)
+	s.active = name
+	return nil
+}
+
+// Regenerate replaces the assistant message at index of the active branch with
+// a new one from generate, on a new branch name that is checked out. The
+// original answer stays on the branch it was written on.
+func (s *BranchingStore) Regenerate(ctx context.Context, name string, index int, generate Generator) (llm.Message, error) {
+	from := s.Active()
+	if _, err := s.Branch(name); err == nil {
+		return llm.Message{}, fmt.Errorf("%w: %s", ErrBranchExists, name)
+	}
+	store, err := s.Branch(from)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	messages, err := store.Messages(ctx)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	if index < 0 || index >= len(messages) || messages[index].Role != llm.RoleAssistant {
+		return llm.Message{}, fmt.Errorf("message %d is not an assistant message", index)
+	}
+
+	response, err := generate(ctx, messages[:index])
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("failed to regenerate message %d: %w", index, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.fork(ctx, from, name, index); err != nil {
+		return llm.Message{}, err
+	}
+	if err := s.branches[name].store.Append(ctx, response); err != nil {
+		return llm.Message{}, err
+	}
+	return response, nil
+}
+
+// Checkout makes name the active branch
+func (s *BranchingStore) Checkout(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.branches[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrBranchNotFound, name)
+	}
+	s.active = name
+	return nil
+}
+
+// Active returns the name of the active branch
+func (s *BranchingStore) Active() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Branches lists the branches in the order they were created
+func (s *BranchingStore) Branches() []BranchInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	branches := make([]BranchInfo, 0, len(s.order))
+	for _, name := range s.order {
+		info := s.branches[name].info
+		info.Active = name == s.active
+		branches = append(branches, info)
+	}
+	return branches
+}
+
+// Branch returns the store of branch name, e.g. to read it without checking it out
+func (s *BranchingStore) Branch(name string) (Store, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.branches[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBranchNotFound, name)
+	}
+	return b.store, nil
+}
+
+// Compare returns the messages branches a and b share and where they differ
+func (s *BranchingStore) Compare(ctx context.Context, a, b string) (BranchDiff, error) {
+	storeA, err := s.Branch(a)
+	if err != nil {
+		return BranchDiff{}, err
+	}
+	storeB, err := s.Branch(b)
+	if err != nil {
+		return BranchDiff{}, err
+	}
+	messagesA, err := storeA.Messages(ctx)
+	if err != nil {
+		return BranchDiff{}, err
+	}
+	messagesB, err := storeB.Messages(ctx)
+	if err != nil {
+		return BranchDiff{}, err
+	}
+
+	common := 0
+	for common < len(messagesA) && common < len(messagesB) && sameMessage(messagesA[common], messagesB[common]) {
+		common++
+	}
+	return BranchDiff{Common: common, A: messagesA[common:], B: messagesB[common:]}, nil
+}
+
+// sameMessage reports whether two messages have the same role, text and tool traffic
+func sameMessage(a, b llm.Message) bool {
+	if a.Role != b.Role || a.Content != b.Content || len(a.ToolCalls) != len(b.ToolCalls) || len(a.ToolResults) != len(b.ToolResults) {
+		return false
+	}
+	for i := range a.ToolCalls {
+		if a.ToolCalls[i].Id != b.ToolCalls[i].Id || a.ToolCalls[i].ToolName != b.ToolCalls[i].ToolName {
+			return false
+		}
+	}
+	for i := range a.ToolResults {
+		if a.ToolResults[i].Id != b.ToolResults[i].Id || a.ToolResults[i].Content != b.ToolResults[i].Content {
+			return false
+		}
+	}
+	return true
+}
+
+// current returns the store of the active branch
+func (s *BranchingStore) current() Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.branches[s.active].store
+}
+
+// Append adds messages to the active branch
+func (s *BranchingStore) Append(ctx context.Context, messages ...llm.Message) error {
+	return s.current().Append(ctx, messages...)
+}
+
+// Messages returns the active branch
+func (s *BranchingStore) Messages(ctx context.Context) ([]llm.Message, error) {
+	return s.current().Messages(ctx)
+}
+
+// Window returns the most recent messages of the active branch
+func (s *BranchingStore) Window(ctx context.Context, limit int) ([]llm.Message, error) {
+	return s.current().Window(ctx, limit)
+}
+
+// Search returns messages of the active branch containing query, most recent first
+func (s *BranchingStore) Search(ctx context.Context, query string, limit int) ([]llm.Message, error) {
+	return s.current().Search(ctx, query, limit)
+}
+
+// Len returns the number of messages on the active branch
+func (s *BranchingStore) Len(ctx context.Context) (int, error) {
+	return s.current().Len(ctx)
+}
+
+// Replace swaps the messages of the active branch
+func (s *BranchingStore) Replace(ctx context.Context, messages []llm.Message) error {
+	return s.current().Replace(ctx, messages)
+}
+
+// Clear removes every message of the active branch
+func (s *BranchingStore) Clear(ctx context.Context) error {
+	return s.current().Clear(ctx)
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestBranchingStore(t *testing.T) {
+	testStore(t, NewBranchingStore(NewInMemoryStore(), nil))
+}
+
+func TestBranchingStore_ForkRegenerateCompare(t *testing.T) {
+	ctx := context.Background()
+	store := NewBranchingStore(NewInMemoryStore(
+		llm.Message{Role: llm.RoleUser, Content: "What is the weather in Paris?"},
+		llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "weather"}}},
+		llm.Message{Role: llm.RoleUser, ToolResults: []llm.ToolResults{{Id: "1", Content: "sunny"}}},
+		llm.Message{Role: llm.RoleAssistant, Content: "It is sunny."},
+	), nil)
+
+	if err := store.Fork(ctx, "split", 2); err == nil {
+		t.Error("forking between tool calls and their results should fail")
+	}
+
+	// Try again: a new answer on its own branch
+	var seen int
+	generate := func(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+		seen = len(messages)
+		return llm.Message{Role: llm.RoleAssistant, Content: "Sunny and 21C."}, nil
+	}
+	response, err := store.Regenerate(ctx, "retry", 3, generate)
+	if err != nil {
+		t.Fatalf("Regenerate failed: %v", err)
+	}
+	if response.Content != "Sunny and 21C." || store.Active() != "retry" || seen != 3 {
+		t.Errorf("Regenerate() = %q on %s from %d messages", response.Content, store.Active(), seen)
+	}
+	if _, err := store.Regenerate(ctx, "other", 0, generate); err == nil {
+		t.Error("regenerating a user message should fail")
+	}
+	if err := store.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "And tomorrow?"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	diff, err := store.Compare(ctx, MainBranch, "retry")
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if diff.Common != 3 || len(diff.A) != 1 || diff.A[0].Content != "It is sunny." || len(diff.B) != 2 || diff.B[0].Content != "Sunny and 21C." {
+		t.Errorf("Compare() = %+v", diff)
+	}
+
+	if err := store.Checkout(MainBranch); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if n, _ := store.Len(ctx); n != 4 {
+		t.Errorf("main branch has %d messages, expected 4", n)
+	}
+	if err := store.Fork(ctx, "retry", 1); !errors.Is(err, ErrBranchExists) {
+		t.Errorf("Fork() to a taken name = %v, expected ErrBranchExists", err)
+	}
+	if err := store.Checkout("missing"); !errors.Is(err, ErrBranchNotFound) {
+		t.Errorf("Checkout() = %v, expected ErrBranchNotFound", err)
+	}
+
+	branches := store.Branches()
+	if len(branches) != 2 || !branches[0].Active || branches[1].Parent != MainBranch || branches[1].ForkedAt != 3 {
+		t.Errorf("Branches() = %+v", branches)
+	}
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// FileStore persists a conversation as JSON lines, one message per line. Messages
+// are cached in memory, so reads never touch the file and appends only add lines.
+type FileStore struct {
+	mu    sync.Mutex
+	path  string
+	cache *InMemoryStore
+}
+
+// NewFileStore opens the conversation stored at path, creating the file and its
+// directory on first append
+func NewFileStore(path string) (*FileStore, error) {
+	messages, err := readMessages(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{path: path, cache: NewInMemoryStore(messages...)}, nil
+}
+
+// Path returns the file backing the store
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Append writes messages to the end of the file
+func (s *FileStore) Append(ctx context.Context, messages ...llm.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	data, err := encodeMessages(messages)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open memory file: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to memory file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close memory file: %w", err)
+	}
+	return s.cache.Append(ctx, messages...)
+}
+
+// Messages returns the whole conversation
+func (s *FileStore) Messages(ctx context.Context) ([]llm.Message, error) {
+	return s.cache.Messages(ctx)
+}
+
+// Window returns the most recent messages
+func (s *FileStore) Window(ctx context.Context, limit int) ([]llm.Message, error) {
+	return s.cache.Window(ctx, limit)
+}
+
+// Search returns messages containing query, most recent first
+func (s *FileStore) Search(ctx context.Context, query string, limit int) ([]llm.Message, error) {
+	return s.cache.Search(ctx, query, limit)
+}
+
+// Len returns the number of stored messages
+func (s *FileStore) Len(ctx context.Context) (int, error) {
+	return s.cache.Len(ctx)
+}
+
+// Replace rewrites the file with messages. The new contents are written to a
+// temporary file first so a crash never leaves a truncated conversation behind.
+func (s *FileStore) Replace(ctx context.Context, messages []llm.Message) error {
+	data, err := encodeMessages(messages)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	temp := s.path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	if err := os.Rename(temp, s.path); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to replace memory file: %w", err)
+	}
+	return s.cache.Replace(ctx, messages)
+}
+
+// Clear empties the file
+func (s *FileStore) Clear(ctx context.Context) error {
+	return s.Replace(ctx, nil)
+}
+
+// encodeMessages renders messages as JSON lines
+func encodeMessages(messages []llm.Message) ([]byte, error) {
+	var data []byte
+	for _, message := range messages {
+		line, err := json.Marshal(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	return data, nil
+}
+
+// readMessages loads a JSON lines file; a missing file is an empty conversation
+func readMessages(path string) ([]llm.Message, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer file.Close()
+
+	var messages []llm.Message
+	scanner := bufio.NewScanner(file)
+	// Messages may carry base64 media, so allow long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var message llm.Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("failed to decode message on line %d of %s: %w", line, path, err)
+		}
+		messages = append(messages, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read memory file: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// InMemoryStore keeps a conversation in process memory
+type InMemoryStore struct {
+	mu       sync.RWMutex
+	messages []llm.Message
+}
+
+// NewInMemoryStore creates an in-memory store seeded with optional messages
+func NewInMemoryStore(messages ...llm.Message) *InMemoryStore {
+	return &InMemoryStore{messages: append([]llm.Message(nil), messages...)}
+}
+
+// Append adds messages to the end of the conversation
+func (s *InMemoryStore) Append(_ context.Context, messages ...llm.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, messages...)
+	return nil
+}
+
+// Messages returns a copy of the whole conversation
+func (s *InMemoryStore) Messages(_ context.Context) ([]llm.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]llm.Message(nil), s.messages...), nil
+}
+
+// Window returns a copy of the most recent messages
+func (s *InMemoryStore) Window(_ context.Context, limit int) ([]llm.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]llm.Message(nil), windowOf(s.messages, limit)...), nil
+}
+
+// Search returns messages containing query, most recent first
+func (s *InMemoryStore) Search(_ context.Context, query string, limit int) ([]llm.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return searchMessages(s.messages, query, limit), nil
+}
+
+// Len returns the number of stored messages
+func (s *InMemoryStore) Len(_ context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.messages), nil
+}
+
+// Replace swaps the whole conversation
+func (s *InMemoryStore) Replace(_ context.Context, messages []llm.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append([]llm.Message(nil), messages...)
+	return nil
+}
+
+// Clear removes every message
+func (s *InMemoryStore) Clear(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	return nil
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// messageOverhead approximates the tokens providers add per message for role and framing
+const messageOverhead = 4
+
+// EstimateMessageTokens approximates the prompt tokens messages use with the given
+// model, including tool calls and tool results
+func EstimateMessageTokens(messages []llm.Message, model string) int {
+	total := 0
+	for _, message := range messages {
+		total += messageOverhead + prompt.EstimateTokens(message.Content, model)
+		for _, call := range message.ToolCalls {
+			total += prompt.EstimateTokens(fmt.Sprintf("%s %v", call.ToolName, call.ToolArgs), model)
+		}
+		for _, result := range message.ToolResults {
+			// Content is usually repeated in the message itself; only errors add to it
+			total += prompt.EstimateTokens(result.Error, model)
+		}
+	}
+	return total
+}
+
+// TokenTrigger fires when the estimated tokens of a conversation exceed maxTokens
+func TokenTrigger(maxTokens int, model string) Trigger {
+	return func(messages []llm.Message) bool {
+		return maxTokens > 0 && EstimateMessageTokens(messages, model) > maxTokens
+	}
+}
+
+// PromptTokens approximates the prompt tokens of the next call with messages.
+// The latest response carrying the token counts its provider reported, in
+// llm.MetaInputTokens and llm.MetaOutputTokens, gives the actual size of the
+// prompt up to it, including the system prompt and tool schemas; the messages
+// after it are estimated. Without reported counts, or when a summary replaced
+// the history since, it returns EstimateMessageTokens.
+func PromptTokens(messages []llm.Message, model string) int {
+	estimate := EstimateMessageTokens(messages, model)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].HasTag(llm.TagSummary) {
+			break
+		}
+		input, err := strconv.Atoi(messages[i].Meta(llm.MetaInputTokens))
+		if err != nil {
+			continue
+		}
+		output, _ := strconv.Atoi(messages[i].Meta(llm.MetaOutputTokens))
+		return max(input+output+EstimateMessageTokens(messages[i+1:], model), estimate)
+	}
+	return estimate
+}
+
+// ContextTrigger fires when the prompt tokens of a conversation, as computed by
+// PromptTokens, exceed percent of the model's context window
+func ContextTrigger(model string, percent float64) Trigger {
+	maxTokens := int(float64(prompt.ContextWindow(model)) * percent / 100)
+	return func(messages []llm.Message) bool {
+		return maxTokens > 0 && PromptTokens(messages, model) > maxTokens
+	}
+}
+
+// Retention decides which recent messages survive summarization verbatim. Each
+// rule can only extend the kept part; a tool call is never separated from its results.
+type Retention struct {
+	KeepMessages         int // Most recent messages to keep
+	KeepTokens           int // Keep recent messages up to this many estimated tokens
+	KeepToolInteractions int // Keep the most recent tool calls together with their results
+}
+
+// Split returns the index of the first message to keep; messages before it are
+// summarized. A split of 0 or 1 leaves nothing worth summarizing.
+func (r Retention) Split(messages []llm.Message, model string) int {
+	split := len(messages)
+	if r.KeepMessages > 0 {
+		split = max(len(messages)-r.KeepMessages, 0)
+	}
+
+	if r.KeepTokens > 0 {
+		tokens := 0
+		for i := len(messages) - 1; i >= 0; i-- {
+			tokens += EstimateMessageTokens(messages[i:i+1], model)
+			if tokens > r.KeepTokens {
+				break
+			}
+			split = min(split, i)
+		}
+	}
+
+	if r.KeepToolInteractions > 0 {
+		found := 0
+		for i := len(messages) - 1; i >= 0; i-- {
+			if len(messages[i].ToolCalls) > 0 && len(messages[i].ToolResults) == 0 {
+				found++
+				if found == r.KeepToolInteractions {
+					split = min(split, i)
+					break
+				}
+			}
+		}
+	}
+
+	// Keep tool results together with the calls that produced them
+	for split > 0 && split < len(messages) && len(messages[split].ToolResults) > 0 {
+		split--
+	}
+	return split
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestRetention_Split(t *testing.T) {
+	call := llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "search"}}}
+	result := llm.Message{Role: llm.RoleUser, Content: "result", ToolCalls: call.ToolCalls, ToolResults: []llm.ToolResults{{Id: "1"}}}
+	text := func(content string) llm.Message { return llm.Message{Role: llm.RoleUser, Content: content} }
+	long := text(strings.Repeat("word ", 200))
+
+	tests := []struct {
+		name      string
+		retention Retention
+		messages  []llm.Message
+		want      int
+	}{
+		{"keep messages", Retention{KeepMessages: 2}, []llm.Message{text("a"), text("b"), text("c"), text("d")}, 2},
+		{"keep more than available", Retention{KeepMessages: 10}, []llm.Message{text("a"), text("b")}, 0},
+		{"keep nothing", Retention{}, []llm.Message{text("a"), text("b")}, 2},
+		{"tool results stay with calls", Retention{KeepMessages: 1}, []llm.Message{text("a"), text("b"), call, result}, 2},
+		{"keep tokens", Retention{KeepTokens: 50}, []llm.Message{long, text("a"), text("b")}, 1},
+		{"keep tokens extends keep messages", Retention{KeepMessages: 1, KeepTokens: 50}, []llm.Message{long, text("a"), text("b")}, 1},
+		{"keep tool interactions", Retention{KeepMessages: 1, KeepToolInteractions: 1}, []llm.Message{text("a"), call, result, text("b"), text("c")}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.retention.Split(tt.messages, ""); got != tt.want {
+				t.Errorf("Split = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenTrigger(t *testing.T) {
+	short := []llm.Message{{Role: llm.RoleUser, Content: "hello"}}
+	long := []llm.Message{{Role: llm.RoleUser, Content: strings.Repeat("a long tool output ", 100)}}
+
+	trigger := TokenTrigger(100, "gpt-4o")
+	if trigger(short) {
+		t.Error("trigger fired for a short conversation")
+	}
+	if !trigger(long) {
+		t.Errorf("trigger did not fire for ~%d tokens", EstimateMessageTokens(long, "gpt-4o"))
+	}
+	if TokenTrigger(0, "gpt-4o")(long) {
+		t.Error("a zero budget should disable the trigger")
+	}
+}
+
+func TestContextTrigger(t *testing.T) {
+	// gpt-4 has an 8192 token window, so 10 percent is 819 tokens
+	answered := llm.Message{Role: llm.RoleAssistant, Content: "Reading the file."}.
+		WithMeta(llm.MetaInputTokens, "810").WithMeta(llm.MetaOutputTokens, "10")
+	toolResult := llm.Message{Role: llm.RoleUser, Content: "the file contents"}
+	summary := llm.Message{Role: llm.RoleUser, Content: SummaryPrefix + "The user asked for a file."}.WithTags(llm.TagSummary)
+
+	tests := []struct {
+		name     string
+		messages []llm.Message
+		want     bool
+	}{
+		{"reported usage", []llm.Message{answered, toolResult}, true},
+		{"estimate only", []llm.Message{{Role: llm.RoleAssistant, Content: "Reading the file."}, toolResult}, false},
+		{"summarized since", []llm.Message{answered, summary, toolResult}, false},
+		{"long tool output", []llm.Message{{Role: llm.RoleUser, Content: strings.Repeat("a long tool output ", 300)}}, true},
+	}
+	trigger := ContextTrigger("gpt-4", 10)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trigger(tt.messages); got != tt.want {
+				t.Errorf("trigger = %v at ~%d prompt tokens, want %v", got, PromptTokens(tt.messages, "gpt-4"), tt.want)
+			}
+		})
+	}
+}
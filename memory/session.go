@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ErrSessionNotFound is returned when loading a session that was never saved
+var ErrSessionNotFound = errors.New("session not found")
+
+// SQLiteSessionTable is the table SQLiteSessionStore keeps sessions in
+const SQLiteSessionTable = "memory_sessions"
+
+// sessionID limits session IDs to characters that are safe in file names
+var sessionID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// Session is a saved conversation: its messages, including summaries, and the
+// application state needed to pick it up again, such as tool permissions
+type Session struct {
+	ID        string          `json:"id"`
+	Messages  []llm.Message   `json:"messages"`
+	State     json.RawMessage `json:"state,omitempty"`
+	Version   int             `json:"version,omitempty"` // Schema version of State; see the migrate package
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// NewSession captures the messages in history and state, which must be JSON
+// encodable; a nil state saves the messages only
+func NewSession(ctx context.Context, id string, history Store, state any) (Session, error) {
+	messages, err := history.Messages(ctx)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to read session messages: %w", err)
+	}
+
+	session := Session{ID: id, Messages: messages, UpdatedAt: time.Now()}
+	if state != nil {
+		session.State, err = json.Marshal(state)
+		if err != nil {
+			return Session{}, fmt.Errorf("failed to encode session state: %w", err)
+		}
+	}
+	return session, nil
+}
+
+// Restore replaces the messages in history with the session's and decodes the
+// saved state into state; a nil state skips it
+func (s Session) Restore(ctx context.Context, history Store, state any) error {
+	if err := history.Replace(ctx, s.Messages); err != nil {
+		return fmt.Errorf("failed to restore session messages: %w", err)
+	}
+	if state != nil && len(s.State) > 0 {
+		if err := json.Unmarshal(s.State, state); err != nil {
+			return fmt.Errorf("failed to decode session state: %w", err)
+		}
+	}
+	return nil
+}
+
+// NewSessionID returns a readable, sortable ID for a new session
+func NewSessionID() string {
+	return time.Now().Format("20060102-150405")
+}
+
+// SessionStore saves sessions by ID
+type SessionStore interface {
+	SaveSession(ctx context.Context, session Session) error
+	// LoadSession returns ErrSessionNotFound for unknown IDs
+	LoadSession(ctx context.Context, id string) (Session, error)
+	// ListSessions returns the saved session IDs, most recently updated first
+	ListSessions(ctx context.Context) ([]string, error)
+	DeleteSession(ctx context.Context, id string) error
+}
+
+// validateSessionID rejects IDs that are empty or unsafe as file names
+func validateSessionID(id string) error {
+	if !sessionID.MatchString(id) {
+		return fmt.Errorf("invalid session ID %q: use letters, digits, '.', '_' and '-'", id)
+	}
+	return nil
+}
+
+// FileSessionStore keeps each session in a JSON file named after its ID
+type FileSessionStore struct {
+	dir string
+}
+
+// NewFileSessionStore stores sessions in dir, creating it if needed
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// Dir returns the directory sessions are stored in
+func (s *FileSessionStore) Dir() string {
+	return s.dir
+}
+
+// SaveSession writes the session, replacing an earlier save atomically
+func (s *FileSessionStore) SaveSession(ctx context.Context, session Session) error {
+	if err := validateSessionID(session.ID); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	path := s.path(session.ID)
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return fmt.Errorf("failed to replace session: %w", err)
+	}
+	return nil
+}
+
+// LoadSession reads a saved session
+func (s *FileSessionStore) LoadSession(ctx context.Context, id string) (Session, error) {
+	if err := validateSessionID(id); err != nil {
+		return Session{}, err
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return Session{}, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	return session, nil
+}
+
+// ListSessions returns the IDs of the session files, most recently modified first
+func (s *FileSessionStore) ListSessions(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	type saved struct {
+		id       string
+		modified time.Time
+	}
+	sessions := make([]saved, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || validateSessionID(id) != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, saved{id: id, modified: info.ModTime()})
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].modified.After(sessions[j].modified) })
+
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.id
+	}
+	return ids, nil
+}
+
+// DeleteSession removes a saved session; deleting an unknown session is not an error
+func (s *FileSessionStore) DeleteSession(ctx context.Context, id string) error {
+	if err := validateSessionID(id); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a session
+func (s *FileSessionStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// SQLiteSessionStore keeps sessions in a SQLite database, one row per session
+type SQLiteSessionStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteSessionStore creates the session table in db if needed. As with
+// NewSQLiteStore, the caller opens db with a driver of their choice.
+func NewSQLiteSessionStore(ctx context.Context, db *sql.DB) (*SQLiteSessionStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+SQLiteSessionTable+` (
+		id TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session table: %w", err)
+	}
+	return &SQLiteSessionStore{db: db}, nil
+}
+
+// SaveSession inserts or replaces the session
+func (s *SQLiteSessionStore) SaveSession(ctx context.Context, session Session) error {
+	if err := validateSessionID(session.ID); err != nil {
+		return err
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO `+SQLiteSessionTable+` (id, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		session.ID, string(data), session.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// LoadSession reads a saved session
+func (s *SQLiteSessionStore) LoadSession(ctx context.Context, id string) (Session, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM `+SQLiteSessionTable+` WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return Session{}, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	return session, nil
+}
+
+// ListSessions returns the saved session IDs, most recently updated first
+func (s *SQLiteSessionStore) ListSessions(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM `+SQLiteSessionTable+` ORDER BY updated_at DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to read session ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteSession removes a saved session
+func (s *SQLiteSessionStore) DeleteSession(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM `+SQLiteSessionTable+` WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
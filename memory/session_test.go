@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+type savedState struct {
+	AllowedTools []string `json:"allowed_tools"`
+}
+
+// testSessionStore exercises the SessionStore contract
+func testSessionStore(t *testing.T, store SessionStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	history := NewInMemoryStore(
+		llm.Message{Role: llm.RoleUser, Content: SummaryPrefix + "The user is planning a trip."},
+		llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "search"}}},
+	)
+	session, err := NewSession(ctx, "trip", history, savedState{AllowedTools: []string{"search"}})
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if err := store.SaveSession(ctx, session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	session.ID = "older"
+	session.UpdatedAt = session.UpdatedAt.Add(-time.Hour)
+	store.SaveSession(ctx, session)
+
+	loaded, err := store.LoadSession(ctx, "trip")
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	restored := NewInMemoryStore(llm.Message{Role: llm.RoleUser, Content: "stale"})
+	var state savedState
+	if err := loaded.Restore(ctx, restored, &state); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	messages, _ := restored.Messages(ctx)
+	if len(messages) != 2 || messages[0].Content != SummaryPrefix+"The user is planning a trip." || messages[1].ToolCalls[0].ToolName != "search" {
+		t.Errorf("restored messages = %+v", messages)
+	}
+	if len(state.AllowedTools) != 1 || state.AllowedTools[0] != "search" {
+		t.Errorf("restored state = %+v", state)
+	}
+
+	if ids, err := store.ListSessions(ctx); err != nil || len(ids) != 2 || ids[0] != "trip" && ids[1] != "trip" {
+		t.Errorf("ListSessions = %v, %v", ids, err)
+	}
+
+	if err := store.DeleteSession(ctx, "trip"); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if _, err := store.LoadSession(ctx, "trip"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound after delete, got %v", err)
+	}
+	if err := store.SaveSession(ctx, Session{ID: "../escape"}); err == nil {
+		t.Error("expected an error for an unsafe session ID")
+	}
+}
+
+func TestFileSessionStore(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore failed: %v", err)
+	}
+	testSessionStore(t, store)
+}
+
+func TestSQLiteSessionStore(t *testing.T) {
+	store, err := NewSQLiteSessionStore(context.Background(), openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSQLiteSessionStore failed: %v", err)
+	}
+	testSessionStore(t, store)
+
+	ids, _ := store.ListSessions(context.Background())
+	if len(ids) != 1 || ids[0] != "older" {
+		t.Errorf("ListSessions after delete = %v", ids)
+	}
+}
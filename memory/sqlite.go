@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// SQLiteTable is the table SQLiteStore keeps messages in
+const SQLiteTable = "memory_messages"
+
+// SQLiteStore keeps conversations in a SQLite database, one row per message.
+// Several conversations can share a database; each store reads and writes only
+// the rows of its own conversation ID.
+type SQLiteStore struct {
+	db             *sql.DB
+	conversationID string
+}
+
+// NewSQLiteStore creates a store for one conversation in db, creating the table
+// if needed. The caller opens db with the SQLite driver of their choice, e.g.
+// modernc.org/sqlite or github.com/mattn/go-sqlite3, and owns closing it.
+func NewSQLiteStore(ctx context.Context, db *sql.DB, conversationID string) (*SQLiteStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	if conversationID == "" {
+		return nil, fmt.Errorf("conversation ID cannot be empty")
+	}
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+SQLiteTable+` (
+		conversation_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		content TEXT NOT NULL,
+		data TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (conversation_id, seq)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory table: %w", err)
+	}
+	return &SQLiteStore{db: db, conversationID: conversationID}, nil
+}
+
+// ConversationID returns the conversation the store reads and writes
+func (s *SQLiteStore) ConversationID() string {
+	return s.conversationID
+}
+
+// Append inserts messages after the last stored message
+func (s *SQLiteStore) Append(ctx context.Context, messages ...llm.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin memory transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var last int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(seq), 0) FROM `+SQLiteTable+` WHERE conversation_id = ?`,
+		s.conversationID).Scan(&last)
+	if err != nil {
+		return fmt.Errorf("failed to read memory sequence: %w", err)
+	}
+	if err := s.insert(ctx, tx, last, messages); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit messages: %w", err)
+	}
+	return nil
+}
+
+// Messages returns the whole conversation
+func (s *SQLiteStore) Messages(ctx context.Context) ([]llm.Message, error) {
+	return s.query(ctx, `SELECT data FROM `+SQLiteTable+` WHERE conversation_id = ? ORDER BY seq`, s.conversationID)
+}
+
+// Window returns the most recent messages
+func (s *SQLiteStore) Window(ctx context.Context, limit int) ([]llm.Message, error) {
+	if limit <= 0 {
+		return s.Messages(ctx)
+	}
+	// Fetch one extra row so windowOf can tell whether older messages were cut off
+	messages, err := s.query(ctx, `SELECT data FROM (
+		SELECT seq, data FROM `+SQLiteTable+` WHERE conversation_id = ? ORDER BY seq DESC LIMIT ?
+	) ORDER BY seq`, s.conversationID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return windowOf(messages, limit), nil
+}
+
+// Search returns messages containing query, most recent first. Matching ignores
+// case for ASCII letters only, following SQLite's LIKE.
+func (s *SQLiteStore) Search(ctx context.Context, query string, limit int) ([]llm.Message, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative limit as no limit
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return s.query(ctx, `SELECT data FROM `+SQLiteTable+`
+		WHERE conversation_id = ? AND content LIKE ? ESCAPE '\'
+		ORDER BY seq DESC LIMIT ?`,
+		s.conversationID, "%"+escaper.Replace(query)+"%", limit)
+}
+
+// Len returns the number of stored messages
+func (s *SQLiteStore) Len(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM `+SQLiteTable+` WHERE conversation_id = ?`,
+		s.conversationID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+	return count, nil
+}
+
+// Replace swaps the conversation's rows in a single transaction
+func (s *SQLiteStore) Replace(ctx context.Context, messages []llm.Message) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin memory transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+SQLiteTable+` WHERE conversation_id = ?`, s.conversationID); err != nil {
+		return fmt.Errorf("failed to delete messages: %w", err)
+	}
+	if err := s.insert(ctx, tx, 0, messages); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit messages: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every message of the conversation
+func (s *SQLiteStore) Clear(ctx context.Context) error {
+	return s.Replace(ctx, nil)
+}
+
+// insert writes messages with sequence numbers following last
+func (s *SQLiteStore) insert(ctx context.Context, tx *sql.Tx, last int64, messages []llm.Message) error {
+	for i, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO `+SQLiteTable+` (conversation_id, seq, role, content, data) VALUES (?, ?, ?, ?, ?)`,
+			s.conversationID, last+int64(i)+1, message.Role, message.Content, string(data))
+		if err != nil {
+			return fmt.Errorf("failed to insert message: %w", err)
+		}
+	}
+	return nil
+}
+
+// query decodes the data column of every returned row
+func (s *SQLiteStore) query(ctx context.Context, query string, args ...any) ([]llm.Message, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []llm.Message
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		var message llm.Message
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			return nil, fmt.Errorf("failed to decode message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages: %w", err)
+	}
+	return messages, nil
+}
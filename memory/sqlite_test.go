@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("sqlite3 driver unavailable: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteStore(t *testing.T) {
+	db := openTestDB(t)
+	store, err := NewSQLiteStore(context.Background(), db, "chat-1")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	testStore(t, store)
+}
+
+func TestSQLiteStore_SeparatesConversations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	first, _ := NewSQLiteStore(ctx, db, "first")
+	second, _ := NewSQLiteStore(ctx, db, "second")
+	first.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "hello from first"})
+	second.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "hello from second"})
+	first.Clear(ctx)
+
+	if n, _ := first.Len(ctx); n != 0 {
+		t.Errorf("first conversation has %d messages after Clear", n)
+	}
+	messages, _ := second.Messages(ctx)
+	if len(messages) != 1 || messages[0].Content != "hello from second" {
+		t.Errorf("second conversation holds %+v", messages)
+	}
+}
+
+func TestNewSQLiteStore_Validation(t *testing.T) {
+	if _, err := NewSQLiteStore(context.Background(), nil, "chat"); err == nil {
+		t.Error("expected an error for a nil database")
+	}
+	if _, err := NewSQLiteStore(context.Background(), openTestDB(t), ""); err == nil {
+		t.Error("expected an error for an empty conversation ID")
+	}
+}
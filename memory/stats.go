@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// HistoryStats is where the tokens of a conversation go, for summarization and
+// cleanup decisions and for logs
+type HistoryStats struct {
+	Model         string
+	Messages      int
+	Tokens        int            // Estimated tokens of all messages, as EstimateMessageTokens counts them
+	PromptTokens  int            // Prompt tokens of the next call, as PromptTokens counts them
+	Window        int            // Context window of Model
+	WindowPercent float64        // PromptTokens as a percentage of Window
+	PerMessage    []int          // Estimated tokens of each message
+	ByRole        map[string]int // Estimated tokens of the messages of each role
+	ByTool        map[string]int // Estimated tokens of the calls and results of each tool
+}
+
+// AnalyzeHistory estimates the tokens messages use with the given model. Tool
+// results are counted for their tool by the call they answer, including the
+// content that tool nodes repeat in the message text, so ByTool shows which
+// tools fill the window; results whose call was cut off count for "unknown".
+func AnalyzeHistory(messages []llm.Message, model string) HistoryStats {
+	stats := HistoryStats{
+		Model:        model,
+		Messages:     len(messages),
+		PromptTokens: PromptTokens(messages, model),
+		Window:       prompt.ContextWindow(model),
+		PerMessage:   make([]int, len(messages)),
+		ByRole:       make(map[string]int),
+		ByTool:       make(map[string]int),
+	}
+	for i := range messages {
+		tokens := EstimateMessageTokens(messages[i:i+1], model)
+		stats.PerMessage[i] = tokens
+		stats.Tokens += tokens
+		stats.ByRole[messages[i].Role] += tokens
+	}
+	if stats.Window > 0 {
+		stats.WindowPercent = float64(stats.PromptTokens) * 100 / float64(stats.Window)
+	}
+
+	for _, exchange := range llm.CorrelateToolCalls(messages) {
+		call := exchange.Call
+		stats.ByTool[call.ToolName] += prompt.EstimateTokens(fmt.Sprintf("%s %v", call.ToolName, call.ToolArgs), model)
+		if exchange.Result != nil {
+			stats.ByTool[call.ToolName] += resultTokens(*exchange.Result, model)
+		}
+	}
+	for _, result := range llm.OrphanedToolResults(messages) {
+		stats.ByTool["unknown"] += resultTokens(result, model)
+	}
+	return stats
+}
+
+// resultTokens estimates the tokens of a tool result
+func resultTokens(result llm.ToolResults, model string) int {
+	return prompt.EstimateTokens(result.Content, model) + prompt.EstimateTokens(result.Error, model)
+}
+
+// Largest returns the indexes of the n messages with the most tokens, largest first
+func (s HistoryStats) Largest(n int) []int {
+	indexes := make([]int, len(s.PerMessage))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return s.PerMessage[indexes[a]] > s.PerMessage[indexes[b]]
+	})
+	return indexes[:min(max(n, 0), len(indexes))]
+}
+
+// String describes the stats in one line for logs, e.g. "12 messages, ~5400
+// tokens, 4.2% of the 128000 token window of gpt-4o; roles: user 3100,
+// assistant 2300; tools: search 2800"
+func (s HistoryStats) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d messages, ~%d tokens, %.1f%% of the %d token window", s.Messages, s.Tokens, s.WindowPercent, s.Window)
+	if s.Model != "" {
+		fmt.Fprintf(&builder, " of %s", s.Model)
+	}
+	if len(s.ByRole) > 0 {
+		builder.WriteString("; roles: " + formatTokens(s.ByRole))
+	}
+	if len(s.ByTool) > 0 {
+		builder.WriteString("; tools: " + formatTokens(s.ByTool))
+	}
+	return builder.String()
+}
+
+// formatTokens lists token counts by name, largest first
+func formatTokens(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool {
+		if counts[names[a]] != counts[names[b]] {
+			return counts[names[a]] > counts[names[b]]
+		}
+		return names[a] < names[b]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StatsTrigger fires when check returns true for the stats of a conversation,
+// e.g. to summarize once a single tool uses a third of the window
+func StatsTrigger(model string, check func(stats HistoryStats) bool) Trigger {
+	return func(messages []llm.Message) bool {
+		return check(AnalyzeHistory(messages, model))
+	}
+}
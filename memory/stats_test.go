@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestAnalyzeHistory(t *testing.T) {
+	output := strings.Repeat("a long file line ", 200)
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are helpful."},
+		{Role: llm.RoleUser, Content: "Read the file"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "read_file", ToolArgs: map[string]any{"path": "a.txt"}}}},
+		{Role: llm.RoleUser, Content: output, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "read_file"}}, ToolResults: []llm.ToolResults{{Id: "1", Content: output}}},
+		{Role: llm.RoleUser, ToolResults: []llm.ToolResults{{Id: "7", Content: "stray"}}},
+	}
+
+	stats := AnalyzeHistory(messages, "gpt-4")
+	if stats.Messages != 5 || len(stats.PerMessage) != 5 {
+		t.Fatalf("stats = %+v, want 5 messages", stats)
+	}
+	if stats.Tokens != EstimateMessageTokens(messages, "gpt-4") {
+		t.Errorf("Tokens = %d, want %d", stats.Tokens, EstimateMessageTokens(messages, "gpt-4"))
+	}
+	sum := 0
+	for _, tokens := range stats.ByRole {
+		sum += tokens
+	}
+	if sum != stats.Tokens || stats.ByRole[llm.RoleUser] <= stats.ByRole[llm.RoleSystem] {
+		t.Errorf("ByRole = %v, want user tokens summing to %d", stats.ByRole, stats.Tokens)
+	}
+	if stats.ByTool["read_file"] < 600 || stats.ByTool["unknown"] == 0 {
+		t.Errorf("ByTool = %v, want the file output counted for read_file and the stray result as unknown", stats.ByTool)
+	}
+	if stats.Window != 8192 || stats.WindowPercent < 5 || stats.WindowPercent > 20 {
+		t.Errorf("Window = %d, WindowPercent = %.1f", stats.Window, stats.WindowPercent)
+	}
+	if got := stats.Largest(1); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Largest(1) = %v, want [3]", got)
+	}
+	if got := stats.Largest(10); len(got) != 5 {
+		t.Errorf("Largest(10) = %v, want all 5 messages", got)
+	}
+
+	line := stats.String()
+	for _, want := range []string{"5 messages", "token window of gpt-4", "roles: user ", "tools: read_file "} {
+		if !strings.Contains(line, want) {
+			t.Errorf("String() = %q, want %q", line, want)
+		}
+	}
+}
+
+func TestStatsTrigger(t *testing.T) {
+	messages := []llm.Message{
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "search"}}},
+		{Role: llm.RoleUser, ToolResults: []llm.ToolResults{{Id: "1", Content: strings.Repeat("result ", 500)}}},
+	}
+	trigger := StatsTrigger("gpt-4", func(stats HistoryStats) bool {
+		return stats.ByTool["search"] > stats.Window/10
+	})
+	if !trigger(messages) {
+		t.Error("trigger did not fire for a large search result")
+	}
+	if trigger(messages[:1]) {
+		t.Error("trigger fired without the result")
+	}
+}
@@ -0,0 +1,64 @@
+// Package memory stores conversation history outside of flow state so chat and
+// agent nodes can share, persist and trim it without holding raw message slices.
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// Store holds the messages of a single conversation
+type Store interface {
+	// Append adds messages to the end of the conversation
+	Append(ctx context.Context, messages ...llm.Message) error
+
+	// Messages returns the whole conversation, oldest first
+	Messages(ctx context.Context) ([]llm.Message, error)
+
+	// Window returns the most recent messages, at most limit of them; a limit of
+	// zero or less returns the whole conversation
+	Window(ctx context.Context, limit int) ([]llm.Message, error)
+
+	// Search returns up to limit messages whose content contains query, ignoring
+	// case, most recent first
+	Search(ctx context.Context, query string, limit int) ([]llm.Message, error)
+
+	// Len returns the number of stored messages
+	Len(ctx context.Context) (int, error)
+
+	// Replace swaps the whole conversation, e.g. after summarizing older messages
+	Replace(ctx context.Context, messages []llm.Message) error
+
+	// Clear removes every message
+	Clear(ctx context.Context) error
+}
+
+// windowOf returns the last limit messages. A window never starts with tool
+// results whose calls were cut off, since providers reject orphaned results.
+func windowOf(messages []llm.Message, limit int) []llm.Message {
+	if limit <= 0 || len(messages) <= limit {
+		return messages
+	}
+	window := messages[len(messages)-limit:]
+	for len(window) > 0 && len(window[0].ToolResults) > 0 {
+		window = window[1:]
+	}
+	return window
+}
+
+// searchMessages scans messages from newest to oldest for query
+func searchMessages(messages []llm.Message, query string, limit int) []llm.Message {
+	query = strings.ToLower(query)
+	var matches []llm.Message
+	for i := len(messages) - 1; i >= 0; i-- {
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+		if strings.Contains(strings.ToLower(messages[i].Content), query) {
+			matches = append(matches, messages[i])
+		}
+	}
+	return matches
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// testStore runs the behaviour every Store implementation must share
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	if n, err := store.Len(ctx); err != nil || n != 0 {
+		t.Fatalf("new store Len = %d, %v; want 0", n, err)
+	}
+
+	err := store.Append(ctx,
+		llm.Message{Role: llm.RoleUser, Content: "What is the weather in Paris?"},
+		llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "weather", ToolArgs: map[string]any{"city": "Paris"}}}},
+		llm.Message{Role: llm.RoleUser, Content: "sunny, 21C", ToolResults: []llm.ToolResults{{Id: "1", Content: "sunny, 21C"}}},
+		llm.Message{Role: llm.RoleAssistant, Content: "It is sunny in PARIS."},
+	)
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	messages, err := store.Messages(ctx)
+	if err != nil {
+		t.Fatalf("Messages failed: %v", err)
+	}
+	if len(messages) != 4 || messages[0].Content != "What is the weather in Paris?" {
+		t.Fatalf("Messages = %+v", messages)
+	}
+	if messages[1].ToolCalls[0].ToolArgs["city"] != "Paris" || messages[2].ToolResults[0].Id != "1" {
+		t.Errorf("tool calls and results were not preserved: %+v", messages)
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{"whole conversation", 0, []string{"What is the weather in Paris?", "", "sunny, 21C", "It is sunny in PARIS."}},
+		{"last message", 1, []string{"It is sunny in PARIS."}},
+		{"drops orphaned tool results", 2, []string{"It is sunny in PARIS."}},
+		{"keeps call with results", 3, []string{"", "sunny, 21C", "It is sunny in PARIS."}},
+	}
+	for _, tt := range tests {
+		t.Run("Window/"+tt.name, func(t *testing.T) {
+			window, err := store.Window(ctx, tt.limit)
+			if err != nil {
+				t.Fatalf("Window failed: %v", err)
+			}
+			if len(window) != len(tt.want) {
+				t.Fatalf("Window(%d) returned %d messages, want %d", tt.limit, len(window), len(tt.want))
+			}
+			for i, message := range window {
+				if message.Content != tt.want[i] {
+					t.Errorf("Window(%d)[%d] = %q, want %q", tt.limit, i, message.Content, tt.want[i])
+				}
+			}
+		})
+	}
+
+	matches, err := store.Search(ctx, "paris", 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Content != "It is sunny in PARIS." {
+		t.Errorf("Search = %+v, want the two Paris messages newest first", matches)
+	}
+	if matches, _ := store.Search(ctx, "paris", 1); len(matches) != 1 {
+		t.Errorf("Search with limit 1 returned %d messages", len(matches))
+	}
+	if matches, _ := store.Search(ctx, "100%", 0); len(matches) != 0 {
+		t.Errorf("Search for a wildcard character matched %+v", matches)
+	}
+
+	if err := store.Replace(ctx, []llm.Message{{Role: llm.RoleUser, Content: "summary"}}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if err := store.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "next"}); err != nil {
+		t.Fatalf("Append after Replace failed: %v", err)
+	}
+	messages, _ = store.Messages(ctx)
+	if len(messages) != 2 || messages[0].Content != "summary" || messages[1].Content != "next" {
+		t.Errorf("Messages after Replace = %+v", messages)
+	}
+
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if n, _ := store.Len(ctx); n != 0 {
+		t.Errorf("Len after Clear = %d", n)
+	}
+}
+
+func TestInMemoryStore(t *testing.T) {
+	testStore(t, NewInMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions", "chat.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	testStore(t, store)
+
+	ctx := context.Background()
+	store.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "remember me"})
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	messages, _ := reopened.Messages(ctx)
+	if len(messages) != 1 || messages[0].Content != "remember me" {
+		t.Errorf("reopened store holds %+v", messages)
+	}
+}
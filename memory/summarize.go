@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// SummaryPrefix starts the content of messages that replace summarized history
+const SummaryPrefix = "Summary of the earlier conversation:\n"
+
+// DefaultSummaryPrompt asks the model to condense a conversation transcript
+const DefaultSummaryPrompt = `Summarize the conversation below so it can replace the original messages.
+Keep facts, decisions, open questions, user preferences and tool results that later turns may rely on.
+Reply with the summary only.`
+
+// Trigger decides whether a conversation should be summarized
+type Trigger func(messages []llm.Message) bool
+
+// MessageCountTrigger fires when a conversation holds more than max messages
+func MessageCountTrigger(max int) Trigger {
+	return func(messages []llm.Message) bool {
+		return max > 0 && len(messages) > max
+	}
+}
+
+// Summarizer condenses messages into a single message
+type Summarizer interface {
+	Summarize(ctx context.Context, messages []llm.Message) (llm.Message, error)
+}
+
+// LLMSummarizer summarizes messages with an LLM provider
+type LLMSummarizer struct {
+	provider llm.LLMProvider
+	prompt   string
+}
+
+// NewLLMSummarizer creates a summarizer; an empty prompt uses DefaultSummaryPrompt
+func NewLLMSummarizer(provider llm.LLMProvider, prompt string) *LLMSummarizer {
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+	return &LLMSummarizer{provider: provider, prompt: prompt}
+}
+
+// Summarize sends a transcript of messages to the provider and returns the summary
+// as a user message tagged llm.TagSummary, which every provider accepts at any
+// position. Messages tagged llm.TagRepair are left out of the transcript.
+func (s *LLMSummarizer) Summarize(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	var transcript strings.Builder
+	for _, message := range messages {
+		if message.HasTag(llm.TagRepair) {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, message.Content)
+		for _, call := range message.ToolCalls {
+			fmt.Fprintf(&transcript, "%s called tool %s with %v\n", message.Role, call.ToolName, call.ToolArgs)
+		}
+	}
+
+	response, err := s.provider.CallLLM(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: s.prompt},
+		{Role: llm.RoleUser, Content: transcript.String()},
+	})
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	return llm.Message{
+		Role:    llm.RoleUser,
+		Content: SummaryPrefix + strings.TrimSpace(response.Content),
+		Tags:    []string{llm.TagSummary},
+	}, nil
+}
+
+// SummarizingStore wraps a Store and, whenever its trigger fires after an append,
+// replaces all but the most recent messages with a summary
+type SummarizingStore struct {
+	Store
+	trigger    Trigger
+	summarizer Summarizer
+	keep       int
+}
+
+// NewSummarizingStore wraps store; keep is the number of recent messages left untouched
+func NewSummarizingStore(store Store, trigger Trigger, summarizer Summarizer, keep int) *SummarizingStore {
+	if keep < 0 {
+		keep = 0
+	}
+	return &SummarizingStore{Store: store, trigger: trigger, summarizer: summarizer, keep: keep}
+}
+
+// Append stores messages and then summarizes if the trigger fires. A summarization
+// error is returned after the messages have been stored, so callers may log it and
+// carry on with the longer history.
+func (s *SummarizingStore) Append(ctx context.Context, messages ...llm.Message) error {
+	if err := s.Store.Append(ctx, messages...); err != nil {
+		return err
+	}
+
+	history, err := s.Store.Messages(ctx)
+	if err != nil {
+		return err
+	}
+	if s.trigger == nil || !s.trigger(history) {
+		return nil
+	}
+	return s.summarize(ctx, history)
+}
+
+// summarize condenses everything before the kept messages
+func (s *SummarizingStore) summarize(ctx context.Context, history []llm.Message) error {
+	split := Retention{KeepMessages: s.keep}.Split(history, "")
+	if split <= 1 {
+		return nil
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, history[:split])
+	if err != nil {
+		return err
+	}
+	return s.Store.Replace(ctx, append([]llm.Message{summary}, history[split:]...))
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestSummarizingStore(t *testing.T) {
+	ctx := context.Background()
+	provider := llm.NewMockProvider("summarizer")
+	provider.SetResponsePattern(map[string]string{"message 1": "The user asked about Paris."})
+
+	store := NewSummarizingStore(NewInMemoryStore(), MessageCountTrigger(4),
+		NewLLMSummarizer(provider, ""), 2)
+
+	for i := 1; i <= 4; i++ {
+		store.Append(ctx, llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("message %d", i)})
+	}
+	if provider.GetCallCount() != 0 {
+		t.Fatalf("summarized before the trigger fired")
+	}
+
+	if err := store.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "message 5"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	messages, _ := store.Messages(ctx)
+	if len(messages) != 3 {
+		t.Fatalf("expected a summary and two kept messages, got %+v", messages)
+	}
+	if !strings.HasPrefix(messages[0].Content, SummaryPrefix) || !strings.Contains(messages[0].Content, "Paris") {
+		t.Errorf("summary message = %q", messages[0].Content)
+	}
+	if messages[1].Content != "message 4" || messages[2].Content != "message 5" {
+		t.Errorf("kept messages = %+v", messages[1:])
+	}
+}
+
+func TestLLMSummarizer_Tags(t *testing.T) {
+	provider := llm.NewMockProvider("summarizer")
+	// Echoes the transcript back as the summary
+	provider.SetResponses([]string{""})
+
+	summary, err := NewLLMSummarizer(provider, "").Summarize(context.Background(), []llm.Message{
+		{Role: llm.RoleUser, Content: "What is the capital of France?"},
+		llm.Message{Role: llm.RoleAssistant, Content: "{broken"}.WithTags(llm.TagRepair),
+		{Role: llm.RoleAssistant, Content: "Paris"},
+	})
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if !summary.HasTag(llm.TagSummary) {
+		t.Errorf("summary tags = %v", summary.Tags)
+	}
+	if strings.Contains(summary.Content, "{broken") || !strings.Contains(summary.Content, "Paris") {
+		t.Errorf("transcript = %q, want it without the repair message", summary.Content)
+	}
+}
+
+func TestSummarizingStore_KeepsToolResultsWithCalls(t *testing.T) {
+	ctx := context.Background()
+	provider := llm.NewMockProvider("summarizer")
+
+	store := NewSummarizingStore(NewInMemoryStore(), MessageCountTrigger(3),
+		NewLLMSummarizer(provider, ""), 1)
+	store.Append(ctx,
+		llm.Message{Role: llm.RoleUser, Content: "first"},
+		llm.Message{Role: llm.RoleUser, Content: "second"},
+		llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCalls{{Id: "1", ToolName: "search"}}},
+		llm.Message{Role: llm.RoleUser, Content: "result", ToolResults: []llm.ToolResults{{Id: "1"}}},
+	)
+
+	messages, _ := store.Messages(ctx)
+	if len(messages) != 3 || len(messages[1].ToolCalls) != 1 || len(messages[2].ToolResults) != 1 {
+		t.Errorf("tool call was separated from its results: %+v", messages)
+	}
+}
+
+func TestSummarizingStore_SummarizerError(t *testing.T) {
+	ctx := context.Background()
+	provider := llm.NewMockProvider("summarizer")
+	provider.SetError(true, "rate limited")
+
+	store := NewSummarizingStore(NewInMemoryStore(), MessageCountTrigger(1),
+		NewLLMSummarizer(provider, ""), 0)
+	err := store.Append(ctx,
+		llm.Message{Role: llm.RoleUser, Content: "first"},
+		llm.Message{Role: llm.RoleUser, Content: "second"},
+	)
+	if err == nil {
+		t.Fatal("expected the summarization error")
+	}
+	if n, _ := store.Len(ctx); n != 2 {
+		t.Errorf("messages should be stored despite the error, Len = %d", n)
+	}
+}
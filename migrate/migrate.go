@@ -0,0 +1,150 @@
+// Package migrate upgrades persisted flow state saved by an older version of a
+// program, so checkpointed runs and saved sessions can be resumed after the
+// flow graph or the state schema changed. A flow's state schema has an integer
+// version; each Migration upgrades state from one version to the next.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+var (
+	// ErrNewerVersion is returned for state saved by a newer version than the program knows
+	ErrNewerVersion = errors.New("state was saved by a newer version")
+	// ErrNoMigration is returned when no migration upgrades state from its version
+	ErrNoMigration = errors.New("no migration registered")
+)
+
+// Migration upgrades state from version From to From+1
+type Migration struct {
+	From int
+	// State rewrites the decoded JSON state in place, e.g. to rename or fill in
+	// fields; nil leaves the state unchanged. Numbers are json.Number.
+	State func(state map[string]any) error
+	// Actions rewrites the actions of a checkpoint's finished steps to match the
+	// new flow graph, e.g. when an action was renamed or a node was inserted;
+	// nil leaves them unchanged
+	Actions func(actions []core.Action) ([]core.Action, error)
+}
+
+// Migrations upgrades state to the current version
+type Migrations struct {
+	current int
+	steps   map[int]Migration
+}
+
+// New returns the migrations to version current. State saved before versioning
+// was introduced has version 0, so a program's first migration starts From 0.
+func New(current int, migrations ...Migration) (*Migrations, error) {
+	if current < 0 {
+		return nil, fmt.Errorf("version cannot be negative, got %d", current)
+	}
+	m := &Migrations{current: current, steps: make(map[int]Migration)}
+	for _, migration := range migrations {
+		if migration.From < 0 || migration.From >= current {
+			return nil, fmt.Errorf("migration from version %d is outside versions 0 to %d", migration.From, current)
+		}
+		if _, exists := m.steps[migration.From]; exists {
+			return nil, fmt.Errorf("migration from version %d is registered twice", migration.From)
+		}
+		m.steps[migration.From] = migration
+	}
+	return m, nil
+}
+
+// Current returns the version state is migrated to
+func (m *Migrations) Current() int {
+	return m.current
+}
+
+// Migrate upgrades state and the actions of finished steps saved at version.
+// State at the current version is returned unchanged.
+func (m *Migrations) Migrate(version int, state []byte, actions []core.Action) ([]byte, []core.Action, error) {
+	if version > m.current {
+		return nil, nil, fmt.Errorf("%w: version %d, this program has version %d", ErrNewerVersion, version, m.current)
+	}
+	if version == m.current {
+		return state, actions, nil
+	}
+
+	var document map[string]any
+	if len(bytes.TrimSpace(state)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(state))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode state of version %d: %w", version, err)
+		}
+	}
+	for ; version < m.current; version++ {
+		migration, ok := m.steps[version]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w from version %d", ErrNoMigration, version)
+		}
+		if migration.State != nil {
+			if document == nil {
+				document = make(map[string]any)
+			}
+			if err := migration.State(document); err != nil {
+				return nil, nil, fmt.Errorf("failed to migrate state from version %d: %w", version, err)
+			}
+		}
+		if migration.Actions != nil {
+			var err error
+			if actions, err = migration.Actions(actions); err != nil {
+				return nil, nil, fmt.Errorf("failed to migrate actions from version %d: %w", version, err)
+			}
+		}
+	}
+
+	if document == nil {
+		return state, actions, nil
+	}
+	migrated, err := json.Marshal(document)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode migrated state: %w", err)
+	}
+	return migrated, actions, nil
+}
+
+// MigrateSession upgrades the state of a saved session and sets its version,
+// before the session is restored with Session.Restore
+func (m *Migrations) MigrateSession(session *memory.Session) error {
+	state, _, err := m.Migrate(session.Version, session.State, nil)
+	if err != nil {
+		return fmt.Errorf("session %s: %w", session.ID, err)
+	}
+	session.State = state
+	session.Version = m.current
+	return nil
+}
+
+// RenameField returns a State migration that moves a top-level field to a new name
+func RenameField(from, to string) func(state map[string]any) error {
+	return func(state map[string]any) error {
+		if value, ok := state[from]; ok {
+			state[to] = value
+			delete(state, from)
+		}
+		return nil
+	}
+}
+
+// RenameAction returns an Actions migration that renames an action
+func RenameAction(from, to core.Action) func(actions []core.Action) ([]core.Action, error) {
+	return func(actions []core.Action) ([]core.Action, error) {
+		renamed := make([]core.Action, len(actions))
+		for i, action := range actions {
+			if action == from {
+				action = to
+			}
+			renamed[i] = action
+		}
+		return renamed, nil
+	}
+}
@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+func testMigrations(t *testing.T) *Migrations {
+	t.Helper()
+	migrations, err := New(3,
+		Migration{From: 0, State: RenameField("history", "messages")},
+		Migration{From: 1, State: func(state map[string]any) error {
+			if _, ok := state["max_steps"]; !ok {
+				state["max_steps"] = 10
+			}
+			return nil
+		}},
+		Migration{From: 2, Actions: RenameAction("tools", "call_tools")},
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return migrations
+}
+
+func TestMigrations_Migrate(t *testing.T) {
+	migrations := testMigrations(t)
+	tests := []struct {
+		name        string
+		version     int
+		state       string
+		wantState   string
+		wantActions string
+		wantErr     error
+	}{
+		{"unversioned", 0, `{"history": ["hi"], "count": 12345678901234567}`, `{"count":12345678901234567,"max_steps":10,"messages":["hi"]}`, "[success call_tools]", nil},
+		{"partly migrated", 2, `{"max_steps": 3}`, `{"max_steps":3}`, "[success call_tools]", nil},
+		{"current", 3, `{"max_steps": 3}`, `{"max_steps": 3}`, "[success tools]", nil},
+		{"empty state", 1, ``, `{"max_steps":10}`, "[success call_tools]", nil},
+		{"newer", 4, `{}`, "", "", ErrNewerVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, actions, err := migrations.Migrate(tt.version, []byte(tt.state), []core.Action{core.ActionSuccess, "tools"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Migrate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(state) != tt.wantState || fmt.Sprint(actions) != tt.wantActions {
+				t.Errorf("Migrate() = %s, %v, %v; want %s, %s", state, actions, err, tt.wantState, tt.wantActions)
+			}
+		})
+	}
+
+	gap, _ := New(2, Migration{From: 1})
+	if _, _, err := gap.Migrate(0, []byte(`{}`), nil); !errors.Is(err, ErrNoMigration) {
+		t.Errorf("Migrate without a migration from 0 = %v, want ErrNoMigration", err)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(1, Migration{From: 1}); err == nil {
+		t.Error("expected an error for a migration from the current version")
+	}
+	if _, err := New(2, Migration{From: 0}, Migration{From: 0}); err == nil {
+		t.Error("expected an error for a duplicate migration")
+	}
+}
+
+func TestMigrations_MigrateSession(t *testing.T) {
+	session := memory.Session{ID: "s1", State: json.RawMessage(`{"history": []}`)}
+	if err := testMigrations(t).MigrateSession(&session); err != nil {
+		t.Fatalf("MigrateSession failed: %v", err)
+	}
+	if session.Version != 3 || string(session.State) != `{"max_steps":10,"messages":[]}` {
+		t.Errorf("migrated session = version %d, state %s", session.Version, session.State)
+	}
+}
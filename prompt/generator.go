@@ -1,16 +1,49 @@
 package prompt
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
+
+	yaml "gopkg.in/yaml.v3"
 )
 
+// Example is a few-shot input/output pair. Output is a Go value of the target type,
+// rendered in the same format (YAML or JSON) as the requested structure.
+type Example struct {
+	Input  string // Source text; may be empty to show only the output
+	Output any
+}
+
+// Options configures GenerateStructuredPrompt
+type Options struct {
+	Examples []Example
+}
+
+// Option modifies Options
+type Option func(*Options)
+
+// WithExamples adds few-shot examples to the generated prompt
+func WithExamples(examples ...Example) Option {
+	return func(o *Options) {
+		o.Examples = append(o.Examples, examples...)
+	}
+}
+
 // GenerateStructuredPrompt creates an instruction prompt for parsing data into type T
 // It analyzes the struct fields, yaml tags, and description tags to build comprehensive instructions
-func GenerateStructuredPrompt[T any]() string {
-	var zero T
-	t := reflect.TypeOf(zero)
+func GenerateStructuredPrompt[T any](options ...Option) string {
+	return GenerateStructuredPromptFor(reflect.TypeFor[T](), options...)
+}
+
+// GenerateStructuredPromptFor is GenerateStructuredPrompt for a type known only
+// at run time, such as a struct type built with reflect.StructOf
+func GenerateStructuredPromptFor(t reflect.Type, options ...Option) string {
+	var opts Options
+	for _, option := range options {
+		option(&opts)
+	}
 
 	// Handle pointer types
 	if t.Kind() == reflect.Ptr {
@@ -28,26 +61,73 @@ func GenerateStructuredPrompt[T any]() string {
 	if hasYamlTags(t) {
 		builder.WriteString("Output the result in YAML format with the following structure:\n\n")
 		builder.WriteString("```yaml\n")
-		writeYamlStructure(t, &builder, 0)
+		writeYamlStructure(t, &builder, 0, make(map[reflect.Type]bool))
 		builder.WriteString("```\n\n")
 	} else {
 		builder.WriteString("Output the result in JSON format with the following structure:\n\n")
 		builder.WriteString("```json\n")
-		writeJsonStructure(t, &builder, 0)
+		writeJsonStructure(t, &builder, 0, make(map[reflect.Type]bool))
 		builder.WriteString("```\n\n")
 	}
 
 	// Add field descriptions
 	builder.WriteString("Field descriptions:\n")
-	writeFieldDescriptions(t, &builder, "")
+	writeFieldDescriptions(t, &builder, "", make(map[reflect.Type]bool))
+
+	if len(opts.Examples) > 0 {
+		writeExamples(opts.Examples, hasYamlTags(t), &builder)
+	}
 
 	builder.WriteString("\nEnsure all fields are properly filled based on the available data. If a field cannot be determined from the data, use appropriate default values or leave empty as applicable.")
 
+	hasRequired, hasOptional := requirementMarkers(t, make(map[reflect.Type]bool))
+	if hasRequired {
+		builder.WriteString(" Fields marked required must always be present with a non-empty value.")
+	}
+	if hasOptional {
+		builder.WriteString(" Fields marked optional may be omitted entirely when the data does not contain them; all other fields must always be present.")
+	}
+
 	return builder.String()
 }
 
+// writeExamples renders few-shot examples with outputs in the prompt's output format
+func writeExamples(examples []Example, useYaml bool, builder *strings.Builder) {
+	builder.WriteString("\nExamples:\n")
+	for i, example := range examples {
+		var output []byte
+		var err error
+		language := "yaml"
+		if useYaml {
+			output, err = yaml.Marshal(example.Output)
+		} else {
+			language = "json"
+			output, err = json.MarshalIndent(example.Output, "", "  ")
+		}
+		if err != nil {
+			// Examples are an aid; a value that cannot be rendered is left out
+			continue
+		}
+
+		builder.WriteString("\n")
+		if input := strings.TrimSpace(example.Input); input != "" {
+			builder.WriteString(fmt.Sprintf("Example %d input:\n```\n%s\n```\n", i+1, input))
+		}
+		builder.WriteString(fmt.Sprintf("Example %d output:\n```%s\n%s\n```\n", i+1, language, strings.TrimSpace(string(output))))
+	}
+}
+
 // hasYamlTags checks if the struct has yaml tags on any field
 func hasYamlTags(t reflect.Type) bool {
+	return yamlTagged(t, make(map[reflect.Type]bool))
+}
+
+// yamlTagged is hasYamlTags skipping the structs already visited
+func yamlTagged(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		if _, ok := field.Tag.Lookup("yaml"); ok {
@@ -55,12 +135,12 @@ func hasYamlTags(t reflect.Type) bool {
 		}
 		// Check nested structs
 		if field.Type.Kind() == reflect.Struct {
-			if hasYamlTags(field.Type) {
+			if yamlTagged(field.Type, visited) {
 				return true
 			}
 		}
 		if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
-			if hasYamlTags(field.Type.Elem()) {
+			if yamlTagged(field.Type.Elem(), visited) {
 				return true
 			}
 		}
@@ -68,9 +148,17 @@ func hasYamlTags(t reflect.Type) bool {
 	return false
 }
 
-// writeYamlStructure writes the YAML structure representation
-func writeYamlStructure(t reflect.Type, builder *strings.Builder, indent int) {
+// writeYamlStructure writes the YAML structure representation; structs in
+// stack are being written, so a struct containing itself refers to its
+// enclosing structure instead of repeating it forever
+func writeYamlStructure(t reflect.Type, builder *strings.Builder, indent int, stack map[reflect.Type]bool) {
 	indentStr := strings.Repeat("  ", indent)
+	if stack[t] {
+		builder.WriteString(fmt.Sprintf("%s# same structure as %s\n", indentStr, structName(t)))
+		return
+	}
+	stack[t] = true
+	defer delete(stack, t)
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
@@ -86,38 +174,100 @@ func writeYamlStructure(t reflect.Type, builder *strings.Builder, indent int) {
 		}
 
 		builder.WriteString(fmt.Sprintf("%s%s: ", indentStr, yamlTag))
+		writeYamlValue(&field, derefType(field.Type), builder, indent, stack)
+	}
+}
 
-		fieldType := field.Type
-		if fieldType.Kind() == reflect.Ptr {
-			fieldType = fieldType.Elem()
+// writeYamlValue writes the value of a YAML entry whose key is already written;
+// field is nil for map values and list items
+func writeYamlValue(field *reflect.StructField, fieldType reflect.Type, builder *strings.Builder, indent int, stack map[reflect.Type]bool) {
+	indentStr := strings.Repeat("  ", indent)
+
+	if _, ok := rendererFor(fieldType); ok {
+		builder.WriteString(yamlScalar(field, fieldType))
+		return
+	}
+
+	// Nested values have no placeholder to carry a comment, so requirement markers go after the key
+	marker := ""
+	if field != nil {
+		if requirement := fieldRequirement(*field); requirement != "" {
+			marker = "# " + requirement
 		}
+	}
 
-		switch fieldType.Kind() {
-		case reflect.Struct:
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		builder.WriteString(marker + "\n")
+		writeYamlStructure(fieldType, builder, indent+1, stack)
+	case reflect.Map:
+		builder.WriteString(fmt.Sprintf("%s\n%s  <key>: ", marker, indentStr))
+		writeYamlValue(nil, derefType(fieldType.Elem()), builder, indent+1, stack)
+	case reflect.Slice, reflect.Array:
+		elemType := derefType(fieldType.Elem())
+		if _, rendered := rendererFor(elemType); !rendered && elemType.Kind() == reflect.Struct {
+			builder.WriteString(marker + "\n")
+			builder.WriteString(fmt.Sprintf("%s  - ", indentStr))
 			builder.WriteString("\n")
-			writeYamlStructure(fieldType, builder, indent+1)
-		case reflect.Slice:
-			elemType := fieldType.Elem()
-			if elemType.Kind() == reflect.Ptr {
-				elemType = elemType.Elem()
-			}
-			if elemType.Kind() == reflect.Struct {
-				builder.WriteString("\n")
-				builder.WriteString(fmt.Sprintf("%s  - ", indentStr))
-				builder.WriteString("\n")
-				writeYamlStructure(elemType, builder, indent+2)
-			} else {
-				builder.WriteString(fmt.Sprintf("[] # array of %s\n", elemType.Kind()))
-			}
-		default:
-			builder.WriteString(fmt.Sprintf("\"\" # %s\n", fieldType.Kind()))
+			writeYamlStructure(elemType, builder, indent+2, stack)
+		} else if !rendered && elemType.Kind() == reflect.Map {
+			builder.WriteString(fmt.Sprintf("%s\n%s  - <key>: ", marker, indentStr))
+			writeYamlValue(nil, derefType(elemType.Elem()), builder, indent+2, stack)
+		} else if marker != "" {
+			builder.WriteString(fmt.Sprintf("[] # array of %s, %s\n", typeHint(elemType), strings.TrimPrefix(marker, "# ")))
+		} else {
+			builder.WriteString(fmt.Sprintf("[] # array of %s\n", typeHint(elemType)))
+		}
+	default:
+		builder.WriteString(yamlScalar(field, fieldType))
+	}
+}
+
+// yamlScalar renders a placeholder value with a comment describing its type,
+// allowed values and format
+func yamlScalar(field *reflect.StructField, fieldType reflect.Type) string {
+	placeholder := "\"\""
+	if renderer, ok := rendererFor(fieldType); ok && renderer.Placeholder != "" {
+		placeholder = renderer.Placeholder
+	}
+
+	comment := typeHint(fieldType)
+	if field != nil {
+		if enumValues := getEnumValues(*field); len(enumValues) > 0 {
+			comment += ", one of: " + strings.Join(enumValues, " | ")
+		}
+		if format := formatHint(*field); format != "" {
+			comment += ", " + format
+		}
+		if requirement := fieldRequirement(*field); requirement != "" {
+			comment += ", " + requirement
 		}
 	}
+	return fmt.Sprintf("%s # %s\n", placeholder, comment)
 }
 
-// writeJsonStructure writes the JSON structure representation
-func writeJsonStructure(t reflect.Type, builder *strings.Builder, indent int) {
+// formatHint describes the format tag of a field with an example value
+func formatHint(field reflect.StructField) string {
+	format := field.Tag.Get("format")
+	if format == "" {
+		return ""
+	}
+	if example, ok := formatExamples[format]; ok {
+		return fmt.Sprintf("format: %s (e.g. %s)", format, example)
+	}
+	return "format: " + format
+}
+
+// writeJsonStructure writes the JSON structure representation, see
+// writeYamlStructure for stack
+func writeJsonStructure(t reflect.Type, builder *strings.Builder, indent int, stack map[reflect.Type]bool) {
 	indentStr := strings.Repeat("  ", indent)
+	if stack[t] {
+		builder.WriteString(fmt.Sprintf("{ \"...\": \"same structure as %s\" }", structName(t)))
+		return
+	}
+	stack[t] = true
+	defer delete(stack, t)
 	builder.WriteString("{\n")
 
 	fieldCount := 0
@@ -139,48 +289,67 @@ func writeJsonStructure(t reflect.Type, builder *strings.Builder, indent int) {
 		}
 
 		builder.WriteString(fmt.Sprintf("%s  \"%s\": ", indentStr, jsonTag))
+		writeJsonValue(derefType(field.Type), builder, indent, stack)
 
-		fieldType := field.Type
-		if fieldType.Kind() == reflect.Ptr {
-			fieldType = fieldType.Elem()
-		}
+		fieldCount++
+	}
 
-		switch fieldType.Kind() {
-		case reflect.Struct:
-			writeJsonStructure(fieldType, builder, indent+1)
-		case reflect.Slice:
-			elemType := fieldType.Elem()
-			if elemType.Kind() == reflect.Ptr {
-				elemType = elemType.Elem()
-			}
-			if elemType.Kind() == reflect.Struct {
-				builder.WriteString("[\n")
-				builder.WriteString(fmt.Sprintf("%s    ", indentStr))
-				writeJsonStructure(elemType, builder, indent+2)
-				builder.WriteString(fmt.Sprintf("\n%s  ]", indentStr))
-			} else {
-				builder.WriteString("[]")
-			}
-		case reflect.String:
+	builder.WriteString(fmt.Sprintf("\n%s}", indentStr))
+}
+
+// writeJsonValue writes a placeholder JSON value for fieldType
+func writeJsonValue(fieldType reflect.Type, builder *strings.Builder, indent int, stack map[reflect.Type]bool) {
+	indentStr := strings.Repeat("  ", indent)
+
+	if renderer, ok := rendererFor(fieldType); ok {
+		if renderer.Placeholder != "" {
+			builder.WriteString(renderer.Placeholder)
+		} else {
 			builder.WriteString("\"\"")
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			builder.WriteString("0")
-		case reflect.Float32, reflect.Float64:
-			builder.WriteString("0.0")
-		case reflect.Bool:
-			builder.WriteString("false")
-		default:
-			builder.WriteString("null")
 		}
-
-		fieldCount++
+		return
 	}
 
-	builder.WriteString(fmt.Sprintf("\n%s}", indentStr))
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		writeJsonStructure(fieldType, builder, indent+1, stack)
+	case reflect.Map:
+		builder.WriteString(fmt.Sprintf("{\n%s    \"<key>\": ", indentStr))
+		writeJsonValue(derefType(fieldType.Elem()), builder, indent+2, stack)
+		builder.WriteString(fmt.Sprintf("\n%s  }", indentStr))
+	case reflect.Slice, reflect.Array:
+		elemType := derefType(fieldType.Elem())
+		if _, rendered := rendererFor(elemType); !rendered && elemType.Kind() == reflect.Struct {
+			builder.WriteString("[\n")
+			builder.WriteString(fmt.Sprintf("%s    ", indentStr))
+			writeJsonStructure(elemType, builder, indent+2, stack)
+			builder.WriteString(fmt.Sprintf("\n%s  ]", indentStr))
+		} else {
+			builder.WriteString("[]")
+		}
+	case reflect.String:
+		builder.WriteString("\"\"")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		builder.WriteString("0")
+	case reflect.Float32, reflect.Float64:
+		builder.WriteString("0.0")
+	case reflect.Bool:
+		builder.WriteString("false")
+	default:
+		builder.WriteString("null")
+	}
 }
 
-// writeFieldDescriptions writes detailed field descriptions
-func writeFieldDescriptions(t reflect.Type, builder *strings.Builder, prefix string) {
+// writeFieldDescriptions writes detailed field descriptions; the fields of a
+// struct containing itself are described once
+func writeFieldDescriptions(t reflect.Type, builder *strings.Builder, prefix string, stack map[reflect.Type]bool) {
+	if stack[t] {
+		return
+	}
+	stack[t] = true
+	defer delete(stack, t)
+
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 
@@ -199,32 +368,170 @@ func writeFieldDescriptions(t reflect.Type, builder *strings.Builder, prefix str
 			fullFieldName = prefix + "." + fieldName
 		}
 
+		fieldType := derefType(field.Type)
+		_, rendered := rendererFor(fieldType)
+
 		// Get description from tag
 		description := field.Tag.Get("description")
 		if description == "" {
-			description = fmt.Sprintf("Field of type %s", field.Type.String())
+			if rendered || fieldType.Kind() == reflect.Map {
+				description = "Field of type " + typeHint(fieldType)
+			} else {
+				description = fmt.Sprintf("Field of type %s", field.Type.String())
+			}
+		} else if rendered {
+			description += " (" + typeHint(fieldType) + ")"
+		}
+
+		if constraints := describeConstraints(field); constraints != "" {
+			description += " (" + constraints + ")"
+		}
+		if requirement := fieldRequirement(field); requirement != "" {
+			description += " [" + requirement + "]"
 		}
 
 		builder.WriteString(fmt.Sprintf("- %s: %s\n", fullFieldName, description))
 
-		// Handle nested structs
-		fieldType := field.Type
-		if fieldType.Kind() == reflect.Ptr {
-			fieldType = fieldType.Elem()
+		// Handle nested structs; types with a renderer are described as a single value
+		if rendered {
+			continue
 		}
+		writeNestedDescriptions(fieldType, builder, fullFieldName, stack)
+	}
+}
 
-		if fieldType.Kind() == reflect.Struct {
-			writeFieldDescriptions(fieldType, builder, fullFieldName)
-		} else if fieldType.Kind() == reflect.Slice {
-			elemType := fieldType.Elem()
-			if elemType.Kind() == reflect.Ptr {
-				elemType = elemType.Elem()
-			}
-			if elemType.Kind() == reflect.Struct {
-				writeFieldDescriptions(elemType, builder, fullFieldName+"[]")
+// writeNestedDescriptions describes the fields of structs nested in t, directly or
+// as list items and map values
+func writeNestedDescriptions(t reflect.Type, builder *strings.Builder, name string, stack map[reflect.Type]bool) {
+	if _, rendered := rendererFor(t); rendered {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		writeFieldDescriptions(t, builder, name, stack)
+	case reflect.Slice, reflect.Array:
+		writeNestedDescriptions(derefType(t.Elem()), builder, name+"[]", stack)
+	case reflect.Map:
+		writeNestedDescriptions(derefType(t.Elem()), builder, name+".<key>", stack)
+	}
+}
+
+// structName names a struct type in the prompt
+func structName(t reflect.Type) string {
+	if t.Name() == "" {
+		return "the enclosing object"
+	}
+	return t.Name()
+}
+
+// fieldRequirement returns "required" for fields tagged required:"true", "optional"
+// for pointers and omitempty fields, and "" for plain fields
+func fieldRequirement(field reflect.StructField) string {
+	if field.Tag.Get("required") == "true" {
+		return "required"
+	}
+	if field.Type.Kind() == reflect.Ptr {
+		return "optional"
+	}
+	for _, key := range []string{"yaml", "json"} {
+		parts := strings.Split(field.Tag.Get(key), ",")
+		for _, option := range parts[1:] {
+			if option == "omitempty" {
+				return "optional"
 			}
 		}
 	}
+	return ""
+}
+
+// requirementMarkers reports whether any field of t or its nested structs is marked
+// required or optional
+func requirementMarkers(t reflect.Type, visited map[reflect.Type]bool) (hasRequired bool, hasOptional bool) {
+	if visited[t] {
+		return false, false
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || getFieldDisplayName(field) == "-" {
+			continue
+		}
+
+		switch fieldRequirement(field) {
+		case "required":
+			hasRequired = true
+		case "optional":
+			hasOptional = true
+		}
+
+		nested := derefType(field.Type)
+		for nested.Kind() == reflect.Slice || nested.Kind() == reflect.Array || nested.Kind() == reflect.Map {
+			nested = derefType(nested.Elem())
+		}
+		if _, rendered := rendererFor(nested); !rendered && nested.Kind() == reflect.Struct {
+			required, optional := requirementMarkers(nested, visited)
+			hasRequired = hasRequired || required
+			hasOptional = hasOptional || optional
+		}
+	}
+	return hasRequired, hasOptional
+}
+
+// getEnumValues returns the trimmed values of the enum tag
+func getEnumValues(field reflect.StructField) []string {
+	enumTag := field.Tag.Get("enum")
+	if enumTag == "" {
+		return nil
+	}
+
+	values := strings.Split(enumTag, ",")
+	for i, value := range values {
+		values[i] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// describeConstraints summarizes the enum, min, max, format and pattern tags of a field
+func describeConstraints(field reflect.StructField) string {
+	var constraints []string
+
+	if enumValues := getEnumValues(field); len(enumValues) > 0 {
+		constraints = append(constraints, "allowed values: "+strings.Join(enumValues, ", "))
+	}
+
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	var unit string
+	switch fieldType.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array:
+		unit = " items"
+	}
+
+	minTag, maxTag := field.Tag.Get("min"), field.Tag.Get("max")
+	switch {
+	case minTag != "" && maxTag != "":
+		constraints = append(constraints, fmt.Sprintf("between %s and %s%s", minTag, maxTag, unit))
+	case minTag != "":
+		constraints = append(constraints, fmt.Sprintf("at least %s%s", minTag, unit))
+	case maxTag != "":
+		constraints = append(constraints, fmt.Sprintf("at most %s%s", maxTag, unit))
+	}
+
+	if format := field.Tag.Get("format"); format != "" {
+		constraints = append(constraints, "format: "+format)
+	}
+	if pattern := field.Tag.Get("pattern"); pattern != "" {
+		constraints = append(constraints, "must match: "+pattern)
+	}
+
+	return strings.Join(constraints, "; ")
 }
 
 // getYamlFieldName extracts the yaml field name from struct tag
@@ -1,8 +1,10 @@
 package prompt
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test struct with yaml and description tags
@@ -164,3 +166,209 @@ func TestHasYamlTags(t *testing.T) {
 		})
 	}
 }
+
+type Ticket struct {
+	Priority string   `yaml:"priority" description:"Ticket priority" enum:"low, medium, high"`
+	Score    int      `yaml:"score" description:"Severity score" min:"1" max:"10"`
+	Title    string   `yaml:"title" description:"Short title" max:"80"`
+	Reporter string   `yaml:"reporter" description:"Reporter email" format:"email"`
+	Labels   []string `yaml:"labels" description:"Labels" min:"1"`
+}
+
+func TestGenerateStructuredPrompt_Constraints(t *testing.T) {
+	result := GenerateStructuredPrompt[Ticket]()
+
+	expected := []string{
+		"priority: \"\" # string, one of: low | medium | high",
+		"- priority: Ticket priority (allowed values: low, medium, high)",
+		"- score: Severity score (between 1 and 10)",
+		"- title: Short title (at most 80 characters)",
+		"- reporter: Reporter email (format: email)",
+		"- labels: Labels (at least 1 items)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected prompt to contain '%s'.\nFull prompt:\n%s", want, result)
+		}
+	}
+}
+
+func TestGenerateStructuredPrompt_Examples(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   string
+		contains []string
+	}{
+		{
+			name: "YAML examples",
+			result: GenerateStructuredPrompt[Ticket](WithExamples(Example{
+				Input:  "Checkout is down for everyone",
+				Output: Ticket{Priority: "high", Score: 9, Title: "Checkout outage"},
+			})),
+			contains: []string{
+				"Example 1 input:\n```\nCheckout is down for everyone\n```",
+				"Example 1 output:\n```yaml\npriority: high\nscore: 9\ntitle: Checkout outage",
+			},
+		},
+		{
+			name:   "JSON examples without input",
+			result: GenerateStructuredPrompt[SimpleStruct](WithExamples(Example{Output: SimpleStruct{ID: 7, Name: "seven"}})),
+			contains: []string{
+				"Example 1 output:\n```json\n{\n  \"id\": 7,\n  \"name\": \"seven\"\n}\n```",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range tt.contains {
+				if !strings.Contains(tt.result, want) {
+					t.Errorf("Expected prompt to contain %q.\nFull prompt:\n%s", want, tt.result)
+				}
+			}
+		})
+	}
+
+	if strings.Contains(GenerateStructuredPrompt[Ticket](), "Examples:") {
+		t.Error("Expected no examples section without WithExamples")
+	}
+}
+
+type Money struct {
+	Cents int64
+}
+
+type Event struct {
+	Name     string             `yaml:"name" description:"Event name"`
+	StartsAt time.Time          `yaml:"starts_at" description:"Start time"`
+	Day      string             `yaml:"day" format:"date"`
+	Length   time.Duration      `yaml:"length"`
+	Tags     map[string]string  `yaml:"tags" description:"Free-form tags"`
+	Venues   map[string]Address `yaml:"venues" description:"Venues by room"`
+	Price    Money              `yaml:"price" description:"Ticket price"`
+	Dates    []time.Time        `yaml:"dates"`
+}
+
+type JSONEvent struct {
+	StartsAt time.Time         `json:"starts_at"`
+	Tags     map[string]string `json:"tags"`
+	Price    Money             `json:"price"`
+}
+
+func TestGenerateStructuredPrompt_TypeRenderers(t *testing.T) {
+	RegisterTypeRenderer[Money](TypeRenderer{Placeholder: "\"0.00 USD\"", Hint: "amount with currency code"})
+	defer func() {
+		renderersMu.Lock()
+		delete(renderers, reflect.TypeOf(Money{}))
+		renderersMu.Unlock()
+	}()
+
+	tests := []struct {
+		name     string
+		result   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:   "YAML",
+			result: GenerateStructuredPrompt[Event](),
+			contains: []string{
+				"starts_at: \"\" # date-time, RFC 3339 (e.g. 2024-01-31T09:00:00Z)\n",
+				"day: \"\" # string, format: date (e.g. 2024-01-31)\n",
+				"length: \"\" # duration (e.g. 1h30m)\n",
+				"tags: \n  <key>: \"\" # string\n",
+				"venues: \n  <key>: \n    street: \"\" # string\n",
+				"price: \"0.00 USD\" # amount with currency code\n",
+				"dates: [] # array of date-time",
+				"- starts_at: Start time (date-time, RFC 3339 (e.g. 2024-01-31T09:00:00Z))",
+				"- tags: Free-form tags",
+				"- venues.<key>.city: City name",
+				"- length: Field of type duration (e.g. 1h30m)",
+			},
+			excludes: []string{"wall", "price.Cents"},
+		},
+		{
+			name:   "JSON",
+			result: GenerateStructuredPrompt[JSONEvent](),
+			contains: []string{
+				"\"starts_at\": \"\"",
+				"\"tags\": {\n    \"<key>\": \"\"\n  }",
+				"\"price\": \"0.00 USD\"",
+				"- tags: Field of type map of string",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range tt.contains {
+				if !strings.Contains(tt.result, want) {
+					t.Errorf("Expected prompt to contain %q.\nFull prompt:\n%s", want, tt.result)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(tt.result, unwanted) {
+					t.Errorf("Expected prompt not to contain %q.\nFull prompt:\n%s", unwanted, tt.result)
+				}
+			}
+		})
+	}
+}
+
+type Contact struct {
+	Name    string   `yaml:"name" description:"Full name" required:"true"`
+	Email   string   `yaml:"email,omitempty" description:"Email address"`
+	Phone   *string  `yaml:"phone" description:"Phone number"`
+	Company *Address `yaml:"company"`
+	Notes   []string `yaml:"notes,omitempty"`
+	Title   string   `yaml:"title" description:"Job title"`
+}
+
+func TestGenerateStructuredPrompt_RequiredOptional(t *testing.T) {
+	result := GenerateStructuredPrompt[Contact]()
+
+	expected := []string{
+		"name: \"\" # string, required\n",
+		"email: \"\" # string, optional\n",
+		"phone: \"\" # string, optional\n",
+		"company: # optional\n  street:",
+		"notes: [] # array of string, optional\n",
+		"title: \"\" # string\n",
+		"- name: Full name [required]",
+		"- email: Email address [optional]",
+		"- phone: Phone number [optional]",
+		"- title: Job title\n",
+		"Fields marked required must always be present",
+		"Fields marked optional may be omitted",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected prompt to contain %q.\nFull prompt:\n%s", want, result)
+		}
+	}
+
+	if plain := GenerateStructuredPrompt[Person](); strings.Contains(plain, "Fields marked") {
+		t.Errorf("Expected no requirement instructions without markers.\nFull prompt:\n%s", plain)
+	}
+}
+
+type promptTree struct {
+	Name     string        `yaml:"name" description:"Name of the node"`
+	Children []*promptTree `yaml:"children"`
+}
+
+type promptJSONTree struct {
+	Name  string          `json:"name"`
+	Child *promptJSONTree `json:"child"`
+}
+
+func TestGenerateStructuredPrompt_Recursive(t *testing.T) {
+	yamlPrompt := GenerateStructuredPrompt[promptTree]()
+	if !strings.Contains(yamlPrompt, "# same structure as promptTree") || strings.Count(yamlPrompt, "- name: Name of the node") != 1 {
+		t.Errorf("prompt of a recursive type:\n%s", yamlPrompt)
+	}
+	jsonPrompt := GenerateStructuredPrompt[promptJSONTree]()
+	if !strings.Contains(jsonPrompt, `"child": { "...": "same structure as promptJSONTree" }`) {
+		t.Errorf("prompt of a recursive type:\n%s", jsonPrompt)
+	}
+}
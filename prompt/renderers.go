@@ -0,0 +1,80 @@
+package prompt
+
+import (
+	"encoding"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TypeRenderer describes how a type appears in generated prompts. Types with a
+// renderer are shown as a single value instead of being expanded field by field.
+type TypeRenderer struct {
+	Placeholder string // Value written in the YAML/JSON structure; defaults to an empty string
+	Hint        string // Short type description used in comments and field descriptions
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[reflect.Type]TypeRenderer{
+		reflect.TypeOf(time.Time{}):      {Hint: "date-time, RFC 3339 (e.g. 2024-01-31T09:00:00Z)"},
+		reflect.TypeOf(time.Duration(0)): {Hint: "duration (e.g. 1h30m)"},
+	}
+)
+
+// RegisterTypeRenderer sets how T is rendered in generated prompts, replacing any
+// previous renderer for T
+func RegisterTypeRenderer[T any](renderer TypeRenderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	renderers[reflect.TypeOf((*T)(nil)).Elem()] = renderer
+}
+
+// formatExamples are sample values shown for the format struct tag
+var formatExamples = map[string]string{
+	"date":      "2024-01-31",
+	"date-time": "2024-01-31T09:00:00Z",
+	"email":     "name@example.com",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"uuid":      "123e4567-e89b-12d3-a456-426614174000",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// rendererFor returns the renderer for t. Types implementing encoding.TextUnmarshaler
+// without a registered renderer are rendered as strings.
+func rendererFor(t reflect.Type) (TypeRenderer, bool) {
+	renderersMu.RLock()
+	renderer, ok := renderers[t]
+	renderersMu.RUnlock()
+	if ok {
+		return renderer, true
+	}
+
+	if t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return TypeRenderer{Hint: "string"}, true
+	}
+	return TypeRenderer{}, false
+}
+
+// typeHint returns the hint for t: its renderer hint or its kind
+func typeHint(t reflect.Type) string {
+	if renderer, ok := rendererFor(t); ok && renderer.Hint != "" {
+		return renderer.Hint
+	}
+	if t.Kind() == reflect.Map {
+		return "map of " + typeHint(derefType(t.Elem()))
+	}
+	return t.Kind().String()
+}
+
+// derefType removes one level of pointer indirection
+func derefType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,250 @@
+package prompt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TemplateExtension is the file extension of prompt templates loaded by a Store
+const TemplateExtension = ".tmpl"
+
+// Store holds named, versioned prompt templates. Templates are loaded from files
+// named <name>.tmpl or <name>@<version>.tmpl; files starting with "_" are partials
+// available to every template. Loading a later source (for example an operator's
+// directory after the embedded defaults) overrides entries with the same name and
+// version, so prompts can be changed per deployment without recompiling.
+type Store struct {
+	mu       sync.RWMutex
+	sources  map[string]map[string]string // name -> version -> template text
+	partials map[string]string
+	pinned   map[string]string
+	compiled map[string]*Template
+}
+
+// NewStore creates an empty prompt store
+func NewStore() *Store {
+	return &Store{
+		sources:  make(map[string]map[string]string),
+		partials: make(map[string]string),
+		pinned:   make(map[string]string),
+		compiled: make(map[string]*Template),
+	}
+}
+
+// Add registers template text under a name and version; an empty version is the
+// unversioned default
+func (s *Store) Add(name, version, text string) error {
+	if name == "" {
+		return fmt.Errorf("prompt name cannot be empty")
+	}
+	// Parse eagerly so syntax errors surface when loading rather than at render time
+	if _, err := NewTemplate(name, text); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sources[name] == nil {
+		s.sources[name] = make(map[string]string)
+	}
+	s.sources[name][version] = text
+	s.compiled = make(map[string]*Template)
+	return nil
+}
+
+// AddPartial registers a partial included with {{template "name" .}} from any template
+func (s *Store) AddPartial(name, text string) error {
+	if name == "" {
+		return fmt.Errorf("partial name cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partials[name] = text
+	s.compiled = make(map[string]*Template)
+	return nil
+}
+
+// LoadFS loads every template in dir of fsys, typically an embed.FS with defaults
+func (s *Store) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), TemplateExtension) {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read prompt %s: %w", entry.Name(), err)
+		}
+
+		base := strings.TrimSuffix(entry.Name(), TemplateExtension)
+		if strings.HasPrefix(base, "_") {
+			err = s.AddPartial(strings.TrimPrefix(base, "_"), string(data))
+		} else {
+			name, version, _ := strings.Cut(base, "@")
+			err = s.Add(name, version, string(data))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load prompt %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// LoadDir loads every template in a directory on disk
+func (s *Store) LoadDir(dir string) error {
+	return s.LoadFS(os.DirFS(dir), ".")
+}
+
+// Use pins the version returned by Get for name; an empty version unpins it
+func (s *Store) Use(name, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == "" {
+		delete(s.pinned, name)
+		return nil
+	}
+	if _, ok := s.sources[name][version]; !ok {
+		return fmt.Errorf("prompt %s has no version %s", name, version)
+	}
+	s.pinned[name] = version
+	return nil
+}
+
+// Get returns the pinned version of a prompt, or its latest version
+func (s *Store) Get(name string) (*Template, error) {
+	s.mu.RLock()
+	version, ok := s.pinned[name]
+	if !ok {
+		versions := s.versionsLocked(name)
+		if len(versions) > 0 {
+			version = versions[len(versions)-1]
+		}
+	}
+	s.mu.RUnlock()
+
+	return s.GetVersion(name, version)
+}
+
+// GetVersion returns a specific version of a prompt
+func (s *Store) GetVersion(name, version string) (*Template, error) {
+	key := name + "@" + version
+
+	s.mu.RLock()
+	if tmpl, ok := s.compiled[key]; ok {
+		s.mu.RUnlock()
+		return tmpl, nil
+	}
+	text, ok := s.sources[name][version]
+	partials := make(map[string]string, len(s.partials))
+	for partialName, partialText := range s.partials {
+		partials[partialName] = partialText
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		if version == "" {
+			return nil, fmt.Errorf("prompt %s not found", name)
+		}
+		return nil, fmt.Errorf("prompt %s version %s not found", name, version)
+	}
+
+	tmpl, err := NewTemplate(name, text)
+	if err != nil {
+		return nil, err
+	}
+	for partialName, partialText := range partials {
+		if partialName == name {
+			continue
+		}
+		if err := tmpl.AddPartial(partialName, partialText); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.compiled[key] = tmpl
+	s.mu.Unlock()
+	return tmpl, nil
+}
+
+// Render renders the current version of a prompt
+func (s *Store) Render(name string, vars Vars) (string, error) {
+	tmpl, err := s.Get(name)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(vars)
+}
+
+// Names returns the sorted names of all prompts
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns the versions of a prompt from oldest to newest
+func (s *Store) Versions(name string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versionsLocked(name)
+}
+
+// versionsLocked sorts the versions of a prompt; the caller must hold the lock
+func (s *Store) versionsLocked(name string) []string {
+	versions := make([]string, 0, len(s.sources[name]))
+	for version := range s.sources[name] {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// compareVersions orders versions like "v1" < "v1.2" < "v10", with the unversioned
+// default first; non-numeric parts compare as strings
+func compareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+	if b == "" {
+		return 1
+	}
+
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numberA, errA := strconv.Atoi(partsA[i])
+		numberB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil && numberA != numberB:
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && partsA[i] != partsB[i]:
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+	return len(partsA) - len(partsB)
+}
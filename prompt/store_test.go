@@ -0,0 +1,72 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStore(t *testing.T) {
+	defaults := fstest.MapFS{
+		"prompts/planning.tmpl":     {Data: []byte("plan v0 {{template \"footer\" .}}")},
+		"prompts/planning@v2.tmpl":  {Data: []byte("plan v2 for {{.Goal}} {{template \"footer\" .}}")},
+		"prompts/planning@v10.tmpl": {Data: []byte("plan v10 {{template \"footer\" .}}")},
+		"prompts/summarizer.tmpl":   {Data: []byte("summarize {{.Text}}")},
+		"prompts/_footer.tmpl":      {Data: []byte("(default footer)")},
+		"prompts/README.md":         {Data: []byte("ignored")},
+	}
+
+	store := NewStore()
+	if err := store.LoadFS(defaults, "prompts"); err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+
+	if names := store.Names(); !reflect.DeepEqual(names, []string{"planning", "summarizer"}) {
+		t.Errorf("Names() = %v", names)
+	}
+	if versions := store.Versions("planning"); !reflect.DeepEqual(versions, []string{"", "v2", "v10"}) {
+		t.Errorf("Versions() = %v", versions)
+	}
+
+	text, err := store.Render("planning", nil)
+	if err != nil || text != "plan v10 (default footer)" {
+		t.Errorf("Render(latest) = %q, %v", text, err)
+	}
+
+	if err := store.Use("planning", "v2"); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if _, err := store.Render("planning", nil); err == nil || !strings.Contains(err.Error(), "Goal") {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+	if err := store.Use("planning", "v3"); err == nil {
+		t.Error("expected error pinning an unknown version")
+	}
+
+	// Operator overrides replace the same name and version, including partials
+	overrides := t.TempDir()
+	os.WriteFile(filepath.Join(overrides, "summarizer.tmpl"), []byte("Summarize briefly: {{.Text}}"), 0o644)
+	os.WriteFile(filepath.Join(overrides, "_footer.tmpl"), []byte("(custom footer)"), 0o644)
+	if err := store.LoadDir(overrides); err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+
+	text, _ = store.Render("summarizer", Vars{"Text": "notes"})
+	if text != "Summarize briefly: notes" {
+		t.Errorf("override not applied: %q", text)
+	}
+	text, _ = store.Render("planning", Vars{"Goal": "launch"})
+	if text != "plan v2 for launch (custom footer)" {
+		t.Errorf("partial override not applied: %q", text)
+	}
+
+	if _, err := store.Get("missing"); err == nil {
+		t.Error("expected error for unknown prompt")
+	}
+	if err := store.Add("broken", "", "{{if}}"); err == nil {
+		t.Error("expected parse error")
+	}
+}
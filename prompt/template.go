@@ -0,0 +1,229 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Vars holds the named variables used to render a Template
+type Vars map[string]any
+
+// Template is a prompt template based on text/template. Variables are referenced as
+// {{.Name}}, partials are included with {{template "name" .}}, and rendering fails
+// when a required variable is missing. A variable used as the condition of an
+// if/with block is optional and may be omitted.
+type Template struct {
+	name string
+	tmpl *template.Template
+}
+
+// templateFuncs are the helper functions available inside prompt templates
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"indent": func(spaces int, text string) string {
+		padding := strings.Repeat(" ", spaces)
+		return padding + strings.ReplaceAll(text, "\n", "\n"+padding)
+	},
+	"notNil": func(value any) bool {
+		return value != nil
+	},
+}
+
+// NewTemplate parses a prompt template
+func NewTemplate(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+	}
+	return &Template{name: name, tmpl: tmpl}, nil
+}
+
+// MustTemplate is NewTemplate that panics on error, for package-level templates
+func MustTemplate(name, text string) *Template {
+	t, err := NewTemplate(name, text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the template name
+func (t *Template) Name() string {
+	return t.name
+}
+
+// AddPartial parses a named partial that the template can include with {{template "name" .}}
+func (t *Template) AddPartial(name, text string) error {
+	if name == t.name {
+		return fmt.Errorf("partial %s has the same name as its template", name)
+	}
+	if _, err := t.tmpl.New(name).Parse(text); err != nil {
+		return fmt.Errorf("failed to parse partial %s: %w", name, err)
+	}
+	return nil
+}
+
+// Variables returns the sorted names of the required and optional top-level variables
+func (t *Template) Variables() (required []string, optional []string) {
+	collector := &variableCollector{
+		tmpl:     t.tmpl,
+		required: make(map[string]bool),
+		optional: make(map[string]bool),
+		visiting: make(map[string]bool),
+	}
+	collector.walkTemplate(t.name)
+
+	for name := range collector.required {
+		if !collector.optional[name] {
+			required = append(required, name)
+		}
+	}
+	for name := range collector.optional {
+		optional = append(optional, name)
+	}
+	sort.Strings(required)
+	sort.Strings(optional)
+	return required, optional
+}
+
+// Render executes the template with vars after checking that every required variable is set
+func (t *Template) Render(vars Vars) (string, error) {
+	required, _ := t.Variables()
+
+	var missing []string
+	for _, name := range required {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt template %s is missing variables: %s", t.name, strings.Join(missing, ", "))
+	}
+
+	if vars == nil {
+		vars = Vars{}
+	}
+
+	var builder strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&builder, t.name, map[string]any(vars)); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", t.name, err)
+	}
+	return builder.String(), nil
+}
+
+// MustRender is Render that panics on error
+func (t *Template) MustRender(vars Vars) string {
+	text, err := t.Render(vars)
+	if err != nil {
+		panic(err)
+	}
+	return text
+}
+
+// variableCollector walks template parse trees collecting references to top-level variables
+type variableCollector struct {
+	tmpl     *template.Template
+	required map[string]bool
+	optional map[string]bool
+	visiting map[string]bool
+}
+
+// walkTemplate walks a named template or partial, guarding against recursive includes
+func (c *variableCollector) walkTemplate(name string) {
+	if c.visiting[name] {
+		return
+	}
+	c.visiting[name] = true
+	defer delete(c.visiting, name)
+
+	if tmpl := c.tmpl.Lookup(name); tmpl != nil && tmpl.Tree != nil {
+		c.walk(tmpl.Tree.Root, true)
+	}
+}
+
+// walk visits a node; atRoot reports whether dot is still the top-level variables
+func (c *variableCollector) walk(node parse.Node, atRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, atRoot)
+		}
+	case *parse.ActionNode:
+		c.walkPipe(n.Pipe, atRoot, c.required)
+	case *parse.IfNode:
+		c.walkPipe(n.Pipe, atRoot, c.optional)
+		c.walk(n.List, atRoot)
+		c.walk(n.ElseList, atRoot)
+	case *parse.WithNode:
+		c.walkPipe(n.Pipe, atRoot, c.optional)
+		// Dot is rebound inside the block but not in the else branch
+		c.walk(n.List, false)
+		c.walk(n.ElseList, atRoot)
+	case *parse.RangeNode:
+		c.walkPipe(n.Pipe, atRoot, c.required)
+		c.walk(n.List, false)
+		c.walk(n.ElseList, atRoot)
+	case *parse.TemplateNode:
+		c.walkPipe(n.Pipe, atRoot, c.required)
+		// Partials only see the top-level variables when invoked with {{template "x" .}}
+		if atRoot && n.Pipe != nil && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); ok {
+				c.walkTemplate(n.Name)
+				return
+			}
+		}
+		c.walkPartialGlobals(n.Name)
+	}
+}
+
+// walkPartialGlobals collects $.Name references from a partial invoked with another dot
+func (c *variableCollector) walkPartialGlobals(name string) {
+	if c.visiting[name] {
+		return
+	}
+	c.visiting[name] = true
+	defer delete(c.visiting, name)
+
+	if tmpl := c.tmpl.Lookup(name); tmpl != nil && tmpl.Tree != nil {
+		c.walk(tmpl.Tree.Root, false)
+	}
+}
+
+// walkPipe records the variables referenced by a pipeline into target
+func (c *variableCollector) walkPipe(pipe *parse.PipeNode, atRoot bool, target map[string]bool) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			c.walkArg(arg, atRoot, target)
+		}
+	}
+}
+
+// walkArg records a field reference such as .Name, .Name.Field or $.Name
+func (c *variableCollector) walkArg(arg parse.Node, atRoot bool, target map[string]bool) {
+	switch n := arg.(type) {
+	case *parse.FieldNode:
+		if atRoot && len(n.Ident) > 0 {
+			target[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			target[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		c.walkArg(n.Node, atRoot, target)
+	case *parse.PipeNode:
+		c.walkPipe(n, atRoot, target)
+	}
+}
@@ -0,0 +1,77 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tmpl, err := NewTemplate("system", `{{.Role}} assistant.
+{{if .Summary}}Summary: {{.Summary}}
+{{end}}{{range .Tools}}- {{.Name}}{{if $.Verbose}}: {{.Description}}{{end}}
+{{end}}{{template "footer" .}}`)
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+	if err := tmpl.AddPartial("footer", "Answer in {{upper .Format}}."); err != nil {
+		t.Fatalf("AddPartial() error = %v", err)
+	}
+
+	required, optional := tmpl.Variables()
+	if want := []string{"Format", "Role", "Tools"}; !reflect.DeepEqual(required, want) {
+		t.Errorf("required = %v, want %v", required, want)
+	}
+	if want := []string{"Summary", "Verbose"}; !reflect.DeepEqual(optional, want) {
+		t.Errorf("optional = %v, want %v", optional, want)
+	}
+
+	tools := []struct{ Name, Description string }{{"search", "Search the web"}, {"calc", "Evaluate math"}}
+
+	tests := []struct {
+		name    string
+		vars    Vars
+		want    string
+		wantErr string
+	}{
+		{
+			name: "optional variables omitted",
+			vars: Vars{"Role": "Helpful", "Tools": tools, "Format": "yaml"},
+			want: "Helpful assistant.\n- search\n- calc\nAnswer in YAML.",
+		},
+		{
+			name: "conditionals and loops",
+			vars: Vars{"Role": "Helpful", "Tools": tools, "Format": "yaml", "Summary": "earlier chat", "Verbose": true},
+			want: "Helpful assistant.\nSummary: earlier chat\n- search: Search the web\n- calc: Evaluate math\nAnswer in YAML.",
+		},
+		{
+			name:    "missing required variables",
+			vars:    Vars{"Tools": tools},
+			wantErr: "missing variables: Format, Role",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tmpl.Render(tt.vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTemplate_InvalidSyntax(t *testing.T) {
+	if _, err := NewTemplate("broken", "{{if .A}}unterminated"); err == nil {
+		t.Error("expected parse error")
+	}
+}
@@ -0,0 +1,164 @@
+package prompt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultContextWindow is assumed for models without a known context window
+const DefaultContextWindow = 8192
+
+// tokenizerRatios are average characters per token for ASCII text by model prefix
+var tokenizerRatios = map[string]float64{
+	"gpt-4o":  4.2,
+	"gpt-4.1": 4.2,
+	"o1":      4.2,
+	"o3":      4.2,
+	"o4":      4.2,
+	"gpt-4":   4.0,
+	"gpt-3.5": 4.0,
+	"claude":  3.5,
+	"gemini":  4.0,
+	"llama":   3.8,
+	"mistral": 3.6,
+	"qwen":    3.8,
+}
+
+var (
+	contextWindowsMu sync.RWMutex
+	contextWindows   = map[string]int{
+		"gpt-4o":        128000,
+		"gpt-4.1":       1047576,
+		"gpt-4-turbo":   128000,
+		"gpt-4-32k":     32768,
+		"gpt-4":         8192,
+		"gpt-3.5-turbo": 16385,
+		"o1":            200000,
+		"o3":            200000,
+		"o4":            200000,
+		"claude":        200000,
+		"gemini-1.5":    1048576,
+		"gemini-2":      1048576,
+		"gemini":        32768,
+		"llama3":        8192,
+		"llama-3.1":     131072,
+		"mistral":       32768,
+		"qwen":          32768,
+	}
+)
+
+// RegisterContextWindow sets the context window for models whose name starts with
+// prefix, e.g. a local model served through Ollama
+func RegisterContextWindow(prefix string, tokens int) {
+	contextWindowsMu.Lock()
+	defer contextWindowsMu.Unlock()
+	contextWindows[strings.ToLower(prefix)] = tokens
+}
+
+// ContextWindow returns the context window of a model in tokens, matching the
+// longest known prefix; unknown models get DefaultContextWindow
+func ContextWindow(model string) int {
+	contextWindowsMu.RLock()
+	defer contextWindowsMu.RUnlock()
+	if tokens, ok := longestPrefixMatch(contextWindows, model); ok {
+		return tokens
+	}
+	return DefaultContextWindow
+}
+
+// EstimateTokens approximates the number of tokens text uses with the given model.
+// It is a character-based heuristic, typically within 10-15% of the real tokenizer
+// for English prose and code; non-Latin scripts are counted as one token per character.
+func EstimateTokens(text, model string) int {
+	if text == "" {
+		return 0
+	}
+
+	ratio, ok := longestPrefixMatch(tokenizerRatios, model)
+	if !ok {
+		ratio = 4.0
+	}
+
+	var asciiChars, otherTokens, words float64
+	inWord := false
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			asciiChars++
+		case unicode.IsLetter(r) && !unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic):
+			// CJK and similar scripts use roughly one token per character
+			otherTokens++
+		default:
+			otherTokens += 0.5
+		}
+
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			words++
+		}
+	}
+
+	// Short words and punctuation-heavy text tokenize worse than the character ratio suggests
+	estimate := math.Max(asciiChars/ratio, words*0.75) + otherTokens
+	return int(math.Ceil(estimate))
+}
+
+// BudgetCheck is the result of comparing a prompt with a share of a model's context window
+type BudgetCheck struct {
+	Model  string
+	Tokens int // Estimated prompt tokens
+	Limit  int // Token budget for the prompt
+}
+
+// Exceeded reports whether the prompt is over its budget
+func (b BudgetCheck) Exceeded() bool {
+	return b.Tokens > b.Limit
+}
+
+// String describes the check for logging
+func (b BudgetCheck) String() string {
+	return fmt.Sprintf("prompt uses ~%d tokens of its %d token budget for %s", b.Tokens, b.Limit, b.Model)
+}
+
+// CheckPromptBudget estimates the tokens of text and compares them with fraction
+// of the model's context window, e.g. 0.25 to keep system prompts under a quarter
+// of the window so history and responses still fit
+func CheckPromptBudget(text, model string, fraction float64) BudgetCheck {
+	if fraction <= 0 || fraction > 1 {
+		fraction = 1
+	}
+	return BudgetCheck{
+		Model:  model,
+		Tokens: EstimateTokens(text, model),
+		Limit:  int(float64(ContextWindow(model)) * fraction),
+	}
+}
+
+// longestPrefixMatch returns the value of the longest key that prefixes model
+func longestPrefixMatch[V any](values map[string]V, model string) (V, bool) {
+	model = strings.ToLower(model)
+	// Provider-qualified names such as "openai/gpt-4o" or "models/gemini-2.0-flash"
+	if index := strings.LastIndex(model, "/"); index != -1 {
+		model = model[index+1:]
+	}
+
+	prefixes := make([]string, 0, len(values))
+	for prefix := range values {
+		if strings.HasPrefix(model, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		var zero V
+		return zero, false
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return values[prefixes[0]], true
+}
@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		model    string
+		min, max int
+	}{
+		{name: "empty", text: "", model: "gpt-4o", min: 0, max: 0},
+		{name: "english prose", text: "The quick brown fox jumps over the lazy dog.", model: "gpt-4o", min: 9, max: 12},
+		{name: "longer text", text: strings.Repeat("Hello world, this is a test sentence. ", 100), model: "gpt-4", min: 800, max: 1000},
+		{name: "cjk", text: "今日は良い天気です", model: "gpt-4o", min: 8, max: 10},
+		{name: "unknown model", text: "some text here", model: "my-local-model", min: 3, max: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateTokens(tt.text, tt.model)
+			if got < tt.min || got > tt.max {
+				t.Errorf("EstimateTokens() = %d, want between %d and %d", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"gpt-4-turbo-preview", 128000},
+		{"models/gemini-2.0-flash", 1048576},
+		{"unknown", DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := ContextWindow(tt.model); got != tt.want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+
+	RegisterContextWindow("tiny-local", 2048)
+	defer func() {
+		contextWindowsMu.Lock()
+		delete(contextWindows, "tiny-local")
+		contextWindowsMu.Unlock()
+	}()
+	if got := ContextWindow("tiny-local:7b"); got != 2048 {
+		t.Errorf("ContextWindow() for registered model = %d", got)
+	}
+}
+
+func TestCheckPromptBudget(t *testing.T) {
+	check := CheckPromptBudget(strings.Repeat("word ", 3000), "gpt-4", 0.25)
+	if !check.Exceeded() || check.Limit != 2048 {
+		t.Errorf("expected exceeded budget of 2048, got %s", check)
+	}
+	if CheckPromptBudget("short prompt", "gpt-4o", 0.25).Exceeded() {
+		t.Error("expected short prompt to fit")
+	}
+}
@@ -0,0 +1,117 @@
+package retrieval
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// SplitText splits text into chunks of at most size bytes, preferring paragraph
+// and then word boundaries. Each chunk after the first starts with up to overlap
+// bytes of the previous chunk's trailing words, so facts spanning a boundary are
+// still retrievable.
+func SplitText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if size <= 0 || len(text) <= size {
+		return []string{text}
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	s := &splitter{size: size, overlap: overlap}
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			continue
+		}
+		// Move a paragraph that fits in one chunk to the next chunk rather than splitting it
+		length := len(strings.Join(words, " "))
+		if s.current.Len() > 0 && s.current.Len()+2+length > size && length <= size {
+			s.flush(length)
+		}
+
+		separator := "\n\n"
+		for _, word := range words {
+			for _, piece := range cutWord(word, size) {
+				s.add(piece, separator)
+				separator = " "
+			}
+		}
+	}
+	if s.current.Len() > 0 {
+		s.chunks = append(s.chunks, s.current.String())
+	}
+	return s.chunks
+}
+
+// splitter accumulates words into chunks
+type splitter struct {
+	size    int
+	overlap int
+	current strings.Builder
+	chunks  []string
+}
+
+// add appends a word, starting a new chunk when it does not fit
+func (s *splitter) add(word, separator string) {
+	if s.current.Len() > 0 && s.current.Len()+len(separator)+len(word) > s.size {
+		s.flush(len(word))
+	}
+	if s.current.Len() > 0 {
+		s.current.WriteString(separator)
+	}
+	s.current.WriteString(word)
+}
+
+// flush closes the current chunk and seeds the next one with the overlap, provided
+// next bytes of text still fit after it
+func (s *splitter) flush(next int) {
+	s.chunks = append(s.chunks, s.current.String())
+	s.current.Reset()
+	if tail := trailingWords(s.chunks[len(s.chunks)-1], s.overlap); tail != "" && len(tail)+2+next <= s.size {
+		s.current.WriteString(tail)
+	}
+}
+
+// cutWord splits a word longer than size at rune boundaries
+func cutWord(word string, size int) []string {
+	var pieces []string
+	for len(word) > size {
+		cut := size
+		for cut > 1 && !utf8.RuneStart(word[cut]) {
+			cut--
+		}
+		pieces = append(pieces, word[:cut])
+		word = word[cut:]
+	}
+	return append(pieces, word)
+}
+
+// trailingWords returns the longest run of whole trailing words of text that fits
+// in limit bytes
+func trailingWords(text string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	words := strings.Fields(text)
+	length := 0
+	start := len(words)
+	for start > 0 {
+		next := length + len(words[start-1])
+		if length > 0 {
+			next++
+		}
+		if next > limit {
+			break
+		}
+		length = next
+		start--
+	}
+	return strings.Join(words[start:], " ")
+}
@@ -0,0 +1,39 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+		want    []string
+	}{
+		{"empty", "  ", 10, 0, nil},
+		{"fits", "short text", 100, 0, []string{"short text"}},
+		{"no limit", "a b c", 0, 0, []string{"a b c"}},
+		{"paragraphs", "first paragraph\n\nsecond paragraph", 20, 0, []string{"first paragraph", "second paragraph"}},
+		{"merges small paragraphs", "one\n\ntwo\n\nthree", 10, 0, []string{"one\n\ntwo", "three"}},
+		{"long paragraph at words", "alpha beta gamma delta", 11, 0, []string{"alpha beta", "gamma delta"}},
+		{"overlap", "alpha beta gamma delta epsilon", 17, 6, []string{"alpha beta gamma", "gamma delta", "delta epsilon"}},
+		{"long word", "abcdefghij", 4, 0, []string{"abcd", "efgh", "ij"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.size, tt.overlap)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("SplitText() = %q, want %q", got, tt.want)
+			}
+			for _, chunk := range got {
+				if tt.size > 0 && len(chunk) > tt.size {
+					t.Errorf("chunk %q is longer than %d", chunk, tt.size)
+				}
+			}
+		})
+	}
+}
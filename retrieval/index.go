@@ -0,0 +1,145 @@
+// Package retrieval adds retrieval-augmented generation on top of core: documents
+// are split into chunks, embedded and stored in a vector index, and the chunks
+// closest to a query are injected into prompts.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Embedder turns texts into vectors; llm/openai's client implements it
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Embed calls f
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return f(ctx, texts)
+}
+
+// Document is a unit of text to index. Content is loaded from Path when empty.
+type Document struct {
+	ID       string
+	Content  string
+	Path     string
+	Metadata map[string]string
+}
+
+// Chunk is an indexed piece of a document
+type Chunk struct {
+	ID         string
+	DocumentID string
+	Content    string
+	Metadata   map[string]string
+	Vector     []float32
+}
+
+// Match is a chunk returned by a search with its similarity to the query;
+// higher scores are closer, 1 being identical for cosine similarity
+type Match struct {
+	Chunk
+	Score float64
+}
+
+// Index stores chunk vectors and finds the nearest ones to a query vector
+type Index interface {
+	// Upsert adds chunks or replaces chunks with the same ID
+	Upsert(ctx context.Context, chunks []Chunk) error
+
+	// Search returns the k chunks closest to vector, best first
+	Search(ctx context.Context, vector []float32, k int) ([]Match, error)
+
+	// Delete removes chunks by ID; unknown IDs are ignored
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// MemoryIndex is an exact, in-process index using cosine similarity. It suits
+// tests and corpora of up to tens of thousands of chunks.
+type MemoryIndex struct {
+	mu     sync.RWMutex
+	chunks map[string]Chunk
+}
+
+// NewMemoryIndex creates an empty in-memory index
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{chunks: make(map[string]Chunk)}
+}
+
+// Upsert stores chunks by ID
+func (m *MemoryIndex) Upsert(_ context.Context, chunks []Chunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, chunk := range chunks {
+		if chunk.ID == "" {
+			return fmt.Errorf("chunk ID cannot be empty")
+		}
+		if len(chunk.Vector) == 0 {
+			return fmt.Errorf("chunk %s has no vector", chunk.ID)
+		}
+		m.chunks[chunk.ID] = chunk
+	}
+	return nil
+}
+
+// Search scores every chunk against vector
+func (m *MemoryIndex) Search(_ context.Context, vector []float32, k int) ([]Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := make([]Match, 0, len(m.chunks))
+	for _, chunk := range m.chunks {
+		if len(chunk.Vector) != len(vector) {
+			return nil, fmt.Errorf("query has %d dimensions, chunk %s has %d", len(vector), chunk.ID, len(chunk.Vector))
+		}
+		matches = append(matches, Match{Chunk: chunk, Score: cosineSimilarity(vector, chunk.Vector)})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Delete removes chunks by ID
+func (m *MemoryIndex) Delete(_ context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.chunks, id)
+	}
+	return nil
+}
+
+// Len returns the number of indexed chunks
+func (m *MemoryIndex) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.chunks)
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, 0 for zero vectors
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
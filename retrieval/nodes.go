@@ -0,0 +1,176 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// RetrievalResult is what a RetrieverNode stores in state
+type RetrievalResult struct {
+	Query   string
+	Matches []Match
+	Context string // Matches rendered with FormatContext, ready to insert into a prompt
+	Err     error
+}
+
+// RetrieverStateInterface is implemented by workflow states that feed a RetrieverNode
+type RetrieverStateInterface interface {
+	GetRetrievalQuery() string
+	SetRetrievalResult(result RetrievalResult)
+}
+
+// RetrieverNode looks up the chunks relevant to the state's query so a following
+// LLM node can add RetrievalResult.Context to its prompt
+type RetrieverNode[S RetrieverStateInterface] struct {
+	retriever *Retriever
+	topK      int
+}
+
+// NewRetrieverNode creates a retriever node returning topK chunks; zero uses the
+// retriever's Config.TopK
+func NewRetrieverNode[S RetrieverStateInterface](retriever *Retriever, topK int) *RetrieverNode[S] {
+	return &RetrieverNode[S]{retriever: retriever, topK: topK}
+}
+
+// Prep reads the query from state; an empty query skips retrieval
+func (n *RetrieverNode[S]) Prep(state *S) []string {
+	query := (*state).GetRetrievalQuery()
+	if query == "" {
+		return []string{}
+	}
+	return []string{query}
+}
+
+// Exec searches the index without the context of the run
+func (n *RetrieverNode[S]) Exec(query string) (RetrievalResult, error) {
+	return n.ExecContext(context.Background(), query)
+}
+
+// ExecContext embeds the query and searches the index, cancelling both with ctx
+func (n *RetrieverNode[S]) ExecContext(ctx context.Context, query string) (RetrievalResult, error) {
+	matches, err := n.retriever.Retrieve(ctx, query, n.topK)
+	if err != nil {
+		return RetrievalResult{Query: query}, err
+	}
+	return RetrievalResult{Query: query, Matches: matches, Context: FormatContext(matches)}, nil
+}
+
+// ExecFallback records the error once retries are exhausted
+func (n *RetrieverNode[S]) ExecFallback(err error) RetrievalResult {
+	return RetrievalResult{Err: err}
+}
+
+// Post stores the result in state; a failed lookup returns ActionFailure so the
+// flow can decide whether to answer without context
+func (n *RetrieverNode[S]) Post(state *S, prepRes []string, execResults ...RetrievalResult) core.Action {
+	if len(execResults) == 0 {
+		(*state).SetRetrievalResult(RetrievalResult{})
+		return core.ActionSuccess
+	}
+
+	result := execResults[0]
+	if result.Query == "" && len(prepRes) > 0 {
+		result.Query = prepRes[0]
+	}
+	(*state).SetRetrievalResult(result)
+	if result.Err != nil {
+		return core.ActionFailure
+	}
+	return core.ActionSuccess
+}
+
+// IndexedDocument is the outcome of indexing one document
+type IndexedDocument struct {
+	Document Document
+	Chunks   int
+	Err      error
+	Duration time.Duration
+}
+
+// IndexReport summarizes an IndexerNode run
+type IndexReport struct {
+	Documents []IndexedDocument
+	Chunks    int
+	Failed    int
+}
+
+// Summary returns a one-line description of the report
+func (r *IndexReport) Summary() string {
+	return fmt.Sprintf("indexed %d chunks from %d documents, %d failed",
+		r.Chunks, len(r.Documents)-r.Failed, r.Failed)
+}
+
+// IndexerStateInterface is implemented by workflow states that feed an IndexerNode
+type IndexerStateInterface interface {
+	GetDocuments() ([]Document, error)
+	SetIndexReport(report *IndexReport)
+}
+
+// IndexerNode ingests the state's documents into the retriever's index. Wrap it
+// with core.NewNode to index several documents concurrently; failures are reported
+// per document instead of failing the whole run.
+type IndexerNode[S IndexerStateInterface] struct {
+	retriever *Retriever
+}
+
+// NewIndexerNode creates an indexer node
+func NewIndexerNode[S IndexerStateInterface](retriever *Retriever) *IndexerNode[S] {
+	return &IndexerNode[S]{retriever: retriever}
+}
+
+// Prep lists the documents to index
+func (n *IndexerNode[S]) Prep(state *S) []IndexedDocument {
+	documents, err := (*state).GetDocuments()
+	if err != nil {
+		return []IndexedDocument{{Err: fmt.Errorf("failed to list documents: %w", err)}}
+	}
+
+	items := make([]IndexedDocument, len(documents))
+	for i, document := range documents {
+		items[i] = IndexedDocument{Document: document}
+	}
+	return items
+}
+
+// Exec indexes one document without the context of the run
+func (n *IndexerNode[S]) Exec(item IndexedDocument) (IndexedDocument, error) {
+	return n.ExecContext(context.Background(), item)
+}
+
+// ExecContext chunks, embeds and stores one document, cancelling the
+// embedding with ctx
+func (n *IndexerNode[S]) ExecContext(ctx context.Context, item IndexedDocument) (IndexedDocument, error) {
+	if item.Err != nil {
+		return item, nil
+	}
+
+	started := time.Now()
+	item.Chunks, item.Err = n.retriever.IndexDocuments(ctx, item.Document)
+	item.Duration = time.Since(started)
+	return item, nil
+}
+
+// ExecFallback records an unexpected execution failure
+func (n *IndexerNode[S]) ExecFallback(err error) IndexedDocument {
+	return IndexedDocument{Err: err}
+}
+
+// Post stores an IndexReport in state
+func (n *IndexerNode[S]) Post(state *S, prepRes []IndexedDocument, execResults ...IndexedDocument) core.Action {
+	report := &IndexReport{Documents: execResults}
+	for _, item := range execResults {
+		report.Chunks += item.Chunks
+		if item.Err != nil {
+			report.Failed++
+		}
+	}
+	(*state).SetIndexReport(report)
+
+	if report.Failed > 0 && report.Failed == len(execResults) {
+		return core.ActionFailure
+	}
+	return core.ActionSuccess
+}
@@ -0,0 +1,137 @@
+package retrieval
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PgVectorConfig configures a PgVectorIndex
+type PgVectorConfig struct {
+	Table      string // Table holding the chunks; default "retrieval_chunks"
+	Dimensions int    // Vector size of the embedding column
+}
+
+// PgVectorIndex stores chunks in PostgreSQL with the pgvector extension and
+// searches them by cosine distance
+type PgVectorIndex struct {
+	db    *sql.DB
+	table string
+}
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// NewPgVectorIndex creates the extension and table if needed. The caller opens db
+// with a PostgreSQL driver such as github.com/jackc/pgx/v5/stdlib or github.com/lib/pq.
+func NewPgVectorIndex(ctx context.Context, db *sql.DB, config PgVectorConfig) (*PgVectorIndex, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	if config.Table == "" {
+		config.Table = "retrieval_chunks"
+	}
+	if !tableName.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid table name %q", config.Table)
+	}
+	if config.Dimensions <= 0 {
+		return nil, fmt.Errorf("dimensions must be positive, got %d", config.Dimensions)
+	}
+
+	if _, err := db.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS vector`); err != nil {
+		return nil, fmt.Errorf("failed to enable pgvector: %w", err)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id TEXT PRIMARY KEY,
+		document_id TEXT NOT NULL,
+		content TEXT NOT NULL,
+		metadata JSONB,
+		embedding vector(%d) NOT NULL
+	)`, config.Table, config.Dimensions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %w", config.Table, err)
+	}
+	return &PgVectorIndex{db: db, table: config.Table}, nil
+}
+
+// Upsert inserts chunks or updates rows with the same ID in one transaction
+func (p *PgVectorIndex) Upsert(ctx context.Context, chunks []Chunk) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statement := fmt.Sprintf(`INSERT INTO %s (id, document_id, content, metadata, embedding)
+		VALUES ($1, $2, $3, $4, $5::vector)
+		ON CONFLICT (id) DO UPDATE SET document_id = EXCLUDED.document_id,
+			content = EXCLUDED.content, metadata = EXCLUDED.metadata, embedding = EXCLUDED.embedding`, p.table)
+	for _, chunk := range chunks {
+		metadata, err := json.Marshal(chunk.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata of chunk %s: %w", chunk.ID, err)
+		}
+		_, err = tx.ExecContext(ctx, statement, chunk.ID, chunk.DocumentID, chunk.Content, string(metadata), vectorLiteral(chunk.Vector))
+		if err != nil {
+			return fmt.Errorf("failed to upsert chunk %s: %w", chunk.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunks: %w", err)
+	}
+	return nil
+}
+
+// Search orders rows by cosine distance; scores are 1 minus the distance
+func (p *PgVectorIndex) Search(ctx context.Context, vector []float32, k int) ([]Match, error) {
+	if k <= 0 {
+		k = 10
+	}
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, document_id, content, metadata, 1 - (embedding <=> $1::vector)
+		FROM %s ORDER BY embedding <=> $1::vector LIMIT $2`, p.table), vectorLiteral(vector), k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", p.table, err)
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var match Match
+		var metadata sql.NullString
+		if err := rows.Scan(&match.ID, &match.DocumentID, &match.Content, &metadata, &match.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		if metadata.Valid && metadata.String != "null" {
+			if err := json.Unmarshal([]byte(metadata.String), &match.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to decode metadata of chunk %s: %w", match.ID, err)
+			}
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chunks: %w", err)
+	}
+	return matches, nil
+}
+
+// Delete removes rows by chunk ID
+func (p *PgVectorIndex) Delete(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if _, err := p.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, p.table), id); err != nil {
+			return fmt.Errorf("failed to delete chunk %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// vectorLiteral formats a vector in pgvector's text representation, e.g. [1,0.5,-2]
+func vectorLiteral(vector []float32) string {
+	parts := make([]string, len(vector))
+	for i, value := range vector {
+		parts[i] = strconv.FormatFloat(float64(value), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,40 @@
+package retrieval
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVectorLiteral(t *testing.T) {
+	tests := []struct {
+		vector []float32
+		want   string
+	}{
+		{nil, "[]"},
+		{[]float32{1, 0.5, -2}, "[1,0.5,-2]"},
+		{[]float32{0.1}, "[0.1]"},
+	}
+	for _, tt := range tests {
+		if got := vectorLiteral(tt.vector); got != tt.want {
+			t.Errorf("vectorLiteral(%v) = %q, want %q", tt.vector, got, tt.want)
+		}
+	}
+}
+
+func TestNewPgVectorIndex_Validation(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewPgVectorIndex(ctx, nil, PgVectorConfig{Dimensions: 3}); err == nil {
+		t.Error("expected an error for a nil database")
+	}
+
+	for _, table := range []string{"chunks; DROP TABLE users", "1chunks", "a.b.c"} {
+		if tableName.MatchString(table) {
+			t.Errorf("table name %q should be rejected", table)
+		}
+	}
+	for _, table := range []string{"retrieval_chunks", "rag.chunks"} {
+		if !tableName.MatchString(table) {
+			t.Errorf("table name %q should be accepted", table)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// QdrantConfig configures a QdrantIndex
+type QdrantConfig struct {
+	URL        string       // Base URL of the REST API, e.g. http://localhost:6333
+	Collection string       // Collection holding the chunks
+	APIKey     string       // Optional api-key header for Qdrant Cloud
+	Dimensions int          // Vector size used when the collection is created
+	HTTPClient *http.Client // Defaults to a client with a 30 second timeout
+}
+
+// QdrantIndex stores chunks in a Qdrant collection through its REST API. Qdrant
+// point IDs must be UUIDs, so chunk IDs are mapped to name-based UUIDs and kept in
+// the payload.
+type QdrantIndex struct {
+	config QdrantConfig
+	client *http.Client
+}
+
+// NewQdrantIndex connects to a collection, creating it with cosine distance when it
+// does not exist yet
+func NewQdrantIndex(ctx context.Context, config QdrantConfig) (*QdrantIndex, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("qdrant URL cannot be empty")
+	}
+	if config.Collection == "" {
+		return nil, fmt.Errorf("qdrant collection cannot be empty")
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	config.URL = strings.TrimRight(config.URL, "/")
+
+	index := &QdrantIndex{config: config, client: client}
+	if err := index.ensureCollection(ctx); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+type qdrantPoint struct {
+	ID      string         `json:"id"`
+	Vector  []float32      `json:"vector,omitempty"`
+	Payload map[string]any `json:"payload,omitempty"`
+}
+
+type qdrantScoredPoint struct {
+	Score   float64 `json:"score"`
+	Payload struct {
+		ChunkID    string            `json:"chunk_id"`
+		DocumentID string            `json:"document_id"`
+		Content    string            `json:"content"`
+		Metadata   map[string]string `json:"metadata"`
+	} `json:"payload"`
+}
+
+// Upsert writes chunks as points and waits until they are searchable
+func (q *QdrantIndex) Upsert(ctx context.Context, chunks []Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	points := make([]qdrantPoint, len(chunks))
+	for i, chunk := range chunks {
+		points[i] = qdrantPoint{
+			ID:     qdrantPointID(chunk.ID),
+			Vector: chunk.Vector,
+			Payload: map[string]any{
+				"chunk_id":    chunk.ID,
+				"document_id": chunk.DocumentID,
+				"content":     chunk.Content,
+				"metadata":    chunk.Metadata,
+			},
+		}
+	}
+	return q.do(ctx, http.MethodPut, "/points?wait=true", map[string]any{"points": points}, nil)
+}
+
+// Search returns the k nearest points with their payloads
+func (q *QdrantIndex) Search(ctx context.Context, vector []float32, k int) ([]Match, error) {
+	if k <= 0 {
+		k = 10
+	}
+	var response struct {
+		Result []qdrantScoredPoint `json:"result"`
+	}
+	request := map[string]any{"vector": vector, "limit": k, "with_payload": true}
+	if err := q.do(ctx, http.MethodPost, "/points/search", request, &response); err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, len(response.Result))
+	for i, point := range response.Result {
+		matches[i] = Match{
+			Chunk: Chunk{
+				ID:         point.Payload.ChunkID,
+				DocumentID: point.Payload.DocumentID,
+				Content:    point.Payload.Content,
+				Metadata:   point.Payload.Metadata,
+			},
+			Score: point.Score,
+		}
+	}
+	return matches, nil
+}
+
+// Delete removes points by chunk ID
+func (q *QdrantIndex) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	points := make([]string, len(ids))
+	for i, id := range ids {
+		points[i] = qdrantPointID(id)
+	}
+	return q.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]any{"points": points}, nil)
+}
+
+// ensureCollection creates the collection if it is missing
+func (q *QdrantIndex) ensureCollection(ctx context.Context) error {
+	err := q.do(ctx, http.MethodGet, "", nil, nil)
+	if err == nil {
+		return nil
+	}
+	var status *qdrantStatusError
+	if !errors.As(err, &status) || status.code != http.StatusNotFound {
+		return err
+	}
+	if q.config.Dimensions <= 0 {
+		return fmt.Errorf("qdrant collection %s does not exist and Dimensions is not set", q.config.Collection)
+	}
+	return q.do(ctx, http.MethodPut, "", map[string]any{
+		"vectors": map[string]any{"size": q.config.Dimensions, "distance": "Cosine"},
+	}, nil)
+}
+
+// qdrantStatusError is returned for non-2xx responses
+type qdrantStatusError struct {
+	code int
+	body string
+}
+
+func (e *qdrantStatusError) Error() string {
+	return fmt.Sprintf("qdrant returned status %d: %s", e.code, e.body)
+}
+
+// do sends a request to the collection endpoint and decodes the response into out
+func (q *QdrantIndex) do(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode qdrant request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := q.config.URL + "/collections/" + url.PathEscape(q.config.Collection) + path
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create qdrant request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if q.config.APIKey != "" {
+		request.Header.Set("api-key", q.config.APIKey)
+	}
+
+	response, err := q.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("qdrant request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read qdrant response: %w", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &qdrantStatusError{code: response.StatusCode, body: strings.TrimSpace(string(data))}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode qdrant response: %w", err)
+		}
+	}
+	return nil
+}
+
+// qdrantPointID derives a stable UUID (version 5 layout) from a chunk ID
+func qdrantPointID(id string) string {
+	sum := sha1.Sum([]byte(id))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
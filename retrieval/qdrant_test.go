@@ -0,0 +1,122 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeQdrant records requests and serves the endpoints QdrantIndex uses
+type fakeQdrant struct {
+	mu         sync.Mutex
+	exists     bool
+	created    map[string]any
+	points     []map[string]any
+	deleted    []any
+	apiKeySeen string
+}
+
+func (f *fakeQdrant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.apiKeySeen = r.Header.Get("api-key")
+
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/collections/docs":
+		if !f.exists {
+			http.Error(w, `{"status":{"error":"Not found"}}`, http.StatusNotFound)
+			return
+		}
+	case r.Method == http.MethodPut && r.URL.Path == "/collections/docs":
+		f.created = body
+		f.exists = true
+	case r.Method == http.MethodPut && r.URL.Path == "/collections/docs/points":
+		for _, point := range body["points"].([]any) {
+			f.points = append(f.points, point.(map[string]any))
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/collections/docs/points/search":
+		payload := f.points[0]["payload"]
+		json.NewEncoder(w).Encode(map[string]any{
+			"result": []any{map[string]any{"id": f.points[0]["id"], "score": 0.92, "payload": payload}},
+		})
+		return
+	case r.Method == http.MethodPost && r.URL.Path == "/collections/docs/points/delete":
+		f.deleted = body["points"].([]any)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(`{"result":true,"status":"ok"}`))
+}
+
+func TestQdrantIndex(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeQdrant{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	index, err := NewQdrantIndex(ctx, QdrantConfig{URL: server.URL + "/", Collection: "docs", APIKey: "secret", Dimensions: 2})
+	if err != nil {
+		t.Fatalf("NewQdrantIndex failed: %v", err)
+	}
+	vectors, _ := fake.created["vectors"].(map[string]any)
+	if vectors["size"] != float64(2) || vectors["distance"] != "Cosine" {
+		t.Errorf("collection created with %v", fake.created)
+	}
+
+	err = index.Upsert(ctx, []Chunk{{ID: "guide.md#0", DocumentID: "guide.md", Content: "hello", Metadata: map[string]string{"lang": "en"}, Vector: []float32{1, 0}}})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	id, _ := fake.points[0]["id"].(string)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("point ID %q is not a UUID", id)
+	}
+	if id != qdrantPointID("guide.md#0") {
+		t.Error("point IDs should be stable for a chunk ID")
+	}
+
+	matches, err := index.Search(ctx, []float32{1, 0}, 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "guide.md#0" || matches[0].Content != "hello" ||
+		matches[0].Metadata["lang"] != "en" || matches[0].Score != 0.92 {
+		t.Errorf("Search = %+v", matches)
+	}
+
+	if err := index.Delete(ctx, "guide.md#0"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != id {
+		t.Errorf("deleted %v", fake.deleted)
+	}
+	if fake.apiKeySeen != "secret" {
+		t.Errorf("api-key header = %q", fake.apiKeySeen)
+	}
+}
+
+func TestQdrantIndex_Errors(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(&fakeQdrant{})
+	defer server.Close()
+
+	if _, err := NewQdrantIndex(ctx, QdrantConfig{URL: server.URL, Collection: "docs"}); err == nil ||
+		!strings.Contains(err.Error(), "Dimensions") {
+		t.Errorf("expected a missing dimensions error, got %v", err)
+	}
+	if _, err := NewQdrantIndex(ctx, QdrantConfig{Collection: "docs"}); err == nil {
+		t.Error("expected an error for an empty URL")
+	}
+	if _, err := NewQdrantIndex(ctx, QdrantConfig{URL: server.URL}); err == nil {
+		t.Error("expected an error for an empty collection")
+	}
+}
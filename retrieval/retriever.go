@@ -0,0 +1,181 @@
+package retrieval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/structured"
+)
+
+// Config controls chunking and retrieval
+type Config struct {
+	ChunkSize      int     // Maximum characters per chunk
+	ChunkOverlap   int     // Characters of the previous chunk repeated at the start of the next
+	EmbedBatchSize int     // Chunks embedded per Embedder call
+	TopK           int     // Chunks returned by Retrieve when k is not given
+	MinScore       float64 // Matches scoring below this are dropped; 0 keeps all
+}
+
+// DefaultConfig returns a default retrieval configuration
+func DefaultConfig() *Config {
+	return &Config{
+		ChunkSize:      1000,
+		ChunkOverlap:   150,
+		EmbedBatchSize: 64,
+		TopK:           4,
+	}
+}
+
+// Retriever ties an Embedder to an Index: it chunks and embeds documents when
+// indexing and embeds queries when retrieving
+type Retriever struct {
+	embedder Embedder
+	index    Index
+	config   *Config
+}
+
+// NewRetriever creates a retriever; a nil config uses DefaultConfig
+func NewRetriever(embedder Embedder, index Index, config *Config) (*Retriever, error) {
+	if embedder == nil {
+		return nil, fmt.Errorf("embedder cannot be nil")
+	}
+	if index == nil {
+		return nil, fmt.Errorf("index cannot be nil")
+	}
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.ChunkOverlap >= config.ChunkSize && config.ChunkSize > 0 {
+		return nil, fmt.Errorf("chunk overlap %d must be smaller than chunk size %d", config.ChunkOverlap, config.ChunkSize)
+	}
+	return &Retriever{embedder: embedder, index: index, config: config}, nil
+}
+
+// Index returns the underlying vector index
+func (r *Retriever) Index() Index {
+	return r.index
+}
+
+// Chunks splits a document into chunks without embedding them. Documents without
+// an ID are identified by Path, or by a hash of their content.
+func (r *Retriever) Chunks(ctx context.Context, document Document) ([]Chunk, error) {
+	if document.Content == "" && document.Path != "" {
+		text, err := structured.LoadFile(ctx, document.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load document %s: %w", document.Path, err)
+		}
+		document.Content = text
+	}
+
+	id := document.ID
+	if id == "" {
+		id = document.Path
+	}
+	if id == "" {
+		sum := sha256.Sum256([]byte(document.Content))
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	texts := SplitText(document.Content, r.config.ChunkSize, r.config.ChunkOverlap)
+	chunks := make([]Chunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = Chunk{
+			ID:         fmt.Sprintf("%s#%d", id, i),
+			DocumentID: id,
+			Content:    text,
+			Metadata:   document.Metadata,
+		}
+	}
+	return chunks, nil
+}
+
+// IndexDocuments chunks, embeds and upserts documents, returning the number of
+// chunks written
+func (r *Retriever) IndexDocuments(ctx context.Context, documents ...Document) (int, error) {
+	var chunks []Chunk
+	for _, document := range documents {
+		documentChunks, err := r.Chunks(ctx, document)
+		if err != nil {
+			return 0, err
+		}
+		chunks = append(chunks, documentChunks...)
+	}
+
+	batchSize := r.config.EmbedBatchSize
+	if batchSize <= 0 {
+		batchSize = len(chunks)
+	}
+	for start := 0; start < len(chunks); start += batchSize {
+		end := min(start+batchSize, len(chunks))
+		batch := chunks[start:end]
+
+		texts := make([]string, len(batch))
+		for i, chunk := range batch {
+			texts[i] = chunk.Content
+		}
+		vectors, err := r.embedder.Embed(ctx, texts)
+		if err != nil {
+			return start, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return start, fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(batch))
+		}
+		for i := range batch {
+			batch[i].Vector = vectors[i]
+		}
+		if err := r.index.Upsert(ctx, batch); err != nil {
+			return start, fmt.Errorf("failed to index chunks: %w", err)
+		}
+	}
+	return len(chunks), nil
+}
+
+// Retrieve returns the k chunks most similar to query; k of zero or less uses
+// Config.TopK
+func (r *Retriever) Retrieve(ctx context.Context, query string, k int) ([]Match, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+	if k <= 0 {
+		k = r.config.TopK
+	}
+
+	vectors, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for one query", len(vectors))
+	}
+
+	matches, err := r.index.Search(ctx, vectors[0], k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	kept := matches[:0]
+	for _, match := range matches {
+		if match.Score >= r.config.MinScore {
+			kept = append(kept, match)
+		}
+	}
+	return kept, nil
+}
+
+// FormatContext renders matches as numbered sources for a prompt, e.g.
+//
+//	[1] handbook.md
+//	Employees accrue 25 days of leave per year.
+func FormatContext(matches []Match) string {
+	var b strings.Builder
+	for i, match := range matches {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "[%d] %s\n%s", i+1, match.DocumentID, match.Content)
+	}
+	return b.String()
+}
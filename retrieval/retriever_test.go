@@ -0,0 +1,192 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// keywordEmbedder embeds texts as counts of a fixed vocabulary
+var keywordEmbedder = EmbedderFunc(func(_ context.Context, texts []string) ([][]float32, error) {
+	vocabulary := []string{"leave", "salary", "laptop", "holiday"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, len(vocabulary))
+		for j, word := range vocabulary {
+			vector[j] = float32(strings.Count(strings.ToLower(text), word))
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+})
+
+func newTestRetriever(t *testing.T) *Retriever {
+	t.Helper()
+	retriever, err := NewRetriever(keywordEmbedder, NewMemoryIndex(), &Config{ChunkSize: 60, TopK: 2})
+	if err != nil {
+		t.Fatalf("NewRetriever failed: %v", err)
+	}
+	return retriever
+}
+
+var handbook = []Document{
+	{ID: "leave.md", Content: "Employees get 25 days of annual leave.\n\nUnused leave and holiday carry over."},
+	{ID: "pay.md", Content: "Salary is paid monthly. Salary reviews happen in March."},
+	{ID: "it.md", Content: "Every employee receives a laptop on day one."},
+}
+
+func TestRetriever_IndexAndRetrieve(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t)
+
+	chunks, err := retriever.IndexDocuments(ctx, handbook...)
+	if err != nil {
+		t.Fatalf("IndexDocuments failed: %v", err)
+	}
+	if chunks != 4 {
+		t.Errorf("expected the leave document to be split into two chunks, got %d chunks in total", chunks)
+	}
+
+	tests := []struct {
+		query    string
+		wantDoc  string
+		wantText string
+	}{
+		{"how much leave do I get", "leave.md", "annual leave"},
+		{"when is salary paid", "pay.md", "monthly"},
+		{"laptop", "it.md", "laptop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			matches, err := retriever.Retrieve(ctx, tt.query, 1)
+			if err != nil {
+				t.Fatalf("Retrieve failed: %v", err)
+			}
+			if len(matches) != 1 || matches[0].DocumentID != tt.wantDoc || !strings.Contains(matches[0].Content, tt.wantText) {
+				t.Errorf("Retrieve(%q) = %+v", tt.query, matches)
+			}
+		})
+	}
+
+	if _, err := retriever.Retrieve(ctx, "  ", 0); err == nil {
+		t.Error("expected an error for an empty query")
+	}
+}
+
+func TestRetriever_MinScore(t *testing.T) {
+	ctx := context.Background()
+	retriever, _ := NewRetriever(keywordEmbedder, NewMemoryIndex(), &Config{TopK: 3, MinScore: 0.5})
+	retriever.IndexDocuments(ctx, handbook...)
+
+	matches, err := retriever.Retrieve(ctx, "laptop", 0)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("expected unrelated chunks to be filtered, got %+v", matches)
+	}
+}
+
+func TestNewRetriever_Validation(t *testing.T) {
+	if _, err := NewRetriever(nil, NewMemoryIndex(), nil); err == nil {
+		t.Error("expected an error for a nil embedder")
+	}
+	if _, err := NewRetriever(keywordEmbedder, nil, nil); err == nil {
+		t.Error("expected an error for a nil index")
+	}
+	if _, err := NewRetriever(keywordEmbedder, NewMemoryIndex(), &Config{ChunkSize: 10, ChunkOverlap: 10}); err == nil {
+		t.Error("expected an error for an overlap as large as the chunk size")
+	}
+}
+
+func TestMemoryIndex(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+
+	index.Upsert(ctx, []Chunk{
+		{ID: "a", Vector: []float32{1, 0}},
+		{ID: "b", Vector: []float32{0.7, 0.7}},
+		{ID: "c", Vector: []float32{0, 1}},
+	})
+	matches, err := index.Search(ctx, []float32{1, 0.1}, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "a" || matches[1].ID != "b" {
+		t.Errorf("Search = %+v", matches)
+	}
+
+	index.Delete(ctx, "a")
+	if index.Len() != 2 {
+		t.Errorf("Len after Delete = %d", index.Len())
+	}
+	if _, err := index.Search(ctx, []float32{1, 0, 0}, 1); err == nil {
+		t.Error("expected an error for mismatched dimensions")
+	}
+	if err := index.Upsert(ctx, []Chunk{{ID: "d"}}); err == nil {
+		t.Error("expected an error for a chunk without a vector")
+	}
+}
+
+type ragState struct {
+	Question  string
+	Retrieved RetrievalResult
+	Documents []Document
+	Report    *IndexReport
+}
+
+func (s *ragState) GetRetrievalQuery() string                 { return s.Question }
+func (s *ragState) SetRetrievalResult(result RetrievalResult) { s.Retrieved = result }
+func (s *ragState) GetDocuments() ([]Document, error)         { return s.Documents, nil }
+func (s *ragState) SetIndexReport(report *IndexReport)        { s.Report = report }
+
+func TestIndexerAndRetrieverNodes(t *testing.T) {
+	retriever := newTestRetriever(t)
+
+	indexer := core.NewNode(NewIndexerNode[*ragState](retriever), 0, 3)
+	lookup := core.NewNode(NewRetrieverNode[*ragState](retriever, 1), 1, 1)
+	indexer.AddSuccessor(lookup, core.ActionSuccess)
+
+	state := &ragState{
+		Question:  "is there a salary review",
+		Documents: append(handbook, Document{ID: "missing", Path: "does-not-exist.txt"}),
+	}
+	action := core.NewFlow[*ragState](indexer).Run(&state)
+	if action != core.ActionSuccess {
+		t.Fatalf("flow returned %v", action)
+	}
+
+	if state.Report == nil || state.Report.Chunks != 4 || state.Report.Failed != 1 {
+		t.Fatalf("unexpected index report %+v", state.Report)
+	}
+	if !strings.Contains(state.Report.Summary(), "1 failed") {
+		t.Errorf("Summary = %q", state.Report.Summary())
+	}
+
+	result := state.Retrieved
+	if result.Err != nil || len(result.Matches) != 1 || result.Matches[0].DocumentID != "pay.md" {
+		t.Fatalf("unexpected retrieval result %+v", result)
+	}
+	if !strings.HasPrefix(result.Context, "[1] pay.md\n") {
+		t.Errorf("Context = %q", result.Context)
+	}
+}
+
+func TestRetrieverNode_Failure(t *testing.T) {
+	failing := EmbedderFunc(func(context.Context, []string) ([][]float32, error) {
+		return nil, fmt.Errorf("embedding service unavailable")
+	})
+	retriever, _ := NewRetriever(failing, NewMemoryIndex(), nil)
+	node := core.NewNode(NewRetrieverNode[*ragState](retriever, 0), 0, 1)
+
+	state := &ragState{Question: "anything"}
+	if action := node.Run(&state); action != core.ActionFailure {
+		t.Errorf("expected ActionFailure, got %v", action)
+	}
+	if state.Retrieved.Err == nil || state.Retrieved.Query != "anything" {
+		t.Errorf("unexpected retrieval result %+v", state.Retrieved)
+	}
+}
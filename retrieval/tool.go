@@ -0,0 +1,98 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// SearchToolName is the default name of the tool AddSearchTool adds
+const SearchToolName = "search_knowledge_base"
+
+// SearchToolConfig configures the knowledge base search tool
+type SearchToolConfig struct {
+	Name        string // Tool name; defaults to SearchToolName
+	Description string // What the knowledge base holds, e.g. "the employee handbook"; helps the model decide when to search
+	TopK        int    // Chunks returned when the model does not ask for a number; 0 uses the retriever's TopK
+	MaxK        int    // Most chunks the model may ask for; defaults to 10
+}
+
+// DefaultSearchToolConfig returns a default search tool configuration
+func DefaultSearchToolConfig() *SearchToolConfig {
+	return &SearchToolConfig{
+		Name: SearchToolName,
+		MaxK: 10,
+	}
+}
+
+// AddSearchTool adds a local tool that searches the indexed documents, so an
+// agent can pull context through tool calls when it needs it rather than once
+// up front through a RetrieverNode. The tool returns the matches numbered as
+// FormatContext renders them.
+func (r *Retriever) AddSearchTool(toolManager *tools.ToolManager, config *SearchToolConfig) error {
+	if toolManager == nil {
+		return fmt.Errorf("tool manager cannot be nil")
+	}
+	if config == nil {
+		config = DefaultSearchToolConfig()
+	}
+	name := config.Name
+	if name == "" {
+		name = SearchToolName
+	}
+	maxK := config.MaxK
+	if maxK <= 0 {
+		maxK = 10
+	}
+	topK := config.TopK
+	if topK <= 0 {
+		topK = min(r.config.TopK, maxK)
+	}
+
+	description := "Search the knowledge base for passages relevant to a query. Returns numbered passages with the document they come from."
+	if config.Description != "" {
+		description = fmt.Sprintf("Search %s for passages relevant to a query. Returns numbered passages with the document they come from.", config.Description)
+	}
+
+	return toolManager.AddLocalToolLegacy(tools.LocalTool{
+		Name:        name,
+		Description: description,
+		Parameters: map[string]tools.Parameter{
+			"query": {Type: "string", Description: "What to look for, in natural language", Required: true},
+			"k":     {Type: "number", Description: fmt.Sprintf("Number of passages to return, at most %d", maxK), Default: topK},
+		},
+		Handler: tools.ToolHandler(func(ctx context.Context, args map[string]interface{}) (string, error) {
+			query, _ := args["query"].(string)
+			if strings.TrimSpace(query) == "" {
+				return "", fmt.Errorf("query is required")
+			}
+			k := topK
+			if requested := intArg(args["k"]); requested >= 1 {
+				k = min(requested, maxK)
+			}
+
+			matches, err := r.Retrieve(ctx, query, k)
+			if err != nil {
+				return "", err
+			}
+			if len(matches) == 0 {
+				return "No passages matched the query.", nil
+			}
+			return FormatContext(matches), nil
+		}),
+	})
+}
+
+// intArg returns a numeric tool argument, which arrives as float64 from JSON,
+// or 0 when it is missing
+func intArg(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package retrieval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+func TestRetriever_AddSearchTool(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t)
+	if _, err := retriever.IndexDocuments(ctx, handbook...); err != nil {
+		t.Fatalf("IndexDocuments failed: %v", err)
+	}
+
+	toolManager := tools.NewToolManager()
+	if err := retriever.AddSearchTool(toolManager, &SearchToolConfig{Description: "the employee handbook", TopK: 1, MaxK: 2}); err != nil {
+		t.Fatalf("AddSearchTool failed: %v", err)
+	}
+	schemas := toolManager.GetAvailableTools()
+	if len(schemas) != 1 || schemas[0].Name != SearchToolName || !strings.Contains(schemas[0].Description, "the employee handbook") {
+		t.Fatalf("unexpected tools %+v", schemas)
+	}
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		passages int
+		want     string
+		isError  bool
+	}{
+		{"default k", map[string]any{"query": "when is salary paid"}, 1, "[1] pay.md\nSalary is paid monthly", false},
+		{"requested k", map[string]any{"query": "leave", "k": float64(2)}, 2, "[1] leave.md", false},
+		{"k capped", map[string]any{"query": "leave", "k": float64(50)}, 2, "[2] ", false},
+		{"no query", map[string]any{"k": float64(2)}, 0, "query is required", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := toolManager.ExecuteTool(ctx, llm.ToolCalls{Id: "call-1", ToolName: SearchToolName, ToolArgs: tt.args})
+			if tt.isError {
+				if err == nil && !result.IsError {
+					t.Fatalf("expected an error, got %+v", result)
+				}
+				return
+			}
+			if err != nil || result.IsError {
+				t.Fatalf("ExecuteTool failed: %v, %+v", err, result)
+			}
+			if passages := strings.Count(result.Content, "\n\n[") + 1; passages != tt.passages {
+				t.Errorf("expected %d passages, got %q", tt.passages, result.Content)
+			}
+			if !strings.Contains(result.Content, tt.want) {
+				t.Errorf("expected %q in %q", tt.want, result.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/sigv4"
+)
+
+// AWSConfig configures the awssm:// resolver for AWS Secrets Manager
+type AWSConfig struct {
+	Region          string // Defaults to the region of an ARN, then AWS_REGION and AWS_DEFAULT_REGION
+	Endpoint        string // API endpoint; defaults to https://secretsmanager.<region>.amazonaws.com
+	AccessKeyID     string // Defaults to AWS_ACCESS_KEY_ID
+	SecretAccessKey string // Defaults to AWS_SECRET_ACCESS_KEY
+	SessionToken    string // Defaults to AWS_SESSION_TOKEN
+}
+
+// awsResolver reads awssm://<name or ARN>#<key> with GetSecretValue
+type awsResolver struct {
+	config AWSConfig
+	client *http.Client
+}
+
+func newAWSResolver(config AWSConfig, lookupEnv func(string) (string, bool), client *http.Client) *awsResolver {
+	if config.Region == "" {
+		config.Region, _ = lookupEnv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region, _ = lookupEnv("AWS_DEFAULT_REGION")
+	}
+	if config.AccessKeyID == "" {
+		config.AccessKeyID, _ = lookupEnv("AWS_ACCESS_KEY_ID")
+		config.SecretAccessKey, _ = lookupEnv("AWS_SECRET_ACCESS_KEY")
+		config.SessionToken, _ = lookupEnv("AWS_SESSION_TOKEN")
+	}
+	return &awsResolver{config: config, client: client}
+}
+
+// Resolve reads the current version of a string secret
+func (r *awsResolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	if r.config.AccessKeyID == "" || r.config.SecretAccessKey == "" {
+		return "", fmt.Errorf("AWS credentials are not set")
+	}
+	region := r.config.Region
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(ref.Path, ":"); len(parts) >= 7 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("AWS region is not set")
+	}
+	endpoint := r.config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	payload, _ := json.Marshal(map[string]string{"SecretId": ref.Path})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	credentials := sigv4.Credentials{AccessKeyID: r.config.AccessKeyID, SecretAccessKey: r.config.SecretAccessKey, SessionToken: r.config.SessionToken}
+	sigv4.Sign(request, payload, credentials, region, "secretsmanager", time.Now())
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		var awsError struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &awsError)
+		if strings.HasSuffix(awsError.Type, "ResourceNotFoundException") {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("secrets manager returned %s: %s %s", response.Status, awsError.Type, awsError.Message)
+	}
+
+	var secret struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("invalid secrets manager response: %w", err)
+	}
+	if secret.SecretString == nil {
+		return "", fmt.Errorf("secret is binary; only string secrets are supported")
+	}
+	return field(*secret.SecretString, ref.Key)
+}
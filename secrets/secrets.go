@@ -0,0 +1,318 @@
+// Package secrets expands secret references in configuration values, so config
+// files can name credentials instead of containing them. A value may refer to
+// environment variables with ${NAME} or ${NAME:-default}, or be a secret
+// manager URI: file:///run/secrets/openai, vault://secret/agent#api_key or
+// awssm://prod/agent#api_key.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a referenced secret or environment variable does not exist
+var ErrNotFound = errors.New("secret not found")
+
+// Reference is a parsed secret URI <scheme>://<path>[#<key>]. The path is kept
+// as written, so it may hold an ARN or characters a URL would not allow.
+type Reference struct {
+	Scheme string
+	Path   string
+	Key    string // Selects a field of a secret holding a JSON object
+}
+
+// ParseReference splits a secret URI
+func ParseReference(uri string) (Reference, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || scheme == "" {
+		return Reference{}, fmt.Errorf("invalid secret reference %q", uri)
+	}
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return Reference{}, fmt.Errorf("secret reference %q has no path", uri)
+	}
+	return Reference{Scheme: scheme, Path: path, Key: key}, nil
+}
+
+// String returns the URI of the reference
+func (r Reference) String() string {
+	if r.Key == "" {
+		return r.Scheme + "://" + r.Path
+	}
+	return r.Scheme + "://" + r.Path + "#" + r.Key
+}
+
+// Resolver reads the secrets of one URI scheme
+type Resolver interface {
+	Resolve(ctx context.Context, ref Reference) (string, error)
+}
+
+// ResolverFunc adapts a function to Resolver
+type ResolverFunc func(ctx context.Context, ref Reference) (string, error)
+
+// Resolve calls f
+func (f ResolverFunc) Resolve(ctx context.Context, ref Reference) (string, error) {
+	return f(ctx, ref)
+}
+
+// Config configures an Expander
+type Config struct {
+	LookupEnv  func(name string) (string, bool) // Defaults to os.LookupEnv
+	Strict     bool                             // Fail on unset variables without a default instead of expanding them to ""
+	Vault      VaultConfig
+	AWS        AWSConfig
+	Resolvers  map[string]Resolver // Additional or replacement resolvers by URI scheme
+	HTTPClient *http.Client        // Used by the Vault and AWS resolvers; defaults to a client with a 10 second timeout
+}
+
+// DefaultConfig reads the environment and resolves file, vault and awssm URIs
+func DefaultConfig() *Config {
+	return &Config{LookupEnv: os.LookupEnv}
+}
+
+// Expander replaces secret references with their values. Resolved URIs are
+// cached, so a secret used in several places is read once.
+type Expander struct {
+	lookupEnv func(string) (string, bool)
+	strict    bool
+	resolvers map[string]Resolver
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewExpander creates an expander; a nil config uses DefaultConfig
+func NewExpander(config *Config) *Expander {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	e := &Expander{
+		lookupEnv: config.LookupEnv,
+		strict:    config.Strict,
+		cache:     make(map[string]string),
+	}
+	if e.lookupEnv == nil {
+		e.lookupEnv = os.LookupEnv
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	e.resolvers = map[string]Resolver{
+		"file":  ResolverFunc(resolveFile),
+		"vault": newVaultResolver(config.Vault, e.lookupEnv, client),
+		"awssm": newAWSResolver(config.AWS, e.lookupEnv, client),
+	}
+	for scheme, resolver := range config.Resolvers {
+		e.resolvers[scheme] = resolver
+	}
+	return e
+}
+
+// Expand returns value with its references replaced. A value that is a URI of
+// a resolver scheme is replaced by the secret; otherwise ${NAME} and
+// ${NAME:-default} are replaced by environment variables, and $${ stays a
+// literal ${.
+func (e *Expander) Expand(ctx context.Context, value string) (string, error) {
+	if scheme, _, ok := strings.Cut(value, "://"); ok {
+		if resolver, ok := e.resolvers[scheme]; ok {
+			return e.resolve(ctx, resolver, value)
+		}
+	}
+
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+	var out strings.Builder
+	rest := value
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			out.WriteString(rest)
+			return out.String(), nil
+		}
+		if start > 0 && rest[start-1] == '$' {
+			// $${ escapes the reference
+			out.WriteString(rest[:start-1] + "${")
+			rest = rest[start+2:]
+			continue
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", value)
+		}
+		out.WriteString(rest[:start])
+		expanded, err := e.variable(rest[start+2 : start+end])
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(expanded)
+		rest = rest[start+end+1:]
+	}
+}
+
+// variable returns the value of a reference "NAME" or "NAME:-default"
+func (e *Expander) variable(reference string) (string, error) {
+	name, fallback, hasDefault := strings.Cut(reference, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty variable reference ${%s}", reference)
+	}
+	value, ok := e.lookupEnv(name)
+	if ok && value != "" {
+		return value, nil
+	}
+	if hasDefault {
+		return fallback, nil
+	}
+	if e.strict && !ok {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrNotFound, name)
+	}
+	return value, nil
+}
+
+// resolve reads the secret of a URI, once per expander
+func (e *Expander) resolve(ctx context.Context, resolver Resolver, uri string) (string, error) {
+	e.mu.Lock()
+	value, ok := e.cache[uri]
+	e.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+
+	ref, err := ParseReference(uri)
+	if err != nil {
+		return "", err
+	}
+	value, err = resolver.Resolve(ctx, ref)
+	if err != nil {
+		// Errors name the reference, never a value
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	e.mu.Lock()
+	e.cache[uri] = value
+	e.mu.Unlock()
+	return value, nil
+}
+
+// ExpandJSON expands the values of the named keys anywhere in a JSON document,
+// e.g. the secret-bearing fields of a config file before it is decoded. All
+// strings within the value of a key are expanded, e.g. every variable of an
+// "env" map; other values and all keys are left as they are, so prompts or
+// arguments that happen to contain ${ or a URI stay literal.
+func (e *Expander) ExpandJSON(ctx context.Context, data []byte, keys ...string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	expanded, err := e.expandKeys(ctx, document, "", keys)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(expanded)
+}
+
+// expandKeys expands the values of keys within a decoded JSON value; path
+// locates it for errors
+func (e *Expander) expandKeys(ctx context.Context, value any, path string, keys []string) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			var expanded any
+			var err error
+			if slices.Contains(keys, key) {
+				expanded, err = e.expandValue(ctx, item, path+"."+key)
+			} else {
+				expanded, err = e.expandKeys(ctx, item, path+"."+key, keys)
+			}
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []any:
+		for i, item := range v {
+			expanded, err := e.expandKeys(ctx, item, fmt.Sprintf("%s[%d]", path, i), keys)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return value, nil
+}
+
+// expandValue expands the strings in a decoded JSON value; path locates it for errors
+func (e *Expander) expandValue(ctx context.Context, value any, path string) (any, error) {
+	switch v := value.(type) {
+	case string:
+		expanded, err := e.Expand(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.TrimPrefix(path, "."), err)
+		}
+		return expanded, nil
+	case map[string]any:
+		for key, item := range v {
+			expanded, err := e.expandValue(ctx, item, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+	case []any:
+		for i, item := range v {
+			expanded, err := e.expandValue(ctx, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	}
+	return value, nil
+}
+
+// resolveFile reads file:///absolute/path or file://relative/path without the
+// trailing newline; a key selects a field of a JSON file
+func resolveFile(ctx context.Context, ref Reference) (string, error) {
+	data, err := os.ReadFile(ref.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, ref.Path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return field(strings.TrimRight(string(data), "\r\n"), ref.Key)
+}
+
+// field returns the value of a key of a JSON object secret, or the whole
+// secret when key is empty
+func field(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so it has no key %q", key)
+	}
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%w: key %q", ErrNotFound, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, _ := json.Marshal(value)
+	return string(data), nil
+}
@@ -0,0 +1,206 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestExpander_Expand(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "openai"), []byte("sk-file\n"), 0o600)
+	os.WriteFile(filepath.Join(dir, "db.json"), []byte(`{"user": "agent", "port": 5432}`), 0o600)
+
+	env := testEnv(map[string]string{"OPENAI_API_KEY": "sk-env", "REGION": "eu", "EMPTY": ""})
+	tests := []struct {
+		name    string
+		value   string
+		strict  bool
+		want    string
+		wantErr error
+	}{
+		{"plain value", "gpt-4o", false, "gpt-4o", nil},
+		{"variable", "${OPENAI_API_KEY}", false, "sk-env", nil},
+		{"variables in text", "https://${REGION}.example.com/${REGION}", false, "https://eu.example.com/eu", nil},
+		{"default of unset variable", "${MISSING:-fallback}", false, "fallback", nil},
+		{"default of empty variable", "${EMPTY:-fallback}", false, "fallback", nil},
+		{"unset variable", "key=${MISSING}", false, "key=", nil},
+		{"unset variable in strict mode", "${MISSING}", true, "", ErrNotFound},
+		{"empty variable in strict mode", "${EMPTY}", true, "", nil},
+		{"escaped reference", "$${OPENAI_API_KEY}", false, "${OPENAI_API_KEY}", nil},
+		{"file", "file://" + filepath.Join(dir, "openai"), false, "sk-file", nil},
+		{"file key", "file://" + filepath.Join(dir, "db.json") + "#port", false, "5432", nil},
+		{"missing file", "file://" + filepath.Join(dir, "missing"), false, "", ErrNotFound},
+		{"other URL", "http://localhost:1337/v1", false, "http://localhost:1337/v1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expander := NewExpander(&Config{LookupEnv: env, Strict: tt.strict})
+			got, err := expander.Expand(context.Background(), tt.value)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expand(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expand(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpander_Vault(t *testing.T) {
+	var requests int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/agent":
+			w.Write([]byte(`{"data": {"data": {"api_key": "sk-vault", "org": "acme"}}}`))
+		case "/v1/kv/data/team/token":
+			w.Write([]byte(`{"data": {"data": {"token": "t-1"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer vault.Close()
+
+	expander := NewExpander(&Config{LookupEnv: testEnv(map[string]string{"VAULT_ADDR": vault.URL, "VAULT_TOKEN": "root"})})
+	ctx := context.Background()
+	tests := []struct {
+		value   string
+		want    string
+		wantErr error
+	}{
+		{"vault://secret/agent#api_key", "sk-vault", nil},
+		{"vault://secret/agent#api_key", "sk-vault", nil}, // Cached
+		{"vault://kv/team/token", "t-1", nil},             // A single field needs no key
+		{"vault://secret/agent#missing", "", ErrNotFound},
+		{"vault://secret/missing#api_key", "", ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := expander.Expand(ctx, tt.value)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expand(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("vault received %d requests, want 4", requests)
+	}
+
+	denied := NewExpander(&Config{Vault: VaultConfig{Addr: vault.URL, Token: "wrong"}})
+	if _, err := denied.Expand(ctx, "vault://secret/agent#api_key"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expand with a wrong token = %v", err)
+	}
+}
+
+func TestExpander_AWSSecretsManager(t *testing.T) {
+	secretsManager := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&input)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch input.SecretId {
+		case "prod/agent":
+			w.Write([]byte(`{"SecretString": "{\"api_key\": \"sk-aws\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer secretsManager.Close()
+
+	expander := NewExpander(&Config{
+		LookupEnv: testEnv(map[string]string{"AWS_REGION": "eu-west-1", "AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"}),
+		AWS:       AWSConfig{Endpoint: secretsManager.URL},
+	})
+	ctx := context.Background()
+	if got, err := expander.Expand(ctx, "awssm://prod/agent#api_key"); err != nil || got != "sk-aws" {
+		t.Errorf("Expand() = %q, %v", got, err)
+	}
+	if got, err := expander.Expand(ctx, "awssm://prod/agent"); err != nil || got != `{"api_key": "sk-aws"}` {
+		t.Errorf("Expand() without a key = %q, %v", got, err)
+	}
+	if _, err := expander.Expand(ctx, "awssm://prod/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expand() of a missing secret = %v, want ErrNotFound", err)
+	}
+}
+
+func TestExpander_ExpandJSON(t *testing.T) {
+	expander := NewExpander(&Config{
+		LookupEnv: testEnv(map[string]string{"OPENAI_API_KEY": "sk-env", "SEARCH_API_KEY": "search"}),
+		Resolvers: map[string]Resolver{"test": ResolverFunc(func(ctx context.Context, ref Reference) (string, error) {
+			return strings.ToUpper(ref.Path), nil
+		})},
+	})
+	config := `{
+		"llm": {"api_key": "${OPENAI_API_KEY}", "temperature": 0.2},
+		"mcp": {"servers": {"search": {"args": ["--key", "test://token"], "env": {"SEARCH_API_KEY": "${SEARCH_API_KEY}", "TOKEN": "test://token"}}}},
+		"agent": {"system_prompt": "Write ${ and ${NAME} as they are"}
+	}`
+	data, err := expander.ExpandJSON(context.Background(), []byte(config), "api_key", "env")
+	if err != nil {
+		t.Fatalf("ExpandJSON failed: %v", err)
+	}
+
+	var got struct {
+		LLM struct {
+			APIKey      string  `json:"api_key"`
+			Temperature float32 `json:"temperature"`
+		} `json:"llm"`
+		MCP struct {
+			Servers map[string]struct {
+				Args []string          `json:"args"`
+				Env  map[string]string `json:"env"`
+			} `json:"servers"`
+		} `json:"mcp"`
+		Agent struct {
+			SystemPrompt string `json:"system_prompt"`
+		} `json:"agent"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid expanded JSON: %v", err)
+	}
+	search := got.MCP.Servers["search"]
+	if got.LLM.APIKey != "sk-env" || got.LLM.Temperature != 0.2 || search.Env["SEARCH_API_KEY"] != "search" || search.Env["TOKEN"] != "TOKEN" {
+		t.Errorf("expanded config = %s", data)
+	}
+	// Only the named keys are expanded
+	if search.Args[1] != "test://token" || got.Agent.SystemPrompt != "Write ${ and ${NAME} as they are" {
+		t.Errorf("expanded config = %s, want the arguments and prompt left alone", data)
+	}
+
+	strict := NewExpander(&Config{LookupEnv: testEnv(nil), Strict: true})
+	if _, err := strict.ExpandJSON(context.Background(), []byte(`{"llm": {"api_key": "${OPENAI_API_KEY}"}}`), "api_key"); err == nil || !strings.Contains(err.Error(), "llm.api_key") {
+		t.Errorf("ExpandJSON error = %v, want it to name llm.api_key", err)
+	}
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VaultConfig configures the vault:// resolver
+type VaultConfig struct {
+	Addr      string // e.g. https://vault.internal:8200; defaults to VAULT_ADDR
+	Token     string // Defaults to VAULT_TOKEN
+	Namespace string // Vault Enterprise namespace; defaults to VAULT_NAMESPACE
+}
+
+// vaultResolver reads vault://<mount>/<path>#<key> from a KV version 2 secrets engine
+type vaultResolver struct {
+	config VaultConfig
+	client *http.Client
+}
+
+func newVaultResolver(config VaultConfig, lookupEnv func(string) (string, bool), client *http.Client) *vaultResolver {
+	if config.Addr == "" {
+		config.Addr, _ = lookupEnv("VAULT_ADDR")
+	}
+	if config.Token == "" {
+		config.Token, _ = lookupEnv("VAULT_TOKEN")
+	}
+	if config.Namespace == "" {
+		config.Namespace, _ = lookupEnv("VAULT_NAMESPACE")
+	}
+	config.Addr = strings.TrimSuffix(config.Addr, "/")
+	return &vaultResolver{config: config, client: client}
+}
+
+// Resolve reads the latest version of the secret. Without a key, a secret with
+// one field resolves to that field and others to their JSON.
+func (r *vaultResolver) Resolve(ctx context.Context, ref Reference) (string, error) {
+	if r.config.Addr == "" || r.config.Token == "" {
+		return "", fmt.Errorf("vault address or token is not set")
+	}
+	mount, path, ok := strings.Cut(strings.Trim(ref.Path, "/"), "/")
+	if !ok || path == "" {
+		return "", fmt.Errorf("vault reference needs a mount and a path, e.g. vault://secret/agent#api_key")
+	}
+
+	endpoint := r.config.Addr + "/v1/" + url.PathEscape(mount) + "/data/" + escapePath(path)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("X-Vault-Token", r.config.Token)
+	if r.config.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", r.config.Namespace)
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return "", ErrNotFound
+	case response.StatusCode != http.StatusOK:
+		var vaultError struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(body, &vaultError)
+		return "", fmt.Errorf("vault returned %s: %s", response.Status, strings.Join(vaultError.Errors, "; "))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	fields := secret.Data.Data
+	if ref.Key == "" && len(fields) == 1 {
+		for key := range fields {
+			ref.Key = key
+		}
+	}
+	data, _ := json.Marshal(fields)
+	return field(string(data), ref.Key)
+}
+
+// escapePath escapes each segment of a slash-separated path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/memory"
+)
+
+var (
+	// ErrNoCheckpoints is returned when a run asks for checkpoints but the server has no CheckpointStore
+	ErrNoCheckpoints = errors.New("checkpoints are not configured")
+	// ErrCheckpointNotFound is returned when resuming from a checkpoint that was never saved
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+)
+
+// Checkpoint is the progress of a run, saved before its first step and after
+// each one, so an interrupted run can be resumed where it stopped
+type Checkpoint struct {
+	ID        string          `json:"id"`
+	Flow      string          `json:"flow"`
+	Actions   []core.Action   `json:"actions"` // Actions of the finished steps, in order
+	State     json.RawMessage `json:"state,omitempty"`
+	Version   int             `json:"version,omitempty"` // Version of the flow that saved the checkpoint; see WithMigrations
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// CheckpointStore saves checkpoints by ID
+type CheckpointStore interface {
+	SaveCheckpoint(ctx context.Context, checkpoint Checkpoint) error
+	// LoadCheckpoint returns ErrCheckpointNotFound for unknown IDs
+	LoadCheckpoint(ctx context.Context, id string) (Checkpoint, error)
+}
+
+// SessionCheckpointStore keeps checkpoints in a memory.SessionStore, one session
+// per checkpoint, so the file and SQLite session stores can hold them
+type SessionCheckpointStore struct {
+	sessions memory.SessionStore
+}
+
+// NewSessionCheckpointStore creates a checkpoint store backed by sessions
+func NewSessionCheckpointStore(sessions memory.SessionStore) *SessionCheckpointStore {
+	return &SessionCheckpointStore{sessions: sessions}
+}
+
+// SaveCheckpoint stores the checkpoint as the state of the session with its ID
+func (s *SessionCheckpointStore) SaveCheckpoint(ctx context.Context, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	return s.sessions.SaveSession(ctx, memory.Session{ID: checkpoint.ID, State: data, UpdatedAt: checkpoint.UpdatedAt})
+}
+
+// LoadCheckpoint reads the checkpoint saved under id
+func (s *SessionCheckpointStore) LoadCheckpoint(ctx context.Context, id string) (Checkpoint, error) {
+	session, err := s.sessions.LoadSession(ctx, id)
+	if errors.Is(err, memory.ErrSessionNotFound) {
+		return Checkpoint{}, fmt.Errorf("%w: %s", ErrCheckpointNotFound, id)
+	}
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(session.State, &checkpoint); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to decode checkpoint %s: %w", id, err)
+	}
+	return checkpoint, nil
+}
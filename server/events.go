@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// eventWriter writes server-sent events, flushing after each one
+type eventWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	id      int
+}
+
+// newEventWriter starts an event stream on w
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	return &eventWriter{w: w, flusher: flusher}
+}
+
+// send writes one event with data encoded as JSON; nodes may emit from several
+// goroutines, so writes are serialized
+func (e *eventWriter) send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("failed to encode %s event: %v", event, err)})
+		event = "error"
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.id++
+	fmt.Fprintf(e.w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, event, payload)
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: flow.proto
+
+package flowpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	FlowService_ListFlows_FullMethodName        = "/pocketflow.v1.FlowService/ListFlows"
+	FlowService_RunFlow_FullMethodName          = "/pocketflow.v1.FlowService/RunFlow"
+	FlowService_StreamEvents_FullMethodName     = "/pocketflow.v1.FlowService/StreamEvents"
+	FlowService_ResumeCheckpoint_FullMethodName = "/pocketflow.v1.FlowService/ResumeCheckpoint"
+)
+
+// FlowServiceClient is the client API for FlowService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// FlowService runs the flows registered with a pocketflow-go server. States
+// are exchanged as JSON, encoded like the state types of the flows.
+type FlowServiceClient interface {
+	// ListFlows returns the names of the registered flows
+	ListFlows(ctx context.Context, in *ListFlowsRequest, opts ...grpc.CallOption) (*ListFlowsResponse, error)
+	// RunFlow runs a flow to completion and returns its final action and state
+	RunFlow(ctx context.Context, in *RunFlowRequest, opts ...grpc.CallOption) (*RunFlowResponse, error)
+	// StreamEvents runs a flow and streams its progress, ending with a done event
+	StreamEvents(ctx context.Context, in *RunFlowRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FlowEvent], error)
+	// ResumeCheckpoint continues an interrupted run after the last step saved in
+	// its checkpoint and streams its progress like StreamEvents
+	ResumeCheckpoint(ctx context.Context, in *ResumeCheckpointRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FlowEvent], error)
+}
+
+type flowServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFlowServiceClient(cc grpc.ClientConnInterface) FlowServiceClient {
+	return &flowServiceClient{cc}
+}
+
+func (c *flowServiceClient) ListFlows(ctx context.Context, in *ListFlowsRequest, opts ...grpc.CallOption) (*ListFlowsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFlowsResponse)
+	err := c.cc.Invoke(ctx, FlowService_ListFlows_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *flowServiceClient) RunFlow(ctx context.Context, in *RunFlowRequest, opts ...grpc.CallOption) (*RunFlowResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunFlowResponse)
+	err := c.cc.Invoke(ctx, FlowService_RunFlow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *flowServiceClient) StreamEvents(ctx context.Context, in *RunFlowRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FlowEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FlowService_ServiceDesc.Streams[0], FlowService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunFlowRequest, FlowEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FlowService_StreamEventsClient = grpc.ServerStreamingClient[FlowEvent]
+
+func (c *flowServiceClient) ResumeCheckpoint(ctx context.Context, in *ResumeCheckpointRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FlowEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &FlowService_ServiceDesc.Streams[1], FlowService_ResumeCheckpoint_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ResumeCheckpointRequest, FlowEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FlowService_ResumeCheckpointClient = grpc.ServerStreamingClient[FlowEvent]
+
+// FlowServiceServer is the server API for FlowService service.
+// All implementations must embed UnimplementedFlowServiceServer
+// for forward compatibility.
+//
+// FlowService runs the flows registered with a pocketflow-go server. States
+// are exchanged as JSON, encoded like the state types of the flows.
+type FlowServiceServer interface {
+	// ListFlows returns the names of the registered flows
+	ListFlows(context.Context, *ListFlowsRequest) (*ListFlowsResponse, error)
+	// RunFlow runs a flow to completion and returns its final action and state
+	RunFlow(context.Context, *RunFlowRequest) (*RunFlowResponse, error)
+	// StreamEvents runs a flow and streams its progress, ending with a done event
+	StreamEvents(*RunFlowRequest, grpc.ServerStreamingServer[FlowEvent]) error
+	// ResumeCheckpoint continues an interrupted run after the last step saved in
+	// its checkpoint and streams its progress like StreamEvents
+	ResumeCheckpoint(*ResumeCheckpointRequest, grpc.ServerStreamingServer[FlowEvent]) error
+	mustEmbedUnimplementedFlowServiceServer()
+}
+
+// UnimplementedFlowServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFlowServiceServer struct{}
+
+func (UnimplementedFlowServiceServer) ListFlows(context.Context, *ListFlowsRequest) (*ListFlowsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFlows not implemented")
+}
+func (UnimplementedFlowServiceServer) RunFlow(context.Context, *RunFlowRequest) (*RunFlowResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunFlow not implemented")
+}
+func (UnimplementedFlowServiceServer) StreamEvents(*RunFlowRequest, grpc.ServerStreamingServer[FlowEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedFlowServiceServer) ResumeCheckpoint(*ResumeCheckpointRequest, grpc.ServerStreamingServer[FlowEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method ResumeCheckpoint not implemented")
+}
+func (UnimplementedFlowServiceServer) mustEmbedUnimplementedFlowServiceServer() {}
+func (UnimplementedFlowServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeFlowServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FlowServiceServer will
+// result in compilation errors.
+type UnsafeFlowServiceServer interface {
+	mustEmbedUnimplementedFlowServiceServer()
+}
+
+func RegisterFlowServiceServer(s grpc.ServiceRegistrar, srv FlowServiceServer) {
+	// If the following call pancis, it indicates UnimplementedFlowServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&FlowService_ServiceDesc, srv)
+}
+
+func _FlowService_ListFlows_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFlowsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FlowServiceServer).ListFlows(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FlowService_ListFlows_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FlowServiceServer).ListFlows(ctx, req.(*ListFlowsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FlowService_RunFlow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunFlowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FlowServiceServer).RunFlow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FlowService_RunFlow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FlowServiceServer).RunFlow(ctx, req.(*RunFlowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FlowService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunFlowRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FlowServiceServer).StreamEvents(m, &grpc.GenericServerStream[RunFlowRequest, FlowEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FlowService_StreamEventsServer = grpc.ServerStreamingServer[FlowEvent]
+
+func _FlowService_ResumeCheckpoint_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ResumeCheckpointRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FlowServiceServer).ResumeCheckpoint(m, &grpc.GenericServerStream[ResumeCheckpointRequest, FlowEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type FlowService_ResumeCheckpointServer = grpc.ServerStreamingServer[FlowEvent]
+
+// FlowService_ServiceDesc is the grpc.ServiceDesc for FlowService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FlowService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pocketflow.v1.FlowService",
+	HandlerType: (*FlowServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFlows",
+			Handler:    _FlowService_ListFlows_Handler,
+		},
+		{
+			MethodName: "RunFlow",
+			Handler:    _FlowService_RunFlow_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _FlowService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ResumeCheckpoint",
+			Handler:       _FlowService_ResumeCheckpoint_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "flow.proto",
+}
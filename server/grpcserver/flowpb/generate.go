@@ -0,0 +1,5 @@
+// Package flowpb contains the messages and gRPC stubs of the flow service,
+// generated from flow.proto
+package flowpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative flow.proto
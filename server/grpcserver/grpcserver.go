@@ -0,0 +1,191 @@
+// Package grpcserver serves the flows registered with a server.Server over
+// gRPC, so other services and languages can run and observe them. The service
+// is defined in flowpb/flow.proto; clients in other languages generate their
+// stubs from it.
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/server"
+	"github.com/alt-coder/pocketflow-go/server/grpcserver/flowpb"
+)
+
+// Service implements flowpb.FlowServiceServer with the flows of a server.Server.
+// Runs share the server's limits and checkpoint store with its HTTP endpoints.
+type Service struct {
+	flowpb.UnimplementedFlowServiceServer
+	server *server.Server
+}
+
+// New creates a service running the flows registered with srv
+func New(srv *server.Server) *Service {
+	return &Service{server: srv}
+}
+
+// Register creates a service for srv and registers it with registrar, e.g. a *grpc.Server
+func Register(registrar grpc.ServiceRegistrar, srv *server.Server) *Service {
+	service := New(srv)
+	flowpb.RegisterFlowServiceServer(registrar, service)
+	return service
+}
+
+// ListFlows returns the registered flow names
+func (s *Service) ListFlows(ctx context.Context, request *flowpb.ListFlowsRequest) (*flowpb.ListFlowsResponse, error) {
+	return &flowpb.ListFlowsResponse{Flows: s.server.Flows()}, nil
+}
+
+// RunFlow runs a flow and returns its final action and state
+func (s *Service) RunFlow(ctx context.Context, request *flowpb.RunFlowRequest) (*flowpb.RunFlowResponse, error) {
+	result, err := s.server.Run(ctx, request.GetFlow(), request.GetStateJson(), server.RunOptions{
+		CheckpointID: request.GetCheckpointId(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return runResponse(result)
+}
+
+// StreamEvents runs a flow and sends a started event, a step event after each
+// step, the custom events of Emitter states and finally a done event
+func (s *Service) StreamEvents(request *flowpb.RunFlowRequest, stream grpc.ServerStreamingServer[flowpb.FlowEvent]) error {
+	events := newEventSender(stream)
+	events.send(&flowpb.FlowEvent{Event: &flowpb.FlowEvent_Started{Started: &flowpb.Started{
+		Flow:         request.GetFlow(),
+		CheckpointId: request.GetCheckpointId(),
+	}}})
+
+	options := events.options()
+	options.CheckpointID = request.GetCheckpointId()
+	result, err := s.server.Run(stream.Context(), request.GetFlow(), request.GetStateJson(), options)
+	return events.finish(result, err)
+}
+
+// ResumeCheckpoint continues the run saved in a checkpoint and streams its
+// progress like StreamEvents; the step numbers continue from the checkpoint
+func (s *Service) ResumeCheckpoint(request *flowpb.ResumeCheckpointRequest, stream grpc.ServerStreamingServer[flowpb.FlowEvent]) error {
+	checkpoint, err := s.server.Checkpoint(stream.Context(), request.GetCheckpointId())
+	if err != nil {
+		return statusError(err)
+	}
+
+	events := newEventSender(stream)
+	events.send(&flowpb.FlowEvent{Event: &flowpb.FlowEvent_Started{Started: &flowpb.Started{
+		Flow:         checkpoint.Flow,
+		CheckpointId: checkpoint.ID,
+	}}})
+
+	result, err := s.server.Resume(stream.Context(), checkpoint.ID, events.options())
+	return events.finish(result, err)
+}
+
+// eventSender sends the events of one run; nodes may emit from several
+// goroutines, so sends are serialized
+type eventSender struct {
+	mu     sync.Mutex
+	stream grpc.ServerStreamingServer[flowpb.FlowEvent]
+	err    error
+}
+
+// newEventSender sends events on stream
+func newEventSender(stream grpc.ServerStreamingServer[flowpb.FlowEvent]) *eventSender {
+	return &eventSender{stream: stream}
+}
+
+// send sends an event; after the first failure, e.g. a client that went away,
+// further events are dropped and the run finishes on its own
+func (e *eventSender) send(event *flowpb.FlowEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err == nil {
+		e.err = e.stream.Send(event)
+	}
+}
+
+// options reports the steps and custom events of a run
+func (e *eventSender) options() server.RunOptions {
+	return server.RunOptions{
+		Observe: func(event core.StepEvent) {
+			e.send(&flowpb.FlowEvent{Event: &flowpb.FlowEvent_Step{Step: stepMessage(event)}})
+		},
+		Emit: func(event string, data any) {
+			payload, err := json.Marshal(data)
+			if err != nil {
+				payload, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("failed to encode %s event: %v", event, err)})
+			}
+			e.send(&flowpb.FlowEvent{Event: &flowpb.FlowEvent_Custom{Custom: &flowpb.Custom{Event: event, DataJson: payload}}})
+		},
+	}
+}
+
+// finish sends the done event of a run, or returns its error as a status
+func (e *eventSender) finish(result server.RunResult, err error) error {
+	if err != nil {
+		return statusError(err)
+	}
+	response, err := runResponse(result)
+	if err != nil {
+		return err
+	}
+	e.send(&flowpb.FlowEvent{Event: &flowpb.FlowEvent_Done{Done: response}})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// runResponse converts a run result, encoding its state as JSON
+func runResponse(result server.RunResult) (*flowpb.RunFlowResponse, error) {
+	state, err := json.Marshal(result.State)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode state: %v", err)
+	}
+
+	steps := make([]*flowpb.Step, len(result.Steps))
+	for i, step := range result.Steps {
+		steps[i] = stepMessage(step)
+	}
+	return &flowpb.RunFlowResponse{
+		Flow:      result.Flow,
+		Action:    string(result.Action),
+		StateJson: state,
+		Steps:     steps,
+		Duration:  durationpb.New(result.Duration),
+	}, nil
+}
+
+// stepMessage converts a step event
+func stepMessage(event core.StepEvent) *flowpb.Step {
+	return &flowpb.Step{
+		Step:     int32(event.Step),
+		Name:     event.Name,
+		Action:   string(event.Action),
+		Duration: durationpb.New(event.Duration),
+	}
+}
+
+// statusError maps run errors to gRPC status codes
+func statusError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, server.ErrUnknownFlow), errors.Is(err, server.ErrCheckpointNotFound):
+		code = codes.NotFound
+	case errors.Is(err, server.ErrInvalidState):
+		code = codes.InvalidArgument
+	case errors.Is(err, server.ErrBusy):
+		code = codes.ResourceExhausted
+	case errors.Is(err, server.ErrNoCheckpoints):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
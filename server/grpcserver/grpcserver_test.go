@@ -0,0 +1,193 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/internal/flowtest"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/server"
+	"github.com/alt-coder/pocketflow-go/server/grpcserver/flowpb"
+)
+
+// counterState counts in the flowtest flow, streaming the count as events
+type counterState struct {
+	flowtest.Counter
+}
+
+func (s *counterState) SetEmitter(emit server.EmitFunc) { s.Emit = emit }
+
+func newTestClient(t *testing.T, node *flowtest.IncrementNode[*counterState]) flowpb.FlowServiceClient {
+	t.Helper()
+	sessions, err := memory.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore failed: %v", err)
+	}
+	srv := server.New(&server.Config{Checkpoints: server.NewSessionCheckpointStore(sessions)})
+	if err := server.Register[*counterState](srv, "counter", flowtest.NewFlow(node), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	Register(grpcServer, srv)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return flowpb.NewFlowServiceClient(conn)
+}
+
+// receive collects the events of a stream
+func receive(t *testing.T, stream grpc.ServerStreamingClient[flowpb.FlowEvent]) ([]*flowpb.FlowEvent, error) {
+	t.Helper()
+	var events []*flowpb.FlowEvent
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
+
+func TestService_RunFlow(t *testing.T) {
+	client := newTestClient(t, &flowtest.IncrementNode[*counterState]{})
+	ctx := context.Background()
+
+	response, err := client.RunFlow(ctx, &flowpb.RunFlowRequest{Flow: "counter", StateJson: []byte(`{"count": 1, "limit": 3}`)})
+	if err != nil {
+		t.Fatalf("RunFlow failed: %v", err)
+	}
+	var state counterState
+	json.Unmarshal(response.StateJson, &state)
+	if response.Action != string(core.ActionSuccess) || state.Count != 3 || len(response.Steps) != 2 {
+		t.Errorf("RunFlow() = %v", response)
+	}
+
+	list, err := client.ListFlows(ctx, &flowpb.ListFlowsRequest{})
+	if err != nil || len(list.Flows) != 1 || list.Flows[0] != "counter" {
+		t.Errorf("ListFlows() = %v, %v", list, err)
+	}
+
+	tests := []struct {
+		name    string
+		request *flowpb.RunFlowRequest
+		code    codes.Code
+	}{
+		{"unknown flow", &flowpb.RunFlowRequest{Flow: "missing"}, codes.NotFound},
+		{"invalid state", &flowpb.RunFlowRequest{Flow: "counter", StateJson: []byte(`{"count": "many"}`)}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.RunFlow(ctx, tt.request)
+			if status.Code(err) != tt.code {
+				t.Errorf("RunFlow() error = %v, want code %v", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestService_StreamEvents(t *testing.T) {
+	client := newTestClient(t, &flowtest.IncrementNode[*counterState]{})
+
+	stream, err := client.StreamEvents(context.Background(), &flowpb.RunFlowRequest{Flow: "counter", StateJson: []byte(`{"limit": 2}`)})
+	if err != nil {
+		t.Fatalf("StreamEvents failed: %v", err)
+	}
+	events, err := receive(t, stream)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	var kinds []string
+	for _, event := range events {
+		switch event.Event.(type) {
+		case *flowpb.FlowEvent_Started:
+			kinds = append(kinds, "started")
+		case *flowpb.FlowEvent_Step:
+			kinds = append(kinds, "step")
+		case *flowpb.FlowEvent_Custom:
+			kinds = append(kinds, event.GetCustom().Event)
+		case *flowpb.FlowEvent_Done:
+			kinds = append(kinds, "done")
+		}
+	}
+	want := []string{"started", "count", "step", "count", "step", "done"}
+	if len(kinds) != len(want) {
+		t.Fatalf("events = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("events = %v, want %v", kinds, want)
+			break
+		}
+	}
+	if done := events[len(events)-1].GetDone(); done.Action != string(core.ActionSuccess) {
+		t.Errorf("done = %v", done)
+	}
+}
+
+func TestService_ResumeCheckpoint(t *testing.T) {
+	node := &flowtest.IncrementNode[*counterState]{CrashAt: 3}
+	client := newTestClient(t, node)
+	ctx := context.Background()
+
+	_, err := client.RunFlow(ctx, &flowpb.RunFlowRequest{
+		Flow:         "counter",
+		StateJson:    []byte(`{"limit": 4}`),
+		CheckpointId: "run-1",
+	})
+	node.CrashAt = 0
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected the interrupted run to fail, got %v", err)
+	}
+
+	stream, err := client.ResumeCheckpoint(ctx, &flowpb.ResumeCheckpointRequest{CheckpointId: "run-1"})
+	if err != nil {
+		t.Fatalf("ResumeCheckpoint failed: %v", err)
+	}
+	events, err := receive(t, stream)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if started := events[0].GetStarted(); started.GetFlow() != "counter" || started.GetCheckpointId() != "run-1" {
+		t.Errorf("started = %v", started)
+	}
+
+	// The checkpoint holds the state after step 2, before the crash
+	done := events[len(events)-1].GetDone()
+	var state counterState
+	json.Unmarshal(done.GetStateJson(), &state)
+	if state.Count != 4 || len(done.GetSteps()) != 2 || done.Steps[0].Step != 3 {
+		t.Errorf("done = %v", done)
+	}
+
+	stream, err = client.ResumeCheckpoint(ctx, &flowpb.ResumeCheckpointRequest{CheckpointId: "missing"})
+	if err == nil {
+		_, err = receive(t, stream)
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("resuming an unknown checkpoint: %v", err)
+	}
+}
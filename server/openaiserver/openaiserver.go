@@ -0,0 +1,380 @@
+// Package openaiserver serves agent flows as models of the OpenAI chat
+// completions API, so existing chat UIs and OpenAI SDKs can talk to an agent:
+//
+//	GET  /v1/models            lists the flows served as models
+//	POST /v1/chat/completions  runs the flow registered as the request's model
+//
+// Each request runs the flow on a new state holding the conversation of the
+// request, and answers with the agent's reply. Tools and memory are those of
+// the flow; the tools and sampling parameters of the request are ignored.
+package openaiserver
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// Config configures a Server
+type Config struct {
+	MaxBodyBytes      int64    // Largest accepted request; 0 uses 4 MiB
+	MaxConcurrentRuns int      // Runs in progress before new ones are rejected with 503; 0 is unlimited
+	APIKeys           []string // Bearer tokens clients authenticate with; empty accepts any request
+	OwnedBy           string   // Owner reported for the models; empty uses "pocketflow-go"
+}
+
+// DefaultConfig accepts requests up to 4 MiB from any client
+func DefaultConfig() *Config {
+	return &Config{MaxBodyBytes: 4 << 20, OwnedBy: "pocketflow-go"}
+}
+
+var (
+	// ErrUnknownModel is returned for requests naming a model that is not registered
+	ErrUnknownModel = errors.New("unknown model")
+	// ErrInvalidRequest is returned for requests the server cannot hand to a flow
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
+// ChatState is implemented by the states of flows served as models. The
+// server hands the conversation of a request to a new state, runs the flow and
+// answers with the reply.
+type ChatState interface {
+	// SetConversation receives the messages of the request in order, usually
+	// ending with the latest user message
+	SetConversation(messages []llm.Message) error
+	// Reply returns the agent's answer after the run, or why there is none
+	Reply() (llm.Message, error)
+}
+
+// Streamer is implemented by states whose nodes write the reply as it is
+// generated. The server sets the writer before the run; streaming requests
+// receive each delta as a chunk, others only the final reply.
+type Streamer interface {
+	SetStreamer(write func(delta string))
+}
+
+// Server answers OpenAI chat completion requests with registered flows
+type Server struct {
+	config *Config
+	mux    *http.ServeMux
+	slots  chan struct{}
+
+	mu     sync.RWMutex
+	models map[string]model
+}
+
+// model runs one registered flow on a conversation
+type model interface {
+	complete(messages []llm.Message, write func(delta string)) (llm.Message, error)
+	created() time.Time
+}
+
+// New creates a server; a nil config uses DefaultConfig
+func New(config *Config) *Server {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 4 << 20
+	}
+	if config.OwnedBy == "" {
+		config.OwnedBy = "pocketflow-go"
+	}
+
+	s := &Server{
+		config: config,
+		mux:    http.NewServeMux(),
+		models: make(map[string]model),
+	}
+	if config.MaxConcurrentRuns > 0 {
+		s.slots = make(chan struct{}, config.MaxConcurrentRuns)
+	}
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleCompletion)
+	return s
+}
+
+// Register serves flow as the model name. Each request runs the flow on a
+// state from newState, which must not be nil. The flow is shared between
+// concurrent requests, so its nodes must keep per-request data in the state.
+func Register[S ChatState](s *Server, name string, flow core.Workflow[S], newState func() S) error {
+	if name == "" {
+		return fmt.Errorf("model name cannot be empty")
+	}
+	if flow == nil {
+		return fmt.Errorf("flow of model %s cannot be nil", name)
+	}
+	if newState == nil {
+		return fmt.Errorf("state factory of model %s cannot be nil", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.models[name]; exists {
+		return fmt.Errorf("model %s is already registered", name)
+	}
+	s.models[name] = &flowModel[S]{flow: flow, newState: newState, registered: time.Now()}
+	return nil
+}
+
+// Models returns the registered model names in order
+func (s *Server) Models() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.models))
+	for name := range s.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", errors.New("invalid API key"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether the request carries one of the API keys
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.config.APIKeys) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	for _, key := range s.config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// model returns the flow registered as name
+func (s *Server) model(name string) (model, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.models[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownModel, name)
+	}
+	return m, nil
+}
+
+// acquire takes a run slot; release gives it back
+func (s *Server) acquire() (release func(), ok bool) {
+	if s.slots == nil {
+		return func() {}, true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// handleModels answers GET /v1/models
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	models := make([]modelObject, 0, len(s.models))
+	for name, m := range s.models {
+		models = append(models, modelObject{ID: name, Object: "model", Created: m.created().Unix(), OwnedBy: s.config.OwnedBy})
+	}
+	s.mu.RUnlock()
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	writeJSON(w, http.StatusOK, modelList{Object: "list", Data: models})
+}
+
+// handleCompletion answers POST /v1/chat/completions
+func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "", err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err)
+		return
+	}
+	var request chatRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", fmt.Errorf("%w: %v", ErrInvalidRequest, err))
+		return
+	}
+	messages, err := request.conversation()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err)
+		return
+	}
+	m, err := s.model(request.Model)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found", err)
+		return
+	}
+
+	release, ok := s.acquire()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "server_error", "", errors.New("too many runs in progress"))
+		return
+	}
+	defer release()
+
+	completion := completionOf(request.Model)
+	if request.Stream {
+		s.stream(w, m, messages, completion)
+		return
+	}
+
+	reply, err := m.complete(messages, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, completion.response(reply))
+}
+
+// stream runs the flow and answers with chat completion chunks as server-sent
+// events: the role, the deltas the nodes write, or the whole reply when they
+// write none, and a final chunk with the finish reason, followed by [DONE]
+func (s *Server) stream(w http.ResponseWriter, m model, messages []llm.Message, completion completion) {
+	events := newEventWriter(w)
+	events.send(completion.chunk(chunkDelta{Role: llm.RoleAssistant}, ""))
+
+	streamed := false
+	reply, err := m.complete(messages, func(delta string) {
+		if delta == "" {
+			return
+		}
+		streamed = true
+		events.send(completion.chunk(chunkDelta{Content: delta}, ""))
+	})
+	if err != nil {
+		events.send(errorBody{Error: errorObject{Message: err.Error(), Type: "server_error"}})
+		return
+	}
+	if !streamed && reply.Content != "" {
+		events.send(completion.chunk(chunkDelta{Content: reply.Content}, ""))
+	}
+	events.send(completion.chunk(chunkDelta{}, "stop"))
+	events.done()
+}
+
+// flowModel runs a flow on states of type S
+type flowModel[S ChatState] struct {
+	flow       core.Workflow[S]
+	newState   func() S
+	registered time.Time
+}
+
+// created returns when the model was registered
+func (f *flowModel[S]) created() time.Time {
+	return f.registered
+}
+
+// complete runs the flow on a new state holding messages and returns its reply
+func (f *flowModel[S]) complete(messages []llm.Message, write func(delta string)) (reply llm.Message, err error) {
+	state := f.newState()
+	if err := state.SetConversation(messages); err != nil {
+		return llm.Message{}, fmt.Errorf("failed to set the conversation: %w", err)
+	}
+	if streamer, ok := any(state).(Streamer); ok {
+		var mu sync.Mutex
+		streamer.SetStreamer(func(delta string) {
+			if write == nil {
+				return
+			}
+			// Nodes may write from several workers
+			mu.Lock()
+			defer mu.Unlock()
+			write(delta)
+		})
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("flow panicked: %v", recovered)
+		}
+	}()
+	action := f.flow.Run(&state)
+	reply, err = state.Reply()
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("flow ended with %s without a reply: %w", action, err)
+	}
+	return reply, nil
+}
+
+// completion holds what the response and chunks of one completion share
+type completion struct {
+	id      string
+	model   string
+	created int64
+}
+
+// completionOf starts a completion of model with a new ID
+func completionOf(model string) completion {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return completion{id: "chatcmpl-" + hex.EncodeToString(id), model: model, created: time.Now().Unix()}
+}
+
+// response returns the chat completion answering with reply. Usage is
+// reported when the provider stamped the reply with its token counts.
+func (c completion) response(reply llm.Message) chatResponse {
+	return chatResponse{
+		ID:      c.id,
+		Object:  "chat.completion",
+		Created: c.created,
+		Model:   c.model,
+		Choices: []choice{{
+			Message:      responseMessage{Role: llm.RoleAssistant, Content: reply.Content},
+			FinishReason: "stop",
+		}},
+		Usage: usageOf(reply),
+	}
+}
+
+// chunk returns a chat completion chunk with delta
+func (c completion) chunk(delta chunkDelta, finishReason string) chatChunk {
+	chunk := chatChunk{
+		ID:      c.id,
+		Object:  "chat.completion.chunk",
+		Created: c.created,
+		Model:   c.model,
+		Choices: []chunkChoice{{Delta: delta}},
+	}
+	if finishReason != "" {
+		chunk.Choices[0].FinishReason = &finishReason
+	}
+	return chunk
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the format of the OpenAI API
+func writeError(w http.ResponseWriter, status int, errorType, code string, err error) {
+	writeJSON(w, status, errorBody{Error: errorObject{Message: err.Error(), Type: errorType, Code: code}})
+}
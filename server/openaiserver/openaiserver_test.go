@@ -0,0 +1,210 @@
+package openaiserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+type echoState struct {
+	messages []llm.Message
+	reply    llm.Message
+	write    func(delta string)
+}
+
+func (s *echoState) SetConversation(messages []llm.Message) error {
+	s.messages = messages
+	return nil
+}
+
+func (s *echoState) Reply() (llm.Message, error) {
+	if s.reply.Content == "" {
+		return llm.Message{}, errors.New("no reply")
+	}
+	return s.reply, nil
+}
+
+func (s *echoState) SetStreamer(write func(delta string)) { s.write = write }
+
+// echoNode replies with the latest user message, writing it word by word
+type echoNode struct{}
+
+func (echoNode) Prep(state **echoState) []llm.Message {
+	messages := (*state).messages
+	return messages[len(messages)-1:]
+}
+
+func (echoNode) Exec(message llm.Message) (llm.Message, error) {
+	if message.Content == "panic" {
+		panic("echo failed")
+	}
+	if message.Content == "silence" {
+		return llm.Message{}, nil
+	}
+	reply := llm.Message{Role: llm.RoleAssistant, Content: "echo: " + message.Content}
+	return reply.WithMeta(llm.MetaInputTokens, "12").WithMeta(llm.MetaOutputTokens, "3"), nil
+}
+
+func (echoNode) ExecFallback(err error) llm.Message { return llm.Message{} }
+
+func (echoNode) Post(state **echoState, _ []llm.Message, replies ...llm.Message) core.Action {
+	words := strings.SplitAfter(replies[0].Content, " ")
+	for _, word := range words {
+		(*state).write(word)
+	}
+	(*state).reply = replies[0]
+	return core.ActionSuccess
+}
+
+func newTestServer(t *testing.T, config *Config) *httptest.Server {
+	t.Helper()
+	srv := New(config)
+	flow := core.NewFlow[*echoState](core.NewNode[*echoState](echoNode{}, 0, 1))
+	newState := func() *echoState { return &echoState{} }
+	if err := Register[*echoState](srv, "echo-agent", flow, newState); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := Register[*echoState](srv, "echo-agent", flow, newState); err == nil {
+		t.Error("expected an error when registering a name twice")
+	}
+	server := httptest.NewServer(srv)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func post(t *testing.T, url, body string, header http.Header) *http.Response {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func TestServer_Completion(t *testing.T) {
+	server := newTestServer(t, nil)
+	response := post(t, server.URL, `{"model": "echo-agent", "messages": [
+		{"role": "developer", "content": "Be brief."},
+		{"role": "user", "content": [{"type": "text", "text": "hello"}, {"type": "text", "text": "there"}]}]}`, nil)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", response.StatusCode)
+	}
+
+	var completion chatResponse
+	if err := json.NewDecoder(response.Body).Decode(&completion); err != nil {
+		t.Fatalf("failed to decode the completion: %v", err)
+	}
+	if completion.Object != "chat.completion" || completion.Model != "echo-agent" || !strings.HasPrefix(completion.ID, "chatcmpl-") {
+		t.Errorf("completion = %+v", completion)
+	}
+	if len(completion.Choices) != 1 || completion.Choices[0].Message.Content != "echo: hello\nthere" || completion.Choices[0].FinishReason != "stop" {
+		t.Errorf("choices = %+v", completion.Choices)
+	}
+	if completion.Usage == nil || completion.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", completion.Usage)
+	}
+}
+
+func TestServer_Stream(t *testing.T) {
+	server := newTestServer(t, nil)
+	response := post(t, server.URL, `{"model": "echo-agent", "stream": true, "messages": [{"role": "user", "content": "hello world"}]}`, nil)
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", response.Header.Get("Content-Type"))
+	}
+
+	var content strings.Builder
+	var chunks []chatChunk
+	done := false
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("failed to decode chunk %s: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+
+	if !done || len(chunks) != 5 {
+		t.Fatalf("got %d chunks, done %v", len(chunks), done)
+	}
+	if chunks[0].Choices[0].Delta.Role != llm.RoleAssistant || content.String() != "echo: hello world" {
+		t.Errorf("streamed %q after %+v", content.String(), chunks[0])
+	}
+	if last := chunks[len(chunks)-1].Choices[0]; last.FinishReason == nil || *last.FinishReason != "stop" {
+		t.Errorf("last chunk = %+v", last)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	server := newTestServer(t, &Config{APIKeys: []string{"sk-test"}})
+	auth := http.Header{"Authorization": {"Bearer sk-test"}}
+	tests := []struct {
+		name       string
+		body       string
+		header     http.Header
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing key", `{"model": "echo-agent", "messages": [{"role": "user", "content": "hi"}]}`, nil, http.StatusUnauthorized, "invalid_api_key"},
+		{"unknown model", `{"model": "gpt-4o", "messages": [{"role": "user", "content": "hi"}]}`, auth, http.StatusNotFound, "model_not_found"},
+		{"invalid JSON", `{"model": `, auth, http.StatusBadRequest, ""},
+		{"no messages", `{"model": "echo-agent", "messages": []}`, auth, http.StatusBadRequest, ""},
+		{"tool message", `{"model": "echo-agent", "messages": [{"role": "tool", "content": "42"}]}`, auth, http.StatusBadRequest, ""},
+		{"image part", `{"model": "echo-agent", "messages": [{"role": "user", "content": [{"type": "image_url"}]}]}`, auth, http.StatusBadRequest, ""},
+		{"no reply", `{"model": "echo-agent", "messages": [{"role": "user", "content": "silence"}]}`, auth, http.StatusInternalServerError, ""},
+		{"panic", `{"model": "echo-agent", "messages": [{"role": "user", "content": "panic"}]}`, auth, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := post(t, server.URL, tt.body, tt.header)
+			var body errorBody
+			if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode the error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus || body.Error.Code != tt.wantCode || body.Error.Message == "" {
+				t.Errorf("status = %d, error = %+v, want %d %q", response.StatusCode, body.Error, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServer_Models(t *testing.T) {
+	server := newTestServer(t, nil)
+	response, err := http.Get(server.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+
+	var models modelList
+	if err := json.NewDecoder(response.Body).Decode(&models); err != nil {
+		t.Fatalf("failed to decode the models: %v", err)
+	}
+	if len(models.Data) != 1 || models.Data[0].ID != "echo-agent" || models.Data[0].OwnedBy != "pocketflow-go" {
+		t.Errorf("models = %+v", models)
+	}
+}
@@ -0,0 +1,216 @@
+package openaiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// chatRequest is the part of a chat completion request the server uses
+type chatRequest struct {
+	Model    string           `json:"model"`
+	Messages []requestMessage `json:"messages"`
+	Stream   bool             `json:"stream"`
+}
+
+// requestMessage is a message of a chat completion request; its content is a
+// string or a list of parts
+type requestMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// contentPart is a part of a message's content
+type contentPart struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// conversation returns the messages of the request. Developer messages are
+// system messages; tool messages are refused, since the agent runs its own tools.
+func (r chatRequest) conversation() ([]llm.Message, error) {
+	if r.Model == "" {
+		return nil, fmt.Errorf("%w: model is required", ErrInvalidRequest)
+	}
+	if len(r.Messages) == 0 {
+		return nil, fmt.Errorf("%w: messages are required", ErrInvalidRequest)
+	}
+
+	messages := make([]llm.Message, 0, len(r.Messages))
+	for i, message := range r.Messages {
+		role := message.Role
+		switch role {
+		case llm.RoleSystem, llm.RoleUser, llm.RoleAssistant:
+		case "developer":
+			role = llm.RoleSystem
+		default:
+			return nil, fmt.Errorf("%w: message %d has unsupported role %q", ErrInvalidRequest, i, message.Role)
+		}
+		content, err := textOf(message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: message %d: %v", ErrInvalidRequest, i, err)
+		}
+		messages = append(messages, llm.Message{Role: role, Content: content})
+	}
+	return messages, nil
+}
+
+// textOf returns the text of a message's content: a string, null, or text
+// parts, which are joined by newlines
+func textOf(content json.RawMessage) (string, error) {
+	if len(content) == 0 || string(content) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text, nil
+	}
+	var parts []contentPart
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return "", fmt.Errorf("content must be a string or a list of parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", fmt.Errorf("unsupported content part %q", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// chatResponse is a chat completion
+type chatResponse struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage,omitempty"`
+}
+
+// choice is the single choice of a chat completion
+type choice struct {
+	Index        int             `json:"index"`
+	Message      responseMessage `json:"message"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+// responseMessage is the reply of a chat completion
+type responseMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// usage is the token usage of a chat completion
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// usageOf returns the token counts a provider stamped on reply, or nil
+func usageOf(reply llm.Message) *usage {
+	input, inputErr := strconv.Atoi(reply.Meta(llm.MetaInputTokens))
+	output, outputErr := strconv.Atoi(reply.Meta(llm.MetaOutputTokens))
+	if inputErr != nil || outputErr != nil {
+		return nil
+	}
+	return &usage{PromptTokens: input, CompletionTokens: output, TotalTokens: input + output}
+}
+
+// chatChunk is a chunk of a streamed chat completion
+type chatChunk struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []chunkChoice `json:"choices"`
+}
+
+// chunkChoice is the single choice of a chunk; FinishReason is null until the last chunk
+type chunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        chunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+// chunkDelta is what a chunk adds to the reply
+type chunkDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// modelList is the response of GET /v1/models
+type modelList struct {
+	Object string        `json:"object"`
+	Data   []modelObject `json:"data"`
+}
+
+// modelObject describes a registered model
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// errorBody is an error response in the format of the OpenAI API
+type errorBody struct {
+	Error errorObject `json:"error"`
+}
+
+// errorObject describes an error
+type errorObject struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// eventWriter writes the data-only server-sent events of the OpenAI API,
+// flushing after each one
+type eventWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventWriter starts an event stream on w
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	return &eventWriter{w: w, flusher: flusher}
+}
+
+// send writes data encoded as JSON as one event
+func (e *eventWriter) send(data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(errorBody{Error: errorObject{Message: fmt.Sprintf("failed to encode chunk: %v", err), Type: "server_error"}})
+	}
+	e.write(payload)
+}
+
+// done ends the stream as the OpenAI API does
+func (e *eventWriter) done() {
+	e.write([]byte("[DONE]"))
+}
+
+// write writes one event with payload as its data
+func (e *eventWriter) write(payload []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.w, "data: %s\n\n", payload)
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+}
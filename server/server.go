@@ -0,0 +1,547 @@
+// Package server exposes registered flows as HTTP endpoints, so agents can run
+// as services instead of CLIs.
+//
+//	GET  /flows             lists the registered flows
+//	POST /flows/{name}/run  runs a flow on the JSON state in the body
+//	GET  /runs/{id}/events  streams the events of a run over a websocket
+//
+// A run answers with the final action and state as JSON, or, when the request
+// accepts text/event-stream, streams progress as server-sent events. Runs
+// given a run ID also send their events, token deltas and approval requests
+// to the websocket clients of that ID. Run and Resume start runs for other
+// transports, such as the gRPC service in grpcserver.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/migrate"
+)
+
+// Config configures a Server
+type Config struct {
+	MaxBodyBytes      int64           // Largest accepted state; 0 uses 1 MiB
+	MaxConcurrentRuns int             // Runs in progress before new ones are rejected with 503; 0 is unlimited
+	Checkpoints       CheckpointStore // Where runs with a checkpoint ID save their progress; nil disables checkpoints
+	RunRetention      time.Duration   // How long websocket clients can still read a finished run's events; 0 uses 1 minute
+
+	// CheckOrigin accepts websocket connections by their request, e.g. to let
+	// a frontend served from another origin connect; nil accepts only
+	// requests without an Origin header or from the server's own host
+	CheckOrigin func(r *http.Request) bool
+}
+
+// DefaultConfig accepts states up to 1 MiB and any number of concurrent runs
+func DefaultConfig() *Config {
+	return &Config{MaxBodyBytes: 1 << 20, RunRetention: time.Minute}
+}
+
+var (
+	// ErrUnknownFlow is returned when running a flow that is not registered
+	ErrUnknownFlow = errors.New("unknown flow")
+	// ErrInvalidState is returned when a state does not decode into the flow's state type
+	ErrInvalidState = errors.New("invalid state")
+	// ErrBusy is returned when MaxConcurrentRuns runs are already in progress
+	ErrBusy = errors.New("too many runs in progress")
+)
+
+// EmitFunc sends a custom event to the client of a streaming run
+type EmitFunc func(event string, data any)
+
+// Emitter is implemented by states whose nodes report their own progress events.
+// The server sets the emitter before the run; outside of streaming runs it
+// discards events.
+type Emitter interface {
+	SetEmitter(emit EmitFunc)
+}
+
+// RunResult is the response of a non-streaming run and the data of the "done" event
+type RunResult struct {
+	Flow     string           `json:"flow"`
+	RunID    string           `json:"run_id,omitempty"`
+	Action   core.Action      `json:"action"`
+	State    any              `json:"state"`
+	Steps    []core.StepEvent `json:"steps"`
+	Duration time.Duration    `json:"duration"`
+}
+
+// RunOptions configures a run started with Run or Resume
+type RunOptions struct {
+	CheckpointID string            // Saves a checkpoint under this ID after each step; requires Config.Checkpoints
+	RunID        string            // Streams the run's events to the websocket clients of this ID
+	Observe      core.StepObserver // Called after each step
+	Emit         EmitFunc          // Receives the events of Emitter states
+}
+
+// stepFunc is called after each step with the state as it is after the step
+type stepFunc func(event core.StepEvent, state any)
+
+// runner runs one registered flow on a request body, skipping the steps whose
+// actions are in resume; nodes receive ctx as the context of the run
+type runner interface {
+	run(ctx context.Context, body []byte, resume []core.Action, step stepFunc, emit EmitFunc) (core.Action, any, error)
+	migrations() *migrate.Migrations
+}
+
+// RegisterOption configures a flow registered with Register
+type RegisterOption func(*registration)
+
+// registration holds the options of a registered flow
+type registration struct {
+	migrations *migrate.Migrations
+}
+
+// WithMigrations versions a flow. Its checkpoints record migrations.Current(),
+// and Resume upgrades the state and actions of checkpoints saved by older
+// versions before continuing them.
+func WithMigrations(migrations *migrate.Migrations) RegisterOption {
+	return func(r *registration) {
+		r.migrations = migrations
+	}
+}
+
+// Server routes requests to registered flows
+type Server struct {
+	config   *Config
+	mux      *http.ServeMux
+	slots    chan struct{}
+	upgrader websocket.Upgrader
+
+	mu    sync.RWMutex
+	flows map[string]runner
+
+	runsMu sync.Mutex
+	runs   map[string]*liveRun
+}
+
+// New creates a server; a nil config uses DefaultConfig
+func New(config *Config) *Server {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 1 << 20
+	}
+	if config.RunRetention <= 0 {
+		config.RunRetention = time.Minute
+	}
+
+	s := &Server{
+		config:   config,
+		mux:      http.NewServeMux(),
+		upgrader: websocket.Upgrader{CheckOrigin: config.CheckOrigin},
+		flows:    make(map[string]runner),
+		runs:     make(map[string]*liveRun),
+	}
+	if config.MaxConcurrentRuns > 0 {
+		s.slots = make(chan struct{}, config.MaxConcurrentRuns)
+	}
+	s.mux.HandleFunc("GET /flows", s.handleList)
+	s.mux.HandleFunc("POST /flows/{name}/run", s.handleRun)
+	s.mux.HandleFunc("GET /runs/{id}/events", s.handleEvents)
+	return s
+}
+
+// Register makes flow available as name. Each run decodes the request body into a
+// state from newState, or into a zero S when newState is nil; pointer states are
+// allocated. The flow is shared between concurrent runs, so its nodes must keep
+// per-run data in the state.
+func Register[S any](s *Server, name string, flow core.Workflow[S], newState func() S, options ...RegisterOption) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid flow name %q", name)
+	}
+	if flow == nil {
+		return fmt.Errorf("flow %s cannot be nil", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.flows[name]; exists {
+		return fmt.Errorf("flow %s is already registered", name)
+	}
+	var r registration
+	for _, option := range options {
+		option(&r)
+	}
+	s.flows[name] = &flowRunner[S]{flow: flow, newState: newState, versions: r.migrations}
+	return nil
+}
+
+// Flows returns the registered flow names in order
+func (s *Server) Flows() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.flows))
+	for name := range s.flows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run runs the named flow on a JSON state, as POST /flows/{name}/run does
+func (s *Server) Run(ctx context.Context, name string, state []byte, options RunOptions) (RunResult, error) {
+	flow, err := s.flow(name)
+	if err != nil {
+		return RunResult{}, err
+	}
+	release, err := s.acquire()
+	if err != nil {
+		return RunResult{}, err
+	}
+	defer release()
+	return s.execute(ctx, name, flow, state, nil, options)
+}
+
+// Resume continues a run from its checkpoint, after the last step it finished.
+// The run keeps saving checkpoints under the same ID. Resuming a run that
+// finished returns its last action and state without running any step.
+func (s *Server) Resume(ctx context.Context, checkpointID string, options RunOptions) (RunResult, error) {
+	checkpoint, err := s.Checkpoint(ctx, checkpointID)
+	if err != nil {
+		return RunResult{}, err
+	}
+	flow, err := s.flow(checkpoint.Flow)
+	if err != nil {
+		return RunResult{}, err
+	}
+	release, err := s.acquire()
+	if err != nil {
+		return RunResult{}, err
+	}
+	defer release()
+
+	state, actions, err := upgrade(flow.migrations(), checkpoint)
+	if err != nil {
+		return RunResult{}, fmt.Errorf("failed to migrate checkpoint %s: %w", checkpoint.ID, err)
+	}
+	options.CheckpointID = checkpoint.ID
+	return s.execute(ctx, checkpoint.Flow, flow, state, actions, options)
+}
+
+// upgrade migrates a checkpoint saved by another version of its flow
+func upgrade(migrations *migrate.Migrations, checkpoint Checkpoint) ([]byte, []core.Action, error) {
+	if migrations == nil {
+		if checkpoint.Version != 0 {
+			return nil, nil, fmt.Errorf("%w: version %d, the flow is not versioned", migrate.ErrNewerVersion, checkpoint.Version)
+		}
+		return checkpoint.State, checkpoint.Actions, nil
+	}
+	return migrations.Migrate(checkpoint.Version, checkpoint.State, checkpoint.Actions)
+}
+
+// Checkpoint returns the checkpoint saved under id
+func (s *Server) Checkpoint(ctx context.Context, id string) (Checkpoint, error) {
+	if s.config.Checkpoints == nil {
+		return Checkpoint{}, ErrNoCheckpoints
+	}
+	return s.config.Checkpoints.LoadCheckpoint(ctx, id)
+}
+
+// flow returns the runner registered as name
+func (s *Server) flow(name string) (runner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flow, ok := s.flows[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownFlow, name)
+	}
+	return flow, nil
+}
+
+// acquire takes a run slot; release gives it back
+func (s *Server) acquire() (release func(), err error) {
+	if s.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, nil
+	default:
+		return nil, ErrBusy
+	}
+}
+
+// execute runs flow on state after the steps in resume and, for runs with a
+// run ID, publishes the events of the run to its websocket clients
+func (s *Server) execute(ctx context.Context, name string, flow runner, state []byte, resume []core.Action, options RunOptions) (RunResult, error) {
+	if options.CheckpointID != "" && s.config.Checkpoints == nil {
+		return RunResult{}, ErrNoCheckpoints
+	}
+	if options.RunID == "" {
+		return s.executeRun(ctx, name, flow, state, resume, options)
+	}
+
+	run, err := s.startRun(options.RunID)
+	if err != nil {
+		return RunResult{}, err
+	}
+	defer s.finishRun(run)
+	run.publish("start", map[string]string{"flow": name, "run_id": run.id})
+	observe, emit := options.Observe, options.Emit
+	options.Observe = func(event core.StepEvent) {
+		run.publish("step", event)
+		if observe != nil {
+			observe(event)
+		}
+	}
+	options.Emit = func(event string, data any) {
+		run.publish(event, data)
+		if emit != nil {
+			emit(event, data)
+		}
+	}
+
+	result, err := s.executeRun(context.WithValue(ctx, runKey{}, run), name, flow, state, resume, options)
+	if err != nil {
+		run.publish("error", map[string]string{"error": err.Error()})
+		return RunResult{}, err
+	}
+	run.publish("done", result)
+	return result, nil
+}
+
+// executeRun runs flow on state after the steps in resume, collecting the
+// steps of the run and saving a checkpoint before the first step and after
+// each one
+func (s *Server) executeRun(ctx context.Context, name string, flow runner, state []byte, resume []core.Action, options RunOptions) (RunResult, error) {
+
+	checkpoint := Checkpoint{ID: options.CheckpointID, Flow: name, Actions: slices.Clone(resume), State: state}
+	if migrations := flow.migrations(); migrations != nil {
+		checkpoint.Version = migrations.Current()
+	}
+	var saveErr error
+	save := func() {
+		if checkpoint.ID != "" && saveErr == nil {
+			checkpoint.UpdatedAt = time.Now()
+			saveErr = s.config.Checkpoints.SaveCheckpoint(ctx, checkpoint)
+		}
+	}
+	save()
+
+	started := time.Now()
+	var steps []core.StepEvent
+	// Runs outlive the request that started them, as before contexts reached
+	// the nodes; the context only carries the run's values, such as its run ID
+	action, result, err := flow.run(context.WithoutCancel(ctx), state, resume, func(event core.StepEvent, state any) {
+		steps = append(steps, event)
+		if checkpoint.ID != "" && saveErr == nil {
+			checkpoint.Actions = append(checkpoint.Actions, event.Action)
+			if checkpoint.State, saveErr = json.Marshal(state); saveErr == nil {
+				save()
+			}
+		}
+		if options.Observe != nil {
+			options.Observe(event)
+		}
+	}, options.Emit)
+	if err != nil {
+		return RunResult{}, err
+	}
+	if saveErr != nil {
+		return RunResult{}, fmt.Errorf("failed to save checkpoint %s: %w", checkpoint.ID, saveErr)
+	}
+	return RunResult{
+		Flow:     name,
+		RunID:    options.RunID,
+		Action:   action,
+		State:    result,
+		Steps:    steps,
+		Duration: time.Since(started),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleList answers GET /flows
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"flows": s.Flows()})
+}
+
+// handleRun answers POST /flows/{name}/run
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	flow, err := s.flow(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	body, err := readBody(w, r, s.config.MaxBodyBytes)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	release, err := s.acquire()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer release()
+
+	runID := r.URL.Query().Get("run_id")
+	if streaming(r) {
+		s.stream(r.Context(), w, name, flow, body, runID)
+		return
+	}
+
+	result, err := s.execute(r.Context(), name, flow, body, nil, RunOptions{RunID: runID})
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// stream runs the flow and reports progress as server-sent events: "start",
+// "step" after each step, custom events from an Emitter state, and finally
+// "done" with the RunResult or "error"
+func (s *Server) stream(ctx context.Context, w http.ResponseWriter, name string, flow runner, body []byte, runID string) {
+	events := newEventWriter(w)
+	events.send("start", map[string]string{"flow": name})
+
+	result, err := s.execute(ctx, name, flow, body, nil, RunOptions{
+		RunID:   runID,
+		Observe: func(event core.StepEvent) { events.send("step", event) },
+		Emit:    events.send,
+	})
+	if err != nil {
+		events.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	events.send("done", result)
+}
+
+// flowRunner decodes states of type S and runs a flow on them
+type flowRunner[S any] struct {
+	flow     core.Workflow[S]
+	newState func() S
+	versions *migrate.Migrations
+}
+
+// migrations returns the migrations of a versioned flow, or nil
+func (f *flowRunner[S]) migrations() *migrate.Migrations {
+	return f.versions
+}
+
+// run decodes the state, runs the flow and returns the final state
+func (f *flowRunner[S]) run(ctx context.Context, body []byte, resume []core.Action, step stepFunc, emit EmitFunc) (action core.Action, result any, err error) {
+	state := f.state()
+	if len(strings.TrimSpace(string(body))) > 0 {
+		target := any(&state)
+		if reflect.ValueOf(state).Kind() == reflect.Pointer {
+			target = state
+		}
+		if err := json.Unmarshal(body, target); err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidState, err)
+		}
+	}
+
+	if emit == nil {
+		emit = func(string, any) {}
+	}
+	if emitter, ok := any(state).(Emitter); ok {
+		emitter.SetEmitter(emit)
+	} else if emitter, ok := any(&state).(Emitter); ok {
+		emitter.SetEmitter(emit)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("flow panicked: %v", recovered)
+		}
+	}()
+
+	observe := func(event core.StepEvent) { step(event, state) }
+	if flow, ok := f.flow.(*core.Flow[S]); ok {
+		action, _, err = flow.ResumeContext(ctx, &state, resume, observe)
+		if err != nil {
+			return "", nil, err
+		}
+	} else if len(resume) > 0 {
+		// Other workflows run as a single step, so the run had finished
+		if len(resume) > 1 {
+			return "", nil, fmt.Errorf("cannot resume after step %d of a single-step workflow", len(resume))
+		}
+		action = resume[0]
+	} else {
+		started := time.Now()
+		action = f.flow.Run(&state)
+		observe(core.StepEvent{Step: 1, Name: core.WorkflowName(f.flow), Action: action, Duration: time.Since(started)})
+	}
+	return action, state, nil
+}
+
+// state returns a new state, allocating pointer states
+func (f *flowRunner[S]) state() S {
+	if f.newState != nil {
+		return f.newState()
+	}
+	var state S
+	if t := reflect.TypeOf(state); t == nil {
+		return state
+	} else if t.Kind() == reflect.Pointer {
+		state = reflect.New(t.Elem()).Interface().(S)
+	}
+	return state
+}
+
+// streaming reports whether the client asked for server-sent events
+func streaming(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream") || r.URL.Query().Get("stream") == "true"
+}
+
+// statusOf maps run errors to HTTP status codes
+func statusOf(err error) int {
+	if errors.Is(err, ErrInvalidState) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, ErrRunIDInUse) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// readBody reads at most limit bytes of the request body
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return body, nil
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
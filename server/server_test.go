@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/internal/flowtest"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/migrate"
+)
+
+// counterState counts in the flowtest flow, streaming the count as events
+type counterState struct {
+	flowtest.Counter
+}
+
+func (s *counterState) SetEmitter(emit EmitFunc) { s.Emit = emit }
+
+func newTestServer(t *testing.T, config *Config) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(newCounterServer(t, config, &flowtest.IncrementNode[*counterState]{}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newCounterServer(t *testing.T, config *Config, node *flowtest.IncrementNode[*counterState]) *Server {
+	t.Helper()
+	srv := New(config)
+	if err := Register[*counterState](srv, "counter", flowtest.NewFlow(node), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := Register[*counterState](srv, "counter", flowtest.NewFlow(node), nil); err == nil {
+		t.Error("expected an error when registering a name twice")
+	}
+	return srv
+}
+
+func TestServer_Run(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	response, err := http.Post(server.URL+"/flows/counter/run", "application/json", strings.NewReader(`{"count": 1, "limit": 3}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		Action core.Action      `json:"action"`
+		State  counterState     `json:"state"`
+		Steps  []core.StepEvent `json:"steps"`
+	}
+	json.NewDecoder(response.Body).Decode(&result)
+	if response.StatusCode != http.StatusOK || result.Action != core.ActionSuccess || result.State.Count != 3 {
+		t.Fatalf("status %d, result %+v", response.StatusCode, result)
+	}
+	if len(result.Steps) != 2 || result.Steps[0].Name != "IncrementNode" || result.Steps[0].Action != core.ActionContinue {
+		t.Errorf("steps = %+v", result.Steps)
+	}
+}
+
+func TestServer_Stream(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/flows/counter/run", strings.NewReader(`{"limit": 2}`))
+	request.Header.Set("Accept", "text/event-stream")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", contentType)
+	}
+
+	var events []string
+	var done string
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok && events[len(events)-1] == "done" {
+			done = data
+		}
+	}
+
+	want := "start count step count step done"
+	if got := strings.Join(events, " "); got != want {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if !strings.Contains(done, `"action":"success"`) || !strings.Contains(done, `"count":2`) {
+		t.Errorf("done event = %s", done)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	server := newTestServer(t, &Config{MaxBodyBytes: 64})
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"unknown flow", "/flows/missing/run", `{}`, http.StatusNotFound},
+		{"invalid state", "/flows/counter/run", `{"count": "many"}`, http.StatusBadRequest},
+		{"state too large", "/flows/counter/run", `{"limit": 1, "padding": "` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := http.Post(server.URL+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.status)
+			}
+		})
+	}
+
+	response, err := http.Get(server.URL + "/flows")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+	var list struct{ Flows []string }
+	json.NewDecoder(response.Body).Decode(&list)
+	if len(list.Flows) != 1 || list.Flows[0] != "counter" {
+		t.Errorf("flows = %+v", list)
+	}
+}
+
+func TestServer_ResumeCheckpoint(t *testing.T) {
+	sessions, err := memory.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore failed: %v", err)
+	}
+	node := &flowtest.IncrementNode[*counterState]{CrashAt: 3}
+	srv := newCounterServer(t, &Config{Checkpoints: NewSessionCheckpointStore(sessions)}, node)
+	ctx := context.Background()
+
+	if _, err := srv.Run(ctx, "counter", []byte(`{"limit": 4}`), RunOptions{CheckpointID: "run-1"}); err == nil {
+		t.Fatal("expected the interrupted run to fail")
+	}
+
+	node.CrashAt = 0
+	var resumed []core.StepEvent
+	result, err := srv.Resume(ctx, "run-1", RunOptions{Observe: func(event core.StepEvent) {
+		resumed = append(resumed, event)
+	}})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if state := result.State.(*counterState); result.Action != core.ActionSuccess || state.Count != 4 {
+		t.Errorf("Resume() = %s with count %d", result.Action, state.Count)
+	}
+	if len(resumed) != 2 || resumed[0].Step != 3 {
+		t.Errorf("resumed steps = %+v, expected steps 3 and 4", resumed)
+	}
+
+	result, err = srv.Resume(ctx, "run-1", RunOptions{})
+	if err != nil || len(result.Steps) != 0 || result.State.(*counterState).Count != 4 {
+		t.Errorf("resuming a finished run = %+v, %v", result, err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"unknown checkpoint", func() error { _, err := srv.Resume(ctx, "missing", RunOptions{}); return err }, ErrCheckpointNotFound},
+		{"unknown flow", func() error { _, err := srv.Run(ctx, "missing", nil, RunOptions{}); return err }, ErrUnknownFlow},
+		{"no checkpoint store", func() error {
+			_, err := newCounterServer(t, nil, &flowtest.IncrementNode[*counterState]{}).Run(ctx, "counter", nil, RunOptions{CheckpointID: "run-2"})
+			return err
+		}, ErrNoCheckpoints},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_ResumeMigratedCheckpoint(t *testing.T) {
+	sessions, err := memory.NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore failed: %v", err)
+	}
+	checkpoints := NewSessionCheckpointStore(sessions)
+	ctx := context.Background()
+
+	// Version 1 renamed the state's "max" field to "limit"
+	migrations, err := migrate.New(1, migrate.Migration{From: 0, State: migrate.RenameField("max", "limit")})
+	if err != nil {
+		t.Fatalf("migrate.New failed: %v", err)
+	}
+	srv := New(&Config{Checkpoints: checkpoints})
+	if err := Register[*counterState](srv, "counter", flowtest.NewFlow(&flowtest.IncrementNode[*counterState]{}), nil, WithMigrations(migrations)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Saved by the unversioned flow after one step
+	old := Checkpoint{ID: "old", Flow: "counter", Actions: []core.Action{core.ActionContinue}, State: json.RawMessage(`{"count": 1, "max": 3}`)}
+	if err := checkpoints.SaveCheckpoint(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	result, err := srv.Resume(ctx, "old", RunOptions{})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if state := result.State.(*counterState); state.Count != 3 || len(result.Steps) != 2 {
+		t.Errorf("Resume() = count %d after %d steps, want 3 after 2", state.Count, len(result.Steps))
+	}
+	if saved, _ := srv.Checkpoint(ctx, "old"); saved.Version != 1 {
+		t.Errorf("checkpoint version = %d, want 1", saved.Version)
+	}
+
+	newer := Checkpoint{ID: "newer", Flow: "counter", State: json.RawMessage(`{"count": 1}`), Version: 2}
+	checkpoints.SaveCheckpoint(ctx, newer)
+	if _, err := srv.Resume(ctx, "newer", RunOptions{}); !errors.Is(err, migrate.ErrNewerVersion) {
+		t.Errorf("Resume of a newer checkpoint = %v, want ErrNewerVersion", err)
+	}
+}
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+)
+
+// Events of runs with a run ID, besides "start", "step", "done", "error" and
+// the events of Emitter states
+const (
+	// EventToken is the event nodes emit with a TokenDelta for each chunk of a
+	// streamed LLM response
+	EventToken = "token"
+	// EventApproval carries a nodes.ApprovalRequest that a client of the run
+	// answers with an ApprovalMessage
+	EventApproval = "approval"
+	// EventApprovalResponse carries the nodes.ApprovalResponse a request was
+	// answered with, so every client of the run can close its prompt
+	EventApprovalResponse = "approval_response"
+)
+
+var (
+	// ErrRunIDInUse is returned when starting a run under the ID of a run in
+	// progress or of a finished run whose events are still kept
+	ErrRunIDInUse = errors.New("run ID is in use")
+	// ErrUnknownRun is returned when answering for a run that is not in progress
+	ErrUnknownRun = errors.New("unknown run")
+	// ErrUnknownApproval is returned when answering a request the run is not waiting for
+	ErrUnknownApproval = errors.New("unknown approval request")
+	// ErrNoRunID is returned by the Approvals source outside of a run with a run ID
+	ErrNoRunID = errors.New("approval requested outside of a run with a run ID")
+)
+
+// TokenDelta is the data of EventToken events
+type TokenDelta struct {
+	Node  string `json:"node,omitempty"`
+	Delta string `json:"delta"`
+}
+
+// RunEvent is a message the websocket clients of a run receive. IDs count the
+// events of the run from 1; replies to a client's own messages have ID 0.
+type RunEvent struct {
+	ID    int             `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// ApprovalMessage is the message websocket clients send to answer an
+// EventApproval request
+type ApprovalMessage struct {
+	Type     string                 `json:"type"` // "approval"
+	Response nodes.ApprovalResponse `json:"response"`
+}
+
+// runKey is the context key of the live run of a flow run
+type runKey struct{}
+
+// RunIDFrom returns the run ID of the run ctx belongs to, or "" when the run
+// has none
+func RunIDFrom(ctx context.Context) string {
+	if run, ok := ctx.Value(runKey{}).(*liveRun); ok {
+		return run.id
+	}
+	return ""
+}
+
+// liveRun keeps the events of a run with a run ID for its websocket clients,
+// and the approval requests it waits for
+type liveRun struct {
+	id string
+
+	mu          sync.Mutex
+	events      []RunEvent
+	changed     chan struct{} // Closed and replaced on each event
+	started     bool
+	finished    bool
+	subscribers int
+	approvals   map[string]chan nodes.ApprovalResponse
+}
+
+// newLiveRun creates a run that has not started
+func newLiveRun(id string) *liveRun {
+	return &liveRun{id: id, changed: make(chan struct{}), approvals: make(map[string]chan nodes.ApprovalResponse)}
+}
+
+// publish adds an event with data encoded as JSON and wakes the clients
+func (r *liveRun) publish(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("failed to encode %s event: %v", event, err)})
+		event = "error"
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, RunEvent{ID: len(r.events) + 1, Event: event, Data: payload})
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// since returns the events after the first n, a channel closed on the next
+// event, and whether the run has finished
+func (r *liveRun) since(n int) ([]RunEvent, <-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[n:], r.changed, r.finished
+}
+
+// finish marks the run finished and wakes the clients
+func (r *liveRun) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = true
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// requestApproval publishes request and waits for a client's answer
+func (r *liveRun) requestApproval(ctx context.Context, request nodes.ApprovalRequest) (nodes.ApprovalResponse, error) {
+	answer := make(chan nodes.ApprovalResponse, 1)
+	r.mu.Lock()
+	r.approvals[request.ID] = answer
+	r.mu.Unlock()
+	r.publish(EventApproval, request)
+
+	select {
+	case response := <-answer:
+		r.publish(EventApprovalResponse, response)
+		return response, nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		delete(r.approvals, request.ID)
+		r.mu.Unlock()
+		return nodes.ApprovalResponse{}, ctx.Err()
+	}
+}
+
+// answer passes response to the request it answers
+func (r *liveRun) answer(response nodes.ApprovalResponse) error {
+	if err := response.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	answer, ok := r.approvals[response.RequestID]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownApproval, response.RequestID)
+	}
+	delete(r.approvals, response.RequestID)
+	answer <- response
+	return nil
+}
+
+// Approvals returns an approval source for the HumanApprovalNodes of
+// registered flows. It sends each request to the websocket clients of the
+// run as an EventApproval event and waits for one of them, or Approve, to
+// answer. Runs without a run ID fail their requests with ErrNoRunID.
+func (s *Server) Approvals() nodes.ApprovalSource {
+	return nodes.ApprovalSourceFunc(func(ctx context.Context, request nodes.ApprovalRequest) (nodes.ApprovalResponse, error) {
+		run, ok := ctx.Value(runKey{}).(*liveRun)
+		if !ok {
+			return nodes.ApprovalResponse{}, ErrNoRunID
+		}
+		return run.requestApproval(ctx, request)
+	})
+}
+
+// Approve answers an approval request of the run in progress under runID, as
+// a websocket client's ApprovalMessage does
+func (s *Server) Approve(runID string, response nodes.ApprovalResponse) error {
+	s.runsMu.Lock()
+	run, ok := s.runs[runID]
+	ok = ok && run.started
+	s.runsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownRun, runID)
+	}
+	return run.answer(response)
+}
+
+// startRun starts the live run of id, which clients may have subscribed to
+// before it started
+func (s *Server) startRun(id string) (*liveRun, error) {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		run = newLiveRun(id)
+		s.runs[id] = run
+	}
+	if run.started {
+		return nil, fmt.Errorf("%w: %s", ErrRunIDInUse, id)
+	}
+	run.started = true
+	return run, nil
+}
+
+// finishRun ends a live run and forgets it once its events are no longer kept
+func (s *Server) finishRun(run *liveRun) {
+	run.finish()
+	time.AfterFunc(s.config.RunRetention, func() {
+		s.runsMu.Lock()
+		defer s.runsMu.Unlock()
+		if s.runs[run.id] == run {
+			delete(s.runs, run.id)
+		}
+	})
+}
+
+// subscribe returns the live run of id, creating it when it has not started
+func (s *Server) subscribe(id string) *liveRun {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		run = newLiveRun(id)
+		s.runs[id] = run
+	}
+	run.subscribers++
+	return run
+}
+
+// unsubscribe forgets a run nobody started once its last client left
+func (s *Server) unsubscribe(run *liveRun) {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+	run.subscribers--
+	if !run.started && run.subscribers == 0 && s.runs[run.id] == run {
+		delete(s.runs, run.id)
+	}
+}
+
+// handleEvents answers GET /runs/{id}/events by upgrading to a websocket that
+// sends the events of the run, from its first one, and receives answers to
+// its approval requests. The server closes the websocket after the last
+// event of a finished run.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has answered the request
+		return
+	}
+	defer conn.Close()
+	run := s.subscribe(r.PathValue("id"))
+	defer s.unsubscribe(run)
+
+	replies := make(chan RunEvent, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var message ApprovalMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				var syntaxErr *json.SyntaxError
+				var typeErr *json.UnmarshalTypeError
+				if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+					return
+				}
+				message.Type = ""
+			}
+			if err := s.receive(run, message); err != nil {
+				data, _ := json.Marshal(map[string]string{"error": err.Error()})
+				select {
+				case replies <- RunEvent{Event: "error", Data: data}:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}
+	}()
+
+	sent := 0
+	for {
+		events, changed, finished := run.since(sent)
+		for _, event := range events {
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+		sent += len(events)
+		if finished {
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "run finished"), time.Now().Add(time.Second))
+			return
+		}
+		select {
+		case <-changed:
+		case reply := <-replies:
+			if err := conn.WriteJSON(reply); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// receive handles a message of a websocket client of run
+func (s *Server) receive(run *liveRun, message ApprovalMessage) error {
+	if message.Type != "approval" {
+		return fmt.Errorf("unknown message type %q", message.Type)
+	}
+	return s.Approve(run.id, message.Response)
+}
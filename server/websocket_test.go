@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+)
+
+type approvalState struct {
+	Response nodes.ApprovalResponse `json:"response"`
+}
+
+func (s *approvalState) GetApprovalRequest() (nodes.ApprovalRequest, bool) {
+	return nodes.ApprovalRequest{ID: "deploy-1", Kind: "deploy", Title: "Deploy to production?"}, true
+}
+
+func (s *approvalState) SetApprovalResponse(response nodes.ApprovalResponse) { s.Response = response }
+
+// dialEvents connects to the events of run id
+func dialEvents(t *testing.T, url, id string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/runs/"+id+"/events", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readEvents reads events until the server closes the websocket
+func readEvents(t *testing.T, conn *websocket.Conn) []RunEvent {
+	t.Helper()
+	var events []RunEvent
+	for {
+		var event RunEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatalf("ReadJSON failed: %v", err)
+			}
+			return events
+		}
+		events = append(events, event)
+	}
+}
+
+// eventNames returns the names of events
+func eventNames(events []RunEvent) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Event
+	}
+	return names
+}
+
+func TestServer_RunEvents(t *testing.T) {
+	server := newTestServer(t, nil)
+	early := dialEvents(t, server.URL, "run-1")
+
+	response, err := http.Post(server.URL+"/flows/counter/run?run_id=run-1", "application/json", strings.NewReader(`{"limit": 2}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	var result RunResult
+	json.NewDecoder(response.Body).Decode(&result)
+	response.Body.Close()
+	if result.RunID != "run-1" || result.Action != core.ActionSuccess {
+		t.Fatalf("result = %+v", result)
+	}
+
+	want := []string{"start", "count", "step", "count", "step", "done"}
+	// A client connecting after the run finished gets the same events
+	late := dialEvents(t, server.URL, "run-1")
+	for name, conn := range map[string]*websocket.Conn{"early": early, "late": late} {
+		events := readEvents(t, conn)
+		if names := eventNames(events); !reflect.DeepEqual(names, want) {
+			t.Errorf("%s client got events %v, want %v", name, names, want)
+		}
+		if len(events) > 0 && events[len(events)-1].ID != len(want) {
+			t.Errorf("%s client: last event ID = %d", name, events[len(events)-1].ID)
+		}
+	}
+
+	response, err = http.Post(server.URL+"/flows/counter/run?run_id=run-1", "application/json", strings.NewReader(`{"limit": 1}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("run with a kept run ID: status = %d, want %d", response.StatusCode, http.StatusConflict)
+	}
+}
+
+func TestServer_Approvals(t *testing.T) {
+	srv := New(nil)
+	node := core.NewNode[*approvalState](nodes.NewHumanApprovalNode[*approvalState](srv.Approvals(), nil), 0, 1)
+	if err := Register[*approvalState](srv, "deploy", core.NewFlow[*approvalState](node), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	server := httptest.NewServer(srv)
+	defer server.Close()
+	conn := dialEvents(t, server.URL, "run-2")
+
+	done := make(chan RunResult)
+	go func() {
+		result, err := srv.Run(context.Background(), "deploy", nil, RunOptions{RunID: "run-2"})
+		if err != nil {
+			t.Errorf("Run failed: %v", err)
+		}
+		done <- result
+	}()
+
+	for {
+		var event RunEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("ReadJSON failed: %v", err)
+		}
+		if event.Event == EventApproval {
+			break
+		}
+	}
+	answer := func(response nodes.ApprovalResponse) RunEvent {
+		t.Helper()
+		if err := conn.WriteJSON(ApprovalMessage{Type: "approval", Response: response}); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+		var event RunEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("ReadJSON failed: %v", err)
+		}
+		return event
+	}
+	if event := answer(nodes.ApprovalResponse{RequestID: "other", Decision: nodes.DecisionApprove}); event.Event != "error" || event.ID != 0 {
+		t.Errorf("answer to an unknown request: %s %s, want an error", event.Event, event.Data)
+	}
+	if event := answer(nodes.ApprovalResponse{RequestID: "deploy-1", Decision: nodes.DecisionApprove, Approver: "ada"}); event.Event != EventApprovalResponse {
+		t.Errorf("answer: %s %s, want %s", event.Event, event.Data, EventApprovalResponse)
+	}
+
+	result := <-done
+	if result.Action != nodes.ActionApprove || result.State.(*approvalState).Response.Approver != "ada" {
+		t.Errorf("result = %+v, want approved by ada", result)
+	}
+	if err := srv.Approve("run-2", nodes.ApprovalResponse{RequestID: "deploy-1", Decision: nodes.DecisionApprove}); !errors.Is(err, ErrUnknownApproval) {
+		t.Errorf("Approve() after the run error = %v, want ErrUnknownApproval", err)
+	}
+	if err := srv.Approve("missing", nodes.ApprovalResponse{Decision: nodes.DecisionApprove}); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("Approve() of an unknown run error = %v, want ErrUnknownRun", err)
+	}
+
+	// Without a run ID nobody can answer, so the node falls back to rejecting
+	result, err := srv.Run(context.Background(), "deploy", nil, RunOptions{})
+	if err != nil || result.Action != nodes.ActionReject {
+		t.Errorf("Run() without a run ID = %+v, %v, want rejected", result, err)
+	}
+}
@@ -0,0 +1,310 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/server"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// CheckpointStore keeps server checkpoints in a Store, so runs of a
+// server.Server or worker.Worker can resume on another process
+type CheckpointStore struct {
+	store Store
+}
+
+// NewCheckpointStore creates a server.CheckpointStore backed by store
+func NewCheckpointStore(store Store) *CheckpointStore {
+	return &CheckpointStore{store: store}
+}
+
+// SaveCheckpoint stores the checkpoint under its ID
+func (s *CheckpointStore) SaveCheckpoint(ctx context.Context, checkpoint server.Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	return s.store.Put(ctx, Record{Kind: KindCheckpoints, Key: checkpoint.ID, Value: data, UpdatedAt: checkpoint.UpdatedAt})
+}
+
+// LoadCheckpoint reads the checkpoint saved under id
+func (s *CheckpointStore) LoadCheckpoint(ctx context.Context, id string) (server.Checkpoint, error) {
+	record, err := s.store.Get(ctx, KindCheckpoints, id)
+	if errors.Is(err, ErrNotFound) {
+		return server.Checkpoint{}, fmt.Errorf("%w: %s", server.ErrCheckpointNotFound, id)
+	}
+	if err != nil {
+		return server.Checkpoint{}, err
+	}
+
+	var checkpoint server.Checkpoint
+	if err := json.Unmarshal(record.Value, &checkpoint); err != nil {
+		return server.Checkpoint{}, fmt.Errorf("failed to decode checkpoint %s: %w", id, err)
+	}
+	return checkpoint, nil
+}
+
+// DeleteCheckpoint removes a checkpoint, e.g. once its run has finished
+func (s *CheckpointStore) DeleteCheckpoint(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, KindCheckpoints, id)
+}
+
+// SessionStore keeps memory sessions in a Store
+type SessionStore struct {
+	store Store
+}
+
+// NewSessionStore creates a memory.SessionStore backed by store
+func NewSessionStore(store Store) *SessionStore {
+	return &SessionStore{store: store}
+}
+
+// SaveSession inserts or replaces the session
+func (s *SessionStore) SaveSession(ctx context.Context, session memory.Session) error {
+	if session.ID == "" {
+		return fmt.Errorf("session ID cannot be empty")
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	return s.store.Put(ctx, Record{Kind: KindSessions, Key: session.ID, Value: data, UpdatedAt: session.UpdatedAt})
+}
+
+// LoadSession reads a saved session
+func (s *SessionStore) LoadSession(ctx context.Context, id string) (memory.Session, error) {
+	record, err := s.store.Get(ctx, KindSessions, id)
+	if errors.Is(err, ErrNotFound) {
+		return memory.Session{}, fmt.Errorf("%w: %s", memory.ErrSessionNotFound, id)
+	}
+	if err != nil {
+		return memory.Session{}, err
+	}
+
+	var session memory.Session
+	if err := json.Unmarshal(record.Value, &session); err != nil {
+		return memory.Session{}, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	return session, nil
+}
+
+// ListSessions returns the saved session IDs, most recently updated first
+func (s *SessionStore) ListSessions(ctx context.Context) ([]string, error) {
+	records, err := s.store.List(ctx, KindSessions, 0)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.Key
+	}
+	return ids, nil
+}
+
+// DeleteSession removes a saved session
+func (s *SessionStore) DeleteSession(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, KindSessions, id)
+}
+
+// PermissionStore keeps the tools a user allowed for the rest of a session,
+// so a restarted service does not ask for them again
+type PermissionStore struct {
+	store Store
+}
+
+// NewPermissionStore creates a permission store backed by store
+func NewPermissionStore(store Store) *PermissionStore {
+	return &PermissionStore{store: store}
+}
+
+// Save records the tools manager no longer asks approval for
+func (s *PermissionStore) Save(ctx context.Context, sessionID string, manager *tools.ToolManager) error {
+	data, err := json.Marshal(manager.AlwaysAllowedTools())
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, Record{Kind: KindPermissions, Key: sessionID, Value: data})
+}
+
+// Load returns the allowed tools of a session; a session without saved
+// permissions has none
+func (s *PermissionStore) Load(ctx context.Context, sessionID string) ([]string, error) {
+	record, err := s.store.Get(ctx, KindPermissions, sessionID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(record.Value, &names); err != nil {
+		return nil, fmt.Errorf("failed to decode permissions of session %s: %w", sessionID, err)
+	}
+	return names, nil
+}
+
+// Restore allows the saved tools of a session on manager
+func (s *PermissionStore) Restore(ctx context.Context, sessionID string, manager *tools.ToolManager) error {
+	names, err := s.Load(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		manager.AlwaysAllow(name)
+	}
+	return nil
+}
+
+// Run is a finished flow run
+type Run struct {
+	ID         string           `json:"id"`
+	Flow       string           `json:"flow"`
+	Action     core.Action      `json:"action,omitempty"` // Final action; empty when the run failed
+	Steps      []core.StepEvent `json:"steps,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// RunHistory records finished runs for auditing and dashboards
+type RunHistory struct {
+	store Store
+}
+
+// NewRunHistory creates a run history backed by store
+func NewRunHistory(store Store) *RunHistory {
+	return &RunHistory{store: store}
+}
+
+// Record saves a run under its ID
+func (h *RunHistory) Record(ctx context.Context, run Run) error {
+	if run.ID == "" {
+		return fmt.Errorf("run ID cannot be empty")
+	}
+	if run.FinishedAt.IsZero() {
+		run.FinishedAt = time.Now()
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	return h.store.Put(ctx, Record{Kind: KindRuns, Key: run.ID, Value: data, UpdatedAt: run.FinishedAt})
+}
+
+// RecordResult saves the outcome of server.Run or server.Resume
+func (h *RunHistory) RecordResult(ctx context.Context, id, flow string, result server.RunResult, runErr error) error {
+	run := Run{ID: id, Flow: flow, Action: result.Action, Steps: result.Steps, FinishedAt: time.Now()}
+	run.StartedAt = run.FinishedAt.Add(-result.Duration)
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	return h.Record(ctx, run)
+}
+
+// Run returns a recorded run; unknown IDs return ErrNotFound
+func (h *RunHistory) Run(ctx context.Context, id string) (Run, error) {
+	record, err := h.store.Get(ctx, KindRuns, id)
+	if err != nil {
+		return Run{}, err
+	}
+	var run Run
+	if err := json.Unmarshal(record.Value, &run); err != nil {
+		return Run{}, fmt.Errorf("failed to decode run %s: %w", id, err)
+	}
+	return run, nil
+}
+
+// Runs returns up to limit runs, most recently finished first
+func (h *RunHistory) Runs(ctx context.Context, limit int) ([]Run, error) {
+	records, err := h.store.List(ctx, KindRuns, limit)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(records))
+	for _, record := range records {
+		var run Run
+		if err := json.Unmarshal(record.Value, &run); err != nil {
+			return nil, fmt.Errorf("failed to decode run %s: %w", record.Key, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// BatchQueue keeps the items of resumable batches in a Store, one record per
+// item under the kind "batch:<name>", so a batch node can resume on another
+// process
+type BatchQueue struct {
+	store Store
+}
+
+// NewBatchQueue creates a nodes.BatchQueue backed by store
+func NewBatchQueue(store Store) *BatchQueue {
+	return &BatchQueue{store: store}
+}
+
+// Load returns the entries of a batch by index
+func (q *BatchQueue) Load(ctx context.Context, batch string) ([]nodes.BatchEntry, error) {
+	records, err := q.store.List(ctx, batchKind(batch), 0)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]nodes.BatchEntry, len(records))
+	for i, record := range records {
+		if err := json.Unmarshal(record.Value, &entries[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode item %s of batch %s: %w", record.Key, batch, err)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Index < entries[j].Index })
+	return entries, nil
+}
+
+// Enqueue replaces the entries of a batch
+func (q *BatchQueue) Enqueue(ctx context.Context, batch string, entries []nodes.BatchEntry) error {
+	if err := q.Remove(ctx, batch); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := q.Update(ctx, batch, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update stores an entry under its index
+func (q *BatchQueue) Update(ctx context.Context, batch string, entry nodes.BatchEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode item %d of batch %s: %w", entry.Index, batch, err)
+	}
+	return q.store.Put(ctx, Record{Kind: batchKind(batch), Key: strconv.Itoa(entry.Index), Value: data, UpdatedAt: entry.UpdatedAt})
+}
+
+// Remove deletes the entries of a batch
+func (q *BatchQueue) Remove(ctx context.Context, batch string) error {
+	records, err := q.store.List(ctx, batchKind(batch), 0)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := q.store.Delete(ctx, batchKind(batch), record.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchKind returns the kind of the records of a batch
+func batchKind(batch string) Kind {
+	return Kind("batch:" + batch)
+}
@@ -0,0 +1,168 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/core/nodes"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/memory"
+	"github.com/alt-coder/pocketflow-go/server"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+type countState struct {
+	Count int `json:"count"`
+}
+
+// countNode adds one to the count until it reaches three
+type countNode struct{}
+
+func (countNode) Prep(state **countState) []int { return []int{(*state).Count} }
+func (countNode) Exec(count int) (int, error)   { return count + 1, nil }
+func (countNode) ExecFallback(err error) int    { return 0 }
+func (countNode) Post(state **countState, _ []int, results ...int) core.Action {
+	(*state).Count = results[0]
+	if (*state).Count >= 3 {
+		return core.ActionSuccess
+	}
+	return core.ActionContinue
+}
+
+func TestCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	node := core.NewNode[*countState](countNode{}, 0, 1)
+	node.AddSuccessor(node, core.ActionContinue)
+	config := server.DefaultConfig()
+	config.Checkpoints = NewCheckpointStore(store)
+	srv := server.New(config)
+	if err := server.Register[*countState](srv, "count", core.NewFlow[*countState](node), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	result, err := srv.Run(ctx, "count", []byte(`{"count": 0}`), server.RunOptions{CheckpointID: "job-1"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	checkpoint, err := srv.Checkpoint(ctx, "job-1")
+	if err != nil || checkpoint.Flow != "count" || len(checkpoint.Actions) != 3 {
+		t.Fatalf("Checkpoint() = %+v, %v", checkpoint, err)
+	}
+	if _, err := srv.Checkpoint(ctx, "job-2"); !errors.Is(err, server.ErrCheckpointNotFound) {
+		t.Errorf("Checkpoint of an unknown run = %v, want ErrCheckpointNotFound", err)
+	}
+
+	history := NewRunHistory(store)
+	if err := history.RecordResult(ctx, "job-1", "count", result, nil); err != nil {
+		t.Fatalf("RecordResult failed: %v", err)
+	}
+	history.Record(ctx, Run{ID: "job-0", Flow: "count", Error: "boom", FinishedAt: time.Now().Add(-time.Hour)})
+	runs, err := history.Runs(ctx, 0)
+	if err != nil || len(runs) != 2 || runs[0].ID != "job-1" || runs[0].Action != core.ActionSuccess || len(runs[0].Steps) != 3 {
+		t.Errorf("Runs() = %+v, %v", runs, err)
+	}
+	if run, err := history.Run(ctx, "job-0"); err != nil || run.Error != "boom" {
+		t.Errorf("Run(job-0) = %+v, %v", run, err)
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+	sessions := NewSessionStore(NewMemoryStore())
+	history := memory.NewInMemoryStore()
+	history.Append(ctx, llm.Message{Role: llm.RoleUser, Content: "hello"})
+
+	for i, id := range []string{"older", "newer"} {
+		session, err := memory.NewSession(ctx, id, history, map[string]int{"turn": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.UpdatedAt = time.Date(2024, 1, 1, i, 0, 0, 0, time.UTC)
+		if err := sessions.SaveSession(ctx, session); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	ids, err := sessions.ListSessions(ctx)
+	if err != nil || fmt.Sprint(ids) != "[newer older]" {
+		t.Errorf("ListSessions() = %v, %v", ids, err)
+	}
+	session, err := sessions.LoadSession(ctx, "newer")
+	if err != nil || len(session.Messages) != 1 || string(session.State) != `{"turn":1}` {
+		t.Errorf("LoadSession() = %+v, %v", session, err)
+	}
+	sessions.DeleteSession(ctx, "newer")
+	if _, err := sessions.LoadSession(ctx, "newer"); !errors.Is(err, memory.ErrSessionNotFound) {
+		t.Errorf("LoadSession after delete = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestPermissionStore(t *testing.T) {
+	ctx := context.Background()
+	permissions := NewPermissionStore(NewMemoryStore())
+	manager := tools.NewToolManager()
+	manager.AlwaysAllow("search")
+	manager.AlwaysAllow("read_file")
+	if err := permissions.Save(ctx, "session-1", manager); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	restored := tools.NewToolManager()
+	if err := permissions.Restore(ctx, "session-1", restored); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got, _ := json.Marshal(restored.AlwaysAllowedTools()); string(got) != `["read_file","search"]` {
+		t.Errorf("restored tools = %s", got)
+	}
+	if names, err := permissions.Load(ctx, "session-2"); err != nil || names != nil {
+		t.Errorf("Load of a session without permissions = %v, %v", names, err)
+	}
+}
+
+func TestBatchQueue(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	queue := NewBatchQueue(store)
+
+	entries := make([]nodes.BatchEntry, 12)
+	for i := range entries {
+		entries[i] = nodes.BatchEntry{Index: i, Item: json.RawMessage(fmt.Sprintf(`"doc-%d"`, i)), Status: nodes.BatchPending}
+	}
+	if err := queue.Enqueue(ctx, "extract", entries); err != nil {
+		t.Fatal(err)
+	}
+	done := entries[10]
+	done.Status, done.Result = nodes.BatchDone, json.RawMessage(`{"total":3}`)
+	if err := queue.Update(ctx, "extract", done); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := queue.Load(ctx, "extract")
+	if err != nil || len(loaded) != 12 {
+		t.Fatalf("Load() = %d entries, %v", len(loaded), err)
+	}
+	for i, entry := range loaded {
+		if entry.Index != i {
+			t.Fatalf("entry %d has index %d", i, entry.Index)
+		}
+	}
+	if loaded[10].Status != nodes.BatchDone || string(loaded[10].Result) != `{"total":3}` {
+		t.Errorf("updated entry = %+v", loaded[10])
+	}
+	if other, _ := queue.Load(ctx, "other"); len(other) != 0 {
+		t.Errorf("unknown batch has %d entries", len(other))
+	}
+
+	if err := queue.Remove(ctx, "extract"); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := store.List(ctx, batchKind("extract"), 0); len(records) != 0 {
+		t.Errorf("%d records left after Remove", len(records))
+	}
+}
@@ -0,0 +1,127 @@
+package statestore
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/resp"
+)
+
+// RedisConfig configures a RedisStore
+type RedisConfig struct {
+	Addr        string        // host:port; default "localhost:6379"
+	Username    string        // ACL user; empty authenticates with the password only
+	Password    string        // Sent with AUTH when set
+	DB          int           // Database selected after connecting
+	TLS         *tls.Config   // Connects with TLS when set
+	Prefix      string        // Prefix of the keys the store uses; default "statestore"
+	DialTimeout time.Duration // Default 10s
+}
+
+// RedisStore keeps each record in a hash at <prefix>:<kind>:<key> and indexes
+// the keys of a kind by update time in a sorted set at <prefix>:<kind>
+type RedisStore struct {
+	client *resp.Client
+	prefix string
+}
+
+// NewRedisStore creates a store; it connects when first used
+func NewRedisStore(config RedisConfig) *RedisStore {
+	if config.Prefix == "" {
+		config.Prefix = "statestore"
+	}
+	return &RedisStore{
+		client: resp.NewClient(resp.Config{
+			Addr:        config.Addr,
+			Username:    config.Username,
+			Password:    config.Password,
+			DB:          config.DB,
+			TLS:         config.TLS,
+			DialTimeout: config.DialTimeout,
+		}),
+		prefix: config.Prefix,
+	}
+}
+
+// Put inserts or replaces a record
+func (s *RedisStore) Put(ctx context.Context, record Record) error {
+	record = stamp(record)
+	updated := strconv.FormatInt(record.UpdatedAt.UnixNano(), 10)
+	if _, err := s.client.Do(ctx, "HSET", s.key(record.Kind, record.Key), "value", string(record.Value), "updated_at", updated); err != nil {
+		return fmt.Errorf("failed to save %s/%s: %w", record.Kind, record.Key, err)
+	}
+	// Microseconds keep the score exact in the sorted set's float64
+	score := strconv.FormatInt(record.UpdatedAt.UnixMicro(), 10)
+	if _, err := s.client.Do(ctx, "ZADD", s.index(record.Kind), score, record.Key); err != nil {
+		return fmt.Errorf("failed to index %s/%s: %w", record.Kind, record.Key, err)
+	}
+	return nil
+}
+
+// Get returns a saved record
+func (s *RedisStore) Get(ctx context.Context, kind Kind, key string) (Record, error) {
+	reply, err := s.client.Do(ctx, "HMGET", s.key(kind, key), "value", "updated_at")
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to load %s/%s: %w", kind, key, err)
+	}
+	fields, _ := reply.([]any)
+	if len(fields) != 2 || fields[0] == nil {
+		return Record{}, notFound(kind, key)
+	}
+	value, _ := fields[0].(string)
+	updated, _ := fields[1].(string)
+	nanos, _ := strconv.ParseInt(updated, 10, 64)
+	return Record{Kind: kind, Key: key, Value: []byte(value), UpdatedAt: time.Unix(0, nanos).UTC()}, nil
+}
+
+// List returns the records of a kind, most recently updated first
+func (s *RedisStore) List(ctx context.Context, kind Kind, limit int) ([]Record, error) {
+	reply, err := s.client.Do(ctx, "ZREVRANGE", s.index(kind), "0", strconv.Itoa(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+	keys, _ := reply.([]any)
+	records := make([]Record, 0, len(keys))
+	for _, item := range keys {
+		key, _ := item.(string)
+		record, err := s.Get(ctx, kind, key)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted since the index was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+// Delete removes a record
+func (s *RedisStore) Delete(ctx context.Context, kind Kind, key string) error {
+	if _, err := s.client.Do(ctx, "DEL", s.key(kind, key)); err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %w", kind, key, err)
+	}
+	if _, err := s.client.Do(ctx, "ZREM", s.index(kind), key); err != nil {
+		return fmt.Errorf("failed to unindex %s/%s: %w", kind, key, err)
+	}
+	return nil
+}
+
+// Close closes the idle connections
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) index(kind Kind) string {
+	return s.prefix + ":" + string(kind)
+}
+
+func (s *RedisStore) key(kind Kind, key string) string {
+	return s.prefix + ":" + string(kind) + ":" + key
+}
@@ -0,0 +1,158 @@
+package statestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is the SQL database an SQLStore talks to
+type Dialect string
+
+// Supported dialects
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// SQLConfig configures an SQLStore
+type SQLConfig struct {
+	Dialect Dialect // Default SQLite
+	Table   string  // Default "statestore_records"
+}
+
+// SQLStore keeps records in a SQLite or PostgreSQL table, one row per record
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+}
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// NewSQLStore creates the table in db if needed. The caller opens db with a
+// driver of their choice, e.g. github.com/mattn/go-sqlite3 or
+// github.com/jackc/pgx/v5/stdlib, and owns closing it.
+func NewSQLStore(ctx context.Context, db *sql.DB, config SQLConfig) (*SQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database cannot be nil")
+	}
+	if config.Dialect == "" {
+		config.Dialect = SQLite
+	}
+	if config.Dialect != SQLite && config.Dialect != Postgres {
+		return nil, fmt.Errorf("unsupported SQL dialect %q", config.Dialect)
+	}
+	if config.Table == "" {
+		config.Table = "statestore_records"
+	}
+	if !tableName.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid table name %q", config.Table)
+	}
+
+	blob := "BLOB"
+	if config.Dialect == Postgres {
+		blob = "BYTEA"
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		kind TEXT NOT NULL,
+		record_key TEXT NOT NULL,
+		value %s NOT NULL,
+		updated_at BIGINT NOT NULL,
+		PRIMARY KEY (kind, record_key)
+	)`, config.Table, blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %w", config.Table, err)
+	}
+	return &SQLStore{db: db, dialect: config.Dialect, table: config.Table}, nil
+}
+
+// Put inserts or replaces a record
+func (s *SQLStore) Put(ctx context.Context, record Record) error {
+	record = stamp(record)
+	if record.Value == nil {
+		record.Value = []byte{}
+	}
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO %s (kind, record_key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, record_key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`),
+		string(record.Kind), record.Key, record.Value, record.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save %s/%s: %w", record.Kind, record.Key, err)
+	}
+	return nil
+}
+
+// Get returns a saved record
+func (s *SQLStore) Get(ctx context.Context, kind Kind, key string) (Record, error) {
+	record := Record{Kind: kind, Key: key}
+	var updated int64
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT value, updated_at FROM %s WHERE kind = ? AND record_key = ?`),
+		string(kind), key).Scan(&record.Value, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, notFound(kind, key)
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to load %s/%s: %w", kind, key, err)
+	}
+	record.UpdatedAt = time.Unix(0, updated).UTC()
+	return record, nil
+}
+
+// List returns the records of a kind, most recently updated first
+func (s *SQLStore) List(ctx context.Context, kind Kind, limit int) ([]Record, error) {
+	query := `SELECT record_key, value, updated_at FROM %s WHERE kind = ? ORDER BY updated_at DESC, record_key`
+	args := []any{string(kind)}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, s.query(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", kind, err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		record := Record{Kind: kind}
+		var updated int64
+		if err := rows.Scan(&record.Key, &record.Value, &updated); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+		}
+		record.UpdatedAt = time.Unix(0, updated).UTC()
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// Delete removes a record
+func (s *SQLStore) Delete(ctx context.Context, kind Kind, key string) error {
+	if _, err := s.db.ExecContext(ctx, s.query(`DELETE FROM %s WHERE kind = ? AND record_key = ?`), string(kind), key); err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %w", kind, key, err)
+	}
+	return nil
+}
+
+// query fills in the table name and, for PostgreSQL, numbers the placeholders
+func (s *SQLStore) query(format string) string {
+	query := fmt.Sprintf(format, s.table)
+	if s.dialect != Postgres {
+		return query
+	}
+	var numbered strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			numbered.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		numbered.WriteRune(r)
+	}
+	return numbered.String()
+}
@@ -0,0 +1,135 @@
+// Package statestore persists the state of long-lived agent services, such as
+// flow checkpoints, sessions, tool permissions and run history, behind one
+// small interface with SQLite, Postgres, Redis and in-memory backends. Adapters
+// plug a Store into the checkpointing of the server and worker packages and
+// into memory's session handling.
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when getting a record that was never saved
+var ErrNotFound = errors.New("record not found")
+
+// Kind names a collection of records
+type Kind string
+
+// Kinds used by the adapters of this package
+const (
+	KindCheckpoints Kind = "checkpoints"
+	KindSessions    Kind = "sessions"
+	KindPermissions Kind = "permissions"
+	KindRuns        Kind = "runs"
+)
+
+// Record is a value saved under a kind and key
+type Record struct {
+	Kind      Kind      `json:"kind"`
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store saves records by kind and key
+type Store interface {
+	// Put inserts or replaces a record; a zero UpdatedAt is set to the current time
+	Put(ctx context.Context, record Record) error
+	// Get returns ErrNotFound for unknown keys
+	Get(ctx context.Context, kind Kind, key string) (Record, error)
+	// List returns up to limit records of a kind, most recently updated first;
+	// a limit of zero or less returns all of them
+	List(ctx context.Context, kind Kind, limit int) ([]Record, error)
+	// Delete removes a record; deleting an unknown key is not an error
+	Delete(ctx context.Context, kind Kind, key string) error
+}
+
+// MemoryStore keeps records in memory, for tests and single-process services
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[Kind]map[string]Record
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[Kind]map[string]Record)}
+}
+
+// Put inserts or replaces a record
+func (s *MemoryStore) Put(ctx context.Context, record Record) error {
+	record = stamp(record)
+	record.Value = append([]byte(nil), record.Value...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records[record.Kind] == nil {
+		s.records[record.Kind] = make(map[string]Record)
+	}
+	s.records[record.Kind][record.Key] = record
+	return nil
+}
+
+// Get returns a saved record
+func (s *MemoryStore) Get(ctx context.Context, kind Kind, key string) (Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[kind][key]
+	if !ok {
+		return Record{}, notFound(kind, key)
+	}
+	record.Value = append([]byte(nil), record.Value...)
+	return record, nil
+}
+
+// List returns the records of a kind, most recently updated first
+func (s *MemoryStore) List(ctx context.Context, kind Kind, limit int) ([]Record, error) {
+	s.mu.RLock()
+	records := make([]Record, 0, len(s.records[kind]))
+	for _, record := range s.records[kind] {
+		record.Value = append([]byte(nil), record.Value...)
+		records = append(records, record)
+	}
+	s.mu.RUnlock()
+
+	sortRecords(records)
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// Delete removes a record
+func (s *MemoryStore) Delete(ctx context.Context, kind Kind, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records[kind], key)
+	return nil
+}
+
+// stamp sets a missing update time to now and keeps times in UTC
+func stamp(record Record) Record {
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = time.Now()
+	}
+	record.UpdatedAt = record.UpdatedAt.UTC()
+	return record
+}
+
+// sortRecords orders records most recently updated first, then by key
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].UpdatedAt.Equal(records[j].UpdatedAt) {
+			return records[i].UpdatedAt.After(records[j].UpdatedAt)
+		}
+		return records[i].Key < records[j].Key
+	})
+}
+
+// notFound wraps ErrNotFound with the record's kind and key
+func notFound(kind Kind, key string) error {
+	return fmt.Errorf("%w: %s/%s", ErrNotFound, kind, key)
+}
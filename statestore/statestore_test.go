@@ -0,0 +1,215 @@
+package statestore
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/resp"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testStores returns a store of each backend that can run here
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(RedisConfig{Addr: newFakeRedis(t)}),
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// Every connection to :memory: is a separate database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Logf("sqlite3 driver unavailable: %v", err)
+		return stores
+	}
+	sqlite, err := NewSQLStore(context.Background(), db, SQLConfig{})
+	if err != nil {
+		t.Fatalf("NewSQLStore failed: %v", err)
+	}
+	stores["sqlite"] = sqlite
+	return stores
+}
+
+func TestStore(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i, key := range []string{"a", "b", "c"} {
+				record := Record{Kind: KindSessions, Key: key, Value: []byte(`{"n":` + strconv.Itoa(i) + `}`), UpdatedAt: base.Add(time.Duration(i) * time.Minute)}
+				if err := store.Put(ctx, record); err != nil {
+					t.Fatalf("Put(%s) failed: %v", key, err)
+				}
+			}
+			store.Put(ctx, Record{Kind: KindRuns, Key: "a", Value: []byte("run")})
+
+			record, err := store.Get(ctx, KindSessions, "b")
+			if err != nil || string(record.Value) != `{"n":1}` || !record.UpdatedAt.Equal(base.Add(time.Minute)) {
+				t.Errorf("Get(b) = %+v, %v", record, err)
+			}
+			if _, err := store.Get(ctx, KindSessions, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+			}
+
+			// Replacing a record moves it to the front
+			store.Put(ctx, Record{Kind: KindSessions, Key: "a", Value: []byte(`{"n":9}`), UpdatedAt: base.Add(time.Hour)})
+			records, err := store.List(ctx, KindSessions, 0)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if got := keys(records); fmt.Sprint(got) != "[a c b]" || string(records[0].Value) != `{"n":9}` {
+				t.Errorf("List() = %v", got)
+			}
+			if records, _ := store.List(ctx, KindSessions, 2); fmt.Sprint(keys(records)) != "[a c]" {
+				t.Errorf("List(2) = %v", keys(records))
+			}
+
+			if err := store.Delete(ctx, KindSessions, "c"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if err := store.Delete(ctx, KindSessions, "never-saved"); err != nil {
+				t.Errorf("Delete of an unknown key = %v", err)
+			}
+			if records, _ := store.List(ctx, KindSessions, 0); fmt.Sprint(keys(records)) != "[a b]" {
+				t.Errorf("List() after Delete = %v", keys(records))
+			}
+			if record, err := store.Get(ctx, KindRuns, "a"); err != nil || string(record.Value) != "run" || record.UpdatedAt.IsZero() {
+				t.Errorf("kinds should not share keys: %+v, %v", record, err)
+			}
+		})
+	}
+}
+
+func TestSQLStore_Postgres(t *testing.T) {
+	store := &SQLStore{dialect: Postgres, table: "records"}
+	got := store.query(`SELECT value FROM %s WHERE kind = ? AND record_key = ?`)
+	if want := `SELECT value FROM records WHERE kind = $1 AND record_key = $2`; got != want {
+		t.Errorf("query() = %q, want %q", got, want)
+	}
+	if _, err := NewSQLStore(context.Background(), &sql.DB{}, SQLConfig{Table: "records; DROP TABLE users"}); err == nil {
+		t.Error("expected an invalid table name to be rejected")
+	}
+}
+
+func keys(records []Record) []string {
+	keys := make([]string, len(records))
+	for i, record := range records {
+		keys[i] = record.Key
+	}
+	return keys
+}
+
+// newFakeRedis serves the hash and sorted set commands RedisStore uses and
+// returns its address
+func newFakeRedis(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	hashes := make(map[string]map[string]string)
+	sets := make(map[string]map[string]float64)
+	handle := func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch args[0] {
+		case "HSET":
+			if hashes[args[1]] == nil {
+				hashes[args[1]] = make(map[string]string)
+			}
+			for i := 2; i+1 < len(args); i += 2 {
+				hashes[args[1]][args[i]] = args[i+1]
+			}
+			return ":1\r\n"
+		case "HMGET":
+			reply := fmt.Sprintf("*%d\r\n", len(args)-2)
+			for _, field := range args[2:] {
+				value, ok := hashes[args[1]][field]
+				if !ok {
+					reply += "$-1\r\n"
+					continue
+				}
+				reply += fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			}
+			return reply
+		case "DEL":
+			delete(hashes, args[1])
+			return ":1\r\n"
+		case "ZADD":
+			if sets[args[1]] == nil {
+				sets[args[1]] = make(map[string]float64)
+			}
+			score, _ := strconv.ParseFloat(args[2], 64)
+			sets[args[1]][args[3]] = score
+			return ":1\r\n"
+		case "ZREM":
+			delete(sets[args[1]], args[2])
+			return ":1\r\n"
+		case "ZREVRANGE":
+			var members []string
+			for member := range sets[args[1]] {
+				members = append(members, member)
+			}
+			set := sets[args[1]]
+			sort.Slice(members, func(i, j int) bool {
+				if set[members[i]] != set[members[j]] {
+					return set[members[i]] > set[members[j]]
+				}
+				return members[i] > members[j]
+			})
+			stop, _ := strconv.Atoi(args[3])
+			if stop >= 0 && stop+1 < len(members) {
+				members = members[:stop+1]
+			}
+			reply := fmt.Sprintf("*%d\r\n", len(members))
+			for _, member := range members {
+				reply += fmt.Sprintf("$%d\r\n%s\r\n", len(member), member)
+			}
+			return reply
+		}
+		return "-ERR unknown command\r\n"
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					reply, err := resp.ReadReply(reader)
+					if err != nil {
+						return
+					}
+					items := reply.([]any)
+					args := make([]string, len(items))
+					for i, item := range items {
+						args[i] = item.(string)
+					}
+					fmt.Fprint(conn, handle(args))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
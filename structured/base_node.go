@@ -2,8 +2,10 @@ package structured
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/alt-coder/pocketflow-go/llm"
@@ -12,6 +14,9 @@ import (
 // StructuredConfig holds common configuration options
 type StructuredConfig struct {
 	*Config
+
+	// Cache turns on caching of parse results; nil parses every time
+	Cache *CacheConfig
 }
 
 // DefaultBaseConfig returns a default base configuration
@@ -25,7 +30,9 @@ func DefaultBaseConfig() *StructuredConfig {
 type StructuredNode[T any] struct {
 	parser    *Parser
 	validator ValidatorInterface[T]
+	schema    *SchemaValidator[T]
 	config    *StructuredConfig
+	cache     *resultCache
 }
 
 // NewStructuredNode creates a new base node with the specified LLM provider, configuration, and validator
@@ -39,31 +46,67 @@ func NewStructuredNode[T any](provider llm.LLMProvider, config *StructuredConfig
 		return nil, fmt.Errorf("failed to create parser: %w", err)
 	}
 
+	cache, err := newResultCache(config.Cache, provider.GetName())
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache config: %w", err)
+	}
+
 	// Use provided validator or create default no-op validator if nil
 	if validator == nil {
 		validator = NewNoOpValidator[T]()
 	}
 
+	// Declarative constraints from struct tags are enforced when T has a schema;
+	// types with channel, function or complex fields are left to the validator
+	schema, err := NewSchemaValidator[T]()
+	if err != nil && !errors.Is(err, errUnsupportedType) {
+		return nil, fmt.Errorf("failed to create schema validator: %w", err)
+	}
+
 	return &StructuredNode[T]{
 		parser:    parser,
 		validator: validator,
+		schema:    schema,
 		config:    config,
+		cache:     cache,
 	}, nil
 }
 
-// ParseFromFile reads a file and parses its content into the specified type
+// ParseFromFile reads a file, extracts its text with the loader registered for its
+// extension (plain text, HTML, DOCX, PDF or custom) and parses it into the specified type.
+// Image files are sent to the LLM as media. With a cache, results are cached
+// by the content of the file, so a hit does not extract its text.
 func (b *StructuredNode[T]) ParseFromFile(ctx context.Context, filePath string, additionalContext ...string) (ParseResult[T], error) {
-	// Read file content
-	fileBytes, err := os.ReadFile(filePath)
+	if isImageFile(filePath) {
+		return b.ParseFromImageFile(ctx, filePath, additionalContext...)
+	}
+	if b.cache == nil {
+		return b.parseFromFile(ctx, filePath, additionalContext...)
+	}
+
+	digest, err := hashFile(filePath)
 	if err != nil {
 		return ParseResult[T]{
 			Data:  nil,
-			Error: fmt.Errorf("failed to read file %s: %w", filePath, err),
+			Error: err,
+		}, err
+	}
+	key := b.cacheKey("file", additionalContext, []byte(strings.ToLower(filepath.Ext(filePath))), digest)
+	return cached(b.cache, ctx, key, func() (ParseResult[T], error) {
+		return b.parseFromFile(ctx, filePath, additionalContext...)
+	})
+}
+
+// parseFromFile is ParseFromFile for text files, without the cache
+func (b *StructuredNode[T]) parseFromFile(ctx context.Context, filePath string, additionalContext ...string) (ParseResult[T], error) {
+	fileContent, err := LoadFile(ctx, filePath)
+	if err != nil {
+		return ParseResult[T]{
+			Data:  nil,
+			Error: err,
 		}, err
 	}
 
-	// Convert to string and validate content
-	fileContent := strings.TrimSpace(string(fileBytes))
 	if fileContent == "" {
 		err := fmt.Errorf("file %s is empty", filePath)
 		return ParseResult[T]{
@@ -78,6 +121,14 @@ func (b *StructuredNode[T]) ParseFromFile(ctx context.Context, filePath string,
 
 // ParseFromText parses text content into the specified type
 func (b *StructuredNode[T]) ParseFromText(ctx context.Context, textContent string, additionalContext ...string) (ParseResult[T], error) {
+	key := b.cacheKey("text", additionalContext, []byte(textContent))
+	return cached(b.cache, ctx, key, func() (ParseResult[T], error) {
+		return b.parseFromText(ctx, textContent, additionalContext...)
+	})
+}
+
+// parseFromText is ParseFromText without the cache
+func (b *StructuredNode[T]) parseFromText(ctx context.Context, textContent string, additionalContext ...string) (ParseResult[T], error) {
 	if strings.TrimSpace(textContent) == "" {
 		err := fmt.Errorf("text content is empty")
 		return ParseResult[T]{
@@ -89,22 +140,88 @@ func (b *StructuredNode[T]) ParseFromText(ctx context.Context, textContent strin
 	return ParseWithStructuredPrompt[T](b.parser, ctx, textContent, additionalContext...)
 }
 
+// ParseFromTextWithRepair parses text content and re-prompts the LLM with parse and
+// validation errors, making up to MaxRetries+1 attempts
+func (b *StructuredNode[T]) ParseFromTextWithRepair(ctx context.Context, textContent string, additionalContext ...string) (ParseResult[T], error) {
+	key := b.cacheKey("repair", additionalContext, []byte(textContent))
+	return cached(b.cache, ctx, key, func() (ParseResult[T], error) {
+		return b.parseFromTextWithRepair(ctx, textContent, additionalContext...)
+	})
+}
+
+// parseFromTextWithRepair is ParseFromTextWithRepair without the cache
+func (b *StructuredNode[T]) parseFromTextWithRepair(ctx context.Context, textContent string, additionalContext ...string) (ParseResult[T], error) {
+	if strings.TrimSpace(textContent) == "" {
+		err := fmt.Errorf("text content is empty")
+		return ParseResult[T]{
+			Data:  nil,
+			Error: err,
+		}, err
+	}
+
+	fullPrompt := buildStructuredPrompt[T](textContent, additionalContext, b.parser.promptOptions()...)
+	return ParseWithRepair[T](b.parser, ctx, fullPrompt, b, b.config.MaxRetries+1)
+}
+
+// Validate implements ValidatorInterface so the node can validate repair attempts
+func (b *StructuredNode[T]) Validate(data *T) error {
+	_, err := b.ValidateWithWarnings(data)
+	return err
+}
+
+// ValidateWithWarnings implements WarningValidator: schema violations and validator
+// errors are fatal, warnings from a WarningValidator are returned separately
+func (b *StructuredNode[T]) ValidateWithWarnings(data *T) ([]string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("parsed data is nil")
+	}
+
+	if b.schema != nil {
+		if err := b.schema.Validate(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return runValidator(b.validator, data)
+}
+
 // ParseWithCustomPrompt parses using a custom prompt
 func (b *StructuredNode[T]) ParseWithCustomPrompt(ctx context.Context, customPrompt string) (ParseResult[T], error) {
-	return ParseWithPrompt[T](b.parser, ctx, customPrompt)
+	return cached(b.cache, ctx, b.cacheKey("prompt", nil, []byte(customPrompt)), func() (ParseResult[T], error) {
+		return ParseWithPrompt[T](b.parser, ctx, customPrompt)
+	})
 }
 
-// ValidateResult validates the parsed result using the configured validator
+// ParseFromMessages parses the type out of a conversation, following an
+// instruction that can refer to its earlier turns. Its results are not cached.
+func (b *StructuredNode[T]) ParseFromMessages(ctx context.Context, messages []llm.Message, instruction string) (ParseResult[T], error) {
+	return ParseWithMessages[T](b.parser, ctx, messages, instruction)
+}
+
+// ValidateResult validates the parsed result against the struct tag schema and the configured validator
 func (b *StructuredNode[T]) ValidateResult(result ParseResult[T]) error {
+	_, err := b.ValidateAndAnnotate(result)
+	return err
+}
+
+// ValidateAndAnnotate validates the parsed result and returns it with any validator
+// warnings attached. The error is set only for fatal problems.
+func (b *StructuredNode[T]) ValidateAndAnnotate(result ParseResult[T]) (ParseResult[T], error) {
 	if result.Error != nil {
-		return result.Error
+		return result, result.Error
 	}
 
-	if result.Data == nil {
-		return fmt.Errorf("parsed data is nil")
-	}
+	warnings, err := b.ValidateWithWarnings(result.Data)
+	result.Warnings = append(result.Warnings, warnings...)
+	return result, err
+}
 
-	return b.validator.Validate(result.Data)
+// Schema returns the JSON Schema generated for T, or nil if T has none
+func (b *StructuredNode[T]) Schema() *JSONSchema {
+	if b.schema == nil {
+		return nil
+	}
+	return b.schema.Schema()
 }
 
 // CreateFallbackResult creates a fallback result with default values
@@ -127,3 +244,11 @@ func FormatIndexedList(items []string) string {
 	return builder.String()
 }
 
+// cacheKey returns the cache key of parsing input of the given kind into T,
+// or "" without a cache
+func (b *StructuredNode[T]) cacheKey(kind string, additionalContext []string, input ...[]byte) string {
+	if b.cache == nil {
+		return ""
+	}
+	return b.cache.key(kind, reflect.TypeFor[T](), b.parser.promptOptions(), additionalContext, input...)
+}
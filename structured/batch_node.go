@@ -0,0 +1,458 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// BatchInput is one document to parse: a file path or inline text
+type BatchInput struct {
+	ID       string // Identifier used in the report; defaults to the file path
+	FilePath string // File to load with the registered Loader (or send as an image)
+	Text     string // Inline text, used when FilePath is empty
+}
+
+// BatchItemResult is the outcome for one input
+type BatchItemResult[T any] struct {
+	Input    BatchInput
+	Result   ParseResult[T]
+	Started  time.Time
+	Duration time.Duration
+	Skipped  bool // Set when the result was loaded from an earlier run instead of parsed
+}
+
+// Succeeded reports whether the input was parsed and validated
+func (r BatchItemResult[T]) Succeeded() bool {
+	return r.Result.Error == nil && r.Result.Data != nil
+}
+
+// status names the outcome in summaries: "ok", "skipped" or "failed"
+func (r BatchItemResult[T]) status() string {
+	switch {
+	case !r.Succeeded():
+		return "failed"
+	case r.Skipped:
+		return "skipped"
+	default:
+		return "ok"
+	}
+}
+
+// BatchReport aggregates the results of a batch run
+type BatchReport[T any] struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	Skipped   int // Inputs whose results were kept from an earlier run; not counted as succeeded
+	Cached    int // Succeeded inputs whose results were found in the cache, see CacheConfig
+	Items     []BatchItemResult[T]
+	Duration  time.Duration // Wall-clock time of the batch
+
+	// OutputErr is set when a DirectoryBatchNode could not write its summary
+	// or failures file
+	OutputErr error
+}
+
+// newBatchReport aggregates the results of a batch run
+func newBatchReport[T any](results []BatchItemResult[T]) *BatchReport[T] {
+	report := &BatchReport[T]{
+		Total: len(results),
+		Items: results,
+	}
+
+	var first, last time.Time
+	for _, item := range results {
+		if !item.Started.IsZero() && !item.Skipped {
+			if first.IsZero() || item.Started.Before(first) {
+				first = item.Started
+			}
+			if end := item.Started.Add(item.Duration); end.After(last) {
+				last = end
+			}
+		}
+		switch {
+		case !item.Succeeded():
+			report.Failed++
+		case item.Skipped:
+			report.Skipped++
+		default:
+			report.Succeeded++
+			if item.Result.Cached {
+				report.Cached++
+			}
+		}
+	}
+
+	report.Duration = last.Sub(first)
+	return report
+}
+
+// Successes returns the parsed data of every successful item in input order
+func (r *BatchReport[T]) Successes() []*T {
+	var data []*T
+	for _, item := range r.Items {
+		if item.Succeeded() {
+			data = append(data, item.Result.Data)
+		}
+	}
+	return data
+}
+
+// Failures returns the items that could not be parsed
+func (r *BatchReport[T]) Failures() []BatchItemResult[T] {
+	var failures []BatchItemResult[T]
+	for _, item := range r.Items {
+		if !item.Succeeded() {
+			failures = append(failures, item)
+		}
+	}
+	return failures
+}
+
+// Summary returns a human-readable report with one line per failure
+func (r *BatchReport[T]) Summary() string {
+	var builder strings.Builder
+	counts := ""
+	if r.Cached > 0 {
+		counts += fmt.Sprintf(", %d cached", r.Cached)
+	}
+	if r.Skipped > 0 {
+		counts += fmt.Sprintf(", %d skipped", r.Skipped)
+	}
+	builder.WriteString(fmt.Sprintf("Parsed %d/%d inputs (%d failed%s) in %s\n", r.Succeeded, r.Total, r.Failed, counts, r.Duration.Round(time.Millisecond)))
+	for _, item := range r.Failures() {
+		builder.WriteString(fmt.Sprintf("  FAILED %s: %v\n", item.Input.ID, item.Result.Error))
+	}
+	for _, item := range r.Items {
+		for _, warning := range item.Result.Warnings {
+			builder.WriteString(fmt.Sprintf("  WARNING %s: %s\n", item.Input.ID, warning))
+		}
+	}
+	if r.OutputErr != nil {
+		builder.WriteString(fmt.Sprintf("  OUTPUT ERROR: %v\n", r.OutputErr))
+	}
+	return builder.String()
+}
+
+// BatchStateInterface is implemented by workflow states that feed a BatchStructuredNode
+type BatchStateInterface[T any] interface {
+	GetBatchInputs() ([]BatchInput, error)
+	SetBatchReport(report *BatchReport[T])
+}
+
+// BatchState is a ready-made state for batch parsing. Inputs are taken from
+// Inputs plus every file in Directory whose extension is in Extensions (all files if empty).
+type BatchState[T any] struct {
+	Directory  string
+	Extensions []string
+	Inputs     []BatchInput
+	Report     *BatchReport[T]
+}
+
+// GetBatchInputs implements BatchStateInterface
+func (s *BatchState[T]) GetBatchInputs() ([]BatchInput, error) {
+	inputs := append([]BatchInput(nil), s.Inputs...)
+	if s.Directory == "" {
+		return inputs, nil
+	}
+
+	directoryInputs, err := DirectoryInputs(s.Directory, s.Extensions...)
+	if err != nil {
+		return nil, err
+	}
+	return append(inputs, directoryInputs...), nil
+}
+
+// SetBatchReport implements BatchStateInterface
+func (s *BatchState[T]) SetBatchReport(report *BatchReport[T]) {
+	s.Report = report
+}
+
+// DirectoryInputs lists the regular files in a directory, optionally filtered by extension,
+// sorted by name
+func DirectoryInputs(directory string, extensions ...string) ([]BatchInput, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", directory, err)
+	}
+
+	allowed := make(map[string]bool, len(extensions))
+	for _, extension := range extensions {
+		allowed[normalizeExtension(extension)] = true
+	}
+
+	var inputs []BatchInput
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if len(allowed) > 0 && !allowed[normalizeExtension(filepath.Ext(entry.Name()))] {
+			continue
+		}
+		path := filepath.Join(directory, entry.Name())
+		inputs = append(inputs, BatchInput{ID: path, FilePath: path})
+	}
+
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].ID < inputs[j].ID })
+	return inputs, nil
+}
+
+// BatchConfig configures a BatchStructuredNode
+type BatchConfig struct {
+	*StructuredConfig
+	RequestsPerSecond float64  // Maximum LLM requests started per second across workers; 0 disables limiting
+	AdditionalContext []string // Context added to every extraction prompt
+	FailOnAnyError    bool     // Return ActionFailure if any input fails instead of only when all fail
+
+	// Schema extracts a target known only at run time instead of T, which must
+	// then be map[string]any; image inputs are not supported
+	Schema *DynamicSchema
+}
+
+// DefaultBatchConfig returns a default batch configuration
+func DefaultBatchConfig() *BatchConfig {
+	return &BatchConfig{
+		StructuredConfig:  DefaultBaseConfig(),
+		RequestsPerSecond: 2,
+	}
+}
+
+// BatchItem is the prep result for one input
+type BatchItem struct {
+	Input BatchInput
+	Err   error // Set when the inputs could not be listed
+}
+
+// BatchStructuredNode parses many documents into T. Wrap it with core.NewNode to choose
+// the number of concurrent workers; retries happen inside the node through repair
+// prompts, so failures are reported per input instead of failing the whole batch.
+type BatchStructuredNode[T any, S BatchStateInterface[T]] struct {
+	*StructuredNode[T]
+	config  *BatchConfig
+	limiter *rateLimiter
+}
+
+// NewBatchStructuredNode creates a batch parsing node
+func NewBatchStructuredNode[T any, S BatchStateInterface[T]](provider llm.LLMProvider, config *BatchConfig, validator ValidatorInterface[T]) (*BatchStructuredNode[T, S], error) {
+	if config == nil {
+		config = DefaultBatchConfig()
+	}
+	if config.StructuredConfig == nil {
+		config.StructuredConfig = DefaultBaseConfig()
+	}
+	if config.RequestsPerSecond < 0 {
+		return nil, fmt.Errorf("requests per second cannot be negative")
+	}
+
+	if config.Schema != nil {
+		if _, ok := any((*T)(nil)).(*map[string]any); !ok {
+			return nil, fmt.Errorf("a dynamic schema needs map[string]any results, not %s", reflect.TypeFor[T]())
+		}
+	}
+
+	node, err := NewStructuredNode[T](provider, config.StructuredConfig, validator)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BatchStructuredNode[T, S]{
+		StructuredNode: node,
+		config:         config,
+		limiter:        newRateLimiter(config.RequestsPerSecond),
+	}, nil
+}
+
+// Prep collects the inputs from state
+func (b *BatchStructuredNode[T, S]) Prep(state *S) []BatchItem {
+	inputs, err := (*state).GetBatchInputs()
+	if err != nil {
+		return []BatchItem{{Input: BatchInput{ID: "inputs"}, Err: err}}
+	}
+
+	items := make([]BatchItem, len(inputs))
+	for i, input := range inputs {
+		if input.ID == "" {
+			input.ID = input.FilePath
+		}
+		if input.ID == "" {
+			input.ID = fmt.Sprintf("input-%d", i)
+		}
+		items[i] = BatchItem{Input: input}
+	}
+	return items
+}
+
+// DescribeItem implements core.ItemDescriber, so progress updates name the input
+func (b *BatchStructuredNode[T, S]) DescribeItem(item BatchItem) string {
+	return item.Input.ID
+}
+
+// Exec parses one input without the context of the run
+func (b *BatchStructuredNode[T, S]) Exec(item BatchItem) (BatchItemResult[T], error) {
+	return b.ExecContext(context.Background(), item)
+}
+
+// ExecContext parses one input, cancelling the LLM call with ctx; failures are
+// returned in the result rather than as errors
+func (b *BatchStructuredNode[T, S]) ExecContext(ctx context.Context, item BatchItem) (BatchItemResult[T], error) {
+	result := BatchItemResult[T]{Input: item.Input, Started: time.Now()}
+
+	if item.Err != nil {
+		result.Result = ParseResult[T]{Error: item.Err}
+		return result, nil
+	}
+
+	var parsed ParseResult[T]
+	var err error
+	if b.cache == nil {
+		parsed, err = b.parse(ctx, item.Input)
+	} else {
+		var key string
+		if key, err = b.batchCacheKey(item.Input); err == nil {
+			parsed, err = cached(b.cache, ctx, key, func() (ParseResult[T], error) {
+				return b.parse(ctx, item.Input)
+			})
+		}
+	}
+
+	if err != nil {
+		parsed.Data = nil
+		parsed.Error = err
+	}
+	result.Result = parsed
+	result.Duration = time.Since(result.Started)
+	return result, nil
+}
+
+// parse parses and validates an input once the rate limit allows it
+func (b *BatchStructuredNode[T, S]) parse(ctx context.Context, input BatchInput) (ParseResult[T], error) {
+	b.limiter.wait(ctx)
+
+	switch {
+	case b.config.Schema != nil:
+		return b.parseDynamic(ctx, input)
+	case input.FilePath != "" && isImageFile(input.FilePath):
+		image, mimeType, err := readImageFile(input.FilePath)
+		if err != nil {
+			return ParseResult[T]{Error: err}, err
+		}
+		parsed, err := ParseFromImage[T](b.parser, ctx, image, mimeType, b.config.AdditionalContext...)
+		if err != nil {
+			return parsed, err
+		}
+		return b.ValidateAndAnnotate(parsed)
+	case input.FilePath != "":
+		text, err := LoadFile(ctx, input.FilePath)
+		if err != nil {
+			return ParseResult[T]{Error: err}, err
+		}
+		return b.parseFromTextWithRepair(ctx, text, b.config.AdditionalContext...)
+	default:
+		return b.parseFromTextWithRepair(ctx, input.Text, b.config.AdditionalContext...)
+	}
+}
+
+// batchCacheKey returns the cache key of an input: files are keyed by their
+// content, so a hit neither extracts their text nor waits for the rate limit
+func (b *BatchStructuredNode[T, S]) batchCacheKey(input BatchInput) (string, error) {
+	target, kind := reflect.TypeFor[T](), "batch"
+	if b.config.Schema != nil {
+		target, kind = b.config.Schema.Type, "batch:"+b.config.Schema.Name
+	}
+	if input.FilePath == "" {
+		return b.cache.key(kind, target, b.parser.promptOptions(), b.config.AdditionalContext, []byte(input.Text)), nil
+	}
+	digest, err := hashFile(input.FilePath)
+	if err != nil {
+		return "", err
+	}
+	extension := []byte(strings.ToLower(filepath.Ext(input.FilePath)))
+	return b.cache.key(kind, target, b.parser.promptOptions(), b.config.AdditionalContext, extension, digest), nil
+}
+
+// parseDynamic parses an input into the configured dynamic schema
+func (b *BatchStructuredNode[T, S]) parseDynamic(ctx context.Context, input BatchInput) (ParseResult[T], error) {
+	text := input.Text
+	if input.FilePath != "" {
+		if isImageFile(input.FilePath) {
+			err := fmt.Errorf("image inputs are not supported with a dynamic schema")
+			return ParseResult[T]{Error: err}, err
+		}
+		var err error
+		if text, err = LoadFile(ctx, input.FilePath); err != nil {
+			return ParseResult[T]{Error: err}, err
+		}
+	}
+
+	// T is map[string]any, checked by the constructor
+	validator := dynamicValidator{schema: b.config.Schema, next: any(b.StructuredNode).(ValidatorInterface[map[string]any])}
+	parsed, err := parseDynamic(b.parser, ctx, b.config.Schema, text, validator, b.config.MaxRetries+1, b.config.AdditionalContext...)
+	return any(parsed).(ParseResult[T]), err
+}
+
+// ExecFallback records an unexpected execution failure
+func (b *BatchStructuredNode[T, S]) ExecFallback(err error) BatchItemResult[T] {
+	return BatchItemResult[T]{Result: ParseResult[T]{Error: err}}
+}
+
+// Post aggregates the results into a BatchReport and stores it in state
+func (b *BatchStructuredNode[T, S]) Post(state *S, prepRes []BatchItem, execResults ...BatchItemResult[T]) core.Action {
+	report := newBatchReport(execResults)
+	(*state).SetBatchReport(report)
+
+	if report.Failed > 0 && (b.config.FailOnAnyError || report.Succeeded == 0) {
+		return core.ActionFailure
+	}
+	return core.ActionSuccess
+}
+
+// rateLimiter spaces out request starts across concurrent workers
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter creates a limiter for the given rate; zero disables limiting
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may start a request
+func (r *rateLimiter) wait(ctx context.Context) {
+	if r.interval == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	delay := r.next.Sub(now)
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,158 @@
+package structured
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// echoNameProvider answers with the input data as the name field; safe for concurrent use
+type echoNameProvider struct {
+	calls atomic.Int32
+}
+
+func (p *echoNameProvider) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	p.calls.Add(1)
+	content := messages[len(messages)-1].Content
+	if start := strings.Index(content, "```\n"); start != -1 {
+		content = content[start+4:]
+		content = content[:strings.Index(content, "\n```")]
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: "name: " + content + "\ncount: 1\n"}, nil
+}
+
+func (p *echoNameProvider) GetName() string { return "echo" }
+
+func (p *echoNameProvider) SetConfig(config map[string]any) error { return nil }
+
+func TestBatchStructuredNode(t *testing.T) {
+	directory := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "Ada", "b.txt": "Grace", "skip.bin": "x", "empty.txt": ""} {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := DefaultBatchConfig()
+	config.RequestsPerSecond = 0
+	config.MaxRetries = 0
+	node, err := NewBatchStructuredNode[repairTarget, *BatchState[repairTarget]](&echoNameProvider{}, config, nil)
+	if err != nil {
+		t.Fatalf("NewBatchStructuredNode() error = %v", err)
+	}
+
+	state := &BatchState[repairTarget]{
+		Directory:  directory,
+		Extensions: []string{"txt"},
+		Inputs:     []BatchInput{{ID: "inline", Text: "Linus"}},
+	}
+	action := core.NewFlow(core.NewNode(node, 0, 3)).Run(&state)
+	if action != core.ActionSuccess {
+		t.Errorf("expected success with partial failures, got %v", action)
+	}
+
+	report := state.Report
+	if report == nil {
+		t.Fatal("expected report in state")
+	}
+	if report.Total != 4 || report.Succeeded != 3 || report.Failed != 1 {
+		t.Fatalf("unexpected report counts: %s", report.Summary())
+	}
+
+	var names []string
+	for _, data := range report.Successes() {
+		names = append(names, data.Name)
+	}
+	if strings.Join(names, ",") != "Linus,Ada,Grace" {
+		t.Errorf("expected results in input order, got %v", names)
+	}
+	if failure := report.Failures()[0]; !strings.HasSuffix(failure.Input.ID, "empty.txt") {
+		t.Errorf("unexpected failure %+v", failure.Input)
+	}
+	if !strings.Contains(report.Summary(), "Parsed 3/4 inputs") {
+		t.Errorf("unexpected summary %q", report.Summary())
+	}
+}
+
+func TestBatchStructuredNode_AllFailed(t *testing.T) {
+	node, err := NewBatchStructuredNode[repairTarget, *BatchState[repairTarget]](&echoNameProvider{}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewBatchStructuredNode() error = %v", err)
+	}
+
+	state := &BatchState[repairTarget]{Directory: filepath.Join(t.TempDir(), "missing")}
+	if action := core.NewFlow(core.NewNode(node, 0, 1)).Run(&state); action != core.ActionFailure {
+		t.Errorf("expected failure when inputs cannot be listed, got %v", action)
+	}
+	if state.Report.Failed != 1 {
+		t.Errorf("expected the listing error in the report, got %s", state.Report.Summary())
+	}
+}
+
+// blockingProvider answers only when its call is cancelled
+type blockingProvider struct {
+	started   chan struct{}
+	cancelled atomic.Bool
+}
+
+func (p *blockingProvider) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	close(p.started)
+	<-ctx.Done()
+	p.cancelled.Store(true)
+	return llm.Message{}, ctx.Err()
+}
+
+func (p *blockingProvider) GetName() string { return "blocking" }
+
+func (p *blockingProvider) SetConfig(config map[string]any) error { return nil }
+
+func TestBatchStructuredNode_RunCancelled(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{})}
+	config := DefaultBatchConfig()
+	config.RequestsPerSecond = 0
+	config.MaxRetries = 0
+	node, err := NewBatchStructuredNode[repairTarget, *BatchState[repairTarget]](provider, config, nil)
+	if err != nil {
+		t.Fatalf("NewBatchStructuredNode() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-provider.started
+		cancel()
+	}()
+	state := &BatchState[repairTarget]{Inputs: []BatchInput{{ID: "inline", Text: "Ada"}}}
+	done := make(chan core.Action)
+	go func() {
+		action, _ := core.NewFlow(core.NewNode(node, 0, 1)).RunContext(ctx, &state, nil)
+		done <- action
+	}()
+
+	select {
+	case action := <-done:
+		if action != core.ActionCancelled || !provider.cancelled.Load() {
+			t.Errorf("action = %v, provider cancelled = %v; want the LLM call cancelled with the run", action, provider.cancelled.Load())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the LLM call did not see the run cancelled")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(50)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		limiter.wait(context.Background())
+	}
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Errorf("expected requests to be spaced by the limiter, took %v", elapsed)
+	}
+}
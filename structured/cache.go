@@ -0,0 +1,220 @@
+package structured
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// CacheConfig turns on caching of parse results, so parsing the same
+// document with the same schema and model again, e.g. in a repeated pipeline
+// run, returns the earlier result instead of calling the LLM
+type CacheConfig struct {
+	Store CacheStore    // Where results are kept, e.g. NewMemoryCache or NewFileCache
+	TTL   time.Duration // How long a result is used; 0 for as long as the store keeps it
+
+	// Model identifies the model in cache keys, next to the provider's name.
+	// Results are reused only for the same Model, so set it to the model the
+	// provider is configured with.
+	Model string
+}
+
+// CacheEntry is a cached parse result
+type CacheEntry struct {
+	Data     json.RawMessage `json:"data"`
+	Warnings []string        `json:"warnings,omitempty"`
+	Created  time.Time       `json:"created"`
+}
+
+// CacheStore keeps cache entries by key. Stores may drop entries at any time.
+type CacheStore interface {
+	// Load returns the entry of key, reporting false when there is none
+	Load(ctx context.Context, key string) (CacheEntry, bool, error)
+	// Store saves the entry of key, replacing an earlier one
+	Store(ctx context.Context, key string, entry CacheEntry) error
+}
+
+// MemoryCache keeps cache entries in memory, for the lifetime of the process
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// NewMemoryCache creates an empty in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]CacheEntry)}
+}
+
+// Load implements CacheStore
+func (c *MemoryCache) Load(ctx context.Context, key string) (CacheEntry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok, nil
+}
+
+// Store implements CacheStore
+func (c *MemoryCache) Store(ctx context.Context, key string, entry CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+// FileCache keeps cache entries as JSON files in a directory, so they outlive
+// the process
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache creates a cache in dir, creating the directory if needed
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+// Load implements CacheStore
+func (c *FileCache) Load(ctx context.Context, key string) (CacheEntry, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return CacheEntry{}, false, nil
+	}
+	if err != nil {
+		return CacheEntry{}, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A partly written entry is a miss, overwritten by the next Store
+		return CacheEntry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+// Store implements CacheStore. The entry is written to a temporary file and
+// renamed, so concurrent readers never see half of it.
+func (c *FileCache) Store(ctx context.Context, key string, entry CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	file, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of key
+func (c *FileCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// resultCache looks parse results up in the configured store
+type resultCache struct {
+	config   *CacheConfig
+	provider string
+}
+
+// cacheKey returns the hex SHA-256 of the parts, each prefixed with its length
+// so that no two lists of parts hash the same
+func cacheKey(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		binary.Write(hash, binary.BigEndian, uint64(len(part)))
+		hash.Write(part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// key returns the cache key of parsing input of the given kind, e.g. "text"
+// or "file", into target t with the prompt options
+func (c *resultCache) key(kind string, t reflect.Type, options []prompt.Option, additionalContext []string, input ...[]byte) string {
+	parts := [][]byte{
+		[]byte(c.provider),
+		[]byte(c.config.Model),
+		[]byte(kind),
+		[]byte(prompt.GenerateStructuredPromptFor(t, options...)),
+	}
+	for _, context := range additionalContext {
+		parts = append(parts, []byte(context))
+	}
+	return cacheKey(append(parts, input...)...)
+}
+
+// cached returns the result cached under key or, on a miss, parses and
+// caches a successful result. Store errors are ignored: the cache only saves
+// calls and never fails a parse.
+func cached[T any](c *resultCache, ctx context.Context, key string, parse func() (ParseResult[T], error)) (ParseResult[T], error) {
+	if c == nil {
+		return parse()
+	}
+	if entry, ok, err := c.config.Store.Load(ctx, key); err == nil && ok && (c.config.TTL == 0 || time.Since(entry.Created) < c.config.TTL) {
+		data := new(T)
+		if err := json.Unmarshal(entry.Data, data); err == nil {
+			return ParseResult[T]{Data: data, Warnings: entry.Warnings, Cached: true}, nil
+		}
+	}
+
+	result, err := parse()
+	if err != nil || result.Error != nil || result.Data == nil {
+		return result, err
+	}
+	if data, err := json.Marshal(result.Data); err == nil {
+		c.config.Store.Store(ctx, key, CacheEntry{Data: data, Warnings: result.Warnings, Created: time.Now()})
+	}
+	return result, nil
+}
+
+// newResultCache returns the cache of config for provider, or nil when
+// caching is off
+func newResultCache(config *CacheConfig, provider string) (*resultCache, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if config.Store == nil {
+		return nil, fmt.Errorf("cache store cannot be nil")
+	}
+	if config.TTL < 0 {
+		return nil, fmt.Errorf("cache TTL cannot be negative")
+	}
+	return &resultCache{config: config, provider: provider}, nil
+}
+
+// hashFile returns the SHA-256 of the content of a file, so a cached result is
+// found without extracting the file's text
+func hashFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return hash.Sum(nil), nil
+}
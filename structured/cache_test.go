@@ -0,0 +1,140 @@
+package structured
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// newCachedNode creates a node parsing with provider and caching in store
+func newCachedNode(t *testing.T, provider *echoNameProvider, cache *CacheConfig) *StructuredNode[repairTarget] {
+	t.Helper()
+	config := DefaultBaseConfig()
+	config.Cache = cache
+	node, err := NewStructuredNode[repairTarget](provider, config, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredNode() error = %v", err)
+	}
+	return node
+}
+
+func TestStructuredNode_Cache(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCache()
+	provider := &echoNameProvider{}
+	node := newCachedNode(t, provider, &CacheConfig{Store: store, Model: "flash"})
+
+	tests := []struct {
+		name       string
+		parse      func() (ParseResult[repairTarget], error)
+		wantCached bool
+		wantCalls  int32
+	}{
+		{"first parse", func() (ParseResult[repairTarget], error) { return node.ParseFromText(ctx, "Ada") }, false, 1},
+		{"same text", func() (ParseResult[repairTarget], error) { return node.ParseFromText(ctx, "Ada") }, true, 1},
+		{"other text", func() (ParseResult[repairTarget], error) { return node.ParseFromText(ctx, "Grace") }, false, 2},
+		{"other context", func() (ParseResult[repairTarget], error) { return node.ParseFromText(ctx, "Ada", "invoices") }, false, 3},
+		{"other kind", func() (ParseResult[repairTarget], error) { return node.ParseFromTextWithRepair(ctx, "Ada") }, false, 4},
+		{"other model", func() (ParseResult[repairTarget], error) {
+			return newCachedNode(t, provider, &CacheConfig{Store: store, Model: "pro"}).ParseFromText(ctx, "Ada")
+		}, false, 5},
+		{"expired", func() (ParseResult[repairTarget], error) {
+			return newCachedNode(t, provider, &CacheConfig{Store: store, Model: "flash", TTL: time.Nanosecond}).ParseFromText(ctx, "Ada")
+		}, false, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.parse()
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if result.Cached != tt.wantCached {
+				t.Errorf("Cached = %v, want %v", result.Cached, tt.wantCached)
+			}
+			if result.Data == nil || result.Data.Name == "" {
+				t.Errorf("Data = %+v, want a name", result.Data)
+			}
+			if calls := provider.calls.Load(); calls != tt.wantCalls {
+				t.Errorf("provider calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStructuredNode_FileCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resume.txt")
+	if err := os.WriteFile(path, []byte("Ada"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	provider := &echoNameProvider{}
+	parse := func() ParseResult[repairTarget] {
+		t.Helper()
+		// A new store on the same directory stands for a later run
+		store, err := NewFileCache(filepath.Join(dir, "cache"))
+		if err != nil {
+			t.Fatalf("NewFileCache() error = %v", err)
+		}
+		result, err := newCachedNode(t, provider, &CacheConfig{Store: store}).ParseFromFile(ctx, path)
+		if err != nil {
+			t.Fatalf("ParseFromFile() error = %v", err)
+		}
+		return result
+	}
+
+	if result := parse(); result.Cached {
+		t.Error("first parse was cached")
+	}
+	if result := parse(); !result.Cached || result.Data.Name != "Ada" || provider.calls.Load() != 1 {
+		t.Errorf("second parse = %+v after %d calls, want Ada from the cache", result, provider.calls.Load())
+	}
+	if err := os.WriteFile(path, []byte("Grace"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result := parse(); result.Cached || result.Data.Name != "Grace" {
+		t.Errorf("parse of the changed file = %+v, want Grace parsed again", result)
+	}
+}
+
+func TestBatchStructuredNode_Cache(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "Ada", "b.txt": "Grace"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider := &echoNameProvider{}
+	config := DefaultBatchConfig()
+	config.RequestsPerSecond = 0
+	config.Cache = &CacheConfig{Store: NewMemoryCache()}
+	node, err := NewBatchStructuredNode[repairTarget, *BatchState[repairTarget]](provider, config, nil)
+	if err != nil {
+		t.Fatalf("NewBatchStructuredNode() error = %v", err)
+	}
+
+	run := func() *BatchReport[repairTarget] {
+		state := &BatchState[repairTarget]{Directory: dir, Inputs: []BatchInput{{ID: "inline", Text: "Linus"}}}
+		core.NewFlow(core.NewNode(node, 0, 2)).Run(&state)
+		return state.Report
+	}
+	if report := run(); report.Succeeded != 3 || report.Cached != 0 {
+		t.Fatalf("first run: %s", report.Summary())
+	}
+	report := run()
+	if report.Succeeded != 3 || report.Cached != 3 || provider.calls.Load() != 3 {
+		t.Errorf("second run after %d calls: %s", provider.calls.Load(), report.Summary())
+	}
+}
+
+func TestNewStructuredNode_CacheConfig(t *testing.T) {
+	config := DefaultBaseConfig()
+	config.Cache = &CacheConfig{}
+	if _, err := NewStructuredNode[repairTarget](&echoNameProvider{}, config, nil); err == nil {
+		t.Error("NewStructuredNode() with a cache without store: error = nil")
+	}
+}
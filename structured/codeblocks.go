@@ -0,0 +1,193 @@
+package structured
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in an LLM response
+type CodeBlock struct {
+	Language string // Normalized language, from the fence tag or detected from the content
+	Tag      string // Info string as written after the opening fence, may be empty
+	Content  string // Block content without the fences, trimmed
+	Detected bool   // Whether Language was detected because the fence had no tag
+}
+
+// languageAliases maps common fence tags to a canonical language name
+var languageAliases = map[string]string{
+	"yml":        "yaml",
+	"sh":         "bash",
+	"shell":      "bash",
+	"zsh":        "bash",
+	"console":    "bash",
+	"golang":     "go",
+	"py":         "python",
+	"python3":    "python",
+	"js":         "javascript",
+	"ts":         "typescript",
+	"postgresql": "sql",
+	"postgres":   "sql",
+	"mysql":      "sql",
+	"sqlite":     "sql",
+	"jsonc":      "json5",
+	"htm":        "html",
+}
+
+// NormalizeLanguage lowercases a fence tag and resolves common aliases ("yml" → "yaml")
+func NormalizeLanguage(tag string) string {
+	language := strings.ToLower(strings.TrimSpace(tag))
+	if fields := strings.Fields(language); len(fields) > 0 {
+		// Info strings may carry extra attributes, e.g. "python title=main.py"
+		language = fields[0]
+	} else {
+		return ""
+	}
+	if alias, ok := languageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// ExtractCodeBlocks returns every fenced code block (``` or ~~~) in order. Blocks without
+// a language tag get a language detected from their content, or "" if unknown.
+// An unterminated final fence is ignored.
+func ExtractCodeBlocks(response string) []CodeBlock {
+	var blocks []CodeBlock
+	lines := strings.SplitAfter(response, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		fence, tag, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		var content strings.Builder
+		closed := false
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if isClosingFence(lines[j], fence) {
+				closed = true
+				break
+			}
+			// Models sometimes put the closing fence right after the last line of content
+			if trimmed := strings.TrimSpace(lines[j]); strings.HasSuffix(trimmed, fence) {
+				content.WriteString(strings.TrimSuffix(trimmed, fence))
+				closed = true
+				break
+			}
+			content.WriteString(lines[j])
+		}
+		if !closed {
+			// Inline "```yaml ... ```" on one line is not a block; an unclosed fence ends the scan
+			break
+		}
+
+		block := CodeBlock{
+			Language: NormalizeLanguage(tag),
+			Tag:      strings.TrimSpace(tag),
+			Content:  strings.TrimSpace(content.String()),
+		}
+		if block.Language == "" {
+			block.Language = DetectLanguage(block.Content)
+			block.Detected = block.Language != ""
+		}
+		blocks = append(blocks, block)
+		i = j
+	}
+
+	return blocks
+}
+
+// FindCodeBlocks returns the blocks whose normalized language is one of languages
+func FindCodeBlocks(response string, languages ...string) []CodeBlock {
+	var matches []CodeBlock
+	for _, block := range ExtractCodeBlocks(response) {
+		for _, language := range languages {
+			if block.Language == NormalizeLanguage(language) {
+				matches = append(matches, block)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// openingFence reports whether a line opens a fenced block and returns its fence and tag
+func openingFence(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, marker := range []byte{'`', '~'} {
+		count := 0
+		for count < len(trimmed) && trimmed[count] == marker {
+			count++
+		}
+		if count < 3 {
+			continue
+		}
+		tag := trimmed[count:]
+		if marker == '`' && strings.Contains(tag, "`") {
+			return "", "", false
+		}
+		return trimmed[:count], tag, true
+	}
+	return "", "", false
+}
+
+// isClosingFence reports whether a line closes a block opened with fence
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, fence) {
+		return false
+	}
+	return strings.Trim(trimmed, fence[:1]) == ""
+}
+
+var (
+	sqlPattern    = regexp.MustCompile(`(?i)^\s*(select|insert\s+into|update|delete\s+from|create\s+(table|index|view)|alter\s+table|drop\s+table|with\s+\w+\s+as)\b`)
+	shellPattern  = regexp.MustCompile(`^\s*(#!/bin/(ba|z)?sh|\$ |sudo |apt(-get)? |brew |npm |pip |go (run|build|get|install|test) |git |curl |docker |kubectl |cd |mkdir |export \w+=|echo )`)
+	pythonPattern = regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|import \w+|from [\w.]+ import |class \w+(\(.*\))?:|if __name__ == )`)
+	goPattern     = regexp.MustCompile(`(?m)^\s*(package \w+|func (\(.*\) )?\w+\(|import \(|type \w+ struct \{)`)
+	yamlPattern   = regexp.MustCompile(`(?m)^\s*(- )?[\w"'-]+:(\s|$)`)
+	tomlPattern   = regexp.MustCompile(`(?m)^\s*(\[\[?[\w.\-]+\]\]?|[\w.\-]+\s*=\s*\S)`)
+)
+
+// DetectLanguage guesses the language of untagged code from its content. It
+// recognizes json, xml, html, sql, bash, python, go, toml and yaml, returning ""
+// when unsure.
+func DetectLanguage(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return ""
+	}
+
+	switch {
+	case (strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}")) ||
+		(strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") && !tomlPattern.MatchString(trimmed[1:])):
+		return "json"
+	case strings.HasPrefix(strings.ToLower(trimmed), "<!doctype html") || strings.HasPrefix(strings.ToLower(trimmed), "<html"):
+		return "html"
+	case strings.HasPrefix(trimmed, "<") && strings.HasSuffix(trimmed, ">"):
+		return "xml"
+	case sqlPattern.MatchString(trimmed):
+		return "sql"
+	case goPattern.MatchString(trimmed):
+		return "go"
+	case pythonPattern.MatchString(trimmed):
+		return "python"
+	case shellPattern.MatchString(trimmed):
+		return "bash"
+	case tomlPattern.MatchString(firstLine(trimmed)) && !yamlPattern.MatchString(firstLine(trimmed)):
+		return "toml"
+	case yamlPattern.MatchString(firstLine(trimmed)):
+		return "yaml"
+	default:
+		return ""
+	}
+}
+
+// firstLine returns text up to the first newline
+func firstLine(text string) string {
+	if index := strings.Index(text, "\n"); index != -1 {
+		return text[:index]
+	}
+	return text
+}
@@ -0,0 +1,52 @@
+package structured
+
+import "testing"
+
+func TestExtractCodeBlocks(t *testing.T) {
+	response := "Run this:\n```sh\ngo test ./...\n```\nThen query:\n```PostgreSQL\nSELECT * FROM users;\n```\n" +
+		"Untagged:\n```\nimport os\ndef main():\n    pass\n```\n~~~~yaml title=config\nname: x\n~~~~\n```json\n{\"a\": 1}```\n```go\nunterminated"
+
+	blocks := ExtractCodeBlocks(response)
+	expected := []CodeBlock{
+		{Language: "bash", Tag: "sh", Content: "go test ./..."},
+		{Language: "sql", Tag: "PostgreSQL", Content: "SELECT * FROM users;"},
+		{Language: "python", Content: "import os\ndef main():\n    pass", Detected: true},
+		{Language: "yaml", Tag: "yaml title=config", Content: "name: x"},
+		{Language: "json", Tag: "json", Content: "{\"a\": 1}"},
+	}
+
+	if len(blocks) != len(expected) {
+		t.Fatalf("ExtractCodeBlocks() returned %d blocks, expected %d: %+v", len(blocks), len(expected), blocks)
+	}
+	for i, block := range blocks {
+		if block != expected[i] {
+			t.Errorf("block %d = %+v, expected %+v", i, block, expected[i])
+		}
+	}
+
+	if sql := FindCodeBlocks(response, "mysql"); len(sql) != 1 || sql[0].Content != "SELECT * FROM users;" {
+		t.Errorf("FindCodeBlocks() = %+v", sql)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"{\"a\": [1, 2]}":                       "json",
+		"[1, 2, 3]":                             "json",
+		"<invoice><total>3</total></invoice>":   "xml",
+		"<!DOCTYPE html><html></html>":          "html",
+		"with recent as (select 1) select 2":    "sql",
+		"package main\n\nfunc main() {}":        "go",
+		"#!/bin/bash\necho hi":                  "bash",
+		"[server]\nport = 8080":                 "toml",
+		"title = \"x\"":                         "toml",
+		"name: Jane\nskills:\n  - Go":           "yaml",
+		"Just some prose without any structure": "",
+	}
+
+	for input, expected := range tests {
+		if got := DetectLanguage(input); got != expected {
+			t.Errorf("DetectLanguage(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// schemaNamePattern matches characters not allowed in a response schema name
+var schemaNamePattern = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ResponseSchemaFor builds the llm.ResponseSchema for type T used for constrained decoding
+func ResponseSchemaFor[T any]() (llm.ResponseSchema, error) {
+	schema, err := GenerateJSONSchema[T]()
+	if err != nil {
+		return llm.ResponseSchema{}, err
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return llm.ResponseSchema{}, err
+	}
+
+	name := strings.Trim(schemaNamePattern.ReplaceAllString(reflect.TypeOf((*T)(nil)).Elem().Name(), "_"), "_")
+	if name == "" {
+		name = "response"
+	}
+
+	return llm.ResponseSchema{
+		Name:        name,
+		Description: schema.Description,
+		Schema:      data,
+	}, nil
+}
+
+// callForType calls the provider for T. Providers implementing llm.SchemaProvider
+// receive T's schema so the response is valid JSON by construction; constrained
+// reports whether that happened.
+func callForType[T any](p *Parser, ctx context.Context, messages []llm.Message) (response llm.Message, constrained bool, err error) {
+	return callWithSchema(p, ctx, messages, ResponseSchemaFor[T])
+}
+
+// callWithSchema is callForType with the schema built by responseSchema
+func callWithSchema(p *Parser, ctx context.Context, messages []llm.Message, responseSchema func() (llm.ResponseSchema, error)) (response llm.Message, constrained bool, err error) {
+	schemaProvider, ok := p.llmProvider.(llm.SchemaProvider)
+	if !ok || p.config.DisableConstrainedDecoding {
+		response, err = p.llmProvider.CallLLM(ctx, messages)
+		return response, false, err
+	}
+
+	schema, err := responseSchema()
+	if err != nil {
+		// Types that cannot be described as a schema are still parsed from text
+		response, err = p.llmProvider.CallLLM(ctx, messages)
+		return response, false, err
+	}
+
+	response, err = schemaProvider.CallLLMWithSchema(ctx, messages, schema)
+	if errors.Is(err, llm.ErrSchemaNotSupported) {
+		response, err = p.llmProvider.CallLLM(ctx, messages)
+		return response, false, err
+	}
+	return response, err == nil, err
+}
+
+// parseForType decodes a constrained response directly and otherwise extracts
+// the structured output from text with ParseResponse
+func parseForType[T any](responseContent string, constrained bool) (ParseResult[T], error) {
+	if constrained {
+		var result T
+		_, answer := llm.SplitReasoning(responseContent)
+		// The schema uses yaml field names and JSON is valid YAML
+		if err := unmarshalWithConverters([]byte(strings.TrimSpace(answer)), yaml.Unmarshal, &result); err == nil {
+			return ParseResult[T]{
+				Data:  &result,
+				Error: nil,
+			}, nil
+		}
+	}
+	return ParseResponse[T](responseContent)
+}
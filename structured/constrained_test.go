@@ -0,0 +1,68 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+type schemaProvider struct {
+	scriptedProvider
+	unsupported bool
+	schemas     []llm.ResponseSchema
+}
+
+func (s *schemaProvider) CallLLMWithSchema(ctx context.Context, messages []llm.Message, schema llm.ResponseSchema) (llm.Message, error) {
+	if s.unsupported {
+		return llm.Message{}, llm.ErrSchemaNotSupported
+	}
+	s.schemas = append(s.schemas, schema)
+	return s.CallLLM(ctx, messages)
+}
+
+func TestConstrainedDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		unsupported bool
+		disabled    bool
+		response    string
+		wantSchemas int
+	}{
+		{name: "schema attached", response: `{"name":"widget","count":3}`, wantSchemas: 1},
+		{name: "provider falls back", unsupported: true, response: "```yaml\nname: widget\ncount: 3\n```", wantSchemas: 0},
+		{name: "disabled by config", disabled: true, response: "name: widget\ncount: 3", wantSchemas: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &schemaProvider{scriptedProvider: scriptedProvider{responses: []string{tt.response}}, unsupported: tt.unsupported}
+			parser := newTestParser(t, provider)
+			parser.config.DisableConstrainedDecoding = tt.disabled
+
+			result, err := ParseWithPrompt[repairTarget](parser, context.Background(), "extract")
+			if err != nil {
+				t.Fatalf("ParseWithPrompt() error = %v", err)
+			}
+			if result.Data.Name != "widget" || result.Data.Count != 3 {
+				t.Errorf("unexpected data %+v", result.Data)
+			}
+			if len(provider.schemas) != tt.wantSchemas {
+				t.Fatalf("expected %d schema calls, got %d", tt.wantSchemas, len(provider.schemas))
+			}
+			if tt.wantSchemas == 0 {
+				return
+			}
+
+			schema := provider.schemas[0]
+			if schema.Name != "repairTarget" {
+				t.Errorf("schema name = %q", schema.Name)
+			}
+			var decoded map[string]any
+			if err := json.Unmarshal(schema.Schema, &decoded); err != nil || decoded["properties"] == nil {
+				t.Errorf("unexpected schema %s", schema.Schema)
+			}
+		})
+	}
+}
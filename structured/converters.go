@@ -0,0 +1,428 @@
+package structured
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ConverterFunc converts a raw decoded value (string, number, bool, list or map)
+// into a domain type
+type ConverterFunc func(raw any) (any, error)
+
+// converterRegistry holds converters keyed by target type and by name
+type converterRegistry struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type]ConverterFunc
+	byName map[string]ConverterFunc
+	needs  map[reflect.Type]bool // Cache of whether decoding a type involves converters
+}
+
+var converters = newConverterRegistry()
+
+func newConverterRegistry() *converterRegistry {
+	registry := &converterRegistry{
+		byType: make(map[reflect.Type]ConverterFunc),
+		byName: make(map[string]ConverterFunc),
+		needs:  make(map[reflect.Type]bool),
+	}
+	registry.byType[reflect.TypeOf(time.Time{})] = wrapConverter(ParseDate)
+	registry.byType[reflect.TypeOf(time.Duration(0))] = wrapConverter(ParseDuration)
+	registry.byName["date"] = wrapConverter(ParseDate)
+	registry.byName["duration"] = wrapConverter(ParseDuration)
+	registry.byName["currency"] = wrapConverter(ParseCurrency)
+	return registry
+}
+
+// wrapConverter adapts a typed converter to a ConverterFunc
+func wrapConverter[T any](convert func(raw any) (T, error)) ConverterFunc {
+	return func(raw any) (any, error) {
+		return convert(raw)
+	}
+}
+
+// RegisterConverter registers a converter used whenever a parsed field has type T.
+// time.Time and time.Duration have built-in converters that can be replaced.
+func RegisterConverter[T any](convert func(raw any) (T, error)) {
+	var zero T
+	converters.mu.Lock()
+	defer converters.mu.Unlock()
+	converters.byType[reflect.TypeOf(&zero).Elem()] = wrapConverter(convert)
+	converters.needs = make(map[reflect.Type]bool)
+}
+
+// UnregisterConverter removes the converter registered for type T
+func UnregisterConverter[T any]() {
+	var zero T
+	converters.mu.Lock()
+	defer converters.mu.Unlock()
+	delete(converters.byType, reflect.TypeOf(&zero).Elem())
+	converters.needs = make(map[reflect.Type]bool)
+}
+
+// RegisterNamedConverter registers a converter selected per field with the
+// `convert:"name"` struct tag. Built-in names are "date", "duration" and "currency".
+func RegisterNamedConverter(name string, convert ConverterFunc) {
+	converters.mu.Lock()
+	defer converters.mu.Unlock()
+	converters.byName[name] = convert
+	converters.needs = make(map[reflect.Type]bool)
+}
+
+// typeConverter returns the converter registered for a type
+func (r *converterRegistry) typeConverter(t reflect.Type) (ConverterFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	convert, ok := r.byType[t]
+	return convert, ok
+}
+
+// namedConverter returns the converter registered under a name
+func (r *converterRegistry) namedConverter(name string) (ConverterFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	convert, ok := r.byName[name]
+	return convert, ok
+}
+
+// needsConversion reports whether decoding t involves a registered converter
+func (r *converterRegistry) needsConversion(t reflect.Type) bool {
+	r.mu.RLock()
+	cached, ok := r.needs[t]
+	r.mu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := r.scan(t, make(map[reflect.Type]bool))
+	r.needs[t] = result
+	return result
+}
+
+// scan walks a type looking for converter types or convert tags; the caller holds mu
+func (r *converterRegistry) scan(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if _, ok := r.byType[t]; ok {
+		return true
+	}
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return r.scan(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("convert") != "" || r.scan(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unmarshalWithConverters unmarshals content into result, applying registered
+// converters when T contains types or fields that need them
+func unmarshalWithConverters[T any](content []byte, unmarshal func([]byte, any) error, result *T) error {
+	target := reflect.ValueOf(result).Elem()
+	if !converters.needsConversion(target.Type()) {
+		return unmarshal(content, result)
+	}
+
+	var raw any
+	if err := unmarshal(content, &raw); err != nil {
+		return err
+	}
+	return decodeValue(raw, target, "", "")
+}
+
+// decodeValue assigns a raw decoded value to v, running converters along the way
+func decodeValue(raw any, v reflect.Value, convertTag string, path string) error {
+	if raw == nil {
+		return nil
+	}
+
+	if convertTag != "" {
+		convert, ok := converters.namedConverter(convertTag)
+		if !ok {
+			return fmt.Errorf("%s: unknown converter %q", displayPath(path), convertTag)
+		}
+		return assignConverted(convert, raw, v, path)
+	}
+	if convert, ok := converters.typeConverter(v.Type()); ok {
+		return assignConverted(convert, raw, v, path)
+	}
+	if !converters.needsConversion(v.Type()) {
+		return decodeRoundTrip(raw, v, path)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeValue(raw, v.Elem(), "", path)
+	case reflect.Struct:
+		fields, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", displayPath(path), raw)
+		}
+		return decodeStruct(fields, v, path)
+	case reflect.Slice:
+		items, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a list, got %T", displayPath(path), raw)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, slice.Index(i), "", fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		items, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a list, got %T", displayPath(path), raw)
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := decodeValue(items[i], v.Index(i), "", fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		entries, ok := raw.(map[string]any)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: expected an object with string keys, got %T", displayPath(path), raw)
+		}
+		result := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for key, entry := range entries {
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(entry, value, "", joinPath(path, key)); err != nil {
+				return err
+			}
+			result.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+		v.Set(result)
+		return nil
+	default:
+		return decodeRoundTrip(raw, v, path)
+	}
+}
+
+// decodeStruct assigns object fields by serialized name, falling back to a
+// case-insensitive match
+func decodeStruct(fields map[string]any, v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		raw, ok := fields[name]
+		if !ok {
+			for key, value := range fields {
+				if strings.EqualFold(key, name) {
+					raw, ok = value, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if err := decodeValue(raw, v.Field(i), field.Tag.Get("convert"), joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignConverted runs a converter and stores its result in v
+func assignConverted(convert ConverterFunc, raw any, v reflect.Value, path string) error {
+	converted, err := convert(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(path), err)
+	}
+
+	value := reflect.ValueOf(converted)
+	if v.Kind() == reflect.Ptr && value.Type() != v.Type() {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case value.Type().AssignableTo(v.Type()):
+		v.Set(value)
+	case value.Type().ConvertibleTo(v.Type()):
+		v.Set(value.Convert(v.Type()))
+	default:
+		return fmt.Errorf("%s: converter returned %s, expected %s", displayPath(path), value.Type(), v.Type())
+	}
+	return nil
+}
+
+// decodeRoundTrip decodes values without converters by re-encoding them
+func decodeRoundTrip(raw any, v reflect.Value, path string) error {
+	data, err := yaml.Marshal(raw)
+	if err == nil {
+		if err = yaml.Unmarshal(data, v.Addr().Interface()); err == nil {
+			return nil
+		}
+	}
+
+	data, jsonErr := json.Marshal(raw)
+	if jsonErr == nil {
+		if jsonErr = json.Unmarshal(data, v.Addr().Interface()); jsonErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w", displayPath(path), err)
+}
+
+// dateLayouts are the date formats accepted by ParseDate
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2006",
+	"January 2006",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// ParseDate converts common date and timestamp strings into time.Time
+func ParseDate(raw any) (time.Time, error) {
+	switch value := raw.(type) {
+	case time.Time:
+		return value, nil
+	case string:
+		text := strings.TrimSpace(value)
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, text); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as a date", value)
+	case int:
+		return time.Unix(int64(value), 0).UTC(), nil
+	case int64:
+		return time.Unix(value, 0).UTC(), nil
+	case float64:
+		return time.Unix(int64(value), 0).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %T to a date", raw)
+	}
+}
+
+// ParseDuration converts Go duration strings ("1h30m") or seconds into time.Duration
+func ParseDuration(raw any) (time.Duration, error) {
+	switch value := raw.(type) {
+	case string:
+		duration, err := time.ParseDuration(strings.ReplaceAll(strings.TrimSpace(value), " ", ""))
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %q as a duration: %w", value, err)
+		}
+		return duration, nil
+	case int:
+		return time.Duration(value) * time.Second, nil
+	case int64:
+		return time.Duration(value) * time.Second, nil
+	case float64:
+		return time.Duration(value * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to a duration", raw)
+	}
+}
+
+// ParseCurrency converts amounts such as "$1,234.56", "EUR 12,50" or "(45.00)"
+// into float64. When both separators appear, the last one is the decimal point.
+func ParseCurrency(raw any) (float64, error) {
+	switch value := raw.(type) {
+	case float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case string:
+		return parseCurrencyString(value)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an amount", raw)
+	}
+}
+
+// parseCurrencyString strips symbols and separators from a currency amount
+func parseCurrencyString(value string) (float64, error) {
+	text := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		negative = true
+		text = text[1 : len(text)-1]
+	}
+
+	var digits strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',':
+			digits.WriteRune(r)
+		case r == '-':
+			negative = true
+		}
+	}
+
+	number := digits.String()
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+	switch {
+	case lastDot != -1 && lastComma != -1 && lastComma > lastDot:
+		// 1.234,56
+		number = strings.ReplaceAll(number, ".", "")
+		number = strings.Replace(number, ",", ".", 1)
+	case lastComma != -1 && lastDot == -1 && len(number)-lastComma-1 != 3:
+		// 12,50
+		number = strings.Replace(number, ",", ".", 1)
+	default:
+		number = strings.ReplaceAll(number, ",", "")
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q as an amount", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
@@ -0,0 +1,90 @@
+package structured
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type sku string
+
+type convertedOrder struct {
+	ID       sku           `yaml:"id"`
+	Placed   time.Time     `yaml:"placed"`
+	Shipped  *time.Time    `yaml:"shipped"`
+	Window   time.Duration `yaml:"window"`
+	Total    float64       `yaml:"total" convert:"currency"`
+	Refunds  []float64     `yaml:"refunds" convert:"currency"`
+	Notes    []string      `yaml:"notes"`
+	Quantity int           `yaml:"quantity"`
+}
+
+func TestParseResponse_Converters(t *testing.T) {
+	RegisterConverter(func(raw any) (sku, error) {
+		return sku(strings.ToUpper(strings.TrimSpace(raw.(string)))), nil
+	})
+	defer UnregisterConverter[sku]()
+
+	response := "```yaml\nid: ab-12\nplaced: Jan 2, 2024\nshipped: \"2024-01-05\"\nwindow: 1h 30m\ntotal: \"$1,234.56\"\nnotes: [fragile]\nquantity: 3\n```"
+	result, err := ParseResponse[convertedOrder](response)
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+
+	order := result.Data
+	if order.ID != "AB-12" {
+		t.Errorf("expected custom converter to run, got %q", order.ID)
+	}
+	if !order.Placed.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected placed date %v", order.Placed)
+	}
+	if order.Shipped == nil || order.Shipped.Day() != 5 {
+		t.Errorf("unexpected shipped date %v", order.Shipped)
+	}
+	if order.Window != 90*time.Minute {
+		t.Errorf("unexpected window %v", order.Window)
+	}
+	if order.Total != 1234.56 {
+		t.Errorf("unexpected total %v", order.Total)
+	}
+	if len(order.Notes) != 1 || order.Quantity != 3 {
+		t.Errorf("plain fields not decoded: %+v", order)
+	}
+}
+
+func TestParseResponse_ConverterError(t *testing.T) {
+	_, err := ParseResponse[convertedOrder]("placed: someday\n")
+	if err == nil {
+		t.Fatal("expected an error for an unparseable date")
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected float64
+	}{
+		{"$1,234.56", 1234.56},
+		{"EUR 1.234,56", 1234.56},
+		{"12,50 €", 12.5},
+		{"1,000", 1000},
+		{"(45.00)", -45},
+		{"-3", -3},
+		{42, 42},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCurrency(tt.input)
+		if err != nil {
+			t.Errorf("ParseCurrency(%v) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseCurrency(%v) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+
+	if _, err := ParseCurrency("free"); err == nil {
+		t.Error("expected an error for a non-numeric amount")
+	}
+}
@@ -0,0 +1,68 @@
+package structured
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatAttempt records one failed attempt to parse a candidate block
+type FormatAttempt struct {
+	Format    Format // Format that was tried
+	Candidate string // Text extracted from the response for this format
+	Err       error  // Parse or decode error
+}
+
+// ParseDiagnostics collects what the parser tried when a response could not be parsed
+type ParseDiagnostics struct {
+	RawResponse string          // Full LLM response
+	Attempts    []FormatAttempt // Failed attempts in the order they were made
+}
+
+// record appends a failed attempt; it is a no-op on nil diagnostics
+func (d *ParseDiagnostics) record(format Format, candidate string, err error) {
+	if d == nil || err == nil {
+		return
+	}
+	d.Attempts = append(d.Attempts, FormatAttempt{Format: format, Candidate: candidate, Err: err})
+}
+
+// Summary returns one line per attempt with the format and its error
+func (d *ParseDiagnostics) Summary() string {
+	if d == nil || len(d.Attempts) == 0 {
+		return "no YAML, JSON, JSON5, TOML or XML content found"
+	}
+
+	parts := make([]string, len(d.Attempts))
+	for i, attempt := range d.Attempts {
+		parts[i] = fmt.Sprintf("%s: %s", attempt.Format, strings.Join(strings.Fields(attempt.Err.Error()), " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// String returns a multi-line report with the raw response and each candidate, for logging
+func (d *ParseDiagnostics) String() string {
+	if d == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Raw response:\n")
+	builder.WriteString(d.RawResponse)
+	builder.WriteString("\n")
+	for _, attempt := range d.Attempts {
+		builder.WriteString(fmt.Sprintf("\n[%s] %v\n", attempt.Format, attempt.Err))
+		builder.WriteString(attempt.Candidate)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// ParseError is returned when a response cannot be parsed in any supported format
+type ParseError struct {
+	Diagnostics *ParseDiagnostics
+}
+
+// Error implements error with the per-format failures
+func (e *ParseError) Error() string {
+	return "failed to parse response as YAML, JSON, JSON5, TOML or XML: " + e.Diagnostics.Summary()
+}
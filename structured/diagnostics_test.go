@@ -0,0 +1,60 @@
+package structured
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseResponse_Diagnostics(t *testing.T) {
+	response := "```json\n{\"name\": \"widget\", \"count\": \"many\"}\n```"
+
+	result, err := ParseResponse[repairTarget](response)
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *ParseError, got %T", err)
+	}
+	if result.Diagnostics == nil || result.Diagnostics.RawResponse != response {
+		t.Fatalf("expected diagnostics with the raw response, got %+v", result.Diagnostics)
+	}
+
+	formats := map[Format]bool{}
+	for _, attempt := range result.Diagnostics.Attempts {
+		formats[attempt.Format] = true
+		if attempt.Candidate == "" || attempt.Err == nil {
+			t.Errorf("attempt %s missing candidate or error", attempt.Format)
+		}
+	}
+	if !formats[FormatYAML] || !formats[FormatJSON] {
+		t.Errorf("expected YAML and JSON attempts, got %v", result.Diagnostics.Attempts)
+	}
+	if !strings.Contains(err.Error(), "json:") || !strings.Contains(err.Error(), "count") {
+		t.Errorf("expected per-format details in error, got %v", err)
+	}
+	if !strings.Contains(result.Diagnostics.String(), "Raw response:") {
+		t.Error("expected String() to include the raw response")
+	}
+}
+
+func TestParseResponse_NoContentDiagnostics(t *testing.T) {
+	_, err := ParseResponse[repairTarget]("")
+	if err == nil || !strings.Contains(err.Error(), "failed to parse response") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseResponse_SkipsReasoning(t *testing.T) {
+	response := "<think>\nThe user wants a widget, maybe:\n```yaml\nname: draft\ncount: many\n```\n</think>\n```yaml\nname: widget\ncount: 3\n```"
+
+	result, err := ParseResponse[repairTarget](response)
+	if err != nil {
+		t.Fatalf("ParseResponse() error = %v", err)
+	}
+	if result.Data.Name != "widget" || result.Data.Count != 3 {
+		t.Errorf("ParseResponse() = %+v, want the answer after the reasoning", result.Data)
+	}
+}
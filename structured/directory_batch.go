@@ -0,0 +1,557 @@
+package structured
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// FileParser parses documents into T. *StructuredNode[T] and the nodes
+// embedding it implement it.
+type FileParser[T any] interface {
+	ParseFromFile(ctx context.Context, filePath string, additionalContext ...string) (ParseResult[T], error)
+	ParseFromTextWithRepair(ctx context.Context, textContent string, additionalContext ...string) (ParseResult[T], error)
+	ValidateAndAnnotate(result ParseResult[T]) (ParseResult[T], error)
+}
+
+// BatchReportReceiver is implemented by workflow states that take the report
+// of a DirectoryBatchNode, such as BatchState
+type BatchReportReceiver[T any] interface {
+	SetBatchReport(report *BatchReport[T])
+}
+
+// DirectoryBatchConfig configures a DirectoryBatchNode
+type DirectoryBatchConfig struct {
+	Patterns []string // Glob patterns of the files to parse; "**" matches any number of directories, e.g. "invoices/**/*.pdf"
+	Files    []string // Files to parse besides the matches, e.g. from ReadFailures
+
+	OutputDir    string // Directory of the results: <file>.json per parsed file, mirroring the file's path
+	BaseDir      string // Directory the result paths are relative to; defaults to the working directory
+	SummaryPath  string // Summary of every file, as .jsonl or .csv; relative paths are inside OutputDir, "" for none
+	FailuresPath string // List of the failed files, one per line, for Files of a rerun; relative paths are inside OutputDir, "" for none
+
+	SkipExisting      bool     // Keep the results of files parsed by an earlier run instead of parsing them again
+	RequestsPerSecond float64  // Maximum files started per second across workers; 0 disables limiting
+	AdditionalContext []string // Context added to every extraction prompt
+	FailOnAnyError    bool     // Return ActionFailure if any file fails instead of only when all fail
+}
+
+// DefaultDirectoryBatchConfig returns a configuration that writes results,
+// summary.jsonl and failures.txt to the results directory
+func DefaultDirectoryBatchConfig() *DirectoryBatchConfig {
+	return &DirectoryBatchConfig{
+		OutputDir:         "results",
+		SummaryPath:       "summary.jsonl",
+		FailuresPath:      "failures.txt",
+		RequestsPerSecond: 2,
+	}
+}
+
+// DirectoryItem is the prep result for one file
+type DirectoryItem struct {
+	Input      BatchInput
+	ResultPath string // File the result is written to
+	Existing   bool   // Set when SkipExisting found a result of an earlier run
+	Err        error  // Set when the files could not be listed
+}
+
+// DirectoryBatchNode parses the files matching glob patterns through a
+// FileParser and writes a JSON result per file, a summary of the run and a
+// list of the failures, so a large batch can be inspected and the failed
+// files reprocessed. Wrap it with core.NewNode to choose the number of
+// concurrent workers.
+type DirectoryBatchNode[T any, S BatchReportReceiver[T]] struct {
+	parser  FileParser[T]
+	config  *DirectoryBatchConfig
+	limiter *rateLimiter
+}
+
+// NewDirectoryBatchNode creates a node that parses files with parser
+func NewDirectoryBatchNode[T any, S BatchReportReceiver[T]](parser FileParser[T], config *DirectoryBatchConfig) (*DirectoryBatchNode[T, S], error) {
+	if parser == nil {
+		return nil, fmt.Errorf("parser is required")
+	}
+	if config == nil {
+		config = DefaultDirectoryBatchConfig()
+	}
+	if config.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if len(config.Patterns) == 0 && len(config.Files) == 0 {
+		return nil, fmt.Errorf("at least one pattern or file is required")
+	}
+	if config.RequestsPerSecond < 0 {
+		return nil, fmt.Errorf("requests per second cannot be negative")
+	}
+	for _, pattern := range config.Patterns {
+		if _, err := filepath.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &DirectoryBatchNode[T, S]{
+		parser:  parser,
+		config:  config,
+		limiter: newRateLimiter(config.RequestsPerSecond),
+	}, nil
+}
+
+// Prep lists the files to parse
+func (d *DirectoryBatchNode[T, S]) Prep(state *S) []DirectoryItem {
+	files, err := d.files()
+	if err != nil {
+		return []DirectoryItem{{Input: BatchInput{ID: "inputs"}, Err: err}}
+	}
+
+	items := make([]DirectoryItem, len(files))
+	for i, file := range files {
+		item := DirectoryItem{Input: BatchInput{ID: file, FilePath: file}, ResultPath: d.resultPath(file)}
+		if d.config.SkipExisting {
+			if info, err := os.Stat(item.ResultPath); err == nil && info.Mode().IsRegular() {
+				item.Existing = true
+			}
+		}
+		items[i] = item
+	}
+	return items
+}
+
+// files returns the files of the patterns and Files, without duplicates, in
+// the order of the patterns and then by name
+func (d *DirectoryBatchNode[T, S]) files() ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(file string) {
+		file = filepath.Clean(file)
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, pattern := range d.config.Patterns {
+		matches, err := globFiles(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	for _, file := range d.config.Files {
+		add(file)
+	}
+	return files, nil
+}
+
+// resultPath returns the result file of an input file
+func (d *DirectoryBatchNode[T, S]) resultPath(file string) string {
+	name := file
+	if d.config.BaseDir != "" || filepath.IsAbs(file) {
+		base := d.config.BaseDir
+		if base == "" {
+			base, _ = os.Getwd()
+		}
+		if relative, err := filepath.Rel(base, file); err == nil {
+			name = relative
+		}
+	}
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		name = filepath.Base(file)
+	}
+	return filepath.Join(d.config.OutputDir, name+".json")
+}
+
+// outputPath resolves a summary or failures path against OutputDir
+func (d *DirectoryBatchNode[T, S]) outputPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(d.config.OutputDir, path)
+}
+
+// DescribeItem implements core.ItemDescriber, so progress updates name the file
+func (d *DirectoryBatchNode[T, S]) DescribeItem(item DirectoryItem) string {
+	return item.Input.ID
+}
+
+// Exec parses one file without the context of the run
+func (d *DirectoryBatchNode[T, S]) Exec(item DirectoryItem) (BatchItemResult[T], error) {
+	return d.ExecContext(context.Background(), item)
+}
+
+// ExecContext parses one file, cancelling the LLM call with ctx, and writes
+// its result; failures are returned in the result rather than as errors
+func (d *DirectoryBatchNode[T, S]) ExecContext(ctx context.Context, item DirectoryItem) (BatchItemResult[T], error) {
+	result := BatchItemResult[T]{Input: item.Input, Started: time.Now()}
+
+	switch {
+	case item.Err != nil:
+		result.Result = ParseResult[T]{Error: item.Err}
+		return result, nil
+	case item.Existing:
+		result.Skipped = true
+		result.Result = readResult[T](item.ResultPath)
+		result.Duration = time.Since(result.Started)
+		return result, nil
+	}
+
+	d.limiter.wait(ctx)
+
+	parsed, err := d.parse(ctx, item.Input.FilePath)
+	if err == nil {
+		err = writeResult(item.ResultPath, parsed.Data)
+	}
+	if err != nil {
+		parsed.Data = nil
+		parsed.Error = err
+		// A result of an earlier run would hide the failure from the next one
+		if removeErr := os.Remove(item.ResultPath); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			parsed.Error = fmt.Errorf("%w; failed to remove the earlier result: %v", err, removeErr)
+		}
+	}
+	result.Result = parsed
+	result.Duration = time.Since(result.Started)
+	return result, nil
+}
+
+// parse parses and validates a file, repairing text documents with the
+// validation errors
+func (d *DirectoryBatchNode[T, S]) parse(ctx context.Context, file string) (ParseResult[T], error) {
+	if isImageFile(file) {
+		parsed, err := d.parser.ParseFromFile(ctx, file, d.config.AdditionalContext...)
+		if err != nil {
+			return parsed, err
+		}
+		return d.parser.ValidateAndAnnotate(parsed)
+	}
+
+	text, err := LoadFile(ctx, file)
+	if err != nil {
+		return ParseResult[T]{Error: err}, err
+	}
+	return d.parser.ParseFromTextWithRepair(ctx, text, d.config.AdditionalContext...)
+}
+
+// ExecFallback records an unexpected execution failure
+func (d *DirectoryBatchNode[T, S]) ExecFallback(err error) BatchItemResult[T] {
+	return BatchItemResult[T]{Result: ParseResult[T]{Error: err}}
+}
+
+// Post writes the summary and the failures, and stores the BatchReport in state
+func (d *DirectoryBatchNode[T, S]) Post(state *S, prepRes []DirectoryItem, execResults ...BatchItemResult[T]) core.Action {
+	report := newBatchReport(execResults)
+
+	var errs []error
+	if d.config.SummaryPath != "" {
+		errs = append(errs, writeSummary(d.outputPath(d.config.SummaryPath), prepRes, execResults))
+	}
+	if d.config.FailuresPath != "" {
+		errs = append(errs, writeFailures(d.outputPath(d.config.FailuresPath), execResults))
+	}
+	report.OutputErr = errors.Join(errs...)
+
+	(*state).SetBatchReport(report)
+
+	if report.OutputErr != nil || (report.Failed > 0 && (d.config.FailOnAnyError || report.Succeeded+report.Skipped == 0)) {
+		return core.ActionFailure
+	}
+	return core.ActionSuccess
+}
+
+// ReadFailures reads a failures file written by a DirectoryBatchNode, for
+// the Files of a run that reprocesses them
+func ReadFailures(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failures: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// globFiles returns the regular files matching a pattern, sorted by name.
+// A "**" element matches any number of directories.
+func globFiles(pattern string) ([]string, error) {
+	root, rest, recursive := strings.Cut(filepath.ToSlash(pattern), "**")
+	if !recursive {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		return regularFiles(matches), nil
+	}
+
+	if strings.ContainsAny(root, `*?[\`) {
+		return nil, fmt.Errorf("invalid pattern %q: wildcards before ** are not supported", pattern)
+	}
+	root = filepath.FromSlash(strings.TrimSuffix(root, "/"))
+	if root == "" {
+		root = "."
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	if rest == "" {
+		rest = "*"
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		// "**" may stand for any leading directories, including none
+		elements := strings.Split(filepath.ToSlash(relative), "/")
+		for i := range elements {
+			if matched, err := filepath.Match(rest, strings.Join(elements[i:], "/")); err != nil {
+				return err
+			} else if matched {
+				matches = append(matches, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to match %q: %w", pattern, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// regularFiles keeps the paths of regular files
+func regularFiles(paths []string) []string {
+	var files []string
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// writeResult writes parsed data as indented JSON
+func writeResult(path string, data any) error {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create result directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(encoded, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+// readResult loads the result of an earlier run
+func readResult[T any](path string) ParseResult[T] {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return ParseResult[T]{Error: fmt.Errorf("failed to read earlier result: %w", err)}
+	}
+	var data T
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return ParseResult[T]{Error: fmt.Errorf("invalid earlier result %s: %w", path, err)}
+	}
+	return ParseResult[T]{Data: &data}
+}
+
+// summaryEntry is a line of a JSONL summary
+type summaryEntry struct {
+	File       string   `json:"file"`
+	Status     string   `json:"status"` // "ok", "skipped" or "failed"
+	Result     string   `json:"result,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+	Data       any      `json:"data,omitempty"`
+}
+
+// writeSummary writes an entry per file as JSON lines, or as CSV rows with a
+// column per top-level field of the data when the path ends in .csv
+func writeSummary[T any](path string, items []DirectoryItem, results []BatchItemResult[T]) error {
+	entries := make([]summaryEntry, len(results))
+	for i, result := range results {
+		entry := summaryEntry{
+			File:       result.Input.ID,
+			Status:     result.status(),
+			Warnings:   result.Result.Warnings,
+			DurationMS: result.Duration.Milliseconds(),
+		}
+		if result.Succeeded() {
+			entry.Data = result.Result.Data
+			if i < len(items) {
+				entry.Result = items[i].ResultPath
+			}
+		} else if result.Result.Error != nil {
+			entry.Error = result.Result.Error.Error()
+		}
+		entries[i] = entry
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create summary directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create summary: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = writeSummaryCSV(writer, reflect.TypeFor[T](), entries)
+	} else {
+		encoder := json.NewEncoder(writer)
+		for _, entry := range entries {
+			if err = encoder.Encode(entry); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return nil
+}
+
+// writeSummaryCSV writes the entries as CSV. Nested data is written as JSON.
+func writeSummaryCSV(w *bufio.Writer, t reflect.Type, entries []summaryEntry) error {
+	fields := make([]map[string]any, len(entries))
+	for i, entry := range entries {
+		if entry.Data == nil {
+			continue
+		}
+		encoded, err := json.Marshal(entry.Data)
+		if err != nil {
+			return err
+		}
+		// Data that is not an object, e.g. a list, has no columns
+		_ = json.Unmarshal(encoded, &fields[i])
+	}
+	columns := dataColumns(t, fields)
+
+	writer := csv.NewWriter(w)
+	header := append([]string{"file", "status", "result", "error", "duration_ms"}, columns...)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		row := []string{entry.File, entry.Status, entry.Result, entry.Error, strconv.FormatInt(entry.DurationMS, 10)}
+		for _, column := range columns {
+			row = append(row, csvValue(fields[i][column]))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// dataColumns returns the JSON names of the fields of a struct in field
+// order, or else the keys of the decoded data sorted by name
+func dataColumns(t reflect.Type, fields []map[string]any) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		var columns []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			columns = append(columns, name)
+		}
+		return columns
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range fields {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// csvValue formats a decoded JSON value as a CSV cell
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// writeFailures writes the failed files one per line; an empty file means
+// every file was parsed
+func writeFailures[T any](path string, results []BatchItemResult[T]) error {
+	var builder strings.Builder
+	for _, result := range results {
+		if !result.Succeeded() && result.Input.FilePath != "" {
+			builder.WriteString(result.Input.FilePath)
+			builder.WriteByte('\n')
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create failures directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(builder.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write failures: %w", err)
+	}
+	return nil
+}
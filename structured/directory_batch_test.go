@@ -0,0 +1,157 @@
+package structured
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+func writeTestFiles(t *testing.T, directory string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(directory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDirectoryBatchNode(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFiles(t, directory, map[string]string{
+		"docs/a.txt":        "Ada",
+		"docs/2024/b.txt":   "Grace",
+		"docs/2024/c.txt":   "",
+		"docs/2024/skip.md": "Linus",
+	})
+	output := filepath.Join(directory, "results")
+
+	provider := &echoNameProvider{}
+	parserConfig := DefaultBaseConfig()
+	parserConfig.MaxRetries = 0
+	parser, err := NewStructuredNode[repairTarget](provider, parserConfig, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredNode() error = %v", err)
+	}
+	config := DefaultDirectoryBatchConfig()
+	config.Patterns = []string{filepath.Join(directory, "docs", "**", "*.txt")}
+	config.OutputDir = output
+	config.BaseDir = filepath.Join(directory, "docs")
+	config.SummaryPath = "summary.csv"
+	config.RequestsPerSecond = 0
+	node, err := NewDirectoryBatchNode[repairTarget, *BatchState[repairTarget]](parser, config)
+	if err != nil {
+		t.Fatalf("NewDirectoryBatchNode() error = %v", err)
+	}
+
+	state := &BatchState[repairTarget]{}
+	if action := core.NewFlow(core.NewNode(node, 0, 3)).Run(&state); action != core.ActionSuccess {
+		t.Errorf("expected success with partial failures, got %v", action)
+	}
+	if report := state.Report; report.Total != 3 || report.Succeeded != 2 || report.Failed != 1 || report.OutputErr != nil {
+		t.Fatalf("unexpected report: %s", report.Summary())
+	}
+
+	var result repairTarget
+	data, err := os.ReadFile(filepath.Join(output, "2024", "b.txt.json"))
+	if err != nil || json.Unmarshal(data, &result) != nil || result.Name != "Grace" {
+		t.Errorf("unexpected result file %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(output, "2024", "c.txt.json")); !os.IsNotExist(err) {
+		t.Errorf("expected no result for the failed file, got %v", err)
+	}
+
+	summary, _ := os.ReadFile(filepath.Join(output, "summary.csv"))
+	lines := strings.Split(strings.TrimSpace(string(summary)), "\n")
+	if len(lines) != 4 || lines[0] != "file,status,result,error,duration_ms,Name,Count" {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+	if !strings.HasSuffix(lines[3], ",Ada,1") || !strings.Contains(lines[2], "c.txt,failed,,") {
+		t.Errorf("unexpected summary rows:\n%s", summary)
+	}
+
+	failures, err := ReadFailures(filepath.Join(output, "failures.txt"))
+	if err != nil || len(failures) != 1 || !strings.HasSuffix(failures[0], "c.txt") {
+		t.Fatalf("ReadFailures() = %v, %v", failures, err)
+	}
+
+	// Rerun with the failure fixed: earlier results are kept
+	writeTestFiles(t, directory, map[string]string{"docs/2024/c.txt": "Linus"})
+	config.SkipExisting = true
+	config.SummaryPath = "summary.jsonl"
+	calls := provider.calls.Load()
+	if action := core.NewFlow(core.NewNode(node, 0, 3)).Run(&state); action != core.ActionSuccess {
+		t.Errorf("expected success on the rerun, got %v", action)
+	}
+	if report := state.Report; report.Succeeded != 1 || report.Skipped != 2 || report.Failed != 0 {
+		t.Errorf("unexpected rerun report: %s", report.Summary())
+	}
+	if parsed := provider.calls.Load() - calls; parsed != 1 {
+		t.Errorf("expected only the failed file to be parsed again, got %d calls", parsed)
+	}
+	if failures, _ := ReadFailures(filepath.Join(output, "failures.txt")); len(failures) != 0 {
+		t.Errorf("expected no failures left, got %v", failures)
+	}
+
+	summary, _ = os.ReadFile(filepath.Join(output, "summary.jsonl"))
+	var statuses []string
+	for _, line := range strings.Split(strings.TrimSpace(string(summary)), "\n") {
+		var entry summaryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid summary line %q: %v", line, err)
+		}
+		statuses = append(statuses, filepath.Base(entry.File)+"="+entry.Status)
+	}
+	if strings.Join(statuses, ",") != "b.txt=skipped,c.txt=ok,a.txt=skipped" {
+		t.Errorf("unexpected summary statuses %v", statuses)
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFiles(t, directory, map[string]string{
+		"a.pdf":         "",
+		"b.txt":         "",
+		"2024/c.pdf":    "",
+		"2024/q1/d.pdf": "",
+	})
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"*.pdf", "a.pdf"},
+		{"**/*.pdf", "2024/c.pdf,2024/q1/d.pdf,a.pdf"},
+		{"**", "2024/c.pdf,2024/q1/d.pdf,a.pdf,b.txt"},
+		{"2024/**/*.pdf", "2024/c.pdf,2024/q1/d.pdf"},
+		{"**/q1/*.pdf", "2024/q1/d.pdf"},
+		{"*", "a.pdf,b.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matches, err := globFiles(filepath.Join(directory, tt.pattern))
+			if err != nil {
+				t.Fatalf("globFiles() error = %v", err)
+			}
+			var names []string
+			for _, match := range matches {
+				relative, _ := filepath.Rel(directory, match)
+				names = append(names, filepath.ToSlash(relative))
+			}
+			if strings.Join(names, ",") != tt.want {
+				t.Errorf("globFiles() = %v, want %s", names, tt.want)
+			}
+		})
+	}
+
+	if _, err := globFiles(filepath.Join(directory, "*", "**", "*.pdf")); err == nil {
+		t.Error("expected an error for wildcards before **")
+	}
+}
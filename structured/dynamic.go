@@ -0,0 +1,711 @@
+package structured
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// DynamicSchema is an extraction target known only at run time, e.g. a schema
+// file given to a command. Its Type is a struct type built with reflect.StructOf
+// carrying the yaml, json, description and constraint tags a Go struct would,
+// so prompts and validation work as they do for compile-time types. Parsed
+// data is held in maps and decoded into Type with Decode.
+type DynamicSchema struct {
+	Name   string       // Name of the target, used for constrained decoding
+	Type   reflect.Type // Struct type of the target
+	schema *JSONSchema
+}
+
+// NewDynamicSchema creates a dynamic schema for a struct type
+func NewDynamicSchema(name string, t reflect.Type) (*DynamicSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dynamic schema %s must be a struct, got %s", name, t.Kind())
+	}
+	schema, err := buildSchema(t)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dynamic schema %s: %w", name, err)
+	}
+	return &DynamicSchema{Name: name, Type: t, schema: schema}, nil
+}
+
+// LoadDynamicSchema reads a schema file: Go struct definitions (.go) or a JSON
+// Schema (.json). typeName selects the struct of a Go file; empty picks the
+// first one declared.
+func LoadDynamicSchema(path, typeName string) (*DynamicSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return ParseGoSchema(data, typeName)
+	case ".json":
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		return ParseJSONSchema(data, strings.TrimSuffix(name, ".schema"))
+	default:
+		return nil, fmt.Errorf("unsupported schema file %s: use a .go file with struct types or a .json JSON Schema", path)
+	}
+}
+
+// JSONSchema returns the schema the parsed data is validated against
+func (d *DynamicSchema) JSONSchema() *JSONSchema {
+	return d.schema
+}
+
+// Prompt builds the extraction prompt for input, as ParseWithStructuredPrompt
+// does for compile-time types
+func (d *DynamicSchema) Prompt(input string, additionalContext []string, options ...prompt.Option) string {
+	return buildStructuredPromptFor(d.Type, input, additionalContext, options...)
+}
+
+// ResponseSchema returns the schema sent to providers supporting constrained decoding
+func (d *DynamicSchema) ResponseSchema() (llm.ResponseSchema, error) {
+	data, err := json.Marshal(d.schema)
+	if err != nil {
+		return llm.ResponseSchema{}, err
+	}
+	name := strings.Trim(schemaNamePattern.ReplaceAllString(d.Name, "_"), "_")
+	if name == "" {
+		name = "response"
+	}
+	return llm.ResponseSchema{Name: name, Description: d.schema.Description, Schema: data}, nil
+}
+
+// New returns a pointer to a new zero value of Type
+func (d *DynamicSchema) New() any {
+	return reflect.New(d.Type).Interface()
+}
+
+// Decode converts parsed data into a new value of Type and returns a pointer
+// to it; values of the wrong type are errors
+func (d *DynamicSchema) Decode(data map[string]any) (any, error) {
+	value := reflect.New(d.Type)
+	if err := decodeValue(data, value.Elem(), "", ""); err != nil {
+		return nil, err
+	}
+	return value.Interface(), nil
+}
+
+// Validate implements ValidatorInterface for data parsed into maps: it must
+// decode into Type and satisfy the schema
+func (d *DynamicSchema) Validate(data *map[string]any) error {
+	if data == nil {
+		return fmt.Errorf("data cannot be nil")
+	}
+	value, err := d.Decode(*data)
+	if err != nil {
+		return err
+	}
+	errs := d.schema.Validate(value)
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Errorf("schema validation failed: %s", strings.Join(messages, "; "))
+}
+
+// ParseDynamic extracts schema from input, re-prompting with parse and
+// validation errors for up to maxAttempts LLM calls. The data is returned as
+// a map; Decode converts it into a value of schema.Type.
+func ParseDynamic(p *Parser, ctx context.Context, schema *DynamicSchema, input string, maxAttempts int, additionalContext ...string) (ParseResult[map[string]any], error) {
+	return parseDynamic(p, ctx, schema, input, schema, maxAttempts, additionalContext...)
+}
+
+// parseDynamic is ParseDynamic with the validator run on every attempt
+func parseDynamic(p *Parser, ctx context.Context, schema *DynamicSchema, input string, validator ValidatorInterface[map[string]any], maxAttempts int, additionalContext ...string) (ParseResult[map[string]any], error) {
+	if strings.TrimSpace(input) == "" {
+		err := fmt.Errorf("text content is empty")
+		return ParseResult[map[string]any]{Error: err}, err
+	}
+	messages := []llm.Message{{Role: llm.RoleUser, Content: schema.Prompt(input, additionalContext, p.promptOptions()...)}}
+	result, _, err := parseMessagesWithRepair(p, ctx, messages, validator, maxAttempts, schema.ResponseSchema)
+	return result, err
+}
+
+// dynamicValidator validates data against a dynamic schema, then with next
+type dynamicValidator struct {
+	schema *DynamicSchema
+	next   ValidatorInterface[map[string]any]
+}
+
+// Validate implements ValidatorInterface
+func (v dynamicValidator) Validate(data *map[string]any) error {
+	if err := v.schema.Validate(data); err != nil {
+		return err
+	}
+	return v.next.Validate(data)
+}
+
+// ParseGoSchema builds a dynamic schema from Go struct definitions, e.g.
+//
+//	type Invoice struct {
+//		Number string  `json:"number" required:"true"`
+//		Total  float64 `json:"total" description:"Amount due"`
+//		Items  []Item  `json:"items"`
+//	}
+//
+// Field types may be basic types, time.Time, time.Duration, pointers, slices,
+// maps with string keys and other structs of the source. Comments on fields
+// without a description tag become their descriptions. typeName selects the
+// struct; empty picks the first one declared. The package clause is optional.
+func ParseGoSchema(source []byte, typeName string) (*DynamicSchema, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(source), []byte("package ")) {
+		source = append([]byte("package schema\n"), source...)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "schema.go", source, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Go schema: %w", err)
+	}
+
+	builder := &goSchemaBuilder{structs: make(map[string]*ast.StructType), built: make(map[string]reflect.Type), building: make(map[string]bool)}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+				builder.structs[typeSpec.Name.Name] = structType
+				builder.order = append(builder.order, typeSpec.Name.Name)
+			}
+		}
+	}
+	if len(builder.order) == 0 {
+		return nil, fmt.Errorf("invalid Go schema: no struct type declared")
+	}
+	if typeName == "" {
+		typeName = builder.order[0]
+	}
+	if builder.structs[typeName] == nil {
+		return nil, fmt.Errorf("invalid Go schema: struct %s is not declared; declared: %s", typeName, strings.Join(builder.order, ", "))
+	}
+
+	t, err := builder.named(typeName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Go schema: %w", err)
+	}
+	return NewDynamicSchema(typeName, t)
+}
+
+// goSchemaBuilder converts the struct types of a Go file into reflect types
+type goSchemaBuilder struct {
+	structs  map[string]*ast.StructType
+	order    []string
+	built    map[string]reflect.Type
+	building map[string]bool
+}
+
+// basicTypes are the predeclared types a Go schema may use
+var basicTypes = map[string]reflect.Type{
+	"string": reflect.TypeFor[string](), "bool": reflect.TypeFor[bool](),
+	"int": reflect.TypeFor[int](), "int8": reflect.TypeFor[int8](), "int16": reflect.TypeFor[int16](),
+	"int32": reflect.TypeFor[int32](), "int64": reflect.TypeFor[int64](),
+	"uint": reflect.TypeFor[uint](), "uint8": reflect.TypeFor[uint8](), "uint16": reflect.TypeFor[uint16](),
+	"uint32": reflect.TypeFor[uint32](), "uint64": reflect.TypeFor[uint64](),
+	"byte": reflect.TypeFor[byte](), "rune": reflect.TypeFor[rune](),
+	"float32": reflect.TypeFor[float32](), "float64": reflect.TypeFor[float64](),
+	"any": reflect.TypeFor[any](),
+}
+
+// named returns the type of the struct declared as name
+func (b *goSchemaBuilder) named(name string) (reflect.Type, error) {
+	if t, ok := b.built[name]; ok {
+		return t, nil
+	}
+	if b.building[name] {
+		return nil, fmt.Errorf("struct %s refers to itself, which dynamic schemas do not support", name)
+	}
+	b.building[name] = true
+	t, err := b.structOf(b.structs[name], name)
+	if err != nil {
+		return nil, err
+	}
+	b.built[name] = t
+	return t, nil
+}
+
+// structOf builds the struct type of a struct expression
+func (b *goSchemaBuilder) structOf(structType *ast.StructType, path string) (reflect.Type, error) {
+	var fields []reflect.StructField
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded fields are not supported", path)
+		}
+		tag := ""
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		// Parsed data is decoded through YAML and written as JSON, so both
+		// must use the same names
+		structTag := reflect.StructTag(tag)
+		if name, ok := structTag.Lookup("json"); ok && structTag.Get("yaml") == "" {
+			tag = strings.TrimSpace(tag + " yaml:" + strconv.Quote(name))
+		} else if name, ok := structTag.Lookup("yaml"); ok && structTag.Get("json") == "" {
+			tag = strings.TrimSpace(tag + " json:" + strconv.Quote(name))
+		}
+		// Comments describe fields without a description tag
+		if reflect.StructTag(tag).Get("description") == "" {
+			comment := strings.TrimSpace(field.Doc.Text() + " " + field.Comment.Text())
+			if comment != "" {
+				tag = strings.TrimSpace(tag + " description:" + strconv.Quote(strings.Join(strings.Fields(comment), " ")))
+			}
+		}
+
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			t, err := b.typeOf(field.Type, path+"."+name.Name)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, reflect.StructField{Name: name.Name, Type: t, Tag: reflect.StructTag(tag)})
+		}
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// typeOf converts a type expression
+func (b *goSchemaBuilder) typeOf(expr ast.Expr, path string) (reflect.Type, error) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if t, ok := basicTypes[expr.Name]; ok {
+			return t, nil
+		}
+		if b.structs[expr.Name] != nil {
+			return b.named(expr.Name)
+		}
+		return nil, fmt.Errorf("%s: unknown type %s", path, expr.Name)
+	case *ast.SelectorExpr:
+		if pkg, ok := expr.X.(*ast.Ident); ok && pkg.Name == "time" {
+			switch expr.Sel.Name {
+			case "Time":
+				return reflect.TypeFor[time.Time](), nil
+			case "Duration":
+				return reflect.TypeFor[time.Duration](), nil
+			}
+		}
+		return nil, fmt.Errorf("%s: unsupported type %s; only time.Time and time.Duration may come from other packages", path, exprString(expr))
+	case *ast.StarExpr:
+		t, err := b.typeOf(expr.X, path)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(t), nil
+	case *ast.ArrayType:
+		t, err := b.typeOf(expr.Elt, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(t), nil
+	case *ast.MapType:
+		if key, ok := expr.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("%s: map keys must be strings", path)
+		}
+		t, err := b.typeOf(expr.Value, path+"{}")
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(reflect.TypeFor[string](), t), nil
+	case *ast.InterfaceType:
+		if len(expr.Methods.List) > 0 {
+			return nil, fmt.Errorf("%s: interfaces with methods are not supported", path)
+		}
+		return reflect.TypeFor[any](), nil
+	case *ast.StructType:
+		return b.structOf(expr, path)
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %s", path, exprString(expr))
+	}
+}
+
+// exprString returns the source of a type expression for error messages
+func exprString(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return exprString(expr.X) + "." + expr.Sel.Name
+	case *ast.ChanType:
+		return "chan " + exprString(expr.Value)
+	case *ast.FuncType:
+		return "func"
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+// jsonSchemaDocument is the part of a JSON Schema document dynamic schemas support
+type jsonSchemaDocument struct {
+	Title                string                         `json:"title"`
+	Description          string                         `json:"description"`
+	Type                 json.RawMessage                `json:"type"`
+	Properties           orderedProperties              `json:"properties"`
+	Required             []string                       `json:"required"`
+	Items                *jsonSchemaDocument            `json:"items"`
+	AdditionalProperties json.RawMessage                `json:"additionalProperties"`
+	Enum                 []any                          `json:"enum"`
+	Format               string                         `json:"format"`
+	Pattern              string                         `json:"pattern"`
+	Minimum              *float64                       `json:"minimum"`
+	Maximum              *float64                       `json:"maximum"`
+	MinLength            *int                           `json:"minLength"`
+	MaxLength            *int                           `json:"maxLength"`
+	MinItems             *int                           `json:"minItems"`
+	MaxItems             *int                           `json:"maxItems"`
+	Ref                  string                         `json:"$ref"`
+	Defs                 map[string]*jsonSchemaDocument `json:"$defs"`
+	Definitions          map[string]*jsonSchemaDocument `json:"definitions"`
+}
+
+// orderedProperties keeps the properties of a JSON Schema in document order,
+// so prompts and output list fields as the schema does
+type orderedProperties struct {
+	names  []string
+	values map[string]*jsonSchemaDocument
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *orderedProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.values); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		p.names = append(p.names, key.(string))
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseJSONSchema builds a dynamic schema from a JSON Schema whose root is an
+// object. Supported are the types, properties, required, items,
+// additionalProperties, enum, format, pattern, length, item count and numeric
+// bounds, and $ref to $defs or definitions.
+func ParseJSONSchema(data []byte, name string) (*DynamicSchema, error) {
+	var root jsonSchemaDocument
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	if root.Title != "" {
+		name = root.Title
+	}
+	builder := &jsonSchemaBuilder{root: &root, building: make(map[string]bool)}
+	t, err := builder.typeOf(&root, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("invalid JSON Schema: the root must be an object with properties")
+	}
+	schema, err := NewDynamicSchema(name, t)
+	if err != nil {
+		return nil, err
+	}
+	schema.schema.Description = root.Description
+	return schema, nil
+}
+
+// jsonSchemaBuilder converts JSON Schema documents into reflect types
+type jsonSchemaBuilder struct {
+	root     *jsonSchemaDocument
+	building map[string]bool
+}
+
+// typeOf converts a schema into a type
+func (b *jsonSchemaBuilder) typeOf(schema *jsonSchemaDocument, path string) (reflect.Type, error) {
+	if schema.Ref != "" {
+		return b.ref(schema.Ref, path)
+	}
+
+	schemaType, err := jsonSchemaType(schema.Type)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", displayPath(path), err)
+	}
+	if schemaType == "" && len(schema.Properties.names) > 0 {
+		schemaType = "object"
+	}
+	switch schemaType {
+	case "string":
+		return reflect.TypeFor[string](), nil
+	case "integer":
+		return reflect.TypeFor[int64](), nil
+	case "number":
+		return reflect.TypeFor[float64](), nil
+	case "boolean":
+		return reflect.TypeFor[bool](), nil
+	case "array":
+		if schema.Items == nil {
+			return reflect.TypeFor[[]any](), nil
+		}
+		items, err := b.typeOf(schema.Items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(items), nil
+	case "object":
+		if len(schema.Properties.names) > 0 {
+			return b.structOf(schema, path)
+		}
+		var values jsonSchemaDocument
+		if err := json.Unmarshal(schema.AdditionalProperties, &values); err != nil {
+			// Absent or boolean additionalProperties allow any value
+			return reflect.TypeFor[map[string]any](), nil
+		}
+		valueType, err := b.typeOf(&values, path+"{}")
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(reflect.TypeFor[string](), valueType), nil
+	case "":
+		return reflect.TypeFor[any](), nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %q", displayPath(path), schemaType)
+	}
+}
+
+// ref resolves a reference to a definition of the root schema
+func (b *jsonSchemaBuilder) ref(ref, path string) (reflect.Type, error) {
+	var definitions map[string]*jsonSchemaDocument
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if ok {
+		definitions = b.root.Defs
+	} else if name, ok = strings.CutPrefix(ref, "#/definitions/"); ok {
+		definitions = b.root.Definitions
+	}
+	definition := definitions[name]
+	if definition == nil {
+		return nil, fmt.Errorf("%s: unresolved reference %s", displayPath(path), ref)
+	}
+	if b.building[ref] {
+		return nil, fmt.Errorf("%s: %s refers to itself, which dynamic schemas do not support", displayPath(path), ref)
+	}
+	b.building[ref] = true
+	defer delete(b.building, ref)
+	return b.typeOf(definition, path)
+}
+
+// structOf builds the struct type of an object schema with properties
+func (b *jsonSchemaBuilder) structOf(schema *jsonSchemaDocument, path string) (reflect.Type, error) {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	fields := make([]reflect.StructField, 0, len(schema.Properties.names))
+	used := make(map[string]bool)
+	for _, name := range schema.Properties.names {
+		property := schema.Properties.values[name]
+		propertyPath := joinPath(path, name)
+		t, err := b.typeOf(property, propertyPath)
+		if err != nil {
+			return nil, err
+		}
+		constraints := property
+		if property.Ref != "" {
+			// Constraints of a referenced scalar apply to the property, along
+			// with those next to the $ref
+			definition, _ := b.resolve(property.Ref)
+			constraints = withSiblings(definition, property)
+		}
+		tag, err := propertyTag(name, required[name], property.Description, constraints, t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", displayPath(propertyPath), err)
+		}
+		fields = append(fields, reflect.StructField{Name: goFieldName(name, used), Type: t, Tag: tag})
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// resolve returns the definition a reference points to
+func (b *jsonSchemaBuilder) resolve(ref string) (*jsonSchemaDocument, bool) {
+	if name, ok := strings.CutPrefix(ref, "#/$defs/"); ok && b.root.Defs[name] != nil {
+		return b.root.Defs[name], true
+	}
+	if name, ok := strings.CutPrefix(ref, "#/definitions/"); ok && b.root.Definitions[name] != nil {
+		return b.root.Definitions[name], true
+	}
+	return &jsonSchemaDocument{}, false
+}
+
+// withSiblings returns the definition with the constraints next to the $ref
+// of property added; where both set one, that of property wins
+func withSiblings(definition, property *jsonSchemaDocument) *jsonSchemaDocument {
+	merged := *definition
+	if len(property.Enum) > 0 {
+		merged.Enum = property.Enum
+	}
+	if property.Format != "" {
+		merged.Format = property.Format
+	}
+	if property.Pattern != "" {
+		merged.Pattern = property.Pattern
+	}
+	if property.Minimum != nil {
+		merged.Minimum = property.Minimum
+	}
+	if property.Maximum != nil {
+		merged.Maximum = property.Maximum
+	}
+	if property.MinLength != nil {
+		merged.MinLength = property.MinLength
+	}
+	if property.MaxLength != nil {
+		merged.MaxLength = property.MaxLength
+	}
+	if property.MinItems != nil {
+		merged.MinItems = property.MinItems
+	}
+	if property.MaxItems != nil {
+		merged.MaxItems = property.MaxItems
+	}
+	return &merged
+}
+
+// jsonSchemaType returns the type of a schema: a string, or the first type
+// other than "null" of a list
+func jsonSchemaType(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return "", fmt.Errorf("type must be a string or a list of strings")
+	}
+	for _, t := range list {
+		if t != "null" {
+			return t, nil
+		}
+	}
+	return "", nil
+}
+
+// propertyTag returns the struct tag carrying a property's name, description
+// and constraints, in the form the prompt generator and schema validation read
+func propertyTag(name string, required bool, description string, schema *jsonSchemaDocument, t reflect.Type) (reflect.StructTag, error) {
+	names := name
+	if !required {
+		names += ",omitempty"
+	}
+	parts := []string{"json:" + strconv.Quote(names), "yaml:" + strconv.Quote(names)}
+	add := func(key, value string) {
+		parts = append(parts, key+":"+strconv.Quote(value))
+	}
+	if description != "" {
+		add("description", description)
+	}
+	if required {
+		add("required", "true")
+	}
+
+	// enum and format of lists constrain their items
+	values := schema
+	if t.Kind() == reflect.Slice && schema.Items != nil {
+		values = schema.Items
+	}
+	if len(values.Enum) > 0 {
+		enum := make([]string, len(values.Enum))
+		for i, value := range values.Enum {
+			enum[i] = fmt.Sprint(value)
+			if strings.Contains(enum[i], ",") {
+				return "", fmt.Errorf("enum value %q contains a comma, which dynamic schemas do not support", enum[i])
+			}
+		}
+		add("enum", strings.Join(enum, ","))
+	}
+	if values.Format != "" {
+		add("format", values.Format)
+	}
+	if schema.Pattern != "" {
+		add("pattern", schema.Pattern)
+	}
+
+	bound := func(key string, value any) {
+		switch value := value.(type) {
+		case *float64:
+			if value != nil {
+				add(key, strconv.FormatFloat(*value, 'f', -1, 64))
+			}
+		case *int:
+			if value != nil {
+				add(key, strconv.Itoa(*value))
+			}
+		}
+	}
+	switch t.Kind() {
+	case reflect.Int64, reflect.Float64:
+		bound("min", schema.Minimum)
+		bound("max", schema.Maximum)
+	case reflect.String:
+		bound("min", schema.MinLength)
+		bound("max", schema.MaxLength)
+	case reflect.Slice:
+		bound("min", schema.MinItems)
+		bound("max", schema.MaxItems)
+	}
+	return reflect.StructTag(strings.Join(parts, " ")), nil
+}
+
+// goFieldName returns an exported Go identifier for a property name, e.g.
+// "InvoiceNumber" for "invoice_number", unique among used
+func goFieldName(name string, used map[string]bool) string {
+	var builder strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+	field := builder.String()
+	if field == "" || !unicode.IsUpper([]rune(field)[0]) {
+		field = "F" + field
+	}
+	unique := field
+	for i := 2; used[unique]; i++ {
+		unique = field + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,260 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+const invoiceGoSchema = `
+type Invoice struct {
+	Number string    ` + "`json:\"number\" required:\"true\"`" + `
+	Issued time.Time ` + "`json:\"issued\"`" + `
+	// Amount due including tax
+	Total  float64   ` + "`json:\"total\" min:\"0\"`" + `
+	Status string    ` + "`json:\"status\" enum:\"paid,open\"`" + `
+	Items  []Item    ` + "`json:\"items\"`" + `
+	notes  string
+}
+
+type Item struct {
+	Name     string ` + "`json:\"line_name\"`" + `
+	Quantity int    ` + "`json:\"quantity\"`" + ` // Units ordered
+}
+`
+
+const invoiceJSONSchema = `{
+	"title": "Invoice",
+	"type": "object",
+	"properties": {
+		"number": {"type": "string", "description": "Invoice number"},
+		"total": {"type": ["number", "null"], "minimum": 0},
+		"status": {"type": "string", "enum": ["paid", "open"]},
+		"items": {"type": "array", "items": {"$ref": "#/$defs/item"}, "maxItems": 5}
+	},
+	"required": ["number"],
+	"$defs": {
+		"item": {
+			"type": "object",
+			"properties": {"line_name": {"type": "string"}, "quantity": {"type": "integer"}}
+		}
+	}
+}`
+
+func TestParseGoSchema(t *testing.T) {
+	schema, err := ParseGoSchema([]byte(invoiceGoSchema), "")
+	if err != nil {
+		t.Fatalf("ParseGoSchema() error = %v", err)
+	}
+	if schema.Name != "Invoice" {
+		t.Errorf("expected the first struct, got %s", schema.Name)
+	}
+
+	var fields []string
+	for i := 0; i < schema.Type.NumField(); i++ {
+		fields = append(fields, schema.Type.Field(i).Name)
+	}
+	if strings.Join(fields, ",") != "Number,Issued,Total,Status,Items" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if field, _ := schema.Type.FieldByName("Issued"); field.Type != reflect.TypeFor[time.Time]() {
+		t.Errorf("expected time.Time, got %s", field.Type)
+	}
+	if field, _ := schema.Type.FieldByName("Total"); field.Tag.Get("description") != "Amount due including tax" {
+		t.Errorf("expected the comment as description, got %q", field.Tag)
+	}
+	items, _ := schema.Type.FieldByName("Items")
+	if quantity, _ := items.Type.Elem().FieldByName("Quantity"); quantity.Tag.Get("description") != "Units ordered" {
+		t.Errorf("expected the line comment as description, got %q", quantity.Tag)
+	}
+
+	prompt := schema.Prompt("Invoice 42", nil)
+	for _, want := range []string{"Invoice 42", "number", "Amount due including tax", "paid"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected %q in prompt:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestParseGoSchema_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		typeName string
+		want     string
+	}{
+		{"no struct", "type ID string", "", "no struct type declared"},
+		{"missing type", "type A struct{ X int }", "B", "struct B is not declared"},
+		{"unknown type", "type A struct{ X Money }", "", "unknown type Money"},
+		{"foreign package", "type A struct{ X big.Int }", "", "unsupported type big.Int"},
+		{"recursive", "type A struct{ Next *A }", "", "refers to itself"},
+		{"embedded", "type A struct{ B }\ntype B struct{}", "", "embedded fields"},
+		{"map key", "type A struct{ X map[int]string }", "", "map keys must be strings"},
+		{"syntax", "type A struct{", "", "invalid Go schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGoSchema([]byte(tt.source), tt.typeName)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseJSONSchema(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(invoiceJSONSchema), "invoice")
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+	if schema.Name != "Invoice" {
+		t.Errorf("expected the title as name, got %s", schema.Name)
+	}
+
+	tests := []struct {
+		field string
+		tag   string
+		want  string
+	}{
+		{"Number", "required", "true"},
+		{"Number", "description", "Invoice number"},
+		{"Total", "json", "total,omitempty"},
+		{"Total", "min", "0"},
+		{"Status", "enum", "paid,open"},
+		{"Items", "max", "5"},
+	}
+	for _, tt := range tests {
+		field, ok := schema.Type.FieldByName(tt.field)
+		if !ok {
+			t.Fatalf("missing field %s in %s", tt.field, schema.Type)
+		}
+		if got := field.Tag.Get(tt.tag); got != tt.want {
+			t.Errorf("%s tag %s = %q, want %q", tt.field, tt.tag, got, tt.want)
+		}
+	}
+
+	items, _ := schema.Type.FieldByName("Items")
+	if _, ok := items.Type.Elem().FieldByName("LineName"); !ok {
+		t.Errorf("expected the referenced item struct, got %s", items.Type)
+	}
+	if total, _ := schema.Type.FieldByName("Total"); total.Type.Kind() != reflect.Float64 {
+		t.Errorf("expected the first non-null type, got %s", total.Type)
+	}
+}
+
+func TestParseJSONSchema_RefSiblings(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{
+		"type": "object",
+		"properties": {"code": {"$ref": "#/$defs/code", "maxLength": 3}},
+		"$defs": {"code": {"type": "string", "pattern": "^[A-Z]+$", "minLength": 2}}
+	}`), "ticket")
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+	field, _ := schema.Type.FieldByName("Code")
+	for tag, want := range map[string]string{"pattern": "^[A-Z]+$", "min": "2", "max": "3"} {
+		if got := field.Tag.Get(tag); got != want {
+			t.Errorf("code tag %s = %q, want %q", tag, got, want)
+		}
+	}
+	if err := schema.Validate(&map[string]any{"code": "ABCD"}); err == nil || !strings.Contains(err.Error(), "at most 3") {
+		t.Errorf("Validate() error = %v, want the length next to $ref enforced", err)
+	}
+}
+
+func TestDynamicSchema_Validate(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(invoiceJSONSchema), "invoice")
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data map[string]any
+		want string
+	}{
+		{"valid", map[string]any{"number": "A-1", "total": 10.5, "items": []any{map[string]any{"line_name": "pen", "quantity": 2}}}, ""},
+		{"missing required", map[string]any{"total": 1}, "required"},
+		{"below minimum", map[string]any{"number": "A-1", "total": -1}, "total"},
+		{"not in enum", map[string]any{"number": "A-1", "status": "void"}, "status"},
+		{"wrong type", map[string]any{"number": "A-1", "total": "lots"}, "lots"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(&tt.data)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseDynamic_Repairs(t *testing.T) {
+	schema, err := ParseGoSchema([]byte(invoiceGoSchema), "Invoice")
+	if err != nil {
+		t.Fatalf("ParseGoSchema() error = %v", err)
+	}
+	provider := &scriptedProvider{responses: []string{
+		"```yaml\nnumber: A-1\nstatus: void\n```",
+		"```yaml\nnumber: A-1\nstatus: paid\nissued: 2024-03-01\nitems:\n  - line_name: pen\n    quantity: 2\n```",
+	}}
+
+	result, err := ParseDynamic(newTestParser(t, provider), context.Background(), schema, "Invoice A-1", 2)
+	if err != nil {
+		t.Fatalf("ParseDynamic() error = %v", err)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("expected a repaired result after 2 attempts, got %d", result.Attempts)
+	}
+
+	value, err := schema.Decode(*result.Data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	encoded, _ := json.Marshal(value)
+	if !strings.Contains(string(encoded), `"issued":"2024-03-01T00:00:00Z"`) || !strings.Contains(string(encoded), `"line_name":"pen"`) {
+		t.Errorf("unexpected decoded value %s", encoded)
+	}
+}
+
+func TestBatchStructuredNode_DynamicSchema(t *testing.T) {
+	schema, err := ParseGoSchema([]byte("type Person struct {\n\tName string `yaml:\"name\" required:\"true\"`\n\tCount int `yaml:\"count\"`\n}"), "")
+	if err != nil {
+		t.Fatalf("ParseGoSchema() error = %v", err)
+	}
+	if _, err := NewBatchStructuredNode[repairTarget, *BatchState[repairTarget]](&echoNameProvider{}, &BatchConfig{Schema: schema}, nil); err == nil {
+		t.Error("expected an error for a dynamic schema without map results")
+	}
+
+	config := DefaultBatchConfig()
+	config.RequestsPerSecond = 0
+	config.MaxRetries = 0
+	config.Schema = schema
+	node, err := NewBatchStructuredNode[map[string]any, *BatchState[map[string]any]](&echoNameProvider{}, config, nil)
+	if err != nil {
+		t.Fatalf("NewBatchStructuredNode() error = %v", err)
+	}
+
+	state := &BatchState[map[string]any]{Inputs: []BatchInput{{Text: "Ada"}, {Text: "Grace"}, {FilePath: "scan.png"}}}
+	core.NewFlow(core.NewNode(node, 0, 2)).Run(&state)
+	if state.Report.Succeeded != 2 || state.Report.Failed != 1 {
+		t.Fatalf("unexpected report: %s", state.Report.Summary())
+	}
+	if name := (*state.Report.Successes()[1])["name"]; name != "Grace" {
+		t.Errorf("expected Grace, got %v", name)
+	}
+	if !strings.Contains(state.Report.Summary(), "image inputs are not supported") {
+		t.Errorf("expected the image input to fail, got %s", state.Report.Summary())
+	}
+}
@@ -0,0 +1,603 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Format identifies an output format the parser understands
+type Format string
+
+const (
+	FormatYAML  Format = "yaml"
+	FormatJSON  Format = "json"
+	FormatJSON5 Format = "json5"
+	FormatTOML  Format = "toml"
+	FormatXML   Format = "xml"
+)
+
+// ExtractFencedBlock returns the content of the first fenced code block tagged with
+// one of the given languages, or "" if there is none
+func ExtractFencedBlock(response string, languages ...string) string {
+	blocks := ExtractCodeBlocks(response)
+	for _, language := range languages {
+		for _, block := range blocks {
+			if !block.Detected && block.Language == NormalizeLanguage(language) {
+				return block.Content
+			}
+		}
+	}
+	return ""
+}
+
+// ExtractJSON5FromResponse extracts relaxed JSON (comments, trailing commas,
+// single quotes, unquoted keys) and normalizes it to strict JSON
+func ExtractJSON5FromResponse(response string) string {
+	content := ExtractFencedBlock(response, "json5", "jsonc", "json", "js", "javascript")
+	if content == "" {
+		content = strings.TrimSpace(ExtractJSONFromResponse(response))
+	}
+	if !strings.HasPrefix(content, "{") && !strings.HasPrefix(content, "[") {
+		return ""
+	}
+	return NormalizeRelaxedJSON(content)
+}
+
+// NormalizeRelaxedJSON converts JSON5-style input into strict JSON. It removes
+// comments and trailing commas, converts single-quoted strings and quotes bare keys.
+func NormalizeRelaxedJSON(input string) string {
+	var out strings.Builder
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+
+		switch {
+		case c == '"' || c == '\'':
+			// Copy the string, re-quoting single-quoted strings with double quotes
+			quote := c
+			out.WriteRune('"')
+			for i++; i < len(runes) && runes[i] != quote; i++ {
+				switch {
+				case runes[i] == '\\' && i+1 < len(runes):
+					if runes[i+1] == '\'' {
+						out.WriteRune('\'')
+					} else {
+						out.WriteRune('\\')
+						out.WriteRune(runes[i+1])
+					}
+					i++
+				case runes[i] == '"':
+					out.WriteString(`\"`)
+				case runes[i] == '\n':
+					out.WriteString(`\n`)
+				default:
+					out.WriteRune(runes[i])
+				}
+			}
+			out.WriteRune('"')
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			if i < len(runes) {
+				out.WriteRune('\n')
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == ',':
+			// Drop commas that are followed only by whitespace before a closing bracket
+			j := i + 1
+			for j < len(runes) && strings.ContainsRune(" \t\r\n", runes[j]) {
+				j++
+			}
+			if j < len(runes) && (runes[j] == '}' || runes[j] == ']') {
+				continue
+			}
+			out.WriteRune(c)
+		case isIdentifierStart(c):
+			j := i
+			for j < len(runes) && isIdentifierPart(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			k := j
+			for k < len(runes) && strings.ContainsRune(" \t", runes[k]) {
+				k++
+			}
+			if k < len(runes) && runes[k] == ':' {
+				out.WriteString(strconv.Quote(word))
+			} else {
+				out.WriteString(word)
+			}
+			i = j - 1
+		default:
+			out.WriteRune(c)
+		}
+	}
+
+	return out.String()
+}
+
+// isIdentifierStart reports whether r may start a bare JSON5 key
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isIdentifierPart reports whether r may continue a bare JSON5 key
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || (r >= '0' && r <= '9') || r == '-'
+}
+
+// ExtractTOMLFromResponse extracts TOML content from a ```toml block or from
+// lines that look like "key = value" assignments and [table] headers
+func ExtractTOMLFromResponse(response string) string {
+	if content := ExtractFencedBlock(response, "toml"); content != "" {
+		return content
+	}
+
+	var tomlLines []string
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if tomlAssignment.MatchString(trimmed) || tomlTableHeader.MatchString(trimmed) {
+			tomlLines = append(tomlLines, line)
+		} else if trimmed != "" && len(tomlLines) > 0 && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+	}
+	return strings.Join(tomlLines, "\n")
+}
+
+var (
+	tomlAssignment  = regexp.MustCompile(`^[A-Za-z0-9_\-."]+\s*=\s*.+$`)
+	tomlTableHeader = regexp.MustCompile(`^\[\[?[A-Za-z0-9_\-."]+\]\]?$`)
+)
+
+// ParseTOML parses the commonly generated subset of TOML (tables, arrays of tables,
+// dotted keys, strings, numbers, booleans, arrays and inline tables) into a map
+func ParseTOML(content string) (map[string]any, error) {
+	root := make(map[string]any)
+	current := root
+
+	lines := strings.Split(content, "\n")
+	for lineNumber := 0; lineNumber < len(lines); lineNumber++ {
+		line := strings.TrimSpace(stripTOMLComment(lines[lineNumber]))
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]"):
+			path := splitTOMLKey(line[2 : len(line)-2])
+			parent, err := tomlTable(root, path[:len(path)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber+1, err)
+			}
+			key := path[len(path)-1]
+			list, _ := parent[key].([]any)
+			table := make(map[string]any)
+			parent[key] = append(list, table)
+			current = table
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			table, err := tomlTable(root, splitTOMLKey(line[1:len(line)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber+1, err)
+			}
+			current = table
+		default:
+			eq := strings.Index(line, "=")
+			if eq == -1 {
+				return nil, fmt.Errorf("line %d: expected key = value", lineNumber+1)
+			}
+			path := splitTOMLKey(line[:eq])
+			rawValue := strings.TrimSpace(line[eq+1:])
+
+			// Multi-line arrays and strings continue until brackets/quotes balance
+			for !tomlValueComplete(rawValue) && lineNumber+1 < len(lines) {
+				lineNumber++
+				rawValue += "\n" + strings.TrimSpace(stripTOMLComment(lines[lineNumber]))
+			}
+
+			value, rest, err := parseTOMLValue(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber+1, err)
+			}
+			if strings.TrimSpace(rest) != "" {
+				return nil, fmt.Errorf("line %d: unexpected trailing content %q", lineNumber+1, rest)
+			}
+
+			table, err := tomlTable(current, path[:len(path)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber+1, err)
+			}
+			table[path[len(path)-1]] = value
+		}
+	}
+
+	return root, nil
+}
+
+// tomlTable walks (and creates) nested tables along a dotted path
+func tomlTable(root map[string]any, path []string) (map[string]any, error) {
+	table := root
+	for _, key := range path {
+		switch next := table[key].(type) {
+		case nil:
+			created := make(map[string]any)
+			table[key] = created
+			table = created
+		case map[string]any:
+			table = next
+		case []any:
+			// Dotted headers refer to the last element of an array of tables
+			if len(next) == 0 {
+				return nil, fmt.Errorf("empty array of tables %q", key)
+			}
+			last, ok := next[len(next)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not a table", key)
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("key %q is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// splitTOMLKey splits a dotted key and removes quotes from each part
+func splitTOMLKey(key string) []string {
+	parts := strings.Split(strings.TrimSpace(key), ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return parts
+}
+
+// stripTOMLComment removes a trailing # comment that is not inside a string
+func stripTOMLComment(line string) string {
+	inString := rune(0)
+	for i, r := range line {
+		switch {
+		case inString != 0 && r == inString:
+			inString = 0
+		case inString == 0 && (r == '"' || r == '\''):
+			inString = r
+		case inString == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlValueComplete reports whether brackets and quotes in a raw value are balanced
+func tomlValueComplete(value string) bool {
+	if strings.HasPrefix(value, `"""`) {
+		return strings.Count(value, `"""`) >= 2
+	}
+	depth := 0
+	inString := rune(0)
+	for _, r := range value {
+		switch {
+		case inString != 0 && r == inString:
+			inString = 0
+		case inString != 0:
+		case r == '"' || r == '\'':
+			inString = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		}
+	}
+	return depth <= 0 && inString == 0
+}
+
+// parseTOMLValue parses one value and returns the unparsed remainder
+func parseTOMLValue(raw string) (any, string, error) {
+	raw = strings.TrimLeft(raw, " \t\r\n")
+	if raw == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+
+	switch {
+	case strings.HasPrefix(raw, `"""`):
+		end := strings.Index(raw[3:], `"""`)
+		if end == -1 {
+			return nil, "", fmt.Errorf("unterminated multi-line string")
+		}
+		return strings.TrimPrefix(raw[3:3+end], "\n"), raw[6+end:], nil
+	case raw[0] == '"':
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '\\' {
+				i++
+				continue
+			}
+			if raw[i] == '"' {
+				value, err := strconv.Unquote(raw[:i+1])
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid string %s: %w", raw[:i+1], err)
+				}
+				return value, raw[i+1:], nil
+			}
+		}
+		return nil, "", fmt.Errorf("unterminated string")
+	case raw[0] == '\'':
+		end := strings.Index(raw[1:], "'")
+		if end == -1 {
+			return nil, "", fmt.Errorf("unterminated literal string")
+		}
+		return raw[1 : 1+end], raw[2+end:], nil
+	case raw[0] == '[':
+		var items []any
+		rest := raw[1:]
+		for {
+			rest = strings.TrimLeft(rest, " \t\r\n,")
+			if strings.HasPrefix(rest, "]") {
+				return items, rest[1:], nil
+			}
+			if rest == "" {
+				return nil, "", fmt.Errorf("unterminated array")
+			}
+			item, remainder, err := parseTOMLValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+			rest = remainder
+		}
+	case raw[0] == '{':
+		table := make(map[string]any)
+		rest := raw[1:]
+		for {
+			rest = strings.TrimLeft(rest, " \t,")
+			if strings.HasPrefix(rest, "}") {
+				return table, rest[1:], nil
+			}
+			eq := strings.Index(rest, "=")
+			if eq == -1 {
+				return nil, "", fmt.Errorf("invalid inline table")
+			}
+			path := splitTOMLKey(rest[:eq])
+			value, remainder, err := parseTOMLValue(rest[eq+1:])
+			if err != nil {
+				return nil, "", err
+			}
+			target, err := tomlTable(table, path[:len(path)-1])
+			if err != nil {
+				return nil, "", err
+			}
+			target[path[len(path)-1]] = value
+			rest = remainder
+		}
+	default:
+		end := strings.IndexAny(raw, ",]}\n")
+		if end == -1 {
+			end = len(raw)
+		}
+		token := strings.TrimSpace(raw[:end])
+		return parseScalar(strings.ReplaceAll(token, "_", "")), raw[end:], nil
+	}
+}
+
+// parseScalar converts a bare token into a bool, integer, float or string
+func parseScalar(token string) any {
+	switch token {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f
+	}
+	return token
+}
+
+// ExtractXMLFromResponse extracts an XML document from a ```xml block or from the
+// first element found in the response
+func ExtractXMLFromResponse(response string) string {
+	if content := ExtractFencedBlock(response, "xml"); content != "" {
+		return content
+	}
+
+	start := strings.Index(response, "<")
+	end := strings.LastIndex(response, ">")
+	if start == -1 || end <= start {
+		return ""
+	}
+	return strings.TrimSpace(response[start : end+1])
+}
+
+// xmlNode is an element parsed from a simple XML document
+type xmlNode struct {
+	name     string
+	text     strings.Builder
+	children []*xmlNode
+}
+
+// ParseXML converts simple XML (elements and text, attributes ignored) into a map.
+// Repeated child elements become lists and the root element is unwrapped.
+func ParseXML(content string) (map[string]any, error) {
+	decoder := xml.NewDecoder(bytes.NewBufferString(content))
+	decoder.Strict = false
+
+	var stack []*xmlNode
+	var root *xmlNode
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no XML element found")
+	}
+
+	result, ok := xmlNodeValue(root, true).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("root element <%s> has no child elements", root.name)
+	}
+	return result, nil
+}
+
+// xmlNodeValue converts an element into a scalar, or a map for elements with children.
+// Below the root, an element whose children all repeat one name becomes a list, so
+// <items><item>..</item><item>..</item></items> decodes into a slice field.
+func xmlNodeValue(node *xmlNode, isRoot bool) any {
+	if len(node.children) == 0 {
+		return parseScalar(strings.TrimSpace(node.text.String()))
+	}
+
+	result := make(map[string]any)
+	for _, child := range node.children {
+		value := xmlNodeValue(child, false)
+		switch existing := result[child.name].(type) {
+		case nil:
+			result[child.name] = value
+		case []any:
+			result[child.name] = append(existing, value)
+		default:
+			result[child.name] = []any{existing, value}
+		}
+	}
+
+	if !isRoot && len(result) == 1 && len(node.children) > 1 {
+		if list, ok := result[node.children[0].name].([]any); ok {
+			return list
+		}
+	}
+	return result
+}
+
+// decodeGeneric decodes a generic map into T, matching yaml tags first and json tags second
+func decodeGeneric[T any](data map[string]any, result *T) error {
+	if converters.needsConversion(reflect.TypeOf(result).Elem()) {
+		var candidate T
+		if err := decodeValue(data, reflect.ValueOf(&candidate).Elem(), "", ""); err != nil {
+			return err
+		}
+		*result = candidate
+		return nil
+	}
+
+	yamlBytes, err := yaml.Marshal(data)
+	if err == nil {
+		var candidate T
+		if err = yaml.Unmarshal(yamlBytes, &candidate); err == nil {
+			*result = candidate
+			return nil
+		}
+	}
+
+	jsonBytes, jsonErr := json.Marshal(data)
+	if jsonErr != nil {
+		return jsonErr
+	}
+	var candidate T
+	if jsonErr = json.Unmarshal(jsonBytes, &candidate); jsonErr != nil {
+		return fmt.Errorf("yaml: %v; json: %w", err, jsonErr)
+	}
+	*result = candidate
+	return nil
+}
+
+// hasAlternateFormatHint reports whether a response is explicitly in JSON5, TOML
+// or XML: a fenced block tagged with that format, or XML starting on its own line
+func hasAlternateFormatHint(response string) bool {
+	if ExtractFencedBlock(response, "json5", "jsonc", "toml", "xml") != "" {
+		return true
+	}
+	if strings.Contains(response, "```") {
+		return false
+	}
+
+	start := strings.Index(response, "<")
+	if start == -1 || start+1 >= len(response) || !isIdentifierStart(rune(response[start+1])) && response[start+1] != '?' {
+		return false
+	}
+	return strings.TrimSpace(response[:start]) == "" || strings.HasSuffix(strings.TrimRight(response[:start], " \t"), "\n")
+}
+
+// parseAlternateFormats tries relaxed JSON, TOML and XML in that order
+func parseAlternateFormats[T any](responseContent string, diagnostics *ParseDiagnostics) (*T, Format, error) {
+	if content := ExtractJSON5FromResponse(responseContent); content != "" {
+		var result T
+		err := unmarshalWithConverters([]byte(content), json.Unmarshal, &result)
+		if err == nil {
+			return &result, FormatJSON5, nil
+		}
+		var generic map[string]any
+		if json.Unmarshal([]byte(content), &generic) == nil {
+			if err = decodeGeneric(generic, &result); err == nil {
+				return &result, FormatJSON5, nil
+			}
+		}
+		diagnostics.record(FormatJSON5, content, err)
+	}
+
+	if content := ExtractTOMLFromResponse(responseContent); content != "" {
+		data, err := ParseTOML(content)
+		if err == nil {
+			var result T
+			if err = decodeGeneric(data, &result); err == nil {
+				return &result, FormatTOML, nil
+			}
+		}
+		diagnostics.record(FormatTOML, content, err)
+	}
+
+	if content := ExtractXMLFromResponse(responseContent); content != "" {
+		data, err := ParseXML(content)
+		if err == nil {
+			var result T
+			if err = decodeGeneric(data, &result); err == nil {
+				return &result, FormatXML, nil
+			}
+		}
+		diagnostics.record(FormatXML, content, err)
+	}
+
+	return nil, "", fmt.Errorf("no alternate format matched")
+}
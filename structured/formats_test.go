@@ -0,0 +1,91 @@
+package structured
+
+import (
+	"reflect"
+	"testing"
+)
+
+type formatItem struct {
+	Description string  `yaml:"description" json:"description"`
+	Quantity    int     `yaml:"quantity" json:"quantity"`
+	Price       float64 `yaml:"price" json:"price"`
+}
+
+type formatInvoice struct {
+	Number string       `yaml:"number" json:"number"`
+	Paid   bool         `yaml:"paid" json:"paid"`
+	Tags   []string     `yaml:"tags" json:"tags"`
+	Items  []formatItem `yaml:"items" json:"items"`
+}
+
+var expectedInvoice = formatInvoice{
+	Number: "INV-1",
+	Paid:   true,
+	Tags:   []string{"urgent", "q3"},
+	Items: []formatItem{
+		{Description: "Widget", Quantity: 2, Price: 9.5},
+		{Description: "Gadget", Quantity: 1, Price: 20},
+	},
+}
+
+func TestParseResponse_AlternateFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{
+			name: "json5 with comments, single quotes and trailing commas",
+			response: "Here you go:\n```json5\n{\n  // invoice header\n  number: 'INV-1',\n  paid: true,\n  tags: ['urgent', 'q3',],\n" +
+				"  /* line items */\n  items: [\n    {description: 'Widget', quantity: 2, price: 9.5,},\n    {description: \"Gadget\", quantity: 1, price: 20},\n  ],\n}\n```",
+		},
+		{
+			name: "toml block",
+			response: "```toml\nnumber = \"INV-1\" # header\npaid = true\ntags = [\"urgent\", \"q3\"]\n\n" +
+				"[[items]]\ndescription = \"Widget\"\nquantity = 2\nprice = 9.5\n\n[[items]]\ndescription = 'Gadget'\nquantity = 1\nprice = 20\n```",
+		},
+		{
+			name: "xml without fence",
+			response: "Result:\n<invoice>\n  <number>INV-1</number>\n  <paid>true</paid>\n  <tags><tag>urgent</tag><tag>q3</tag></tags>\n" +
+				"  <items>\n    <item><description>Widget</description><quantity>2</quantity><price>9.5</price></item>\n" +
+				"    <item><description>Gadget</description><quantity>1</quantity><price>20</price></item>\n  </items>\n</invoice>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseResponse[formatInvoice](tt.response)
+			if err != nil {
+				t.Fatalf("ParseResponse() error = %v", err)
+			}
+			if !reflect.DeepEqual(*result.Data, expectedInvoice) {
+				t.Errorf("ParseResponse() = %+v, expected %+v", *result.Data, expectedInvoice)
+			}
+		})
+	}
+}
+
+func TestNormalizeRelaxedJSON(t *testing.T) {
+	input := `{key: 'it\'s', "url": "http://x.y/z", list: [1, 2,], /* c */ ok: true,}`
+	expected := `{"key": "it's", "url": "http://x.y/z", "list": [1, 2],  "ok": true}`
+	if got := NormalizeRelaxedJSON(input); got != expected {
+		t.Errorf("NormalizeRelaxedJSON() = %s, expected %s", got, expected)
+	}
+}
+
+func TestParseTOML_NestedTables(t *testing.T) {
+	data, err := ParseTOML("title = \"x\"\n[owner]\nname = \"Tom\"\n[owner.address]\ncity = \"Paris\"\nports = [\n  80,\n  443,\n]\npoint = { x = 1, y = 2 }\n")
+	if err != nil {
+		t.Fatalf("ParseTOML() error = %v", err)
+	}
+	owner := data["owner"].(map[string]any)
+	address := owner["address"].(map[string]any)
+	if address["city"] != "Paris" {
+		t.Errorf("expected nested table value, got %v", data)
+	}
+	if !reflect.DeepEqual(address["ports"], []any{int64(80), int64(443)}) {
+		t.Errorf("expected multi-line array, got %v", address["ports"])
+	}
+	if point := address["point"].(map[string]any); point["y"] != int64(2) {
+		t.Errorf("expected inline table, got %v", point)
+	}
+}
@@ -0,0 +1,190 @@
+package structured
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// fuzzRecord is the payload the fuzz targets embed in generated responses
+type fuzzRecord struct {
+	Name   string   `json:"name" yaml:"name"`
+	Note   string   `json:"note" yaml:"note"`
+	Count  int      `json:"count" yaml:"count"`
+	Tags   []string `json:"tags" yaml:"tags"`
+	Active bool     `json:"active" yaml:"active"`
+}
+
+// responseStyles are the ways wrapResponse can wrap a payload, as models do
+const responseStyles = 8
+
+// wrapResponse wraps a payload the way a model might answer with it: bare,
+// in a tagged or untagged fence, with prose around it, with CRLF line
+// endings, indented, or after a thinking block. Prose is one line of free
+// text that cannot itself be read as a payload.
+func wrapResponse(payload, prose, language string, style uint8) string {
+	switch style % responseStyles {
+	case 0:
+		return payload
+	case 1:
+		return "```" + language + "\n" + payload + "\n```"
+	case 2:
+		return "```\n" + payload + "\n```"
+	case 3:
+		return prose + "\n\n```" + language + "\n" + payload + "\n```\n\n" + prose
+	case 4:
+		return prose + "\n\n" + payload + "\n\n" + prose
+	case 5:
+		return strings.ReplaceAll("```"+language+"\n"+payload+"\n```", "\n", "\r\n")
+	case 6:
+		return "  " + strings.ReplaceAll(payload, "\n", "\n  ")
+	default:
+		return "<think>\n" + prose + "\n</think>\n```" + language + "\n" + payload + "\n```"
+	}
+}
+
+// fuzzProse turns fuzz input into a line of prose: without characters that
+// start payloads or fences, and ending with a full stop as sentences do
+func fuzzProse(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("{}[]`~:#-\r\n\t\"'|>&*!%@", r) || r < ' ' {
+			return -1
+		}
+		return r
+	}, text)
+	return "Here is the result " + strings.TrimSpace(text) + "."
+}
+
+// seedRecords adds records that once broke the extraction heuristics
+func seedRecords(f *testing.F) {
+	f.Add("order", "plain", 3, "a,b", true, "", uint8(0))
+	f.Add("brace } inside", "{ and } and {{", -7, "}", false, "see below", uint8(4))
+	f.Add("multi\nline", "line one\nline two: with colon\n  indented", 0, "x,y", true, "ok", uint8(3))
+	f.Add("```json", "fence ``` inside", 1, "`", false, "fenced", uint8(1))
+	f.Add("ünïcødé ✓", "tab\there", 42, "", true, "done", uint8(5))
+	f.Add("- dash", "# hash", 1, "-,#", false, "x", uint8(6))
+	f.Add("", "", 0, "", false, "", uint8(7))
+	f.Add("0", "0", 5, "0\n", false, "0", uint8(4))
+}
+
+// newFuzzRecord builds a record from fuzz arguments
+func newFuzzRecord(name, note string, count int, tags string, active bool) fuzzRecord {
+	record := fuzzRecord{Name: name, Note: note, Count: count, Tags: []string{}, Active: active}
+	if tags != "" {
+		record.Tags = strings.Split(tags, ",")
+	}
+	return record
+}
+
+// normalizeTags makes missing tags empty, as YAML decodes an empty list as nil
+func normalizeTags(record fuzzRecord) fuzzRecord {
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	return record
+}
+
+// validFuzzString reports whether strings survive encoding unchanged
+func validFuzzString(values ...string) bool {
+	for _, value := range values {
+		if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzExtractJSONFromResponse(f *testing.F) {
+	seedRecords(f)
+	f.Fuzz(func(t *testing.T, name, note string, count int, tags string, active bool, prose string, style uint8) {
+		if !validFuzzString(name, note, tags) {
+			t.Skip()
+		}
+		want := newFuzzRecord(name, note, count, tags, active)
+		payload, err := json.Marshal(want)
+		if err != nil {
+			t.Skip()
+		}
+		for _, indented := range []bool{false, true} {
+			if indented {
+				payload, _ = json.MarshalIndent(want, "", "  ")
+			}
+			response := wrapResponse(string(payload), fuzzProse(prose), "json", style)
+			if style%responseStyles == responseStyles-1 {
+				continue // ExtractJSONFromResponse expects thinking to be split off
+			}
+
+			extracted := ExtractJSONFromResponse(response)
+			var got fuzzRecord
+			if err := json.Unmarshal([]byte(extracted), &got); err != nil {
+				t.Fatalf("extracted invalid JSON from %q: %v\n%s", response, err, extracted)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("extracted %+v from %q, want %+v", got, response, want)
+			}
+		}
+	})
+}
+
+func FuzzExtractYAMLFromResponse(f *testing.F) {
+	seedRecords(f)
+	f.Fuzz(func(t *testing.T, name, note string, count int, tags string, active bool, prose string, style uint8) {
+		if !validFuzzString(name, note, tags) {
+			t.Skip()
+		}
+		want := newFuzzRecord(name, note, count, tags, active)
+		payload, err := yaml.Marshal(want)
+		if err != nil {
+			t.Skip()
+		}
+		var direct fuzzRecord
+		if yaml.Unmarshal(payload, &direct) != nil || !reflect.DeepEqual(normalizeTags(direct), want) {
+			t.Skip() // Values the YAML encoder itself does not round-trip
+		}
+		response := wrapResponse(strings.TrimSuffix(string(payload), "\n"), fuzzProse(prose), "yaml", style)
+		if style%responseStyles == responseStyles-1 || style%responseStyles == 5 {
+			t.Skip() // Thinking is split off by ParseResponse; CRLF changes YAML block scalars
+		}
+
+		extracted := ExtractYAMLFromResponse(response)
+		var got fuzzRecord
+		if err := yaml.Unmarshal([]byte(extracted), &got); err != nil {
+			t.Fatalf("extracted invalid YAML from %q: %v\n%s", response, err, extracted)
+		}
+		if got = normalizeTags(got); !reflect.DeepEqual(got, want) {
+			t.Fatalf("extracted %+v from %q, want %+v\n%s", got, response, want, extracted)
+		}
+	})
+}
+
+func FuzzParseResponse(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"{",
+		"```json\n{\"name\": \"x\"\n",
+		"```\n```\n```",
+		"name: [unclosed\ncount: }",
+		"<think>\n{\"name\": 1}\n</think>\n",
+		"{\"name\": \"a\", \"count\": 1e400}",
+		"count: 99999999999999999999999",
+		"name: &a [*a, *a]\ntags: *a",
+		"<record><name>x</name></record>",
+		"```toml\nname = \"x\"\ncount = 'y'\n```",
+		"\xff\xfe{\"name\": \"\xc3\x28\"}",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, response string) {
+		if len(response) > 4096 {
+			t.Skip() // yaml.v3 takes quadratic time on long runs of repeated keys
+		}
+		result, err := ParseResponse[fuzzRecord](response)
+		if (err == nil) != (result.Data != nil) {
+			t.Fatalf("ParseResponse(%q) returned data %v with error %v", response, result.Data, err)
+		}
+	})
+}
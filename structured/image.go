@@ -0,0 +1,114 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// BuildImagePrompt builds the extraction prompt sent alongside an image for type T
+func BuildImagePrompt[T any](additionalContext ...string) string {
+	return buildImagePrompt[T](additionalContext)
+}
+
+// buildImagePrompt is BuildImagePrompt with prompt generation options
+func buildImagePrompt[T any](additionalContext []string, options ...prompt.Option) string {
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString("Analyze the attached document image and extract the requested information. ")
+	promptBuilder.WriteString("Read all visible text, including tables, stamps and handwriting.\n\n")
+
+	writeContextAndFormat(&promptBuilder, reflect.TypeFor[T](), additionalContext, options...)
+
+	return promptBuilder.String()
+}
+
+// ParseFromImage sends image bytes to a vision-capable provider together with the
+// structured prompt for T. An empty mimeType is detected from the content.
+func ParseFromImage[T any](p *Parser, ctx context.Context, image []byte, mimeType string, additionalContext ...string) (ParseResult[T], error) {
+	mimeType, err := imageMimeType(image, mimeType)
+	if err != nil {
+		return ParseResult[T]{
+			Data:  nil,
+			Error: err,
+		}, err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	message := llm.Message{
+		Role:     llm.RoleUser,
+		Content:  buildImagePrompt[T](additionalContext, p.promptOptions()...),
+		Media:    image,
+		MimeType: mimeType,
+	}
+
+	response, constrained, err := callForType[T](p, timeoutCtx, []llm.Message{message})
+	if err != nil {
+		return ParseResult[T]{
+			Data:  nil,
+			Error: fmt.Errorf("LLM call failed: %w", err),
+		}, err
+	}
+
+	return parseForType[T](response.Content, constrained)
+}
+
+// imageMimeType validates or detects the media type of an image
+func imageMimeType(image []byte, mimeType string) (string, error) {
+	if len(image) == 0 {
+		return "", fmt.Errorf("image content is empty")
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(image)
+	}
+	mimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+
+	if !strings.HasPrefix(mimeType, "image/") && mimeType != "application/pdf" {
+		return "", fmt.Errorf("unsupported media type %s: expected an image or PDF", mimeType)
+	}
+	return mimeType, nil
+}
+
+// isImageFile reports whether a path has an image file extension
+func isImageFile(filePath string) bool {
+	return strings.HasPrefix(mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath))), "image/")
+}
+
+// ParseFromImage parses an image (scan, photo or screenshot) into the specified type
+func (b *StructuredNode[T]) ParseFromImage(ctx context.Context, image []byte, mimeType string, additionalContext ...string) (ParseResult[T], error) {
+	key := b.cacheKey("image", additionalContext, []byte(mimeType), image)
+	return cached(b.cache, ctx, key, func() (ParseResult[T], error) {
+		return ParseFromImage[T](b.parser, ctx, image, mimeType, additionalContext...)
+	})
+}
+
+// ParseFromImageFile reads an image file and parses it into the specified type
+func (b *StructuredNode[T]) ParseFromImageFile(ctx context.Context, filePath string, additionalContext ...string) (ParseResult[T], error) {
+	image, mimeType, err := readImageFile(filePath)
+	if err != nil {
+		return ParseResult[T]{
+			Data:  nil,
+			Error: err,
+		}, err
+	}
+
+	return b.ParseFromImage(ctx, image, mimeType, additionalContext...)
+}
+
+// readImageFile returns the content of an image file and the media type of its extension
+func readImageFile(filePath string) ([]byte, string, error) {
+	image, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	return image, mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath))), nil
+}
@@ -0,0 +1,63 @@
+package structured
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestParseFromImage(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"```yaml\nname: receipt\ncount: 3\n```"}}
+
+	result, err := ParseFromImage[repairTarget](newTestParser(t, provider), context.Background(), pngHeader, "", "Store: ACME")
+	if err != nil {
+		t.Fatalf("ParseFromImage() error = %v", err)
+	}
+	if result.Data.Name != "receipt" {
+		t.Errorf("unexpected result %+v", result.Data)
+	}
+
+	message := provider.requests[0][0]
+	if message.MimeType != "image/png" || len(message.Media) != len(pngHeader) {
+		t.Errorf("expected PNG media on the request, got %q with %d bytes", message.MimeType, len(message.Media))
+	}
+	if !strings.Contains(message.Content, "Store: ACME") || !strings.Contains(message.Content, "name:") {
+		t.Errorf("expected context and format instructions in prompt, got %s", message.Content)
+	}
+}
+
+func TestParseFromImage_RejectsNonImage(t *testing.T) {
+	provider := &scriptedProvider{}
+	if _, err := ParseFromImage[repairTarget](newTestParser(t, provider), context.Background(), []byte("plain text"), "", ""); err == nil {
+		t.Error("expected an error for non-image content")
+	}
+	if len(provider.requests) != 0 {
+		t.Error("provider should not be called for invalid media")
+	}
+}
+
+func TestStructuredNode_ParseFromFileRoutesImages(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"name: scan\n"}}
+	node, err := NewStructuredNode[repairTarget](provider, nil, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredNode() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "invoice.png")
+	if err := os.WriteFile(path, pngHeader, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := node.ParseFromFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ParseFromFile() error = %v", err)
+	}
+	if result.Data.Name != "scan" || provider.requests[0][0].MimeType != "image/png" {
+		t.Errorf("expected image request, got %+v", provider.requests[0][0].MimeType)
+	}
+}
@@ -0,0 +1,436 @@
+package structured
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Loader extracts plain text from a document so it can be sent to the LLM
+type Loader interface {
+	Load(ctx context.Context, data []byte) (string, error)
+}
+
+// LoaderFunc adapts a function to the Loader interface
+type LoaderFunc func(ctx context.Context, data []byte) (string, error)
+
+// Load implements Loader
+func (f LoaderFunc) Load(ctx context.Context, data []byte) (string, error) {
+	return f(ctx, data)
+}
+
+var (
+	loadersMu sync.RWMutex
+	loaders   = map[string]Loader{
+		".txt":  TextLoader,
+		".md":   TextLoader,
+		".csv":  TextLoader,
+		".json": TextLoader,
+		".yaml": TextLoader,
+		".yml":  TextLoader,
+		".html": HTMLLoader,
+		".htm":  HTMLLoader,
+		".docx": DOCXLoader,
+		".pdf":  PDFLoader,
+	}
+)
+
+// RegisterLoader sets the loader used for files with the given extension (e.g. ".pdf").
+// Registering a loader for an extension replaces the built-in one.
+func RegisterLoader(extension string, loader Loader) {
+	loadersMu.Lock()
+	defer loadersMu.Unlock()
+	loaders[normalizeExtension(extension)] = loader
+}
+
+// LoaderFor returns the loader registered for a file path; unknown extensions use TextLoader
+func LoaderFor(filePath string) Loader {
+	loadersMu.RLock()
+	defer loadersMu.RUnlock()
+	if loader, ok := loaders[normalizeExtension(filepath.Ext(filePath))]; ok {
+		return loader
+	}
+	return TextLoader
+}
+
+// LoadFile reads a file and extracts its text with the loader registered for its extension
+func LoadFile(ctx context.Context, filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	text, err := LoaderFor(filePath).Load(ctx, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text from %s: %w", filePath, err)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// normalizeExtension lowercases an extension and ensures the leading dot
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if extension != "" && !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return extension
+}
+
+// TextLoader returns the file content unchanged
+var TextLoader Loader = LoaderFunc(func(ctx context.Context, data []byte) (string, error) {
+	return string(data), nil
+})
+
+// HTMLLoader extracts visible text from HTML, dropping scripts and styles and
+// keeping block elements on separate lines
+var HTMLLoader Loader = LoaderFunc(func(ctx context.Context, data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var builder strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "script", "style", "noscript", "head", "template":
+				return
+			}
+		}
+		if node.Type == html.TextNode {
+			if text := strings.Join(strings.Fields(node.Data), " "); text != "" {
+				builder.WriteString(text)
+				builder.WriteString(" ")
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if node.Type == html.ElementNode && htmlBlockElements[node.Data] {
+			builder.WriteString("\n")
+		}
+	}
+	walk(doc)
+
+	return collapseBlankLines(builder.String()), nil
+})
+
+// htmlBlockElements end a line of extracted text
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "header": true, "footer": true, "ul": true, "ol": true,
+}
+
+// DefaultMaxDecompressedSize is the most bytes DOCXLoader and PDFLoader
+// decompress from one document, 64 MiB
+const DefaultMaxDecompressedSize = 64 << 20
+
+// DOCXLoader extracts paragraph text from the word/document.xml part of a
+// DOCX file, decompressing at most DefaultMaxDecompressedSize bytes
+var DOCXLoader = NewDOCXLoader(DefaultMaxDecompressedSize)
+
+// NewDOCXLoader creates a DOCX loader failing on documents whose
+// word/document.xml decompresses to more than maxSize bytes; 0 uses
+// DefaultMaxDecompressedSize
+func NewDOCXLoader(maxSize int64) Loader {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	return LoaderFunc(func(ctx context.Context, data []byte) (string, error) {
+		return loadDOCX(data, maxSize)
+	})
+}
+
+// loadDOCX extracts the text of a DOCX file
+func loadDOCX(data []byte, maxSize int64) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open DOCX archive: %w", err)
+	}
+
+	for _, file := range archive.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open document.xml: %w", err)
+		}
+		defer reader.Close()
+		text, err := extractDOCXText(&sizeLimitedReader{reader: reader, left: maxSize})
+		if errors.Is(err, errTooLarge) {
+			return "", fmt.Errorf("word/document.xml decompresses to more than %d bytes", maxSize)
+		}
+		return text, err
+	}
+	return "", fmt.Errorf("word/document.xml not found in DOCX archive")
+}
+
+// extractDOCXText walks WordprocessingML collecting text runs, tabs and paragraph breaks
+func extractDOCXText(reader io.Reader) (string, error) {
+	decoder := xml.NewDecoder(reader)
+	var builder strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse document.xml: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				builder.WriteString("\t")
+			case "br", "cr":
+				builder.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				builder.WriteString("\n")
+			case "tc":
+				builder.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				builder.Write(element)
+			}
+		}
+	}
+
+	return collapseBlankLines(builder.String()), nil
+}
+
+var (
+	pdfStreamPattern = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+	pdfTextPattern   = regexp.MustCompile(`(?s)BT(.*?)ET`)
+	pdfOperatorToken = regexp.MustCompile(`(?s)\((?:\\.|[^\\)])*\)|\[(?:\\.|[^\]])*\]\s*TJ|T\*|'|"|Td|TD|Tj`)
+	pdfArrayToken    = regexp.MustCompile(`\((?:\\.|[^\\)])*\)|-?\d+(?:\.\d+)?`)
+)
+
+// PDFLoader extracts text drawn with Tj/TJ operators from uncompressed or
+// Flate-compressed content streams, inflating at most DefaultMaxDecompressedSize
+// bytes. It covers PDFs generated from text documents; scanned PDFs or
+// CID-encoded fonts need a custom loader such as CommandLoader("pdftotext", "-", "-").
+var PDFLoader = NewPDFLoader(DefaultMaxDecompressedSize)
+
+// NewPDFLoader creates a PDF loader failing on documents whose compressed
+// streams inflate to more than maxSize bytes in total; 0 uses
+// DefaultMaxDecompressedSize
+func NewPDFLoader(maxSize int64) Loader {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	return LoaderFunc(func(ctx context.Context, data []byte) (string, error) {
+		return loadPDF(data, maxSize)
+	})
+}
+
+// loadPDF extracts the text of a PDF document
+func loadPDF(data []byte, maxSize int64) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("%PDF")) {
+		return "", fmt.Errorf("not a PDF document")
+	}
+
+	var builder strings.Builder
+	left := maxSize
+	for _, match := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		dictionary := data[match[2]:match[3]]
+		start := match[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end == -1 {
+			continue
+		}
+		stream := data[start : start+end]
+
+		if bytes.Contains(dictionary, []byte("/FlateDecode")) {
+			inflated, err := io.ReadAll(&sizeLimitedReader{reader: zlibReader(stream), left: left})
+			if errors.Is(err, errTooLarge) {
+				return "", fmt.Errorf("PDF streams inflate to more than %d bytes", maxSize)
+			}
+			if len(inflated) == 0 && err != nil {
+				continue
+			}
+			left -= int64(len(inflated))
+			stream = inflated
+		} else if bytes.Contains(dictionary, []byte("/Filter")) {
+			// Images and other encodings carry no extractable text
+			continue
+		}
+
+		for _, block := range pdfTextPattern.FindAllSubmatch(stream, -1) {
+			builder.WriteString(extractPDFTextBlock(block[1]))
+			builder.WriteString("\n")
+		}
+	}
+
+	text := collapseBlankLines(builder.String())
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("no extractable text found in PDF")
+	}
+	return text, nil
+}
+
+// zlibReader returns a reader over inflated data, or an erroring reader
+func zlibReader(data []byte) io.Reader {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errorReader{err}
+	}
+	return reader
+}
+
+// errTooLarge is returned by a sizeLimitedReader reading past its limit
+var errTooLarge = errors.New("size limit exceeded")
+
+// sizeLimitedReader fails once more than left bytes are read from reader
+type sizeLimitedReader struct {
+	reader io.Reader
+	left   int64
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.left+1 {
+		// One byte past the limit tells a stream at the limit from a larger one
+		p = p[:r.left+1]
+	}
+	n, err := r.reader.Read(p)
+	if r.left -= int64(n); r.left < 0 {
+		return n + int(r.left), errTooLarge
+	}
+	return n, err
+}
+
+// errorReader always fails with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// extractPDFTextBlock turns the show-text and positioning operators of a BT/ET block into text
+func extractPDFTextBlock(block []byte) string {
+	var builder strings.Builder
+	var pending []string
+
+	for _, token := range pdfOperatorToken.FindAll(block, -1) {
+		switch text := string(token); {
+		case strings.HasPrefix(text, "("):
+			pending = append(pending, decodePDFString(text[1:len(text)-1]))
+		case strings.HasPrefix(text, "["):
+			for _, part := range pdfArrayToken.FindAllString(text, -1) {
+				if strings.HasPrefix(part, "(") {
+					builder.WriteString(decodePDFString(part[1 : len(part)-1]))
+				} else if len(part) > 0 && part[0] == '-' && len(part) > 3 {
+					// Large negative kerning usually separates words
+					builder.WriteString(" ")
+				}
+			}
+		case text == "Tj":
+			builder.WriteString(strings.Join(pending, ""))
+			pending = nil
+		case text == "'" || text == "\"":
+			builder.WriteString("\n")
+			builder.WriteString(strings.Join(pending, ""))
+			pending = nil
+		case text == "Td" || text == "TD" || text == "T*":
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
+
+// decodePDFString resolves escape sequences in a PDF literal string
+func decodePDFString(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 >= len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		case 'b', 'f':
+			// Backspace and form feed carry no text
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			octal := 0
+			j := i
+			for ; j < len(value) && j < i+3 && value[j] >= '0' && value[j] <= '7'; j++ {
+				octal = octal*8 + int(value[j]-'0')
+			}
+			builder.WriteByte(byte(octal))
+			i = j - 1
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
+
+// CommandLoader runs an external converter that reads the document on stdin and
+// writes text to stdout, e.g. CommandLoader("pdftotext", "-", "-")
+func CommandLoader(name string, args ...string) Loader {
+	return LoaderFunc(func(ctx context.Context, data []byte) (string, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdin = bytes.NewReader(data)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		return string(output), nil
+	})
+}
+
+// collapseBlankLines trims each line and removes runs of empty lines
+func collapseBlankLines(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(strings.TrimLeft(line, " "), " \t")
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
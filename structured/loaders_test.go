@@ -0,0 +1,133 @@
+package structured
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLLoader(t *testing.T) {
+	input := `<html><head><title>x</title><style>p{}</style></head><body>
+<h1>Jane   Doe</h1><script>alert(1)</script><p>Senior <b>Engineer</b></p><ul><li>Go</li><li>SQL</li></ul></body></html>`
+
+	text, err := HTMLLoader.Load(context.Background(), []byte(input))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	expected := "Jane Doe\nSenior Engineer\nGo\nSQL"
+	if text != expected {
+		t.Errorf("Load() = %q, expected %q", text, expected)
+	}
+}
+
+func TestDOCXLoader(t *testing.T) {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	part, _ := archive.Create("word/document.xml")
+	fmt.Fprint(part, `<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`+
+		`<w:p><w:r><w:t>Invoice</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">INV-7</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t>Total: $12.00</w:t></w:r></w:p></w:body></w:document>`)
+	archive.Close()
+
+	text, err := DOCXLoader.Load(context.Background(), buffer.Bytes())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if text != "Invoice\tINV-7\nTotal: $12.00" {
+		t.Errorf("Load() = %q", text)
+	}
+}
+
+func TestPDFLoader(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	fmt.Fprint(writer, "BT /F1 12 Tf 72 700 Td [(Total) -250 (due)] TJ 0 -14 Td (\\(USD\\) 42) Tj ET")
+	writer.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Length 40 >>\nstream\nBT (Invoice INV-7) Tj ET\nendstream\nendobj\n")
+	fmt.Fprintf(&pdf, "2 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF")
+
+	text, err := PDFLoader.Load(context.Background(), pdf.Bytes())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, want := range []string{"Invoice INV-7", "Total due", "(USD) 42"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in extracted text %q", want, text)
+		}
+	}
+
+	if _, err := PDFLoader.Load(context.Background(), []byte("plain")); err == nil {
+		t.Error("expected an error for non-PDF input")
+	}
+}
+
+func TestLoaders_DecompressedSizeLimit(t *testing.T) {
+	oversized := bytes.Repeat([]byte("A"), 4096)
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	fmt.Fprintf(writer, "BT (%s) Tj ET", oversized)
+	writer.Close()
+	var pdf bytes.Buffer
+	fmt.Fprintf(&pdf, "%%PDF-1.4\n1 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF")
+
+	var docx bytes.Buffer
+	archive := zip.NewWriter(&docx)
+	part, _ := archive.Create("word/document.xml")
+	fmt.Fprintf(part, `<w:document><w:body><w:p><w:r><w:t>%s</w:t></w:r></w:p></w:body></w:document>`, oversized)
+	archive.Close()
+
+	tests := []struct {
+		name   string
+		small  Loader
+		large  Loader
+		data   []byte
+		errMsg string
+	}{
+		{"pdf", NewPDFLoader(1024), NewPDFLoader(8192), pdf.Bytes(), "more than 1024 bytes"},
+		{"docx", NewDOCXLoader(1024), NewDOCXLoader(8192), docx.Bytes(), "more than 1024 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.small.Load(context.Background(), tt.data); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Load() error = %v, want %q", err, tt.errMsg)
+			}
+			text, err := tt.large.Load(context.Background(), tt.data)
+			if err != nil || text != string(oversized) {
+				t.Errorf("Load() = %d bytes, %v; want the whole text", len(text), err)
+			}
+		})
+	}
+}
+
+func TestLoadFile_CustomLoader(t *testing.T) {
+	RegisterLoader("RTF", LoaderFunc(func(ctx context.Context, data []byte) (string, error) {
+		return strings.ToUpper(string(data)), nil
+	}))
+	defer RegisterLoader(".rtf", TextLoader)
+
+	path := filepath.Join(t.TempDir(), "resume.rtf")
+	if err := os.WriteFile(path, []byte("  jane doe \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := LoadFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if text != "JANE DOE" {
+		t.Errorf("LoadFile() = %q", text)
+	}
+}
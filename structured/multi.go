@@ -0,0 +1,201 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ParseAll extracts every T contained in a response: each fenced YAML/JSON block,
+// each "---" separated YAML document, each element of a top-level list and each
+// JSON object found in free text. Items that fail to decode are returned with
+// their own Error; an error is returned only when no items are found.
+func ParseAll[T any](responseContent string) ([]ParseResult[T], error) {
+	_, responseContent = llm.SplitReasoning(responseContent)
+	candidates := extractFencedBlocks(responseContent, "yaml", "yml", "json", "json5", "")
+	if len(candidates) == 0 {
+		candidates = []string{strings.TrimSpace(responseContent)}
+	}
+
+	var results []ParseResult[T]
+	for _, candidate := range candidates {
+		results = append(results, parseCandidateItems[T](candidate)...)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no YAML or JSON documents found in response")
+	}
+	return results, nil
+}
+
+// parseCandidateItems splits one block of text into items and decodes each into T
+func parseCandidateItems[T any](content string) []ParseResult[T] {
+	if content == "" {
+		return nil
+	}
+
+	var zero T
+	items, err := decodeDocuments(content)
+	if err != nil || !containsFieldsOf(items, reflect.TypeOf(&zero).Elem()) {
+		// Free text with embedded JSON objects, e.g. one object per line
+		if objects := scanJSONObjects(content); len(objects) > 0 {
+			items, err = nil, nil
+			for _, object := range objects {
+				var item any
+				if decodeErr := json.Unmarshal([]byte(NormalizeRelaxedJSON(object)), &item); decodeErr != nil {
+					items = append(items, decodeFailure{fmt.Errorf("invalid JSON object: %w", decodeErr)})
+					continue
+				}
+				items = append(items, item)
+			}
+		}
+	}
+
+	var results []ParseResult[T]
+	for _, item := range items {
+		results = append(results, decodeItem[T](item))
+	}
+	if err != nil {
+		results = append(results, ParseResult[T]{Error: fmt.Errorf("failed to parse document: %w", err)})
+	}
+	return results
+}
+
+// decodeFailure marks an item that could not be decoded from the response
+type decodeFailure struct {
+	err error
+}
+
+// decodeDocuments reads all YAML documents (JSON is valid YAML) and flattens
+// top-level lists into their elements
+func decodeDocuments(content string) ([]any, error) {
+	decoder := yaml.NewDecoder(bytes.NewBufferString(content))
+
+	var items []any
+	for {
+		var document any
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return items, err
+		}
+
+		switch value := document.(type) {
+		case nil:
+			continue
+		case []any:
+			items = append(items, value...)
+		default:
+			items = append(items, value)
+		}
+	}
+}
+
+// containsFieldsOf reports whether any decoded item is a map with a key naming a
+// field of t, which tells a YAML document apart from prose that happens to parse
+func containsFieldsOf(items []any, t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, item := range items {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name := fieldName(t.Field(i))
+			for key := range fields {
+				if strings.EqualFold(key, name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// decodeItem converts one decoded item into T
+func decodeItem[T any](item any) ParseResult[T] {
+	if failure, ok := item.(decodeFailure); ok {
+		return ParseResult[T]{Error: failure.err}
+	}
+
+	var result T
+	if err := decodeValue(item, reflect.ValueOf(&result).Elem(), "", ""); err != nil {
+		return ParseResult[T]{Error: fmt.Errorf("failed to decode item: %w", err)}
+	}
+	return ParseResult[T]{Data: &result}
+}
+
+// scanJSONObjects returns every balanced top-level {...} in text, skipping braces
+// inside strings
+func scanJSONObjects(text string) []string {
+	var objects []string
+	depth := 0
+	start := -1
+	var quote rune
+	escaped := false
+
+	for i, r := range text {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch r {
+		case '"', '\'':
+			if depth > 0 {
+				quote = r
+			}
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				objects = append(objects, text[start:i+1])
+			}
+		}
+	}
+	return objects
+}
+
+// extractFencedBlocks returns the content of every fenced block tagged with one of
+// the given languages; "" matches untagged blocks
+func extractFencedBlocks(response string, languages ...string) []string {
+	var contents []string
+	for _, block := range ExtractCodeBlocks(response) {
+		for _, language := range languages {
+			if (language == "" && block.Tag == "") || (block.Tag != "" && block.Language == NormalizeLanguage(language)) {
+				contents = append(contents, block.Content)
+				break
+			}
+		}
+	}
+	return contents
+}
@@ -0,0 +1,83 @@
+package structured
+
+import "testing"
+
+type multiRecord struct {
+	Name  string `yaml:"name" json:"name"`
+	Years int    `yaml:"years" json:"years"`
+}
+
+func TestParseAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected []string
+		failures int
+	}{
+		{
+			name:     "multiple fenced blocks",
+			response: "First:\n```yaml\nname: Ada\nyears: 3\n```\nSecond:\n```json\n{\"name\": \"Linus\", \"years\": 5}\n```",
+			expected: []string{"Ada", "Linus"},
+		},
+		{
+			name:     "yaml document stream",
+			response: "name: Ada\nyears: 3\n---\nname: Grace\nyears: 7\n",
+			expected: []string{"Ada", "Grace"},
+		},
+		{
+			name:     "top-level list",
+			response: "```json\n[{\"name\": \"Ada\"}, {\"name\": \"Grace\"}, {\"name\": \"Linus\"}]\n```",
+			expected: []string{"Ada", "Grace", "Linus"},
+		},
+		{
+			name:     "json objects in prose",
+			response: "Resume 1: {\"name\": \"Ada\", \"years\": 3}\nResume 2: {\"name\": \"Bob {the builder}\", \"years\": 1}",
+			expected: []string{"Ada", "Bob {the builder}"},
+		},
+		{
+			name:     "reasoning before the answer",
+			response: "<think>\nA draft:\n```yaml\nname: Draft\n```\n</think>\n```yaml\nname: Ada\nyears: 3\n```",
+			expected: []string{"Ada"},
+		},
+		{
+			name:     "per-item errors",
+			response: "```yaml\n- name: Ada\n  years: 3\n- name: Grace\n  years: many\n```",
+			expected: []string{"Ada"},
+			failures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ParseAll[multiRecord](tt.response)
+			if err != nil {
+				t.Fatalf("ParseAll() error = %v", err)
+			}
+
+			var names []string
+			failures := 0
+			for _, result := range results {
+				if result.Error != nil {
+					failures++
+					continue
+				}
+				names = append(names, result.Data.Name)
+			}
+
+			if len(names) != len(tt.expected) || failures != tt.failures {
+				t.Fatalf("ParseAll() names = %v (failures %d), expected %v (failures %d)", names, failures, tt.expected, tt.failures)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Errorf("item %d = %s, expected %s", i, names[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseAll_Empty(t *testing.T) {
+	if _, err := ParseAll[multiRecord]("   "); err == nil {
+		t.Error("expected an error for an empty response")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -16,6 +17,13 @@ import (
 type Config struct {
 	MaxRetries int           // Maximum retry attempts
 	Timeout    time.Duration // LLM call timeout
+
+	// DisableConstrainedDecoding always parses text responses, even when the
+	// provider implements llm.SchemaProvider
+	DisableConstrainedDecoding bool
+
+	// Examples are few-shot input/output pairs added to generated prompts
+	Examples []prompt.Example
 }
 
 // DefaultConfig returns a default configuration for structured parsing
@@ -67,8 +75,14 @@ func NewParser(provider llm.LLMProvider, config *Config) (*Parser, error) {
 
 // ParseResult contains the result of structured parsing
 type ParseResult[T any] struct {
-	Data  *T
-	Error error
+	Data     *T
+	Error    error
+	Attempts int      // Number of LLM calls made when parsing with repair
+	Warnings []string // Non-fatal issues reported by a WarningValidator
+	Cached   bool     // The result was found in the cache instead of parsed, see CacheConfig
+
+	// Diagnostics describes every failed format attempt when parsing fails
+	Diagnostics *ParseDiagnostics
 }
 
 // ParseWithPrompt executes LLM parsing with a custom prompt and parses the response into type T
@@ -83,8 +97,30 @@ func ParseWithPrompt[T any](p *Parser, ctx context.Context, customPrompt string)
 		Content: customPrompt,
 	}
 
-	// Call LLM provider with constructed prompt
-	response, err := p.llmProvider.CallLLM(timeoutCtx, []llm.Message{message})
+	return parseMessages[T](p, timeoutCtx, []llm.Message{message})
+}
+
+// ParseWithMessages parses T out of a conversation: the earlier messages are
+// sent as they are, followed by a user message with the instruction and the
+// structured format of T, so the instruction can refer to earlier turns, e.g.
+// "extract the order discussed above". An empty instruction asks for the
+// requested information from the conversation.
+func ParseWithMessages[T any](p *Parser, ctx context.Context, messages []llm.Message, instruction string) (ParseResult[T], error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	conversation := make([]llm.Message, len(messages), len(messages)+1)
+	copy(conversation, messages)
+	conversation = append(conversation, llm.Message{
+		Role:    llm.RoleUser,
+		Content: buildInstructionPrompt[T](instruction, p.promptOptions()...),
+	})
+	return parseMessages[T](p, timeoutCtx, conversation)
+}
+
+// parseMessages calls the LLM with messages and parses the response into type T
+func parseMessages[T any](p *Parser, ctx context.Context, messages []llm.Message) (ParseResult[T], error) {
+	response, constrained, err := callForType[T](p, ctx, messages)
 	if err != nil {
 		return ParseResult[T]{
 			Data:  nil,
@@ -93,14 +129,34 @@ func ParseWithPrompt[T any](p *Parser, ctx context.Context, customPrompt string)
 	}
 
 	// Parse the response into the target type
-	return ParseResponse[T](response.Content)
+	return parseForType[T](response.Content, constrained)
 }
 
 // ParseWithStructuredPrompt generates a structured prompt for type T and executes parsing
 func ParseWithStructuredPrompt[T any](p *Parser, ctx context.Context, inputData string, additionalContext ...string) (ParseResult[T], error) {
-	// Generate structured prompt for type T
-	structuredPrompt := prompt.GenerateStructuredPrompt[T]()
+	return ParseWithPrompt[T](p, ctx, buildStructuredPrompt[T](inputData, additionalContext, p.promptOptions()...))
+}
 
+// promptOptions returns the prompt generation options from the parser configuration
+func (p *Parser) promptOptions() []prompt.Option {
+	if len(p.config.Examples) == 0 {
+		return nil
+	}
+	return []prompt.Option{prompt.WithExamples(p.config.Examples...)}
+}
+
+// BuildStructuredPrompt builds the full extraction prompt for type T from input data and context
+func BuildStructuredPrompt[T any](inputData string, additionalContext ...string) string {
+	return buildStructuredPrompt[T](inputData, additionalContext)
+}
+
+// buildStructuredPrompt is BuildStructuredPrompt with prompt generation options
+func buildStructuredPrompt[T any](inputData string, additionalContext []string, options ...prompt.Option) string {
+	return buildStructuredPromptFor(reflect.TypeFor[T](), inputData, additionalContext, options...)
+}
+
+// buildStructuredPromptFor is buildStructuredPrompt for a type known only at run time
+func buildStructuredPromptFor(t reflect.Type, inputData string, additionalContext []string, options ...prompt.Option) string {
 	// Build the full prompt with input data and context
 	var promptBuilder strings.Builder
 	promptBuilder.WriteString("Analyze the following data and extract the requested information.\n\n")
@@ -109,6 +165,25 @@ func ParseWithStructuredPrompt[T any](p *Parser, ctx context.Context, inputData
 	promptBuilder.WriteString(inputData)
 	promptBuilder.WriteString("\n```\n\n")
 
+	writeContextAndFormat(&promptBuilder, t, additionalContext, options...)
+
+	return promptBuilder.String()
+}
+
+// buildInstructionPrompt builds the message that asks for T at the end of a conversation
+func buildInstructionPrompt[T any](instruction string, options ...prompt.Option) string {
+	if strings.TrimSpace(instruction) == "" {
+		instruction = "Extract the requested information from the conversation above."
+	}
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(instruction)
+	promptBuilder.WriteString("\n\n")
+	writeContextAndFormat(&promptBuilder, reflect.TypeFor[T](), nil, options...)
+	return promptBuilder.String()
+}
+
+// writeContextAndFormat appends additional context and the structured format instructions for t
+func writeContextAndFormat(promptBuilder *strings.Builder, t reflect.Type, additionalContext []string, options ...prompt.Option) {
 	// Add additional context if provided
 	for i, context := range additionalContext {
 		promptBuilder.WriteString(fmt.Sprintf("**Additional Context %d:**\n", i+1))
@@ -116,79 +191,90 @@ func ParseWithStructuredPrompt[T any](p *Parser, ctx context.Context, inputData
 		promptBuilder.WriteString("\n\n")
 	}
 
-	promptBuilder.WriteString(structuredPrompt)
-
-	return ParseWithPrompt[T](p, ctx, promptBuilder.String())
+	// Generate structured prompt for type t
+	promptBuilder.WriteString(prompt.GenerateStructuredPromptFor(t, options...))
 }
 
-// ParseResponse parses LLM response content into the target type T
+// ParseResponse parses LLM response content into the target type T. Thinking
+// a reasoning model wrote before its answer is skipped.
 func ParseResponse[T any](responseContent string) (ParseResult[T], error) {
 	var result T
+	diagnostics := &ParseDiagnostics{RawResponse: responseContent}
+	_, responseContent = llm.SplitReasoning(responseContent)
+
+	// An explicitly tagged TOML/XML/JSON5 block or a bare XML document would be
+	// misread by the lenient YAML parser, so handle those first
+	triedAlternates := false
+	if hasAlternateFormatHint(responseContent) {
+		triedAlternates = true
+		if data, _, err := parseAlternateFormats[T](responseContent, diagnostics); err == nil {
+			return ParseResult[T]{
+				Data:  data,
+				Error: nil,
+			}, nil
+		}
+	}
 
 	// Try YAML parsing first
 	yamlContent := ExtractYAMLFromResponse(responseContent)
 	if yamlContent != "" {
-		err := yaml.Unmarshal([]byte(yamlContent), &result)
+		err := unmarshalWithConverters([]byte(yamlContent), yaml.Unmarshal, &result)
 		if err == nil {
 			return ParseResult[T]{
 				Data:  &result,
 				Error: nil,
 			}, nil
 		}
+		diagnostics.record(FormatYAML, yamlContent, err)
 	}
 
 	// Try JSON parsing as fallback
 	jsonContent := ExtractJSONFromResponse(responseContent)
 	if jsonContent != "" {
-		err := json.Unmarshal([]byte(jsonContent), &result)
+		result = *new(T)
+		err := unmarshalWithConverters([]byte(jsonContent), json.Unmarshal, &result)
 		if err == nil {
 			return ParseResult[T]{
 				Data:  &result,
 				Error: nil,
 			}, nil
 		}
+		diagnostics.record(FormatJSON, jsonContent, err)
+	}
+
+	// Models sometimes ignore the format instruction; try relaxed JSON, TOML and XML
+	if !triedAlternates {
+		if data, _, err := parseAlternateFormats[T](responseContent, diagnostics); err == nil {
+			return ParseResult[T]{
+				Data:  data,
+				Error: nil,
+			}, nil
+		}
 	}
 
-	// If both parsing methods fail, return error
-	err := fmt.Errorf("failed to parse response as YAML or JSON")
+	// If all parsing methods fail, return an error carrying the diagnostics
+	err := &ParseError{Diagnostics: diagnostics}
 	return ParseResult[T]{
-		Data:  nil,
-		Error: err,
+		Data:        nil,
+		Error:       err,
+		Diagnostics: diagnostics,
 	}, err
 }
 
 // ExtractYAMLFromResponse extracts YAML content from LLM response using string parsing
 func ExtractYAMLFromResponse(response string) string {
+	blocks := ExtractCodeBlocks(response)
+
 	// Look for YAML code blocks first (```yaml ... ```)
-	yamlBlockStart := "```yaml"
-	yamlBlockEnd := "```"
-
-	startIndex := strings.Index(response, yamlBlockStart)
-	if startIndex != -1 {
-		// Found YAML code block, extract content between markers
-		startIndex += len(yamlBlockStart)
-		endIndex := strings.Index(response[startIndex:], yamlBlockEnd)
-		if endIndex != -1 {
-			yamlContent := strings.TrimSpace(response[startIndex : startIndex+endIndex])
-			return yamlContent
+	for _, block := range blocks {
+		if !block.Detected && block.Language == "yaml" {
+			return block.Content
 		}
 	}
 
-	// Look for generic code blocks (``` ... ```)
-	codeBlockStart := "```"
-	startIndex = strings.Index(response, codeBlockStart)
-	if startIndex != -1 {
-		startIndex += len(codeBlockStart)
-		// Skip any language identifier on the same line
-		if newlineIndex := strings.Index(response[startIndex:], "\n"); newlineIndex != -1 {
-			startIndex += newlineIndex + 1
-		}
-
-		endIndex := strings.Index(response[startIndex:], yamlBlockEnd)
-		if endIndex != -1 {
-			yamlContent := strings.TrimSpace(response[startIndex : startIndex+endIndex])
-			return yamlContent
-		}
+	// Fall back to the first generic code block
+	if len(blocks) > 0 {
+		return blocks[0].Content
 	}
 
 	// If no code blocks found, try to extract YAML-like content
@@ -205,10 +291,11 @@ func ExtractYAMLFromResponse(response string) string {
 		}
 
 		if inYAML {
-			// Stop if we hit a line that doesn't look like YAML
+			// Stop if we hit a line that doesn't look like YAML; indented
+			// lines continue block scalars and nested values
 			if trimmedLine != "" && !strings.HasPrefix(trimmedLine, "#") &&
 				!strings.Contains(trimmedLine, ":") && !strings.HasPrefix(trimmedLine, "-") &&
-				!strings.HasPrefix(trimmedLine, " ") {
+				!strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
 				break
 			}
 			yamlLines = append(yamlLines, line)
@@ -225,77 +312,62 @@ func ExtractYAMLFromResponse(response string) string {
 
 // ExtractJSONFromResponse extracts JSON content from LLM response
 func ExtractJSONFromResponse(response string) string {
+	blocks := ExtractCodeBlocks(response)
+
 	// Look for JSON code blocks first (```json ... ```)
-	jsonBlockStart := "```json"
-	jsonBlockEnd := "```"
-
-	startIndex := strings.Index(response, jsonBlockStart)
-	if startIndex != -1 {
-		startIndex += len(jsonBlockStart)
-		endIndex := strings.Index(response[startIndex:], jsonBlockEnd)
-		if endIndex != -1 {
-			jsonContent := strings.TrimSpace(response[startIndex : startIndex+endIndex])
-			return jsonContent
+	for _, block := range blocks {
+		if !block.Detected && (block.Language == "json" || block.Language == "json5") {
+			return block.Content
 		}
 	}
 
 	// Look for generic code blocks that might contain JSON
-	codeBlockStart := "```"
-	startIndex = strings.Index(response, codeBlockStart)
-	if startIndex != -1 {
-		startIndex += len(codeBlockStart)
-		// Skip any language identifier on the same line
-		if newlineIndex := strings.Index(response[startIndex:], "\n"); newlineIndex != -1 {
-			startIndex += newlineIndex + 1
+	for _, block := range blocks {
+		if strings.HasPrefix(block.Content, "{") || strings.HasPrefix(block.Content, "[") {
+			return block.Content
 		}
+	}
 
-		endIndex := strings.Index(response[startIndex:], jsonBlockEnd)
-		if endIndex != -1 {
-			content := strings.TrimSpace(response[startIndex : startIndex+endIndex])
-			// Check if it looks like JSON (starts with { or [)
-			if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
-				return content
+	// Try to find a JSON object starting a line of the response
+	offset := 0
+	for _, line := range strings.SplitAfter(response, "\n") {
+		if trimmed := strings.TrimLeft(line, " \t"); strings.HasPrefix(trimmed, "{") {
+			object := response[offset+len(line)-len(trimmed):]
+			if end := jsonValueEnd(object); end > 0 {
+				return object[:end]
 			}
+			// Unbalanced braces: leave the error to the JSON parser
+			return object
 		}
+		offset += len(line)
 	}
 
-	// Try to find JSON objects in the response
-	lines := strings.Split(response, "\n")
-	var jsonLines []string
-	inJSON := false
-	braceCount := 0
-
-	for _, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-
-		if strings.HasPrefix(trimmedLine, "{") {
-			inJSON = true
-			braceCount = 0
-		}
-
-		if inJSON {
-			jsonLines = append(jsonLines, line)
-
-			// Count braces to find the end of JSON object
-			for _, char := range trimmedLine {
-				switch char {
-				case '{':
-					braceCount++
-				case '}':
-					braceCount--
-				}
-			}
+	return response
+}
 
-			// If braces are balanced, we've found the complete JSON
-			if braceCount == 0 && len(jsonLines) > 0 {
-				break
+// jsonValueEnd returns the length of the JSON object or array text starts
+// with, counting brackets outside strings, or -1 when it is not closed
+func jsonValueEnd(text string) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return i + 1
 			}
 		}
 	}
-
-	if len(jsonLines) > 0 {
-		return strings.Join(jsonLines, "\n")
-	}
-
-	return response
+	return -1
 }
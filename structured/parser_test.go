@@ -0,0 +1,52 @@
+package structured
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestParseWithMessages(t *testing.T) {
+	history := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You take orders for a hardware store."},
+		{Role: llm.RoleUser, Content: "I'd like three widgets."},
+		{Role: llm.RoleAssistant, Content: "Three widgets, anything else?"},
+	}
+	tests := []struct {
+		name        string
+		instruction string
+		wantPrefix  string
+	}{
+		{"instruction", "Extract the order discussed above.", "Extract the order discussed above."},
+		{"default instruction", "", "Extract the requested information from the conversation above."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{responses: []string{"```yaml\nname: widget\ncount: 3\n```"}}
+			result, err := ParseWithMessages[repairTarget](newTestParser(t, provider), context.Background(), history, tt.instruction)
+			if err != nil {
+				t.Fatalf("ParseWithMessages() error = %v", err)
+			}
+			if result.Data.Name != "widget" || result.Data.Count != 3 {
+				t.Errorf("unexpected data %+v", result.Data)
+			}
+
+			sent := provider.requests[0]
+			if len(sent) != len(history)+1 {
+				t.Fatalf("expected %d messages, got %d", len(history)+1, len(sent))
+			}
+			for i, message := range history {
+				if sent[i].Role != message.Role || sent[i].Content != message.Content {
+					t.Errorf("message %d = %+v, want %+v", i, sent[i], message)
+				}
+			}
+			last := sent[len(history)]
+			if last.Role != llm.RoleUser || !strings.HasPrefix(last.Content, tt.wantPrefix) || !strings.Contains(last.Content, "count") {
+				t.Errorf("instruction message = %q", last.Content)
+			}
+		})
+	}
+}
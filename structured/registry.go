@@ -0,0 +1,192 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/prompt"
+)
+
+// Example is an input/output pair shown to the LLM as a few-shot demonstration
+type Example[T any] struct {
+	Input  string
+	Output T
+}
+
+// ExtractionType describes a reusable extraction: the target type T plus the
+// instructions, validator and examples used with it
+type ExtractionType[T any] struct {
+	Name         string                // Unique name used to look the type up
+	Description  string                // What the extraction produces
+	Instructions string                // Extra guidance added to every prompt
+	Validator    ValidatorInterface[T] // Optional domain validation
+	Examples     []Example[T]          // Optional few-shot examples
+	Config       *StructuredConfig     // Optional parsing configuration
+}
+
+// ExtractionResult is the type-erased outcome of a registry extraction
+type ExtractionResult struct {
+	Type     string   `json:"type"`
+	Data     any      `json:"data,omitempty"` // Pointer to the registered type
+	Warnings []string `json:"warnings,omitempty"`
+	Attempts int      `json:"attempts"`
+}
+
+// Extractor is a registered extraction type with its Go type erased
+type Extractor interface {
+	Name() string
+	Description() string
+	Schema() *JSONSchema
+	// New returns a pointer to a zero value of the registered type
+	New() any
+	// Extract parses input into the registered type using the given provider
+	Extract(ctx context.Context, provider llm.LLMProvider, input string) (*ExtractionResult, error)
+}
+
+// typedExtractor adapts an ExtractionType[T] to Extractor
+type typedExtractor[T any] struct {
+	definition ExtractionType[T]
+	config     *StructuredConfig
+	schema     *JSONSchema
+	context    []string
+}
+
+// NewExtractor validates an ExtractionType and returns it as an Extractor
+func NewExtractor[T any](definition ExtractionType[T]) (Extractor, error) {
+	if strings.TrimSpace(definition.Name) == "" {
+		return nil, fmt.Errorf("extraction type name cannot be empty")
+	}
+
+	schema, err := GenerateJSONSchema[T]()
+	if err != nil {
+		return nil, fmt.Errorf("extraction type %s: %w", definition.Name, err)
+	}
+
+	// Copy the configuration so the examples do not leak into a shared config
+	config := DefaultBaseConfig()
+	if definition.Config != nil && definition.Config.Config != nil {
+		parserConfig := *definition.Config.Config
+		config = &StructuredConfig{Config: &parserConfig}
+	}
+	for _, example := range definition.Examples {
+		config.Examples = append(config.Examples, prompt.Example{Input: example.Input, Output: example.Output})
+	}
+
+	extractor := &typedExtractor[T]{definition: definition, config: config, schema: schema}
+	if definition.Instructions != "" {
+		extractor.context = append(extractor.context, definition.Instructions)
+	}
+	return extractor, nil
+}
+
+// Name implements Extractor
+func (e *typedExtractor[T]) Name() string {
+	return e.definition.Name
+}
+
+// Description implements Extractor
+func (e *typedExtractor[T]) Description() string {
+	return e.definition.Description
+}
+
+// Schema implements Extractor
+func (e *typedExtractor[T]) Schema() *JSONSchema {
+	return e.schema
+}
+
+// New implements Extractor
+func (e *typedExtractor[T]) New() any {
+	return new(T)
+}
+
+// Extract implements Extractor using a StructuredNode with repair prompts
+func (e *typedExtractor[T]) Extract(ctx context.Context, provider llm.LLMProvider, input string) (*ExtractionResult, error) {
+	node, err := NewStructuredNode[T](provider, e.config, e.definition.Validator)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := node.ParseFromTextWithRepair(ctx, input, e.context...)
+	if err != nil {
+		return nil, fmt.Errorf("extraction %s failed: %w", e.definition.Name, err)
+	}
+
+	return &ExtractionResult{
+		Type:     e.definition.Name,
+		Data:     result.Data,
+		Warnings: result.Warnings,
+		Attempts: result.Attempts,
+	}, nil
+}
+
+// Registry holds extraction types by name so services can expose many of them
+// without code per type
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{extractors: make(map[string]Extractor)}
+}
+
+// DefaultRegistry is the package-level registry used by RegisterType
+var DefaultRegistry = NewRegistry()
+
+// Register adds an extractor; names must be unique
+func (r *Registry) Register(extractor Extractor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.extractors[extractor.Name()]; exists {
+		return fmt.Errorf("extraction type %s is already registered", extractor.Name())
+	}
+	r.extractors[extractor.Name()] = extractor
+	return nil
+}
+
+// RegisterType builds an Extractor from definition and adds it to the registry;
+// a nil registry means DefaultRegistry
+func RegisterType[T any](registry *Registry, definition ExtractionType[T]) error {
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	extractor, err := NewExtractor(definition)
+	if err != nil {
+		return err
+	}
+	return registry.Register(extractor)
+}
+
+// Get returns the extractor registered under name
+func (r *Registry) Get(name string) (Extractor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	extractor, ok := r.extractors[name]
+	return extractor, ok
+}
+
+// Names returns the registered type names in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.extractors))
+	for name := range r.extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Extract runs the named extraction
+func (r *Registry) Extract(ctx context.Context, name string, provider llm.LLMProvider, input string) (*ExtractionResult, error) {
+	extractor, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown extraction type %s", name)
+	}
+	return extractor.Extract(ctx, provider, input)
+}
@@ -0,0 +1,61 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	err := RegisterType(registry, ExtractionType[repairTarget]{
+		Name:         "widget",
+		Description:  "Widget counts",
+		Instructions: "Count only blue widgets.",
+		Examples:     []Example[repairTarget]{{Input: "two blue widgets", Output: repairTarget{Name: "widget", Count: 2}}},
+	})
+	if err != nil {
+		t.Fatalf("RegisterType() error = %v", err)
+	}
+	if err := RegisterType(registry, ExtractionType[repairTarget]{Name: "widget"}); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+	if err := RegisterType(registry, ExtractionType[repairTarget]{}); err == nil {
+		t.Error("expected empty name to fail")
+	}
+
+	if names := registry.Names(); len(names) != 1 || names[0] != "widget" {
+		t.Errorf("Names() = %v", names)
+	}
+
+	extractor, _ := registry.Get("widget")
+	if _, ok := extractor.New().(*repairTarget); !ok {
+		t.Errorf("New() returned %T", extractor.New())
+	}
+	if extractor.Schema().Properties["count"] == nil {
+		t.Error("expected schema for registered type")
+	}
+
+	provider := &scriptedProvider{responses: []string{"name: widget\ncount: 3\n"}}
+	result, err := registry.Extract(context.Background(), "widget", provider, "three blue widgets")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	prompt := provider.requests[0][0].Content
+	for _, want := range []string{"Count only blue widgets.", "Example 1 input:\n```\ntwo blue widgets", "Example 1 output:\n```yaml\nname: widget\ncount: 2"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q", want)
+		}
+	}
+
+	encoded, _ := json.Marshal(result)
+	if !strings.Contains(string(encoded), `"type":"widget"`) || result.Data.(*repairTarget).Count != 3 {
+		t.Errorf("unexpected result %s", encoded)
+	}
+
+	if _, err := registry.Extract(context.Background(), "missing", provider, "x"); err == nil {
+		t.Error("expected unknown type error")
+	}
+}
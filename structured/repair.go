@@ -0,0 +1,109 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// BuildRepairPrompt creates the follow-up message sent to the LLM after its output
+// could not be parsed or validated
+func BuildRepairPrompt(parseErr error, previousOutput string) string {
+	var builder strings.Builder
+	builder.WriteString("Your previous response could not be used because of the following error(s):\n\n")
+	builder.WriteString(parseErr.Error())
+	builder.WriteString("\n\n")
+
+	if strings.TrimSpace(previousOutput) != "" {
+		builder.WriteString("**Previous response:**\n```\n")
+		builder.WriteString(previousOutput)
+		builder.WriteString("\n```\n\n")
+	}
+
+	builder.WriteString("Please fix the problems listed above and respond again using exactly the requested format. ")
+	builder.WriteString("Return only the corrected structured output.")
+	return builder.String()
+}
+
+// ParseWithRepair sends a prompt to the LLM and, when the response cannot be parsed
+// into T or fails validation, re-prompts with the errors and the previous output.
+// At most maxAttempts LLM calls are made; a validator may be nil.
+func ParseWithRepair[T any](p *Parser, ctx context.Context, customPrompt string, validator ValidatorInterface[T], maxAttempts int) (ParseResult[T], error) {
+	messages := []llm.Message{
+		{
+			Role:    llm.RoleUser,
+			Content: customPrompt,
+		},
+	}
+
+	result, _, err := ParseMessagesWithRepair[T](p, ctx, messages, validator, maxAttempts)
+	return result, err
+}
+
+// ParseMessagesWithRepair is ParseWithRepair for an existing conversation.
+// It also returns the last LLM message so chat nodes can record it in their history.
+func ParseMessagesWithRepair[T any](p *Parser, ctx context.Context, messages []llm.Message, validator ValidatorInterface[T], maxAttempts int) (ParseResult[T], llm.Message, error) {
+	return parseMessagesWithRepair(p, ctx, messages, validator, maxAttempts, ResponseSchemaFor[T])
+}
+
+// parseMessagesWithRepair is ParseMessagesWithRepair with the response schema
+// for constrained decoding built by responseSchema
+func parseMessagesWithRepair[T any](p *Parser, ctx context.Context, messages []llm.Message, validator ValidatorInterface[T], maxAttempts int, responseSchema func() (llm.ResponseSchema, error)) (ParseResult[T], llm.Message, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	conversation := make([]llm.Message, len(messages), len(messages)+2*maxAttempts)
+	copy(conversation, messages)
+
+	var result ParseResult[T]
+	var response llm.Message
+	var lastErr error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		timeoutCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+		var constrained bool
+		var err error
+		response, constrained, err = callWithSchema(p, timeoutCtx, conversation, responseSchema)
+		cancel()
+		if err != nil {
+			// Provider failures are not something the model can repair
+			return ParseResult[T]{
+				Data:     nil,
+				Error:    fmt.Errorf("LLM call failed: %w", err),
+				Attempts: attempt,
+			}, response, err
+		}
+
+		result, lastErr = parseForType[T](response.Content, constrained)
+		if lastErr == nil && validator != nil {
+			warnings, err := runValidator(validator, result.Data)
+			if err != nil {
+				lastErr = fmt.Errorf("validation failed: %w", err)
+			}
+			result.Warnings = warnings
+		}
+
+		if lastErr == nil {
+			result.Attempts = attempt
+			return result, response, nil
+		}
+
+		// Feed the failure back to the model for the next attempt; the previous
+		// output is already part of the conversation as the assistant turn
+		conversation = append(conversation,
+			llm.Message{Role: llm.RoleAssistant, Content: response.Content},
+			llm.Message{Role: llm.RoleUser, Content: BuildRepairPrompt(lastErr, "")},
+		)
+	}
+
+	err := fmt.Errorf("failed to obtain valid output after %d attempts: %w", maxAttempts, lastErr)
+	return ParseResult[T]{
+		Data:        nil,
+		Error:       err,
+		Attempts:    maxAttempts,
+		Diagnostics: result.Diagnostics,
+	}, response, err
+}
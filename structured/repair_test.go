@@ -0,0 +1,89 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// scriptedProvider returns canned responses in order and records every request
+type scriptedProvider struct {
+	responses []string
+	requests  [][]llm.Message
+}
+
+func (s *scriptedProvider) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	s.requests = append(s.requests, messages)
+	if len(s.requests) > len(s.responses) {
+		return llm.Message{}, fmt.Errorf("no scripted response left")
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: s.responses[len(s.requests)-1]}, nil
+}
+
+func (s *scriptedProvider) GetName() string { return "scripted" }
+
+func (s *scriptedProvider) SetConfig(config map[string]any) error { return nil }
+
+type repairTarget struct {
+	Name  string `yaml:"name" required:"true"`
+	Count int    `yaml:"count" max:"10"`
+}
+
+func newTestParser(t *testing.T, provider llm.LLMProvider) *Parser {
+	t.Helper()
+	parser, err := NewParser(provider, &Config{MaxRetries: 0, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	return parser
+}
+
+func TestParseWithRepair_RecoversFromValidationError(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		"```yaml\nname: widget\ncount: 42\n```",
+		"```yaml\nname: widget\ncount: 4\n```",
+	}}
+	validator, err := NewSchemaValidator[repairTarget]()
+	if err != nil {
+		t.Fatalf("NewSchemaValidator() error = %v", err)
+	}
+
+	result, err := ParseWithRepair[repairTarget](newTestParser(t, provider), context.Background(), "extract", validator, 3)
+	if err != nil {
+		t.Fatalf("ParseWithRepair() error = %v", err)
+	}
+	if result.Attempts != 2 || result.Data.Count != 4 {
+		t.Errorf("expected repaired result after 2 attempts, got %+v (attempts %d)", result.Data, result.Attempts)
+	}
+
+	// The second request must contain the failed output and the validation error
+	second := provider.requests[1]
+	if len(second) != 3 {
+		t.Fatalf("expected 3 messages in repair request, got %d", len(second))
+	}
+	if second[1].Role != llm.RoleAssistant || !strings.Contains(second[1].Content, "count: 42") {
+		t.Errorf("repair request should replay the previous output, got %+v", second[1])
+	}
+	if !strings.Contains(second[2].Content, "count must be <= 10") {
+		t.Errorf("repair prompt should include the validation error, got %q", second[2].Content)
+	}
+}
+
+func TestParseWithRepair_GivesUpAfterMaxAttempts(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"name: [unclosed", "name: [still broken", "unused"}}
+
+	result, err := ParseWithRepair[repairTarget](newTestParser(t, provider), context.Background(), "extract", nil, 2)
+	if err == nil {
+		t.Fatal("expected error after exhausting attempts")
+	}
+	if result.Attempts != 2 || len(provider.requests) != 2 {
+		t.Errorf("expected exactly 2 attempts, got %d (requests %d)", result.Attempts, len(provider.requests))
+	}
+	if !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,573 @@
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchema is the subset of JSON Schema generated from target structs.
+// Constraints come from struct tags:
+//
+//	required:"true"      field must be present and non-zero
+//	min:"1" max:"10"     numeric bounds, string length or item count
+//	pattern:"^[A-Z]+$"   regular expression for string fields
+//	enum:"low,high"      allowed values (applies to items for lists)
+//	format:"email"       email, uri, uuid, date, date-time, ipv4 or ipv6
+type JSONSchema struct {
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+
+	// target is the schema Ref points to, which validates in its place
+	target *JSONSchema
+
+	// fieldIndex maps property names back to struct field indexes for validation
+	fieldIndex map[string]int
+	fieldOrder []string
+	pattern    *regexp.Regexp
+}
+
+// String returns the schema as indented JSON
+func (s *JSONSchema) String() string {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// GenerateJSONSchema builds a JSON Schema for type T from its fields and struct tags
+func GenerateJSONSchema[T any]() (*JSONSchema, error) {
+	var zero T
+	t := reflect.TypeOf(zero)
+	if t == nil {
+		return nil, fmt.Errorf("cannot generate schema for nil type")
+	}
+	return buildSchema(t)
+}
+
+// errUnsupportedType reports a type a schema cannot describe, e.g. a channel
+var errUnsupportedType = errors.New("unsupported type")
+
+// schemaBuilder converts Go types into schemas, referring to the structs that
+// contain themselves, e.g. a tree whose nodes have child nodes
+type schemaBuilder struct {
+	root     reflect.Type
+	building map[reflect.Type]bool          // Structs whose schema is being built
+	refs     map[reflect.Type][]*JSONSchema // References to structs on the stack, resolved once they are built
+	names    map[reflect.Type]string        // Names of the structs in $defs
+	used     map[string]bool                // The names taken
+	defs     map[string]*JSONSchema
+}
+
+// buildSchema converts a Go type into a schema. A struct that contains itself
+// is defined once: the root as "#", others under $defs of the root.
+func buildSchema(t reflect.Type) (*JSONSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	b := &schemaBuilder{
+		root:     t,
+		building: make(map[reflect.Type]bool),
+		refs:     make(map[reflect.Type][]*JSONSchema),
+		names:    make(map[reflect.Type]string),
+		used:     make(map[string]bool),
+	}
+	schema, err := b.schemaForType(t, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(b.defs) > 0 {
+		schema.Defs = b.defs
+	}
+	return schema, nil
+}
+
+// ref returns a reference to the struct t, whose schema is being built
+func (b *schemaBuilder) ref(t reflect.Type) *JSONSchema {
+	ref := &JSONSchema{Ref: "#"}
+	if t != b.root {
+		name, ok := b.names[t]
+		if !ok {
+			name = t.Name()
+			for i := 2; b.used[name]; i++ {
+				name = fmt.Sprintf("%s%d", t.Name(), i)
+			}
+			b.names[t], b.used[name] = name, true
+		}
+		ref.Ref = "#/$defs/" + name
+	}
+	b.refs[t] = append(b.refs[t], ref)
+	return ref
+}
+
+// schemaForType recursively converts a Go type into a schema
+func (b *schemaBuilder) schemaForType(t reflect.Type, path string) (*JSONSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Types with a registered converter are produced from strings by the LLM
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	case reflect.TypeOf(time.Duration(0)):
+		return &JSONSchema{Type: "string", Format: "duration"}, nil
+	}
+	if _, ok := converters.typeConverter(t); ok {
+		return &JSONSchema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := b.schemaForType(t.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		values, err := b.schemaForType(t.Elem(), path+"{}")
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+	case reflect.Struct:
+		if b.building[t] {
+			return b.ref(t), nil
+		}
+		if defined, ok := b.defs[b.names[t]]; ok {
+			return &JSONSchema{Ref: "#/$defs/" + b.names[t], target: defined}, nil
+		}
+		b.building[t] = true
+		schema, err := b.schemaForStruct(t, path)
+		delete(b.building, t)
+		if err != nil {
+			return nil, err
+		}
+		if refs := b.refs[t]; len(refs) > 0 {
+			for _, ref := range refs {
+				ref.target = schema
+			}
+			delete(b.refs, t)
+			if t != b.root {
+				if b.defs == nil {
+					b.defs = make(map[string]*JSONSchema)
+				}
+				b.defs[b.names[t]] = schema
+				// Every occurrence refers to the definition
+				return &JSONSchema{Ref: "#/$defs/" + b.names[t], target: schema}, nil
+			}
+		}
+		return schema, nil
+	default:
+		return nil, fmt.Errorf("%w %s at %s", errUnsupportedType, t.Kind(), displayPath(path))
+	}
+}
+
+// schemaForStruct converts a struct type into an object schema
+func (b *schemaBuilder) schemaForStruct(t reflect.Type, path string) (*JSONSchema, error) {
+	schema := &JSONSchema{
+		Type:       "object",
+		Properties: make(map[string]*JSONSchema),
+		fieldIndex: make(map[string]int),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		property, err := b.schemaForType(field.Type, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		property.Description = field.Tag.Get("description")
+
+		if err := applyConstraintTags(property, field, fieldPath); err != nil {
+			return nil, err
+		}
+
+		if field.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = property
+		schema.fieldIndex[name] = i
+		schema.fieldOrder = append(schema.fieldOrder, name)
+	}
+
+	return schema, nil
+}
+
+// applyConstraintTags reads min, max, pattern, enum and format tags into the schema
+func applyConstraintTags(schema *JSONSchema, field reflect.StructField, path string) error {
+	// enum and format describe scalar values, so for lists they constrain the items
+	valueSchema := schema
+	if schema.Type == "array" && schema.Items != nil {
+		valueSchema = schema.Items
+	}
+	if enumTag := field.Tag.Get("enum"); enumTag != "" {
+		values := strings.Split(enumTag, ",")
+		for i, value := range values {
+			values[i] = strings.TrimSpace(value)
+		}
+		valueSchema.Enum = values
+	}
+	if format := field.Tag.Get("format"); format != "" {
+		valueSchema.Format = format
+	}
+
+	if pattern := field.Tag.Get("pattern"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern tag on %s: %w", path, err)
+		}
+		schema.Pattern = pattern
+		schema.pattern = re
+	}
+
+	for _, bound := range []string{"min", "max"} {
+		tag := field.Tag.Get(bound)
+		if tag == "" {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(tag, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s tag on %s: %w", bound, path, err)
+		}
+
+		switch schema.Type {
+		case "integer", "number":
+			if bound == "min" {
+				schema.Minimum = &value
+			} else {
+				schema.Maximum = &value
+			}
+		case "string":
+			length := int(value)
+			if bound == "min" {
+				schema.MinLength = &length
+			} else {
+				schema.MaxLength = &length
+			}
+		case "array":
+			count := int(value)
+			if bound == "min" {
+				schema.MinItems = &count
+			} else {
+				schema.MaxItems = &count
+			}
+		default:
+			return fmt.Errorf("%s tag is not supported on %s of type %s", bound, path, schema.Type)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks a value against the schema and returns every violation found
+func (s *JSONSchema) Validate(value any) []error {
+	return s.validateValue(reflect.ValueOf(value), "")
+}
+
+// validateValue walks a reflected value alongside the schema
+func (s *JSONSchema) validateValue(v reflect.Value, path string) []error {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	var errs []error
+	schemaType := s.Type
+	if s.target != nil {
+		// Constraints next to $ref apply along with those of the target
+		errs = s.target.validateValue(v, path)
+		if schemaType == "" {
+			schemaType = s.target.Type
+		}
+	}
+	if err := s.validateEnum(v, path); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch schemaType {
+	case "object":
+		if v.Kind() == reflect.Struct {
+			errs = append(errs, s.validateStruct(v, path)...)
+		} else if v.Kind() == reflect.Map && s.AdditionalProperties != nil {
+			iter := v.MapRange()
+			for iter.Next() {
+				key := fmt.Sprintf("%v", iter.Key().Interface())
+				errs = append(errs, s.AdditionalProperties.validateValue(iter.Value(), joinPath(path, key))...)
+			}
+		}
+	case "array":
+		length := v.Len()
+		if s.MinItems != nil && length < *s.MinItems {
+			errs = append(errs, fmt.Errorf("%s must contain at least %d items, got %d", displayPath(path), *s.MinItems, length))
+		}
+		if s.MaxItems != nil && length > *s.MaxItems {
+			errs = append(errs, fmt.Errorf("%s must contain at most %d items, got %d", displayPath(path), *s.MaxItems, length))
+		}
+		if s.Items != nil {
+			for i := 0; i < length; i++ {
+				errs = append(errs, s.Items.validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		if v.Kind() != reflect.String {
+			break
+		}
+		str := v.String()
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = append(errs, fmt.Errorf("%s must be at least %d characters long", displayPath(path), *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = append(errs, fmt.Errorf("%s must be at most %d characters long", displayPath(path), *s.MaxLength))
+		}
+		if s.pattern != nil && str != "" && !s.pattern.MatchString(str) {
+			errs = append(errs, fmt.Errorf("%s value %q does not match pattern %s", displayPath(path), str, s.Pattern))
+		}
+		if str != "" && s.Format != "" {
+			if err := validateFormat(s.Format, str); err != nil {
+				errs = append(errs, fmt.Errorf("%s value %q is not a valid %s: %w", displayPath(path), str, s.Format, err))
+			}
+		}
+	case "integer", "number":
+		number, ok := numericValue(v)
+		if !ok {
+			break
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			errs = append(errs, fmt.Errorf("%s must be >= %v, got %v", displayPath(path), *s.Minimum, number))
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			errs = append(errs, fmt.Errorf("%s must be <= %v, got %v", displayPath(path), *s.Maximum, number))
+		}
+	}
+
+	return errs
+}
+
+// validateEnum checks scalar values against the allowed enum values; empty strings
+// are left to the required check
+func (s *JSONSchema) validateEnum(v reflect.Value, path string) error {
+	if len(s.Enum) == 0 || !isScalar(v) || (v.Kind() == reflect.String && v.String() == "") {
+		return nil
+	}
+
+	value := fmt.Sprintf("%v", v.Interface())
+	for _, enumValue := range s.Enum {
+		if enumValue == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s value %q is not one of the allowed values: %s", displayPath(path), value, strings.Join(s.Enum, ", "))
+}
+
+// validateStruct validates required fields and recurses into properties
+func (s *JSONSchema) validateStruct(v reflect.Value, path string) []error {
+	var errs []error
+
+	for _, name := range s.Required {
+		index, ok := s.fieldIndex[name]
+		if !ok {
+			continue
+		}
+		if v.Field(index).IsZero() {
+			errs = append(errs, fmt.Errorf("%s is required but empty or missing", displayPath(joinPath(path, name))))
+		}
+	}
+
+	for _, name := range s.fieldOrder {
+		property := s.Properties[name]
+		errs = append(errs, property.validateValue(v.Field(s.fieldIndex[name]), joinPath(path, name))...)
+	}
+
+	return errs
+}
+
+// SchemaValidator validates parsed data against the JSON Schema generated from T
+type SchemaValidator[T any] struct {
+	schema *JSONSchema
+}
+
+// NewSchemaValidator creates a validator from the struct tags of T
+func NewSchemaValidator[T any]() (*SchemaValidator[T], error) {
+	schema, err := GenerateJSONSchema[T]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+	return &SchemaValidator[T]{schema: schema}, nil
+}
+
+// Schema returns the schema used by the validator
+func (v *SchemaValidator[T]) Schema() *JSONSchema {
+	return v.schema
+}
+
+// Validate implements ValidatorInterface by checking data against the schema
+func (v *SchemaValidator[T]) Validate(data *T) error {
+	if data == nil {
+		return fmt.Errorf("data cannot be nil")
+	}
+
+	errs := v.schema.Validate(data)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Errorf("schema validation failed: %s", strings.Join(messages, "; "))
+}
+
+// fieldName returns the serialized name of a field, preferring yaml then json tags
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"yaml", "json"} {
+		if tag := field.Tag.Get(key); tag != "" {
+			if name := strings.Split(tag, ",")[0]; name != "" {
+				return name
+			}
+		}
+	}
+	return field.Name
+}
+
+// isScalar reports whether v holds a string, number or boolean
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Bool:
+		return true
+	default:
+		_, ok := numericValue(v)
+		return ok
+	}
+}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// validateFormat checks a string against a named format; unknown formats are not checked
+func validateFormat(format, value string) error {
+	switch format {
+	case "email":
+		if !emailPattern.MatchString(value) {
+			return fmt.Errorf("expected name@domain")
+		}
+	case "uri", "url":
+		parsed, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		if parsed.Scheme == "" || (parsed.Host == "" && parsed.Opaque == "") {
+			return fmt.Errorf("missing scheme or host")
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			return fmt.Errorf("expected 8-4-4-4-12 hex digits")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("expected YYYY-MM-DD")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("expected RFC 3339 timestamp")
+		}
+	case "ipv4":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("expected dotted IPv4 address")
+		}
+	case "ipv6":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("expected IPv6 address")
+		}
+	}
+	return nil
+}
+
+// numericValue converts an integer or float value to float64
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// joinPath appends a field name to a dotted path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// displayPath returns a printable path for error messages
+func displayPath(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
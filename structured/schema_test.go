@@ -0,0 +1,240 @@
+package structured
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type schemaContact struct {
+	Email string `yaml:"email" required:"true" pattern:"^[^@]+@[^@]+$" description:"Email address"`
+	Phone string `yaml:"phone,omitempty"`
+}
+
+type schemaCandidate struct {
+	Name     string          `yaml:"name" required:"true" min:"2" max:"50"`
+	Age      int             `yaml:"age" min:"18" max:"99"`
+	Skills   []string        `yaml:"skills" min:"1" max:"3"`
+	Contacts []schemaContact `yaml:"contacts"`
+	Internal string          `yaml:"-"`
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	schema, err := GenerateJSONSchema[schemaCandidate]()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema() error = %v", err)
+	}
+
+	if schema.Type != "object" {
+		t.Errorf("expected object schema, got %s", schema.Type)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "name" {
+		t.Errorf("expected required [name], got %v", schema.Required)
+	}
+	if _, ok := schema.Properties["Internal"]; ok {
+		t.Error("fields tagged with '-' should be skipped")
+	}
+
+	age := schema.Properties["age"]
+	if age.Type != "integer" || *age.Minimum != 18 || *age.Maximum != 99 {
+		t.Errorf("unexpected age schema: %s", age)
+	}
+
+	name := schema.Properties["name"]
+	if *name.MinLength != 2 || *name.MaxLength != 50 {
+		t.Errorf("unexpected name schema: %s", name)
+	}
+
+	skills := schema.Properties["skills"]
+	if skills.Type != "array" || *skills.MinItems != 1 || *skills.MaxItems != 3 {
+		t.Errorf("unexpected skills schema: %s", skills)
+	}
+
+	email := schema.Properties["contacts"].Items.Properties["email"]
+	if email.Pattern == "" || email.Description != "Email address" {
+		t.Errorf("unexpected email schema: %s", email)
+	}
+
+	if !strings.Contains(schema.String(), `"minimum": 18`) {
+		t.Errorf("schema JSON should contain minimum, got %s", schema.String())
+	}
+}
+
+type schemaTree struct {
+	Name     string        `yaml:"name" required:"true"`
+	Children []*schemaTree `yaml:"children"`
+}
+
+type schemaSection struct {
+	Title    string          `yaml:"title" required:"true"`
+	Sections []schemaSection `yaml:"sections"`
+}
+
+type schemaDocument struct {
+	Intro    schemaSection   `yaml:"intro"`
+	Sections []schemaSection `yaml:"sections"`
+}
+
+func TestGenerateJSONSchema_Recursive(t *testing.T) {
+	tree, err := GenerateJSONSchema[schemaTree]()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema() error = %v", err)
+	}
+	if ref := tree.Properties["children"].Items.Ref; ref != "#" || tree.Defs != nil {
+		t.Errorf("children items $ref = %q, $defs = %v, want the root", ref, tree.Defs)
+	}
+	value := schemaTree{Name: "root", Children: []*schemaTree{{Name: "a", Children: []*schemaTree{{}}}}}
+	if errs := tree.Validate(value); len(errs) != 1 || !strings.Contains(errs[0].Error(), "children[0].children[0].name") {
+		t.Errorf("Validate() = %v, want the nested name required", errs)
+	}
+
+	document, err := GenerateJSONSchema[schemaDocument]()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema() error = %v", err)
+	}
+	section := document.Defs["schemaSection"]
+	if section == nil || section.Properties["sections"].Items.Ref != "#/$defs/schemaSection" {
+		t.Fatalf("$defs = %v, want schemaSection referring to itself", document.Defs)
+	}
+	if document.Properties["intro"].Ref != "#/$defs/schemaSection" || document.Properties["sections"].Items.Ref != "#/$defs/schemaSection" {
+		t.Errorf("document = %s, want both fields to refer to the definition", document)
+	}
+	if _, err := ResponseSchemaFor[schemaDocument](); err != nil {
+		t.Errorf("ResponseSchemaFor() error = %v", err)
+	}
+	if !strings.Contains(document.String(), `"$defs"`) {
+		t.Errorf("String() = %s, want $defs", document)
+	}
+	if errs := document.Validate(schemaDocument{Sections: []schemaSection{{Title: "a", Sections: []schemaSection{{}}}}}); len(errs) != 2 {
+		t.Errorf("Validate() = %v, want the intro and nested titles required", errs)
+	}
+}
+
+func TestJSONSchema_RefSiblings(t *testing.T) {
+	limit := 3
+	code := &JSONSchema{Type: "string", Pattern: "^[A-Z]+$", pattern: regexp.MustCompile("^[A-Z]+$")}
+	ref := &JSONSchema{Ref: "#/$defs/code", MaxLength: &limit, target: code}
+
+	errs := ref.Validate("abcd")
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "pattern") || !strings.Contains(errs[1].Error(), "at most 3") {
+		t.Errorf("Validate() = %v, want the pattern of the target and the length next to $ref", errs)
+	}
+	if errs := ref.Validate("ABC"); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want none", errs)
+	}
+}
+
+func TestGenerateJSONSchema_InvalidTags(t *testing.T) {
+	type badPattern struct {
+		Code string `pattern:"[unclosed"`
+	}
+	if _, err := GenerateJSONSchema[badPattern](); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+
+	type badBound struct {
+		Flag bool `min:"1"`
+	}
+	if _, err := GenerateJSONSchema[badBound](); err == nil {
+		t.Error("expected error for min tag on bool")
+	}
+}
+
+func TestSchemaValidator_Validate(t *testing.T) {
+	validator, err := NewSchemaValidator[schemaCandidate]()
+	if err != nil {
+		t.Fatalf("NewSchemaValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     *schemaCandidate
+		wantErrs []string
+	}{
+		{
+			name: "valid",
+			data: &schemaCandidate{
+				Name:     "Jane Doe",
+				Age:      30,
+				Skills:   []string{"go"},
+				Contacts: []schemaContact{{Email: "jane@example.com"}},
+			},
+		},
+		{
+			name:     "nil data",
+			data:     nil,
+			wantErrs: []string{"data cannot be nil"},
+		},
+		{
+			name: "every constraint violated",
+			data: &schemaCandidate{
+				Age:      12,
+				Skills:   []string{"a", "b", "c", "d"},
+				Contacts: []schemaContact{{Email: "not-an-email"}, {}},
+			},
+			wantErrs: []string{
+				"name is required",
+				"age must be >= 18",
+				"skills must contain at most 3 items",
+				"contacts[0].email value \"not-an-email\" does not match pattern",
+				"contacts[1].email is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.data)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Validate() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() expected error, got nil")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error %q should contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+type schemaTicket struct {
+	Priority string   `yaml:"priority" enum:"low,medium,high"`
+	Level    int      `yaml:"level" enum:"1,2,3"`
+	Tags     []string `yaml:"tags" enum:"bug,feature"`
+	Reporter string   `yaml:"reporter" format:"email"`
+	Link     string   `yaml:"link" format:"uri"`
+	Due      string   `yaml:"due" format:"date"`
+}
+
+func TestSchemaValidator_EnumAndFormat(t *testing.T) {
+	validator, err := NewSchemaValidator[schemaTicket]()
+	if err != nil {
+		t.Fatalf("NewSchemaValidator() error = %v", err)
+	}
+
+	if items := validator.Schema().Properties["tags"].Items; len(items.Enum) != 2 {
+		t.Errorf("expected enum on list items, got %v", items.Enum)
+	}
+
+	valid := &schemaTicket{Priority: "high", Level: 2, Tags: []string{"bug"}, Reporter: "a@b.io", Link: "https://x.io/1", Due: "2024-02-29"}
+	if err := validator.Validate(valid); err != nil {
+		t.Errorf("Validate() unexpected error = %v", err)
+	}
+
+	invalid := &schemaTicket{Priority: "urgent", Level: 5, Tags: []string{"bug", "chore"}, Reporter: "nobody", Link: "x.io", Due: "29/02/2024"}
+	err = validator.Validate(invalid)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"priority value \"urgent\"", "level value \"5\"", "tags[1] value \"chore\"", "reporter value \"nobody\" is not a valid email", "link", "due"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got %v", want, err)
+		}
+	}
+}
@@ -9,6 +9,22 @@ type ValidatorInterface[T any] interface {
 	Validate(data *T) error
 }
 
+// WarningValidator is implemented by validators that can report non-fatal issues.
+// Warnings are attached to the ParseResult; only the returned error triggers
+// retries, repair prompts or fallbacks.
+type WarningValidator[T any] interface {
+	ValidatorInterface[T]
+	ValidateWithWarnings(data *T) (warnings []string, err error)
+}
+
+// runValidator validates data, collecting warnings when the validator supports them
+func runValidator[T any](validator ValidatorInterface[T], data *T) ([]string, error) {
+	if warningValidator, ok := validator.(WarningValidator[T]); ok {
+		return warningValidator.ValidateWithWarnings(data)
+	}
+	return nil, validator.Validate(data)
+}
+
 // NoOpValidator provides a validator that does no validation (always passes)
 type NoOpValidator[T any] struct{}
 
@@ -0,0 +1,99 @@
+package structured
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// countWarningValidator warns on large counts and fails on negative ones
+type countWarningValidator struct{}
+
+func (v countWarningValidator) Validate(data *repairTarget) error {
+	_, err := v.ValidateWithWarnings(data)
+	return err
+}
+
+func (v countWarningValidator) ValidateWithWarnings(data *repairTarget) ([]string, error) {
+	if data.Count < 0 {
+		return nil, fmt.Errorf("count cannot be negative")
+	}
+	if data.Count > 5 {
+		return []string{"count is unusually high"}, nil
+	}
+	return nil, nil
+}
+
+func TestStructuredNode_ValidateAndAnnotate(t *testing.T) {
+	node, err := NewStructuredNode[repairTarget](&scriptedProvider{}, nil, countWarningValidator{})
+	if err != nil {
+		t.Fatalf("NewStructuredNode() error = %v", err)
+	}
+
+	result, err := node.ValidateAndAnnotate(ParseResult[repairTarget]{Data: &repairTarget{Name: "a", Count: 7}})
+	if err != nil {
+		t.Fatalf("warnings must not be fatal, got %v", err)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected one warning, got %v", result.Warnings)
+	}
+
+	if _, err := node.ValidateAndAnnotate(ParseResult[repairTarget]{Data: &repairTarget{Name: "a", Count: -1}}); err == nil {
+		t.Error("expected fatal validator error")
+	}
+	if err := node.ValidateResult(ParseResult[repairTarget]{Data: &repairTarget{Count: 1}}); err == nil {
+		t.Error("expected schema error for missing required name")
+	}
+}
+
+func TestParseWithRepair_WarningsDoNotRetry(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"name: a\ncount: 9\n"}}
+
+	result, err := ParseWithRepair[repairTarget](newTestParser(t, provider), context.Background(), "extract", countWarningValidator{}, 3)
+	if err != nil {
+		t.Fatalf("ParseWithRepair() error = %v", err)
+	}
+	if result.Attempts != 1 || len(result.Warnings) != 1 {
+		t.Errorf("expected a single attempt with one warning, got attempts %d warnings %v", result.Attempts, result.Warnings)
+	}
+}
+
+// streamTarget has fields no schema can describe
+type streamTarget struct {
+	Name    string      `yaml:"name" required:"true"`
+	Updates chan string `yaml:"updates"`
+	OnDone  func()      `yaml:"on_done"`
+	Phase   complex128  `yaml:"phase"`
+}
+
+type streamValidator struct{}
+
+func (streamValidator) Validate(data *streamTarget) error {
+	if data.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	return nil
+}
+
+func TestStructuredNode_TypeWithoutSchema(t *testing.T) {
+	node, err := NewStructuredNode[streamTarget](&scriptedProvider{}, nil, streamValidator{})
+	if err != nil {
+		t.Fatalf("NewStructuredNode() error = %v", err)
+	}
+	if node.Schema() != nil {
+		t.Errorf("Schema() = %s, want none", node.Schema())
+	}
+	if err := node.Validate(&streamTarget{Name: "a"}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := node.Validate(&streamTarget{}); err == nil {
+		t.Error("expected the validator to still run")
+	}
+
+	type badPattern struct {
+		Code string `pattern:"[unclosed"`
+	}
+	if _, err := NewStructuredNode[badPattern](&scriptedProvider{}, nil, nil); err == nil {
+		t.Error("expected invalid tags to fail")
+	}
+}
@@ -0,0 +1,93 @@
+// Package testkit helps unit test nodes and flows: a fake clock, a scripted
+// LLM provider, fake tools and golden-file assertions on the actions and
+// states that runs produce.
+package testkit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock that only moves when the test advances it. Give nodes
+// its Now method, or its After and Sleep methods, instead of the time package.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []clockWaiter
+}
+
+// clockWaiter is a channel waiting for the clock to reach a time
+type clockWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock creates a clock set to start; a zero start uses 2024-01-01 00:00 UTC
+func NewFakeClock(start time.Time) *FakeClock {
+	if start.IsZero() {
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &FakeClock{now: start}
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the fake time elapsed since t
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the fake time once the clock has been
+// advanced by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, clockWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Sleep blocks until the clock has been advanced by d
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d and wakes the waiters that are due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to t and wakes the waiters that are due, earliest first
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+	pending := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.at.After(t) {
+			pending = append(pending, waiter)
+			continue
+		}
+		waiter.c <- t
+	}
+	c.waiters = pending
+}
+
+// Waiters returns the number of After and Sleep calls still waiting, so a test
+// can wait for a goroutine to block on the clock before advancing it
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// Path returns the names of the steps of the run, in order
+func (r Result[S]) Path() []string {
+	path := make([]string, len(r.Steps))
+	for i, step := range r.Steps {
+		path[i] = step.Name
+	}
+	return path
+}
+
+// ExpectPath fails the test unless the run visited exactly the named steps,
+// in order. Steps are named like core.WorkflowName names them, and a nested
+// flow is one step.
+func (r Result[S]) ExpectPath(t testing.TB, names ...string) Result[S] {
+	t.Helper()
+	path := r.Path()
+	if !slices.Equal(path, names) {
+		t.Errorf("run visited %s, want %s", formatPath(path), formatPath(names))
+	}
+	return r
+}
+
+// ExpectAction fails the test unless the run ended with action
+func (r Result[S]) ExpectAction(t testing.TB, action core.Action) Result[S] {
+	t.Helper()
+	if r.Action != action {
+		t.Errorf("run ended with %q, want %q", r.Action, action)
+	}
+	return r
+}
+
+// ExpectState fails the test unless the final state equals want. States are
+// compared as JSON, like golden files, and the failure lists the paths that
+// differ, as core.DiffStates names them.
+func (r Result[S]) ExpectState(t testing.TB, want S) Result[S] {
+	t.Helper()
+	diff := core.DiffStates(&want, r.State)
+	if diff.Error != "" {
+		t.Fatalf("cannot compare states: %s", diff.Error)
+	}
+	if len(diff.Changes) == 0 {
+		return r
+	}
+	var builder strings.Builder
+	for _, change := range diff.Changes {
+		switch change.Kind {
+		case core.ChangeAdded:
+			fmt.Fprintf(&builder, "\n  %s: unexpected %s", change.Path, change.After)
+		case core.ChangeRemoved:
+			fmt.Fprintf(&builder, "\n  %s: missing, want %s", change.Path, change.Before)
+		default:
+			fmt.Fprintf(&builder, "\n  %s: got %s, want %s", change.Path, change.After, change.Before)
+		}
+	}
+	if diff.Omitted > 0 {
+		fmt.Fprintf(&builder, "\n  and %d more", diff.Omitted)
+	}
+	t.Errorf("final state differs:%s", builder.String())
+	return r
+}
+
+// ExpectStateFunc calls check with the final state, for states only part of
+// which the test knows, e.g. with generated IDs or timestamps
+func (r Result[S]) ExpectStateFunc(t testing.TB, check func(t testing.TB, state *S)) Result[S] {
+	t.Helper()
+	check(t, r.State)
+	return r
+}
+
+// formatPath renders step names as "plan -> approve -> execute"
+func formatPath(names []string) string {
+	if len(names) == 0 {
+		return "no steps"
+	}
+	return strings.Join(names, " -> ")
+}
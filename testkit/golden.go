@@ -0,0 +1,94 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// UpdateEnv is the environment variable that makes AssertGolden write the
+// golden files instead of comparing, e.g. UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// Step is a step of a run; durations are left out so results are reproducible
+type Step struct {
+	Name   string      `json:"name"`
+	Action core.Action `json:"action"`
+}
+
+// Result is the outcome of RunNode or RunFlow
+type Result[S any] struct {
+	Action core.Action `json:"action"`
+	Steps  []Step      `json:"steps,omitempty"`
+	State  *S          `json:"state"`
+}
+
+// RunNode runs node once on state without retries and returns its action
+func RunNode[S, P, E any](node core.BaseNode[S, P, E], state *S) Result[S] {
+	action := core.NewNode(node, 0, 1).Run(state)
+	return Result[S]{Action: action, State: state}
+}
+
+// RunFlow runs workflow on state. The steps of a *core.Flow are recorded; other
+// workflows are recorded as one step.
+func RunFlow[S any](workflow core.Workflow[S], state *S) Result[S] {
+	result := Result[S]{State: state}
+	if flow, ok := workflow.(*core.Flow[S]); ok {
+		result.Action = flow.RunObserved(state, func(event core.StepEvent) {
+			result.Steps = append(result.Steps, Step{Name: event.Name, Action: event.Action})
+		})
+		return result
+	}
+	result.Action = workflow.Run(state)
+	result.Steps = []Step{{Name: core.WorkflowName(workflow), Action: result.Action}}
+	return result
+}
+
+// AssertGolden compares the result with the golden file testdata/<name>.golden
+func (r Result[S]) AssertGolden(t testing.TB, name string) {
+	t.Helper()
+	AssertGolden(t, name, r)
+}
+
+// AssertGolden compares got with the golden file testdata/<name>.golden. Strings
+// and byte slices are compared as they are, other values as indented JSON. With
+// UPDATE_GOLDEN set, the file is written instead.
+func AssertGolden(t testing.TB, name string, got any) {
+	t.Helper()
+	var data []byte
+	switch v := got.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		encoded, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", name, err)
+		}
+		data = append(encoded, '\n')
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with %s=1 to create it: %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s does not match %s; run with %s=1 to update it\ngot:\n%s\nwant:\n%s", name, path, UpdateEnv, data, want)
+	}
+}
@@ -0,0 +1,83 @@
+package testkit
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// leakTimeout is how long ExpectNoLeaks waits for goroutines to stop
+var leakTimeout = 2 * time.Second
+
+// ignoredGoroutines are functions of goroutines that the runtime and the
+// standard library start once and keep, which are not leaks
+var ignoredGoroutines = []string{
+	"os/signal.signal_recv",
+	"os/signal.loop",
+	"runtime.ensureSigM",
+	"testing.(*T).Run",
+	"testing.(*F).Fuzz",
+	"testing.runFuzzTests",
+	"net/http.(*persistConn)",
+}
+
+// ExpectNoLeaks fails the test if goroutines started during it are still
+// running when it ends, e.g. node workers, LLM calls or tool executions that
+// outlived a flow run. Goroutines get two seconds to stop; the failure lists
+// the stacks of those still running. Call it first in the test, and do not use
+// it in parallel tests, which start goroutines of their own.
+func ExpectNoLeaks(t testing.TB) {
+	t.Helper()
+	before := make(map[string]bool)
+	for _, stack := range goroutines() {
+		before[goroutineID(stack)] = true
+	}
+	t.Cleanup(func() {
+		deadline := time.Now().Add(leakTimeout)
+		for {
+			var leaked []string
+			for _, stack := range goroutines() {
+				if !before[goroutineID(stack)] && !ignoredGoroutine(stack) {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("%d goroutines outlived the test:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// goroutines returns the stacks of all goroutines
+func goroutines() []string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return strings.Split(strings.TrimSpace(string(buf[:n])), "\n\n")
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineID returns the ID of the goroutine of stack, e.g. "goroutine 7"
+func goroutineID(stack string) string {
+	id, _, _ := strings.Cut(stack, " [")
+	return id
+}
+
+// ignoredGoroutine reports whether stack belongs to a goroutine that is not a leak
+func ignoredGoroutine(stack string) bool {
+	for _, function := range ignoredGoroutines {
+		if strings.Contains(stack, function) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ErrScriptExhausted is returned by a ScriptedProvider called more often than scripted
+var ErrScriptExhausted = errors.New("no scripted turns left")
+
+// Turn is one scripted response of a ScriptedProvider
+type Turn struct {
+	Expect   string      // When set, the last message of the request must contain it
+	Response llm.Message // Returned when Err is nil; the role defaults to assistant
+	Err      error       // Returned instead of a response
+}
+
+// Reply scripts an assistant message with content
+func Reply(content string) Turn {
+	return Turn{Response: llm.Message{Role: llm.RoleAssistant, Content: content}}
+}
+
+// CallTools scripts an assistant message requesting calls
+func CallTools(calls ...llm.ToolCalls) Turn {
+	return Turn{Response: llm.Message{Role: llm.RoleAssistant, ToolCalls: calls}}
+}
+
+// Fail scripts a failed call
+func Fail(err error) Turn {
+	return Turn{Err: err}
+}
+
+// Expecting returns the turn with an expectation on the last message of its request
+func (t Turn) Expecting(text string) Turn {
+	t.Expect = text
+	return t
+}
+
+// ScriptedProvider is an llm.LLMProvider that returns its turns in order and
+// records the requests, so tests can drive a conversation step by step. Unlike
+// llm.MockProvider, it fails calls that do not match the script.
+type ScriptedProvider struct {
+	mu    sync.Mutex
+	name  string
+	turns []Turn
+	calls [][]llm.Message
+}
+
+// NewScriptedProvider creates a provider that answers with turns in order
+func NewScriptedProvider(turns ...Turn) *ScriptedProvider {
+	return &ScriptedProvider{name: "scripted", turns: turns}
+}
+
+// Add appends turns to the script
+func (p *ScriptedProvider) Add(turns ...Turn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.turns = append(p.turns, turns...)
+}
+
+// CallLLM returns the next turn. It fails with ErrScriptExhausted when no turns
+// are left and when the request does not meet the turn's expectation.
+func (p *ScriptedProvider) CallLLM(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, append([]llm.Message(nil), messages...))
+	call := len(p.calls)
+	if call > len(p.turns) {
+		return llm.Message{}, fmt.Errorf("call %d: %w", call, ErrScriptExhausted)
+	}
+
+	turn := p.turns[call-1]
+	if turn.Expect != "" {
+		var last string
+		if len(messages) > 0 {
+			last = messages[len(messages)-1].Content
+		}
+		if !strings.Contains(last, turn.Expect) {
+			return llm.Message{}, fmt.Errorf("call %d: expected the last message to contain %q, got %q", call, turn.Expect, last)
+		}
+	}
+	if turn.Err != nil {
+		return llm.Message{}, turn.Err
+	}
+	response := turn.Response
+	if response.Role == "" {
+		response.Role = llm.RoleAssistant
+	}
+	return response, nil
+}
+
+// GetName returns the provider name
+func (p *ScriptedProvider) GetName() string {
+	return p.name
+}
+
+// SetConfig accepts and ignores any configuration
+func (p *ScriptedProvider) SetConfig(config map[string]any) error {
+	return nil
+}
+
+// Calls returns the messages of each request received so far
+func (p *ScriptedProvider) Calls() [][]llm.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]llm.Message(nil), p.calls...)
+}
+
+// Remaining returns the number of turns not used yet
+func (p *ScriptedProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return max(len(p.turns)-len(p.calls), 0)
+}
+
+// AssertDone fails the test when scripted turns were not used
+func (p *ScriptedProvider) AssertDone(t testing.TB) {
+	t.Helper()
+	if remaining := p.Remaining(); remaining > 0 {
+		t.Errorf("%d scripted turns were not used", remaining)
+	}
+}
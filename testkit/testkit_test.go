@@ -0,0 +1,307 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+type conversation struct {
+	Messages  []llm.Message `json:"messages"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// chatNode asks the provider for the next message and routes tool calls
+type chatNode struct {
+	provider llm.LLMProvider
+	clock    *FakeClock
+}
+
+func (n chatNode) Prep(state *conversation) [][]llm.Message { return [][]llm.Message{state.Messages} }
+func (n chatNode) Exec(messages []llm.Message) (llm.Message, error) {
+	return n.provider.CallLLM(context.Background(), messages)
+}
+func (n chatNode) ExecFallback(err error) llm.Message {
+	return llm.Message{Role: llm.RoleAssistant, Content: "error: " + err.Error()}
+}
+func (n chatNode) Post(state *conversation, _ [][]llm.Message, results ...llm.Message) core.Action {
+	n.clock.Advance(time.Second)
+	state.Messages = append(state.Messages, results[0])
+	state.UpdatedAt = n.clock.Now()
+	if len(results[0].ToolCalls) > 0 {
+		return "tools"
+	}
+	return core.ActionSuccess
+}
+
+// toolNode runs the tool calls of the last message
+type toolNode struct {
+	manager *tools.ToolManager
+}
+
+func (n toolNode) Prep(state *conversation) []llm.ToolCalls {
+	return state.Messages[len(state.Messages)-1].ToolCalls
+}
+func (n toolNode) Exec(call llm.ToolCalls) (llm.ToolResults, error) {
+	return n.manager.ExecuteTool(context.Background(), call)
+}
+func (n toolNode) ExecFallback(err error) llm.ToolResults { return llm.ToolResults{IsError: true} }
+func (n toolNode) Post(state *conversation, _ []llm.ToolCalls, results ...llm.ToolResults) core.Action {
+	state.Messages = append(state.Messages, llm.Message{Role: llm.RoleUser, Content: results[0].Content, ToolResults: results})
+	return core.ActionContinue
+}
+
+func TestGoldenFlow(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	provider := NewScriptedProvider(
+		CallTools(llm.ToolCalls{Id: "1", ToolName: "weather", ToolArgs: map[string]any{"city": "Oslo"}}).Expecting("weather in Oslo"),
+		Reply("It is 4°C in Oslo.").Expecting("4°C"),
+	)
+	fakeTools := NewFakeTools(t, FakeTool{
+		Name:       "weather",
+		Parameters: map[string]tools.Parameter{"city": {Type: "string", Required: true}},
+		Results:    []string{"4°C and raining"},
+	})
+
+	chat := core.NewNode[conversation](chatNode{provider: provider, clock: clock}, 0, 1)
+	run := core.NewNode[conversation](toolNode{manager: fakeTools.Manager()}, 0, 1)
+	chat.AddSuccessor(run, "tools")
+	run.AddSuccessor(chat, core.ActionContinue)
+
+	state := &conversation{Messages: []llm.Message{{Role: llm.RoleUser, Content: "What is the weather in Oslo?"}}}
+	RunFlow[conversation](core.NewFlow[conversation](chat), state).AssertGolden(t, "weather_flow")
+
+	provider.AssertDone(t)
+	if calls := fakeTools.Calls("weather"); len(calls) != 1 || calls[0]["city"] != "Oslo" {
+		t.Errorf("weather calls = %v", calls)
+	}
+	if len(provider.Calls()) != 2 || len(provider.Calls()[1]) != 3 {
+		t.Errorf("requests = %+v", provider.Calls())
+	}
+}
+
+func TestRunNode(t *testing.T) {
+	provider := NewScriptedProvider(Fail(errors.New("rate limited")))
+	state := &conversation{}
+	result := RunNode[conversation](chatNode{provider: provider, clock: NewFakeClock(time.Time{})}, state)
+
+	if result.Action != core.ActionSuccess || state.Messages[0].Content != "error: rate limited" {
+		t.Errorf("RunNode() = %+v", result)
+	}
+	if _, err := provider.CallLLM(context.Background(), nil); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("call past the script = %v, want ErrScriptExhausted", err)
+	}
+}
+
+func TestScriptedProvider_Expectation(t *testing.T) {
+	provider := NewScriptedProvider(Reply("hi").Expecting("hello"))
+	_, err := provider.CallLLM(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "bye"}})
+	if err == nil {
+		t.Error("expected an error when the request does not match the turn")
+	}
+}
+
+func TestFakeTools(t *testing.T) {
+	fakeTools := NewFakeTools(t,
+		FakeTool{Name: "counter", Results: []string{"1", "2"}},
+		FakeTool{Name: "broken", Err: errors.New("disk full")},
+	)
+	ctx := context.Background()
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		result, _ := fakeTools.Manager().ExecuteTool(ctx, llm.ToolCalls{ToolName: "counter"})
+		got = append(got, result.Content)
+	}
+	if got[0] != "1" || got[1] != "2" || got[2] != "2" {
+		t.Errorf("results = %v, want 1, 2, 2", got)
+	}
+
+	result, _ := fakeTools.Manager().ExecuteTool(ctx, llm.ToolCalls{ToolName: "broken"})
+	if !result.IsError || result.Error != "Tool execution failed: disk full" {
+		t.Errorf("result of failing tool = %+v", result)
+	}
+	if len(fakeTools.Calls("counter")) != 3 || len(fakeTools.Calls("broken")) != 1 {
+		t.Errorf("calls = %d and %d", len(fakeTools.Calls("counter")), len(fakeTools.Calls("broken")))
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	start := clock.Now()
+
+	woke := make(chan time.Time)
+	go func() {
+		clock.Sleep(time.Minute)
+		woke <- clock.Now()
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(30 * time.Second)
+	select {
+	case <-woke:
+		t.Fatal("sleeper woke before its time")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(30 * time.Second)
+	if now := <-woke; now.Sub(start) != time.Minute {
+		t.Errorf("woke at %v, want a minute after start", now.Sub(start))
+	}
+	if clock.Since(start) != time.Minute || clock.Waiters() != 0 {
+		t.Errorf("Since() = %v with %d waiters", clock.Since(start), clock.Waiters())
+	}
+}
+
+// recordingT records the failures of assertions that are meant to fail
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestResult_Expect(t *testing.T) {
+	provider := NewScriptedProvider(
+		CallTools(llm.ToolCalls{Id: "1", ToolName: "weather", ToolArgs: map[string]any{"city": "Oslo"}}),
+		Reply("It is 4°C in Oslo."),
+	)
+	fakeTools := NewFakeTools(t, FakeTool{Name: "weather", Parameters: map[string]tools.Parameter{"city": {Type: "string"}}, Results: []string{"4°C"}})
+	chat := core.NewNode[conversation](chatNode{provider: provider, clock: NewFakeClock(time.Time{})}, 0, 1)
+	run := core.NewNode[conversation](toolNode{manager: fakeTools.Manager()}, 0, 1)
+	chat.AddSuccessor(run, "tools")
+	run.AddSuccessor(chat, core.ActionContinue)
+
+	result := RunFlow[conversation](core.NewFlow[conversation](chat), &conversation{})
+	result.ExpectPath(t, "chatNode", "toolNode", "chatNode").
+		ExpectAction(t, core.ActionSuccess).
+		ExpectStateFunc(t, func(t testing.TB, state *conversation) {
+			if len(state.Messages) != 3 || state.Messages[2].Content != "It is 4°C in Oslo." {
+				t.Errorf("messages = %+v", state.Messages)
+			}
+		})
+
+	recorder := &recordingT{TB: t}
+	result.ExpectPath(recorder, "chatNode", "chatNode").ExpectAction(recorder, core.ActionFailure)
+	want := []string{
+		"run visited chatNode -> toolNode -> chatNode, want chatNode -> chatNode",
+		`run ended with "success", want "failure"`,
+	}
+	if !reflect.DeepEqual(recorder.failures, want) {
+		t.Errorf("failures = %q, want %q", recorder.failures, want)
+	}
+}
+
+func TestResult_ExpectState(t *testing.T) {
+	type order struct {
+		ID     string   `json:"id"`
+		Items  []string `json:"items"`
+		Status string   `json:"status,omitempty"`
+	}
+	result := Result[order]{State: &order{ID: "A1", Items: []string{"tea"}, Status: "paid"}}
+	result.ExpectState(t, order{ID: "A1", Items: []string{"tea"}, Status: "paid"})
+
+	recorder := &recordingT{TB: t}
+	result.ExpectState(recorder, order{ID: "A1", Items: []string{"tea", "cake"}})
+	want := "final state differs:\n  items[1]: missing, want \"cake\"\n  status: unexpected \"paid\""
+	if len(recorder.failures) != 1 || recorder.failures[0] != want {
+		t.Errorf("failures = %q, want %q", recorder.failures, want)
+	}
+}
+
+// leakT runs its cleanups when asked and records failures
+type leakT struct {
+	recordingT
+	cleanups []func()
+}
+
+func (l *leakT) Cleanup(cleanup func()) {
+	l.cleanups = append(l.cleanups, cleanup)
+}
+
+func TestExpectNoLeaks(t *testing.T) {
+	defer func(timeout time.Duration) { leakTimeout = timeout }(leakTimeout)
+	leakTimeout = 50 * time.Millisecond
+
+	check := func(leak bool) []string {
+		lt := &leakT{recordingT: recordingT{TB: t}}
+		ExpectNoLeaks(lt)
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			<-stop
+		}()
+		if !leak {
+			close(stop)
+		}
+		for _, cleanup := range lt.cleanups {
+			cleanup()
+		}
+		if leak {
+			close(stop)
+		}
+		<-done
+		return lt.failures
+	}
+
+	if failures := check(false); len(failures) != 0 {
+		t.Errorf("ExpectNoLeaks() failed without a leak: %v", failures)
+	}
+	failures := check(true)
+	if len(failures) != 1 || !strings.Contains(failures[0], "1 goroutines outlived the test") || !strings.Contains(failures[0], "TestExpectNoLeaks") {
+		t.Errorf("ExpectNoLeaks() failures = %v, want the leaked goroutine", failures)
+	}
+}
+
+// waitNode calls an LLM that only answers once the run is cancelled
+type waitNode struct {
+	provider llm.LLMProvider
+}
+
+func (n waitNode) Prep(state *conversation) []int { return []int{1} }
+func (n waitNode) Exec(int) (llm.Message, error) {
+	return n.ExecContext(context.Background(), 0)
+}
+func (n waitNode) ExecContext(ctx context.Context, _ int) (llm.Message, error) {
+	return n.provider.CallLLM(ctx, nil)
+}
+func (n waitNode) ExecFallback(err error) llm.Message { return llm.Message{Content: err.Error()} }
+func (n waitNode) Post(state *conversation, _ []int, results ...llm.Message) core.Action {
+	state.Messages = append(state.Messages, results...)
+	return core.ActionSuccess
+}
+
+// blockingProvider answers once the context of the call is done
+type blockingProvider struct{ *llm.MockProvider }
+
+func (p blockingProvider) CallLLM(ctx context.Context, _ []llm.Message) (llm.Message, error) {
+	<-ctx.Done()
+	return llm.Message{}, ctx.Err()
+}
+
+func TestExpectNoLeaks_RunContext(t *testing.T) {
+	ExpectNoLeaks(t)
+	provider := llm.NewHedgedProvider(blockingProvider{llm.NewMockProvider("slow")}, nil, &llm.HedgeConfig{})
+	flow := core.NewFlow[conversation](core.NewNode[conversation](waitNode{provider: provider}, 0, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if action, report := flow.RunContext(ctx, &conversation{}, nil); action != core.ActionCancelled || !report.Cancelled {
+		t.Errorf("RunContext() = %v, %+v, want a cancelled run", action, report)
+	}
+}
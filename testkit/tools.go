@@ -0,0 +1,75 @@
+package testkit
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// FakeTool describes a tool of FakeTools
+type FakeTool struct {
+	Name        string
+	Description string
+	Parameters  map[string]tools.Parameter                // Arguments the tool accepts; the manager rejects others
+	Results     []string                                  // Returned in turn, the last one repeating
+	Err         error                                     // Fails every call when set
+	Handle      func(args map[string]any) (string, error) // Overrides Results and Err
+}
+
+// FakeTools is a tools.ToolManager with canned tools that records their calls
+type FakeTools struct {
+	manager *tools.ToolManager
+	mu      sync.Mutex
+	calls   map[string][]map[string]any
+}
+
+// NewFakeTools creates a manager with fakes; the test fails if one cannot be added
+func NewFakeTools(t testing.TB, fakes ...FakeTool) *FakeTools {
+	t.Helper()
+	f := &FakeTools{manager: tools.NewToolManager(), calls: make(map[string][]map[string]any)}
+	for _, fake := range fakes {
+		if err := f.manager.AddLocalToolLegacy(tools.LocalTool{
+			Name:        fake.Name,
+			Description: fake.Description,
+			Parameters:  fake.Parameters,
+			Handler:     f.handler(fake),
+		}); err != nil {
+			t.Fatalf("failed to add fake tool %s: %v", fake.Name, err)
+		}
+	}
+	return f
+}
+
+// handler records each call of fake and answers it
+func (f *FakeTools) handler(fake FakeTool) tools.ToolHandler {
+	return func(ctx context.Context, args map[string]any) (string, error) {
+		f.mu.Lock()
+		call := len(f.calls[fake.Name])
+		f.calls[fake.Name] = append(f.calls[fake.Name], args)
+		f.mu.Unlock()
+
+		switch {
+		case fake.Handle != nil:
+			return fake.Handle(args)
+		case fake.Err != nil:
+			return "", fake.Err
+		case len(fake.Results) == 0:
+			return "", nil
+		}
+		return fake.Results[min(call, len(fake.Results)-1)], nil
+	}
+}
+
+// Manager returns the manager to hand to the nodes under test
+func (f *FakeTools) Manager() *tools.ToolManager {
+	return f.manager
+}
+
+// Calls returns the arguments of each call of the tool name, in order
+func (f *FakeTools) Calls(name string) []map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]any(nil), f.calls[name]...)
+}
@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ApprovalDecision is the answer given for a single tool call
+type ApprovalDecision string
+
+const (
+	// ApprovalAllow runs the tool call once
+	ApprovalAllow ApprovalDecision = "allow"
+	// ApprovalDeny skips the tool call
+	ApprovalDeny ApprovalDecision = "deny"
+	// ApprovalAlways runs the tool call and every later call to the same tool
+	ApprovalAlways ApprovalDecision = "always"
+	// ApprovalFeedback interrupts approval with a free-form message for the LLM
+	ApprovalFeedback ApprovalDecision = "feedback"
+)
+
+// ApprovalResponse is returned by an ApprovalFunc
+type ApprovalResponse struct {
+	Decision ApprovalDecision
+	Feedback string // Message from the approver when Decision is ApprovalFeedback
+}
+
+// ApprovalFunc decides whether a tool call may run
+type ApprovalFunc func(ctx context.Context, toolCall llm.ToolCalls) (ApprovalResponse, error)
+
+// ApprovalOutcome summarizes the decisions for a batch of tool calls
+type ApprovalOutcome struct {
+	Approved []llm.ToolCalls // Tool calls that may be executed
+	Denied   []llm.ToolCalls // Tool calls that were rejected
+	Feedback string          // Set when the approver interrupted with a message; remaining calls are not approved
+}
+
+// Interrupted reports whether the approver answered with feedback instead of a decision
+func (o ApprovalOutcome) Interrupted() bool {
+	return o.Feedback != ""
+}
+
+// AutoApprove approves every tool call; it is the default ApprovalFunc
+func AutoApprove(ctx context.Context, toolCall llm.ToolCalls) (ApprovalResponse, error) {
+	return ApprovalResponse{Decision: ApprovalAllow}, nil
+}
+
+// NewTerminalApprover returns an ApprovalFunc that asks on the terminal with the
+// y(es) / n(o) / a(lways) prompt. Any other non-empty answer is returned as feedback.
+// The approver reads in line by line across calls, asking about one call at a time.
+func NewTerminalApprover(in io.Reader, out io.Writer) ApprovalFunc {
+	// One scanner for all calls, so lines it buffered ahead are not lost
+	scanner := bufio.NewScanner(in)
+	var mu sync.Mutex
+	return func(ctx context.Context, toolCall llm.ToolCalls) (ApprovalResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fmt.Fprintf(out, "\nTool '%s' requires permission.\n", toolCall.ToolName)
+		fmt.Fprintf(out, "Arguments: %v\n", toolCall.ToolArgs)
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return ApprovalResponse{Decision: ApprovalDeny}, err
+			}
+
+			fmt.Fprint(out, "Allow? [y=yes, n=no, a=always allow]: ")
+
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return ApprovalResponse{Decision: ApprovalDeny}, fmt.Errorf("failed to read approval: %w", err)
+				}
+				return ApprovalResponse{Decision: ApprovalDeny}, io.EOF
+			}
+
+			response := strings.TrimSpace(scanner.Text())
+			switch strings.ToLower(response) {
+			case "y", "yes":
+				return ApprovalResponse{Decision: ApprovalAllow}, nil
+			case "n", "no":
+				return ApprovalResponse{Decision: ApprovalDeny}, nil
+			case "a", "always":
+				return ApprovalResponse{Decision: ApprovalAlways}, nil
+			case "":
+				fmt.Fprintln(out, "Please enter a valid response (y/n/a)")
+				continue
+			default:
+				return ApprovalResponse{Decision: ApprovalFeedback, Feedback: response}, nil
+			}
+		}
+	}
+}
+
+// SetApprovalFunc sets the function used to approve tool calls; nil restores AutoApprove
+func (tm *ToolManager) SetApprovalFunc(approve ApprovalFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.approve = approve
+}
+
+// AlwaysAllow marks a tool as approved for the rest of the session
+func (tm *ToolManager) AlwaysAllow(toolName string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.alwaysAllowed == nil {
+		tm.alwaysAllowed = make(map[string]struct{})
+	}
+	tm.alwaysAllowed[toolName] = struct{}{}
+}
+
+// Disallow withdraws the standing approval of a tool, so its calls are approved one by one again
+func (tm *ToolManager) Disallow(toolName string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	delete(tm.alwaysAllowed, toolName)
+}
+
+// IsAlwaysAllowed reports whether a tool was approved with ApprovalAlways
+func (tm *ToolManager) IsAlwaysAllowed(toolName string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	_, ok := tm.alwaysAllowed[toolName]
+	return ok
+}
+
+// AlwaysAllowedTools returns the tools approved for the rest of the session, sorted by
+// name, e.g. to save them with the conversation
+func (tm *ToolManager) AlwaysAllowedTools() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	names := make([]string, 0, len(tm.alwaysAllowed))
+	for name := range tm.alwaysAllowed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApproveToolCalls runs the configured ApprovalFunc over a batch of tool calls.
+// Tools previously approved with ApprovalAlways are not asked about again.
+func (tm *ToolManager) ApproveToolCalls(ctx context.Context, toolCalls []llm.ToolCalls) (ApprovalOutcome, error) {
+	tm.mu.RLock()
+	approve := tm.approve
+	tm.mu.RUnlock()
+
+	if approve == nil {
+		approve = AutoApprove
+	}
+
+	outcome := ApprovalOutcome{
+		Approved: make([]llm.ToolCalls, 0, len(toolCalls)),
+	}
+
+	for _, toolCall := range toolCalls {
+		toolName := tm.ResolveToolName(toolCall.ToolName)
+		if tm.IsAlwaysAllowed(toolName) {
+			outcome.Approved = append(outcome.Approved, toolCall)
+			continue
+		}
+
+		response, err := approve(ctx, toolCall)
+		if err != nil {
+			return ApprovalOutcome{}, fmt.Errorf("approval for tool '%s' failed: %w", toolCall.ToolName, err)
+		}
+
+		switch response.Decision {
+		case ApprovalAllow:
+			outcome.Approved = append(outcome.Approved, toolCall)
+		case ApprovalAlways:
+			tm.AlwaysAllow(toolName)
+			outcome.Approved = append(outcome.Approved, toolCall)
+		case ApprovalFeedback:
+			outcome.Feedback = response.Feedback
+			outcome.Approved = nil
+			return outcome, nil
+		default:
+			outcome.Denied = append(outcome.Denied, toolCall)
+		}
+	}
+
+	return outcome, nil
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestTerminalApprover(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []ApprovalDecision
+		wantErr error
+	}{
+		{"approve", "y\n", []ApprovalDecision{ApprovalAllow}, nil},
+		{"deny", "no\n", []ApprovalDecision{ApprovalDeny}, nil},
+		{"always allow", "A\n", []ApprovalDecision{ApprovalAlways}, nil},
+		{"blank lines are asked again", "\n  \nyes\n", []ApprovalDecision{ApprovalAllow}, nil},
+		{"one reader for several calls", "y\nn\na\n", []ApprovalDecision{ApprovalAllow, ApprovalDeny, ApprovalAlways}, nil},
+		{"end of input denies", "y\n", []ApprovalDecision{ApprovalAllow, ApprovalDeny}, io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			approve := NewTerminalApprover(strings.NewReader(tt.input), &out)
+			for i, want := range tt.want {
+				response, err := approve(context.Background(), llm.ToolCalls{Id: "1", ToolName: "shell", ToolArgs: map[string]any{"cmd": "ls"}})
+				if i == len(tt.want)-1 && tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("call %d error = %v, want %v", i, err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("call %d error = %v", i, err)
+				}
+				if response.Decision != want {
+					t.Errorf("call %d decision = %s, want %s", i, response.Decision, want)
+				}
+			}
+			if !strings.Contains(out.String(), "Tool 'shell' requires permission") {
+				t.Errorf("prompt = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestTerminalApprover_Feedback(t *testing.T) {
+	var out strings.Builder
+	approve := NewTerminalApprover(strings.NewReader("use the staging database\n"), &out)
+	response, err := approve(context.Background(), llm.ToolCalls{Id: "1", ToolName: "shell"})
+	if err != nil {
+		t.Fatalf("approve() error = %v", err)
+	}
+	if response.Decision != ApprovalFeedback || response.Feedback != "use the staging database" {
+		t.Errorf("response = %+v, want the line as feedback", response)
+	}
+}
+
+func TestToolManager_ApproveToolCalls(t *testing.T) {
+	tm := NewToolManager()
+	tm.SetApprovalFunc(NewTerminalApprover(strings.NewReader("a\nn\nstop\n"), io.Discard))
+	calls := []llm.ToolCalls{{Id: "1", ToolName: "search"}, {Id: "2", ToolName: "search"}, {Id: "3", ToolName: "shell"}}
+
+	outcome, err := tm.ApproveToolCalls(context.Background(), calls)
+	if err != nil {
+		t.Fatalf("ApproveToolCalls() error = %v", err)
+	}
+	// The second search is approved without asking, so "n" answers the shell call
+	if len(outcome.Approved) != 2 || len(outcome.Denied) != 1 || outcome.Denied[0].Id != "3" {
+		t.Errorf("outcome = %+v, want both searches approved and the shell call denied", outcome)
+	}
+	if !tm.IsAlwaysAllowed("search") || tm.IsAlwaysAllowed("shell") {
+		t.Errorf("always allowed = %v, want [search]", tm.AlwaysAllowedTools())
+	}
+
+	outcome, err = tm.ApproveToolCalls(context.Background(), []llm.ToolCalls{{Id: "4", ToolName: "shell"}, {Id: "5", ToolName: "search"}})
+	if err != nil {
+		t.Fatalf("ApproveToolCalls() error = %v", err)
+	}
+	if !outcome.Interrupted() || outcome.Feedback != "stop" || len(outcome.Approved) != 0 {
+		t.Errorf("outcome = %+v, want the feedback and no approved calls", outcome)
+	}
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// CompactToolSchemas returns copies of schemas with shorter descriptions, for system
+// prompts that would otherwise exceed their token budget. Tool descriptions keep their
+// first sentence (at most maxDescription characters); parameter descriptions are
+// dropped for optional parameters and shortened the same way for required ones.
+func CompactToolSchemas(schemas []ToolSchema, maxDescription int) []ToolSchema {
+	compacted := make([]ToolSchema, len(schemas))
+	for i, schema := range schemas {
+		schema.Description = firstSentence(schema.Description, maxDescription)
+
+		parameters := make(map[string]Parameter, len(schema.Parameters))
+		for name, param := range schema.Parameters {
+			if param.Required {
+				param.Description = firstSentence(param.Description, maxDescription)
+			} else {
+				param.Description = ""
+			}
+			parameters[name] = param
+		}
+		schema.Parameters = parameters
+		compacted[i] = schema
+	}
+	return compacted
+}
+
+// firstSentence returns the first sentence of text, truncated to maxLength characters
+func firstSentence(text string, maxLength int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if index := strings.Index(text, ". "); index != -1 {
+		text = text[:index+1]
+	}
+	if maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		runes := []rune(text)
+		text = strings.TrimSpace(string(runes[:maxLength])) + "…"
+	}
+	return text
+}
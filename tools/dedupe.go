@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// CallCache remembers the results of tool calls, so a call repeating an
+// earlier one, same tool and same arguments, is answered with the earlier
+// result instead of running again. Use one cache per planning turn and Reset
+// it when the turn ends. The zero value is ready to use.
+type CallCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedCall
+}
+
+// cachedCall is a call that ran or is still running; done is closed once
+// result is set
+type cachedCall struct {
+	done   chan struct{}
+	result llm.ToolResults
+}
+
+// CallKey identifies a call by its tool name and its arguments as canonical
+// JSON, so the order of the arguments does not matter
+func CallKey(call llm.ToolCalls) string {
+	args, err := json.Marshal(call.ToolArgs)
+	if err != nil {
+		// Arguments that cannot be encoded never match another call
+		return ""
+	}
+	return call.ToolName + "\x00" + string(args)
+}
+
+// Do returns the result of an earlier identical call, waiting for it when it
+// is still running, and reports true; otherwise it runs the call with run.
+// Failed results are not kept, so a failed call runs again when repeated.
+func (c *CallCache) Do(call llm.ToolCalls, run func() llm.ToolResults) (llm.ToolResults, bool) {
+	key := CallKey(call)
+	if key == "" {
+		return run(), false
+	}
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		<-entry.done
+		result := entry.result
+		result.Id = call.Id
+		return result, true
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedCall)
+	}
+	entry := &cachedCall{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.result = run()
+	if entry.result.IsError {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.result, false
+}
+
+// Reset forgets all results, e.g. when a new planning turn starts
+func (c *CallCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestCallKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b llm.ToolCalls
+		same bool
+	}{
+		{"argument order", llm.ToolCalls{ToolName: "ls", ToolArgs: map[string]any{"path": ".", "all": true}}, llm.ToolCalls{ToolName: "ls", ToolArgs: map[string]any{"all": true, "path": "."}}, true},
+		{"call ids", llm.ToolCalls{Id: "1", ToolName: "ls"}, llm.ToolCalls{Id: "2", ToolName: "ls"}, true},
+		{"arguments", llm.ToolCalls{ToolName: "ls", ToolArgs: map[string]any{"path": "."}}, llm.ToolCalls{ToolName: "ls", ToolArgs: map[string]any{"path": "/"}}, false},
+		{"tools", llm.ToolCalls{ToolName: "ls"}, llm.ToolCalls{ToolName: "cat"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := CallKey(tt.a) == CallKey(tt.b); same != tt.same {
+				t.Errorf("CallKey() equal = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestCallCache(t *testing.T) {
+	var cache CallCache
+	var runs atomic.Int32
+	release := make(chan struct{})
+	run := func() llm.ToolResults {
+		runs.Add(1)
+		<-release
+		return llm.ToolResults{Id: "first", Content: "a.txt"}
+	}
+	call := llm.ToolCalls{ToolName: "ls", ToolArgs: map[string]any{"path": "."}}
+
+	// An identical call waits for the one still running
+	var wg sync.WaitGroup
+	results := make([]llm.ToolResults, 3)
+	repeated := make([]bool, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			call := call
+			call.Id = string(rune('a' + i))
+			results[i], repeated[i] = cache.Do(call, run)
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+	if runs.Load() != 1 {
+		t.Fatalf("the call ran %d times, want once", runs.Load())
+	}
+	count := 0
+	for i, result := range results {
+		if repeated[i] {
+			count++
+			if result.Id != string(rune('a'+i)) || result.Content != "a.txt" {
+				t.Errorf("repeated result = %+v", result)
+			}
+		}
+	}
+	if count != 2 {
+		t.Errorf("%d calls were repeated, want 2", count)
+	}
+
+	// Failures are not kept
+	failing := llm.ToolCalls{ToolName: "cat"}
+	for i := 0; i < 2; i++ {
+		if _, repeated := cache.Do(failing, func() llm.ToolResults { return llm.ToolResults{IsError: true} }); repeated {
+			t.Error("a failed call was answered from the cache")
+		}
+	}
+
+	cache.Reset()
+	if _, repeated := cache.Do(call, func() llm.ToolResults { return llm.ToolResults{} }); repeated {
+		t.Error("the call was answered from the cache after Reset")
+	}
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// fuzzInput has a field of each kind tool inputs commonly use
+type fuzzInput struct {
+	Text    string         `json:"text"`
+	Count   int            `json:"count,omitempty" default:"1"`
+	Small   int8           `json:"small,omitempty" default:"0"`
+	Size    uint32         `json:"size,omitempty" default:"0"`
+	Ratio   float32        `json:"ratio,omitempty" default:"0.5"`
+	Flag    bool           `json:"flag,omitempty" default:"false"`
+	Mode    string         `json:"mode,omitempty" enum:"fast,slow" default:"fast"`
+	Tags    []string       `json:"tags,omitempty" default:"[]"`
+	Scores  []int          `json:"scores,omitempty" default:"[]"`
+	Limit   *int           `json:"limit,omitempty"`
+	Options map[string]any `json:"options,omitempty" default:"{}"`
+}
+
+// hostileArgs are tool arguments that once broke or fooled argument
+// population: wrong types, fractions and out-of-range numbers, nulls and
+// nested values where scalars belong
+var hostileArgs = []string{
+	`{"text": "hello"}`,
+	`{"text": 65}`,
+	`{"text": "x", "count": 3.7}`,
+	`{"text": "x", "count": 1e300}`,
+	`{"text": "x", "small": 300}`,
+	`{"text": "x", "size": -1}`,
+	`{"text": "x", "ratio": 1e39}`,
+	`{"text": "x", "flag": "true"}`,
+	`{"text": "x", "mode": ["fast"]}`,
+	`{"text": "x", "tags": ["a", 1, null]}`,
+	`{"text": "x", "scores": [1, 2.5]}`,
+	`{"text": "x", "limit": null}`,
+	`{"text": "x", "limit": {"value": 1}}`,
+	`{"text": "x", "options": [1, 2]}`,
+	`{"text": {"nested": {"deeper": []}}}`,
+	`{"text": null, "count": -9223372036854775808}`,
+	`{"text": "x", "count": 9223372036854775807}`,
+}
+
+func FuzzPopulateStructFromArgs(f *testing.F) {
+	for _, args := range hostileArgs {
+		f.Add([]byte(args))
+	}
+	tm := NewToolManager()
+	var got fuzzInput
+	if err := tm.AddLocalTool("fuzz", "Fuzz target", func(input fuzzInput) string {
+		got = input
+		return "ok"
+	}); err != nil {
+		f.Fatalf("AddLocalTool failed: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var args map[string]any
+		if err := json.Unmarshal(data, &args); err != nil || args == nil {
+			t.Skip()
+		}
+		got = fuzzInput{}
+		result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "fuzz", ToolArgs: args})
+		if err != nil {
+			t.Fatalf("ExecuteTool() error = %v", err)
+		}
+		if result.IsError {
+			return
+		}
+
+		// Accepted arguments must arrive unchanged
+		if text, ok := args["text"].(string); ok && got.Text != text {
+			t.Errorf("text = %q, want %q", got.Text, text)
+		}
+		if _, ok := args["text"].(string); !ok && args["text"] != nil {
+			t.Errorf("accepted text %v of type %T", args["text"], args["text"])
+		}
+		for name, field := range map[string]float64{"count": float64(got.Count), "small": float64(got.Small), "size": float64(got.Size)} {
+			if number, ok := args[name].(float64); ok && (number != field || number != math.Trunc(number)) {
+				t.Errorf("%s = %v from argument %v", name, field, number)
+			}
+		}
+		if number, ok := args["ratio"].(float64); ok && float32(number) != got.Ratio {
+			t.Errorf("ratio = %v from argument %v", got.Ratio, number)
+		}
+		if tags, ok := args["tags"].([]any); ok {
+			if len(tags) != len(got.Tags) {
+				t.Fatalf("tags = %q from argument %v", got.Tags, tags)
+			}
+			for i, tag := range tags {
+				if tag != nil && tag != got.Tags[i] {
+					t.Errorf("tags = %q from argument %v", got.Tags, tags)
+				}
+			}
+		}
+		if mode, ok := args["mode"].(string); ok && mode != got.Mode {
+			t.Errorf("mode = %q from argument %v", got.Mode, mode)
+		}
+	})
+}
+
+func TestSetFieldValue_Numbers(t *testing.T) {
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("fuzz", "Fuzz target", func(input fuzzInput) fuzzInput { return input }); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+
+	tests := []struct {
+		args    string
+		want    string // Part of the result, or of the error when wantErr is set
+		wantErr bool
+	}{
+		{`{"text": "x", "count": 3}`, `"count":3`, false},
+		{`{"text": "x", "count": -3}`, `"count":-3`, false},
+		{`{"text": "x", "ratio": 2}`, `"ratio":2`, false},
+		{`{"text": "x", "tags": ["a", "b"], "scores": [1, 2]}`, `"tags":["a","b"],"scores":[1,2]`, false},
+		{`{"text": "x", "count": 3.7}`, "3.7 is not a whole number", true},
+		{`{"text": "x", "count": 1e300}`, "overflows int", true},
+		{`{"text": "x", "small": 300}`, "300 overflows int8", true},
+		{`{"text": "x", "size": -1}`, "-1 is negative", true},
+		{`{"text": "x", "ratio": 1e39}`, "overflows float32", true},
+		{`{"text": 65}`, "cannot assign float64 to string", true},
+		{`{"text": "x", "scores": [1, 2.5]}`, "element 1: 2.5 is not a whole number", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			var args map[string]any
+			if err := json.Unmarshal([]byte(tt.args), &args); err != nil {
+				t.Fatal(err)
+			}
+			result, _ := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "fuzz", ToolArgs: args})
+			if result.IsError != tt.wantErr {
+				t.Fatalf("result = %+v, want error %v", result, tt.wantErr)
+			}
+			if got := result.Content + result.Error; !strings.Contains(got, tt.want) {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
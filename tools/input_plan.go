@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// inputPlan is what executing a struct tool needs to know about its input
+// struct, worked out once by AddLocalTool instead of on every call
+type inputPlan struct {
+	fields []fieldPlan
+}
+
+// fieldPlan describes how one input field is read from the tool arguments
+type fieldPlan struct {
+	name         string // Argument name: the JSON name, or the Go name
+	index        int
+	required     bool
+	enum         map[string]struct{} // Allowed values, trimmed; nil allows any value
+	enumValues   []string            // Allowed values as written in the tag, for errors
+	defaultTag   string
+	defaultValue reflect.Value // Parsed default tag; invalid when there is none or it must be parsed per call
+}
+
+// newInputPlan builds the plan for an input struct, parsing enum and default
+// tags so that an invalid default fails when the tool is added
+func (tm *ToolManager) newInputPlan(structType reflect.Type) (*inputPlan, error) {
+	plan := &inputPlan{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+			if tagName, _, _ := strings.Cut(jsonTag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+		if name == "-" {
+			continue
+		}
+
+		defaultTag := field.Tag.Get("default")
+		fp := fieldPlan{
+			name:     name,
+			index:    i,
+			required: field.Type.Kind() != reflect.Ptr && defaultTag == "",
+		}
+		if enumTag := field.Tag.Get("enum"); enumTag != "" {
+			fp.enumValues = strings.Split(enumTag, ",")
+			fp.enum = make(map[string]struct{}, len(fp.enumValues))
+			for _, value := range fp.enumValues {
+				fp.enum[strings.TrimSpace(value)] = struct{}{}
+			}
+		}
+		if defaultTag != "" {
+			value := reflect.New(field.Type).Elem()
+			if err := tm.setFieldFromString(value, defaultTag); err != nil {
+				return nil, fmt.Errorf("invalid default value for %s: %v", name, err)
+			}
+			fp.defaultTag = defaultTag
+			// Defaults that hold references are parsed per call, so that a
+			// handler changing its input cannot change the default
+			switch field.Type.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Struct:
+			default:
+				fp.defaultValue = value
+			}
+		}
+		plan.fields = append(plan.fields, fp)
+	}
+	return plan, nil
+}
+
+// populateStructFromArgs populates an input struct from tool arguments
+func (tm *ToolManager) populateStructFromArgs(plan *inputPlan, structValue reflect.Value, args map[string]interface{}) error {
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		fieldValue := structValue.Field(field.index)
+
+		argValue, exists := args[field.name]
+		if !exists {
+			if field.defaultValue.IsValid() {
+				fieldValue.Set(field.defaultValue)
+			} else if field.defaultTag != "" {
+				if err := tm.setFieldFromString(fieldValue, field.defaultTag); err != nil {
+					return fmt.Errorf("failed to set default value for %s: %v", field.name, err)
+				}
+			} else if field.required {
+				return fmt.Errorf("required parameter '%s' is missing", field.name)
+			}
+			continue
+		}
+
+		if field.enum != nil {
+			var argStr string
+			if s, ok := argValue.(string); ok {
+				argStr = s
+			} else {
+				argStr = fmt.Sprintf("%v", argValue)
+			}
+			if _, ok := field.enum[argStr]; !ok {
+				return fmt.Errorf("parameter '%s' value '%v' is not in allowed enum values: %v", field.name, argValue, field.enumValues)
+			}
+		}
+
+		if err := tm.setFieldValue(fieldValue, argValue); err != nil {
+			return fmt.Errorf("failed to set field %s: %v", field.name, err)
+		}
+	}
+	return nil
+}
+
+// isNumberKind reports whether a kind is an integer or floating-point number
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setNumber sets a number field from a number argument, failing when the
+// argument is not a whole number for an integer field or does not fit the
+// field. Arguments decoded from JSON are float64.
+func setNumber(field, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		number := value.Float()
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("%v is not a finite number", number)
+		}
+		switch field.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if field.OverflowFloat(number) {
+				return fmt.Errorf("%v overflows %s", number, field.Type())
+			}
+			field.SetFloat(number)
+			return nil
+		}
+		if number != math.Trunc(number) {
+			return fmt.Errorf("%v is not a whole number", number)
+		}
+		if number < -(1<<63) || number >= 1<<64 {
+			return fmt.Errorf("%v overflows %s", number, field.Type())
+		}
+		if number < 0 {
+			return setInteger(field, int64(number), 0, true)
+		}
+		return setInteger(field, 0, uint64(number), false)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if number := value.Int(); number < 0 {
+			return setInteger(field, number, 0, true)
+		}
+		return setInteger(field, 0, uint64(value.Int()), false)
+	default:
+		return setInteger(field, 0, value.Uint(), false)
+	}
+}
+
+// setInteger sets a number field to the negative number signed, or to the
+// non-negative number unsigned
+func setInteger(field reflect.Value, signed int64, unsigned uint64, negative bool) error {
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if negative {
+			field.SetFloat(float64(signed))
+		} else {
+			field.SetFloat(float64(unsigned))
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if negative {
+			if field.OverflowInt(signed) {
+				return fmt.Errorf("%d overflows %s", signed, field.Type())
+			}
+			field.SetInt(signed)
+			return nil
+		}
+		if unsigned > math.MaxInt64 || field.OverflowInt(int64(unsigned)) {
+			return fmt.Errorf("%d overflows %s", unsigned, field.Type())
+		}
+		field.SetInt(int64(unsigned))
+		return nil
+	default:
+		if negative {
+			return fmt.Errorf("%d is negative, %s cannot hold it", signed, field.Type())
+		}
+		if field.OverflowUint(unsigned) {
+			return fmt.Errorf("%d overflows %s", unsigned, field.Type())
+		}
+		field.SetUint(unsigned)
+		return nil
+	}
+}
@@ -3,11 +3,14 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/alt-coder/pocketflow-go/internal/inflight"
 	"github.com/alt-coder/pocketflow-go/llm"
 )
 
@@ -16,6 +19,32 @@ type ToolManager struct {
 	localTools map[string]LocalTool
 	mcpManager *MCPManager
 	mu         sync.RWMutex
+
+	// Quotas and usage tracking
+	sessionQuota Quota
+	toolQuotas   map[string]Quota
+	sessionUsage ToolUsage
+	toolUsage    map[string]*ToolUsage
+
+	// Human approval
+	approve       ApprovalFunc
+	alwaysAllowed map[string]struct{}
+
+	// Compiled modules of WASM tools, closed when their tool is removed
+	wasmModules map[string]WASMModule
+
+	// Tool calls in flight, which Shutdown waits for
+	calls inflight.Tracker
+
+	// Where results too large for the conversation go
+	spillover SpilloverConfig
+	// Most bytes of a result added to the conversation, 0 for no limit
+	maxResultSize int
+
+	// How tools are presented to the LLM, by locale ("" for the deployment),
+	// and the registered names of renamed tools by their presented names
+	overrides map[string]ToolOverrides
+	renames   map[string]string
 }
 
 // LocalTool represents a locally defined tool function
@@ -26,6 +55,8 @@ type LocalTool struct {
 	Handler     interface{} // func(InputStruct) OutputStruct
 	inputType   reflect.Type
 	outputType  reflect.Type
+	inputPlan   *inputPlan
+	handler     reflect.Value
 }
 
 // Parameter represents a tool parameter definition
@@ -52,7 +83,12 @@ type ToolSchema struct {
 // NewToolManager creates a new tool manager
 func NewToolManager() *ToolManager {
 	return &ToolManager{
-		localTools: make(map[string]LocalTool),
+		localTools:    make(map[string]LocalTool),
+		toolQuotas:    make(map[string]Quota),
+		toolUsage:     make(map[string]*ToolUsage),
+		approve:       AutoApprove,
+		alwaysAllowed: make(map[string]struct{}),
+		wasmModules:   make(map[string]WASMModule),
 	}
 }
 
@@ -92,6 +128,11 @@ func (tm *ToolManager) AddLocalTool(name, description string, handler interface{
 		return fmt.Errorf("failed to generate parameters schema: %v", err)
 	}
 
+	plan, err := tm.newInputPlan(inputType)
+	if err != nil {
+		return err
+	}
+
 	tool := LocalTool{
 		Name:        name,
 		Description: description,
@@ -99,6 +140,8 @@ func (tm *ToolManager) AddLocalTool(name, description string, handler interface{
 		Handler:     handler,
 		inputType:   inputType,
 		outputType:  outputType,
+		inputPlan:   plan,
+		handler:     reflect.ValueOf(handler),
 	}
 
 	tm.localTools[name] = tool
@@ -130,8 +173,14 @@ func (tm *ToolManager) SetMCPManager(mcpManager *MCPManager) {
 	tm.mcpManager = mcpManager
 }
 
-// GetAvailableTools returns all available tools (local + MCP)
+// GetAvailableTools returns all available tools (local + MCP), as presented
+// with the overrides of the deployment
 func (tm *ToolManager) GetAvailableTools() []ToolSchema {
+	return tm.GetAvailableToolsFor("")
+}
+
+// availableTools returns all available tools as they are registered
+func (tm *ToolManager) availableTools() []ToolSchema {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
@@ -157,7 +206,7 @@ func (tm *ToolManager) GetAvailableTools() []ToolSchema {
 				params[name] = Parameter{
 					Type:        string(prop.Type),
 					Description: prop.Description,
-					Enum:       prop.Enum,
+					Enum:        prop.Enum,
 				}
 			}
 
@@ -175,27 +224,50 @@ func (tm *ToolManager) GetAvailableTools() []ToolSchema {
 
 // ExecuteTool executes a tool call, routing to local or MCP handler
 func (tm *ToolManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (llm.ToolResults, error) {
+	if !tm.calls.Start() {
+		return shutdownResult(toolCall), nil
+	}
+	defer tm.calls.Done()
+
 	tm.mu.RLock()
+	toolCall.ToolName = tm.resolve(toolCall.ToolName)
 	localTool, isLocal := tm.localTools[toolCall.ToolName]
+	mcpManager := tm.mcpManager
 	tm.mu.RUnlock()
 
-	// Try local tool first
-	if isLocal {
-		return tm.executeLocalTool(ctx, localTool, toolCall)
+	// Tool not found; checked before the call counts against a quota
+	if !isLocal && (mcpManager == nil || !mcpManager.HasTool(toolCall.ToolName)) {
+		return llm.ToolResults{
+			Id:      toolCall.Id,
+			Content: "",
+			IsError: true,
+			Code:    llm.ToolErrorNotFound,
+			Error:   fmt.Sprintf("Tool '%s' not found", toolCall.ToolName),
+		}, nil
 	}
 
-	// Try MCP tool if manager is available
-	if tm.mcpManager != nil {
-		return tm.mcpManager.ExecuteTool(ctx, toolCall)
+	// Enforce session and per-tool quotas before running anything
+	if exceeded := tm.reserveQuota(toolCall.ToolName); exceeded != nil {
+		return quotaExceededResult(toolCall, exceeded), nil
 	}
 
-	// Tool not found
-	return llm.ToolResults{
-		Id:      toolCall.Id,
-		Content: "",
-		IsError: true,
-		Error:   fmt.Sprintf("Tool '%s' not found", toolCall.ToolName),
-	}, nil
+	start := time.Now()
+	defer func() {
+		tm.recordRuntime(toolCall.ToolName, time.Since(start))
+	}()
+
+	// Try local tool first, then fall back to the MCP manager
+	var result llm.ToolResults
+	var err error
+	if isLocal {
+		result, err = tm.executeLocalTool(ctx, localTool, toolCall)
+	} else {
+		result, err = mcpManager.ExecuteTool(ctx, toolCall)
+	}
+	if err != nil {
+		return result, err
+	}
+	return tm.limitResult(toolCall, tm.spill(ctx, toolCall, result)), nil
 }
 
 // executeLocalTool executes a local tool
@@ -217,6 +289,7 @@ func (tm *ToolManager) executeLegacyTool(ctx context.Context, tool LocalTool, to
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorInvalidArgs,
 			Error:   fmt.Sprintf("Parameter validation failed: %v", err),
 		}, nil
 	}
@@ -228,6 +301,7 @@ func (tm *ToolManager) executeLegacyTool(ctx context.Context, tool LocalTool, to
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorCodeOf(err, llm.ToolErrorFailed),
 			Error:   fmt.Sprintf("Tool execution failed: %v", err),
 		}, nil
 	}
@@ -245,18 +319,18 @@ func (tm *ToolManager) executeStructTool(ctx context.Context, tool LocalTool, to
 	inputValue := reflect.New(tool.inputType).Elem()
 
 	// Populate struct fields from tool arguments
-	if err := tm.populateStructFromArgs(inputValue, toolCall.ToolArgs); err != nil {
+	if err := tm.populateStructFromArgs(tool.inputPlan, inputValue, toolCall.ToolArgs); err != nil {
 		return llm.ToolResults{
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorInvalidArgs,
 			Error:   fmt.Sprintf("Failed to populate input struct: %v", err),
 		}, nil
 	}
 
 	// Call the handler function
-	handlerValue := reflect.ValueOf(tool.Handler)
-	results := handlerValue.Call([]reflect.Value{inputValue})
+	results := tool.handler.Call([]reflect.Value{inputValue})
 
 	// Get the result
 	resultValue := results[0]
@@ -268,6 +342,7 @@ func (tm *ToolManager) executeStructTool(ctx context.Context, tool LocalTool, to
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorFailed,
 			Error:   fmt.Sprintf("Failed to marshal result: %v", err),
 		}, nil
 	}
@@ -279,92 +354,6 @@ func (tm *ToolManager) executeStructTool(ctx context.Context, tool LocalTool, to
 	}, nil
 }
 
-// populateStructFromArgs populates a struct from tool arguments
-func (tm *ToolManager) populateStructFromArgs(structValue reflect.Value, args map[string]interface{}) error {
-	structType := structValue.Type()
-
-	// Track which required fields we've seen
-	requiredFields := make(map[string]bool)
-
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
-		fieldValue := structValue.Field(i)
-
-		// Skip unexported fields
-		if !field.IsExported() {
-			continue
-		}
-
-		// Get field name (use json tag if available)
-		fieldName := field.Name
-		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
-			if parts := strings.Split(jsonTag, ","); len(parts) > 0 && parts[0] != "" {
-				fieldName = parts[0]
-			}
-		}
-
-		// Skip fields marked as json:"-"
-		if fieldName == "-" {
-			continue
-		}
-
-		// Check if field is required
-		isRequired := field.Type.Kind() != reflect.Ptr && field.Tag.Get("default") == ""
-		if isRequired {
-			requiredFields[fieldName] = false
-		}
-
-		// Get value from arguments
-		argValue, exists := args[fieldName]
-		if !exists {
-			// Check if field has default value
-			if defaultTag := field.Tag.Get("default"); defaultTag != "" {
-				if err := tm.setFieldFromString(fieldValue, defaultTag); err != nil {
-					return fmt.Errorf("failed to set default value for %s: %v", fieldName, err)
-				}
-			} else if isRequired {
-				return fmt.Errorf("required parameter '%s' is missing", fieldName)
-			}
-			continue
-		}
-
-		// Mark required field as provided
-		if isRequired {
-			requiredFields[fieldName] = true
-		}
-
-		// Validate enum values if specified
-		if enumTag := field.Tag.Get("enum"); enumTag != "" {
-			enumValues := strings.Split(enumTag, ",")
-			argStr := fmt.Sprintf("%v", argValue)
-			validEnum := false
-			for _, enumValue := range enumValues {
-				if strings.TrimSpace(enumValue) == argStr {
-					validEnum = true
-					break
-				}
-			}
-			if !validEnum {
-				return fmt.Errorf("parameter '%s' value '%v' is not in allowed enum values: %v", fieldName, argValue, enumValues)
-			}
-		}
-
-		// Set field value
-		if err := tm.setFieldValue(fieldValue, argValue); err != nil {
-			return fmt.Errorf("failed to set field %s: %v", fieldName, err)
-		}
-	}
-
-	// Check that all required fields were provided
-	for fieldName, provided := range requiredFields {
-		if !provided {
-			return fmt.Errorf("required parameter '%s' is missing", fieldName)
-		}
-	}
-
-	return nil
-}
-
 // setFieldValue sets a struct field value from an interface{}
 func (tm *ToolManager) setFieldValue(fieldValue reflect.Value, value interface{}) error {
 	if value == nil {
@@ -388,8 +377,26 @@ func (tm *ToolManager) setFieldValue(fieldValue reflect.Value, value interface{}
 		return nil
 	}
 
-	// Type conversion
-	if valueReflect.Type().ConvertibleTo(fieldType) {
+	// Numbers are converted only when they fit, so 3.7 or -1 are not
+	// silently truncated or wrapped
+	if isNumberKind(valueReflect.Kind()) && isNumberKind(fieldType.Kind()) {
+		return setNumber(fieldValue, valueReflect)
+	}
+
+	// JSON arrays are converted element by element, e.g. to a []string
+	if fieldType.Kind() == reflect.Slice && valueReflect.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(fieldType, valueReflect.Len(), valueReflect.Len())
+		for i := 0; i < valueReflect.Len(); i++ {
+			if err := tm.setFieldValue(slice.Index(i), valueReflect.Index(i).Interface()); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		fieldValue.Set(slice)
+		return nil
+	}
+
+	// Type conversion, except numbers to strings, which Go converts to a character
+	if valueReflect.Type().ConvertibleTo(fieldType) && !(fieldType.Kind() == reflect.String && isNumberKind(valueReflect.Kind())) {
 		fieldValue.Set(valueReflect.Convert(fieldType))
 		return nil
 	}
@@ -516,13 +523,14 @@ func (tm *ToolManager) RemoveLocalTool(toolName string) error {
 	}
 
 	delete(tm.localTools, toolName)
-	return nil
+	return tm.closeWASMModule(toolName)
 }
 
 // HasTool checks if a tool exists (local or MCP)
 func (tm *ToolManager) HasTool(toolName string) bool {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
+	toolName = tm.resolve(toolName)
 
 	// Check local tools
 	if _, exists := tm.localTools[toolName]; exists {
@@ -563,7 +571,7 @@ func (tm *ToolManager) generateParametersFromStruct(structType reflect.Type) (ma
 			if parts := strings.Split(jsonTag, ","); len(parts) > 0 && parts[0] != "" {
 				fieldName = parts[0]
 			}
-		}else if yamltag := field.Tag.Get("yaml"); yamltag != "" {
+		} else if yamltag := field.Tag.Get("yaml"); yamltag != "" {
 			if parts := strings.Split(yamltag, ","); len(parts) > 0 && parts[0] != "" {
 				fieldName = parts[0]
 			}
@@ -638,6 +646,31 @@ func (tm *ToolManager) goTypeToJSONType(goType reflect.Type) (string, error) {
 	}
 }
 
+// Shutdown stops the tool manager gracefully: new tool calls fail with an
+// error result, calls in flight are waited for until ctx is done, and then the
+// MCP manager is shut down and everything is closed
+func (tm *ToolManager) Shutdown(ctx context.Context) error {
+	err := tm.calls.Shutdown(ctx)
+
+	tm.mu.RLock()
+	mcpManager := tm.mcpManager
+	tm.mu.RUnlock()
+	if mcpManager != nil {
+		err = errors.Join(err, mcpManager.Shutdown(ctx))
+	}
+	return errors.Join(err, tm.Close())
+}
+
+// shutdownResult is the result of a tool call made after Shutdown
+func shutdownResult(toolCall llm.ToolCalls) llm.ToolResults {
+	return llm.ToolResults{
+		Id:      toolCall.Id,
+		IsError: true,
+		Code:    llm.ToolErrorUnavailable,
+		Error:   fmt.Sprintf("Tool '%s' not run: the tool manager is shutting down", toolCall.ToolName),
+	}
+}
+
 // Close closes the tool manager and cleans up resources
 func (tm *ToolManager) Close() error {
 	tm.mu.Lock()
@@ -645,6 +678,9 @@ func (tm *ToolManager) Close() error {
 
 	// Clear local tools
 	tm.localTools = make(map[string]LocalTool)
+	for name := range tm.wasmModules {
+		tm.closeWASMModule(name)
+	}
 
 	// Close MCP manager if available
 	if tm.mcpManager != nil {
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+type searchInput struct {
+	Query   string        `json:"query" description:"Search query"`
+	Limit   int           `json:"limit" default:"10"`
+	Sort    string        `json:"sort" enum:"relevance, date" default:"relevance"`
+	Tags    []interface{} `json:"tags" default:"[\"web\"]"`
+	Exact   *bool         `json:"exact"`
+	Verbose bool          `json:"-"`
+}
+
+type searchOutput struct {
+	Query string        `json:"query"`
+	Limit int           `json:"limit"`
+	Sort  string        `json:"sort"`
+	Tags  []interface{} `json:"tags"`
+	Exact bool          `json:"exact"`
+}
+
+func search(input searchInput) searchOutput {
+	output := searchOutput{Query: input.Query, Limit: input.Limit, Sort: input.Sort, Tags: append([]interface{}(nil), input.Tags...)}
+	if input.Exact != nil {
+		output.Exact = *input.Exact
+	}
+	// Changing the input must not change the defaults of later calls
+	if len(input.Tags) > 0 {
+		input.Tags[0] = "changed"
+	}
+	return output
+}
+
+func TestToolManager_ExecuteStructTool(t *testing.T) {
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("search", "Search the web", search); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{"defaults", map[string]interface{}{"query": "go"}, `{"query":"go","limit":10,"sort":"relevance","tags":["web"],"exact":false}`, ""},
+		{"defaults again", map[string]interface{}{"query": "go"}, `{"query":"go","limit":10,"sort":"relevance","tags":["web"],"exact":false}`, ""},
+		{"all arguments", map[string]interface{}{"query": "go", "limit": float64(3), "sort": "date", "tags": []interface{}{"news"}, "exact": true}, `{"query":"go","limit":3,"sort":"date","tags":["news"],"exact":true}`, ""},
+		{"missing required", map[string]interface{}{"limit": float64(3)}, "", "required parameter 'query' is missing"},
+		{"value outside enum", map[string]interface{}{"query": "go", "sort": "popularity"}, "", "not in allowed enum values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "search", ToolArgs: tt.args})
+			if err != nil {
+				t.Fatalf("ExecuteTool failed: %v", err)
+			}
+			if tt.wantErr != "" {
+				if !result.IsError || !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("result = %+v, want error %q", result, tt.wantErr)
+				}
+				return
+			}
+			if result.IsError || result.Content != tt.want {
+				t.Errorf("result = %+v, want %s", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolManager_AddLocalToolInvalidDefault(t *testing.T) {
+	type input struct {
+		Limit int `json:"limit" default:"ten"`
+	}
+	err := NewToolManager().AddLocalTool("bad", "Invalid default", func(input) string { return "" })
+	if err == nil || !strings.Contains(err.Error(), "invalid default value for limit") {
+		t.Errorf("AddLocalTool error = %v, want an invalid default", err)
+	}
+}
+
+func BenchmarkToolManager_ExecuteStructTool(b *testing.B) {
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("search", "Search the web", search); err != nil {
+		b.Fatalf("AddLocalTool failed: %v", err)
+	}
+	benchmarks := []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"defaults", map[string]interface{}{"query": "go"}},
+		{"all arguments", map[string]interface{}{"query": "go", "limit": float64(3), "sort": "date", "tags": []interface{}{"news"}, "exact": true}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			call := llm.ToolCalls{Id: "1", ToolName: "search", ToolArgs: bm.args}
+			ctx := context.Background()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if result, _ := tm.ExecuteTool(ctx, call); result.IsError {
+					b.Fatal(result.Error)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkToolManager_ExecuteStructToolParallel(b *testing.B) {
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("search", "Search the web", search); err != nil {
+		b.Fatalf("AddLocalTool failed: %v", err)
+	}
+	call := llm.ToolCalls{Id: "1", ToolName: "search", ToolArgs: map[string]interface{}{"query": "go", "limit": float64(3)}}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if result, _ := tm.ExecuteTool(context.Background(), call); result.IsError {
+				b.Error(result.Error)
+				return
+			}
+		}
+	})
+}
+
+func TestToolManager_Shutdown(t *testing.T) {
+	type input struct{}
+	started, release := make(chan struct{}), make(chan struct{})
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("slow", "A slow tool", func(input) string {
+		close(started)
+		<-release
+		return "done"
+	}); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+	if err := tm.AddLocalTool("fast", "A fast tool", func(input) string { return "done" }); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+
+	results := make(chan llm.ToolResults, 1)
+	go func() {
+		result, _ := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "slow"})
+		results <- result
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- tm.Shutdown(context.Background()) }()
+	// Wait for Shutdown to refuse new calls before making one
+	for {
+		result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "2", ToolName: "fast"})
+		if err != nil {
+			t.Fatalf("ExecuteTool failed: %v", err)
+		}
+		if result.IsError {
+			if !strings.Contains(result.Error, "shutting down") || result.Code != llm.ToolErrorUnavailable {
+				t.Errorf("result = %+v, want a shutdown error", result)
+			}
+			break
+		}
+	}
+
+	close(release)
+	if result := <-results; result.IsError || result.Content != `"done"` {
+		t.Errorf("call in flight = %+v, want it to finish", result)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+}
+
+func TestToolManager_ErrorCodes(t *testing.T) {
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("search", "Search", func(input searchInput) searchOutput { return searchOutput{} }); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+	for name, err := range map[string]error{
+		"missing": os.ErrNotExist,
+		"slow":    context.DeadlineExceeded,
+		"coded":   llm.NewToolError(llm.ToolErrorServerCrash, "backend crashed"),
+		"broken":  context.Canceled,
+	} {
+		if addErr := tm.AddLocalToolLegacy(LocalTool{
+			Name:        name,
+			Description: "Fails",
+			Handler: ToolHandler(func(ctx context.Context, args map[string]interface{}) (string, error) {
+				return "", err
+			}),
+		}); addErr != nil {
+			t.Fatalf("AddLocalToolLegacy failed: %v", addErr)
+		}
+	}
+
+	tests := []struct {
+		call llm.ToolCalls
+		want llm.ToolErrorCode
+	}{
+		{llm.ToolCalls{ToolName: "unknown"}, llm.ToolErrorNotFound},
+		{llm.ToolCalls{ToolName: "search", ToolArgs: map[string]any{"query": 1}}, llm.ToolErrorInvalidArgs},
+		{llm.ToolCalls{ToolName: "missing"}, llm.ToolErrorNotFound},
+		{llm.ToolCalls{ToolName: "slow"}, llm.ToolErrorTimeout},
+		{llm.ToolCalls{ToolName: "coded"}, llm.ToolErrorServerCrash},
+		{llm.ToolCalls{ToolName: "broken"}, llm.ToolErrorFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call.ToolName, func(t *testing.T) {
+			result, err := tm.ExecuteTool(context.Background(), tt.call)
+			if err != nil {
+				t.Fatalf("ExecuteTool failed: %v", err)
+			}
+			if !result.IsError || result.Code != tt.want {
+				t.Errorf("result = %+v, want code %q", result, tt.want)
+			}
+		})
+	}
+}
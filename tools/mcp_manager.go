@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"github.com/ThinkInAIXYZ/go-mcp/client"
 	"github.com/ThinkInAIXYZ/go-mcp/protocol"
 	"github.com/ThinkInAIXYZ/go-mcp/transport"
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/internal/inflight"
 	"github.com/alt-coder/pocketflow-go/llm"
 )
 
@@ -19,6 +22,7 @@ type MCPManager struct {
 	tools      map[string]MCPToolSchema             // Available tools
 	mu         sync.RWMutex                         // Thread safety
 	config     *MCPConfig                           // MCP configuration
+	calls      inflight.Tracker                     // Tool calls in flight, which Shutdown waits for
 }
 
 // MCPToolSchema represents an MCP tool schema
@@ -40,8 +44,12 @@ type MCPServerConfig struct {
 	Args     []string          `json:"args"`
 	Env      map[string]string `json:"env"`
 	Disabled bool              `json:"disabled"`
+	Timeout  core.Duration     `json:"timeout,omitempty"` // Limit of a tool call, e.g. "2m"; defaults to 30s
 }
 
+// defaultMCPToolTimeout limits tool calls of servers without a timeout
+const defaultMCPToolTimeout = 30 * time.Second
+
 // NewMCPManager creates a new MCP manager
 func NewMCPManager(config *MCPConfig) (*MCPManager) {
 	if config == nil {
@@ -169,6 +177,11 @@ func (m *MCPManager) GetAvailableTools() []MCPToolSchema {
 
 // ExecuteTool executes an MCP tool call
 func (m *MCPManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (llm.ToolResults, error) {
+	if !m.calls.Start() {
+		return shutdownResult(toolCall), nil
+	}
+	defer m.calls.Done()
+
 	m.mu.RLock()
 	tool, exists := m.tools[toolCall.ToolName]
 	m.mu.RUnlock()
@@ -178,6 +191,7 @@ func (m *MCPManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (l
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorNotFound,
 			Error:   fmt.Sprintf("MCP tool '%s' not found", toolCall.ToolName),
 		}, nil
 	}
@@ -185,13 +199,18 @@ func (m *MCPManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (l
 	// Find the client for this tool's server
 	m.mu.RLock()
 	targetClient, clientExists := m.clients[tool.ServerName]
+	timeout := m.config.Servers[tool.ServerName].Timeout.Std()
 	m.mu.RUnlock()
+	if timeout <= 0 {
+		timeout = defaultMCPToolTimeout
+	}
 
 	if !clientExists {
 		return llm.ToolResults{
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorUnavailable,
 			Error:   fmt.Sprintf("MCP client for server '%s' not available", tool.ServerName),
 		}, nil
 	}
@@ -203,7 +222,7 @@ func (m *MCPManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (l
 	}
 
 	// Execute tool with timeout
-	toolCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	result, err := targetClient.CallTool(toolCtx, request)
@@ -212,6 +231,7 @@ func (m *MCPManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (l
 			Id:      toolCall.Id,
 			Content: "",
 			IsError: true,
+			Code:    llm.ToolErrorCodeOf(err, llm.ToolErrorServerCrash),
 			Error:   fmt.Sprintf("MCP tool execution failed: %v", err),
 		}, nil
 	}
@@ -220,6 +240,9 @@ func (m *MCPManager) ExecuteTool(ctx context.Context, toolCall llm.ToolCalls) (l
 	var toolResult llm.ToolResults
 	toolResult.Id = toolCall.Id
 	toolResult.IsError = result.IsError
+	if result.IsError {
+		toolResult.Code = llm.ToolErrorFailed
+	}
 
 	// Process content items
 	for _, contentItem := range result.Content {
@@ -298,6 +321,13 @@ func (m *MCPManager) RemoveServer(serverName string) error {
 	return nil
 }
 
+// Shutdown stops new tool calls, waits for the calls in flight until ctx is
+// done and then closes the connections to the servers
+func (m *MCPManager) Shutdown(ctx context.Context) error {
+	err := m.calls.Shutdown(ctx)
+	return errors.Join(err, m.Close())
+}
+
 // Close closes all MCP connections and cleans up resources
 func (m *MCPManager) Close() error {
 	m.mu.Lock()
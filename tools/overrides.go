@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ToolOverride changes how a tool is presented to the LLM without changing
+// the tool, e.g. a clearer description than the terse one an MCP server
+// provides. Empty fields keep the tool's own.
+type ToolOverride struct {
+	Name        string            `json:"name,omitempty"`        // Name the LLM sees and calls the tool by
+	Description string            `json:"description,omitempty"` // Description of the tool
+	Parameters  map[string]string `json:"parameters,omitempty"`  // Descriptions of parameters by parameter name
+}
+
+// ToolOverrides are overrides by the name the tool is registered under
+type ToolOverrides map[string]ToolOverride
+
+// LoadToolOverrides reads overrides from a JSON file holding an object of
+// overrides by tool name, e.g.
+//
+//	{"search": {"name": "web_search", "description": "Search the public web..."}}
+func LoadToolOverrides(path string) (ToolOverrides, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool overrides: %w", err)
+	}
+	var overrides ToolOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("invalid tool overrides %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// SetToolOverrides sets the overrides of the deployment, which apply wherever
+// tools are presented to the LLM. A renamed tool is called by its new name;
+// ExecuteTool, HasTool and ApproveToolCalls take either name. Renames that
+// would present two tools under one name, in any locale, are rejected.
+func (tm *ToolManager) SetToolOverrides(overrides ToolOverrides) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.setOverrides("", overrides)
+}
+
+// SetLocaleOverrides sets the overrides of a locale such as "de" or "pt-BR",
+// applied over those of the deployment by GetAvailableToolsFor. The overrides
+// of a language apply to its regional locales too.
+func (tm *ToolManager) SetLocaleOverrides(locale string, overrides ToolOverrides) error {
+	if locale == "" {
+		return fmt.Errorf("locale cannot be empty")
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.setOverrides(locale, overrides)
+}
+
+// setOverrides replaces the overrides of a locale, "" for the deployment, and
+// rebuilds the renames; tm.mu must be held
+func (tm *ToolManager) setOverrides(locale string, overrides ToolOverrides) error {
+	all := make(map[string]ToolOverrides, len(tm.overrides)+1)
+	for l, o := range tm.overrides {
+		all[l] = o
+	}
+	all[locale] = overrides
+
+	renames := make(map[string]string)
+	for l, localeOverrides := range all {
+		for tool, override := range localeOverrides {
+			if override.Name == "" || override.Name == tool {
+				continue
+			}
+			if existing, ok := renames[override.Name]; ok && existing != tool {
+				return fmt.Errorf("tools '%s' and '%s' are both presented as '%s'", existing, tool, override.Name)
+			}
+			if tm.registered(override.Name) {
+				return fmt.Errorf("tool '%s' cannot be presented as '%s' of locale %q, which is another tool", tool, override.Name, l)
+			}
+			renames[override.Name] = tool
+		}
+	}
+
+	tm.overrides = all
+	tm.renames = renames
+	return nil
+}
+
+// registered reports whether a tool is registered under name; tm.mu must be held
+func (tm *ToolManager) registered(name string) bool {
+	if _, ok := tm.localTools[name]; ok {
+		return true
+	}
+	return tm.mcpManager != nil && tm.mcpManager.HasTool(name)
+}
+
+// ResolveToolName returns the name a tool is registered under for the name
+// the LLM called it by
+func (tm *ToolManager) ResolveToolName(name string) string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.resolve(name)
+}
+
+// resolve maps a presented name to the registered one; tm.mu must be held
+func (tm *ToolManager) resolve(name string) string {
+	if tool, ok := tm.renames[name]; ok && !tm.registered(name) {
+		return tool
+	}
+	return name
+}
+
+// GetAvailableToolsFor returns all available tools as presented in locale:
+// with the overrides of the deployment, then of the locale's language, then
+// of the locale
+func (tm *ToolManager) GetAvailableToolsFor(locale string) []ToolSchema {
+	tools := tm.availableTools()
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	layers := []ToolOverrides{tm.overrides[""]}
+	if language, _, regional := strings.Cut(locale, "-"); regional {
+		layers = append(layers, tm.overrides[language])
+	}
+	if locale != "" {
+		layers = append(layers, tm.overrides[locale])
+	}
+
+	for i := range tools {
+		name := tools[i].Name
+		for _, overrides := range layers {
+			if override, ok := overrides[tools[i].Name]; ok {
+				tools[i] = override.apply(tools[i])
+				if override.Name != "" {
+					name = override.Name
+				}
+			}
+		}
+		// Renamed last, so each layer finds the tool by its registered name
+		tools[i].Name = name
+	}
+	return tools
+}
+
+// apply presents tool with the description and parameter descriptions of the override
+func (o ToolOverride) apply(tool ToolSchema) ToolSchema {
+	if o.Description != "" {
+		tool.Description = o.Description
+	}
+	if len(o.Parameters) > 0 {
+		parameters := make(map[string]Parameter, len(tool.Parameters))
+		for name, parameter := range tool.Parameters {
+			if description, ok := o.Parameters[name]; ok {
+				parameter.Description = description
+			}
+			parameters[name] = parameter
+		}
+		tool.Parameters = parameters
+	}
+	return tool
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestToolManager_Overrides(t *testing.T) {
+	ctx := context.Background()
+	manager := NewToolManager()
+	for _, name := range []string{"search", "fetch"} {
+		manager.AddLocalToolLegacy(LocalTool{
+			Name:        name,
+			Description: "terse",
+			Parameters:  map[string]Parameter{"q": {Type: "string", Description: "query", Required: true}},
+			Handler: ToolHandler(func(_ context.Context, args map[string]interface{}) (string, error) {
+				return name + " " + args["q"].(string), nil
+			}),
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	os.WriteFile(path, []byte(`{"search": {"name": "web_search", "description": "Search the public web for recent pages.", "parameters": {"q": "Keywords, not a question"}}}`), 0o644)
+	overrides, err := LoadToolOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetToolOverrides(overrides); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetLocaleOverrides("de", ToolOverrides{"search": {Description: "Durchsucht das Web."}, "fetch": {Name: "abrufen"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	presented := func(locale string) map[string]ToolSchema {
+		tools := make(map[string]ToolSchema)
+		for _, tool := range manager.GetAvailableToolsFor(locale) {
+			tools[tool.Name] = tool
+		}
+		return tools
+	}
+	tools := presented("")
+	if search, ok := tools["web_search"]; !ok || search.Description != "Search the public web for recent pages." || search.Parameters["q"].Description != "Keywords, not a question" || !search.Parameters["q"].Required {
+		t.Errorf("deployment tools = %+v", tools)
+	}
+	if _, ok := tools["fetch"]; !ok || len(manager.GetAvailableTools()) != 2 {
+		t.Errorf("deployment tools = %+v", tools)
+	}
+	tools = presented("de-AT")
+	if tools["web_search"].Description != "Durchsucht das Web." || tools["web_search"].Parameters["q"].Description != "Keywords, not a question" || tools["abrufen"].Name != "abrufen" {
+		t.Errorf("de-AT tools = %+v", tools)
+	}
+
+	// The registered tool is unchanged and reachable by either name
+	for name, want := range map[string]string{"web_search": "search x", "search": "search x", "abrufen": "fetch x"} {
+		result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{ToolName: name, ToolArgs: map[string]interface{}{"q": "x"}})
+		if result.Content != want || !manager.HasTool(name) {
+			t.Errorf("%s = %+v", name, result)
+		}
+	}
+	if manager.localTools["search"].Description != "terse" || manager.localTools["search"].Parameters["q"].Description != "query" {
+		t.Error("overrides changed the registered tool")
+	}
+
+	if err := manager.SetLocaleOverrides("fr", ToolOverrides{"fetch": {Name: "web_search"}}); err == nil {
+		t.Error("expected an error for two tools presented under one name")
+	}
+	if err := manager.SetToolOverrides(ToolOverrides{"search": {Name: "fetch"}}); err == nil {
+		t.Error("expected an error for a name of another tool")
+	}
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// Quota limits how often and how long tools may run
+type Quota struct {
+	MaxCalls   int           // Maximum number of calls, 0 = unlimited
+	MaxRuntime time.Duration // Maximum cumulative runtime, 0 = unlimited
+}
+
+// ToolUsage tracks the calls made and runtime consumed by a tool or session
+type ToolUsage struct {
+	Calls   int           `json:"calls"`
+	Runtime time.Duration `json:"runtime"`
+}
+
+// QuotaExceeded describes which quota blocked a tool call.
+// It is serialized into the Content of the returned ToolResults so the planner can adapt.
+type QuotaExceeded struct {
+	Error   string `json:"error"`   // Always "quota_exceeded"
+	Tool    string `json:"tool"`    // Tool that was blocked
+	Scope   string `json:"scope"`   // "session" or "tool"
+	Limit   string `json:"limit"`   // "max_calls" or "max_runtime"
+	Used    string `json:"used"`    // Usage at the time of the call
+	Allowed string `json:"allowed"` // Configured limit
+	Message string `json:"message"` // Human/LLM readable explanation
+}
+
+// SetSessionQuota sets the quota shared by all tools managed by this manager
+func (tm *ToolManager) SetSessionQuota(quota Quota) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.sessionQuota = quota
+}
+
+// SetToolQuota sets the quota for a single tool (local or MCP)
+func (tm *ToolManager) SetToolQuota(toolName string, quota Quota) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.toolQuotas == nil {
+		tm.toolQuotas = make(map[string]Quota)
+	}
+	tm.toolQuotas[toolName] = quota
+}
+
+// GetToolUsage returns the usage recorded for a tool
+func (tm *ToolManager) GetToolUsage(toolName string) ToolUsage {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if usage, ok := tm.toolUsage[toolName]; ok {
+		return *usage
+	}
+	return ToolUsage{}
+}
+
+// GetSessionUsage returns the usage recorded across all tools
+func (tm *ToolManager) GetSessionUsage() ToolUsage {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.sessionUsage
+}
+
+// ResetUsage clears all recorded usage, e.g. when a new session starts
+func (tm *ToolManager) ResetUsage() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.sessionUsage = ToolUsage{}
+	tm.toolUsage = make(map[string]*ToolUsage)
+}
+
+// reserveQuota counts a call against the session and tool quotas, or returns a
+// non-nil QuotaExceeded if it would exceed one. Checking and counting under one
+// lock keeps concurrent calls from all passing the check before any is counted.
+// Calls count whether or not they succeed; their runtime is added once they end.
+func (tm *ToolManager) reserveQuota(toolName string) *QuotaExceeded {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if exceeded := quotaExceeded(toolName, "session", tm.sessionQuota, tm.sessionUsage); exceeded != nil {
+		return exceeded
+	}
+	if tm.toolUsage == nil {
+		tm.toolUsage = make(map[string]*ToolUsage)
+	}
+	usage, ok := tm.toolUsage[toolName]
+	if !ok {
+		usage = &ToolUsage{}
+	}
+	if quota, ok := tm.toolQuotas[toolName]; ok {
+		if exceeded := quotaExceeded(toolName, "tool", quota, *usage); exceeded != nil {
+			return exceeded
+		}
+	}
+
+	tm.toolUsage[toolName] = usage
+	tm.sessionUsage.Calls++
+	usage.Calls++
+	return nil
+}
+
+// recordRuntime adds the runtime of a reserved call to the session and tool usage
+func (tm *ToolManager) recordRuntime(toolName string, runtime time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.sessionUsage.Runtime += runtime
+	// ResetUsage may have dropped the tool's usage while the call ran
+	usage, ok := tm.toolUsage[toolName]
+	if !ok {
+		usage = &ToolUsage{}
+		tm.toolUsage[toolName] = usage
+	}
+	usage.Runtime += runtime
+}
+
+// quotaExceeded compares usage against a quota
+func quotaExceeded(toolName, scope string, quota Quota, usage ToolUsage) *QuotaExceeded {
+	if quota.MaxCalls > 0 && usage.Calls >= quota.MaxCalls {
+		return &QuotaExceeded{
+			Error:   "quota_exceeded",
+			Tool:    toolName,
+			Scope:   scope,
+			Limit:   "max_calls",
+			Used:    fmt.Sprintf("%d", usage.Calls),
+			Allowed: fmt.Sprintf("%d", quota.MaxCalls),
+			Message: fmt.Sprintf("The %s call limit of %d has been reached. Do not call '%s' again; continue with the information you already have.", scope, quota.MaxCalls, toolName),
+		}
+	}
+	if quota.MaxRuntime > 0 && usage.Runtime >= quota.MaxRuntime {
+		return &QuotaExceeded{
+			Error:   "quota_exceeded",
+			Tool:    toolName,
+			Scope:   scope,
+			Limit:   "max_runtime",
+			Used:    usage.Runtime.String(),
+			Allowed: quota.MaxRuntime.String(),
+			Message: fmt.Sprintf("The %s runtime budget of %s has been used up. Do not call '%s' again; continue with the information you already have.", scope, quota.MaxRuntime, toolName),
+		}
+	}
+	return nil
+}
+
+// quotaExceededResult converts a QuotaExceeded into a tool result
+func quotaExceededResult(toolCall llm.ToolCalls, exceeded *QuotaExceeded) llm.ToolResults {
+	content, _ := json.Marshal(exceeded)
+	return llm.ToolResults{
+		Id:      toolCall.Id,
+		Content: string(content),
+		IsError: true,
+		Code:    llm.ToolErrorQuotaExceeded,
+		Error:   exceeded.Message,
+		MetaData: llm.MetaData{
+			ContentType: "application/json",
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestToolManager_Quotas(t *testing.T) {
+	type input struct{}
+	tests := []struct {
+		name      string
+		session   Quota
+		tool      Quota
+		calls     int
+		wantRun   int
+		wantScope string
+		wantLimit string
+	}{
+		{"unlimited", Quota{}, Quota{}, 5, 5, "", ""},
+		{"session calls", Quota{MaxCalls: 2}, Quota{}, 4, 2, "session", "max_calls"},
+		{"tool calls", Quota{MaxCalls: 5}, Quota{MaxCalls: 1}, 3, 1, "tool", "max_calls"},
+		{"tool runtime", Quota{}, Quota{MaxRuntime: time.Nanosecond}, 3, 1, "tool", "max_runtime"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs int
+			tm := NewToolManager()
+			if err := tm.AddLocalTool("search", "Search the web", func(input) string {
+				runs++
+				time.Sleep(time.Millisecond)
+				return "found"
+			}); err != nil {
+				t.Fatalf("AddLocalTool failed: %v", err)
+			}
+			tm.SetSessionQuota(tt.session)
+			tm.SetToolQuota("search", tt.tool)
+
+			var last llm.ToolResults
+			for i := 0; i < tt.calls; i++ {
+				result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "search"})
+				if err != nil {
+					t.Fatalf("ExecuteTool failed: %v", err)
+				}
+				last = result
+			}
+			if runs != tt.wantRun {
+				t.Errorf("tool ran %d times, want %d", runs, tt.wantRun)
+			}
+			if usage := tm.GetToolUsage("search"); usage.Calls != tt.wantRun || usage.Runtime <= 0 {
+				t.Errorf("tool usage = %+v, want %d calls and some runtime", usage, tt.wantRun)
+			}
+			if usage := tm.GetSessionUsage(); usage.Calls != tt.wantRun {
+				t.Errorf("session usage = %+v, want %d calls", usage, tt.wantRun)
+			}
+
+			if tt.wantScope == "" {
+				if last.IsError {
+					t.Errorf("last result = %+v, want success", last)
+				}
+				return
+			}
+			var exceeded QuotaExceeded
+			if err := json.Unmarshal([]byte(last.Content), &exceeded); err != nil {
+				t.Fatalf("last result content %q: %v", last.Content, err)
+			}
+			if !last.IsError || last.Code != llm.ToolErrorQuotaExceeded || exceeded.Scope != tt.wantScope || exceeded.Limit != tt.wantLimit {
+				t.Errorf("last result = %+v, want a %s %s quota error", last, tt.wantScope, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestToolManager_QuotaConcurrentCalls(t *testing.T) {
+	type input struct{}
+	var runs atomic.Int32
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("search", "Search the web", func(input) string {
+		runs.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return "found"
+	}); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+	tm.SetSessionQuota(Quota{MaxCalls: 5})
+	tm.SetToolQuota("search", Quota{MaxCalls: 3})
+
+	var wg sync.WaitGroup
+	var refused atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "search"})
+			if err != nil {
+				t.Errorf("ExecuteTool failed: %v", err)
+			}
+			if result.Code == llm.ToolErrorQuotaExceeded {
+				refused.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if runs.Load() != 3 || refused.Load() != 17 {
+		t.Errorf("tool ran %d times and refused %d calls, want 3 and 17", runs.Load(), refused.Load())
+	}
+	if usage := tm.GetSessionUsage(); usage.Calls != 3 {
+		t.Errorf("session usage = %+v, want 3 calls", usage)
+	}
+}
+
+func TestToolManager_QuotaUnknownTool(t *testing.T) {
+	type input struct{}
+	tm := NewToolManager()
+	if err := tm.AddLocalTool("search", "Search the web", func(input) string { return "found" }); err != nil {
+		t.Fatalf("AddLocalTool failed: %v", err)
+	}
+	tm.SetSessionQuota(Quota{MaxCalls: 1})
+
+	for _, mcp := range []*MCPManager{nil, NewMCPManager(nil)} {
+		tm.SetMCPManager(mcp)
+		result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "serach"})
+		if err != nil || result.Code != llm.ToolErrorNotFound {
+			t.Errorf("ExecuteTool(serach) = %+v, %v; want a not found error", result, err)
+		}
+	}
+	if usage := tm.GetSessionUsage(); usage.Calls != 0 {
+		t.Errorf("session usage = %+v, want unknown tools not counted", usage)
+	}
+	if result, _ := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "2", ToolName: "search"}); result.IsError {
+		t.Errorf("ExecuteTool(search) = %+v, want the quota left for it", result)
+	}
+}
@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ToolSelector narrows the tools advertised to the LLM to those relevant to a
+// request, so dozens of connected MCP tools do not fill the system prompt
+type ToolSelector interface {
+	SelectTools(ctx context.Context, request string, tools []ToolSchema) ([]ToolSchema, error)
+}
+
+// SelectionConfig configures a ToolSelector
+type SelectionConfig struct {
+	MaxTools int      // Most tools selected per request, 8 if not set; requests with fewer tools get all of them
+	Always   []string // Tools advertised for every request, e.g. ReadArtifactTool; they do not count toward MaxTools
+}
+
+// DefaultSelectionConfig selects up to eight tools
+func DefaultSelectionConfig() *SelectionConfig {
+	return &SelectionConfig{MaxTools: 8}
+}
+
+// selectionConfig returns config, or the default for nil, with a MaxTools of at least one
+func selectionConfig(config *SelectionConfig) *SelectionConfig {
+	if config == nil {
+		return DefaultSelectionConfig()
+	}
+	if config.MaxTools <= 0 {
+		withDefault := *config
+		withDefault.MaxTools = DefaultSelectionConfig().MaxTools
+		return &withDefault
+	}
+	return config
+}
+
+// SelectTools returns the tools available in locale that selector picks for
+// request. When selection fails, it returns all of them with the error, so the
+// caller can go on with the full list.
+func (tm *ToolManager) SelectTools(ctx context.Context, selector ToolSelector, request, locale string) ([]ToolSchema, error) {
+	tools := tm.GetAvailableToolsFor(locale)
+	selected, err := selector.SelectTools(ctx, request, tools)
+	if err != nil {
+		return tools, fmt.Errorf("tool selection failed: %w", err)
+	}
+	return selected, nil
+}
+
+// candidates splits tools into those always advertised and those to select
+// from, and reports whether selection is needed at all
+func (c *SelectionConfig) candidates(tools []ToolSchema) (candidates []ToolSchema, needed bool) {
+	always := make(map[string]bool, len(c.Always))
+	for _, name := range c.Always {
+		always[name] = true
+	}
+	for _, tool := range tools {
+		if !always[tool.Name] {
+			candidates = append(candidates, tool)
+		}
+	}
+	return candidates, len(candidates) > c.MaxTools
+}
+
+// selected returns the tools that are always advertised or chosen, in the order of tools
+func (c *SelectionConfig) selected(tools []ToolSchema, chosen map[string]bool) []ToolSchema {
+	for _, name := range c.Always {
+		chosen[name] = true
+	}
+	var selected []ToolSchema
+	for _, tool := range tools {
+		if chosen[tool.Name] {
+			selected = append(selected, tool)
+		}
+	}
+	return selected
+}
+
+// Embedder turns texts into vectors. retrieval.Embedder and the OpenAI client
+// implement it.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbeddingSelector selects the tools whose name and description are most
+// similar to the request. Tool embeddings are computed once and cached, so a
+// request costs one embedding call.
+type EmbeddingSelector struct {
+	embedder Embedder
+	config   *SelectionConfig
+
+	mu      sync.Mutex
+	vectors map[string][]float32 // Embeddings of tools by their text
+}
+
+// NewEmbeddingSelector creates a selector that ranks tools with embedder; a nil
+// config uses DefaultSelectionConfig
+func NewEmbeddingSelector(embedder Embedder, config *SelectionConfig) *EmbeddingSelector {
+	return &EmbeddingSelector{embedder: embedder, config: selectionConfig(config), vectors: make(map[string][]float32)}
+}
+
+// SelectTools returns the MaxTools tools closest to request
+func (s *EmbeddingSelector) SelectTools(ctx context.Context, request string, tools []ToolSchema) ([]ToolSchema, error) {
+	candidates, needed := s.config.candidates(tools)
+	if !needed {
+		return tools, nil
+	}
+
+	texts := []string{request}
+	s.mu.Lock()
+	for _, tool := range candidates {
+		if _, ok := s.vectors[toolText(tool)]; !ok {
+			texts = append(texts, toolText(tool))
+		}
+	}
+	s.mu.Unlock()
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed request: %w", err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+
+	s.mu.Lock()
+	for i, text := range texts[1:] {
+		s.vectors[text] = vectors[i+1]
+	}
+	scores := make([]float64, len(candidates))
+	for i, tool := range candidates {
+		scores[i] = cosine(vectors[0], s.vectors[toolText(tool)])
+	}
+	s.mu.Unlock()
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	chosen := make(map[string]bool, s.config.MaxTools)
+	for _, i := range order[:s.config.MaxTools] {
+		chosen[candidates[i].Name] = true
+	}
+	return s.config.selected(tools, chosen), nil
+}
+
+// toolText is what a tool is embedded as
+func toolText(tool ToolSchema) string {
+	return tool.Name + ": " + tool.Description
+}
+
+// cosine returns the cosine similarity of two vectors, 0 if either is zero
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// LLMSelector asks a cheap model which tools could help with the request,
+// showing it each tool's name and the first sentence of its description
+type LLMSelector struct {
+	provider llm.LLMProvider
+	config   *SelectionConfig
+}
+
+// NewLLMSelector creates a selector that asks provider; a nil config uses
+// DefaultSelectionConfig
+func NewLLMSelector(provider llm.LLMProvider, config *SelectionConfig) *LLMSelector {
+	return &LLMSelector{provider: provider, config: selectionConfig(config)}
+}
+
+// SelectTools returns up to MaxTools tools the model picked for request.
+// Names the model made up are ignored.
+func (s *LLMSelector) SelectTools(ctx context.Context, request string, tools []ToolSchema) ([]ToolSchema, error) {
+	candidates, needed := s.config.candidates(tools)
+	if !needed {
+		return tools, nil
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Pick at most %d of the tools below that could help with the request. ", s.config.MaxTools)
+	prompt.WriteString("Reply with only a JSON array of the tool names, most useful first, or [] if none helps.\n\nTools:\n")
+	for _, tool := range candidates {
+		fmt.Fprintf(&prompt, "- %s: %s\n", tool.Name, firstSentence(tool.Description, 150))
+	}
+	fmt.Fprintf(&prompt, "\nRequest: %s", request)
+
+	response, err := s.provider.CallLLM(ctx, []llm.Message{{Role: llm.RoleUser, Content: prompt.String()}})
+	if err != nil {
+		return nil, err
+	}
+	names, err := parseToolNames(response.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(candidates))
+	for _, tool := range candidates {
+		known[tool.Name] = true
+	}
+	chosen := make(map[string]bool, s.config.MaxTools)
+	for _, name := range names {
+		if known[name] && len(chosen) < s.config.MaxTools {
+			chosen[name] = true
+		}
+	}
+	return s.config.selected(tools, chosen), nil
+}
+
+// parseToolNames reads the JSON array of names in a response, which may be
+// wrapped in text or a code block
+func parseToolNames(content string) ([]string, error) {
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no tool names in response %q", firstSentence(content, 100))
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &names); err != nil {
+		return nil, fmt.Errorf("invalid tool names in response: %w", err)
+	}
+	return names, nil
+}
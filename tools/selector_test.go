@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// topicEmbedder embeds texts by the topics they mention
+type topicEmbedder struct {
+	calls [][]string
+}
+
+func (e *topicEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, texts)
+	topics := []string{"weather", "mail", "calendar", "files"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(topics))
+		for j, topic := range topics {
+			if strings.Contains(strings.ToLower(text), topic) {
+				vectors[i][j] = 1
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func selectionTools() []ToolSchema {
+	return []ToolSchema{
+		{Name: "forecast", Description: "Weather forecast for a city."},
+		{Name: "send_mail", Description: "Send a mail message."},
+		{Name: "read_mail", Description: "Read mail from the inbox."},
+		{Name: "events", Description: "List calendar events."},
+		{Name: ReadArtifactTool, Description: "Read large results."},
+	}
+}
+
+func names(tools []ToolSchema) string {
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestEmbeddingSelector(t *testing.T) {
+	embedder := &topicEmbedder{}
+	selector := NewEmbeddingSelector(embedder, &SelectionConfig{MaxTools: 2, Always: []string{ReadArtifactTool}})
+
+	selected, err := selector.SelectTools(context.Background(), "Did I get mail from Ana?", selectionTools())
+	if err != nil || names(selected) != "send_mail,read_mail,read_artifact" {
+		t.Errorf("SelectTools() = %s, %v", names(selected), err)
+	}
+	selected, _ = selector.SelectTools(context.Background(), "Weather in Oslo?", selectionTools())
+	if !strings.HasPrefix(names(selected), "forecast,") || len(selected) != 3 {
+		t.Errorf("SelectTools() = %s", names(selected))
+	}
+	// Tools are embedded once
+	if len(embedder.calls) != 2 || len(embedder.calls[1]) != 1 {
+		t.Errorf("embed calls = %v", embedder.calls)
+	}
+
+	// Few tools need no selection
+	if selected, _ := selector.SelectTools(context.Background(), "mail", selectionTools()[3:]); len(selected) != 2 || len(embedder.calls) != 2 {
+		t.Errorf("SelectTools() of few tools = %s", names(selected))
+	}
+}
+
+func TestLLMSelector(t *testing.T) {
+	provider := llm.NewMockProvider("cheap")
+	selector := NewLLMSelector(provider, &SelectionConfig{MaxTools: 2})
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{"names", `["events", "send_mail"]`, "send_mail,events", false},
+		{"code block and made-up names", "```json\n[\"read_mail\", \"delete_mail\"]\n```", "read_mail", false},
+		{"capped", `["forecast", "events", "read_mail"]`, "forecast,events", false},
+		{"none", `[]`, "", false},
+		{"no array", "I would use the mail tools.", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.SetResponsePattern(map[string]string{"Plan my day": tt.response})
+			selected, err := selector.SelectTools(context.Background(), "Plan my day", selectionTools())
+			if (err != nil) != tt.wantErr || names(selected) != tt.want {
+				t.Errorf("SelectTools() = %s, %v", names(selected), err)
+			}
+		})
+	}
+}
+
+// failingSelector fails every selection
+type failingSelector struct{}
+
+func (failingSelector) SelectTools(context.Context, string, []ToolSchema) ([]ToolSchema, error) {
+	return nil, errors.New("selector down")
+}
+
+func TestToolManager_SelectTools(t *testing.T) {
+	manager := NewToolManager()
+	for _, tool := range selectionTools() {
+		manager.AddLocalToolLegacy(LocalTool{Name: tool.Name, Description: tool.Description, Handler: ToolHandler(func(context.Context, map[string]interface{}) (string, error) {
+			return "", nil
+		})})
+	}
+	manager.SetToolOverrides(ToolOverrides{"events": {Description: "List calendar meetings."}})
+
+	selected, err := manager.SelectTools(context.Background(), NewEmbeddingSelector(&topicEmbedder{}, &SelectionConfig{MaxTools: 1}), "calendar for Monday", "")
+	if err != nil || names(selected) != "events" || selected[0].Description != "List calendar meetings." {
+		t.Errorf("SelectTools() = %+v, %v", selected, err)
+	}
+	if selected, err := manager.SelectTools(context.Background(), failingSelector{}, "anything", ""); err == nil || len(selected) != 5 {
+		t.Errorf("failed SelectTools() = %s, %v", names(selected), err)
+	}
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/alt-coder/pocketflow-go/artifacts"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ReadArtifactTool is the name of the built-in tool SetSpillover adds for
+// reading spilled results
+const ReadArtifactTool = "read_artifact"
+
+// SpilloverConfig keeps large tool results out of the conversation
+type SpilloverConfig struct {
+	Store       artifacts.Store // Where larger results are written
+	MaxSize     int             // Results longer than this many characters are spilled; 0 disables spillover
+	PreviewSize int             // Characters of a spilled result kept in the conversation; 0 uses a quarter of MaxSize
+}
+
+// SetSpillover writes tool results longer than config.MaxSize to config.Store
+// and replaces them with a reference and a preview. It adds the
+// ReadArtifactTool tool, so the model can read the rest on demand. A zero
+// MaxSize turns spillover off again.
+func (tm *ToolManager) SetSpillover(config SpilloverConfig) error {
+	if config.MaxSize > 0 && config.Store == nil {
+		return fmt.Errorf("spillover needs an artifact store")
+	}
+	if config.PreviewSize <= 0 || config.PreviewSize > config.MaxSize {
+		config.PreviewSize = config.MaxSize / 4
+	}
+
+	tm.mu.Lock()
+	tm.spillover = config
+	tm.mu.Unlock()
+	if config.MaxSize <= 0 {
+		if tm.HasTool(ReadArtifactTool) {
+			return tm.RemoveLocalTool(ReadArtifactTool)
+		}
+		return nil
+	}
+	return tm.AddLocalToolLegacy(LocalTool{
+		Name:        ReadArtifactTool,
+		Description: "Read part of a tool result that was too large for the conversation, by its artifact ID.",
+		Parameters: map[string]Parameter{
+			"id":     {Type: "string", Description: "Artifact ID from the tool result", Required: true},
+			"offset": {Type: "number", Description: "Character to start reading at, 0 for the start"},
+			"length": {Type: "number", Description: fmt.Sprintf("Characters to read, at most %d", config.MaxSize)},
+		},
+		Handler: ToolHandler(func(ctx context.Context, args map[string]interface{}) (string, error) {
+			return readArtifact(ctx, config, args)
+		}),
+	})
+}
+
+// spill replaces a result longer than the spillover limit with a reference to
+// the artifact it is written to and a preview. When the artifact cannot be
+// written, the result is cut to the limit instead.
+func (tm *ToolManager) spill(ctx context.Context, toolCall llm.ToolCalls, result llm.ToolResults) llm.ToolResults {
+	tm.mu.RLock()
+	config := tm.spillover
+	tm.mu.RUnlock()
+	if config.MaxSize <= 0 || result.IsError || toolCall.ToolName == ReadArtifactTool {
+		return result
+	}
+	size := utf8.RuneCountInString(result.Content)
+	if size <= config.MaxSize {
+		return result
+	}
+
+	artifact, err := config.Store.Put(ctx, toolCall.ToolName+" result", "text/plain", []byte(result.Content))
+	if err != nil {
+		result.Content = fmt.Sprintf("%s\n[Result cut at %d of %d characters: %v]", prefix(result.Content, config.MaxSize), config.MaxSize, size, err)
+		return result
+	}
+	result.Content = fmt.Sprintf("[The result has %d characters and was stored as artifact %s. Call %s with this id, an offset and a length to read more of it.]\nPreview:\n%s",
+		size, artifact.ID, ReadArtifactTool, prefix(result.Content, config.PreviewSize))
+	return result
+}
+
+// SetMaxResultSize sets a hard limit on the bytes of a tool result, media
+// included, checked after spillover. Larger results are replaced with an
+// error result of code llm.ToolErrorTooLarge, so a runaway tool cannot blow
+// up the prompt. Zero removes the limit.
+func (tm *ToolManager) SetMaxResultSize(bytes int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.maxResultSize = max(bytes, 0)
+}
+
+// limitResult replaces a result over the size limit with an error result
+func (tm *ToolManager) limitResult(toolCall llm.ToolCalls, result llm.ToolResults) llm.ToolResults {
+	tm.mu.RLock()
+	limit := tm.maxResultSize
+	tm.mu.RUnlock()
+	size := len(result.Content) + len(result.Media)
+	if limit <= 0 || size <= limit {
+		return result
+	}
+	err := &llm.SizeLimitError{What: "tool result", Size: size, Limit: limit}
+	return llm.ErrorResult(toolCall.Id, llm.ToolErrorTooLarge, err.Error())
+}
+
+// readArtifact implements ReadArtifactTool
+func readArtifact(ctx context.Context, config SpilloverConfig, args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	data, _, err := config.Store.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	content := []rune(string(data))
+	offset, length := intArg(args["offset"], 0), intArg(args["length"], config.MaxSize)
+	offset = min(max(offset, 0), len(content))
+	if length <= 0 || length > config.MaxSize {
+		length = config.MaxSize
+	}
+	end := min(offset+length, len(content))
+	if end < len(content) {
+		return fmt.Sprintf("%s\n[Characters %d to %d of %d]", string(content[offset:end]), offset, end, len(content)), nil
+	}
+	return string(content[offset:end]), nil
+}
+
+// intArg returns a numeric tool argument, which arrives as float64 from JSON
+func intArg(value interface{}, fallback int) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return fallback
+}
+
+// prefix returns the first n characters of s
+func prefix(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
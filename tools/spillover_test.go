@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/artifacts"
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestToolManager_Spillover(t *testing.T) {
+	ctx := context.Background()
+	manager := NewToolManager()
+	output := strings.Repeat("0123456789", 100)
+	manager.AddLocalToolLegacy(LocalTool{Name: "dump", Handler: ToolHandler(func(context.Context, map[string]interface{}) (string, error) {
+		return output, nil
+	})})
+	if err := manager.SetSpillover(SpilloverConfig{MaxSize: 100}); err == nil {
+		t.Error("expected an error without a store")
+	}
+	if err := manager.SetSpillover(SpilloverConfig{Store: artifacts.NewInMemoryStore(), MaxSize: 100, PreviewSize: 20}); err != nil {
+		t.Fatalf("SetSpillover failed: %v", err)
+	}
+
+	result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{Id: "1", ToolName: "dump"})
+	match := regexp.MustCompile(`artifact (\w+)`).FindStringSubmatch(result.Content)
+	if match == nil || !strings.HasSuffix(result.Content, "\n"+output[:20]) || result.Id != "1" {
+		t.Fatalf("spilled result = %q", result.Content)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"start", map[string]interface{}{"id": match[1], "length": 10.0}, "0123456789\n[Characters 0 to 10 of 1000]"},
+		{"end", map[string]interface{}{"id": match[1], "offset": 995.0}, "56789"},
+		{"length capped", map[string]interface{}{"id": match[1], "length": 5000.0}, output[:100] + "\n[Characters 0 to 100 of 1000]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{ToolName: ReadArtifactTool, ToolArgs: tt.args})
+			if result.IsError || result.Content != tt.want {
+				t.Errorf("%s = %q (%s), want %q", ReadArtifactTool, result.Content, result.Error, tt.want)
+			}
+		})
+	}
+	if result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{ToolName: ReadArtifactTool, ToolArgs: map[string]interface{}{"id": "missing"}}); !result.IsError {
+		t.Errorf("reading a missing artifact = %+v", result)
+	}
+
+	manager.SetSpillover(SpilloverConfig{})
+	if result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{ToolName: "dump"}); result.Content != output || manager.HasTool(ReadArtifactTool) {
+		t.Error("spillover still active after turning it off")
+	}
+}
+
+func TestToolManager_MaxResultSize(t *testing.T) {
+	ctx := context.Background()
+	manager := NewToolManager()
+	output := strings.Repeat("0123456789", 100)
+	manager.AddLocalToolLegacy(LocalTool{Name: "dump", Handler: ToolHandler(func(context.Context, map[string]interface{}) (string, error) {
+		return output, nil
+	})})
+	manager.SetMaxResultSize(500)
+
+	result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{Id: "1", ToolName: "dump"})
+	if !result.IsError || result.Code != llm.ToolErrorTooLarge || result.Content != "" || result.Id != "1" {
+		t.Errorf("oversized result = %+v", result)
+	}
+
+	// Spilled results are checked after spillover
+	manager.SetSpillover(SpilloverConfig{Store: artifacts.NewInMemoryStore(), MaxSize: 100})
+	if result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{ToolName: "dump"}); result.IsError {
+		t.Errorf("spilled result = %+v", result)
+	}
+
+	manager.SetSpillover(SpilloverConfig{})
+	manager.SetMaxResultSize(0)
+	if result, _ := manager.ExecuteTool(ctx, llm.ToolCalls{ToolName: "dump"}); result.Content != output {
+		t.Error("result limited after removing the limit")
+	}
+}
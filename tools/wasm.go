@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+// ErrCapabilityDenied is returned by a host function the tool has no capability for
+var ErrCapabilityDenied = errors.New("capability denied")
+
+// Capabilities lists what a WASM tool may do through its host functions.
+// Everything not listed is denied: a module has no access to files, the
+// network, the environment or the clock other than through these functions.
+type Capabilities struct {
+	ReadPaths      []string          // Files and directories the tool may read
+	HTTPHosts      []string          // Hosts the tool may GET from over HTTPS, e.g. "api.github.com"
+	Env            map[string]string // Variables the tool can read; the process environment is never visible
+	MaxMemoryPages uint32            // Memory limit in 64 KiB pages; default 256 (16 MiB)
+	MaxOutputBytes int               // Limit of a result or a host function reply; default 1 MiB
+	Timeout        time.Duration     // Limit of a call; default 10s
+}
+
+// DefaultCapabilities allows nothing but computation, within the default limits
+func DefaultCapabilities() Capabilities {
+	return Capabilities{MaxMemoryPages: 256, MaxOutputBytes: 1 << 20, Timeout: 10 * time.Second}
+}
+
+// HostFunctions are the functions a WASM runtime links into tool modules, each
+// checked against the tool's capabilities
+type HostFunctions struct {
+	capabilities Capabilities
+	client       *http.Client
+	logf         func(format string, args ...any)
+}
+
+// NewHostFunctions returns the host functions of a tool; missing limits are
+// taken from DefaultCapabilities
+func NewHostFunctions(capabilities Capabilities) *HostFunctions {
+	defaults := DefaultCapabilities()
+	if capabilities.MaxMemoryPages == 0 {
+		capabilities.MaxMemoryPages = defaults.MaxMemoryPages
+	}
+	if capabilities.MaxOutputBytes <= 0 {
+		capabilities.MaxOutputBytes = defaults.MaxOutputBytes
+	}
+	if capabilities.Timeout <= 0 {
+		capabilities.Timeout = defaults.Timeout
+	}
+	h := &HostFunctions{capabilities: capabilities, logf: func(string, ...any) {}}
+	h.client = &http.Client{
+		Timeout: capabilities.Timeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			// A redirect must not lead to a host the tool may not reach
+			if err := h.checkURL(request.URL); err != nil {
+				return err
+			}
+			if len(via) >= 5 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return nil
+		},
+	}
+	return h
+}
+
+// Capabilities returns the capabilities with their limits filled in
+func (h *HostFunctions) Capabilities() Capabilities {
+	return h.capabilities
+}
+
+// SetLogger sets where Log writes; messages are dropped by default
+func (h *HostFunctions) SetLogger(logf func(format string, args ...any)) {
+	h.logf = logf
+}
+
+// Log records a message from the tool
+func (h *HostFunctions) Log(message string) {
+	h.logf("wasm tool: %s", message)
+}
+
+// Env returns a variable given to the tool
+func (h *HostFunctions) Env(name string) (string, error) {
+	value, ok := h.capabilities.Env[name]
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %s", ErrCapabilityDenied, name)
+	}
+	return value, nil
+}
+
+// ReadFile reads a file under one of the allowed paths. Symbolic links are
+// resolved first, so a link cannot lead out of an allowed directory.
+func (h *HostFunctions) ReadFile(path string) ([]byte, error) {
+	resolved, err := filepath.Abs(path)
+	if err == nil {
+		resolved, err = filepath.EvalSymlinks(resolved)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: read %s", ErrCapabilityDenied, path)
+	}
+	allowed := false
+	for _, root := range h.capabilities.ReadPaths {
+		root, err := filepath.Abs(root)
+		if err == nil {
+			root, err = filepath.EvalSymlinks(root)
+		}
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: read %s", ErrCapabilityDenied, path)
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return h.readLimited(file)
+}
+
+// HTTPGet fetches a URL from one of the allowed hosts over HTTPS
+func (h *HostFunctions) HTTPGet(ctx context.Context, rawURL string) ([]byte, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkURL(target); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := h.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", target.Host, response.Status)
+	}
+	return h.readLimited(response.Body)
+}
+
+// checkURL denies URLs that are not HTTPS or not on an allowed host
+func (h *HostFunctions) checkURL(target *url.URL) error {
+	if target.Scheme == "https" {
+		for _, host := range h.capabilities.HTTPHosts {
+			if strings.EqualFold(target.Hostname(), host) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: fetch %s://%s", ErrCapabilityDenied, target.Scheme, target.Host)
+}
+
+// readLimited reads at most MaxOutputBytes
+func (h *HostFunctions) readLimited(reader io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, int64(h.capabilities.MaxOutputBytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > h.capabilities.MaxOutputBytes {
+		return nil, fmt.Errorf("reply exceeds %d bytes", h.capabilities.MaxOutputBytes)
+	}
+	return data, nil
+}
+
+// WASMRuntime compiles tool modules. The wazero runtime in tools/wasmruntime
+// implements it; the tools package itself depends on no WASM engine.
+type WASMRuntime interface {
+	// Load compiles a module and links it to the host functions
+	Load(ctx context.Context, module []byte, host *HostFunctions) (WASMModule, error)
+}
+
+// WASMModule is a compiled tool module. Call runs the module's "run" export
+// with the JSON arguments of a tool call and returns its JSON reply, a
+// wasmReply. Runtimes should give every call fresh memory, so that calls
+// cannot see each other's data.
+type WASMModule interface {
+	Call(ctx context.Context, input []byte) ([]byte, error)
+	Close(ctx context.Context) error
+}
+
+// WASMToolConfig describes a tool implemented by a WASM module
+type WASMToolConfig struct {
+	Name         string
+	Description  string
+	Parameters   map[string]Parameter
+	Module       []byte // WebAssembly binary
+	Runtime      WASMRuntime
+	Capabilities Capabilities
+}
+
+// wasmReply is what a tool module returns from "run"
+type wasmReply struct {
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AddWASMTool compiles a WASM module and adds it as a local tool. The module
+// runs in the runtime's sandbox and reaches the outside only through the host
+// functions its capabilities allow.
+func (tm *ToolManager) AddWASMTool(ctx context.Context, config WASMToolConfig) error {
+	if config.Name == "" {
+		return fmt.Errorf("tool name cannot be empty")
+	}
+	if config.Runtime == nil {
+		return fmt.Errorf("WASM runtime cannot be nil")
+	}
+	host := NewHostFunctions(config.Capabilities)
+	module, err := config.Runtime.Load(ctx, config.Module, host)
+	if err != nil {
+		return fmt.Errorf("failed to load WASM tool %s: %w", config.Name, err)
+	}
+	limits := host.Capabilities()
+
+	var handler ToolHandler = func(ctx context.Context, args map[string]interface{}) (string, error) {
+		input, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode arguments: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+		output, err := module.Call(ctx, input)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return "", llm.NewToolError(llm.ToolErrorTimeout, fmt.Sprintf("tool did not finish within %s", limits.Timeout))
+			}
+			return "", err
+		}
+		if len(output) > limits.MaxOutputBytes {
+			return "", fmt.Errorf("tool result exceeds %d bytes", limits.MaxOutputBytes)
+		}
+		var reply wasmReply
+		if err := json.Unmarshal(output, &reply); err != nil {
+			return "", fmt.Errorf("invalid tool reply: %w", err)
+		}
+		if reply.Error != "" {
+			return "", errors.New(reply.Error)
+		}
+		return reply.Content, nil
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if previous, ok := tm.wasmModules[config.Name]; ok {
+		previous.Close(ctx)
+	}
+	tm.localTools[config.Name] = LocalTool{
+		Name:        config.Name,
+		Description: config.Description,
+		Parameters:  config.Parameters,
+		Handler:     handler,
+	}
+	tm.wasmModules[config.Name] = module
+	return nil
+}
+
+// closeWASMModule releases the module of a removed tool; tm.mu must be held
+func (tm *ToolManager) closeWASMModule(name string) error {
+	module, ok := tm.wasmModules[name]
+	if !ok {
+		return nil
+	}
+	delete(tm.wasmModules, name)
+	return module.Close(context.Background())
+}
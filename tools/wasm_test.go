@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+)
+
+func TestHostFunctions_ReadFile(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	os.Mkdir(allowed, 0o755)
+	os.WriteFile(filepath.Join(allowed, "notes.txt"), []byte("notes"), 0o600)
+	os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o600)
+	os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(allowed, "link.txt"))
+	os.WriteFile(filepath.Join(allowed, "large.txt"), make([]byte, 100), 0o600)
+
+	host := NewHostFunctions(Capabilities{ReadPaths: []string{allowed}, MaxOutputBytes: 50})
+	tests := []struct {
+		path    string
+		want    string
+		wantErr string
+	}{
+		{filepath.Join(allowed, "notes.txt"), "notes", ""},
+		{filepath.Join(dir, "secret.txt"), "", "capability denied"},
+		{filepath.Join(allowed, "..", "secret.txt"), "", "capability denied"},
+		{filepath.Join(allowed, "link.txt"), "", "capability denied"},
+		{filepath.Join(allowed, "missing.txt"), "", "capability denied"},
+		{filepath.Join(allowed, "large.txt"), "", "exceeds 50 bytes"},
+	}
+	for _, tt := range tests {
+		got, err := host.ReadFile(tt.path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadFile(%s) error = %v, want %q", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("ReadFile(%s) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestHostFunctions_EnvAndHTTP(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "https://example.com/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("pong"))
+	}))
+	defer server.Close()
+
+	t.Setenv("WASM_TEST_SECRET", "process-secret")
+	host := NewHostFunctions(Capabilities{HTTPHosts: []string{"127.0.0.1"}, Env: map[string]string{"REGION": "eu"}})
+	host.client.Transport = server.Client().Transport
+
+	if value, err := host.Env("REGION"); err != nil || value != "eu" {
+		t.Errorf("Env(REGION) = %q, %v", value, err)
+	}
+	if _, err := host.Env("WASM_TEST_SECRET"); !errors.Is(err, ErrCapabilityDenied) {
+		t.Errorf("Env of a process variable error = %v, want ErrCapabilityDenied", err)
+	}
+
+	ctx := context.Background()
+	if data, err := host.HTTPGet(ctx, server.URL+"/ping"); err != nil || string(data) != "pong" {
+		t.Errorf("HTTPGet of an allowed host = %q, %v", data, err)
+	}
+	for _, url := range []string{
+		strings.Replace(server.URL, "https://", "http://", 1),
+		"https://example.com/",
+		server.URL + "/redirect",
+	} {
+		if _, err := host.HTTPGet(ctx, url); !errors.Is(err, ErrCapabilityDenied) {
+			t.Errorf("HTTPGet(%s) error = %v, want ErrCapabilityDenied", url, err)
+		}
+	}
+}
+
+// fakeRuntime stands in for a WASM engine: its modules run Go code against the
+// host functions they were linked to
+type fakeRuntime struct {
+	run func(ctx context.Context, host *HostFunctions, args map[string]any) wasmReply
+}
+
+type fakeModule struct {
+	runtime *fakeRuntime
+	host    *HostFunctions
+	closed  bool
+}
+
+func (r *fakeRuntime) Load(ctx context.Context, module []byte, host *HostFunctions) (WASMModule, error) {
+	if string(module) != "\x00asm" {
+		return nil, errors.New("not a WASM module")
+	}
+	return &fakeModule{runtime: r, host: host}, nil
+}
+
+func (m *fakeModule) Call(ctx context.Context, input []byte) ([]byte, error) {
+	var args map[string]any
+	if err := json.Unmarshal(input, &args); err != nil {
+		return nil, err
+	}
+	reply := m.runtime.run(ctx, m.host, args)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(reply)
+}
+
+func (m *fakeModule) Close(ctx context.Context) error {
+	m.closed = true
+	return nil
+}
+
+func TestToolManager_AddWASMTool(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "readme.md"), []byte("# Project"), 0o600)
+
+	runtime := &fakeRuntime{run: func(ctx context.Context, host *HostFunctions, args map[string]any) wasmReply {
+		switch args["mode"] {
+		case "slow":
+			<-ctx.Done()
+			return wasmReply{}
+		case "fail":
+			return wasmReply{Error: "bad input"}
+		}
+		data, err := host.ReadFile(args["path"].(string))
+		if err != nil {
+			return wasmReply{Error: err.Error()}
+		}
+		return wasmReply{Content: string(data)}
+	}}
+
+	tm := NewToolManager()
+	config := WASMToolConfig{
+		Name:        "read",
+		Description: "Reads a project file",
+		Parameters: map[string]Parameter{
+			"path": {Type: "string", Required: true},
+			"mode": {Type: "string"},
+		},
+		Module:       []byte("\x00asm"),
+		Runtime:      runtime,
+		Capabilities: Capabilities{ReadPaths: []string{dir}, Timeout: 50 * time.Millisecond},
+	}
+	if err := tm.AddWASMTool(context.Background(), config); err != nil {
+		t.Fatalf("AddWASMTool failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{"allowed file", map[string]interface{}{"path": filepath.Join(dir, "readme.md")}, "# Project", ""},
+		{"denied file", map[string]interface{}{"path": "/etc/passwd"}, "", "capability denied"},
+		{"tool error", map[string]interface{}{"path": "x", "mode": "fail"}, "", "bad input"},
+		{"timeout", map[string]interface{}{"path": "x", "mode": "slow"}, "", "did not finish within 50ms"},
+		{"missing parameter", map[string]interface{}{}, "", "required parameter 'path' is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tm.ExecuteTool(context.Background(), llm.ToolCalls{Id: "1", ToolName: "read", ToolArgs: tt.args})
+			if err != nil {
+				t.Fatalf("ExecuteTool failed: %v", err)
+			}
+			if tt.wantErr != "" {
+				if !result.IsError || !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("result = %+v, want error %q", result, tt.wantErr)
+				}
+				return
+			}
+			if result.IsError || result.Content != tt.want {
+				t.Errorf("result = %+v, want %q", result, tt.want)
+			}
+		})
+	}
+
+	module := tm.wasmModules["read"].(*fakeModule)
+	if err := tm.RemoveLocalTool("read"); err != nil || !module.closed {
+		t.Errorf("RemoveLocalTool() = %v, module closed %v", err, module.closed)
+	}
+
+	config.Module = []byte("not wasm")
+	if err := tm.AddWASMTool(context.Background(), config); err == nil {
+		t.Error("AddWASMTool accepted an invalid module")
+	}
+}
@@ -0,0 +1,206 @@
+// Package wasmruntime runs WASM tools with wazero, a WebAssembly runtime
+// written in Go. A module gets no WASI imports, so it has no files, network,
+// environment or clock of its own; it reaches the outside only through the
+// host functions of the "pocketflow" import module, which check the tool's
+// capabilities.
+package wasmruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// hostModuleName is the import module of the host functions
+const hostModuleName = "pocketflow"
+
+// Runtime loads tool modules into wazero; it implements tools.WASMRuntime
+type Runtime struct{}
+
+// New returns a wazero runtime for WASM tools
+func New() *Runtime {
+	return &Runtime{}
+}
+
+// module is a compiled tool module with a wazero runtime of its own, so its
+// memory limit and host functions apply to it alone
+type module struct {
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	maxOutput int
+}
+
+// callState holds the last host function error of a call
+type callState struct {
+	lastError string
+}
+
+type callStateKey struct{}
+
+// Load compiles a module and checks that it exports memory, alloc and run
+func (r *Runtime) Load(ctx context.Context, wasm []byte, host *tools.HostFunctions) (tools.WASMModule, error) {
+	capabilities := host.Capabilities()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(capabilities.MaxMemoryPages).
+		WithCloseOnContextDone(true))
+
+	if err := linkHostFunctions(ctx, runtime, host); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile module: %w", err)
+	}
+	exports := compiled.ExportedFunctions()
+	for _, name := range []string{"alloc", "run"} {
+		if _, ok := exports[name]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("module does not export %s", name)
+		}
+	}
+	if len(compiled.ExportedMemories()) == 0 {
+		runtime.Close(ctx)
+		return nil, errors.New("module does not export its memory")
+	}
+	return &module{runtime: runtime, compiled: compiled, maxOutput: capabilities.MaxOutputBytes}, nil
+}
+
+// Call runs the module in a new instance, so no memory is kept between calls
+func (m *module) Call(ctx context.Context, input []byte) ([]byte, error) {
+	ctx = context.WithValue(ctx, callStateKey{}, &callState{})
+	// Anonymous instances, so that concurrent calls can instantiate the module
+	instance, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	defer instance.Close(ctx)
+
+	packed, err := writeBytes(ctx, instance, input)
+	if err != nil {
+		return nil, err
+	}
+	results, err := instance.ExportedFunction("run").Call(ctx, packed>>32, packed&0xffffffff)
+	if err != nil {
+		return nil, err
+	}
+	return readBytes(instance, results[0], m.maxOutput)
+}
+
+// Close releases the compiled module and its runtime
+func (m *module) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
+
+// linkHostFunctions instantiates the "pocketflow" import module. Strings are
+// passed as a pointer and a length into the memory of the tool; replies are
+// written to memory the host allocates with the tool's alloc export and are
+// returned packed as pointer<<32 | length, or 0 after an error, whose message
+// last_error returns.
+func linkHostFunctions(ctx context.Context, runtime wazero.Runtime, host *tools.HostFunctions) error {
+	reply := func(ctx context.Context, m api.Module, data []byte, err error) uint64 {
+		if err != nil {
+			ctx.Value(callStateKey{}).(*callState).lastError = err.Error()
+			return 0
+		}
+		packed, err := writeBytes(ctx, m, data)
+		if err != nil {
+			ctx.Value(callStateKey{}).(*callState).lastError = err.Error()
+			return 0
+		}
+		return packed
+	}
+
+	_, err := runtime.NewHostModuleBuilder(hostModuleName).
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, ptr, size uint32) {
+			if message, ok := m.Memory().Read(ptr, size); ok {
+				host.Log(string(message))
+			}
+		}).Export("log").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, ptr, size uint32) uint64 {
+			name, err := readString(m, ptr, size)
+			if err != nil {
+				return reply(ctx, m, nil, err)
+			}
+			value, err := host.Env(name)
+			return reply(ctx, m, []byte(value), err)
+		}).Export("env").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, ptr, size uint32) uint64 {
+			path, err := readString(m, ptr, size)
+			if err != nil {
+				return reply(ctx, m, nil, err)
+			}
+			data, err := host.ReadFile(path)
+			return reply(ctx, m, data, err)
+		}).Export("read_file").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, ptr, size uint32) uint64 {
+			url, err := readString(m, ptr, size)
+			if err != nil {
+				return reply(ctx, m, nil, err)
+			}
+			data, err := host.HTTPGet(ctx, url)
+			return reply(ctx, m, data, err)
+		}).Export("http_get").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module) uint64 {
+			state := ctx.Value(callStateKey{}).(*callState)
+			if state.lastError == "" {
+				return 0
+			}
+			packed, err := writeBytes(ctx, m, []byte(state.lastError))
+			if err != nil {
+				return 0
+			}
+			return packed
+		}).Export("last_error").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to link host functions: %w", err)
+	}
+	return nil
+}
+
+// writeBytes copies data into memory allocated with the module's alloc export
+func writeBytes(ctx context.Context, m api.Module, data []byte) (uint64, error) {
+	results, err := m.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("alloc failed: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !m.Memory().Write(ptr, data) {
+		return 0, fmt.Errorf("alloc returned memory out of range")
+	}
+	return uint64(ptr)<<32 | uint64(len(data)), nil
+}
+
+// readBytes copies a packed pointer and length out of the module's memory,
+// failing without copying when the length exceeds limit
+func readBytes(m api.Module, packed uint64, limit int) ([]byte, error) {
+	if size := uint32(packed); uint64(size) > uint64(limit) {
+		return nil, fmt.Errorf("tool result of %d bytes exceeds %d bytes", size, limit)
+	}
+	data, ok := m.Memory().Read(uint32(packed>>32), uint32(packed))
+	if !ok {
+		return nil, fmt.Errorf("module returned memory out of range")
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// readString reads a string argument of a host function
+func readString(m api.Module, ptr, size uint32) (string, error) {
+	data, ok := m.Memory().Read(ptr, size)
+	if !ok {
+		return "", fmt.Errorf("argument out of memory range")
+	}
+	return string(data), nil
+}
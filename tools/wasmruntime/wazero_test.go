@@ -0,0 +1,202 @@
+package wasmruntime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// testModule describes a tool module assembled by wasmBinary. Its run export
+// logs the reply through the host and returns it, claiming length bytes.
+type testModule struct {
+	reply     string
+	length    uint32 // Length run returns; 0 for the length of reply
+	noRun     bool   // Leave out the run export
+	memoryMin uint32 // Initial memory in pages
+}
+
+// wasmBinary assembles the WebAssembly binary of m
+func wasmBinary(m testModule) []byte {
+	length := m.length
+	if length == 0 {
+		length = uint32(len(m.reply))
+	}
+	memoryMin := m.memoryMin
+	if memoryMin == 0 {
+		memoryMin = 1
+	}
+
+	i32, i64 := byte(0x7f), byte(0x7e)
+	types := vector(
+		append([]byte{0x60}, append(vector([]byte{i32}), vector([]byte{i32})...)...),              // alloc
+		append([]byte{0x60}, append(vector([]byte{i32}, []byte{i32}), vector([]byte{i64})...)...), // run
+		append([]byte{0x60}, append(vector([]byte{i32}, []byte{i32}), vector()...)...),            // log
+	)
+	imports := vector(append(append(name(hostModuleName), name("log")...), 0x00, 2))
+	functions := vector([]byte{0}, []byte{1})
+	memories := vector(append([]byte{0x00}, uleb(uint64(memoryMin))...))
+	exports := [][]byte{
+		append(name("memory"), 0x02, 0),
+		append(name("alloc"), 0x00, 1),
+	}
+	if !m.noRun {
+		exports = append(exports, append(name("run"), 0x00, 2))
+	}
+
+	// alloc returns memory past the reply; run logs the reply and returns it
+	alloc := append([]byte{0x41}, sleb(1<<15)...)
+	run := []byte{0x41, 0x00}
+	run = append(run, 0x41)
+	run = append(run, sleb(int64(len(m.reply)))...)
+	run = append(run, 0x10, 0x00, 0x42)
+	run = append(run, sleb(int64(length))...)
+	code := vector(body(alloc), body(run))
+	data := vector(append([]byte{0x00, 0x41, 0x00, 0x0b}, vector(splitBytes(m.reply)...)...))
+
+	binary := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+	binary = append(binary, section(1, types)...)
+	binary = append(binary, section(2, imports)...)
+	binary = append(binary, section(3, functions)...)
+	binary = append(binary, section(5, memories)...)
+	binary = append(binary, section(7, vector(exports...))...)
+	binary = append(binary, section(10, code)...)
+	binary = append(binary, section(11, data)...)
+	return binary
+}
+
+// splitBytes returns each byte of s as an element of a vector
+func splitBytes(s string) [][]byte {
+	elements := make([][]byte, len(s))
+	for i := range s {
+		elements[i] = []byte{s[i]}
+	}
+	return elements
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(content)))...), content...)
+}
+
+func vector(elements ...[]byte) []byte {
+	encoded := uleb(uint64(len(elements)))
+	for _, element := range elements {
+		encoded = append(encoded, element...)
+	}
+	return encoded
+}
+
+func name(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+// body encodes a function without locals ending after instructions
+func body(instructions []byte) []byte {
+	content := append(append([]byte{0x00}, instructions...), 0x0b)
+	return append(uleb(uint64(len(content))), content...)
+}
+
+func uleb(v uint64) []byte {
+	var encoded []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(encoded, b)
+		}
+		encoded = append(encoded, b|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var encoded []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(encoded, b)
+		}
+		encoded = append(encoded, b|0x80)
+	}
+}
+
+func TestRuntime_Call(t *testing.T) {
+	ctx := context.Background()
+	reply := `{"content":"pong"}`
+	host := tools.NewHostFunctions(tools.Capabilities{})
+	var logged []string
+	host.SetLogger(func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+
+	module, err := New().Load(ctx, wasmBinary(testModule{reply: reply}), host)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer module.Close(ctx)
+	for i := 0; i < 2; i++ {
+		output, err := module.Call(ctx, []byte(`{"ping":true}`))
+		if err != nil || string(output) != reply {
+			t.Fatalf("Call() = %q, %v; want %q", output, err, reply)
+		}
+	}
+	if len(logged) != 2 || logged[0] != "wasm tool: "+reply {
+		t.Errorf("logged %v, want the reply once per call", logged)
+	}
+}
+
+func TestRuntime_OutputLimit(t *testing.T) {
+	ctx := context.Background()
+	// run claims 2 MiB of its 4 MiB memory, above the 1 MiB default limit
+	host := tools.NewHostFunctions(tools.Capabilities{})
+	module, err := New().Load(ctx, wasmBinary(testModule{reply: `{}`, length: 2 << 20, memoryMin: 64}), host)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer module.Close(ctx)
+	if _, err := module.Call(ctx, nil); err == nil || !strings.Contains(err.Error(), "exceeds 1048576 bytes") {
+		t.Errorf("Call() error = %v, want the output limit exceeded", err)
+	}
+
+	// Lengths past the end of memory are refused too
+	module, err = New().Load(ctx, wasmBinary(testModule{reply: `{}`, length: 1 << 17}), tools.NewHostFunctions(tools.Capabilities{MaxOutputBytes: 1 << 20}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer module.Close(ctx)
+	if _, err := module.Call(ctx, nil); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Call() error = %v, want memory out of range", err)
+	}
+}
+
+func TestRuntime_LoadChecksExports(t *testing.T) {
+	host := tools.NewHostFunctions(tools.Capabilities{})
+	if _, err := New().Load(context.Background(), wasmBinary(testModule{reply: `{}`, noRun: true}), host); err == nil || !strings.Contains(err.Error(), "does not export run") {
+		t.Errorf("Load() error = %v, want a missing run export", err)
+	}
+	if _, err := New().Load(context.Background(), []byte("not wasm"), host); err == nil {
+		t.Error("Load() succeeded for an invalid module")
+	}
+}
+
+func TestRuntime_WASMTool(t *testing.T) {
+	ctx := context.Background()
+	tm := tools.NewToolManager()
+	if err := tm.AddWASMTool(ctx, tools.WASMToolConfig{
+		Name:        "ping",
+		Description: "Answers pong",
+		Module:      wasmBinary(testModule{reply: `{"content":"pong"}`}),
+		Runtime:     New(),
+	}); err != nil {
+		t.Fatalf("AddWASMTool() error = %v", err)
+	}
+	defer tm.Shutdown(ctx)
+
+	result, err := tm.ExecuteTool(ctx, llm.ToolCalls{Id: "1", ToolName: "ping"})
+	if err != nil || result.IsError || result.Content != "pong" {
+		t.Errorf("ExecuteTool() = %+v, %v; want pong", result, err)
+	}
+}
@@ -0,0 +1,309 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// DefaultUserAgent identifies fetches to sites and their robots.txt
+const DefaultUserAgent = "pocketflow-go/1.0 (+https://github.com/alt-coder/pocketflow-go)"
+
+// ErrRobotsDisallowed is returned for URLs the site's robots.txt disallows
+var ErrRobotsDisallowed = errors.New("robots.txt disallows fetching this URL")
+
+// ErrPrivateAddress is returned for hosts that resolve to loopback, private
+// or link-local addresses while they are not allowed
+var ErrPrivateAddress = errors.New("refusing to fetch a private network address")
+
+// FetchConfig configures the fetch_url tool
+type FetchConfig struct {
+	MaxBytes     int64         `json:"max_bytes,omitempty"`     // Most bytes of a response read; defaults to 2 MiB
+	MaxChars     int           `json:"max_chars,omitempty"`     // Most characters of text returned per call; defaults to 20000
+	Timeout      core.Duration `json:"timeout,omitempty"`       // Limit of a fetch, including redirects; defaults to 20s
+	UserAgent    string        `json:"user_agent,omitempty"`    // Defaults to DefaultUserAgent; its first word is matched against robots.txt groups
+	IgnoreRobots bool          `json:"ignore_robots,omitempty"` // Fetch pages the site's robots.txt disallows
+
+	// AllowPrivateNetworks lets the model fetch loopback, private and
+	// link-local addresses, which are refused by default so that it cannot
+	// reach internal services. Proxies from the environment are only used
+	// when it is set.
+	AllowPrivateNetworks bool `json:"allow_private_networks,omitempty"`
+}
+
+// DefaultFetchConfig returns a default fetch configuration
+func DefaultFetchConfig() *FetchConfig {
+	return &FetchConfig{
+		MaxBytes:  2 << 20,
+		MaxChars:  20000,
+		Timeout:   core.Duration(20 * time.Second),
+		UserAgent: DefaultUserAgent,
+	}
+}
+
+// Page is the readable content of a fetched URL
+type Page struct {
+	URL         string // Final URL after redirects
+	Title       string
+	ContentType string
+	Text        string // Main text of HTML pages, or the body of other text responses
+	Truncated   bool   // Set when the response was larger than MaxBytes
+}
+
+// Fetcher downloads pages and extracts their readable text
+type Fetcher struct {
+	config       *FetchConfig
+	client       *http.Client
+	robotsClient *http.Client
+
+	mu     sync.Mutex
+	robots map[string]*robotsEntry // By scheme and host
+}
+
+// robotsEntry is the cached robots.txt of a site
+type robotsEntry struct {
+	rules   *robotsRules
+	fetched time.Time
+}
+
+// robotsTTL is how long a robots.txt is cached
+const robotsTTL = 24 * time.Hour
+
+// maxRedirects is the number of redirects a fetch follows
+const maxRedirects = 5
+
+// NewFetcher creates a fetcher; a nil config uses DefaultFetchConfig
+func NewFetcher(config *FetchConfig) *Fetcher {
+	defaults := DefaultFetchConfig()
+	if config == nil {
+		config = defaults
+	}
+	resolved := *config
+	if resolved.MaxBytes <= 0 {
+		resolved.MaxBytes = defaults.MaxBytes
+	}
+	if resolved.MaxChars <= 0 {
+		resolved.MaxChars = defaults.MaxChars
+	}
+	if resolved.Timeout <= 0 {
+		resolved.Timeout = defaults.Timeout
+	}
+	if resolved.UserAgent == "" {
+		resolved.UserAgent = defaults.UserAgent
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: resolved.Timeout.Std(),
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	if resolved.AllowPrivateNetworks {
+		transport.Proxy = http.ProxyFromEnvironment
+	} else {
+		// Checked on the resolved address, so a public name pointing to an
+		// internal address is refused too
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		}
+	}
+
+	f := &Fetcher{config: &resolved, robots: make(map[string]*robotsEntry)}
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   resolved.Timeout.Std(),
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.check(request.Context(), request.URL)
+		},
+	}
+	// robots.txt requests follow redirects without looking up robots.txt again
+	f.robotsClient = &http.Client{
+		Transport: transport,
+		Timeout:   resolved.Timeout.Std(),
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if request.URL.Scheme != "http" && request.URL.Scheme != "https" {
+				return fmt.Errorf("redirected to a %s URL", request.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// Fetch downloads a URL and extracts its readable text
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := f.check(ctx, target); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", f.config.UserAgent)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	response, err := f.client.Do(request)
+	if err != nil {
+		return nil, unwrapURLError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned %s", response.Request.URL, response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, f.config.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", response.Request.URL, err)
+	}
+	page := &Page{URL: response.Request.URL.String(), ContentType: response.Header.Get("Content-Type")}
+	if int64(len(body)) > f.config.MaxBytes {
+		body = body[:f.config.MaxBytes]
+		page.Truncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(page.ContentType)
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || (mediaType == "" && looksLikeHTML(body)):
+		page.Title, page.Text, err = ExtractReadable(body)
+		if err != nil {
+			return nil, err
+		}
+	case isTextType(mediaType) || (mediaType == "" && utf8.Valid(body)):
+		page.Text = strings.ToValidUTF8(string(body), "")
+	default:
+		return nil, fmt.Errorf("%s has content type %s, which is not text", page.URL, mediaType)
+	}
+	return page, nil
+}
+
+// check refuses URLs that are not http(s) or that robots.txt disallows
+func (f *Fetcher) check(ctx context.Context, target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("only http and https URLs can be fetched, not %q", target.String())
+	}
+	if target.Host == "" {
+		return fmt.Errorf("URL %q has no host", target.String())
+	}
+	if f.config.IgnoreRobots {
+		return nil
+	}
+	rules, err := f.robotsFor(ctx, target)
+	if err != nil {
+		return err
+	}
+	if !rules.allowed(target.RequestURI()) {
+		return fmt.Errorf("%w: %s", ErrRobotsDisallowed, target.String())
+	}
+	return nil
+}
+
+// robotsFor returns the robots.txt rules for the user agent at the site of
+// target, fetching them when they are not cached. A missing robots.txt
+// allows everything; an unreachable one disallows everything.
+func (f *Fetcher) robotsFor(ctx context.Context, target *url.URL) (*robotsRules, error) {
+	site := target.Scheme + "://" + target.Host
+	f.mu.Lock()
+	entry := f.robots[site]
+	f.mu.Unlock()
+	if entry != nil && time.Since(entry.fetched) < robotsTTL {
+		return entry.rules, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, site+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", f.config.UserAgent)
+	response, err := f.robotsClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt of %s: %w", target.Host, unwrapURLError(err))
+	}
+	defer response.Body.Close()
+
+	var rules *robotsRules
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode <= 299:
+		data, err := io.ReadAll(io.LimitReader(response.Body, 512<<10))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read robots.txt of %s: %w", target.Host, err)
+		}
+		rules = parseRobots(data, f.config.UserAgent)
+	case response.StatusCode >= 400 && response.StatusCode <= 499:
+		rules = &robotsRules{}
+	default:
+		return nil, fmt.Errorf("robots.txt of %s is unavailable: %s", target.Host, response.Status)
+	}
+
+	f.mu.Lock()
+	f.robots[site] = &robotsEntry{rules: rules, fetched: time.Now()}
+	f.mu.Unlock()
+	return rules, nil
+}
+
+// unwrapURLError drops the "Get <url>:" prefix the client adds to errors
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	// Carrier-grade NAT, 100.64.0.0/10
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xC0 == 64 {
+		return false
+	}
+	return true
+}
+
+// isTextType reports whether a media type is text the model can read
+func isTextType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" || mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// looksLikeHTML reports whether an untyped body starts like an HTML document
+func looksLikeHTML(body []byte) bool {
+	start := bytes.ToLower(bytes.TrimSpace(body[:min(len(body), 512)]))
+	return bytes.HasPrefix(start, []byte("<!doctype html")) || bytes.HasPrefix(start, []byte("<html"))
+}
@@ -0,0 +1,320 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractReadable returns the title and the main text of an HTML page,
+// leaving out navigation, sidebars, footers, scripts and forms. Like
+// readability tools, it scores the elements holding paragraphs and keeps the
+// best one with its similarly scored siblings; headings, list items and
+// preformatted blocks keep their structure in the text.
+func ExtractReadable(data []byte) (title, text string, err error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	title = strings.Join(strings.Fields(textContent(findElement(doc, "title"))), " ")
+	body := findElement(doc, "body")
+	if body == nil {
+		body = doc
+	}
+
+	writer := &textWriter{}
+	for _, node := range mainContent(body) {
+		writer.breakLine(2)
+		renderText(writer, node)
+	}
+	return title, writer.builder.String(), nil
+}
+
+// skippedElements never hold readable text
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "canvas": true,
+	"iframe": true, "form": true, "button": true, "input": true, "select": true, "textarea": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "head": true, "dialog": true,
+}
+
+var (
+	// unlikelyPattern matches classes and IDs of page furniture
+	unlikelyPattern = regexp.MustCompile(`(?i)comment|sidebar|footer|masthead|menu|navbar|breadcrumb|share|social|cookie|consent|banner|promo|related|advert|sponsor|popup|modal|subscribe|newsletter`)
+
+	// likelyPattern matches classes and IDs of article content, which wins over unlikelyPattern
+	likelyPattern = regexp.MustCompile(`(?i)article|content|main|body|post|entry|story|text`)
+)
+
+// skipped reports whether a node is left out of the text
+func skipped(node *html.Node) bool {
+	if node.Type == html.CommentNode {
+		return true
+	}
+	if node.Type != html.ElementNode {
+		return false
+	}
+	if skippedElements[node.Data] || attribute(node, "hidden") != "" || attribute(node, "aria-hidden") == "true" {
+		return true
+	}
+	switch node.Data {
+	case "body", "article", "main":
+		return false
+	}
+	names := attribute(node, "class") + " " + attribute(node, "id")
+	return unlikelyPattern.MatchString(names) && !likelyPattern.MatchString(names)
+}
+
+// mainContent returns the elements of the main content, in document order
+func mainContent(body *html.Node) []*html.Node {
+	scores := make(map[*html.Node]float64)
+	var score func(node *html.Node)
+	score = func(node *html.Node) {
+		if skipped(node) {
+			return
+		}
+		if node.Type == html.ElementNode && (node.Data == "p" || node.Data == "pre" || node.Data == "td" || node.Data == "blockquote") {
+			text := strings.Join(strings.Fields(textContent(node)), " ")
+			if len(text) >= 25 {
+				points := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+				if parent := node.Parent; parent != nil {
+					scores[parent] += points
+					if grandparent := parent.Parent; grandparent != nil {
+						scores[grandparent] += points / 2
+					}
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			score(child)
+		}
+	}
+	score(body)
+
+	var top *html.Node
+	best := 0.0
+	for node, points := range scores {
+		points *= 1 - linkDensity(node)
+		scores[node] = points
+		if points > best || (points == best && top != nil && precedes(node, top)) {
+			top, best = node, points
+		}
+	}
+	if top == nil {
+		for _, name := range []string{"article", "main"} {
+			if node := findElement(body, name); node != nil {
+				return []*html.Node{node}
+			}
+		}
+		return []*html.Node{body}
+	}
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	// Content split across sibling containers is kept together
+	threshold := max(10, best*0.2)
+	var content []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == top || scores[sibling] >= threshold {
+			content = append(content, sibling)
+		}
+	}
+	return content
+}
+
+// linkDensity is the share of a node's text inside links
+func linkDensity(node *html.Node) float64 {
+	total := len(strings.Join(strings.Fields(textContent(node)), " "))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "a" {
+			linked += len(strings.Join(strings.Fields(textContent(node)), " "))
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return float64(linked) / float64(total)
+}
+
+// paragraphElements are separated from the text around them by an empty
+// line, lineElements by a line break
+var (
+	paragraphElements = map[string]bool{
+		"p": true, "pre": true, "blockquote": true, "ul": true, "ol": true, "dl": true, "table": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "figure": true, "hr": true,
+	}
+	lineElements = map[string]bool{
+		"div": true, "br": true, "li": true, "tr": true, "dt": true, "dd": true, "figcaption": true,
+		"section": true, "article": true, "main": true,
+	}
+)
+
+// textWriter joins text nodes with the spaces and line breaks between them
+type textWriter struct {
+	builder strings.Builder
+	breaks  int  // Line breaks owed before the next text: 1 for a new line, 2 for an empty line
+	space   bool // A space is owed before the next text
+}
+
+// breakLine asks for a new line, or an empty line when n is 2, before the next text
+func (w *textWriter) breakLine(n int) {
+	w.breaks = max(w.breaks, n)
+}
+
+// write adds text after the breaks or space it is owed
+func (w *textWriter) write(text string) {
+	if w.builder.Len() > 0 {
+		switch {
+		case w.breaks > 0:
+			w.builder.WriteString(strings.Repeat("\n", w.breaks))
+		case w.space:
+			w.builder.WriteString(" ")
+		}
+	}
+	w.breaks, w.space = 0, false
+	w.builder.WriteString(text)
+}
+
+// renderText writes the readable text of a node
+func renderText(w *textWriter, node *html.Node) {
+	if skipped(node) {
+		return
+	}
+	switch node.Type {
+	case html.TextNode:
+		text := strings.Join(strings.Fields(node.Data), " ")
+		if text == "" {
+			w.space = w.space || node.Data != ""
+			return
+		}
+		if strings.TrimLeft(node.Data, " \t\r\n") != node.Data {
+			w.space = true
+		}
+		w.write(text)
+		w.space = strings.TrimRight(node.Data, " \t\r\n") != node.Data
+		return
+	case html.ElementNode:
+		switch {
+		case node.Data == "pre":
+			w.breakLine(2)
+			w.write(strings.Trim(textContent(node), "\n"))
+			w.breakLine(2)
+			return
+		case node.Data == "br":
+			w.breakLine(1)
+			return
+		case len(node.Data) == 2 && node.Data[0] == 'h' && node.Data[1] >= '1' && node.Data[1] <= '6':
+			w.breakLine(2)
+			w.write(strings.Repeat("#", int(node.Data[1]-'0')))
+			w.space = true
+		case node.Data == "li":
+			w.breakLine(1)
+			w.write("-")
+			w.space = true
+		case node.Data == "td" || node.Data == "th":
+			w.space = true
+		case paragraphElements[node.Data]:
+			w.breakLine(2)
+		case lineElements[node.Data]:
+			w.breakLine(1)
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		renderText(w, child)
+	}
+	if node.Type == html.ElementNode {
+		switch {
+		case paragraphElements[node.Data]:
+			w.breakLine(2)
+		case lineElements[node.Data]:
+			w.breakLine(1)
+		}
+	}
+}
+
+// textContent returns all text below a node, without skipped elements
+func textContent(node *html.Node) string {
+	if node == nil {
+		return ""
+	}
+	var builder strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+			return
+		}
+		if node.Type == html.ElementNode && node.Data != "title" && skipped(node) {
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return builder.String()
+}
+
+// findElement returns the first element named name below node
+func findElement(node *html.Node, name string) *html.Node {
+	if node.Type == html.ElementNode && node.Data == name {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// attribute returns the value of an attribute of an element
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			if attr.Val == "" {
+				return name // Boolean attributes such as hidden
+			}
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// precedes reports whether a comes before b in the document
+func precedes(a, b *html.Node) bool {
+	found := false
+	var walk func(node *html.Node) bool
+	walk = func(node *html.Node) bool {
+		if node == a {
+			found = true
+			return true
+		}
+		if node == b {
+			return true
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	root := a
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	walk(root)
+	return found
+}
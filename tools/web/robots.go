@@ -0,0 +1,101 @@
+package web
+
+import (
+	"regexp"
+	"strings"
+)
+
+// robotsRules are the allow and disallow rules of a robots.txt that apply to
+// one user agent
+type robotsRules struct {
+	rules []robotsRule
+}
+
+// robotsRule is an allow or disallow line
+type robotsRule struct {
+	allow   bool
+	pattern string // Path pattern as written, whose length orders the rules
+	match   *regexp.Regexp
+}
+
+// parseRobots reads the groups of a robots.txt (RFC 9309) that apply to
+// userAgent: the groups naming its product token, or else the "*" groups
+func parseRobots(data []byte, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i != -1 {
+		token = token[:i]
+	}
+
+	var specific, wildcard []robotsRule
+	var agents []string
+	inRules := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue // An empty disallow allows everything
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value, match: robotsPattern(value)}
+			for _, agent := range agents {
+				switch agent {
+				case token:
+					specific = append(specific, rule)
+				case "*":
+					wildcard = append(wildcard, rule)
+				}
+			}
+		}
+	}
+
+	if specific != nil {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// robotsPattern compiles a path pattern, where "*" matches any characters
+// and a trailing "$" anchors the end
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expression += "$"
+	}
+	return regexp.MustCompile(expression)
+}
+
+// allowed reports whether a path, with its query, may be fetched: the
+// longest matching rule decides, allow winning ties, and paths no rule
+// matches are allowed
+func (r *robotsRules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !rule.match.MatchString(path) {
+			continue
+		}
+		if length := len(rule.pattern); length > longest || (length == longest && rule.allow) {
+			allowed, longest = rule.allow, length
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,285 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alt-coder/pocketflow-go/core"
+)
+
+// SearchResult is a web page found by a search
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// Searcher searches the web. Implement it to add a backend to the
+// web_search tool.
+type Searcher interface {
+	Search(ctx context.Context, query string, count int) ([]SearchResult, error)
+}
+
+// SearcherFunc adapts a function to the Searcher interface
+type SearcherFunc func(ctx context.Context, query string, count int) ([]SearchResult, error)
+
+// Search implements Searcher
+func (f SearcherFunc) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	return f(ctx, query, count)
+}
+
+// SearchConfig configures the web_search tool and its backend
+type SearchConfig struct {
+	Backend    string        `json:"backend"`               // "brave", "serpapi" or "tavily"
+	APIKey     string        `json:"api_key"`               // Key of the backend's API
+	BaseURL    string        `json:"base_url,omitempty"`    // API endpoint, e.g. of a proxy; defaults to the backend's
+	MaxResults int           `json:"max_results,omitempty"` // Results per search; defaults to 5
+	Timeout    core.Duration `json:"timeout,omitempty"`     // Limit of a search request; defaults to 15s
+
+	HTTPClient *http.Client `json:"-"` // Client of the requests; nil uses one with Timeout
+}
+
+// Search backends
+const (
+	BackendBrave   = "brave"
+	BackendSerpAPI = "serpapi"
+	BackendTavily  = "tavily"
+)
+
+// NewSearcher creates the searcher of a backend
+func NewSearcher(config *SearchConfig) (Searcher, error) {
+	if config == nil {
+		return nil, fmt.Errorf("search config cannot be nil")
+	}
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("%s search needs an API key", config.Backend)
+	}
+	client := config.HTTPClient
+	if client == nil {
+		timeout := config.Timeout.Std()
+		if timeout <= 0 {
+			timeout = 15 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	base := strings.TrimSuffix(config.BaseURL, "/")
+
+	switch strings.ToLower(config.Backend) {
+	case BackendBrave:
+		return &braveSearcher{apiKey: config.APIKey, baseURL: orDefault(base, "https://api.search.brave.com"), client: client}, nil
+	case BackendSerpAPI:
+		return &serpAPISearcher{apiKey: config.APIKey, baseURL: orDefault(base, "https://serpapi.com"), client: client}, nil
+	case BackendTavily:
+		return &tavilySearcher{apiKey: config.APIKey, baseURL: orDefault(base, "https://api.tavily.com"), client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %s. Supported backends: brave, serpapi, tavily", config.Backend)
+	}
+}
+
+// braveSearcher searches with the Brave Search API
+type braveSearcher struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// Search implements Searcher
+func (b *braveSearcher) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(count)}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/res/v1/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Subscription-Token", b.apiKey)
+
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doJSON(b.client, request, "brave", &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(response.Web.Results))
+	for _, result := range response.Web.Results {
+		results = append(results, SearchResult{Title: stripTags(result.Title), URL: result.URL, Snippet: stripTags(result.Description)})
+	}
+	return limit(results, count), nil
+}
+
+// serpAPISearcher searches Google through SerpAPI
+type serpAPISearcher struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// Search implements Searcher
+func (s *serpAPISearcher) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	params := url.Values{"engine": {"google"}, "q": {query}, "num": {strconv.Itoa(count)}, "api_key": {s.apiKey}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/search.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Error          string `json:"error"`
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := doJSON(s.client, request, "serpapi", &response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" && len(response.OrganicResults) == 0 {
+		// SerpAPI reports searches without results as an error
+		if strings.Contains(response.Error, "hasn't returned any results") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("serpapi search failed: %s", response.Error)
+	}
+
+	results := make([]SearchResult, 0, len(response.OrganicResults))
+	for _, result := range response.OrganicResults {
+		results = append(results, SearchResult{Title: result.Title, URL: result.Link, Snippet: result.Snippet})
+	}
+	return limit(results, count), nil
+}
+
+// tavilySearcher searches with the Tavily API
+type tavilySearcher struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// Search implements Searcher
+func (t *tavilySearcher) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	body, err := json.Marshal(map[string]any{"query": query, "max_results": count})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	var response struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doJSON(t.client, request, "tavily", &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(response.Results))
+	for _, result := range response.Results {
+		results = append(results, SearchResult{Title: result.Title, URL: result.URL, Snippet: result.Content})
+	}
+	return limit(results, count), nil
+}
+
+// doJSON sends a request and decodes the JSON response, turning error
+// statuses into errors that quote the start of the body
+func doJSON(client *http.Client, request *http.Request, backend string, out any) error {
+	response, err := client.Do(request)
+	if err != nil {
+		// The URL of the error may carry the API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s search request failed: %w", backend, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s search response: %w", backend, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s search returned %d: %s", backend, response.StatusCode, strings.TrimSpace(truncate(string(body), 300)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid %s search response: %w", backend, err)
+	}
+	return nil
+}
+
+// tagPattern matches HTML tags in snippets, such as the <strong> around matched words
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags removes HTML tags and entities from a snippet
+func stripTags(text string) string {
+	return html.UnescapeString(tagPattern.ReplaceAllString(text, ""))
+}
+
+// limit keeps the first count results
+func limit(results []SearchResult, count int) []SearchResult {
+	if count > 0 && len(results) > count {
+		return results[:count]
+	}
+	return results
+}
+
+// formatResults renders search results as a numbered list for the model
+func formatResults(results []SearchResult) string {
+	if len(results) == 0 {
+		return "No results found."
+	}
+	var builder strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			builder.WriteString("\n\n")
+		}
+		fmt.Fprintf(&builder, "%d. %s\n   %s", i+1, result.Title, result.URL)
+		if snippet := strings.TrimSpace(result.Snippet); snippet != "" {
+			fmt.Fprintf(&builder, "\n   %s", strings.Join(strings.Fields(snippet), " "))
+		}
+	}
+	return builder.String()
+}
+
+// orDefault returns value, or fallback when it is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// truncate returns the first n bytes of s, cut at a rune boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,163 @@
+// Package web provides first-party web_search and fetch_url tools, so agents
+// can do basic research without an external MCP server. web_search queries
+// Brave, SerpAPI, Tavily or a custom Searcher; fetch_url downloads a page,
+// honoring robots.txt and size limits, and returns its readable text.
+//
+//	err := web.AddTools(toolManager, &web.Config{
+//		Search: &web.SearchConfig{Backend: web.BackendBrave, APIKey: os.Getenv("BRAVE_API_KEY")},
+//		Fetch:  web.DefaultFetchConfig(),
+//	})
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+// Tool names
+const (
+	SearchTool = "web_search"
+	FetchTool  = "fetch_url"
+)
+
+// Config selects the web tools to add
+type Config struct {
+	Search *SearchConfig `json:"search,omitempty"` // Backend of web_search; nil adds no web_search tool
+	Fetch  *FetchConfig  `json:"fetch,omitempty"`  // Limits of fetch_url; nil adds no fetch_url tool
+}
+
+// AddTools adds the tools of config to a tool manager
+func AddTools(toolManager *tools.ToolManager, config *Config) error {
+	if config == nil {
+		return fmt.Errorf("web tool config cannot be nil")
+	}
+	if config.Search != nil {
+		searcher, err := NewSearcher(config.Search)
+		if err != nil {
+			return err
+		}
+		if err := AddSearchTool(toolManager, searcher, config.Search.MaxResults); err != nil {
+			return err
+		}
+	}
+	if config.Fetch != nil {
+		if err := AddFetchTool(toolManager, NewFetcher(config.Fetch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSearchTool adds the web_search tool over any searcher; maxResults of
+// zero returns 5 results per search
+func AddSearchTool(toolManager *tools.ToolManager, searcher Searcher, maxResults int) error {
+	if toolManager == nil || searcher == nil {
+		return fmt.Errorf("tool manager and searcher are required")
+	}
+	if maxResults <= 0 {
+		maxResults = 5
+	}
+
+	return toolManager.AddLocalToolLegacy(tools.LocalTool{
+		Name:        SearchTool,
+		Description: "Search the web. Returns numbered results with their title, URL and a snippet; use fetch_url to read a result.",
+		Parameters: map[string]tools.Parameter{
+			"query": {Type: "string", Description: "Search query", Required: true},
+			"count": {Type: "number", Description: fmt.Sprintf("Number of results, at most %d", maxResults), Default: maxResults},
+		},
+		Handler: tools.ToolHandler(func(ctx context.Context, args map[string]interface{}) (string, error) {
+			query, _ := args["query"].(string)
+			if strings.TrimSpace(query) == "" {
+				return "", llm.NewToolError(llm.ToolErrorInvalidArgs, "query is required")
+			}
+			count := maxResults
+			if requested := intArg(args["count"]); requested >= 1 {
+				count = min(requested, maxResults)
+			}
+
+			results, err := searcher.Search(ctx, query, count)
+			if err != nil {
+				return "", err
+			}
+			return formatResults(limit(results, count)), nil
+		}),
+	})
+}
+
+// AddFetchTool adds the fetch_url tool
+func AddFetchTool(toolManager *tools.ToolManager, fetcher *Fetcher) error {
+	if toolManager == nil || fetcher == nil {
+		return fmt.Errorf("tool manager and fetcher are required")
+	}
+	maxChars := fetcher.config.MaxChars
+
+	return toolManager.AddLocalToolLegacy(tools.LocalTool{
+		Name:        FetchTool,
+		Description: fmt.Sprintf("Fetch a web page and return its main text, at most %d characters per call. Pass offset to read on where a long page was cut.", maxChars),
+		Parameters: map[string]tools.Parameter{
+			"url":    {Type: "string", Description: "http or https URL of the page", Required: true},
+			"offset": {Type: "number", Description: "Character of the text to start at, 0 for the start"},
+		},
+		Handler: tools.ToolHandler(func(ctx context.Context, args map[string]interface{}) (string, error) {
+			target, _ := args["url"].(string)
+			if strings.TrimSpace(target) == "" {
+				return "", llm.NewToolError(llm.ToolErrorInvalidArgs, "url is required")
+			}
+			page, err := fetcher.Fetch(ctx, target)
+			if errors.Is(err, ErrRobotsDisallowed) || errors.Is(err, ErrPrivateAddress) {
+				return "", llm.NewToolError(llm.ToolErrorPermissionDenied, err.Error())
+			}
+			if err != nil {
+				return "", err
+			}
+			return formatPage(page, max(intArg(args["offset"]), 0), maxChars), nil
+		}),
+	})
+}
+
+// formatPage renders the part of a page's text starting at offset
+func formatPage(page *Page, offset, maxChars int) string {
+	var builder strings.Builder
+	if page.Title != "" {
+		fmt.Fprintf(&builder, "Title: %s\n", page.Title)
+	}
+	fmt.Fprintf(&builder, "URL: %s\n\n", page.URL)
+
+	text := page.Text
+	total := utf8.RuneCountInString(text)
+	if offset > 0 || total > maxChars {
+		runes := []rune(text)
+		offset = min(offset, total)
+		end := min(offset+maxChars, total)
+		builder.WriteString(string(runes[offset:end]))
+		if end < total {
+			fmt.Fprintf(&builder, "\n\n[Characters %d to %d of %d. Call %s with offset %d to read on.]", offset, end, total, FetchTool, end)
+		} else if offset > 0 {
+			fmt.Fprintf(&builder, "\n\n[Characters %d to %d of %d.]", offset, end, total)
+		}
+	} else {
+		builder.WriteString(text)
+	}
+	if page.Truncated {
+		builder.WriteString("\n\n[The page was larger than the download limit; its end is missing.]")
+	}
+	return builder.String()
+}
+
+// intArg returns a numeric tool argument, which arrives as float64 from JSON,
+// or 0 when it is missing
+func intArg(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,248 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alt-coder/pocketflow-go/llm"
+	"github.com/alt-coder/pocketflow-go/tools"
+)
+
+func TestNewSearcher_Backends(t *testing.T) {
+	tests := []struct {
+		backend string
+		path    string
+		check   func(r *http.Request) bool
+		body    string
+	}{
+		{
+			backend: BackendBrave,
+			path:    "/res/v1/web/search",
+			check: func(r *http.Request) bool {
+				return r.Header.Get("X-Subscription-Token") == "key" && r.URL.Query().Get("q") == "go generics" && r.URL.Query().Get("count") == "2"
+			},
+			body: `{"web": {"results": [{"title": "Go <strong>generics</strong>", "url": "https://go.dev/doc/tutorial/generics", "description": "Tutorial &amp; examples"}]}}`,
+		},
+		{
+			backend: BackendSerpAPI,
+			path:    "/search.json",
+			check: func(r *http.Request) bool {
+				return r.URL.Query().Get("api_key") == "key" && r.URL.Query().Get("engine") == "google" && r.URL.Query().Get("q") == "go generics"
+			},
+			body: `{"organic_results": [{"title": "Go generics", "link": "https://go.dev/doc/tutorial/generics", "snippet": "Tutorial & examples"}]}`,
+		},
+		{
+			backend: BackendTavily,
+			path:    "/search",
+			check: func(r *http.Request) bool {
+				var body struct {
+					Query      string `json:"query"`
+					MaxResults int    `json:"max_results"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				return r.Method == http.MethodPost && r.Header.Get("Authorization") == "Bearer key" && body.Query == "go generics" && body.MaxResults == 2
+			},
+			body: `{"results": [{"title": "Go generics", "url": "https://go.dev/doc/tutorial/generics", "content": "Tutorial & examples"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path || !tt.check(r) {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			searcher, err := NewSearcher(&SearchConfig{Backend: tt.backend, APIKey: "key", BaseURL: server.URL})
+			if err != nil {
+				t.Fatalf("NewSearcher() error = %v", err)
+			}
+			results, err := searcher.Search(context.Background(), "go generics", 2)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			want := SearchResult{Title: "Go generics", URL: "https://go.dev/doc/tutorial/generics", Snippet: "Tutorial & examples"}
+			if len(results) != 1 || results[0] != want {
+				t.Errorf("Search() = %+v", results)
+			}
+		})
+	}
+
+	if _, err := NewSearcher(&SearchConfig{Backend: "bing", APIKey: "key"}); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestSearch_ErrorHidesKey(t *testing.T) {
+	searcher, _ := NewSearcher(&SearchConfig{Backend: BackendSerpAPI, APIKey: "secret-key", BaseURL: "http://127.0.0.1:1"})
+	_, err := searcher.Search(context.Background(), "query", 1)
+	if err == nil || strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("expected an error without the API key, got %v", err)
+	}
+}
+
+const articlePage = `<!doctype html>
+<html><head><title>Tides Explained</title><style>p { color: red }</style></head>
+<body>
+  <header><nav><a href="/">Home</a> <a href="/blog">Blog</a></nav></header>
+  <div class="sidebar"><p>Subscribe to our newsletter for weekly updates, tips and more.</p></div>
+  <div id="content">
+    <h1>Tides</h1>
+    <p>Tides are the rise and fall of sea levels, caused by the gravity of the Moon and the Sun.</p>
+    <p>Most coasts see two high tides and two low tides every day, about twelve hours apart.</p>
+    <ul><li>Spring tides</li><li>Neap tides</li></ul>
+    <script>track()</script>
+  </div>
+  <footer><p>Copyright 2024, Example Inc. All rights reserved, everywhere.</p></footer>
+</body></html>`
+
+func TestExtractReadable(t *testing.T) {
+	title, text, err := ExtractReadable([]byte(articlePage))
+	if err != nil {
+		t.Fatalf("ExtractReadable() error = %v", err)
+	}
+	if title != "Tides Explained" {
+		t.Errorf("title = %q", title)
+	}
+	want := "# Tides\n\nTides are the rise and fall of sea levels, caused by the gravity of the Moon and the Sun.\n\n" +
+		"Most coasts see two high tides and two low tides every day, about twelve hours apart.\n\n- Spring tides\n- Neap tides"
+	if text != want {
+		t.Errorf("text =\n%s\nwant\n%s", text, want)
+	}
+}
+
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			io.WriteString(w, "User-agent: *\nDisallow: /private\n\nUser-agent: otherbot\nDisallow: /\n")
+		case "/tides", "/private/tides":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, articlePage)
+		case "/moved":
+			http.Redirect(w, r, "/private/tides", http.StatusFound)
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, strings.Repeat("0123456789", 10))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	server := newTestSite(t)
+	fetcher := NewFetcher(&FetchConfig{MaxBytes: 64, AllowPrivateNetworks: true})
+	ctx := context.Background()
+
+	if _, err := fetcher.Fetch(ctx, server.URL+"/private/tides"); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Errorf("expected robots.txt to disallow /private, got %v", err)
+	}
+	if _, err := fetcher.Fetch(ctx, server.URL+"/moved"); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Errorf("expected a redirect into /private to be disallowed, got %v", err)
+	}
+	if _, err := fetcher.Fetch(ctx, server.URL+"/logo.png"); err == nil || !strings.Contains(err.Error(), "image/png") {
+		t.Errorf("expected an error for an image, got %v", err)
+	}
+	if _, err := fetcher.Fetch(ctx, "file:///etc/passwd"); err == nil {
+		t.Error("expected an error for a file URL")
+	}
+
+	page, err := fetcher.Fetch(ctx, server.URL+"/notes.txt")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if !page.Truncated || len(page.Text) != 64 {
+		t.Errorf("expected the body cut at 64 bytes, got %d bytes, truncated %v", len(page.Text), page.Truncated)
+	}
+
+	guarded := NewFetcher(nil)
+	if _, err := guarded.Fetch(ctx, server.URL+"/tides"); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("expected a loopback address to be refused by default, got %v", err)
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := `
+# Comments are ignored
+User-agent: *
+Disallow: /search
+Allow: /search/about
+
+User-agent: pocketflow-go
+User-agent: otherbot
+Disallow: /drafts/
+Disallow: /*.pdf$
+Allow: /drafts/public
+`
+	tests := []struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{"Mozilla/5.0", "/search?q=go", false},
+		{"Mozilla/5.0", "/search/about", true},
+		{"Mozilla/5.0", "/drafts/x", true},
+		{DefaultUserAgent, "/search?q=go", true},
+		{DefaultUserAgent, "/drafts/x", false},
+		{DefaultUserAgent, "/drafts/public/x", true},
+		{DefaultUserAgent, "/papers/tides.pdf", false},
+		{DefaultUserAgent, "/papers/tides.pdf?download=1", true},
+		{DefaultUserAgent, "/robots.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.userAgent+tt.path, func(t *testing.T) {
+			if got := parseRobots([]byte(robots), tt.userAgent).allowed(tt.path); got != tt.want {
+				t.Errorf("allowed(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTools(t *testing.T) {
+	server := newTestSite(t)
+	toolManager := tools.NewToolManager()
+	searcher := SearcherFunc(func(ctx context.Context, query string, count int) ([]SearchResult, error) {
+		return []SearchResult{{Title: "Tides", URL: server.URL + "/tides", Snippet: "Rise and fall\nof the sea"}, {Title: "More", URL: server.URL}}, nil
+	})
+	if err := AddSearchTool(toolManager, searcher, 1); err != nil {
+		t.Fatalf("AddSearchTool() error = %v", err)
+	}
+	if err := AddFetchTool(toolManager, NewFetcher(&FetchConfig{MaxChars: 40, AllowPrivateNetworks: true})); err != nil {
+		t.Fatalf("AddFetchTool() error = %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := toolManager.ExecuteTool(ctx, llm.ToolCalls{Id: "1", ToolName: SearchTool, ToolArgs: map[string]any{"query": "tides", "count": float64(5)}})
+	if err != nil || result.IsError {
+		t.Fatalf("web_search failed: %v, %+v", err, result)
+	}
+	if want := "1. Tides\n   " + server.URL + "/tides\n   Rise and fall of the sea"; result.Content != want {
+		t.Errorf("web_search = %q, want %q", result.Content, want)
+	}
+
+	result, err = toolManager.ExecuteTool(ctx, llm.ToolCalls{Id: "2", ToolName: FetchTool, ToolArgs: map[string]any{"url": server.URL + "/tides", "offset": float64(40)}})
+	if err != nil || result.IsError {
+		t.Fatalf("fetch_url failed: %v, %+v", err, result)
+	}
+	for _, want := range []string{"Title: Tides Explained\n", "URL: " + server.URL + "/tides\n", "Characters 40 to 80 of", "offset 80"} {
+		if !strings.Contains(result.Content, want) {
+			t.Errorf("expected %q in %q", want, result.Content)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryQueue is a transport within one process, for tests and for running
+// producers and workers side by side
+type MemoryQueue struct {
+	messages chan []byte
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewMemoryQueue creates a queue holding up to size messages; Send blocks while it is full
+func NewMemoryQueue(size int) *MemoryQueue {
+	return &MemoryQueue{messages: make(chan []byte, size), done: make(chan struct{})}
+}
+
+// Send adds a message to the queue
+func (q *MemoryQueue) Send(ctx context.Context, body []byte) error {
+	select {
+	case <-q.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case q.messages <- append([]byte(nil), body...):
+		return nil
+	case <-q.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive returns the oldest message, waiting for one if the queue is empty
+func (q *MemoryQueue) Receive(ctx context.Context) (Message, error) {
+	select {
+	case body := <-q.messages:
+		return &memoryMessage{queue: q, body: body}, nil
+	case <-q.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Len returns the number of waiting messages
+func (q *MemoryQueue) Len() int {
+	return len(q.messages)
+}
+
+// Close makes Send and Receive fail with ErrClosed
+func (q *MemoryQueue) Close() error {
+	q.once.Do(func() { close(q.done) })
+	return nil
+}
+
+// memoryMessage is a message taken from a MemoryQueue
+type memoryMessage struct {
+	queue *MemoryQueue
+	body  []byte
+}
+
+func (m *memoryMessage) Body() []byte { return m.body }
+
+// Ack drops the message
+func (m *memoryMessage) Ack(ctx context.Context) error { return nil }
+
+// Nack puts the message back at the end of the queue
+func (m *memoryMessage) Nack(ctx context.Context) error {
+	return m.queue.Send(ctx, m.body)
+}
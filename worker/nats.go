@@ -0,0 +1,321 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSConfig configures a NATSQueue
+type NATSConfig struct {
+	URL         string        // nats://[user:password@]host:port; default "nats://localhost:4222"
+	Subject     string        // Subject the messages are published on
+	QueueGroup  string        // Workers in the same group share the messages; default "pocketflow-workers"
+	Token       string        // Authentication token, if the server uses tokens
+	Name        string        // Connection name shown by the server; default "pocketflow-worker"
+	TLS         *tls.Config   // Used when the URL scheme is tls or the server requires TLS
+	DialTimeout time.Duration // Default 10s
+	Buffer      int           // Messages received ahead of Receive calls; default 64
+}
+
+// NATSQueue sends messages on a NATS subject and receives them as a member of
+// a queue group, so each message goes to one worker of the group. Core NATS
+// delivers at most once: Ack does nothing, and a message is lost if its worker
+// crashes. Nack publishes the message again. Use a broker with acknowledgements,
+// such as Redis or SQS, when jobs must survive worker crashes.
+type NATSQueue struct {
+	config   NATSConfig
+	server   *url.URL
+	mu       sync.Mutex
+	conn     *natsConn
+	receives bool // Whether the queue subscribes when it connects
+	closed   bool
+}
+
+// NewNATSQueue creates a queue; it connects when it is first used
+func NewNATSQueue(config NATSConfig) (*NATSQueue, error) {
+	if config.Subject == "" {
+		return nil, fmt.Errorf("nats subject cannot be empty")
+	}
+	if config.URL == "" {
+		config.URL = "nats://localhost:4222"
+	}
+	server, err := url.Parse(config.URL)
+	if err != nil || server.Host == "" {
+		return nil, fmt.Errorf("invalid nats URL %q", config.URL)
+	}
+	if config.QueueGroup == "" {
+		config.QueueGroup = "pocketflow-workers"
+	}
+	if config.Name == "" {
+		config.Name = "pocketflow-worker"
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = 64
+	}
+	return &NATSQueue{config: config, server: server}, nil
+}
+
+// Send publishes a message on the subject
+func (q *NATSQueue) Send(ctx context.Context, body []byte) error {
+	conn, err := q.connection(ctx, false)
+	if err != nil {
+		return err
+	}
+	return conn.publish(q.config.Subject, body)
+}
+
+// Receive returns the next message delivered to this member of the queue group
+func (q *NATSQueue) Receive(ctx context.Context) (Message, error) {
+	conn, err := q.connection(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case body := <-conn.messages:
+		return &natsMessage{queue: q, body: body}, nil
+	case <-conn.done:
+		q.drop(conn)
+		return nil, fmt.Errorf("nats connection lost: %w", conn.err)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the connection
+func (q *NATSQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	if q.conn != nil {
+		q.conn.close(ErrClosed)
+		q.conn = nil
+	}
+	return nil
+}
+
+// connection returns the live connection, connecting and subscribing first if needed
+func (q *NATSQueue) connection(ctx context.Context, receive bool) (*natsConn, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	if q.conn != nil {
+		select {
+		case <-q.conn.done:
+			q.conn = nil
+		default:
+		}
+	}
+	if q.conn == nil {
+		conn, err := q.dial(ctx)
+		if err != nil {
+			return nil, err
+		}
+		q.conn = conn
+		if q.receives {
+			if err := conn.subscribe(q.config.Subject, q.config.QueueGroup); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if receive && !q.receives {
+		if err := q.conn.subscribe(q.config.Subject, q.config.QueueGroup); err != nil {
+			return nil, err
+		}
+		q.receives = true
+	}
+	return q.conn, nil
+}
+
+// drop forgets a connection that failed, so the next call reconnects
+func (q *NATSQueue) drop(conn *natsConn) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.conn == conn {
+		q.conn = nil
+	}
+}
+
+// natsInfo is the part of the server's INFO message the client needs
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// dial connects to the server and completes the handshake
+func (q *NATSQueue) dial(ctx context.Context) (*natsConn, error) {
+	dialer := &net.Dialer{Timeout: q.config.DialTimeout}
+	raw, err := dialer.DialContext(ctx, "tcp", q.server.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	raw.SetDeadline(time.Now().Add(q.config.DialTimeout))
+
+	reader := bufio.NewReader(raw)
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		raw.Close()
+		return nil, fmt.Errorf("nats handshake failed: %q %v", line, err)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line[5:])), &info); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("nats handshake failed: %w", err)
+	}
+
+	conn := raw
+	if info.TLSRequired || q.server.Scheme == "tls" || q.config.TLS != nil {
+		config := q.config.TLS
+		if config == nil {
+			config = &tls.Config{ServerName: q.server.Hostname()}
+		}
+		tlsConn := tls.Client(raw, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, fmt.Errorf("nats TLS handshake failed: %w", err)
+		}
+		conn, reader = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	options := map[string]any{"verbose": false, "pedantic": false, "lang": "go", "name": q.config.Name, "protocol": 1}
+	if user := q.server.User; user != nil {
+		password, _ := user.Password()
+		options["user"], options["pass"] = user.Username(), password
+	}
+	if q.config.Token != "" {
+		options["auth_token"] = q.config.Token
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats handshake failed: %w", err)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("nats handshake failed: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, fmt.Errorf("nats refused the connection: %s", strings.TrimSpace(line[4:]))
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &natsConn{conn: conn, reader: reader, messages: make(chan []byte, q.config.Buffer), done: make(chan struct{})}
+	go c.read()
+	return c, nil
+}
+
+// natsConn is one connection to a NATS server
+type natsConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	writeMu  sync.Mutex
+	messages chan []byte
+	done     chan struct{}
+	once     sync.Once
+	err      error
+}
+
+// publish sends a message on subject
+func (c *natsConn) publish(subject string, body []byte) error {
+	return c.write(fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(body), body))
+}
+
+// subscribe joins the queue group of subject
+func (c *natsConn) subscribe(subject, group string) error {
+	return c.write(fmt.Sprintf("SUB %s %s 1\r\n", subject, group))
+}
+
+// write sends protocol text
+func (c *natsConn) write(text string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := io.WriteString(c.conn, text); err != nil {
+		c.close(err)
+		return fmt.Errorf("nats write failed: %w", err)
+	}
+	return nil
+}
+
+// close closes the connection with the reason the next Receive reports
+func (c *natsConn) close(err error) {
+	c.once.Do(func() {
+		c.err = err
+		c.conn.Close()
+		close(c.done)
+	})
+}
+
+// read handles server messages until the connection fails
+func (c *natsConn) read() {
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.close(err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			c.write("PONG\r\n")
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <size>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				c.close(fmt.Errorf("invalid nats message %q", line))
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(c.reader, payload); err != nil {
+				c.close(err)
+				return
+			}
+			select {
+			case c.messages <- payload[:size]:
+			case <-c.done:
+				return
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			c.close(errors.New(strings.Trim(strings.TrimSpace(line[4:]), "'")))
+			return
+		}
+	}
+}
+
+// natsMessage is a message delivered to the queue group
+type natsMessage struct {
+	queue *NATSQueue
+	body  []byte
+}
+
+func (m *natsMessage) Body() []byte { return m.body }
+
+// Ack does nothing; core NATS does not track deliveries
+func (m *natsMessage) Ack(ctx context.Context) error { return nil }
+
+// Nack publishes the message again for another member of the group
+func (m *natsMessage) Nack(ctx context.Context) error {
+	return m.queue.Send(ctx, m.body)
+}
@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNATS is a NATS server with one queue group per subject; it delivers each
+// message to the subscribers in turn
+type fakeNATS struct {
+	mu          sync.Mutex
+	subscribers map[string][]net.Conn
+	connects    []string
+	next        int
+	listener    net.Listener
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	n := &fakeNATS{subscribers: make(map[string][]net.Conn), listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(conn)
+		}
+	}()
+	return n
+}
+
+func (n *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			n.mu.Lock()
+			n.connects = append(n.connects, strings.TrimSpace(line[len("CONNECT "):]))
+			n.mu.Unlock()
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "SUB":
+			n.mu.Lock()
+			n.subscribers[fields[1]] = append(n.subscribers[fields[1]], conn)
+			n.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			n.mu.Lock()
+			if subscribers := n.subscribers[fields[1]]; len(subscribers) > 0 {
+				subscriber := subscribers[n.next%len(subscribers)]
+				n.next++
+				fmt.Fprintf(subscriber, "MSG %s 1 %d\r\n%s", fields[1], size, payload)
+			}
+			n.mu.Unlock()
+		}
+	}
+}
+
+// subscriberCount returns the number of subscribers of subject
+func (n *fakeNATS) subscriberCount(subject string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.subscribers[subject])
+}
+
+func TestNATSQueue(t *testing.T) {
+	nats := newFakeNATS(t)
+	ctx := context.Background()
+	url := "nats://worker:secret@" + nats.listener.Addr().String()
+
+	var workers []*NATSQueue
+	for i := 0; i < 2; i++ {
+		queue, err := NewNATSQueue(NATSConfig{URL: url, Subject: "jobs"})
+		if err != nil {
+			t.Fatalf("NewNATSQueue failed: %v", err)
+		}
+		defer queue.Close()
+		// Receiving once subscribes the queue
+		short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		queue.Receive(short)
+		cancel()
+		workers = append(workers, queue)
+	}
+	for deadline := time.Now().Add(5 * time.Second); nats.subscriberCount("jobs") < 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("workers did not subscribe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	producer, err := NewNATSQueue(NATSConfig{URL: url, Subject: "jobs"})
+	if err != nil {
+		t.Fatalf("NewNATSQueue failed: %v", err)
+	}
+	defer producer.Close()
+	for _, body := range []string{"one", "two"} {
+		if err := producer.Send(ctx, []byte(body)); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	// Each member of the group gets one message
+	first, second := receive(t, workers[0]), receive(t, workers[1])
+	if got := string(first.Body()) + "," + string(second.Body()); got != "one,two" {
+		t.Errorf("received %s, want one,two", got)
+	}
+	if err := first.Ack(ctx); err != nil {
+		t.Errorf("Ack failed: %v", err)
+	}
+
+	// A rejected message goes to the next member
+	if err := second.Nack(ctx); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	if got := string(receive(t, workers[0]).Body()); got != "two" {
+		t.Errorf("message after Nack = %q, want two", got)
+	}
+
+	nats.mu.Lock()
+	connect := nats.connects[0]
+	nats.mu.Unlock()
+	if !strings.Contains(connect, `"user":"worker"`) || !strings.Contains(connect, `"pass":"secret"`) {
+		t.Errorf("CONNECT %s does not carry the URL credentials", connect)
+	}
+
+	workers[1].Close()
+	if _, err := workers[1].Receive(ctx); err != ErrClosed {
+		t.Errorf("Receive after Close = %v, want ErrClosed", err)
+	}
+}
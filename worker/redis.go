@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/resp"
+)
+
+// RedisConfig configures a RedisQueue
+type RedisConfig struct {
+	Addr         string        // host:port; default "localhost:6379"
+	Username     string        // ACL user; empty authenticates with the password only
+	Password     string        // Sent with AUTH when set
+	DB           int           // Database selected after connecting
+	TLS          *tls.Config   // Connects with TLS when set
+	Queue        string        // List the messages are pushed to
+	Consumer     string        // Names this worker's processing list; keep it stable across restarts. Defaults to the host name.
+	BlockTimeout time.Duration // How long one blocking receive waits on the server; default 5s
+	DialTimeout  time.Duration // Default 10s
+}
+
+// RedisQueue is a reliable queue on a Redis list. Receive moves a message to
+// a processing list of the consumer with BLMOVE (Redis 6.2 or later), Ack
+// removes it from there and Nack moves it back. Messages a crashed worker left
+// in its processing list are returned to the queue when the consumer connects again.
+type RedisQueue struct {
+	config     RedisConfig
+	processing string
+	client     *resp.Client
+}
+
+// NewRedisQueue connects to Redis and returns the messages left in the
+// consumer's processing list to the queue
+func NewRedisQueue(ctx context.Context, config RedisConfig) (*RedisQueue, error) {
+	if config.Queue == "" {
+		return nil, fmt.Errorf("redis queue name cannot be empty")
+	}
+	if config.Consumer == "" {
+		config.Consumer, _ = os.Hostname()
+	}
+	if config.BlockTimeout <= 0 {
+		config.BlockTimeout = 5 * time.Second
+	}
+
+	q := &RedisQueue{
+		config:     config,
+		processing: config.Queue + ":processing:" + config.Consumer,
+		client: resp.NewClient(resp.Config{
+			Addr:        config.Addr,
+			Username:    config.Username,
+			Password:    config.Password,
+			DB:          config.DB,
+			TLS:         config.TLS,
+			DialTimeout: config.DialTimeout,
+		}),
+	}
+	if err := q.recover(ctx); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// recover moves the messages of the processing list back to the queue
+func (q *RedisQueue) recover(ctx context.Context) error {
+	for {
+		reply, err := q.do(ctx, "LMOVE", q.processing, q.config.Queue, "LEFT", "RIGHT")
+		if err != nil {
+			return fmt.Errorf("failed to recover unacknowledged messages: %w", err)
+		}
+		if reply == nil {
+			return nil
+		}
+	}
+}
+
+// Send pushes a message onto the queue
+func (q *RedisQueue) Send(ctx context.Context, body []byte) error {
+	_, err := q.do(ctx, "LPUSH", q.config.Queue, string(body))
+	return err
+}
+
+// Receive moves the oldest message to the processing list, waiting for one if
+// the queue is empty
+func (q *RedisQueue) Receive(ctx context.Context) (Message, error) {
+	timeout := strconv.FormatFloat(q.config.BlockTimeout.Seconds(), 'f', -1, 64)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reply, err := q.do(ctx, "BLMOVE", q.config.Queue, q.processing, "RIGHT", "LEFT", timeout)
+		if err != nil {
+			return nil, err
+		}
+		if body, ok := reply.(string); ok {
+			return &redisMessage{queue: q, body: body}, nil
+		}
+	}
+}
+
+// Close closes the idle connections
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
+
+// do runs a command on a pooled connection
+func (q *RedisQueue) do(ctx context.Context, args ...string) (any, error) {
+	return q.client.Do(ctx, args...)
+}
+
+// redisMessage is a message in the consumer's processing list
+type redisMessage struct {
+	queue *RedisQueue
+	body  string
+}
+
+func (m *redisMessage) Body() []byte { return []byte(m.body) }
+
+// Ack removes the message from the processing list
+func (m *redisMessage) Ack(ctx context.Context) error {
+	_, err := m.queue.do(ctx, "LREM", m.queue.processing, "1", m.body)
+	return err
+}
+
+// Nack moves the message back to the front of the queue
+func (m *redisMessage) Nack(ctx context.Context) error {
+	if _, err := m.queue.do(ctx, "RPUSH", m.queue.config.Queue, m.body); err != nil {
+		return err
+	}
+	return m.Ack(ctx)
+}
@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/resp"
+)
+
+// fakeRedis serves the list commands RedisQueue uses
+type fakeRedis struct {
+	mu       sync.Mutex
+	lists    map[string][]string
+	listener net.Listener
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	r := &fakeRedis{lists: make(map[string][]string), listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := resp.ReadReply(reader)
+		if err != nil {
+			return
+		}
+		items := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = item.(string)
+		}
+		fmt.Fprint(conn, r.handle(args))
+	}
+}
+
+// handle runs a command; lists are stored left to right
+func (r *fakeRedis) handle(args []string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch args[0] {
+	case "LPUSH":
+		r.lists[args[1]] = append([]string{args[2]}, r.lists[args[1]]...)
+		return ":" + strconv.Itoa(len(r.lists[args[1]])) + "\r\n"
+	case "RPUSH":
+		r.lists[args[1]] = append(r.lists[args[1]], args[2])
+		return ":" + strconv.Itoa(len(r.lists[args[1]])) + "\r\n"
+	case "LMOVE", "BLMOVE":
+		source := r.lists[args[1]]
+		if len(source) == 0 {
+			if args[0] == "BLMOVE" {
+				// Stand in for the server-side wait
+				r.mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				r.mu.Lock()
+			}
+			return "$-1\r\n"
+		}
+		var value string
+		if args[3] == "LEFT" {
+			value, r.lists[args[1]] = source[0], source[1:]
+		} else {
+			value, r.lists[args[1]] = source[len(source)-1], source[:len(source)-1]
+		}
+		if args[4] == "LEFT" {
+			r.lists[args[2]] = append([]string{value}, r.lists[args[2]]...)
+		} else {
+			r.lists[args[2]] = append(r.lists[args[2]], value)
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "LREM":
+		list := r.lists[args[1]]
+		for i, value := range list {
+			if value == args[3] {
+				r.lists[args[1]] = append(list[:i:i], list[i+1:]...)
+				return ":1\r\n"
+			}
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+// list returns a copy of the list stored under key
+func (r *fakeRedis) list(key string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.lists[key]...)
+}
+
+func TestRedisQueue(t *testing.T) {
+	redis := newFakeRedis(t)
+	ctx := context.Background()
+	config := RedisConfig{Addr: redis.listener.Addr().String(), Queue: "jobs", Consumer: "w1", BlockTimeout: time.Second}
+	queue, err := NewRedisQueue(ctx, config)
+	if err != nil {
+		t.Fatalf("NewRedisQueue failed: %v", err)
+	}
+	defer queue.Close()
+
+	for _, body := range []string{"one", "two", "three"} {
+		if err := queue.Send(ctx, []byte(body)); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	first := receive(t, queue)
+	if string(first.Body()) != "one" {
+		t.Errorf("first message = %q, want one", first.Body())
+	}
+	if got := redis.list("jobs:processing:w1"); len(got) != 1 || got[0] != "one" {
+		t.Errorf("processing list = %v", got)
+	}
+	if err := first.Ack(ctx); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+
+	second := receive(t, queue)
+	if err := second.Nack(ctx); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	if got := string(receive(t, queue).Body()); got != "two" {
+		t.Errorf("message after Nack = %q, want two", got)
+	}
+
+	// A restarted consumer returns what it had taken to the front of the queue
+	receive(t, queue)
+	if _, err := NewRedisQueue(ctx, config); err != nil {
+		t.Fatalf("NewRedisQueue failed: %v", err)
+	}
+	if got := redis.list("jobs:processing:w1"); len(got) != 0 {
+		t.Errorf("processing list after recovery = %v", got)
+	}
+	if got := redis.list("jobs"); strings.Join(got, ",") != "three,two" {
+		t.Errorf("queue after recovery = %v, want [three two]", got)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := queue.Receive(canceled); err == nil {
+		t.Error("expected Receive to fail with a canceled context")
+	}
+}
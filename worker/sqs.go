@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/internal/sigv4"
+)
+
+// SQSConfig configures an SQSQueue
+type SQSConfig struct {
+	QueueURL        string        // e.g. https://sqs.us-east-1.amazonaws.com/123456789012/jobs
+	Region          string        // Defaults to AWS_REGION, then the region in the queue URL
+	Endpoint        string        // API endpoint; defaults to https://sqs.<region>.amazonaws.com, set it for LocalStack or ElasticMQ
+	AccessKeyID     string        // Defaults to AWS_ACCESS_KEY_ID
+	SecretAccessKey string        // Defaults to AWS_SECRET_ACCESS_KEY
+	SessionToken    string        // Defaults to AWS_SESSION_TOKEN
+	WaitTime        time.Duration // Long polling wait of one receive, at most 20s; default 20s
+	HTTPClient      *http.Client  // Defaults to a client with a 60 second timeout
+}
+
+// SQSQueue is a transport on an Amazon SQS queue. A received message is hidden
+// from other workers for the queue's visibility timeout; Ack deletes it and Nack
+// makes it visible again right away. Messages of a crashed worker reappear once
+// their visibility timeout expires, so set it longer than a job takes.
+type SQSQueue struct {
+	config SQSConfig
+	client *http.Client
+}
+
+// NewSQSQueue creates a queue using the AWS JSON protocol of SQS
+func NewSQSQueue(config SQSConfig) (*SQSQueue, error) {
+	queueURL, err := url.Parse(config.QueueURL)
+	if err != nil || config.QueueURL == "" || queueURL.Host == "" {
+		return nil, fmt.Errorf("invalid SQS queue URL %q", config.QueueURL)
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		// sqs.<region>.amazonaws.com
+		if parts := strings.Split(queueURL.Hostname(), "."); len(parts) >= 4 && parts[0] == "sqs" {
+			config.Region = parts[1]
+		}
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("SQS region is not set")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://sqs." + config.Region + ".amazonaws.com"
+	}
+	if config.AccessKeyID == "" {
+		credentials := sigv4.EnvCredentials()
+		config.AccessKeyID, config.SecretAccessKey, config.SessionToken = credentials.AccessKeyID, credentials.SecretAccessKey, credentials.SessionToken
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS credentials are not set")
+	}
+	if config.WaitTime <= 0 || config.WaitTime > 20*time.Second {
+		config.WaitTime = 20 * time.Second
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &SQSQueue{config: config, client: client}, nil
+}
+
+// Send adds a message to the queue
+func (q *SQSQueue) Send(ctx context.Context, body []byte) error {
+	return q.call(ctx, "SendMessage", map[string]any{"QueueUrl": q.config.QueueURL, "MessageBody": string(body)}, nil)
+}
+
+// Receive long polls the queue until a message arrives or ctx is done
+func (q *SQSQueue) Receive(ctx context.Context) (Message, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var response struct {
+			Messages []struct {
+				Body          string `json:"Body"`
+				ReceiptHandle string `json:"ReceiptHandle"`
+			} `json:"Messages"`
+		}
+		err := q.call(ctx, "ReceiveMessage", map[string]any{
+			"QueueUrl":            q.config.QueueURL,
+			"MaxNumberOfMessages": 1,
+			"WaitTimeSeconds":     int(q.config.WaitTime.Seconds()),
+		}, &response)
+		if err != nil {
+			return nil, err
+		}
+		if len(response.Messages) > 0 {
+			message := response.Messages[0]
+			return &sqsMessage{queue: q, body: message.Body, receipt: message.ReceiptHandle}, nil
+		}
+	}
+}
+
+// Close does nothing; the queue holds no connections of its own
+func (q *SQSQueue) Close() error {
+	return nil
+}
+
+// call invokes an SQS action and decodes its response into out
+func (q *SQSQueue) call(ctx context.Context, action string, input map[string]any, out any) error {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, q.config.Endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	request.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+	credentials := sigv4.Credentials{AccessKeyID: q.config.AccessKeyID, SecretAccessKey: q.config.SecretAccessKey, SessionToken: q.config.SessionToken}
+	sigv4.Sign(request, payload, credentials, q.config.Region, "sqs", time.Now())
+
+	response, err := q.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("SQS %s failed: %w", action, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("SQS %s failed: %w", action, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		var apiError struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &apiError)
+		return fmt.Errorf("SQS %s failed with %s: %s %s", action, response.Status, apiError.Type, apiError.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// sqsMessage is a message received from SQS
+type sqsMessage struct {
+	queue   *SQSQueue
+	body    string
+	receipt string
+}
+
+func (m *sqsMessage) Body() []byte { return []byte(m.body) }
+
+// Ack deletes the message
+func (m *sqsMessage) Ack(ctx context.Context) error {
+	return m.queue.call(ctx, "DeleteMessage", map[string]any{"QueueUrl": m.queue.config.QueueURL, "ReceiptHandle": m.receipt}, nil)
+}
+
+// Nack makes the message visible to other workers again
+func (m *sqsMessage) Nack(ctx context.Context) error {
+	return m.queue.call(ctx, "ChangeMessageVisibility", map[string]any{
+		"QueueUrl":          m.queue.config.QueueURL,
+		"ReceiptHandle":     m.receipt,
+		"VisibilityTimeout": 0,
+	}, nil)
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSQS serves the SQS actions SQSQueue uses for one queue
+type fakeSQS struct {
+	mu       sync.Mutex
+	messages []string          // Visible messages
+	inFlight map[string]string // Received messages by receipt handle
+	receipts int
+	targets  []string
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"__type": "MissingAuthenticationToken", "message": "not signed"}`))
+		return
+	}
+	var input struct {
+		MessageBody   string
+		ReceiptHandle string
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	target := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.")
+	f.targets = append(f.targets, target)
+	switch target {
+	case "SendMessage":
+		f.messages = append(f.messages, input.MessageBody)
+		w.Write([]byte(`{"MessageId": "1"}`))
+	case "ReceiveMessage":
+		if len(f.messages) == 0 {
+			w.Write([]byte(`{}`))
+			return
+		}
+		f.receipts++
+		receipt := "r" + strconv.Itoa(f.receipts)
+		body := f.messages[0]
+		f.messages = f.messages[1:]
+		f.inFlight[receipt] = body
+		json.NewEncoder(w).Encode(map[string]any{"Messages": []map[string]string{{"Body": body, "ReceiptHandle": receipt}}})
+	case "DeleteMessage":
+		delete(f.inFlight, input.ReceiptHandle)
+		w.Write([]byte(`{}`))
+	case "ChangeMessageVisibility":
+		f.messages = append(f.messages, f.inFlight[input.ReceiptHandle])
+		delete(f.inFlight, input.ReceiptHandle)
+		w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type": "InvalidAction", "message": "unknown action"}`))
+	}
+}
+
+func TestSQSQueue(t *testing.T) {
+	sqs := &fakeSQS{inFlight: make(map[string]string)}
+	server := httptest.NewServer(sqs)
+	defer server.Close()
+	ctx := context.Background()
+
+	queue, err := NewSQSQueue(SQSConfig{
+		QueueURL:        "https://sqs.eu-west-1.amazonaws.com/123456789012/jobs",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		WaitTime:        time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewSQSQueue failed: %v", err)
+	}
+	if queue.config.Region != "eu-west-1" {
+		t.Errorf("region = %q, want the region of the queue URL", queue.config.Region)
+	}
+
+	for _, body := range []string{"one", "two"} {
+		if err := queue.Send(ctx, []byte(body)); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	first := receive(t, queue)
+	if string(first.Body()) != "one" {
+		t.Errorf("first message = %q, want one", first.Body())
+	}
+	if err := first.Ack(ctx); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	second := receive(t, queue)
+	if err := second.Nack(ctx); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+	if got := string(receive(t, queue).Body()); got != "two" {
+		t.Errorf("message after Nack = %q, want two", got)
+	}
+
+	sqs.mu.Lock()
+	targets := strings.Join(sqs.targets, ",")
+	sqs.mu.Unlock()
+	if want := "SendMessage,SendMessage,ReceiveMessage,DeleteMessage,ReceiveMessage,ChangeMessageVisibility,ReceiveMessage"; targets != want {
+		t.Errorf("actions = %s, want %s", targets, want)
+	}
+
+	queue.config.AccessKeyID = "other"
+	if err := queue.Send(ctx, []byte("three")); err == nil || !strings.Contains(err.Error(), "MissingAuthenticationToken") {
+		t.Errorf("Send with rejected credentials = %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package worker runs flows for jobs taken from a queue, so a fleet of workers
+// can share the long-running agent jobs of many producers. Jobs run through a
+// server.Server, which checkpoints them after each step; a job redelivered after
+// a worker crashed resumes from its checkpoint instead of starting over.
+//
+// Queues are reached through the Transport interface. The package ships
+// transports for Redis lists, NATS queue groups, Amazon SQS and process memory;
+// they speak the brokers' protocols directly and need no client libraries.
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/server"
+)
+
+// ErrClosed is returned by transports that were closed
+var ErrClosed = errors.New("transport closed")
+
+// Job asks a worker to run a flow
+type Job struct {
+	ID         string          `json:"id"`              // Identifies the job and its checkpoint
+	Flow       string          `json:"flow"`            // Name the flow is registered under
+	State      json.RawMessage `json:"state,omitempty"` // Initial state as JSON
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+}
+
+// Result reports a finished job
+type Result struct {
+	JobID      string           `json:"job_id"`
+	Flow       string           `json:"flow"`
+	Action     core.Action      `json:"action,omitempty"`
+	State      any              `json:"state,omitempty"`
+	Steps      []core.StepEvent `json:"steps,omitempty"`
+	Error      string           `json:"error,omitempty"` // Set when the job failed
+	Resumed    bool             `json:"resumed,omitempty"`
+	Worker     string           `json:"worker"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// Message is a message received from a transport. It stays on the queue
+// until it is acknowledged; messages that are neither acknowledged nor
+// rejected are redelivered once the broker notices the worker is gone.
+type Message interface {
+	Body() []byte
+	// Ack removes the message from the queue
+	Ack(ctx context.Context) error
+	// Nack returns the message to the queue for another worker
+	Nack(ctx context.Context) error
+}
+
+// Transport moves messages through a queue
+type Transport interface {
+	// Send adds a message to the queue
+	Send(ctx context.Context, body []byte) error
+	// Receive blocks until a message arrives or ctx is done
+	Receive(ctx context.Context) (Message, error)
+	Close() error
+}
+
+// Reporter receives the results of finished jobs
+type Reporter interface {
+	Report(ctx context.Context, result Result) error
+}
+
+// ReporterFunc adapts a function to Reporter
+type ReporterFunc func(ctx context.Context, result Result) error
+
+// Report calls f
+func (f ReporterFunc) Report(ctx context.Context, result Result) error {
+	return f(ctx, result)
+}
+
+// TransportReporter publishes results as JSON on a transport, e.g. a results queue
+type TransportReporter struct {
+	Transport Transport
+}
+
+// Report sends result on the transport
+func (r TransportReporter) Report(ctx context.Context, result Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of job %s: %w", result.JobID, err)
+	}
+	return r.Transport.Send(ctx, body)
+}
+
+// Enqueue sends a job to run flow on state; the job gets an ID when it has none
+func Enqueue(ctx context.Context, transport Transport, job Job) (Job, error) {
+	if job.Flow == "" {
+		return job, fmt.Errorf("job flow cannot be empty")
+	}
+	if job.ID == "" {
+		job.ID = newJobID()
+	}
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		return job, fmt.Errorf("failed to encode job: %w", err)
+	}
+	if err := transport.Send(ctx, body); err != nil {
+		return job, fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+	}
+	return job, nil
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Config configures a Worker
+type Config struct {
+	Name        string                           // Reported in results; defaults to the host name and process ID
+	Concurrency int                              // Jobs run at the same time; 0 uses 1
+	Reporter    Reporter                         // Receives the result of every job; nil drops results
+	RetryDelay  time.Duration                    // Wait after a receive error or a busy server before taking the next job
+	Logf        func(format string, args ...any) // Reports transport and reporter errors; nil ignores them
+}
+
+// DefaultConfig runs one job at a time
+func DefaultConfig() *Config {
+	return &Config{Concurrency: 1, RetryDelay: time.Second}
+}
+
+// Worker takes jobs from a transport and runs them on a server.Server
+type Worker struct {
+	server    *server.Server
+	transport Transport
+	config    Config
+}
+
+// New creates a worker running the jobs of transport with the flows of srv. Give
+// srv a checkpoint store so redelivered jobs resume where they stopped.
+func New(srv *server.Server, transport Transport, config *Config) *Worker {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	w := &Worker{server: srv, transport: transport, config: *config}
+	if w.config.Concurrency < 1 {
+		w.config.Concurrency = 1
+	}
+	if w.config.Name == "" {
+		host, _ := os.Hostname()
+		w.config.Name = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if w.config.Logf == nil {
+		w.config.Logf = func(string, ...any) {}
+	}
+	return w
+}
+
+// Run processes jobs until ctx is done, then waits for the jobs in progress to
+// finish. Flows do not observe ctx, so a job that started runs to its end.
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < w.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// loop receives and processes messages until ctx is done
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		message, err := w.transport.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+				return
+			}
+			w.config.Logf("worker %s: receive failed: %v", w.config.Name, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.config.RetryDelay):
+			}
+			continue
+		}
+		w.Process(ctx, message)
+	}
+}
+
+// Process runs the job of one message, reports its result and acknowledges it.
+// Jobs the server is too busy for are returned to the queue.
+func (w *Worker) Process(ctx context.Context, message Message) {
+	// A job that ran is reported and acknowledged even when the worker is stopping
+	done := context.WithoutCancel(ctx)
+	var job Job
+	result := Result{Worker: w.config.Name, StartedAt: time.Now()}
+	if err := json.Unmarshal(message.Body(), &job); err != nil {
+		result.Error = fmt.Sprintf("invalid job: %v", err)
+	} else {
+		result.JobID, result.Flow = job.ID, job.Flow
+		run, resumed, err := w.run(ctx, job)
+		if errors.Is(err, server.ErrBusy) || (err != nil && ctx.Err() != nil) {
+			if err := message.Nack(done); err != nil {
+				w.config.Logf("worker %s: failed to return job %s to the queue: %v", w.config.Name, job.ID, err)
+			}
+			// Give other workers a chance before taking another job
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.config.RetryDelay):
+			}
+			return
+		}
+		result.Resumed = resumed
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Action, result.State, result.Steps = run.Action, run.State, run.Steps
+		}
+	}
+	result.FinishedAt = time.Now()
+
+	if w.config.Reporter != nil {
+		if err := w.config.Reporter.Report(done, result); err != nil {
+			// Keep the job so its result is reported when it is redelivered; the
+			// checkpoint makes the rerun cheap
+			w.config.Logf("worker %s: failed to report job %s: %v", w.config.Name, result.JobID, err)
+			if err := message.Nack(done); err != nil {
+				w.config.Logf("worker %s: failed to return job %s to the queue: %v", w.config.Name, result.JobID, err)
+			}
+			return
+		}
+	}
+	if err := message.Ack(done); err != nil {
+		w.config.Logf("worker %s: failed to acknowledge job %s: %v", w.config.Name, result.JobID, err)
+	}
+}
+
+// run runs job, resuming it from its checkpoint when an earlier attempt saved one
+func (w *Worker) run(ctx context.Context, job Job) (server.RunResult, bool, error) {
+	if job.ID == "" {
+		return server.RunResult{}, false, fmt.Errorf("job has no ID")
+	}
+	_, err := w.server.Checkpoint(ctx, job.ID)
+	switch {
+	case errors.Is(err, server.ErrNoCheckpoints):
+		result, err := w.server.Run(ctx, job.Flow, job.State, server.RunOptions{})
+		return result, false, err
+	case errors.Is(err, server.ErrCheckpointNotFound):
+		result, err := w.server.Run(ctx, job.Flow, job.State, server.RunOptions{CheckpointID: job.ID})
+		return result, false, err
+	case err != nil:
+		return server.RunResult{}, false, fmt.Errorf("failed to load checkpoint %s: %w", job.ID, err)
+	}
+	result, err := w.server.Resume(ctx, job.ID, server.RunOptions{})
+	return result, true, err
+}
@@ -0,0 +1,214 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alt-coder/pocketflow-go/core"
+	"github.com/alt-coder/pocketflow-go/internal/flowtest"
+	"github.com/alt-coder/pocketflow-go/server"
+)
+
+type counterState = flowtest.Counter
+
+// memoryCheckpoints is a CheckpointStore in a map
+type memoryCheckpoints struct {
+	mu          sync.Mutex
+	checkpoints map[string]server.Checkpoint
+}
+
+func (m *memoryCheckpoints) SaveCheckpoint(ctx context.Context, checkpoint server.Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[checkpoint.ID] = checkpoint
+	return nil
+}
+
+func (m *memoryCheckpoints) LoadCheckpoint(ctx context.Context, id string) (server.Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	checkpoint, ok := m.checkpoints[id]
+	if !ok {
+		return server.Checkpoint{}, server.ErrCheckpointNotFound
+	}
+	return checkpoint, nil
+}
+
+func newCounterServer(t *testing.T, checkpoints bool, node *flowtest.IncrementNode[*counterState]) *server.Server {
+	t.Helper()
+	config := server.DefaultConfig()
+	if checkpoints {
+		config.Checkpoints = &memoryCheckpoints{checkpoints: make(map[string]server.Checkpoint)}
+	}
+	srv := server.New(config)
+	if err := server.Register[*counterState](srv, "counter", flowtest.NewFlow(node), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	return srv
+}
+
+// receive takes the next message of queue, failing the test when none arrives
+func receive(t *testing.T, queue Transport) Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	message, err := queue.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	return message
+}
+
+func TestWorker_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		checkpoints bool
+	}{
+		{"with checkpoints", true},
+		{"without checkpoints", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := NewMemoryQueue(10)
+			results := NewMemoryQueue(10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			for _, limit := range []int{2, 3, 4} {
+				state, _ := json.Marshal(counterState{Limit: limit})
+				if _, err := Enqueue(ctx, jobs, Job{Flow: "counter", State: state}); err != nil {
+					t.Fatalf("Enqueue failed: %v", err)
+				}
+			}
+
+			w := New(newCounterServer(t, tt.checkpoints, &flowtest.IncrementNode[*counterState]{}), jobs, &Config{
+				Name:        "test",
+				Concurrency: 2,
+				Reporter:    TransportReporter{Transport: results},
+			})
+			done := make(chan error)
+			go func() { done <- w.Run(ctx) }()
+
+			counts := make(map[int]bool)
+			for i := 0; i < 3; i++ {
+				var result struct {
+					Result
+					State counterState `json:"state"`
+				}
+				if err := json.Unmarshal(receive(t, results).Body(), &result); err != nil {
+					t.Fatalf("invalid result: %v", err)
+				}
+				if result.Error != "" || result.Action != core.ActionSuccess || result.Worker != "test" || result.JobID == "" {
+					t.Errorf("result = %+v", result)
+				}
+				if result.State.Count != result.State.Limit {
+					t.Errorf("count = %d, want %d", result.State.Count, result.State.Limit)
+				}
+				counts[result.State.Count] = true
+			}
+			if len(counts) != 3 {
+				t.Errorf("finished counts = %v, want 2, 3 and 4", counts)
+			}
+
+			cancel()
+			if err := <-done; !errors.Is(err, context.Canceled) {
+				t.Errorf("Run() = %v", err)
+			}
+			if jobs.Len() != 0 {
+				t.Errorf("%d jobs left in the queue", jobs.Len())
+			}
+		})
+	}
+}
+
+func TestWorker_ResumesRedeliveredJob(t *testing.T) {
+	ctx := context.Background()
+	jobs := NewMemoryQueue(1)
+	var results []Result
+	node := &flowtest.IncrementNode[*counterState]{CrashAt: 3}
+	w := New(newCounterServer(t, true, node), jobs, &Config{Reporter: ReporterFunc(func(ctx context.Context, result Result) error {
+		results = append(results, result)
+		return nil
+	})})
+
+	state, _ := json.Marshal(counterState{Limit: 4})
+	job, err := Enqueue(ctx, jobs, Job{Flow: "counter", State: state})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	w.Process(ctx, receive(t, jobs))
+	if len(results) != 1 || results[0].Error == "" || results[0].JobID != job.ID {
+		t.Fatalf("results of the interrupted job = %+v", results)
+	}
+
+	node.CrashAt = 0
+	jobs.Send(ctx, mustMarshal(t, job))
+	w.Process(ctx, receive(t, jobs))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	result := results[1]
+	if !result.Resumed || result.Error != "" || result.Action != core.ActionSuccess {
+		t.Errorf("result of the redelivered job = %+v", result)
+	}
+	if len(result.Steps) != 2 || result.Steps[0].Step != 3 {
+		t.Errorf("steps = %+v, want steps 3 and 4", result.Steps)
+	}
+	if state := result.State.(*counterState); state.Count != 4 {
+		t.Errorf("count = %d, want 4", state.Count)
+	}
+}
+
+func TestWorker_Process(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		reportErr error
+		wantError bool
+		requeued  bool
+	}{
+		{"finished job is acknowledged", `{"id": "a", "flow": "counter", "state": {"limit": 1}}`, nil, false, false},
+		{"invalid job is reported", `not json`, nil, true, false},
+		{"unknown flow is reported", `{"id": "b", "flow": "missing"}`, nil, true, false},
+		{"job without ID is reported", `{"flow": "counter"}`, nil, true, false},
+		{"failed report returns the job", `{"id": "c", "flow": "counter", "state": {"limit": 1}}`, errors.New("unavailable"), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs := NewMemoryQueue(1)
+			var reported []Result
+			w := New(newCounterServer(t, true, &flowtest.IncrementNode[*counterState]{}), jobs, &Config{Reporter: ReporterFunc(func(ctx context.Context, result Result) error {
+				reported = append(reported, result)
+				return tt.reportErr
+			})})
+
+			jobs.Send(ctx, []byte(tt.body))
+			w.Process(ctx, receive(t, jobs))
+
+			if len(reported) != 1 {
+				t.Fatalf("got %d results, want 1", len(reported))
+			}
+			if got := reported[0].Error != ""; got != tt.wantError {
+				t.Errorf("result error = %q", reported[0].Error)
+			}
+			if got := jobs.Len() == 1; got != tt.requeued {
+				t.Errorf("requeued = %v, want %v", got, tt.requeued)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return data
+}